@@ -0,0 +1,73 @@
+// Package cert implements actions managing the TLS certificates of platforms
+package cert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/cert"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Issue implements the platform:cert:issue command
+type Issue struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring  keyring.Keyring
+	Name     string
+	Password string
+	Staging  bool
+
+	PropagationTimeout string
+}
+
+// Execute runs the platform:cert:issue action
+func (i *Issue) Execute(ctx context.Context) error {
+	l, err := lock.Platform(ctx, i.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	platform, m, err := newManager(i.Name, i.Password, i.Staging, i.PropagationTimeout)
+	if err != nil {
+		return err
+	}
+	m.WithLogger, m.WithTerm, m.Keyring = i.WithLogger, i.WithTerm, i.Keyring
+
+	status, err := m.Issue(ctx, i.Name, platform)
+	if err != nil {
+		return err
+	}
+	i.Term().Success().Printfln("Issued certificate of %v, valid until %s", status.Names, status.NotAfter.Format(time.DateOnly))
+	i.Term().Info().Printfln("  Certificate: %s", status.CertFile)
+	i.Term().Info().Printfln("  Key (vault): %s", status.KeyFile)
+	return nil
+}
+
+// newManager loads the platform and returns a certificate manager for it
+func newManager(name, password string, staging bool, propagationTimeout string) (*schema.Platform, *cert.Manager, error) {
+	platform, err := schema.Load(name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return nil, nil, failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	timeout, err := time.ParseDuration(propagationTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid propagation timeout %q: %w", propagationTimeout, err)
+	}
+
+	m := &cert.Manager{Password: password, PropagationTimeout: timeout}
+	if staging {
+		m.Directory = cert.LetsEncryptStagingURL
+	}
+	return platform, m, nil
+}
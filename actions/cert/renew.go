@@ -0,0 +1,54 @@
+package cert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+)
+
+// Renew implements the platform:cert:renew command
+type Renew struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring  keyring.Keyring
+	Name     string
+	Password string
+	Staging  bool
+	Days     int
+	Force    bool
+
+	PropagationTimeout string
+}
+
+// Execute runs the platform:cert:renew action
+func (r *Renew) Execute(ctx context.Context) error {
+	if r.Days < 0 {
+		return fmt.Errorf("invalid number of days %d", r.Days)
+	}
+	l, err := lock.Platform(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	platform, m, err := newManager(r.Name, r.Password, r.Staging, r.PropagationTimeout)
+	if err != nil {
+		return err
+	}
+	m.WithLogger, m.WithTerm, m.Keyring = r.WithLogger, r.WithTerm, r.Keyring
+
+	status, renewed, err := m.Renew(ctx, r.Name, platform, time.Duration(r.Days)*24*time.Hour, r.Force)
+	if err != nil {
+		return err
+	}
+	if !renewed {
+		r.Term().Success().Printfln("Certificate is valid until %s, nothing to renew before %d days of expiry", status.NotAfter.Format(time.DateOnly), r.Days)
+		return nil
+	}
+	r.Term().Success().Printfln("Renewed certificate of %v, valid until %s", status.Names, status.NotAfter.Format(time.DateOnly))
+	return nil
+}
@@ -0,0 +1,79 @@
+package cert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/cert"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Status implements the platform:cert:status command
+type Status struct {
+	action.WithLogger
+	action.WithTerm
+
+	Name   string
+	Format string
+}
+
+// Execute runs the platform:cert:status action
+func (s *Status) Execute() error {
+	platform, err := schema.Load(s.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", s.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := cert.LoadStatus(s.Name, platform)
+	if errors.Is(err, cert.ErrNotIssued) {
+		return fmt.Errorf("no certificate issued for platform %q, run platform:cert:issue %s", s.Name, s.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(s.Format) == "json" {
+		output, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	remaining := status.Remaining(time.Now())
+	s.Term().Info().Printfln("Names:       %s", strings.Join(status.Names, ", "))
+	s.Term().Info().Printfln("Issuer:      %s", status.Issuer)
+	s.Term().Info().Printfln("Valid from:  %s", status.NotBefore.Format(time.RFC3339))
+	s.Term().Info().Printfln("Valid until: %s", status.NotAfter.Format(time.RFC3339))
+	s.Term().Info().Printfln("Certificate: %s", status.CertFile)
+	if status.KeyStored {
+		s.Term().Info().Printfln("Key (vault): %s", status.KeyFile)
+	} else {
+		s.Term().Warning().Printfln("Key (vault): missing at %s", status.KeyFile)
+	}
+
+	names, err := cert.Names(platform)
+	if err != nil {
+		return err
+	}
+	switch {
+	case remaining <= 0:
+		s.Term().Error().Printfln("Certificate expired on %s, run platform:cert:renew %s", status.NotAfter.Format(time.DateOnly), s.Name)
+	case !status.Covers(names):
+		s.Term().Warning().Printfln("Certificate doesn't cover %v, run platform:cert:renew %s", names, s.Name)
+	case remaining <= cert.DefaultRenewBefore:
+		s.Term().Warning().Printfln("Certificate expires in %d days, run platform:cert:renew %s", int(remaining.Hours()/24), s.Name)
+	default:
+		s.Term().Success().Printfln("Certificate is valid for %d more days", int(remaining.Hours()/24))
+	}
+	return nil
+}
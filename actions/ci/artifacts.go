@@ -0,0 +1,79 @@
+// Package ci implements actions working with CI pipelines
+package ci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/ci"
+)
+
+// Artifacts implements the platform:ci:artifacts command
+type Artifacts struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring      keyring.Keyring
+	Platform     string // Platform of the credentials of the keyring, only shared ones are read if empty
+	Pipeline     int
+	Job          string
+	OutputDir    string
+	GitlabDomain string
+	GitlabAuth   string
+	APIRetries   int
+	APITimeout   string
+}
+
+// Execute runs the platform:ci:artifacts action
+func (a *Artifacts) Execute(ctx context.Context) error {
+	api, err := ci.ParseAPIConfig(a.APIRetries, a.APITimeout)
+	if err != nil {
+		return err
+	}
+	c := &ci.ContinuousIntegration{WithLogger: a.WithLogger, WithTerm: a.WithTerm, API: api, Keyring: a.Keyring, Platform: a.Platform}
+
+	token, err := c.GitLabToken(ctx, a.Keyring, a.GitlabDomain, a.GitlabAuth)
+	if err != nil {
+		return err
+	}
+
+	repoPath, err := c.GetRepoPath()
+	if err != nil {
+		return fmt.Errorf("failed to get repo path: %w", err)
+	}
+	projectID, err := c.GetProjectID(ctx, a.GitlabDomain, token, repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get ID of project %q: %w", repoPath, err)
+	}
+
+	pipelineID := a.Pipeline
+	if pipelineID == 0 {
+		branchName, err := c.GetBranchName()
+		if err != nil {
+			return fmt.Errorf("failed to get branch name: %w", err)
+		}
+		pipelineID, err = c.GetLatestPipeline(ctx, a.GitlabDomain, token, projectID, branchName)
+		if id, ok := c.RefreshProjectID(ctx, a.GitlabDomain, token, repoPath, err); ok {
+			projectID = id
+			pipelineID, err = c.GetLatestPipeline(ctx, a.GitlabDomain, token, projectID, branchName)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	images, err := c.DownloadImages(ctx, a.GitlabDomain, token, projectID, pipelineID, a.Job, a.OutputDir)
+	if id, ok := c.RefreshProjectID(ctx, a.GitlabDomain, token, repoPath, err); ok {
+		images, err = c.DownloadImages(ctx, a.GitlabDomain, token, id, pipelineID, a.Job, a.OutputDir)
+	}
+	if err != nil {
+		return err
+	}
+	a.Term().Success().Printfln("Downloaded %d Platform Image(s) of pipeline %d", len(images), pipelineID)
+	for _, img := range images {
+		a.Term().Printfln("  %s", img)
+	}
+	return nil
+}
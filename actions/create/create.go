@@ -1,14 +1,20 @@
 package create
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/event"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
 	"github.com/plasmash/plasmactl-platform/pkg/schema"
-	"gopkg.in/yaml.v3"
 )
 
 // Create implements the platform:create command
@@ -22,6 +28,11 @@ type Create struct {
 	DNSProvider   string
 	Domain        string
 	SkipDNS       bool
+
+	WaitPropagation    bool
+	PropagationTimeout string
+
+	summary *result.PlatformSummary
 }
 
 // SetLogger sets the logger for the action
@@ -35,57 +46,52 @@ func (c *Create) SetTerm(term *launchr.Terminal) {
 }
 
 // Execute runs the platform:create action
-func (c *Create) Execute() error {
-	instDir := filepath.Join("inst", c.Name)
+func (c *Create) Execute(ctx context.Context) error {
+	instDir := schema.PlatformDir(c.Name)
 	nodesDir := filepath.Join(instDir, "nodes")
-	platformFile := filepath.Join(instDir, "platform.yaml")
+	platformFile := schema.PlatformFile(c.Name)
 
 	// Check if platform already exists
 	if _, err := os.Stat(instDir); !os.IsNotExist(err) {
 		return fmt.Errorf("platform %q already exists at %s", c.Name, instDir)
 	}
+	timeout, err := time.ParseDuration(c.PropagationTimeout)
+	if c.WaitPropagation && err != nil {
+		return fmt.Errorf("invalid propagation timeout %q: %w", c.PropagationTimeout, err)
+	}
+
+	// Provider-specific defaults, e.g. the API token of the metal provider
+	platform := schema.NewPlatform(c.Name, c.MetalProvider, c.DNSProvider, c.Domain)
+	platform.ApplyDefaults(c.MetalProvider)
+	if err := platform.Validate(); err != nil {
+		return err
+	}
+	metalProvider, dnsProvider := platform.Infrastructure.MetalProvider, platform.DNS.Provider
 
 	c.Term.Info().Printfln("Creating platform %q", c.Name)
-	c.Term.Info().Printfln("  Metal provider: %s", c.MetalProvider)
-	c.Term.Info().Printfln("  DNS provider: %s", c.DNSProvider)
+	c.Term.Info().Printfln("  Metal provider: %s", metalProvider)
+	c.Term.Info().Printfln("  DNS provider: %s", dnsProvider)
 	c.Term.Info().Printfln("  Domain: %s", c.Domain)
 
 	// Create directories
 	if err := os.MkdirAll(nodesDir, 0755); err != nil {
 		return fmt.Errorf("failed to create nodes directory: %w", err)
 	}
-
-	// Create platform.yaml
-	platform := schema.NewPlatform(c.Name, c.MetalProvider, c.DNSProvider, c.Domain)
-
-	// Set provider-specific defaults for metal provider
-	switch c.MetalProvider {
-	case "scaleway":
-		platform.Infrastructure.API = schema.APIConfig{
-			URI:   "https://api.online.net/api/v1/",
-			Token: "{{ .keyring.scaleway_api_token }}",
-		}
-	case "hetzner":
-		platform.Infrastructure.API = schema.APIConfig{
-			Token: "{{ .keyring.hetzner_api_token }}",
-		}
-	case "ovh":
-		platform.Infrastructure.API = schema.APIConfig{
-			Token: "{{ .keyring.ovh_api_token }}",
-		}
-	case "aws", "gcp", "azure":
-		// Cloud providers use environment variables or SDK defaults
-	case "manual":
-		// No API configuration needed
-	}
-
-	data, err := yaml.Marshal(platform)
+	l, err := lock.Platform(ctx, c.Name)
 	if err != nil {
-		return fmt.Errorf("failed to marshal platform.yaml: %w", err)
+		return err
 	}
+	defer l.Release()
 
-	if err := os.WriteFile(platformFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write platform.yaml: %w", err)
+	// Create platform.yaml
+	if err := platform.Save(platformFile); err != nil {
+		return err
+	}
+	c.summary = &result.PlatformSummary{
+		Name:          platform.Name,
+		Domain:        platform.DNS.Domain,
+		MetalProvider: platform.Infrastructure.MetalProvider,
+		DNSProvider:   platform.DNS.Provider,
 	}
 
 	// Create .gitkeep in nodes directory to ensure it's tracked
@@ -95,26 +101,29 @@ func (c *Create) Execute() error {
 	}
 
 	c.Term.Success().Printfln("Created platform scaffold at %s", instDir)
+	event.Publish(event.Event{Type: event.PlatformCreated, Platform: c.Name, Data: map[string]any{
+		"domain":         platform.DNS.Domain,
+		"metal_provider": platform.Infrastructure.MetalProvider,
+		"dns_provider":   platform.DNS.Provider,
+	}})
 
 	// Configure DNS if not skipped and not manual
-	if !c.SkipDNS && c.DNSProvider != "manual" {
+	if !c.SkipDNS && dnsProvider != dnsprovider.ProviderManual {
 		c.Term.Info().Println()
 		c.Term.Info().Println("Configuring DNS records...")
-		if err := c.configureDNS(); err != nil {
+		if err := c.configureDNS(ctx, platform, timeout); err != nil {
 			c.Term.Warning().Printfln("DNS configuration failed: %v", err)
-			c.Term.Warning().Println("You can configure DNS manually or retry with platform:validate")
-		} else {
-			c.Term.Success().Println("DNS records configured successfully")
+			c.Term.Warning().Printfln("You can configure DNS manually or retry with platform:dns:apply %s", c.Name)
 		}
 	}
 
 	// Print next steps
 	c.Term.Info().Println()
 	c.Term.Info().Println("Next steps:")
-	if c.MetalProvider != "manual" {
-		c.Term.Info().Printfln("  1. Ensure credentials are configured: plasmactl keyring:login %s", c.MetalProvider)
-		if c.DNSProvider != "manual" && c.DNSProvider != c.MetalProvider {
-			c.Term.Info().Printfln("  2. Ensure DNS credentials: plasmactl keyring:login %s", c.DNSProvider)
+	if metalProvider != schema.ProviderManual {
+		c.Term.Info().Printfln("  1. Ensure credentials are configured: plasmactl keyring:login %s", metalProvider)
+		if dnsProvider != schema.ProviderManual && dnsProvider != metalProvider {
+			c.Term.Info().Printfln("  2. Ensure DNS credentials: plasmactl keyring:login %s", dnsProvider)
 			c.Term.Info().Printfln("  3. Provision nodes: plasmactl node:provision %s -c <chassis>:<offer>:<count>", c.Name)
 		} else {
 			c.Term.Info().Printfln("  2. Provision nodes: plasmactl node:provision %s -c <chassis>:<offer>:<count>", c.Name)
@@ -128,21 +137,34 @@ func (c *Create) Execute() error {
 	return nil
 }
 
-// configureDNS sets up DNS records (MX, DKIM, DMARC, SPF, rDNS)
-func (c *Create) configureDNS() error {
-	// TODO: Implement DNS configuration via Terraform
-	// This will use terraform-exec to:
-	// 1. Generate Terraform configuration for the DNS provider
-	// 2. Apply the configuration to create:
-	//    - MX records
-	//    - DKIM records
-	//    - DMARC records
-	//    - SPF records
-	//    - rDNS (if supported by provider)
-
-	c.Term.Info().Println("  DNS configuration via Terraform is not yet implemented")
-	c.Term.Info().Println("  Manual DNS setup required for now")
+// Result returns the summary of the created platform for the callers of the action, nil if it wasn't
+// created
+func (c *Create) Result() *result.PlatformSummary {
+	return c.summary
+}
 
+// configureDNS sets up the DNS records of platform.yaml (MX, DKIM, DMARC, SPF, A/AAAA)
+func (c *Create) configureDNS(ctx context.Context, platform *schema.Platform, timeout time.Duration) error {
+	records, err := dnsprovider.Records(platform.DNS)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		c.Term.Info().Println("  No DNS records configured yet, add dns.addresses and dns.mail to platform.yaml")
+		c.Term.Info().Printfln("  and apply them with platform:dns:apply %s", c.Name)
+		return nil
+	}
+
+	m := &dns.Manager{Keyring: c.Keyring, Platform: c.Name}
+	m.SetLogger(c.Log)
+	m.SetTerm(c.Term)
+	if _, err = m.Apply(ctx, platform, false); err != nil {
+		return err
+	}
+	c.Term.Success().Println("DNS records configured successfully")
+
+	if c.WaitPropagation {
+		return m.WaitPropagation(ctx, platform, timeout)
+	}
 	return nil
 }
-
@@ -0,0 +1,252 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/publish"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/internal/secrets"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"golang.org/x/term"
+)
+
+// Statuses of the bootstrapped items
+const (
+	statusPresent  = "present"
+	statusShared   = "shared" // Only the item shared by all platforms exists
+	statusAdded    = "added"
+	statusOptional = "optional"
+)
+
+// Labels of the credentials which aren't namespaced by purpose
+const (
+	purposeReference = "reference" // Keyring items referenced by platform.yaml
+	purposeVault     = "vault"     // Ansible vault passwords in the secrets backend
+)
+
+// Bootstrap implements the platform:credentials command
+type Bootstrap struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+	Config  launchr.Config // Config of the artifact repository
+
+	Name         string
+	Check        bool // Only report the credentials, fail if some are missing
+	GitlabDomain string
+	GiteaDomain  string
+}
+
+// Execute runs the platform:credentials action
+func (b *Bootstrap) Execute(ctx context.Context) error {
+	if b.Keyring == nil {
+		return errors.New("keyring is not available")
+	}
+	platform, err := schema.Load(b.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", b.Name)
+	}
+	if err != nil {
+		return err
+	}
+	artifactRepo, err := publish.LoadPlatformConfig(b.Config, b.Name)
+	if err != nil {
+		return err
+	}
+	refs, err := schema.Refs(b.Name)
+	if err != nil {
+		return err
+	}
+	backend, err := secrets.New(platform, b.Keyring, b.Log(), b.Term())
+	if err != nil {
+		return err
+	}
+
+	items := referencedItems(refs)
+	items = append(items, platformItems(platform, nil, b.GitlabDomain, b.GiteaDomain, artifactRepo)...)
+	for _, key := range vaultPasswordKeys(platform.Secrets) {
+		items = append(items, item{purpose: purposeVault, key: key})
+	}
+	for i := range items {
+		if items[i].status, err = b.status(ctx, backend, items[i]); err != nil {
+			return err
+		}
+	}
+
+	interactive := !b.Check && term.IsTerminal(int(os.Stdin.Fd()))
+	added := 0
+	for i := range items {
+		it := &items[i]
+		if it.status != statusMissing || !interactive {
+			continue
+		}
+		if err = b.request(ctx, backend, *it); err != nil {
+			return err
+		}
+		it.status = statusAdded
+		added++
+	}
+	if added > 0 {
+		if err = b.Keyring.Save(); err != nil {
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "PURPOSE\tITEM\tSTATUS")
+	missing, shared := 0, 0
+	for _, it := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", it.purpose, b.itemName(it), it.status)
+		switch it.status {
+		case statusMissing:
+			missing++
+		case statusShared:
+			shared++
+		}
+	}
+	w.Flush()
+
+	if added > 0 {
+		b.Term().Success().Printfln("Added %d credential(s) of %s", added, b.Name)
+	}
+	if shared > 0 {
+		b.Term().Info().Printfln("%d credential(s) are shared by all platforms, copy them to %s with platform:credentials:migrate", shared, b.Name)
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d missing credential(s) of %s, add them with platform:credentials %s on an interactive terminal", missing, b.Name, b.Name)
+	}
+	return nil
+}
+
+// referencedItems returns the keyring items referenced by platform.yaml, once per key. The malformed
+// references and the other sources are reported by platform:validate.
+func referencedItems(refs []schema.Ref) []item {
+	var items []item
+	seen := make(map[string]bool)
+	for _, r := range refs {
+		if r.Source != schema.KeyringSource || seen[r.Key] {
+			continue
+		}
+		seen[r.Key] = true
+		items = append(items, item{purpose: purposeReference, key: r.Key})
+	}
+	return items
+}
+
+// vaultPasswordKeys returns the keys of the vault passwords in the secrets backend
+func vaultPasswordKeys(cfg schema.SecretsConfig) []string {
+	if len(cfg.VaultIDs) == 0 {
+		return []string{secrets.VaultPasswordKey}
+	}
+	keys := make([]string, len(cfg.VaultIDs))
+	for i, id := range cfg.VaultIDs {
+		keys[i] = secrets.VaultIDKey(id)
+	}
+	return keys
+}
+
+// scope returns the keyring scope of an item, the referenced items and the vault passwords are shared
+func (b *Bootstrap) scope(it item) api.Scope {
+	if it.purpose == purposeReference || it.purpose == purposeVault {
+		return api.Scope{}
+	}
+	return api.NewScope(b.Name, it.purpose)
+}
+
+// itemName returns the keyring key or URL of an item
+func (b *Bootstrap) itemName(it item) string {
+	if it.url != "" {
+		return b.scope(it).URL(it.url)
+	}
+	return b.scope(it).Key(it.key)
+}
+
+// status looks an item up in the scope of the platform, then in the shared items
+func (b *Bootstrap) status(ctx context.Context, backend secrets.Backend, it item) (string, error) {
+	if it.purpose == purposeVault {
+		_, err := backend.Get(ctx, it.key)
+		if errors.Is(err, secrets.ErrNotFound) {
+			return statusMissing, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return statusPresent, nil
+	}
+
+	scope := b.scope(it)
+	lookup := func(name string) error {
+		if it.url != "" {
+			_, err := b.Keyring.GetForURL(name)
+			return err
+		}
+		_, err := b.Keyring.GetForKey(name)
+		return err
+	}
+	err := lookup(b.itemName(it))
+	if err == nil {
+		return statusPresent, nil
+	}
+	if !errors.Is(err, keyring.ErrNotFound) {
+		return "", err
+	}
+	if !scope.IsShared() {
+		name := it.key
+		if it.url != "" {
+			name = it.url
+		}
+		if err = lookup(name); err == nil {
+			return statusShared, nil
+		} else if !errors.Is(err, keyring.ErrNotFound) {
+			return "", err
+		}
+	}
+	if it.optional {
+		return statusOptional, nil
+	}
+	return statusMissing, nil
+}
+
+// request requests a missing item on the terminal. The keyring items are added to the scope of the
+// platform and saved at the end of the session, the vault passwords are stored in the secrets backend.
+func (b *Bootstrap) request(ctx context.Context, backend secrets.Backend, it item) error {
+	if it.purpose == purposeVault {
+		_, err := secrets.Request(ctx, backend, b.Term(), it.key)
+		return err
+	}
+
+	name := b.itemName(it)
+	if it.url != "" {
+		b.Term().Info().Printfln("Please add the credentials of %s to the keyring", name)
+		creds := keyring.CredentialsItem{URL: name}
+		if err := keyring.RequestCredentialsFromTty(&creds); err != nil {
+			return err
+		}
+		if creds.Username == "" || creds.Password == "" {
+			return fmt.Errorf("credentials of %q must have a username and a password", name)
+		}
+		redact.Add(creds.Password)
+		return b.Keyring.AddItem(creds)
+	}
+
+	b.Term().Info().Printfln("Please add the %s item %q to the keyring", it.purpose, name)
+	kv := keyring.KeyValueItem{Key: name, Value: ""}
+	if err := keyring.RequestKeyValueFromTty(&kv); err != nil {
+		return err
+	}
+	value, ok := kv.Value.(string)
+	if !ok || value == "" {
+		return fmt.Errorf("keyring value %q must be a non-empty string", name)
+	}
+	redact.Add(value)
+	return b.Keyring.AddItem(kv)
+}
@@ -0,0 +1,209 @@
+// Package credentials implements actions managing the keyring credentials of platforms
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/ci"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	"github.com/plasmash/plasmactl-platform/internal/metal"
+	"github.com/plasmash/plasmactl-platform/internal/publish"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Statuses of the migrated items
+const (
+	statusCopied  = "copied"
+	statusPlanned = "to copy"
+	statusExists  = "exists"  // Already in the scope of the platform
+	statusMissing = "missing" // No shared item to copy
+)
+
+// Migrate implements the platform:credentials:migrate command
+type Migrate struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+	Config  launchr.Config // Config of the artifact repository
+
+	Name         string
+	Purposes     []string // Purposes of the migrated credentials, all by default
+	DryRun       bool
+	GitlabDomain string
+	GiteaDomain  string
+}
+
+// item is a credential of the platform, a key-value item or the credentials of a URL
+type item struct {
+	purpose  string
+	key      string
+	url      string
+	optional bool // Alternative to other credentials, e.g. the GitLab access token
+	status   string
+}
+
+// Execute runs the platform:credentials:migrate action
+func (m *Migrate) Execute() error {
+	if m.Keyring == nil {
+		return errors.New("keyring is not available")
+	}
+	for _, p := range m.Purposes {
+		if !slices.Contains(api.Purposes(), p) {
+			return fmt.Errorf("unknown purpose %q (use %v)", p, api.Purposes())
+		}
+	}
+	platform, err := schema.Load(m.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	artifactRepo, err := publish.LoadPlatformConfig(m.Config, m.Name)
+	if err != nil {
+		return err
+	}
+
+	items := platformItems(platform, m.Purposes, m.GitlabDomain, m.GiteaDomain, artifactRepo)
+	copied := 0
+	for i := range items {
+		it := &items[i]
+		if it.status, err = m.migrate(*it); err != nil {
+			return err
+		}
+		if it.status == statusCopied {
+			copied++
+		}
+	}
+	if len(items) == 0 {
+		m.Term().Info().Printfln("No credentials of %s to migrate", m.Name)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "PURPOSE\tITEM\tPLATFORM ITEM\tSTATUS")
+	for _, it := range items {
+		scope := api.NewScope(m.Name, it.purpose)
+		name, scoped := it.key, scope.Key(it.key)
+		if it.url != "" {
+			name, scoped = it.url, scope.URL(it.url)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", it.purpose, name, scoped, it.status)
+	}
+	w.Flush()
+
+	if copied > 0 {
+		if err = m.Keyring.Save(); err != nil {
+			return err
+		}
+		m.Term().Success().Printfln("Copied %d credential(s) to %s", copied, m.Name)
+		m.Term().Info().Println("The shared items are kept for the other platforms, remove them with keyring:unset and keyring:logout once migrated")
+	}
+	return nil
+}
+
+// platformItems returns the credentials used by the platform for the purposes, all if none
+func platformItems(platform *schema.Platform, purposes []string, gitlabDomain, giteaDomain string, artifactRepo publish.Config) []item {
+	var items []item
+	add := func(purpose string, keys ...string) {
+		if len(purposes) > 0 && !slices.Contains(purposes, purpose) {
+			return
+		}
+		for _, k := range keys {
+			items = append(items, item{purpose: purpose, key: k})
+		}
+	}
+	addURL := func(purpose, url string) {
+		if url != "" && (len(purposes) == 0 || slices.Contains(purposes, purpose)) {
+			items = append(items, item{purpose: purpose, url: url})
+		}
+	}
+
+	// Tokens set in infrastructure.api.token are explicit, they aren't namespaced
+	infra := platform.Infrastructure
+	switch {
+	case infra.MetalProvider == metal.ProviderScaleway && infra.API.Token == "":
+		add(api.PurposeProvider, metal.ScalewayTokenKey)
+	case infra.MetalProvider == metal.ProviderHetzner && infra.API.Token == "":
+		add(api.PurposeProvider, metal.HetznerTokenKey)
+	case infra.MetalProvider == metal.ProviderHetznerRobot:
+		add(api.PurposeProvider, metal.HetznerRobotUserKey, metal.HetznerRobotPasswordKey)
+	case infra.MetalProvider == dns.ProviderOVH:
+		add(api.PurposeDNS, dns.OVHApplicationKeyKey, dns.OVHApplicationSecretKey, dns.OVHConsumerKeyKey)
+	}
+	switch platform.DNS.Provider {
+	case dns.ProviderCloudflare:
+		add(api.PurposeDNS, dns.CloudflareTokenKey)
+	case dns.ProviderRoute53:
+		add(api.PurposeDNS, dns.AWSAccessKeyIDKey, dns.AWSSecretAccessKeyKey)
+	}
+
+	// The GitLab access token replaces the username and password of OAuth
+	if len(purposes) == 0 || slices.Contains(purposes, api.PurposeCI) {
+		items = append(items, item{purpose: api.PurposeCI, key: ci.GitLabTokenKey, optional: gitlabDomain != ""})
+	}
+	addURL(api.PurposeCI, gitlabDomain)
+	addURL(api.PurposeCI, giteaDomain)
+	// The artifact repositories authenticate with a token or the credentials of their URL
+	if artifactRepo.TokenAuth() {
+		add(api.PurposeArtifactRepo, artifactRepo.TokenKey)
+	} else {
+		for _, u := range artifactRepo.URLs() {
+			addURL(api.PurposeArtifactRepo, u)
+		}
+	}
+	return items
+}
+
+// migrate copies a shared item to the scope of the platform, unless it is missing or already there
+func (m *Migrate) migrate(it item) (string, error) {
+	scope := api.NewScope(m.Name, it.purpose)
+	var scoped keyring.SecretItem
+	if it.url != "" {
+		if _, err := m.Keyring.GetForURL(scope.URL(it.url)); err == nil {
+			return statusExists, nil
+		} else if !errors.Is(err, keyring.ErrNotFound) {
+			return "", err
+		}
+		creds, err := m.Keyring.GetForURL(it.url)
+		if errors.Is(err, keyring.ErrNotFound) {
+			return statusMissing, nil
+		} else if err != nil {
+			return "", err
+		}
+		creds.URL = scope.URL(it.url)
+		scoped = creds
+	} else {
+		if _, err := m.Keyring.GetForKey(scope.Key(it.key)); err == nil {
+			return statusExists, nil
+		} else if !errors.Is(err, keyring.ErrNotFound) {
+			return "", err
+		}
+		kv, err := m.Keyring.GetForKey(it.key)
+		if errors.Is(err, keyring.ErrNotFound) {
+			return statusMissing, nil
+		} else if err != nil {
+			return "", err
+		}
+		kv.Key = scope.Key(it.key)
+		scoped = kv
+	}
+
+	if m.DryRun {
+		return statusPlanned, nil
+	}
+	if err := m.Keyring.AddItem(scoped); err != nil {
+		return "", err
+	}
+	return statusCopied, nil
+}
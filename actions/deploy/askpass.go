@@ -0,0 +1,386 @@
+package deploy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchrctl/launchr"
+)
+
+// Modes of handing the vault password over to ansible
+const (
+	PasswordModeAuto   = "auto"   // PasswordModeAuto uses the socket where supported, the askpass script otherwise
+	PasswordModeSocket = "socket" // PasswordModeSocket serves the password to the askpass helper over a private socket
+	PasswordModeScript = "script" // PasswordModeScript passes the password in the environment of an askpass program
+)
+
+// Environment variables used to hand the vault password over to ansible and ssh.
+const (
+	// vaultPassEnv holds the password itself, it is only ever set in the child process environment.
+	// The passwords of the vault identities are in vaultPassEnv_<n>, n being their position in vaultIDsEnv.
+	vaultPassEnv = "PLASMA_VAULT_PASS"
+	// vaultIDsEnv holds the space separated IDs of the vault identities.
+	vaultIDsEnv = "PLASMA_VAULT_IDS"
+	// askpassSocketEnv is the socket the askpass helper reads the password from instead of vaultPassEnv.
+	askpassSocketEnv = "PLASMA_ASKPASS_SOCKET"
+)
+
+// askpassProgram is the name of the link to the plasmactl binary run as askpass program. Only this
+// name switches the binary into askpass helper mode, the plasmactl runs nested in the ansible
+// processes are unaffected. Ansible passes the vault ID to the programs named *-client.
+const askpassProgram = "plasma-askpass-client"
+
+// vaultPassword is the password of an Ansible vault identity, the single password of a platform
+// without vault identities has no ID
+type vaultPassword struct {
+	ID       string
+	Password string
+}
+
+// askpass provides the vault password to ansible-playbook and ssh without writing it to disk
+type askpass interface {
+	// Env returns environment variables pointing ansible and ssh to the askpass program. The first
+	// password is the one of ssh.
+	Env(passwords []vaultPassword) []string
+	// Close releases resources held by the askpass program
+	Close() error
+}
+
+// newAskpass selects the askpass strategy of the password mode, identities tells whether the program
+// must serve the passwords of vault identities
+func newAskpass(mode string, identities bool, log *launchr.Logger) (askpass, error) {
+	switch mode {
+	case "", PasswordModeAuto:
+		ap, err := newSocketAskpass()
+		if err == nil {
+			return ap, nil
+		}
+		log.Debug("socket unavailable for the vault password, falling back to the askpass script", "error", err)
+		return newEnvAskpass(identities)
+	case PasswordModeSocket:
+		return newSocketAskpass()
+	case PasswordModeScript:
+		return newEnvAskpass(identities)
+	default:
+		return nil, fmt.Errorf("unknown password mode %q (use %s, %s or %s)", mode, PasswordModeAuto, PasswordModeSocket, PasswordModeScript)
+	}
+}
+
+// newEnvAskpass selects the askpass program reading the password from the environment supported by
+// the current OS
+func newEnvAskpass(identities bool) (askpass, error) {
+	switch runtime.GOOS {
+	case "windows", "plan9":
+		// Ansible only passes the vault ID to programs named *-client
+		if identities {
+			return nil, fmt.Errorf("vault identities are not supported by the askpass helper on %s", runtime.GOOS)
+		}
+		return newHelperAskpass()
+	default:
+		return newScriptAskpass()
+	}
+}
+
+// askpassEnv builds the environment shared by all askpass strategies. The client program is passed
+// the ID of the vault identities, see [vaultEnv].
+func askpassEnv(program, client string, passwords []vaultPassword) []string {
+	return []string{
+		fmt.Sprintf("SSH_ASKPASS=%s", program),
+		"SSH_ASKPASS_REQUIRE=force",
+		vaultEnv(program, client, passwords),
+	}
+}
+
+// vaultEnv points ansible to the program printing the vault password, or with vault identities to
+// the client program printing the password of the ID passed with --vault-id. Ansible recognizes the
+// client programs by their name ending with -client.
+func vaultEnv(program, client string, passwords []vaultPassword) string {
+	if len(passwords) == 1 && passwords[0].ID == "" {
+		return fmt.Sprintf("ANSIBLE_VAULT_PASSWORD_FILE=%s", program)
+	}
+	ids := make([]string, len(passwords))
+	for i, p := range passwords {
+		ids[i] = p.ID + "@" + client
+	}
+	return fmt.Sprintf("ANSIBLE_VAULT_IDENTITY_LIST=%s", strings.Join(ids, ","))
+}
+
+// passwordsEnv passes the passwords to the askpass program in its environment
+func passwordsEnv(passwords []vaultPassword) []string {
+	// Pass password via env var - the program echoes this, password never written to disk
+	env := []string{fmt.Sprintf("%s=%s", vaultPassEnv, passwords[0].Password)}
+	var ids []string
+	for _, p := range passwords {
+		if p.ID == "" {
+			continue
+		}
+		ids = append(ids, p.ID)
+		env = append(env, fmt.Sprintf("%s_%d=%s", vaultPassEnv, len(ids), p.Password))
+	}
+	if len(ids) > 0 {
+		env = append(env, fmt.Sprintf("%s=%s", vaultIDsEnv, strings.Join(ids, " ")))
+	}
+	return env
+}
+
+// passwordOf returns the password of a vault identity, the first one for an unknown ID
+func passwordOf(passwords []vaultPassword, id string) string {
+	for _, p := range passwords {
+		if p.ID == id {
+			return p.Password
+		}
+	}
+	return passwords[0].Password
+}
+
+// scriptAskpass is a /bin/sh script that reads password from env var
+// This avoids writing the actual password to disk - only a script that echoes an env var
+type scriptAskpass struct {
+	path string
+}
+
+// askpassScript prints the password of the vault identity passed with --vault-id, or the first one
+var askpassScript = `#!/bin/sh
+if [ "$1" = "--vault-id" ]; then
+	i=0
+	for id in $` + vaultIDsEnv + `; do
+		i=$((i + 1))
+		if [ "$id" = "$2" ]; then
+			eval "echo \"\$` + vaultPassEnv + `_$i\""
+			exit 0
+		fi
+	done
+fi
+echo "$` + vaultPassEnv + `"
+`
+
+func newScriptAskpass() (*scriptAskpass, error) {
+	// The name ending with -client makes ansible pass the vault ID
+	tmpFile, err := os.CreateTemp("", "askpass-*-client.sh")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create askpass script: %w", err)
+	}
+
+	// Script reads password from environment variable, not from file
+	// The actual password is passed via PLASMA_VAULT_PASS env var at runtime
+	if _, err := tmpFile.WriteString(askpassScript); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("failed to write askpass script: %w", err)
+	}
+	tmpFile.Close()
+
+	if err := os.Chmod(tmpFile.Name(), 0700); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("failed to chmod askpass script: %w", err)
+	}
+
+	return &scriptAskpass{path: tmpFile.Name()}, nil
+}
+
+// Env implements askpass interface
+func (s *scriptAskpass) Env(passwords []vaultPassword) []string {
+	return append(askpassEnv(s.path, s.path, passwords), passwordsEnv(passwords)...)
+}
+
+// Close implements askpass interface
+func (s *scriptAskpass) Close() error {
+	return os.Remove(s.path)
+}
+
+// helperAskpass re-executes the running plasmactl binary as the askpass program
+// It is used where shell scripts can't be executed directly, e.g. on Windows
+type helperAskpass struct {
+	path string
+	dir  string
+}
+
+func newHelperAskpass() (*helperAskpass, error) {
+	dir, err := os.MkdirTemp("", "askpass-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create askpass directory: %w", err)
+	}
+	path, err := linkAskpass(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &helperAskpass{path: path, dir: dir}, nil
+}
+
+// Env implements askpass interface
+func (h *helperAskpass) Env(passwords []vaultPassword) []string {
+	return append(askpassEnv(h.path, h.path, passwords), passwordsEnv(passwords)...)
+}
+
+// Close implements askpass interface
+func (h *helperAskpass) Close() error {
+	return os.RemoveAll(h.dir)
+}
+
+// linkAskpass links the running plasmactl binary into the directory as askpass program. Hard links
+// and copies replace the symlinks where they need privileges, e.g. on Windows.
+func linkAskpass(dir string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate plasmactl executable for askpass: %w", err)
+	}
+	path := filepath.Join(dir, askpassProgram+filepath.Ext(exe))
+	if err = os.Symlink(exe, path); err == nil {
+		return path, nil
+	}
+	if err = os.Link(exe, path); err == nil {
+		return path, nil
+	}
+	if err = copyExecutable(exe, path); err != nil {
+		return "", fmt.Errorf("failed to link askpass program: %w", err)
+	}
+	return path, nil
+}
+
+// copyExecutable copies the binary at src to dst
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0700)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// socketAskpass re-executes the running plasmactl binary as the askpass program, which reads the
+// password from a unix socket of a private temporary directory. The password is neither written to
+// disk nor set in the environment of ansible and ssh, where it could be read from /proc.
+type socketAskpass struct {
+	path   string // Link to the binary named as askpass program
+	dir    string
+	socket string
+	ln     net.Listener
+	done   chan struct{}
+
+	mu        sync.Mutex
+	passwords []vaultPassword
+}
+
+func newSocketAskpass() (*socketAskpass, error) {
+	dir, err := os.MkdirTemp("", "askpass-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create askpass directory: %w", err)
+	}
+	path, err := linkAskpass(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	socket := filepath.Join(dir, "askpass.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on askpass socket: %w", err)
+	}
+
+	s := &socketAskpass{path: path, dir: dir, socket: socket, ln: ln, done: make(chan struct{})}
+	go s.serve()
+	return s, nil
+}
+
+// serve answers each connection with the password of the vault ID it sent, until the socket is closed
+func (s *socketAskpass) serve() {
+	defer close(s.done)
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+		id, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil {
+			s.mu.Lock()
+			password := passwordOf(s.passwords, strings.TrimSuffix(id, "\n"))
+			s.mu.Unlock()
+			_, _ = io.WriteString(conn, password)
+		}
+		conn.Close()
+	}
+}
+
+// Env implements askpass interface
+func (s *socketAskpass) Env(passwords []vaultPassword) []string {
+	s.mu.Lock()
+	s.passwords = passwords
+	s.mu.Unlock()
+	return append(askpassEnv(s.path, s.path, passwords), fmt.Sprintf("%s=%s", askpassSocketEnv, s.socket))
+}
+
+// Close implements askpass interface
+func (s *socketAskpass) Close() error {
+	err := s.ln.Close()
+	<-s.done
+	if rmErr := os.RemoveAll(s.dir); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// IsAskpass reports whether the binary is run as askpass program by ansible or ssh, through the link
+// named after [askpassProgram]
+func IsAskpass() bool {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == askpassProgram
+}
+
+// RunAskpass prints the vault password for the askpass program, args are the ones ansible or ssh
+// passed to it. The password is read from the socket of the deployment, or its environment.
+func RunAskpass(out io.Writer, args []string) error {
+	// Ansible passes the vault ID to the vault client program
+	id := ""
+	if len(args) > 1 && args[0] == "--vault-id" {
+		id = args[1]
+	}
+	socket := os.Getenv(askpassSocketEnv)
+	if socket == "" {
+		_, err := fmt.Fprintln(out, envPassword(id))
+		return err
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("askpass: %w", err)
+	}
+	defer conn.Close()
+	if _, err = io.WriteString(conn, id+"\n"); err != nil {
+		return fmt.Errorf("askpass: %w", err)
+	}
+	password, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("askpass: %w", err)
+	}
+	_, err = fmt.Fprintln(out, string(password))
+	return err
+}
+
+// envPassword returns the password of a vault identity passed in the environment, the first one for
+// an unknown ID
+func envPassword(id string) string {
+	for i, v := range strings.Fields(os.Getenv(vaultIDsEnv)) {
+		if v == id {
+			return os.Getenv(fmt.Sprintf("%s_%d", vaultPassEnv, i+1))
+		}
+	}
+	return os.Getenv(vaultPassEnv)
+}
@@ -1,20 +1,56 @@
 package deploy
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/inventory"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/internal/secrets"
+	"github.com/plasmash/plasmactl-platform/internal/sigstore"
+	"github.com/plasmash/plasmactl-platform/pkg/event"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/metrics"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
+// playbookPath is the platform playbook relative to the working directory
+const playbookPath = "platform/platform.yaml"
+
+// Inventory sources of the deployment
+const (
+	InventorySourceCache = "cache" // Dynamic inventory of the metal provider, read from its cache
+	InventorySourceNodes = "nodes" // Static inventory generated from the node files
+)
+
+// nodesInventoryDir is the directory of the generated inventories relative to the working directory
+const nodesInventoryDir = "inventories/nodes"
+
+// playbookStopDelay is the time ansible-playbook has to exit after the deployment is cancelled
+const playbookStopDelay = 30 * time.Second
+
+// simulatedPassword is the vault password of the deployments in simulation, see the simulate package
+const simulatedPassword = "simulated"
+
 // Deploy implements the platform:deploy command
 type Deploy struct {
 	Log     *launchr.Logger
@@ -24,14 +60,31 @@ type Deploy struct {
 	Environment string
 	Tags        string
 	Img         string
+	BaseImg     string
 	Debug       bool
 	Check       bool
 	Password    string
 	Logs        bool
 	PrepareDir  string
+	SyntaxCheck bool
+	Lint        bool
+	Strict      bool
+	PlanOutput  string
+
+	InventorySource string
+	Limit           string
+	SkipPreflight   bool
+	PasswordMode    string
 
 	originalDir  string
+	platform     string // Directory of the platform of the environment in inst/, e.g. ski-dev for dev
 	extractedDir string
+	inventory    string
+	limit        string
+	imageVersion string
+	passwords    []vaultPassword
+	deployment   *history.Deployment
+	plan         *Plan // Changes of a check mode run
 }
 
 // SetLogger sets the logger for the action
@@ -45,16 +98,50 @@ func (d *Deploy) SetTerm(term *launchr.Terminal) {
 }
 
 // Execute runs the platform:deploy action
-func (d *Deploy) Execute() error {
+func (d *Deploy) Execute(ctx context.Context) error {
+	if err := validatePlanOutput(d.PlanOutput); err != nil {
+		return err
+	}
+	if d.jsonPlan() {
+		// Keep stdout for the plan
+		d.Term.SetOutput(os.Stderr)
+	}
 	var err error
 	d.originalDir, err = os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed to get current directory: %w", err)
 	}
+	d.platform, err = schema.FindPlatform(d.originalDir, d.Environment)
+	if err != nil {
+		return err
+	}
+	// The configuration of the platform must not change during the deployment
+	l, err := lock.Platform(ctx, d.platform)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	// Malformed node files would fail deep inside the playbook
+	if err := d.validateNodes(); err != nil {
+		return err
+	}
+	if err := d.validateRefs(); err != nil {
+		return err
+	}
+	if err := d.resolvePasswords(ctx); err != nil {
+		return err
+	}
+	for _, p := range d.passwords {
+		redact.Add(p.Password)
+	}
+	if err := d.resolveLimit(); err != nil {
+		return err
+	}
 
 	// Extract Platform Image if provided
 	if d.Img != "" {
-		if err := d.extractImage(); err != nil {
+		if err := d.extractImage(ctx); err != nil {
 			return err
 		}
 		defer d.cleanup()
@@ -74,11 +161,23 @@ func (d *Deploy) Execute() error {
 		return fmt.Errorf("failed to change to prepare directory %s: %w", workDir, err)
 	}
 	defer os.Chdir(d.originalDir)
+	if err := d.checkPlaybook(workDir); err != nil {
+		return err
+	}
 
-	// Check if hosts cache exists
-	if !d.cacheExists() {
-		d.Term.Warning().Println("Inventory cache does not exist, skipping deployment")
-		return nil
+	switch d.InventorySource {
+	case InventorySourceNodes:
+		if err := d.writeInventory(); err != nil {
+			return err
+		}
+	case "", InventorySourceCache:
+		// Check if hosts cache exists
+		if !d.cacheExists() {
+			d.Term.Warning().Println("Inventory cache does not exist, skipping deployment")
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown inventory source %q (use %s or %s)", d.InventorySource, InventorySourceCache, InventorySourceNodes)
 	}
 
 	d.Term.Info().Printfln("Deploying %s to %s...", d.Tags, d.Environment)
@@ -89,19 +188,38 @@ func (d *Deploy) Execute() error {
 	// Set up environment
 	env := d.buildEnvironment()
 
-	// Create askpass script for vault password
-	askpassScript, err := d.createAskpassScript()
+	// Create askpass program for vault password
+	ap, err := newAskpass(d.PasswordMode, d.passwords[0].ID != "", d.Log)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(askpassScript)
+	defer ap.Close()
+
+	// Fail fast on broken wiring and playbooks before the real run
+	if simulate.Enabled() {
+		d.Term.Info().Println("Skipping preflight checks in simulation")
+	} else if !d.SkipPreflight {
+		if err := d.runPreflight(ctx, env, ap); err != nil {
+			return err
+		}
+	}
+	if d.SyntaxCheck || d.Strict {
+		if err := d.runSyntaxCheck(ctx, env, ap); err != nil {
+			return err
+		}
+	}
+	if d.Lint {
+		if err := d.runLint(ctx, env); err != nil {
+			return err
+		}
+	}
 
 	// Run ansible-playbook
-	return d.runAnsiblePlaybook(args, env, askpassScript)
+	return d.runAnsiblePlaybook(ctx, args, env, ap)
 }
 
-// extractImage extracts a Platform Image (.pm) file
-func (d *Deploy) extractImage() error {
+// extractImage extracts a Platform Image (.pi) file
+func (d *Deploy) extractImage(ctx context.Context) error {
 	imgPath := d.Img
 	if !filepath.IsAbs(imgPath) {
 		imgPath = filepath.Join(d.originalDir, imgPath)
@@ -111,6 +229,21 @@ func (d *Deploy) extractImage() error {
 		return fmt.Errorf("platform image not found: %s", imgPath)
 	}
 
+	baseImg := d.BaseImg
+	if baseImg != "" && !filepath.IsAbs(baseImg) {
+		baseImg = filepath.Join(d.originalDir, baseImg)
+	}
+	// The bases of a delta image are applied with it, they are verified as well
+	layers, err := pi.Layers(imgPath, baseImg)
+	if err != nil {
+		return err
+	}
+
+	// Verify signature before extracting anything
+	if err := d.verifyImage(ctx, layers); err != nil {
+		return err
+	}
+
 	// Create extraction directory
 	d.extractedDir = ".deploy"
 	if err := os.RemoveAll(d.extractedDir); err != nil && !os.IsNotExist(err) {
@@ -121,59 +254,237 @@ func (d *Deploy) extractImage() error {
 	}
 
 	d.Term.Info().Printfln("Extracting Platform Image: %s", imgPath)
+	d.printManifest(imgPath)
+
+	// Extraction of large platforms takes minutes, report progress unless --quiet is set
+	var progress *pi.Progress
+	if launchr.EnvVarQuietMode.Get() != "1" {
+		progress = pi.NewProgress(d.Term)
+	}
+	if err := pi.ApplyLayers(ctx, layers, d.extractedDir, progress); err != nil {
+		return err
+	}
 
-	// Open the tar.gz file
-	file, err := os.Open(imgPath)
+	d.Term.Info().Printfln("Platform Image extracted to %s/", d.extractedDir)
+	return nil
+}
+
+// printManifest displays the Platform Image manifest, if the image embeds one
+func (d *Deploy) printManifest(imgPath string) {
+	m, err := pi.ReadManifest(imgPath)
 	if err != nil {
-		return fmt.Errorf("failed to open platform image: %w", err)
+		d.Log.Warn("Failed to read Platform Image manifest", "path", imgPath, "error", err)
+		return
+	}
+
+	d.imageVersion = m.Version
+	d.Term.Printfln("  Name:       %s", m.Name)
+	d.Term.Printfln("  Version:    %s", m.Version)
+	if m.Commit != "" {
+		d.Term.Printfln("  Commit:     %s", m.Commit)
+	}
+	d.Term.Printfln("  Built:      %s by %s", m.BuildTime.Format(time.RFC3339), m.Builder)
+	d.Term.Printfln("  Files:      %d", m.FileCount)
+	d.Term.Printfln("  Digest:     %s", m.Digest)
+	if m.Base != nil {
+		d.Term.Printfln("  Base:       %s (commit %s), %d changed files, %d deleted", m.Base.Version, m.Base.Commit, m.LayerFiles, len(m.Deleted))
 	}
-	defer file.Close()
+}
 
-	gzr, err := gzip.NewReader(file)
+// verifyImage checks the signatures of the layers of the Platform Image according to the platform
+// signature policy
+func (d *Deploy) verifyImage(ctx context.Context, layers []string) error {
+	policy, err := d.signaturePolicy()
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return err
+	}
+	if !policy.Enabled() {
+		d.Log.Debug("No signature policy configured, skipping verification", "environment", d.Environment)
+		return nil
+	}
+	for _, imgPath := range layers {
+		if err := d.verifyLayer(ctx, imgPath, policy); err != nil {
+			return err
+		}
 	}
-	defer gzr.Close()
+	return nil
+}
 
-	tr := tar.NewReader(gzr)
+// verifyLayer checks the signature of a layer of the Platform Image, a delta or a full image
+func (d *Deploy) verifyLayer(ctx context.Context, imgPath string, policy schema.SignaturePolicy) error {
+	bundle := sigstore.BundlePath(imgPath)
+	if _, err := os.Stat(bundle); os.IsNotExist(err) && !policy.Required {
+		d.Term.Warning().Printfln("Platform Image is not signed (%s not found), skipping verification", bundle)
+		return nil
+	}
 
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
+	d.Term.Info().Printfln("Verifying Platform Image signature: %s", bundle)
+	if err := sigstore.Verify(ctx, imgPath, bundle, policy, os.Stdout, os.Stderr); err != nil {
+		return err
+	}
+	d.Term.Success().Println("Platform Image signature verified")
+	return nil
+}
+
+// signaturePolicy reads the signature policy of the platform of the target environment
+func (d *Deploy) signaturePolicy() (schema.SignaturePolicy, error) {
+	platform, err := schema.LoadFile(filepath.Join(d.originalDir, schema.PlatformFile(d.platform)))
+	if err != nil {
+		return schema.SignaturePolicy{}, err
+	}
+	return platform.Image.Signature, nil
+}
+
+// validateNodes checks the node files of the target platform, if any. It runs in the original directory.
+func (d *Deploy) validateNodes() error {
+	platform, err := schema.Load(d.platform)
+	if err != nil {
+		return err
+	}
+	_, errs := schema.ValidateNodes(schema.NodesDir(d.platform), platform)
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		d.Term.Error().Printfln("✗ %v", err)
+	}
+	return fmt.Errorf("%d problem(s) in the node files of %s, see platform:validate %s", len(errs), d.platform, d.platform)
+}
+
+// vaultIDPattern matches the IDs of the vault identities, passed to the askpass program in its
+// environment
+var vaultIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// resolvePasswords fetches the Ansible vault passwords from the secrets backend of the platform: the
+// vault password unless passed with --password, or the password of each vault identity of
+// platform.yaml. A password missing in the backend is requested on an interactive terminal.
+func (d *Deploy) resolvePasswords(ctx context.Context) error {
+	platform, err := schema.Load(d.platform)
+	if err != nil {
+		return err
+	}
+	ids := platform.Secrets.VaultIDs
+	if d.Password != "" {
+		if len(ids) > 0 {
+			return fmt.Errorf("--password can't be used with the vault identities of %s, store their passwords with platform:secret:set", d.Environment)
+		}
+		d.passwords = []vaultPassword{{Password: d.Password}}
+		return nil
+	}
+	if err = checkVaultIDs(ids); err != nil {
+		return err
+	}
+	if simulate.Enabled() {
+		// The playbook doesn't run in simulation, no secret is read
+		d.passwords = []vaultPassword{{Password: simulatedPassword}}
+		if len(ids) > 0 {
+			d.passwords = nil
+			for _, id := range ids {
+				d.passwords = append(d.passwords, vaultPassword{ID: id.ID, Password: simulatedPassword})
+			}
+		}
+		return nil
+	}
+	b, err := secrets.New(platform, d.Keyring, d.Log, d.Term)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		password, err := d.vaultPassword(ctx, b, secrets.VaultPasswordKey, " or pass --password")
+		if err != nil {
+			return err
 		}
+		d.passwords = []vaultPassword{{Password: password}}
+		return nil
+	}
+	for _, id := range ids {
+		password, err := d.vaultPassword(ctx, b, secrets.VaultIDKey(id), "")
 		if err != nil {
-			return fmt.Errorf("failed to read tar: %w", err)
+			return err
 		}
+		d.passwords = append(d.passwords, vaultPassword{ID: id.ID, Password: password})
+	}
+	return nil
+}
 
-		target := filepath.Join(d.extractedDir, header.Name)
+// vaultPassword returns a vault password of the secrets backend, requested on an interactive
+// terminal if missing. The hint completes the error of a missing password.
+func (d *Deploy) vaultPassword(ctx context.Context, b secrets.Backend, key, hint string) (string, error) {
+	password, err := b.Get(ctx, key)
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return password, err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", failure.Newf(failure.ErrConfigMissing, "no vault password %s in the %s, store it with platform:secret:set %s %s%s", key, b.Name(), d.Environment, key, hint)
+	}
+	return secrets.Request(ctx, b, d.Term, key)
+}
 
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-				return fmt.Errorf("failed to create parent directory: %w", err)
-			}
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
-			if err != nil {
-				return fmt.Errorf("failed to create file: %w", err)
-			}
-			if _, err := io.Copy(f, tr); err != nil {
-				f.Close()
-				return fmt.Errorf("failed to write file: %w", err)
-			}
-			f.Close()
-		case tar.TypeSymlink:
-			if err := os.Symlink(header.Linkname, target); err != nil {
-				return fmt.Errorf("failed to create symlink: %w", err)
-			}
+// checkVaultIDs checks the IDs of the vault identities are valid and unique
+func checkVaultIDs(ids []schema.VaultID) error {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !vaultIDPattern.MatchString(id.ID) {
+			return fmt.Errorf("invalid vault identity %q in secrets.vault_ids, use letters, digits, _, . and -", id.ID)
+		}
+		if seen[id.ID] {
+			return fmt.Errorf("duplicate vault identity %q in secrets.vault_ids", id.ID)
 		}
+		seen[id.ID] = true
 	}
+	return nil
+}
 
-	d.Term.Info().Printfln("Platform Image extracted to %s/", d.extractedDir)
+// validateRefs checks the keyring items referenced by platform.yaml of the target platform exist, so
+// missing credentials fail before the playbook. It runs in the original directory.
+func (d *Deploy) validateRefs() error {
+	if simulate.Enabled() {
+		// The playbook doesn't run in simulation, it needs no credentials
+		return nil
+	}
+	refs, err := schema.Refs(d.platform)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, r := range refs {
+		if err := api.CheckRef(d.Keyring, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		d.Term.Error().Printfln("✗ %v", err)
+	}
+	return failure.Newf(failure.ErrConfigMissing, "%d missing credential(s) in platform.yaml of %s, add them with plasmactl keyring:set", len(errs), d.Environment)
+}
+
+// resolveLimit resolves the node groups of the --limit pattern into hostnames. It runs in the original directory.
+func (d *Deploy) resolveLimit() error {
+	if d.Limit == "" {
+		return nil
+	}
+	platform, err := schema.Load(d.platform)
+	if err != nil {
+		return err
+	}
+	nodes, err := schema.LoadNodes(d.platform)
+	if err != nil {
+		return err
+	}
+	d.limit, err = schema.ResolveLimit(platform, nodes, d.Limit)
+	if err != nil {
+		return err
+	}
+	if d.limit == "" {
+		return fmt.Errorf("--limit %q selects no host", d.Limit)
+	}
+	if d.limit != d.Limit {
+		d.Term.Info().Printfln("Limiting to %s", d.limit)
+	}
 	return nil
 }
 
@@ -215,13 +526,42 @@ func (d *Deploy) cacheExists() bool {
 	return true
 }
 
+// writeInventory generates the static inventory of the node files of the target platform into the
+// working directory
+func (d *Deploy) writeInventory() error {
+	nodesDir := filepath.Join(d.originalDir, schema.NodesDir(d.platform))
+	path := filepath.Join(nodesInventoryDir, d.Environment+".yaml")
+	count, err := inventory.Write(nodesDir, path)
+	if errors.Is(err, inventory.ErrNoNodes) {
+		return fmt.Errorf("%w, add them by hand or run platform:nodes:sync %s", err, d.Environment)
+	}
+	if err != nil {
+		return err
+	}
+	d.inventory = path
+	d.Term.Info().Printfln("Generated inventory of %d node(s) at %s", count, path)
+	return nil
+}
+
+// inventoryArgs returns the inventory argument of ansible-playbook, if the inventory is generated
+func (d *Deploy) inventoryArgs() []string {
+	if d.inventory == "" {
+		return nil
+	}
+	return []string{"-i", d.inventory}
+}
+
 // buildAnsibleArgs builds the ansible-playbook command arguments
 func (d *Deploy) buildAnsibleArgs() []string {
 	args := []string{
-		"platform/platform.yaml",
+		playbookPath,
 		"--tags", d.Tags,
 		"--extra-vars", fmt.Sprintf("machine_target_config=%s", d.Environment),
 	}
+	args = append(args, d.inventoryArgs()...)
+	if d.limit != "" {
+		args = append(args, "--limit", d.limit)
+	}
 
 	if d.Debug {
 		args = append(args, "-vvv")
@@ -234,6 +574,58 @@ func (d *Deploy) buildAnsibleArgs() []string {
 	return args
 }
 
+// runSyntaxCheck runs ansible-playbook --syntax-check against the platform playbook
+func (d *Deploy) runSyntaxCheck(ctx context.Context, env []string, ap askpass) error {
+	args := []string{
+		playbookPath,
+		"--syntax-check",
+		"--extra-vars", fmt.Sprintf("machine_target_config=%s", d.Environment),
+	}
+	args = append(args, d.inventoryArgs()...)
+
+	d.Term.Info().Printfln("Running: ansible-playbook %s", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "ansible-playbook", args...)
+	cmd.Env = append(env, ap.Env(d.passwords)...)
+	stdout, stderr := redact.Writer(os.Stdout), redact.Writer(os.Stderr)
+	defer stdout.Close()
+	defer stderr.Close()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := simulate.Run(cmd); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("ansible-playbook syntax check failed with exit code %d", exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run ansible-playbook syntax check: %w", err)
+	}
+
+	d.Term.Success().Println("Syntax check passed")
+	return nil
+}
+
+// runLint runs ansible-lint against the platform playbook
+func (d *Deploy) runLint(ctx context.Context, env []string) error {
+	if _, err := exec.LookPath("ansible-lint"); err != nil && !simulate.Enabled() {
+		return fmt.Errorf("ansible-lint is not installed: %w", err)
+	}
+
+	d.Term.Info().Printfln("Running: ansible-lint %s", playbookPath)
+	cmd := exec.CommandContext(ctx, "ansible-lint", playbookPath)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := simulate.Run(cmd); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("ansible-lint failed with exit code %d", exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run ansible-lint: %w", err)
+	}
+
+	d.Term.Success().Println("Lint passed")
+	return nil
+}
+
 // buildEnvironment builds the environment variables for ansible-playbook
 func (d *Deploy) buildEnvironment() []string {
 	env := os.Environ()
@@ -278,44 +670,25 @@ func (d *Deploy) buildEnvironment() []string {
 	return env
 }
 
-// createAskpassScript creates a script for SSH_ASKPASS that reads password from env var
-// This avoids writing the actual password to disk - only a script that echoes an env var
-func (d *Deploy) createAskpassScript() (string, error) {
-	tmpFile, err := os.CreateTemp("", "askpass-*.sh")
-	if err != nil {
-		return "", fmt.Errorf("failed to create askpass script: %w", err)
-	}
-
-	// Script reads password from environment variable, not from file
-	// The actual password is passed via PLASMA_VAULT_PASS env var at runtime
-	script := "#!/bin/sh\necho \"$PLASMA_VAULT_PASS\"\n"
-	if _, err := tmpFile.WriteString(script); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
-		return "", fmt.Errorf("failed to write askpass script: %w", err)
+// runAnsiblePlaybook executes ansible-playbook
+func (d *Deploy) runAnsiblePlaybook(ctx context.Context, args, env []string, ap askpass) error {
+	cmd := exec.CommandContext(ctx, "ansible-playbook", args...)
+	cmd.Env = append(env, ap.Env(d.passwords)...)
+	// Let ansible stop its running tasks and close its connections when the deployment is cancelled,
+	// it's killed if it doesn't exit in time
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
 	}
-	tmpFile.Close()
+	cmd.WaitDelay = playbookStopDelay
 
-	if err := os.Chmod(tmpFile.Name(), 0700); err != nil {
-		os.Remove(tmpFile.Name())
-		return "", fmt.Errorf("failed to chmod askpass script: %w", err)
+	// Keep stdout clean for the JSON plan summary and the JSON logs
+	var stdout io.Writer = os.Stdout
+	if d.jsonPlan() || output.JSONLogs() {
+		stdout = os.Stderr
 	}
 
-	return tmpFile.Name(), nil
-}
-
-// runAnsiblePlaybook executes ansible-playbook
-func (d *Deploy) runAnsiblePlaybook(args, env []string, askpassScript string) error {
-	cmd := exec.Command("ansible-playbook", args...)
-	cmd.Env = append(env,
-		fmt.Sprintf("SSH_ASKPASS=%s", askpassScript),
-		"SSH_ASKPASS_REQUIRE=force",
-		fmt.Sprintf("ANSIBLE_VAULT_PASSWORD_FILE=%s", askpassScript),
-		// Pass password via env var - the script echoes this, password never written to disk
-		fmt.Sprintf("PLASMA_VAULT_PASS=%s", d.Password),
-	)
-
 	// Set up output
+	var stderr io.Writer = os.Stderr
 	if d.Logs {
 		logFile, err := os.Create("deploy.log")
 		if err != nil {
@@ -324,24 +697,114 @@ func (d *Deploy) runAnsiblePlaybook(args, env []string, askpassScript string) er
 		defer logFile.Close()
 
 		// Tee output to both stdout/stderr and log file
-		cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
-		cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
-	} else {
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		stdout = io.MultiWriter(stdout, logFile)
+		stderr = io.MultiWriter(stderr, logFile)
 	}
+	// Ansible echoes the variables and the environment of the tasks in verbose mode, mask the secrets
+	maskedOut, maskedErr := redact.Writer(stdout), redact.Writer(stderr)
+	defer maskedOut.Close()
+	defer maskedErr.Close()
+	cmd.Stdout = maskedOut
+	cmd.Stderr = maskedErr
+
+	// Record changed tasks to summarize them in check mode, and the recap of the hosts of a failure
+	recorder := newPlanRecorder()
+	cmd.Stdout = io.MultiWriter(cmd.Stdout, recorder)
 
 	cmd.Stdin = os.Stdin
 
 	d.Term.Info().Printfln("Running: ansible-playbook %s", strings.Join(args, " "))
 
-	if err := cmd.Run(); err != nil {
+	if !d.Check {
+		event.Publish(event.Event{Type: event.DeployStarted, Platform: d.Environment, Data: d.eventData()})
+		d.Log.Info("deployment started", "tags", d.Tags, "image", d.Img, "version", d.imageVersion, "limit", d.limit)
+	}
+	start := time.Now()
+	if err := simulate.Run(cmd); err != nil {
+		if !d.Check {
+			d.recordDeployment(history.ResultFailed, start)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("ansible-playbook was interrupted: %w", ctx.Err())
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("ansible-playbook failed with exit code %d", exitErr.ExitCode())
+			return &failure.AnsibleError{ExitCode: exitErr.ExitCode(), Recap: recorder.Recap()}
 		}
 		return fmt.Errorf("failed to run ansible-playbook: %w", err)
 	}
 
+	if d.Check {
+		d.Term.Success().Println("Check completed successfully")
+		plan := recorder.Plan()
+		d.plan = &plan
+		return d.printPlan(plan)
+	}
+
 	d.Term.Success().Println("Deployment completed successfully")
+	d.recordDeployment(history.ResultSuccess, start)
 	return nil
 }
+
+// recordDeployment appends the deployment started at start to the journal of the platform, the
+// repository keeps working if it can't
+func (d *Deploy) recordDeployment(result string, start time.Time) {
+	deployment := history.Deployment{
+		Environment: d.Environment,
+		Tags:        d.Tags,
+		Image:       d.Img,
+		Version:     d.imageVersion,
+		Time:        time.Now().UTC(),
+		Result:      result,
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+	d.deployment = &deployment
+	if simulate.Enabled() {
+		// The history holds the deployments which changed the nodes
+		d.Log.Debug("not recording the simulated deployment")
+	} else if err := history.Record(d.originalDir, deployment); err != nil {
+		d.Log.Warn("Failed to record the deployment", "error", err)
+	}
+	d.Log.Info("deployment finished", "tags", d.Tags, "result", result, "duration_ms", deployment.DurationMs)
+	metrics.Record(d.Environment, metrics.Metric{
+		Name:   metrics.DeployDuration,
+		Labels: map[string]string{"result": result, "tags": d.Tags},
+		Value:  time.Since(start).Seconds(),
+	})
+	data := d.eventData()
+	data["result"] = result
+	data["duration_ms"] = deployment.DurationMs
+	event.Publish(event.Event{Type: event.DeployFinished, Platform: d.Environment, Data: data})
+}
+
+// eventData returns the details of the deployment events
+func (d *Deploy) eventData() map[string]any {
+	data := map[string]any{"tags": d.Tags}
+	if d.Img != "" {
+		data["image"] = d.Img
+		data["version"] = d.imageVersion
+	}
+	if d.limit != "" {
+		data["limit"] = d.limit
+	}
+	return data
+}
+
+// Deployment returns the deployment run by Execute, nil if the playbook didn't run or ran in check mode
+func (d *Deploy) Deployment() *history.Deployment {
+	return d.deployment
+}
+
+// Result returns the result of a successful deployment or check mode run for the callers of the
+// action, nil otherwise
+func (d *Deploy) Result() *result.DeployResult {
+	r := &result.DeployResult{Platform: d.Environment, Tags: d.Tags, Image: d.Img, Version: d.imageVersion}
+	switch {
+	case d.plan != nil:
+		r.Check, r.Changed, r.Hosts = true, d.plan.Changed, d.plan.Hosts
+	case d.deployment != nil && d.deployment.Result == history.ResultSuccess:
+		r.Duration = time.Duration(d.deployment.DurationMs) * time.Millisecond
+	default:
+		return nil
+	}
+	return r
+}
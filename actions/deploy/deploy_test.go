@@ -0,0 +1,120 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// writePlatform writes the platform.yaml of inst/<dir> in the current directory
+func writePlatform(t *testing.T, dir, data string) {
+	t.Helper()
+	path := schema.PlatformFile(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeNode writes a node file of inst/<dir> in the current directory
+func writeNode(t *testing.T, dir, hostname, data string) {
+	t.Helper()
+	path := schema.NodeFile(dir, hostname)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newDeploy returns the deployment of an environment from the platform directory resolved for it
+func newDeploy(t *testing.T, environment string) *Deploy {
+	t.Helper()
+	d := &Deploy{Environment: environment, Log: launchr.Log(), Term: launchr.Term()}
+	var err error
+	if d.platform, err = schema.FindPlatform("", environment); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestResolvePasswords(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writePlatform(t, "ski-dev", "name: dev\nsecrets:\n  vault_ids:\n    - id: prod\n")
+
+	// The vault identities of inst/ski-dev apply to the dev environment
+	d := newDeploy(t, "dev")
+	d.Password = "secret"
+	err := d.resolvePasswords(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "vault identities") {
+		t.Errorf("resolvePasswords() error = %v, want a refusal of --password with vault identities", err)
+	}
+
+	// A deployment to an environment without platform fails
+	err = (&Deploy{Environment: "prod", Term: launchr.Term()}).Execute(context.Background())
+	if !errors.Is(err, schema.ErrNotFound) {
+		t.Errorf("Execute() error = %v, want %v", err, schema.ErrNotFound)
+	}
+}
+
+func TestValidateNodes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writePlatform(t, "ski-dev", "name: dev\n")
+	writeNode(t, "ski-dev", "node1", "hostname: node1\n")
+
+	// The node files of inst/ski-dev are validated for the dev environment
+	err := newDeploy(t, "dev").validateNodes()
+	if err == nil || !strings.Contains(err.Error(), "platform:validate ski-dev") {
+		t.Errorf("validateNodes() error = %v, want the node without address of ski-dev", err)
+	}
+}
+
+func TestResolveLimit(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writePlatform(t, "ski-dev", "name: dev\ngroups:\n  workers:\n    roles: [worker]\n")
+	writeNode(t, "ski-dev", "node1", "hostname: node1\npublic_ips: [192.0.2.1]\nroles: [worker]\n")
+	writeNode(t, "ski-dev", "node2", "hostname: node2\npublic_ips: [192.0.2.2]\n")
+
+	// The groups and nodes of inst/ski-dev resolve the limit of the dev environment
+	d := newDeploy(t, "dev")
+	d.Limit = "@workers"
+	if err := d.resolveLimit(); err != nil {
+		t.Fatal(err)
+	}
+	if d.limit != "node1" {
+		t.Errorf("limit = %q, want node1", d.limit)
+	}
+
+	d.originalDir = dir
+	if err := d.writeInventory(); err != nil {
+		t.Fatalf("writeInventory() error = %v, want the inventory of the nodes of ski-dev", err)
+	}
+}
+
+func TestExecuteLocksPlatform(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(lock.EnvVarTimeout.String(), "0s")
+	writePlatform(t, "ski-dev", "name: dev\n")
+	// A lock file of another process
+	if err := os.WriteFile(filepath.Join(schema.PlatformDir("ski-dev"), lock.FileName), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deployment of dev waits for the lock of inst/ski-dev
+	err := (&Deploy{Environment: "dev", Term: launchr.Term()}).Execute(context.Background())
+	if !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("Execute() error = %v, want %v", err, lock.ErrLocked)
+	}
+}
@@ -0,0 +1,220 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+// Plan summarizes the tasks that would change during a check mode run
+type Plan struct {
+	Changed int        `json:"changed"`
+	Hosts   []string   `json:"hosts"`
+	Roles   []PlanRole `json:"roles"`
+}
+
+// PlanRole groups changed tasks by Ansible role
+type PlanRole struct {
+	Name  string     `json:"name"`
+	Tasks []PlanTask `json:"tasks"`
+}
+
+// PlanTask is a task reported as changed on one or more hosts
+type PlanTask struct {
+	Name  string   `json:"name"`
+	Hosts []string `json:"hosts"`
+}
+
+// Formats of the plan summary of a check mode run, the human-readable text is the default
+const (
+	PlanOutputText = "text"
+	PlanOutputJSON = "json"
+)
+
+// playbookRole is used for tasks defined directly in the playbook
+const playbookRole = "(playbook)"
+
+// recapLine matches the line of a host in the PLAY RECAP of ansible-playbook
+var recapLine = regexp.MustCompile(`^(\S+)\s+:\s+ok=(\d+)\s+changed=(\d+)\s+unreachable=(\d+)\s+failed=(\d+)(?:\s+skipped=(\d+))?(?:\s+rescued=(\d+))?(?:\s+ignored=(\d+))?`)
+
+// planRecorder parses ansible-playbook output and records changed tasks and the play recap
+type planRecorder struct {
+	mx   sync.Mutex
+	buf  bytes.Buffer
+	role string
+	task string
+
+	inRecap bool
+	recap   []failure.HostRecap
+
+	// changes maps role -> task -> set of hosts, order keeps first seen tasks
+	changes map[string]map[string]map[string]struct{}
+	order   []string
+}
+
+func newPlanRecorder() *planRecorder {
+	return &planRecorder{changes: make(map[string]map[string]map[string]struct{})}
+}
+
+// Write implements io.Writer interface
+func (r *planRecorder) Write(p []byte) (int, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.buf.Write(p)
+	for {
+		line, err := r.buf.ReadString('\n')
+		if err != nil {
+			// Keep incomplete line for the next write
+			r.buf.Reset()
+			r.buf.WriteString(line)
+			break
+		}
+		r.parseLine(strings.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+// parseLine handles a single line of the default ansible stdout callback
+func (r *planRecorder) parseLine(line string) {
+	switch {
+	case strings.HasPrefix(line, "PLAY RECAP"):
+		r.inRecap = true
+	case r.inRecap:
+		if m := recapLine.FindStringSubmatch(line); m != nil {
+			n := func(i int) int {
+				v, _ := strconv.Atoi(m[i])
+				return v
+			}
+			r.recap = append(r.recap, failure.HostRecap{
+				Host: m[1], OK: n(2), Changed: n(3), Unreachable: n(4), Failed: n(5),
+				Skipped: n(6), Rescued: n(7), Ignored: n(8),
+			})
+		}
+	case strings.HasPrefix(line, "TASK ["), strings.HasPrefix(line, "RUNNING HANDLER ["):
+		start := strings.Index(line, "[")
+		end := strings.LastIndex(line, "]")
+		if end <= start {
+			return
+		}
+		name := line[start+1 : end]
+		r.role = playbookRole
+		r.task = name
+		if parts := strings.SplitN(name, " : ", 2); len(parts) == 2 {
+			r.role = parts[0]
+			r.task = parts[1]
+		}
+	case strings.HasPrefix(line, "changed: ["):
+		end := strings.Index(line, "]")
+		if end < 0 || r.task == "" {
+			return
+		}
+		// Strip delegation suffix, e.g. "host -> delegate"
+		host := strings.SplitN(line[len("changed: ["):end], " -> ", 2)[0]
+		r.record(host)
+	}
+}
+
+func (r *planRecorder) record(host string) {
+	tasks, ok := r.changes[r.role]
+	if !ok {
+		tasks = make(map[string]map[string]struct{})
+		r.changes[r.role] = tasks
+	}
+	hosts, ok := tasks[r.task]
+	if !ok {
+		hosts = make(map[string]struct{})
+		tasks[r.task] = hosts
+		r.order = append(r.order, r.role+"\x00"+r.task)
+	}
+	hosts[host] = struct{}{}
+}
+
+// Recap returns the hosts of the play recaps
+func (r *planRecorder) Recap() []failure.HostRecap {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return slices.Clone(r.recap)
+}
+
+// Plan builds the summary of recorded changes
+func (r *planRecorder) Plan() Plan {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	plan := Plan{Hosts: []string{}, Roles: []PlanRole{}}
+	allHosts := make(map[string]struct{})
+	roleIdx := make(map[string]int)
+	for _, key := range r.order {
+		parts := strings.SplitN(key, "\x00", 2)
+		role, task := parts[0], parts[1]
+		idx, ok := roleIdx[role]
+		if !ok {
+			idx = len(plan.Roles)
+			roleIdx[role] = idx
+			plan.Roles = append(plan.Roles, PlanRole{Name: role})
+		}
+		hosts := make([]string, 0, len(r.changes[role][task]))
+		for h := range r.changes[role][task] {
+			hosts = append(hosts, h)
+			allHosts[h] = struct{}{}
+		}
+		sort.Strings(hosts)
+		plan.Roles[idx].Tasks = append(plan.Roles[idx].Tasks, PlanTask{Name: task, Hosts: hosts})
+		plan.Changed++
+	}
+	for h := range allHosts {
+		plan.Hosts = append(plan.Hosts, h)
+	}
+	sort.Strings(plan.Hosts)
+	return plan
+}
+
+// validatePlanOutput checks the format of the plan summary
+func validatePlanOutput(format string) error {
+	switch strings.ToLower(format) {
+	case "", PlanOutputText, PlanOutputJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown plan output %q (use %s or %s)", format, PlanOutputText, PlanOutputJSON)
+}
+
+// jsonPlan reports whether the plan summary of a check mode run is printed as JSON, stdout then only
+// holds the plan
+func (d *Deploy) jsonPlan() bool {
+	return d.Check && strings.EqualFold(d.PlanOutput, PlanOutputJSON)
+}
+
+// printPlan outputs the check mode plan summary in the requested format
+func (d *Deploy) printPlan(plan Plan) error {
+	if d.jsonPlan() {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+
+	d.Term.Println()
+	if plan.Changed == 0 {
+		d.Term.Success().Println("Plan: no tasks would change")
+		return nil
+	}
+	d.Term.Info().Printfln("Plan: %d tasks would change on %d hosts, grouped by role", plan.Changed, len(plan.Hosts))
+	for _, role := range plan.Roles {
+		d.Term.Printfln("  %s (%d tasks)", role.Name, len(role.Tasks))
+		for _, task := range role.Tasks {
+			d.Term.Printfln("    ~ %s [%s]", task.Name, strings.Join(task.Hosts, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package deploy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+func TestPlanOutput(t *testing.T) {
+	tests := []struct {
+		format   string
+		check    bool
+		wantErr  bool
+		wantJSON bool
+	}{
+		{format: "", check: true},
+		{format: PlanOutputText, check: true},
+		{format: PlanOutputJSON, check: true, wantJSON: true},
+		{format: "JSON", check: true, wantJSON: true},
+		{format: PlanOutputJSON},
+		{format: "yaml", check: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			d := &Deploy{Check: tt.check, PlanOutput: tt.format}
+			if err := validatePlanOutput(tt.format); (err != nil) != tt.wantErr {
+				t.Errorf("validatePlanOutput(%q) error = %v, wantErr %t", tt.format, err, tt.wantErr)
+			}
+			if got := d.jsonPlan(); got != tt.wantJSON {
+				t.Errorf("jsonPlan() = %t, want %t", got, tt.wantJSON)
+			}
+		})
+	}
+
+	// An unknown format fails before the deployment starts
+	err := (&Deploy{Environment: "dev", Check: true, PlanOutput: "yaml"}).Execute(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unknown plan output") {
+		t.Errorf("Execute() error = %v, want an unknown plan output", err)
+	}
+}
+
+// checkOutput is the output of an ansible-playbook --check run
+const checkOutput = `
+PLAY [platform] ****************************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [node1]
+ok: [node2]
+
+TASK [Set the facts of the playbook] *******************************************
+changed: [node1]
+
+TASK [users : Create the users] ************************************************
+changed: [node1]
+changed: [node2]
+ok: [node3]
+
+TASK [nginx : Write the site] **************************************************
+changed: [node2 -> proxy1]
+changed: [node1 -> proxy1]
+
+TASK [users : Write the keys] **************************************************
+changed: [node2]
+
+RUNNING HANDLER [nginx : Reload nginx] *****************************************
+changed: [node1]
+
+PLAY RECAP *********************************************************************
+node1                      : ok=5    changed=4    unreachable=0    failed=0    skipped=1    rescued=0    ignored=0
+node2                      : ok=4    changed=3    unreachable=0    failed=1    skipped=0    rescued=1    ignored=2
+node3                      : ok=1    changed=0    unreachable=1    failed=0
+`
+
+func TestPlanRecorder(t *testing.T) {
+	wantPlan := Plan{
+		Changed: 5,
+		Hosts:   []string{"node1", "node2"},
+		Roles: []PlanRole{
+			{Name: playbookRole, Tasks: []PlanTask{{Name: "Set the facts of the playbook", Hosts: []string{"node1"}}}},
+			{Name: "users", Tasks: []PlanTask{
+				{Name: "Create the users", Hosts: []string{"node1", "node2"}},
+				{Name: "Write the keys", Hosts: []string{"node2"}},
+			}},
+			{Name: "nginx", Tasks: []PlanTask{
+				{Name: "Write the site", Hosts: []string{"node1", "node2"}},
+				{Name: "Reload nginx", Hosts: []string{"node1"}},
+			}},
+		},
+	}
+	wantRecap := []failure.HostRecap{
+		{Host: "node1", OK: 5, Changed: 4, Skipped: 1},
+		{Host: "node2", OK: 4, Changed: 3, Failed: 1, Rescued: 1, Ignored: 2},
+		{Host: "node3", OK: 1, Unreachable: 1},
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		chunk  int // Size of the writes, the whole output at once if 0
+		plan   Plan
+		recap  []failure.HostRecap
+	}{
+		{name: "whole output", output: checkOutput, plan: wantPlan, recap: wantRecap},
+		{name: "lines split across writes", output: checkOutput, chunk: 7, plan: wantPlan, recap: wantRecap},
+		{name: "CRLF line endings", output: strings.ReplaceAll(checkOutput, "\n", "\r\n"), chunk: 64, plan: wantPlan, recap: wantRecap},
+		{
+			name:   "no change",
+			output: "TASK [users : Create the users] ****\nok: [node1]\n",
+			plan:   Plan{Hosts: []string{}, Roles: []PlanRole{}},
+		},
+		{
+			name:   "change before any task",
+			output: "changed: [node1]\nPLAY RECAP ****\nnot a recap line\n",
+			plan:   Plan{Hosts: []string{}, Roles: []PlanRole{}},
+		},
+		{
+			name:   "incomplete last line",
+			output: "TASK [users : Create the users] ****\nchanged: [node1]\nchanged: [node2]",
+			plan: Plan{Changed: 1, Hosts: []string{"node1"}, Roles: []PlanRole{
+				{Name: "users", Tasks: []PlanTask{{Name: "Create the users", Hosts: []string{"node1"}}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newPlanRecorder()
+			data := []byte(tt.output)
+			chunk := tt.chunk
+			if chunk == 0 {
+				chunk = len(data)
+			}
+			for len(data) > 0 {
+				n := min(chunk, len(data))
+				if w, err := r.Write(data[:n]); err != nil || w != n {
+					t.Fatalf("Write() = %d, %v, want %d", w, err, n)
+				}
+				data = data[n:]
+			}
+			if got := r.Plan(); !reflect.DeepEqual(got, tt.plan) {
+				t.Errorf("Plan() = %+v, want %+v", got, tt.plan)
+			}
+			if got := r.Recap(); !reflect.DeepEqual(got, tt.recap) {
+				t.Errorf("Recap() = %+v, want %+v", got, tt.recap)
+			}
+		})
+	}
+}
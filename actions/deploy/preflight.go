@@ -0,0 +1,250 @@
+package deploy
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+)
+
+// tool is an executable of the deployment
+type tool struct {
+	names   []string       // Executable names, the first found is used, e.g. python3 then python
+	args    []string       // Arguments printing its version
+	version *regexp.Regexp // Matches the version in the output of args
+	min     string         // Minimum version
+	hint    string         // Installation hint
+}
+
+// Executables of the deployment
+var (
+	ansibleTool = tool{
+		names:   []string{"ansible-playbook"},
+		args:    []string{"--version"},
+		version: regexp.MustCompile(`ansible-playbook (?:\[core )?(\d+\.\d+(?:\.\d+)?)`),
+		min:     "2.12",
+		hint:    "install ansible-core with pipx install ansible-core, or pip install --user ansible-core",
+	}
+	ansibleInventoryTool = tool{
+		names: []string{"ansible-inventory"},
+		hint:  "install ansible-core with pipx install ansible-core, or pip install --user ansible-core",
+	}
+	pythonTool = tool{
+		names:   []string{"python3", "python"},
+		args:    []string{"--version"},
+		version: regexp.MustCompile(`Python (\d+\.\d+(?:\.\d+)?)`),
+		min:     "3.8",
+		hint:    "install Python 3 with your package manager, e.g. apt install python3 or brew install python",
+	}
+	terraformTool = tool{
+		names:   []string{"terraform"},
+		args:    []string{"version"},
+		version: regexp.MustCompile(`Terraform v(\d+\.\d+(?:\.\d+)?)`),
+		min:     "1.0",
+		hint:    "install Terraform, see https://developer.hashicorp.com/terraform/install",
+	}
+)
+
+// specialTags are the tags of ansible-playbook selecting tasks without being declared in the playbook
+var specialTags = []string{"all", "tagged", "untagged", "always", "never"}
+
+// tagsList matches the tag lists of the output of ansible-playbook --list-tags, e.g. TASK TAGS: [a, b]
+var tagsList = regexp.MustCompile(`TAGS: \[([^\]]*)\]`)
+
+// checkPlaybook checks the platform playbook exists in the working directory
+func (d *Deploy) checkPlaybook(workDir string) error {
+	_, err := os.Stat(playbookPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("playbook %s not found in %s, run platform:prepare or check --prepare-dir", playbookPath, workDir)
+	}
+	return err
+}
+
+// runPreflight checks the deployment can run before the playbook does: the executables are installed
+// in compatible versions, the requested tags are declared in the playbook, and ansible-inventory
+// parses the inventory into hosts
+func (d *Deploy) runPreflight(ctx context.Context, env []string, ap askpass) error {
+	d.Term.Info().Println("Running preflight checks")
+
+	tools := []tool{ansibleTool, ansibleInventoryTool, pythonTool}
+	if hasTerraform() {
+		tools = append(tools, terraformTool)
+	}
+	var errs []error
+	for _, t := range tools {
+		name, version, err := t.check(ctx)
+		if err != nil {
+			d.Term.Error().Printfln("✗ %v", err)
+			d.Term.Printfln("    %s", t.hint)
+			errs = append(errs, err)
+			continue
+		}
+		if version != "" {
+			d.Term.Success().Printfln("✓ %s %s", name, version)
+		} else {
+			d.Term.Success().Printfln("✓ %s", name)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d executable(s) missing or too old, use --skip-preflight to deploy anyway", len(errs))
+	}
+
+	tags, err := d.playbookTags(ctx, env, ap)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, tag := range strings.Split(d.Tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(specialTags, tag) && !slices.Contains(tags, tag) {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("tag(s) %s not in %s, which has %s", strings.Join(missing, ", "), playbookPath, strings.Join(tags, ", "))
+	}
+	d.Term.Success().Printfln("✓ Tags %s in %s", d.Tags, playbookPath)
+
+	hosts, err := d.inventoryHosts(ctx, env, ap)
+	if err != nil {
+		return err
+	}
+	if hosts == 0 {
+		return fmt.Errorf("inventory of %s has no host", d.Environment)
+	}
+	d.Term.Success().Printfln("✓ Inventory parsed with %d host(s)", hosts)
+	return nil
+}
+
+// check returns the executable of the tool found and its version, an error if none is found or its
+// version is below the minimum
+func (t tool) check(ctx context.Context) (string, string, error) {
+	name := ""
+	for _, n := range t.names {
+		if _, err := exec.LookPath(n); err == nil {
+			name = n
+			break
+		}
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("%s is not installed", t.names[0])
+	}
+	if t.version == nil {
+		return name, "", nil
+	}
+
+	output, err := exec.CommandContext(ctx, name, t.args...).CombinedOutput()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get the version of %s: %w", name, err)
+	}
+	m := t.version.FindStringSubmatch(string(output))
+	if m == nil {
+		return name, "", nil
+	}
+	if compareVersions(m[1], t.min) < 0 {
+		return "", "", fmt.Errorf("%s %s is older than %s", name, m[1], t.min)
+	}
+	return name, m[1], nil
+}
+
+// compareVersions compares dotted versions numerically, missing parts being zero
+func compareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(pa), len(pb)) {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	return 0
+}
+
+// hasTerraform reports whether the working directory has Terraform files, at its root or in a
+// terraform directory
+func hasTerraform() bool {
+	for _, pattern := range []string{"*.tf", "terraform/*.tf"} {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// playbookTags returns the sorted tags declared in the playbook, listed by ansible-playbook --list-tags
+func (d *Deploy) playbookTags(ctx context.Context, env []string, ap askpass) ([]string, error) {
+	args := []string{
+		playbookPath,
+		"--list-tags",
+		"--extra-vars", fmt.Sprintf("machine_target_config=%s", d.Environment),
+	}
+	args = append(args, d.inventoryArgs()...)
+	output, err := d.runQuiet(ctx, "ansible-playbook", args, append(env, ap.Env(d.passwords)...))
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, m := range tagsList.FindAllStringSubmatch(output, -1) {
+		for _, tag := range strings.Split(m[1], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+// inventoryHosts returns the number of hosts of the inventory parsed by ansible-inventory
+func (d *Deploy) inventoryHosts(ctx context.Context, env []string, ap askpass) (int, error) {
+	args := append([]string{"--list"}, d.inventoryArgs()...)
+	output, err := d.runQuiet(ctx, "ansible-inventory", args, append(env, ap.Env(d.passwords)...))
+	if err != nil {
+		return 0, err
+	}
+	var inventory struct {
+		Meta struct {
+			HostVars map[string]json.RawMessage `json:"hostvars"`
+		} `json:"_meta"`
+	}
+	if err = json.Unmarshal([]byte(output), &inventory); err != nil {
+		return 0, fmt.Errorf("failed to parse the output of ansible-inventory: %w", err)
+	}
+	return len(inventory.Meta.HostVars), nil
+}
+
+// runQuiet runs an Ansible command and returns its output, printing its errors only if it fails
+func (d *Deploy) runQuiet(ctx context.Context, name string, args, env []string) (string, error) {
+	d.Log.Debug("running preflight command", "cmd", name, "args", strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Stderr.WriteString(redact.String(stderr.String()))
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("%s failed with exit code %d", name, exitErr.ExitCode())
+		}
+		return "", fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
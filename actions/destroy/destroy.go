@@ -2,6 +2,8 @@ package destroy
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +11,14 @@ import (
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	"github.com/plasmash/plasmactl-platform/internal/terraform"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/event"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
 )
 
 // Destroy implements the platform:destroy command
@@ -33,12 +43,12 @@ func (d *Destroy) SetTerm(term *launchr.Terminal) {
 }
 
 // Execute runs the platform:destroy action
-func (d *Destroy) Execute() error {
-	instDir := filepath.Join("inst", d.Name)
+func (d *Destroy) Execute(ctx context.Context) error {
+	instDir := schema.PlatformDir(d.Name)
 
 	// Check if platform exists
 	if _, err := os.Stat(instDir); os.IsNotExist(err) {
-		return fmt.Errorf("platform %q not found", d.Name)
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", d.Name)
 	}
 
 	// Confirm destruction
@@ -52,35 +62,124 @@ func (d *Destroy) Execute() error {
 		}
 	}
 
+	l, err := lock.Platform(ctx, d.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
 	d.Term.Info().Printfln("Destroying platform %q...", d.Name)
 
-	// TODO: Destroy DNS records if not --keep-dns
+	// Destroy DNS records if not --keep-dns, the platform directory is kept on failure to retry
 	if !d.KeepDNS {
-		d.Term.Info().Println("  Removing DNS records...")
-		// DNS removal via Terraform would go here
-		d.Term.Warning().Println("  DNS removal not yet implemented")
+		if err := d.removeDNS(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Destroy the nodes with the Terraform workspace, the platform directory is kept on failure to retry
+	if err := d.destroyNodes(ctx, instDir); err != nil {
+		return err
+	}
+
+	// Remove the environment directory
+	d.Term.Info().Println("  Removing platform directory...")
+	if err := os.RemoveAll(instDir); err != nil {
+		return fmt.Errorf("failed to remove platform directory: %w", err)
+	}
+
+	d.Term.Success().Printfln("Platform %q destroyed", d.Name)
+	event.Publish(event.Event{Type: event.DestroyCompleted, Platform: d.Name, Data: map[string]any{"keep_dns": d.KeepDNS}})
+	return nil
+}
+
+// destroyNodes destroys the resources of the Terraform workspace of the platform, the nodes of
+// platforms without workspace are listed to be removed with their metal provider
+func (d *Destroy) destroyNodes(ctx context.Context, instDir string) error {
+	ws := terraform.Open(d.Name, d.Log)
+	if ws.Exists() {
+		d.Term.Info().Printfln("  Destroying the resources of %s...", ws.Dir)
+		if err := ws.Destroy(ctx, terraform.Options{}); err != nil {
+			return fmt.Errorf("failed to destroy the nodes: %w, retry or destroy them with terraform in %s", err, ws.Dir)
+		}
+		return nil
 	}
 
-	// TODO: Destroy nodes via Terraform
-	// This should invoke node:destroy for each node
 	nodesDir := filepath.Join(instDir, "nodes")
 	if nodeEntries, err := os.ReadDir(nodesDir); err == nil {
 		for _, nodeEntry := range nodeEntries {
 			if !nodeEntry.IsDir() && filepath.Ext(nodeEntry.Name()) == ".yaml" && nodeEntry.Name() != ".gitkeep" {
 				nodeName := nodeEntry.Name()[:len(nodeEntry.Name())-5]
-				d.Term.Info().Printfln("  Would destroy node: %s", nodeName)
-				// node destruction via Terraform would go here
+				d.Term.Warning().Printfln("  Node %s has no Terraform workspace, remove it with the metal provider", nodeName)
 			}
 		}
 	}
+	return nil
+}
 
-	// Remove the environment directory
-	d.Term.Info().Println("  Removing platform directory...")
-	if err := os.RemoveAll(instDir); err != nil {
-		return fmt.Errorf("failed to remove platform directory: %w", err)
+// removeDNS restores the default reverse DNS of the platform addresses and deletes the DNS records of
+// platform.yaml, unless they are managed by hand
+func (d *Destroy) removeDNS(ctx context.Context) error {
+	platform, err := schema.Load(d.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = d.resetReverse(ctx, platform); err != nil {
+		return fmt.Errorf("failed to reset reverse DNS: %w, retry or pass --keep-dns to keep it", err)
+	}
+	if platform.DNS.Provider == "" || platform.DNS.Provider == dnsprovider.ProviderManual {
+		d.Term.Info().Println("  DNS records are managed manually, remove them with your DNS provider")
+		return nil
 	}
 
-	d.Term.Success().Printfln("Platform %q destroyed", d.Name)
+	d.Term.Info().Println("  Removing DNS records...")
+	m := &dns.Manager{Keyring: d.Keyring, Platform: d.Name}
+	m.SetLogger(d.Log)
+	m.SetTerm(d.Term)
+	if _, err = m.Delete(ctx, platform); err != nil {
+		return fmt.Errorf("%w, retry or pass --keep-dns to keep them", err)
+	}
+	return nil
+}
+
+// resetReverse restores the default reverse DNS of the platform addresses at the metal provider, so
+// they don't keep pointing to the domain of the destroyed platform. PTR records changed since are kept.
+func (d *Destroy) resetReverse(ctx context.Context, platform *schema.Platform) error {
+	records, err := dnsprovider.PTRRecords(platform.DNS)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	mp, err := provider.New(platform.Infrastructure, provider.Options{Keyring: d.Keyring, Log: d.Log, Term: d.Term, Platform: d.Name})
+	if errors.Is(err, provider.ErrUnsupported) {
+		d.Log.Debug("metal provider can't reset reverse DNS", "provider", platform.Infrastructure.MetalProvider)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	servers, err := mp.ListServers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		server, ok := provider.FindServer(servers, r.Name)
+		if !ok {
+			continue
+		}
+		for _, a := range server.Addresses {
+			if a.IP != r.Name || !r.SameContent(dnsprovider.Record{Type: "PTR", Content: a.Reverse}) {
+				continue
+			}
+			d.Term.Info().Printfln("  Resetting reverse DNS of %s (%s)...", r.Name, r.Content)
+			if err = mp.SetPTR(ctx, r.Name, ""); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
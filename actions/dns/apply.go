@@ -0,0 +1,77 @@
+// Package dns implements actions managing the DNS records of platforms
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Apply implements the platform:dns:apply command
+type Apply struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring keyring.Keyring
+	Name    string
+	DryRun  bool
+
+	WaitPropagation    bool
+	PropagationTimeout string
+
+	Resolver string // DNS server of the checks as ip:port, the system resolver by default
+	DoH      string // DNS-over-HTTPS endpoint of the checks, URL or provider name
+}
+
+// Execute runs the platform:dns:apply action
+func (a *Apply) Execute(ctx context.Context) error {
+	platform, err := schema.Load(a.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", a.Name)
+	}
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(a.PropagationTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid propagation timeout %q: %w", a.PropagationTimeout, err)
+	}
+	server, err := dnsprovider.NewServer(a.Resolver, a.DoH)
+	if err != nil {
+		return err
+	}
+
+	if a.DryRun {
+		a.Term().Info().Printfln("Planning DNS records of %s with %s...", platform.DNS.Domain, platform.DNS.Provider)
+	} else {
+		a.Term().Info().Printfln("Applying DNS records of %s with %s...", platform.DNS.Domain, platform.DNS.Provider)
+	}
+
+	m := &dns.Manager{WithLogger: a.WithLogger, WithTerm: a.WithTerm, Keyring: a.Keyring, Resolver: server, Platform: a.Name}
+	changes, err := m.Apply(ctx, platform, a.DryRun)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(changes) == 0:
+		a.Term().Success().Println("DNS records are up to date")
+	case a.DryRun:
+		a.Term().Info().Printfln("%d change(s) to apply, run without --dry-run to apply them", len(changes))
+	default:
+		a.Term().Success().Printfln("Applied %d change(s)", len(changes))
+	}
+
+	if a.WaitPropagation && !a.DryRun {
+		return m.WaitPropagation(ctx, platform, timeout)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Export implements the platform:dns:export command
+type Export struct {
+	action.WithLogger
+	action.WithTerm
+
+	Name   string
+	Format string
+}
+
+// Execute runs the platform:dns:export action
+func (e *Export) Execute() error {
+	platform, err := schema.Load(e.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", e.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	export, err := dnsprovider.NewExport(platform.DNS)
+	if err != nil {
+		return err
+	}
+	if len(export.Records) == 0 {
+		e.Term().Warning().Println("No DNS records configured, add dns.addresses and dns.mail to platform.yaml")
+	}
+
+	switch strings.ToLower(e.Format) {
+	case "json":
+		output, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(output))
+	case "", "zone":
+		return export.WriteZone(os.Stdout)
+	default:
+		return fmt.Errorf("unknown output format %q, expected zone or json", e.Format)
+	}
+	return nil
+}
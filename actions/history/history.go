@@ -0,0 +1,47 @@
+// Package history implements the platform:history command listing the deployments of a platform
+package history
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+)
+
+// History implements the platform:history command
+type History struct {
+	action.WithLogger
+	action.WithTerm
+
+	Name   string
+	Format string
+	Limit  int // Number of deployments, all if not positive
+}
+
+// Execute runs the platform:history action
+func (h *History) Execute() error {
+	if _, err := output.Parse(h.Format); err != nil {
+		return err
+	}
+	if h.Limit < 0 {
+		return fmt.Errorf("invalid limit %d, use 0 for all deployments", h.Limit)
+	}
+	deployments, err := history.Journal("", h.Name, h.Limit)
+	if err != nil {
+		return err
+	}
+	if deployments == nil {
+		deployments = []history.Deployment{}
+	}
+
+	return output.Render(os.Stdout, h.Format, deployments, func(wide bool) error {
+		if len(deployments) == 0 {
+			h.Term().Info().Printfln("No deployment of %s recorded in %s", h.Name, history.JournalFile(h.Name))
+			return nil
+		}
+		history.Print(os.Stdout, deployments, wide)
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"gopkg.in/yaml.v3"
+)
+
+// Inspect implements the platform:image:inspect command
+type Inspect struct {
+	Log    *launchr.Logger
+	Term   *launchr.Terminal
+	Image  string
+	Format string
+}
+
+// SetLogger sets the logger for the action
+func (i *Inspect) SetLogger(log *launchr.Logger) {
+	i.Log = log
+}
+
+// SetTerm sets the terminal for the action
+func (i *Inspect) SetTerm(term *launchr.Terminal) {
+	i.Term = term
+}
+
+// Execute runs the platform:image:inspect action
+func (i *Inspect) Execute(ctx context.Context) error {
+	info, err := pi.Inspect(ctx, i.Image)
+	if err != nil {
+		return err
+	}
+
+	// Output based on format
+	switch strings.ToLower(i.Format) {
+	case "json":
+		output, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(output))
+
+	case "yaml":
+		output, err := yaml.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		fmt.Println(string(output))
+
+	default: // table
+		fmt.Printf("Image:       %s\n", info.Path)
+		if m := info.Manifest; m != nil {
+			fmt.Printf("Name:        %s\n", m.Name)
+			fmt.Printf("Version:     %s\n", m.Version)
+			if m.Commit != "" {
+				fmt.Printf("Commit:      %s\n", m.Commit)
+			}
+			if m.Tag != "" {
+				fmt.Printf("Tag:         %s\n", m.Tag)
+			}
+			fmt.Printf("Built:       %s\n", m.BuildTime.Format(time.RFC3339))
+			fmt.Printf("Builder:     %s\n", m.Builder)
+			fmt.Printf("Source:      %s\n", m.SourceDir)
+			fmt.Printf("Digest:      %s\n", m.Digest)
+			if m.Base != nil {
+				fmt.Printf("Base:        %s (commit %s, digest %s)\n", m.Base.Version, m.Base.Commit, m.Base.Digest)
+				fmt.Printf("Layer:       %d changed files, %d deleted\n", m.LayerFiles, len(m.Deleted))
+			}
+		} else {
+			fmt.Println("Manifest:    none")
+		}
+		fmt.Printf("Files:       %d\n", info.FileCount)
+		fmt.Printf("Size:        %s\n", pi.FormatSize(info.TotalSize))
+		fmt.Printf("Compressed:  %s (%s, ratio %.2f)\n", pi.FormatSize(info.CompressedSize), info.Compression, info.Ratio)
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "PATH\tFILES\tSIZE")
+		for _, e := range info.Layout {
+			name := e.Name
+			if e.Dir {
+				name += "/"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\n", name, e.Files, pi.FormatSize(e.Size))
+		}
+		w.Flush()
+	}
+
+	return nil
+}
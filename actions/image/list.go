@@ -0,0 +1,86 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"gopkg.in/yaml.v3"
+)
+
+// List implements the platform:image:list command
+type List struct {
+	Log    *launchr.Logger
+	Term   *launchr.Terminal
+	Format string
+}
+
+// SetLogger sets the logger for the action
+func (l *List) SetLogger(log *launchr.Logger) {
+	l.Log = log
+}
+
+// SetTerm sets the terminal for the action
+func (l *List) SetTerm(term *launchr.Terminal) {
+	l.Term = term
+}
+
+// Execute runs the platform:image:list action
+func (l *List) Execute() error {
+	images, err := pi.ListImages()
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 && l.Format == "" {
+		l.Term.Info().Printfln("No Platform Images found in %s/ or %s/", pi.ImageDir, pi.CacheDir)
+		return nil
+	}
+	if images == nil {
+		images = []pi.LocalImage{}
+	}
+
+	// Output based on format
+	switch strings.ToLower(l.Format) {
+	case "json":
+		output, err := json.MarshalIndent(images, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(output))
+
+	case "yaml":
+		output, err := yaml.Marshal(images)
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		fmt.Println(string(output))
+
+	default: // table
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tSIZE\tCREATED\tSOURCE\tHEAD\tPATH")
+		for _, img := range images {
+			version := img.Version
+			if version == "" {
+				version = "-"
+			}
+			if img.Delta {
+				version += " (delta)"
+			}
+			head := ""
+			if img.Current {
+				head = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				img.Name, version, pi.FormatSize(img.Size), img.Created.Local().Format(time.DateTime), img.Source, head, img.Path)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/pkg/metrics"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Package implements the platform:package command
+type Package struct {
+	Log          *launchr.Logger
+	Term         *launchr.Terminal
+	Config       launchr.Config
+	Environment  string
+	SourceDir    string
+	OutputDir    string
+	NameTemplate string
+	Compression  string
+	Level        int
+	Base         string
+
+	image *result.ImageInfo
+}
+
+// SetLogger sets the logger for the action
+func (p *Package) SetLogger(log *launchr.Logger) {
+	p.Log = log
+}
+
+// SetTerm sets the terminal for the action
+func (p *Package) SetTerm(term *launchr.Terminal) {
+	p.Term = term
+}
+
+// Execute runs the platform:package action
+func (p *Package) Execute(ctx context.Context) error {
+	img, err := p.Build(ctx)
+	if err != nil {
+		return err
+	}
+	p.image = img
+
+	p.Term.Success().Printfln("Platform Image created: %s", img.Path)
+	p.Term.Printfln("  Version:     %s", img.Version)
+	p.Term.Printfln("  Files:       %d", img.Files)
+	p.Term.Printfln("  Compression: %s", img.Compression)
+	p.Term.Printfln("  Digest:      %s", img.Digest)
+	if img.Base != "" {
+		p.Term.Printfln("  Layer:       %d changed files, %d deleted", img.LayerFiles, img.Deleted)
+	}
+	return nil
+}
+
+// Result returns the Platform Image created by Execute, nil if it failed
+func (p *Package) Result() *result.ImageInfo {
+	return p.image
+}
+
+// Build creates the Platform Image without printing it
+func (p *Package) Build(ctx context.Context) (*result.ImageInfo, error) {
+	compression, err := pi.ParseCompression(p.Compression)
+	if err != nil {
+		return nil, err
+	}
+	nameTemplate, err := p.nameTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Base != "" {
+		p.Term.Info().Printfln("Packaging %s as a delta over %s", p.SourceDir, p.Base)
+	} else {
+		p.Term.Info().Printfln("Packaging %s", p.SourceDir)
+	}
+
+	// Packaging large platforms takes minutes, report progress unless --quiet is set
+	var progress *pi.Progress
+	if launchr.EnvVarQuietMode.Get() != "1" {
+		progress = pi.NewProgress(p.Term)
+	}
+
+	start := time.Now()
+	imgPath, m, err := pi.Create(ctx, pi.CreateOptions{
+		SourceDir:    p.SourceDir,
+		OutputDir:    p.OutputDir,
+		Base:         p.Base,
+		NameTemplate: nameTemplate,
+		Environment:  p.Environment,
+		Compression:  compression,
+		Level:        p.Level,
+		Progress:     progress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create platform image: %w", err)
+	}
+	p.recordMetrics(imgPath, time.Since(start))
+
+	img := &result.ImageInfo{
+		Path:        imgPath,
+		Version:     m.Version,
+		Digest:      m.Digest,
+		Compression: string(m.Compression),
+		Files:       m.FileCount,
+	}
+	if m.Base != nil {
+		img.Base = m.Base.Version
+		img.LayerFiles = m.LayerFiles
+		img.Deleted = len(m.Deleted)
+	}
+	return img, nil
+}
+
+// recordMetrics records the build time and the size of the image for the platform of the environment
+func (p *Package) recordMetrics(imgPath string, elapsed time.Duration) {
+	metrics.Record(p.Environment, metrics.Metric{Name: metrics.ImageBuildDuration, Value: elapsed.Seconds()})
+	if info, err := os.Stat(imgPath); err == nil {
+		metrics.Record(p.Environment, metrics.Metric{Name: metrics.ImageSize, Value: float64(info.Size())})
+	}
+}
+
+// nameTemplate resolves the image name template by priority:
+// --name-template, platform.yaml of the environment, repository config, default.
+func (p *Package) nameTemplate() (string, error) {
+	if p.NameTemplate != "" {
+		return p.NameTemplate, nil
+	}
+
+	if p.Environment != "" {
+		platform, err := schema.LoadOptional(p.Environment)
+		if err != nil {
+			return "", err
+		}
+		if platform.Image.NameTemplate != "" {
+			return platform.Image.NameTemplate, nil
+		}
+	}
+
+	var tmpl string
+	if p.Config != nil {
+		if err := p.Config.Get(pi.NameTemplateConfigKey, &tmpl); err != nil {
+			return "", fmt.Errorf("failed to read %s config: %w", pi.NameTemplateConfigKey, err)
+		}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,71 @@
+package image
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/sigstore"
+)
+
+// Prune implements the platform:image:prune command
+type Prune struct {
+	Log    *launchr.Logger
+	Term   *launchr.Terminal
+	Keep   int
+	DryRun bool
+}
+
+// SetLogger sets the logger for the action
+func (p *Prune) SetLogger(log *launchr.Logger) {
+	p.Log = log
+}
+
+// SetTerm sets the terminal for the action
+func (p *Prune) SetTerm(term *launchr.Terminal) {
+	p.Term = term
+}
+
+// Execute runs the platform:image:prune action
+func (p *Prune) Execute() error {
+	if p.Keep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+
+	images, err := pi.ListImages()
+	if err != nil {
+		return err
+	}
+
+	prune := pi.PruneCandidates(images, p.Keep)
+	if len(prune) == 0 {
+		p.Term.Info().Printfln("Nothing to prune, %d Platform Images kept", len(images))
+		return nil
+	}
+
+	var reclaimed int64
+	for _, img := range prune {
+		if p.DryRun {
+			p.Term.Printfln("Would remove %s (%s)", img.Path, pi.FormatSize(img.Size))
+			reclaimed += img.Size
+			continue
+		}
+		if err := os.Remove(img.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", img.Path, err)
+		}
+		// Signature bundle is useless without its image
+		if err := os.Remove(sigstore.BundlePath(img.Path)); err != nil && !os.IsNotExist(err) {
+			p.Log.Warn("Failed to remove signature bundle", "path", sigstore.BundlePath(img.Path), "error", err)
+		}
+		p.Term.Printfln("Removed %s (%s)", img.Path, pi.FormatSize(img.Size))
+		reclaimed += img.Size
+	}
+
+	if p.DryRun {
+		p.Term.Info().Printfln("Would remove %d Platform Images, reclaiming %s", len(prune), pi.FormatSize(reclaimed))
+		return nil
+	}
+	p.Term.Success().Printfln("Removed %d Platform Images, reclaimed %s", len(prune), pi.FormatSize(reclaimed))
+	return nil
+}
@@ -0,0 +1,50 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/sigstore"
+)
+
+// Sign implements the platform:image:sign command
+type Sign struct {
+	Log    *launchr.Logger
+	Term   *launchr.Terminal
+	Image  string
+	Key    string
+	Bundle string
+}
+
+// SetLogger sets the logger for the action
+func (s *Sign) SetLogger(log *launchr.Logger) {
+	s.Log = log
+}
+
+// SetTerm sets the terminal for the action
+func (s *Sign) SetTerm(term *launchr.Terminal) {
+	s.Term = term
+}
+
+// Execute runs the platform:image:sign action
+func (s *Sign) Execute(ctx context.Context) error {
+	if _, err := os.Stat(s.Image); os.IsNotExist(err) {
+		return fmt.Errorf("platform image not found: %s", s.Image)
+	}
+
+	if s.Key != "" {
+		s.Term.Info().Printfln("Signing Platform Image %s with key %s", s.Image, s.Key)
+	} else {
+		s.Term.Info().Printfln("Signing Platform Image %s (keyless)", s.Image)
+	}
+
+	bundle, err := sigstore.Sign(ctx, s.Image, sigstore.SignOptions{Key: s.Key, Bundle: s.Bundle}, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	s.Term.Success().Printfln("Signature bundle written to %s", bundle)
+	return nil
+}
@@ -0,0 +1,157 @@
+package list
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Columns of the platform table, also the keys of --sort for name, domain, nodes and last-deploy
+const (
+	columnName       = "name"
+	columnDomain     = "domain"
+	columnProvider   = "provider"
+	columnDNS        = "dns"
+	columnNodes      = "nodes"
+	columnLabels     = "labels"
+	columnLastDeploy = "last-deploy"
+	columnRepository = "repository"
+)
+
+// defaultColumns are the columns of the table without --columns
+var defaultColumns = []string{columnName, columnDomain, columnProvider, columnNodes}
+
+// columns are the available columns of the table
+var columns = []string{columnName, columnDomain, columnProvider, columnDNS, columnNodes, columnLabels, columnLastDeploy, columnRepository}
+
+// wideColumns returns the columns of the wide table, all of them, with the repository when listing across
+// repositories
+func wideColumns(repositories bool) []string {
+	if repositories {
+		return append([]string{columnRepository}, columns[:len(columns)-1]...)
+	}
+	return columns[:len(columns)-1]
+}
+
+// sortKeys are the values of --sort
+var sortKeys = []string{columnName, columnNodes, columnDomain, columnLastDeploy}
+
+// parseColumns returns the columns of a comma-separated list, in its order, or the default ones
+func parseColumns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return defaultColumns, nil
+	}
+	var selected []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(columns, c) {
+			return nil, fmt.Errorf("unknown column %q (use %s)", c, strings.Join(columns, ", "))
+		}
+		if !slices.Contains(selected, c) {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+// checkSort checks the key of --sort, empty keeps the order of the directories
+func checkSort(key string) error {
+	if key != "" && !slices.Contains(sortKeys, key) {
+		return fmt.Errorf("unknown sort key %q (use %s)", key, strings.Join(sortKeys, ", "))
+	}
+	return nil
+}
+
+// sortPlatforms sorts the platforms by a key, ascending. Last deployments are sorted the most recent
+// first, platforms never deployed last.
+func sortPlatforms(platforms []schema.PlatformInfo, key string) {
+	slices.SortStableFunc(platforms, func(a, b schema.PlatformInfo) int {
+		switch key {
+		case columnName:
+			return strings.Compare(a.Name, b.Name)
+		case columnNodes:
+			return cmp.Compare(a.NodeCount, b.NodeCount)
+		case columnDomain:
+			return strings.Compare(a.Domain, b.Domain)
+		case columnLastDeploy:
+			switch {
+			case a.LastDeploy == nil && b.LastDeploy == nil:
+				return 0
+			case a.LastDeploy == nil:
+				return 1
+			case b.LastDeploy == nil:
+				return -1
+			}
+			return b.LastDeploy.Compare(*a.LastDeploy)
+		}
+		return 0
+	})
+}
+
+// printTable writes the table of the platforms with the columns, and their header unless disabled
+func printTable(out io.Writer, platforms []schema.PlatformInfo, cols []string, header bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	if header {
+		fmt.Fprintln(w, strings.Join(titles(cols), "\t"))
+	}
+	for _, p := range platforms {
+		fmt.Fprintln(w, strings.Join(cells(p, cols), "\t"))
+	}
+	w.Flush()
+}
+
+// titles returns the header of the columns
+func titles(cols []string) []string {
+	t := make([]string, len(cols))
+	for i, c := range cols {
+		t[i] = strings.ToUpper(c)
+	}
+	return t
+}
+
+// cells returns the values of the columns of a platform
+func cells(p schema.PlatformInfo, cols []string) []string {
+	values := make([]string, len(cols))
+	for i, c := range cols {
+		values[i] = cell(p, c)
+	}
+	return values
+}
+
+// cell returns the value of a column of a platform
+func cell(p schema.PlatformInfo, column string) string {
+	switch column {
+	case columnName:
+		return p.Name
+	case columnDomain:
+		return p.Domain
+	case columnProvider:
+		return p.MetalProvider
+	case columnDNS:
+		return p.DNSProvider
+	case columnNodes:
+		return strconv.Itoa(p.NodeCount)
+	case columnLabels:
+		labels := make([]string, 0, len(p.Labels))
+		for _, k := range slices.Sorted(maps.Keys(p.Labels)) {
+			labels = append(labels, k+"="+p.Labels[k])
+		}
+		return strings.Join(labels, ",")
+	case columnLastDeploy:
+		if p.LastDeploy == nil {
+			return "-"
+		}
+		return p.LastDeploy.Local().Format(time.DateTime)
+	case columnRepository:
+		return p.Repository
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package list
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// maxRootDepth is the depth of the directories searched for repositories under a root
+const maxRootDepth = 4
+
+// findRepositories returns the repositories of platforms under the roots, the directories with an inst/
+// directory, in the order of the roots. A root can be a repository itself. Paths are relative to the
+// current directory when under it, absolute otherwise.
+func findRepositories(roots []string) ([]string, error) {
+	var repos []string
+	seen := map[string]bool{}
+	for _, root := range roots {
+		abs, err := filepath.Abs(expandHome(root))
+		if err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		if _, err = os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		err = filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == abs {
+					return err
+				}
+				return fs.SkipDir // Unreadable directories are skipped
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if path != abs && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return fs.SkipDir
+			}
+			if info, err := os.Stat(filepath.Join(path, schema.InstDir)); err == nil && info.IsDir() {
+				if !seen[path] {
+					seen[path] = true
+					repos = append(repos, displayPath(path))
+				}
+				return fs.SkipDir
+			}
+			if rel, _ := filepath.Rel(abs, path); strings.Count(rel, string(filepath.Separator)) >= maxRootDepth-1 && rel != "." {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search root %s: %w", root, err)
+		}
+	}
+	return repos, nil
+}
+
+// expandHome replaces the ~ prefix of a path, which the shell doesn't expand in config values, with the
+// home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// displayPath returns a path relative to the current directory when under it, the absolute path otherwise
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
@@ -0,0 +1,168 @@
+package list
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Keys of the --filter conditions, labels are selected with label.<key>
+const (
+	keyName     = "name"
+	keyProvider = "provider"
+	keyDNS      = "dns"
+	keyDomain   = "domain"
+	keyNodes    = "nodes"
+	keyValid    = "valid"
+	labelPrefix = "label."
+)
+
+// operators of the conditions, the longest first for parsing
+var operators = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// condition is a condition of a --filter expression, e.g. nodes>3
+type condition struct {
+	key   string
+	op    string
+	value string
+	num   int  // Value of the nodes conditions
+	valid bool // Value of the valid conditions
+}
+
+// filter is a --filter expression, its conditions must all match
+type filter []condition
+
+// parseFilter parses a --filter expression: conditions separated by commas, like provider=hetzner or
+// nodes>3. String values are glob patterns, e.g. domain=*.skilld.cloud.
+func parseFilter(expr string) (filter, error) {
+	var f filter
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		c, err := parseCondition(term)
+		if err != nil {
+			return nil, err
+		}
+		f = append(f, c)
+	}
+	return f, nil
+}
+
+// parseCondition parses a condition of a --filter expression
+func parseCondition(term string) (condition, error) {
+	var c condition
+	for _, op := range operators {
+		if key, value, ok := strings.Cut(term, op); ok {
+			c = condition{key: strings.TrimSpace(key), op: op, value: strings.TrimSpace(value)}
+			break
+		}
+	}
+	if c.op == "" {
+		return c, fmt.Errorf("invalid filter %q, expected <key><operator><value>, e.g. provider=hetzner", term)
+	}
+
+	switch {
+	case c.key == keyNodes:
+		n, err := strconv.Atoi(c.value)
+		if err != nil {
+			return c, fmt.Errorf("invalid filter %q, nodes must be compared to a number", term)
+		}
+		c.num = n
+		return c, nil
+	case c.key == keyValid:
+		v, err := strconv.ParseBool(c.value)
+		if err != nil {
+			return c, fmt.Errorf("invalid filter %q, valid must be true or false", term)
+		}
+		c.valid = v
+	case c.key == keyName, c.key == keyProvider, c.key == keyDNS, c.key == keyDomain:
+	case strings.HasPrefix(c.key, labelPrefix) && len(c.key) > len(labelPrefix):
+	default:
+		return c, fmt.Errorf("unknown filter key %q (use %s, %s, %s, %s, %s, %s or %s<key>)", c.key, keyName, keyProvider, keyDNS, keyDomain, keyNodes, keyValid, labelPrefix)
+	}
+	if c.op != "=" && c.op != "!=" {
+		return c, fmt.Errorf("invalid filter %q, %s can only be compared with = or !=", term, c.key)
+	}
+	if _, err := path.Match(c.value, ""); err != nil {
+		return c, fmt.Errorf("invalid filter %q: %w", term, err)
+	}
+	return c, nil
+}
+
+// matches reports whether a platform matches all the conditions. Validity is only checked when a
+// condition needs it, from platform.yaml and the node files without network checks.
+func (f filter) matches(info schema.PlatformInfo, nodesDir string, platform *schema.Platform) bool {
+	for _, c := range f {
+		var ok bool
+		switch {
+		case c.key == keyNodes:
+			ok = compare(info.NodeCount, c.op, c.num)
+		case c.key == keyValid:
+			ok = isValid(nodesDir, platform) == c.valid
+			if c.op == "!=" {
+				ok = !ok
+			}
+		default:
+			ok = glob(c.value, c.field(info))
+			if c.op == "!=" {
+				ok = !ok
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// field returns the value of the string field of the condition
+func (c condition) field(info schema.PlatformInfo) string {
+	switch c.key {
+	case keyName:
+		return info.Name
+	case keyProvider:
+		return info.MetalProvider
+	case keyDNS:
+		return info.DNSProvider
+	case keyDomain:
+		return info.Domain
+	}
+	return info.Labels[strings.TrimPrefix(c.key, labelPrefix)]
+}
+
+// compare compares numbers with an operator
+func compare(a int, op string, b int) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	}
+	return a <= b
+}
+
+// glob reports whether a value matches a pattern, case-insensitively
+func glob(pattern, value string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return ok
+}
+
+// isValid reports whether platform.yaml has its required keys and the node files are valid
+func isValid(nodesDir string, platform *schema.Platform) bool {
+	if platform.Name == "" || platform.Infrastructure.MetalProvider == "" {
+		return false
+	}
+	nodes, errs := schema.ValidateNodes(nodesDir, platform)
+	return len(errs) == 0 && len(schema.ValidateGroups(platform, nodes)) == 0
+}
@@ -1,16 +1,16 @@
 package list
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
-	"text/tabwriter"
 
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/output"
 	"github.com/plasmash/plasmactl-platform/pkg/schema"
-	"gopkg.in/yaml.v3"
 )
 
 // List implements the platform:list command
@@ -18,24 +18,113 @@ type List struct {
 	Log    *launchr.Logger
 	Term   *launchr.Terminal
 	Format string
+	Filter string // Conditions the listed platforms match, e.g. provider=hetzner,nodes>3
+
+	Sort     string // Key of the order of the platforms: name, nodes, domain or last-deploy
+	Columns  string // Columns of the table, comma-separated
+	NoHeader bool   // Table without header, for scripts
+
+	Roots []string // Directories searched for repositories of platforms, the current repository by default
+
+	Watch    bool   // Refresh the table periodically
+	Interval string // Interval of the refreshes of Watch, e.g. 10s
 }
 
 func (l *List) SetLogger(log *launchr.Logger) { l.Log = log }
 func (l *List) SetTerm(term *launchr.Terminal) { l.Term = term }
 
-func (l *List) Execute() error {
-	instDir := "inst"
+func (l *List) Execute(ctx context.Context) error {
+	format, err := output.Parse(l.Format)
+	if err != nil {
+		return err
+	}
+	f, err := parseFilter(l.Filter)
+	if err != nil {
+		return err
+	}
+	cols, err := parseColumns(l.Columns)
+	if err != nil {
+		return err
+	}
+	if err = checkSort(l.Sort); err != nil {
+		return err
+	}
+
+	switch {
+	case format == output.Wide && l.Columns == "":
+		cols = wideColumns(len(l.Roots) > 0)
+	case len(l.Roots) > 0 && l.Columns == "":
+		cols = append([]string{columnRepository}, cols...)
+	}
+	if l.Watch {
+		return l.watch(ctx, f, cols)
+	}
 
-	// Check if inst directory exists
-	if _, err := os.Stat(instDir); os.IsNotExist(err) {
-		l.Term.Info().Println("No platforms found (inst/ directory does not exist)")
+	platforms, repos, err := l.collect(f)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, l.Format, platforms, func(bool) error {
+		switch {
+		case len(repos) == 0 && len(l.Roots) == 0:
+			l.Term.Info().Println("No platforms found (inst/ directory does not exist)")
+		case len(platforms) == 0 && len(f) > 0:
+			l.Term.Info().Printfln("No platforms match %q", l.Filter)
+		case len(platforms) == 0:
+			l.Term.Info().Println("No platforms found")
+		default:
+			printTable(os.Stdout, platforms, cols, !l.NoHeader)
+		}
 		return nil
+	})
+}
+
+// Platforms returns the sorted platforms matching the filter, without printing them
+func (l *List) Platforms() ([]schema.PlatformInfo, error) {
+	f, err := parseFilter(l.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSort(l.Sort); err != nil {
+		return nil, err
+	}
+	platforms, _, err := l.collect(f)
+	return platforms, err
+}
+
+// collect returns the sorted platforms matching the filter, and the repositories they were searched in
+func (l *List) collect(f filter) ([]schema.PlatformInfo, []string, error) {
+	repos := []string{""}
+	if len(l.Roots) > 0 {
+		var err error
+		if repos, err = findRepositories(l.Roots); err != nil {
+			return nil, nil, err
+		}
+	} else if _, err := os.Stat(schema.InstDir); os.IsNotExist(err) {
+		repos = nil // No platforms without inst directory
 	}
 
+	platforms := []schema.PlatformInfo{}
+	for _, repo := range repos {
+		found, err := l.listRepository(repo, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		platforms = append(platforms, found...)
+	}
+	sortPlatforms(platforms, l.Sort)
+	return platforms, repos, nil
+}
+
+// listRepository returns the platforms of the inst/ directory of a repository matching the filter, the
+// current one when empty
+func (l *List) listRepository(repo string, f filter) ([]schema.PlatformInfo, error) {
+	instDir := filepath.Join(repo, schema.InstDir)
+
 	// List all directories in inst/
 	entries, err := os.ReadDir(instDir)
 	if err != nil {
-		return fmt.Errorf("failed to read inst directory: %w", err)
+		return nil, fmt.Errorf("failed to read inst directory: %w", err)
 	}
 
 	var platforms []schema.PlatformInfo
@@ -45,21 +134,12 @@ func (l *List) Execute() error {
 			continue
 		}
 
-		platformFile := filepath.Join(instDir, entry.Name(), "platform.yaml")
-		if _, err := os.Stat(platformFile); os.IsNotExist(err) {
+		platform, err := schema.LoadFile(filepath.Join(repo, schema.PlatformFile(entry.Name())))
+		if errors.Is(err, schema.ErrNotFound) {
 			continue // Not a valid platform directory
 		}
-
-		// Read platform.yaml
-		data, err := os.ReadFile(platformFile)
 		if err != nil {
-			l.Log.Warn("Failed to read %s: %v", platformFile, err)
-			continue
-		}
-
-		var platform schema.Platform
-		if err := yaml.Unmarshal(data, &platform); err != nil {
-			l.Log.Warn("Failed to parse %s: %v", platformFile, err)
+			l.Log.Warn("Failed to load platform", "name", entry.Name(), "error", err)
 			continue
 		}
 
@@ -74,44 +154,23 @@ func (l *List) Execute() error {
 			}
 		}
 
-		platforms = append(platforms, schema.PlatformInfo{
+		info := schema.PlatformInfo{
 			Name:          platform.Name,
 			Domain:        platform.DNS.Domain,
 			MetalProvider: platform.Infrastructure.MetalProvider,
 			DNSProvider:   platform.DNS.Provider,
 			NodeCount:     nodeCount,
-		})
-	}
-
-	if len(platforms) == 0 {
-		l.Term.Info().Println("No platforms found")
-		return nil
-	}
-
-	// Output based on format
-	switch strings.ToLower(l.Format) {
-	case "json":
-		output, err := json.MarshalIndent(platforms, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+			Labels:        platform.Labels,
+			Repository:    repo,
 		}
-		fmt.Println(string(output))
-
-	case "yaml":
-		output, err := yaml.Marshal(platforms)
-		if err != nil {
-			return fmt.Errorf("failed to marshal YAML: %w", err)
+		if last, err := history.Last(repo, entry.Name()); err != nil {
+			l.Log.Warn("Failed to read the last deployment", "name", entry.Name(), "error", err)
+		} else if last != nil {
+			info.LastDeploy = &last.Time
 		}
-		fmt.Println(string(output))
-
-	default: // table
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "NAME\tDOMAIN\tPROVIDER\tNODES")
-		for _, p := range platforms {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", p.Name, p.Domain, p.MetalProvider, p.NodeCount)
+		if f.matches(info, nodesDir, platform) {
+			platforms = append(platforms, info)
 		}
-		w.Flush()
 	}
-
-	return nil
+	return platforms, nil
 }
@@ -0,0 +1,152 @@
+package list
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"golang.org/x/term"
+)
+
+// DefaultWatchInterval is the default interval between the refreshes of --watch
+const DefaultWatchInterval = 10 * time.Second
+
+// Escape sequences of the terminal refreshed by --watch
+const (
+	clearScreen = "\033[H\033[2J"
+	styleNew    = "\033[32m"   // Green, platforms which appeared
+	styleChange = "\033[1;33m" // Bold yellow, values which changed
+	styleGone   = "\033[31m"   // Red, platforms which disappeared
+	styleReset  = "\033[0m"
+)
+
+// row is a platform of the table refreshed by --watch
+type row struct {
+	key   string // Repository and name of the platform
+	cells []string
+}
+
+// watch refreshes the table of the platforms until interrupted. On a terminal the screen is redrawn
+// with the changes since the previous refresh highlighted, otherwise the table is printed again when
+// it changes.
+func (l *List) watch(ctx context.Context, f filter, cols []string) error {
+	if output.Structured(l.Format) {
+		return fmt.Errorf("--watch refreshes a table, it can't be used with --output %s", l.Format)
+	}
+	interval := DefaultWatchInterval
+	if l.Interval != "" {
+		d, err := time.ParseDuration(l.Interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid interval %q", l.Interval)
+		}
+		interval = d
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	var previous []row
+	for first := true; ; first = false {
+		platforms, _, err := l.collect(f)
+		if err != nil {
+			return err
+		}
+		current := make([]row, len(platforms))
+		for i, p := range platforms {
+			current[i] = row{key: p.Repository + "/" + p.Name, cells: cells(p, cols)}
+		}
+
+		if tty {
+			fmt.Print(clearScreen)
+			fmt.Printf("Every %s: platform:list, %d platform(s)    %s\n\n", interval, len(current), time.Now().Format(time.DateTime))
+			printChanges(os.Stdout, cols, previous, current, !first, !l.NoHeader)
+		} else if first || !slices.EqualFunc(previous, current, equalRows) {
+			fmt.Printf("%s, %d platform(s)\n", time.Now().Format(time.DateTime), len(current))
+			printTable(os.Stdout, platforms, cols, !l.NoHeader)
+			fmt.Println()
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// equalRows reports whether two rows are the same platform with the same values
+func equalRows(a, b row) bool {
+	return a.key == b.key && slices.Equal(a.cells, b.cells)
+}
+
+// printChanges writes the aligned table of the current rows, highlighting the values which changed
+// since the previous rows, the new platforms, and the platforms which disappeared at the end
+func printChanges(out io.Writer, cols []string, previous, current []row, highlight, header bool) {
+	before := make(map[string][]string, len(previous))
+	for _, r := range previous {
+		before[r.key] = r.cells
+	}
+	now := make(map[string]bool, len(current))
+	for _, r := range current {
+		now[r.key] = true
+	}
+	var gone []row
+	for _, r := range previous {
+		if !now[r.key] {
+			gone = append(gone, r)
+		}
+	}
+
+	widths := make([]int, len(cols))
+	for _, r := range slices.Concat([]row{{cells: titles(cols)}}, current, gone) {
+		for i, c := range r.cells {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+	if header {
+		printRow(out, titles(cols), widths, func(int) string { return "" })
+	}
+	for _, r := range current {
+		old, seen := before[r.key]
+		printRow(out, r.cells, widths, func(i int) string {
+			switch {
+			case !highlight:
+				return ""
+			case !seen:
+				return styleNew
+			case old[i] != r.cells[i]:
+				return styleChange
+			}
+			return ""
+		})
+	}
+	for _, r := range gone {
+		printRow(out, r.cells, widths, func(int) string { return styleGone })
+	}
+}
+
+// printRow writes the cells padded to the widths of the columns, each in the style returned for it
+func printRow(out io.Writer, values []string, widths []int, style func(i int) string) {
+	var b strings.Builder
+	for i, v := range values {
+		padding := ""
+		if i < len(values)-1 {
+			padding = strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)+3)
+		}
+		if s := style(i); s != "" {
+			v = s + v + styleReset
+		}
+		b.WriteString(v + padding)
+	}
+	fmt.Fprintln(out, b.String())
+}
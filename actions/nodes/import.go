@@ -0,0 +1,86 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/terraform"
+	"github.com/plasmash/plasmactl-platform/internal/tfstate"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Import implements the platform:nodes:import command
+type Import struct {
+	action.WithLogger
+	action.WithTerm
+
+	Name    string
+	TFState string
+	DryRun  bool
+}
+
+// Execute runs the platform:nodes:import action
+func (i *Import) Execute(ctx context.Context) error {
+	ws := terraform.Open(i.Name, i.Log())
+	if i.TFState == "" && !ws.Exists() {
+		return fmt.Errorf("no Terraform state given, use --tfstate or add the Terraform workspace %s", ws.Dir)
+	}
+	l, err := lock.Platform(ctx, i.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	platform, err := schema.Load(i.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", i.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The state of the workspace of the platform is read by default
+	var state *tfstate.State
+	if i.TFState != "" {
+		state, err = tfstate.Read(ctx, i.TFState)
+	} else {
+		state, err = ws.State(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	servers, skipped := state.Servers()
+	if len(skipped) > 0 {
+		i.Log().Debug("skipped Terraform resources of unsupported types", "types", skipped)
+	}
+	if len(servers) == 0 {
+		return fmt.Errorf("no server resource in the Terraform state, supported types are %s", strings.Join(tfstate.Types(), ", "))
+	}
+	nodes, err := schema.LoadNodes(i.Name)
+	if err != nil {
+		return err
+	}
+
+	if i.DryRun {
+		i.Term().Info().Printfln("Planning nodes of %s from %d server(s) of the Terraform state...", i.Name, len(servers))
+	} else {
+		i.Term().Info().Printfln("Importing nodes of %s from %d server(s) of the Terraform state...", i.Name, len(servers))
+	}
+
+	_, added, updated, err := writeNodes(i.Term(), i.Name, nodes, servers, offerChassis(platform.Chassis), i.DryRun)
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf("%d added, %d updated, %d unchanged", added, updated, len(servers)-added-updated)
+	if i.DryRun {
+		i.Term().Info().Printfln("Dry run: %s", summary)
+		return nil
+	}
+	i.Term().Success().Printfln("Nodes imported: %s", summary)
+	return nil
+}
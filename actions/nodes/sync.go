@@ -0,0 +1,225 @@
+// Package nodes implements actions managing the node files of platforms
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/pkg/event"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// unsafeFileChars are replaced in the file names of new nodes
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// Sync implements the platform:nodes:sync command
+type Sync struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring keyring.Keyring
+	Name    string
+	DryRun  bool
+}
+
+// Execute runs the platform:nodes:sync action
+func (s *Sync) Execute(ctx context.Context) error {
+	l, err := lock.Platform(ctx, s.Name)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	platform, err := schema.Load(s.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", s.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	name := platform.Infrastructure.MetalProvider
+	mp, err := provider.New(platform.Infrastructure, provider.Options{Keyring: s.Keyring, Log: s.Log(), Term: s.Term(), Platform: s.Name})
+	if errors.Is(err, provider.ErrUnsupported) {
+		return fmt.Errorf("servers of metal provider %q can't be listed, add the nodes to %s by hand", name, schema.NodesDir(s.Name))
+	}
+	if err != nil {
+		return err
+	}
+	servers, err := mp.ListServers(ctx)
+	if err != nil {
+		return err
+	}
+	nodes, err := schema.LoadNodes(s.Name)
+	if err != nil {
+		return err
+	}
+
+	if s.DryRun {
+		s.Term().Info().Printfln("Planning nodes of %s from %d server(s) at %s...", s.Name, len(servers), name)
+	} else {
+		s.Term().Info().Printfln("Syncing nodes of %s from %d server(s) at %s...", s.Name, len(servers), name)
+	}
+
+	matched, added, updated, err := writeNodes(s.Term(), s.Name, nodes, servers, offerChassis(platform.Chassis), s.DryRun)
+	if err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, node := range nodes {
+		if !matched[node] {
+			missing++
+			s.Term().Warning().Printfln("  ! %s of %s is not a server at %s", node.Hostname, node.File, name)
+		}
+	}
+
+	summary := fmt.Sprintf("%d added, %d updated, %d unchanged, %d not at %s", added, updated, len(servers)-added-updated, missing, name)
+	if s.DryRun {
+		s.Term().Info().Printfln("Dry run: %s", summary)
+		return nil
+	}
+	s.Term().Success().Printfln("Nodes synced: %s", summary)
+	if added+updated > 0 {
+		event.Publish(event.Event{Type: event.ConfigChanged, Platform: s.Name, Data: map[string]any{
+			"source":  "platform:nodes:sync",
+			"added":   added,
+			"updated": updated,
+		}})
+	}
+	return nil
+}
+
+// writeNodes writes the node files of the servers, matched with the existing nodes. It returns the
+// matched nodes and the counts of added and updated ones.
+func writeNodes(term *launchr.Terminal, name string, nodes []*schema.Node, servers []provider.Server, chassis map[string]string, dryRun bool) (map[*schema.Node]bool, int, int, error) {
+	slices.SortFunc(servers, func(a, b provider.Server) int { return strings.Compare(a.Hostname, b.Hostname) })
+	matched := make(map[*schema.Node]bool)
+	added, updated := 0, 0
+	for _, server := range servers {
+		node := provider.FindNode(nodes, server)
+		isNew := node == nil
+		if isNew {
+			node = &schema.Node{File: newFile(name, nodes, server)}
+			nodes = append(nodes, node)
+		}
+		matched[node] = true
+
+		next := *node
+		if server.Hostname != "" {
+			next.Hostname = server.Hostname
+		}
+		next.ProviderID = server.ID
+		next.PublicIPs = server.PublicIPs()
+		next.PrivateIPs = privateIPs(server)
+		next.Offer = server.Offer
+		next.Location = server.Location
+		if next.Chassis == "" {
+			next.Chassis = chassis[strings.ToLower(server.Offer)]
+		}
+
+		changes := nodeChanges(*node, next)
+		switch {
+		case isNew:
+			added++
+			term.Info().Printfln("  + %s (%s, %s) at %s", next.Hostname, next.ProviderID, next.Offer, next.File)
+			if next.Chassis == "" {
+				term.Warning().Printfln("    ! No chassis profile with offer %s, set the chassis of the node", server.Offer)
+			}
+		case len(changes) > 0:
+			updated++
+			term.Info().Printfln("  ~ %s (%s): %s", next.Hostname, next.ProviderID, strings.Join(changes, ", "))
+		default:
+			continue
+		}
+		if dryRun {
+			continue
+		}
+		if err := next.Save(); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	return matched, added, updated, nil
+}
+
+// newFile returns the file of a new node, named after its hostname unless another node has it
+func newFile(name string, nodes []*schema.Node, server provider.Server) string {
+	base := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(server.Hostname), "-"), "-.")
+	if base == "" {
+		base = "server"
+	}
+	file := schema.NodeFile(name, base)
+	taken := slices.ContainsFunc(nodes, func(n *schema.Node) bool { return n.File == file })
+	if _, err := os.Stat(file); taken || err == nil {
+		file = schema.NodeFile(name, base+"-"+unsafeFileChars.ReplaceAllString(strings.ToLower(server.ID), "-"))
+	}
+	return file
+}
+
+// offerChassis returns the chassis of each offer of the chassis profiles, in lower case. Offers of
+// several chassis are left out, their nodes are attached by hand.
+func offerChassis(profiles map[string][]schema.ChassisProfile) map[string]string {
+	chassis := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	for name, list := range profiles {
+		for _, p := range list {
+			offer := strings.ToLower(p.Type)
+			if c, ok := chassis[offer]; ok && c != name {
+				ambiguous[offer] = true
+			}
+			chassis[offer] = name
+		}
+	}
+	for offer := range ambiguous {
+		delete(chassis, offer)
+	}
+	return chassis
+}
+
+// privateIPs returns the private addresses of the server
+func privateIPs(server provider.Server) []string {
+	var ips []string
+	for _, a := range server.Addresses {
+		if a.Private {
+			ips = append(ips, a.IP)
+		}
+	}
+	return ips
+}
+
+// nodeChanges returns the keys of the changed values of a node
+func nodeChanges(old, next schema.Node) []string {
+	var changes []string
+	if old.Hostname != next.Hostname {
+		changes = append(changes, "hostname")
+	}
+	if old.ProviderID != next.ProviderID {
+		changes = append(changes, "provider_id")
+	}
+	if !slices.Equal(old.PublicIPs, next.PublicIPs) {
+		changes = append(changes, "public_ips")
+	}
+	if !slices.Equal(old.PrivateIPs, next.PrivateIPs) {
+		changes = append(changes, "private_ips")
+	}
+	if old.Chassis != next.Chassis {
+		changes = append(changes, "chassis")
+	}
+	if old.Offer != next.Offer {
+		changes = append(changes, "offer")
+	}
+	if old.Location != next.Location {
+		changes = append(changes, "location")
+	}
+	return changes
+}
@@ -0,0 +1,271 @@
+// Package plan implements the platform:plan command comparing the chassis profiles of platforms with
+// their nodes and the servers of their metal provider
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Actions of the changes of a plan
+const (
+	ActionOrder  = "order"  // Order servers of an offer at the metal provider
+	ActionAttach = "attach" // Attach a server without chassis to a chassis
+	ActionRetire = "retire" // Detach a node and release its server
+	ActionRemove = "remove" // Remove the node file of a server gone from the metal provider
+)
+
+// Plan implements the platform:plan command
+type Plan struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring keyring.Keyring
+	Name    string
+	Format  string
+	Offline bool
+}
+
+// Result is the provisioning plan of a platform
+type Result struct {
+	Platform string    `json:"platform" yaml:"platform"`
+	Provider string    `json:"provider,omitempty" yaml:"provider,omitempty"` // Metal provider whose servers were listed, empty when offline
+	Profiles []Profile `json:"profiles" yaml:"profiles"`
+	Changes  []Change  `json:"changes" yaml:"changes"`
+}
+
+// Profile compares a chassis profile with the nodes attached to it
+type Profile struct {
+	Chassis string `json:"chassis" yaml:"chassis"`
+	Offer   string `json:"offer" yaml:"offer"`
+	Needed  int    `json:"needed" yaml:"needed"`
+	Nodes   int    `json:"nodes" yaml:"nodes"` // Nodes of the chassis with the offer, still at the provider
+	Attach  int    `json:"attach" yaml:"attach"`
+	Order   int    `json:"order" yaml:"order"`
+	Retire  int    `json:"retire" yaml:"retire"`
+}
+
+// Change is a change of the plan
+type Change struct {
+	Action  string `json:"action" yaml:"action"`
+	Chassis string `json:"chassis,omitempty" yaml:"chassis,omitempty"`
+	Offer   string `json:"offer,omitempty" yaml:"offer,omitempty"`
+	Count   int    `json:"count" yaml:"count"`
+	Node    string `json:"node,omitempty" yaml:"node,omitempty"`     // Hostname of the node, or ID of a server without node file
+	Server  string `json:"server,omitempty" yaml:"server,omitempty"` // Provider ID of the server
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// free is a server without chassis, available to the chassis profiles of its offer
+type free struct {
+	node   string
+	server string
+	offer  string
+	reason string
+}
+
+// Execute runs the platform:plan action
+func (p *Plan) Execute(ctx context.Context) error {
+	if _, err := output.Parse(p.Format); err != nil {
+		return err
+	}
+	platform, err := schema.Load(p.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", p.Name)
+	}
+	if err != nil {
+		return err
+	}
+	nodes, err := schema.LoadNodes(p.Name)
+	if err != nil {
+		return err
+	}
+	servers, listed, err := p.listServers(ctx, platform.Infrastructure)
+	if err != nil {
+		return err
+	}
+
+	result := Result{Platform: p.Name, Profiles: []Profile{}, Changes: []Change{}}
+	if listed {
+		result.Provider = platform.Infrastructure.MetalProvider
+	}
+	result.plan(platform.Chassis, nodes, servers, listed)
+
+	return output.Render(os.Stdout, p.Format, result, func(bool) error {
+		p.print(result)
+		return nil
+	})
+}
+
+// listServers returns the servers of the metal provider, and whether they could be listed
+func (p *Plan) listServers(ctx context.Context, infra schema.Infrastructure) ([]provider.Server, bool, error) {
+	name := infra.MetalProvider
+	if p.Offline || name == "" || name == provider.ProviderManual {
+		return nil, false, nil
+	}
+	mp, err := provider.New(infra, provider.Options{Keyring: p.Keyring, Log: p.Log(), Term: p.Term(), Platform: p.Name})
+	if errors.Is(err, provider.ErrUnsupported) {
+		p.Term().Warning().Printfln("Servers of metal provider %q can't be listed, planning from the node files only", name)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	servers, err := mp.ListServers(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return servers, true, nil
+}
+
+// plan compares the chassis profiles with the nodes and servers. Nodes of servers gone from the provider
+// don't count, servers without chassis are attached before ordering new ones, and surplus nodes or nodes
+// matching no profile are retired.
+func (r *Result) plan(chassis map[string][]schema.ChassisProfile, nodes []*schema.Node, servers []provider.Server, listed bool) {
+	live := make([]*schema.Node, 0, len(nodes))
+	matched := make(map[*schema.Node]provider.Server)
+	for _, s := range servers {
+		if n := provider.FindNode(nodes, s); n != nil {
+			matched[n] = s
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := matched[n]; listed && !ok {
+			r.Changes = append(r.Changes, Change{Action: ActionRemove, Chassis: n.Chassis, Offer: n.Offer, Count: 1, Node: n.Hostname, Server: n.ProviderID, Reason: fmt.Sprintf("not a server of %s, remove %s", r.Provider, n.File)})
+			continue
+		}
+		live = append(live, n)
+	}
+	slices.SortFunc(live, func(a, b *schema.Node) int { return strings.Compare(a.Hostname, b.Hostname) })
+
+	// Servers without chassis, either without node file or with a node file not attached
+	var pool []free
+	for _, n := range live {
+		if n.Chassis == "" {
+			pool = append(pool, free{node: n.Hostname, server: n.ProviderID, offer: n.Offer})
+		}
+	}
+	for _, s := range servers {
+		if provider.FindNode(nodes, s) == nil {
+			pool = append(pool, free{node: s.ID, server: s.ID, offer: s.Offer, reason: "no node file, run platform:nodes:sync first"})
+		}
+	}
+
+	profiled := make(map[*schema.Node]bool)
+	for _, name := range slices.Sorted(maps.Keys(chassis)) {
+		profiles := chassis[name]
+		for _, cp := range profiles {
+			var attached []*schema.Node
+			for _, n := range live {
+				if n.Chassis != name || profiled[n] {
+					continue
+				}
+				// Nodes without offer belong to the profile of chassis having a single one
+				if strings.EqualFold(n.Offer, cp.Type) || (n.Offer == "" && len(profiles) == 1) {
+					attached = append(attached, n)
+					profiled[n] = true
+				}
+			}
+
+			prof := Profile{Chassis: name, Offer: cp.Type, Needed: cp.Count, Nodes: len(attached)}
+			for _, n := range attached[min(cp.Count, len(attached)):] {
+				prof.Retire++
+				r.Changes = append(r.Changes, Change{Action: ActionRetire, Chassis: name, Offer: cp.Type, Count: 1, Node: n.Hostname, Server: n.ProviderID, Reason: fmt.Sprintf("%d node(s) for %d in the profile", len(attached), cp.Count)})
+			}
+			for missing := cp.Count - len(attached); missing > 0; missing-- {
+				i := slices.IndexFunc(pool, func(f free) bool { return strings.EqualFold(f.offer, cp.Type) })
+				if i < 0 {
+					prof.Order = missing
+					r.Changes = append(r.Changes, Change{Action: ActionOrder, Chassis: name, Offer: cp.Type, Count: missing})
+					break
+				}
+				prof.Attach++
+				r.Changes = append(r.Changes, Change{Action: ActionAttach, Chassis: name, Offer: cp.Type, Count: 1, Node: pool[i].node, Server: pool[i].server, Reason: pool[i].reason})
+				pool = slices.Delete(pool, i, i+1)
+			}
+			r.Profiles = append(r.Profiles, prof)
+		}
+	}
+
+	for _, n := range live {
+		if n.Chassis == "" || profiled[n] {
+			continue
+		}
+		reason := fmt.Sprintf("offer %s has no profile in chassis %s", n.Offer, n.Chassis)
+		if _, ok := chassis[n.Chassis]; !ok {
+			reason = fmt.Sprintf("chassis %s has no profile", n.Chassis)
+		}
+		r.Changes = append(r.Changes, Change{Action: ActionRetire, Chassis: n.Chassis, Offer: n.Offer, Count: 1, Node: n.Hostname, Server: n.ProviderID, Reason: reason})
+	}
+	for _, f := range pool {
+		r.Changes = append(r.Changes, Change{Action: ActionRetire, Offer: f.offer, Count: 1, Node: f.node, Server: f.server, Reason: "not needed by the chassis profiles"})
+	}
+}
+
+// print shows the plan on the terminal
+func (p *Plan) print(r Result) {
+	if r.Provider != "" {
+		p.Term().Info().Printfln("Plan of %s against the chassis profiles and the servers at %s", r.Platform, r.Provider)
+	} else {
+		p.Term().Info().Printfln("Plan of %s against the chassis profiles and the node files", r.Platform)
+	}
+	if len(r.Profiles) == 0 {
+		p.Term().Info().Println("No chassis profile in platform.yaml")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "CHASSIS\tOFFER\tNEEDED\tNODES\tATTACH\tORDER\tRETIRE")
+		for _, prof := range r.Profiles {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n", prof.Chassis, prof.Offer, prof.Needed, prof.Nodes, prof.Attach, prof.Order, prof.Retire)
+		}
+		w.Flush()
+	}
+
+	if len(r.Changes) == 0 {
+		p.Term().Success().Println("Nodes match the chassis profiles")
+		return
+	}
+	for _, c := range r.Changes {
+		switch c.Action {
+		case ActionOrder:
+			p.Term().Info().Printfln("  + order %d × %s for %s", c.Count, c.Offer, c.Chassis)
+		case ActionAttach:
+			if c.Reason != "" {
+				p.Term().Info().Printfln("  ~ attach %s to %s: %s", label(c), c.Chassis, c.Reason)
+				continue
+			}
+			p.Term().Info().Printfln("  ~ attach %s to %s", label(c), c.Chassis)
+		case ActionRetire:
+			p.Term().Warning().Printfln("  - retire %s: %s", label(c), c.Reason)
+		case ActionRemove:
+			p.Term().Warning().Printfln("  ! %s: %s", label(c), c.Reason)
+		}
+	}
+}
+
+// label returns the node of a change with its offer and server
+func label(c Change) string {
+	var details []string
+	if c.Server != "" && c.Server != c.Node {
+		details = append(details, c.Server)
+	}
+	if c.Offer != "" {
+		details = append(details, c.Offer)
+	}
+	if len(details) == 0 {
+		return c.Node
+	}
+	return fmt.Sprintf("%s (%s)", c.Node, strings.Join(details, ", "))
+}
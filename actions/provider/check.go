@@ -0,0 +1,233 @@
+// Package provider implements actions checking the metal and DNS providers of platforms
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	metalprovider "github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Kinds of checked providers
+const (
+	kindMetal   = "metal"
+	kindDNS     = "dns"
+	kindReverse = "reverse"
+)
+
+// Check implements the platform:provider:check command
+type Check struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring keyring.Keyring
+	Name    string
+	Format  string
+}
+
+// Result is the check of a provider of the platform
+type Result struct {
+	Kind     string `json:"kind"` // metal, dns or reverse
+	Provider string `json:"provider"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	metalprovider.Check
+
+	Offers []Offer `json:"offers,omitempty"` // Servers of the chassis profiles at the metal provider
+}
+
+// Offer compares the servers of an offer needed by the chassis profiles with the ones of the account
+type Offer struct {
+	Offer   string `json:"offer"`
+	Needed  int    `json:"needed"`
+	Servers int    `json:"servers"`
+}
+
+// Execute runs the platform:provider:check action
+func (c *Check) Execute(ctx context.Context) error {
+	platform, err := schema.Load(c.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", c.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	results := []Result{}
+	if r, ok := c.checkMetal(ctx, platform); ok {
+		results = append(results, r)
+	}
+	if r, ok := c.checkDNS(ctx, platform.DNS); ok {
+		results = append(results, r)
+	}
+
+	failed := slices.ContainsFunc(results, func(r Result) bool { return !r.OK })
+	if strings.ToLower(c.Format) == "json" {
+		output, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(output))
+	} else {
+		c.print(results)
+	}
+	if failed {
+		return fmt.Errorf("provider check failed")
+	}
+	return nil
+}
+
+// checkMetal checks the credentials of the metal provider, or of its reverse DNS provider when it
+// has no metal provider implementation, and compares its servers with the chassis profiles
+func (c *Check) checkMetal(ctx context.Context, platform *schema.Platform) (Result, bool) {
+	infra := platform.Infrastructure
+	if infra.MetalProvider == "" || infra.MetalProvider == metalprovider.ProviderManual {
+		return Result{}, false
+	}
+	r := Result{Kind: kindMetal, Provider: infra.MetalProvider}
+	mp, err := metalprovider.New(infra, metalprovider.Options{Keyring: c.Keyring, Log: c.Log(), Term: c.Term(), Platform: c.Name})
+	if errors.Is(err, metalprovider.ErrUnsupported) {
+		return c.checkReverse(ctx, infra)
+	}
+	if err != nil {
+		return r.fail(err), true
+	}
+
+	if checker, ok := mp.(metalprovider.Checker); ok {
+		if r.Check, err = checker.Check(ctx); err != nil {
+			return r.fail(err), true
+		}
+	}
+	// Listing the servers verifies the read access of providers without checker
+	servers, err := mp.ListServers(ctx)
+	if err != nil {
+		return r.fail(err), true
+	}
+
+	needed := make(map[string]int)
+	for _, profiles := range platform.Chassis {
+		for _, p := range profiles {
+			needed[p.Type] += p.Count
+		}
+	}
+	for _, offer := range slices.Sorted(maps.Keys(needed)) {
+		o := Offer{Offer: offer, Needed: needed[offer]}
+		for _, s := range servers {
+			if strings.EqualFold(s.Offer, offer) {
+				o.Servers++
+			}
+		}
+		r.Offers = append(r.Offers, o)
+	}
+	r.OK = len(r.Missing) == 0
+	return r, true
+}
+
+// checkReverse checks the credentials of the reverse DNS provider of the metal provider, if any
+func (c *Check) checkReverse(ctx context.Context, infra schema.Infrastructure) (Result, bool) {
+	rp, err := dnsprovider.NewReverse(infra, dnsprovider.Options{Keyring: c.Keyring, Log: c.Log(), Term: c.Term(), Platform: c.Name})
+	if errors.Is(err, dnsprovider.ErrReverseUnsupported) {
+		c.Log().Debug("metal provider has no API integration to check", "provider", infra.MetalProvider)
+		return Result{}, false
+	}
+	r := Result{Kind: kindReverse, Provider: infra.MetalProvider}
+	if err != nil {
+		return r.fail(err), true
+	}
+
+	if checker, ok := rp.(metalprovider.Checker); ok {
+		r.Check, err = checker.Check(ctx)
+	} else {
+		// Planning no record reads the addresses of the account
+		_, err = rp.PlanReverse(ctx, nil)
+	}
+	if err != nil {
+		return r.fail(err), true
+	}
+	r.OK = len(r.Missing) == 0
+	return r, true
+}
+
+// checkDNS checks the credentials of the DNS provider
+func (c *Check) checkDNS(ctx context.Context, cfg schema.DNSConfig) (Result, bool) {
+	if cfg.Provider == "" || cfg.Provider == dnsprovider.ProviderManual {
+		return Result{}, false
+	}
+	r := Result{Kind: kindDNS, Provider: cfg.Provider}
+	m := &dns.Manager{WithLogger: c.WithLogger, WithTerm: c.WithTerm, Keyring: c.Keyring, Platform: c.Name}
+	p, err := m.NewProvider(cfg)
+	if err != nil {
+		return r.fail(err), true
+	}
+
+	if checker, ok := p.(metalprovider.Checker); ok {
+		r.Check, err = checker.Check(ctx)
+	} else {
+		// Planning the records reads the zone without changing it
+		var records []dnsprovider.Record
+		if records, err = dnsprovider.Records(cfg); err == nil {
+			_, err = p.PlanChanges(ctx, records)
+		}
+	}
+	if err != nil {
+		return r.fail(err), true
+	}
+	r.OK = len(r.Missing) == 0
+	return r, true
+}
+
+// fail marks the check as failed with the error
+func (r Result) fail(err error) Result {
+	r.OK = false
+	r.Error = err.Error()
+	return r
+}
+
+// print shows the results on the terminal
+func (c *Check) print(results []Result) {
+	if len(results) == 0 {
+		c.Term().Info().Printfln("Platform %q has no provider with API to check", c.Name)
+		return
+	}
+	for _, r := range results {
+		label := fmt.Sprintf("%s provider %s", r.Kind, r.Provider)
+		switch {
+		case r.Error != "":
+			c.Term().Error().Printfln("✗ %s: %s", label, r.Error)
+		case !r.OK:
+			c.Term().Error().Printfln("✗ %s: missing permissions %s", label, strings.Join(r.Missing, ", "))
+		case r.Account != "":
+			c.Term().Success().Printfln("✓ %s: %s", label, r.Account)
+		default:
+			c.Term().Success().Printfln("✓ %s", label)
+		}
+		if len(r.Scopes) > 0 {
+			c.Term().Info().Printfln("    Permissions: %s", strings.Join(r.Scopes, ", "))
+		}
+		for _, q := range r.Quotas {
+			if q.Limit < 0 {
+				c.Term().Info().Printfln("    %s: %d used", q.Resource, q.Used)
+				continue
+			}
+			c.Term().Info().Printfln("    %s: %d of %d used, %d left", q.Resource, q.Used, q.Limit, q.Remaining())
+		}
+		for _, o := range r.Offers {
+			if o.Servers < o.Needed {
+				c.Term().Warning().Printfln("    ! %s: %d server(s) for %d in the chassis profiles", o.Offer, o.Servers, o.Needed)
+				continue
+			}
+			c.Term().Info().Printfln("    %s: %d server(s) for %d in the chassis profiles", o.Offer, o.Servers, o.Needed)
+		}
+	}
+}
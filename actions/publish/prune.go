@@ -0,0 +1,83 @@
+package publish
+
+import (
+	"context"
+	"errors"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/publish"
+)
+
+// Prune implements the platform:artifact:prune command
+type Prune struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+	Config  launchr.Config
+
+	Platform    string // Platform of the pruned artifacts, all the artifacts of the repository if empty
+	URL         string // Repository URL overriding the config, http backend
+	Credentials string // Source of the credentials of the http backend
+	Keep        int    // Newest artifacts kept in each directory, overriding the retention config if positive
+	MaxAge      string // Artifacts younger than this are kept too, overriding the retention config if set
+	DryRun      bool
+}
+
+// Execute runs the platform:artifact:prune action
+func (p *Prune) Execute(ctx context.Context) error {
+	if p.Keep < 0 {
+		return errors.New("--keep must not be negative")
+	}
+	cfg, err := loadConfig(p.Config, p.Platform, p.URL)
+	if err != nil {
+		return err
+	}
+	retention := cfg.Retention
+	if p.Keep > 0 {
+		retention.Keep = p.Keep
+	}
+	if p.MaxAge != "" {
+		retention.MaxAge = p.MaxAge
+	}
+	if retention.Keep == 0 {
+		return errors.New("no retention policy, pass --keep or set platform.publish.retention.keep")
+	}
+
+	pub := &publish.Publisher{
+		WithLogger:  p.WithLogger,
+		WithTerm:    p.WithTerm,
+		Keyring:     p.Keyring,
+		Config:      cfg,
+		Platform:    p.Platform,
+		Credentials: p.Credentials,
+	}
+	return prune(ctx, pub, retention, p.DryRun)
+}
+
+// prune deletes the published artifacts beyond the retention policy and reports them
+func prune(ctx context.Context, pub *publish.Publisher, retention publish.Retention, dryRun bool) error {
+	pruned, err := pub.Prune(ctx, retention, dryRun)
+	var reclaimed int64
+	for _, o := range pruned {
+		reclaimed += o.Size
+		if dryRun {
+			pub.Term().Printfln("Would remove %s (%s)", o.Path, pi.FormatSize(o.Size))
+		} else {
+			pub.Term().Printfln("Removed %s (%s)", o.Path, pi.FormatSize(o.Size))
+		}
+	}
+	switch {
+	case err != nil:
+		return err
+	case len(pruned) == 0:
+		pub.Term().Info().Printfln("Nothing to prune, the newest %d artifact(s) of each directory are kept", retention.Keep)
+	case dryRun:
+		pub.Term().Info().Printfln("Would remove %d published file(s), reclaiming %s", len(pruned), pi.FormatSize(reclaimed))
+	default:
+		pub.Term().Success().Printfln("Removed %d published file(s), reclaimed %s", len(pruned), pi.FormatSize(reclaimed))
+	}
+	return nil
+}
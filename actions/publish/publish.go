@@ -0,0 +1,101 @@
+// Package publish implements the platform:publish action
+package publish
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/publish"
+)
+
+// Publish implements the platform:publish command
+type Publish struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+	Config  launchr.Config
+
+	Image       string // Artifact to publish, default is the Platform Image of the current commit
+	Platform    string // Platform of the publish config and the keyring credentials, the shared ones if empty
+	URL         string // Repository URL overriding the config, http backend
+	Path        string // Artifact path template overriding the config
+	Credentials string // Source of the credentials of the http backend
+	Verify      bool   // Download the published files back and check their checksum
+	PruneKeep   int    // Prune the published artifacts beyond the newest ones after publishing, if positive
+}
+
+// Execute runs the platform:publish action
+func (p *Publish) Execute(ctx context.Context) error {
+	image, err := p.image()
+	if err != nil {
+		return err
+	}
+	if p.PruneKeep < 0 {
+		return fmt.Errorf("--prune-keep must not be negative")
+	}
+	cfg, err := loadConfig(p.Config, p.Platform, p.URL)
+	if err != nil {
+		return err
+	}
+	if p.Path != "" {
+		if err = publish.ValidatePath(p.Path); err != nil {
+			return err
+		}
+		cfg.Path = p.Path
+	}
+
+	pub := &publish.Publisher{
+		WithLogger:  p.WithLogger,
+		WithTerm:    p.WithTerm,
+		Keyring:     p.Keyring,
+		Config:      cfg,
+		Platform:    p.Platform,
+		Credentials: p.Credentials,
+		Verify:      p.Verify,
+	}
+	if _, err = pub.Publish(ctx, image); err != nil || p.PruneKeep == 0 {
+		return err
+	}
+	retention := cfg.Retention
+	retention.Keep = p.PruneKeep
+	return prune(ctx, pub, retention, false)
+}
+
+// loadConfig loads the publish config of the platform, with the repository URL overridden if set
+func loadConfig(cfg launchr.Config, platform, url string) (publish.Config, error) {
+	c, err := publish.LoadPlatformConfig(cfg, platform)
+	if err != nil || url == "" {
+		return c, err
+	}
+	if c.Backend != publish.BackendHTTP {
+		return c, fmt.Errorf("--url only applies to the %s backend, the configured one is %s", publish.BackendHTTP, c.Backend)
+	}
+	c.URL, c.FallbackURLs = url, nil
+	return c, nil
+}
+
+// image returns the artifact to publish, the newest Platform Image built from the current commit by
+// default
+func (p *Publish) image() (string, error) {
+	if p.Image != "" {
+		if _, err := os.Stat(p.Image); err != nil {
+			return "", fmt.Errorf("artifact not found: %w", err)
+		}
+		return p.Image, nil
+	}
+	images, err := pi.ListImages()
+	if err != nil {
+		return "", err
+	}
+	for _, img := range images {
+		if img.Source == pi.SourceLocal && img.Current {
+			return img.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no Platform Image of the current commit found in %s/, run platform:package first", pi.ImageDir)
+}
@@ -0,0 +1,60 @@
+// Package secret implements actions managing the secrets of platforms in their backend
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/secrets"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Get implements the platform:secret:get command
+type Get struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+
+	Name string
+	Key  string
+}
+
+// Execute runs the platform:secret:get action
+func (g *Get) Execute(ctx context.Context) error {
+	b, err := newBackend(g.Name, g.Keyring, g.Log(), g.Term())
+	if err != nil {
+		return err
+	}
+	value, err := b.Get(ctx, g.Key)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return fmt.Errorf("secret %q of %s not found in the %s", g.Key, g.Name, b.Name())
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// newBackend loads the platform and returns its secrets backend
+func newBackend(name string, k keyring.Keyring, log *launchr.Logger, term *launchr.Terminal) (secrets.Backend, error) {
+	platform, err := loadPlatform(name)
+	if err != nil {
+		return nil, err
+	}
+	return secrets.New(platform, k, log, term)
+}
+
+// loadPlatform loads the platform.yaml of a platform
+func loadPlatform(name string) (*schema.Platform, error) {
+	platform, err := schema.Load(name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return nil, failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", name)
+	}
+	return platform, err
+}
@@ -0,0 +1,108 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Entry is a secret of a platform, stored in its backend or expected by its configuration
+type Entry struct {
+	Key    string   `json:"key" yaml:"key"`
+	Stored bool     `json:"stored" yaml:"stored"`                   // Stored in the backend
+	Files  []string `json:"files,omitempty" yaml:"files,omitempty"` // Config files holding the key as a vault value
+}
+
+// List implements the platform:secret:list command
+type List struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+
+	Name   string
+	Format string
+}
+
+// Execute runs the platform:secret:list action
+func (l *List) Execute(ctx context.Context) error {
+	if _, err := output.Parse(l.Format); err != nil {
+		return err
+	}
+	b, err := newBackend(l.Name, l.Keyring, l.Log(), l.Term())
+	if err != nil {
+		return err
+	}
+	keys, err := b.List(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := schema.LoadConfig(l.Name)
+	if err != nil {
+		return err
+	}
+
+	entries := map[string]*Entry{}
+	for _, k := range keys {
+		entries[k] = &Entry{Key: k, Stored: true}
+	}
+	for _, v := range config.Values {
+		if !v.Vault {
+			continue
+		}
+		e, ok := entries[v.Key]
+		if !ok {
+			e = &Entry{Key: v.Key}
+			entries[v.Key] = e
+		}
+		e.Files = append(e.Files, v.File)
+	}
+	list := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+
+	return output.Render(os.Stdout, l.Format, list, func(bool) error {
+		if len(list) == 0 {
+			l.Term().Info().Printfln("No secrets of %s in the %s", l.Name, b.Name())
+			return nil
+		}
+		printEntries(os.Stdout, list, schema.ConfigDir(l.Name))
+		return nil
+	})
+}
+
+// printEntries prints the secrets as a table, the config files relative to the config directory
+func printEntries(out io.Writer, list []Entry, dir string) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTORED\tCONFIG")
+	for _, e := range list {
+		stored := "no"
+		if e.Stored {
+			stored = "yes"
+		}
+		files := make([]string, len(e.Files))
+		for i, f := range e.Files {
+			if rel, err := filepath.Rel(dir, f); err == nil {
+				f = rel
+			}
+			files[i] = f
+		}
+		config := strings.Join(files, ", ")
+		if config == "" {
+			config = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Key, stored, config)
+	}
+	w.Flush()
+}
@@ -0,0 +1,88 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/internal/secrets"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/secretgen"
+)
+
+// Set implements the platform:secret:set command
+type Set struct {
+	action.WithLogger
+	action.WithTerm
+	Keyring keyring.Keyring
+
+	Name     string
+	Key      string
+	Value    string // Requested on the terminal if empty
+	Generate bool   // Generate the value with the policy of the key
+	Force    bool   // Replace an existing secret with the generated value
+}
+
+// Execute runs the platform:secret:set action
+func (s *Set) Execute(ctx context.Context) error {
+	if s.Generate && s.Value != "" {
+		return errors.New("the value can't be passed with --generate")
+	}
+	platform, err := loadPlatform(s.Name)
+	if err != nil {
+		return err
+	}
+	b, err := secrets.New(platform, s.Keyring, s.Log(), s.Term())
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.Generate:
+		err = s.generate(ctx, b, platform.Secrets)
+	case s.Value == "":
+		_, err = secrets.Request(ctx, b, s.Term(), s.Key)
+	default:
+		err = b.Set(ctx, s.Key, s.Value)
+	}
+	if err != nil {
+		return err
+	}
+	s.Term().Success().Printfln("Stored secret %q of %s in the %s", s.Key, s.Name, b.Name())
+	return nil
+}
+
+// generate stores a secret generated with its policy. An existing secret is only replaced with
+// --force, e.g. a generated vault password would no longer decrypt the vaulted files.
+func (s *Set) generate(ctx context.Context, b secrets.Backend, cfg schema.SecretsConfig) error {
+	if !s.Force {
+		_, err := b.Get(ctx, s.Key)
+		if err == nil {
+			return fmt.Errorf("secret %q of %s already exists, pass --force to replace it", s.Key, s.Name)
+		}
+		if !errors.Is(err, secrets.ErrNotFound) {
+			return err
+		}
+	}
+
+	policy, ok := cfg.Policy(s.Key)
+	if !ok {
+		s.Log().Debug("no generation policy for the secret in platform.yaml, using the default one", "key", s.Key)
+	}
+	value, err := secretgen.Generate(policy)
+	if err != nil {
+		return fmt.Errorf("invalid generation policy of secret %q of %s: %w", s.Key, s.Name, err)
+	}
+	redact.Add(value)
+	if err = b.Set(ctx, s.Key, value); err != nil {
+		return err
+	}
+	format := policy.Format
+	if format == "" {
+		format = secretgen.FormatPassword
+	}
+	s.Term().Info().Printfln("Generated %s secret %q, read it with platform:secret:get", format, s.Key)
+	return nil
+}
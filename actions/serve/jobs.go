@@ -0,0 +1,134 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+)
+
+// maxJobs is the number of deployments kept by the server, the oldest completed ones are dropped
+const maxJobs = 100
+
+// Statuses of the deployments triggered from the API
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is a deployment triggered from the API
+type Job struct {
+	ID         string               `json:"id"`
+	Platform   string               `json:"platform"`
+	Tags       string               `json:"tags"`
+	Check      bool                 `json:"check,omitempty"`
+	Status     string               `json:"status"`
+	Started    time.Time            `json:"started"`
+	Finished   *time.Time           `json:"finished,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	Code       failure.Code         `json:"code,omitempty"`
+	Deployment *platform.Deployment `json:"deployment,omitempty"` // Recorded deployment, nil for a check run
+}
+
+// deployFunc runs a deployment, see [platform.Client.Deploy]
+type deployFunc func(context.Context, platform.DeployOptions) (*platform.Deployment, error)
+
+// jobs runs the deployments one at a time, they share the prepared platform and the terminal
+type jobs struct {
+	ctx  context.Context
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	seq  int
+	jobs []*Job // Oldest first
+}
+
+// newJobs creates the deployments, they are cancelled with ctx
+func newJobs(ctx context.Context) *jobs {
+	return &jobs{ctx: ctx}
+}
+
+// start runs a deployment in the background and returns its job, the error reports a running one
+func (j *jobs) start(opts platform.DeployOptions, deploy deployFunc) (Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.ctx.Err() != nil {
+		return Job{}, errors.New("the server is stopping")
+	}
+	for _, job := range j.jobs {
+		if job.Status == StatusRunning {
+			return Job{}, fmt.Errorf("deployment %s of %s is running", job.ID, job.Platform)
+		}
+	}
+
+	j.seq++
+	job := &Job{
+		ID:       strconv.Itoa(j.seq),
+		Platform: opts.Name,
+		Tags:     opts.Tags,
+		Check:    opts.Check,
+		Status:   StatusRunning,
+		Started:  time.Now().UTC(),
+	}
+	j.jobs = append(j.jobs, job)
+	if len(j.jobs) > maxJobs {
+		j.jobs = slices.Delete(j.jobs, 0, len(j.jobs)-maxJobs)
+	}
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		d, err := deploy(j.ctx, opts)
+		j.finish(job, d, err)
+	}()
+	return *job, nil
+}
+
+// finish records the result of a deployment
+func (j *jobs) finish(job *Job, d *platform.Deployment, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	finished := time.Now().UTC()
+	job.Finished = &finished
+	job.Deployment = d
+	job.Status = StatusSucceeded
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.Code = failure.CodeOf(err)
+	}
+}
+
+// list returns the deployments, the most recent first
+func (j *jobs) list() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	list := make([]Job, 0, len(j.jobs))
+	for i := len(j.jobs) - 1; i >= 0; i-- {
+		list = append(list, *j.jobs[i])
+	}
+	return list
+}
+
+// get returns a deployment by ID
+func (j *jobs) get(id string) (Job, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, job := range j.jobs {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// wait waits for the running deployment
+func (j *jobs) wait() {
+	j.wg.Wait()
+}
@@ -0,0 +1,306 @@
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/health"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// defaultHistoryLimit is the number of deployments of the history endpoint without limit
+const defaultHistoryLimit = 10
+
+// server handles the requests of the API with the Go API of the platform operations
+type server struct {
+	client      *platform.Client
+	token       string
+	allowDeploy bool
+	jobs        *jobs
+	log         *launchr.Logger
+}
+
+// newServer creates the server, its deployments are cancelled with ctx
+func newServer(ctx context.Context, client *platform.Client, token string, allowDeploy bool, log *launchr.Logger) *server {
+	return &server{client: client, token: token, allowDeploy: allowDeploy, jobs: newJobs(ctx), log: log}
+}
+
+// routes returns the handler of the endpoints, all but /healthz need the token
+func (s *server) routes() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /v1/platforms", s.list)
+	api.HandleFunc("GET /v1/platforms/{name}", s.show)
+	api.HandleFunc("GET /v1/platforms/{name}/status", s.status)
+	api.HandleFunc("GET /v1/platforms/{name}/validation", s.validation)
+	api.HandleFunc("GET /v1/platforms/{name}/history", s.history)
+	api.HandleFunc("POST /v1/platforms/{name}/deployments", s.deploy)
+	api.HandleFunc("GET /v1/deployments", s.deployments)
+	api.HandleFunc("GET /v1/deployments/{id}", s.deployment)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("/", s.authorize(api))
+	return mux
+}
+
+// authorize rejects the requests without the bearer token of the server
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="plasmactl"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		s.log.Debug("API request", "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// list returns the platforms, filtered and sorted like platform:list
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	platforms, err := s.client.List(platform.ListOptions{Filter: q.Get("filter"), Sort: q.Get("sort")})
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if platforms == nil {
+		platforms = []schema.PlatformInfo{}
+	}
+	writeJSON(w, http.StatusOK, platforms)
+}
+
+// show returns the configuration, the nodes and the last deployment of a platform
+func (s *server) show(w http.ResponseWriter, r *http.Request) {
+	name, ok := platformName(w, r)
+	if !ok {
+		return
+	}
+	details, err := s.client.Show(name)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+// status returns the reachability of the nodes of a platform, like platform:status
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	name, ok := platformName(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	sshPort, err := intParam(q.Get("ssh_port"), health.DefaultSSHPort)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts, err := health.ParseOptions(q.Get("http"), sshPort, q.Get("timeout"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = schema.Load(name); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	nodes, err := schema.LoadNodes(name)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	results := health.Check(r.Context(), nodes, opts)
+	if results == nil {
+		results = []health.Result{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// validation returns the report of a validation of a platform, the checks of the comma-separated skip
+// parameter are skipped, e.g. skip=infra,tls
+func (s *server) validation(w http.ResponseWriter, r *http.Request) {
+	name, ok := platformName(w, r)
+	if !ok {
+		return
+	}
+	opts := platform.ValidateOptions{Name: name}
+	for _, check := range strings.Split(r.URL.Query().Get("skip"), ",") {
+		switch strings.TrimSpace(check) {
+		case "":
+		case "dns":
+			opts.SkipDNS = true
+		case "mail":
+			opts.SkipMail = true
+		case "infra":
+			opts.SkipInfra = true
+		case "mta-sts":
+			opts.SkipMTASTS = true
+		case "tls-rpt":
+			opts.SkipTLSRPT = true
+		case "dnssec":
+			opts.SkipDNSSEC = true
+		case "tls":
+			opts.SkipTLS = true
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown check %q (use dns, mail, infra, mta-sts, tls-rpt, dnssec or tls)", check))
+			return
+		}
+	}
+	report, err := s.client.Validate(r.Context(), opts)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// history returns the last deployments of a platform, the most recent first
+func (s *server) history(w http.ResponseWriter, r *http.Request) {
+	name, ok := platformName(w, r)
+	if !ok {
+		return
+	}
+	limit, err := intParam(r.URL.Query().Get("limit"), defaultHistoryLimit)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q, use 0 for all deployments", r.URL.Query().Get("limit")))
+		return
+	}
+	if _, err = schema.Load(name); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	deployments, err := history.Journal("", name, limit)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if deployments == nil {
+		deployments = []history.Deployment{}
+	}
+	writeJSON(w, http.StatusOK, deployments)
+}
+
+// deployRequest is the body of the requests triggering a deployment
+type deployRequest struct {
+	Tags  string `json:"tags"`
+	Img   string `json:"img,omitempty"`
+	Limit string `json:"limit,omitempty"`
+	Check bool   `json:"check,omitempty"`
+}
+
+// deploy starts a deployment of a platform and returns its job, the deployment runs in the background
+func (s *server) deploy(w http.ResponseWriter, r *http.Request) {
+	if !s.allowDeploy {
+		writeError(w, http.StatusForbidden, errors.New("deployments are disabled, start the server with --allow-deploy"))
+		return
+	}
+	name, ok := platformName(w, r)
+	if !ok {
+		return
+	}
+	var req deployRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if req.Tags == "" {
+		writeError(w, http.StatusBadRequest, errors.New("tags are required"))
+		return
+	}
+	if _, err := schema.Load(name); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	opts := platform.DeployOptions{Name: name, Tags: req.Tags, Img: req.Img, Limit: req.Limit, Check: req.Check}
+	j, err := s.jobs.start(opts, s.client.Deploy)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.log.Info("deployment started from the API", "platform", name, "tags", req.Tags, "job", j.ID)
+	w.Header().Set("Location", "/v1/deployments/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+// deployments returns the deployments triggered since the server started, the most recent first
+func (s *server) deployments(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.list())
+}
+
+// deployment returns a deployment triggered since the server started
+func (s *server) deployment(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("deployment %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+// platformName returns the platform of the request path, a bad request if the name is invalid. The
+// name is decoded from the path, e.g. ..%2F is ../, it must not leave inst/.
+func platformName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if err := schema.ValidateName(name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return name, true
+}
+
+// intParam parses an integer query parameter, def if empty
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// errorBody is the body of the failed requests
+type errorBody struct {
+	Error string       `json:"error"`
+	Code  failure.Code `json:"code,omitempty"` // Class of the failure, see the error codes
+	Hint  string       `json:"hint,omitempty"`
+}
+
+// writeFailure writes a failed operation with the status of its class
+func writeFailure(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch failure.CodeOf(err) {
+	case failure.CodePlatformNotFound:
+		status = http.StatusNotFound
+	case failure.CodeConfigMissing:
+		status = http.StatusUnprocessableEntity
+	case failure.CodeProviderAuth:
+		status = http.StatusBadGateway
+	}
+	writeError(w, status, err)
+}
+
+// writeError writes an error with its class, if any
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error(), Code: failure.CodeOf(err), Hint: failure.HintOf(err)})
+}
+
+// writeJSON writes the JSON of a value, like the json output of the actions
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,80 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+)
+
+func TestRoutes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "inst", "dev"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "inst", "dev", "platform.yaml"), []byte("name: dev\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	log := launchr.Log()
+	s := newServer(context.Background(), platform.New(platform.Options{Log: log, Term: launchr.Term()}), "token", false, log)
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"health without token", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"missing token", http.MethodGet, "/v1/platforms", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodGet, "/v1/platforms", "other", http.StatusUnauthorized},
+		{"list", http.MethodGet, "/v1/platforms", "token", http.StatusOK},
+		{"show", http.MethodGet, "/v1/platforms/dev", "token", http.StatusOK},
+		{"unknown platform", http.MethodGet, "/v1/platforms/prod", "token", http.StatusNotFound},
+		{"encoded parent path", http.MethodGet, "/v1/platforms/..%2F..%2Fetc", "token", http.StatusBadRequest},
+		{"encoded parent history", http.MethodGet, "/v1/platforms/..%2Finst%2Fdev/history", "token", http.StatusBadRequest},
+		{"encoded parent deploy", http.MethodPost, "/v1/platforms/..%2Fdev/deployments", "token", http.StatusForbidden},
+		{"deploy disabled", http.MethodPost, "/v1/platforms/dev/deployments", "token", http.StatusForbidden},
+		{"unknown deployment", http.MethodGet, "/v1/deployments/1", "token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+
+	s.allowDeploy = true
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/platforms/..%2Fdev/deployments", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("deploy of an encoded parent path = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,106 @@
+// Package serve implements the platform:serve command exposing the platform operations over a local
+// REST API, for dashboards and ChatOps bots
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+)
+
+// Defaults of the server
+const (
+	DefaultListen   = "127.0.0.1:8686"
+	DefaultTokenKey = "platform_serve_token"
+)
+
+// shutdownTimeout is the time given to the running requests once the server is stopped
+const shutdownTimeout = 10 * time.Second
+
+// EnvVarToken is the token of the API, it takes precedence over the keyring
+const EnvVarToken = launchr.EnvVar("serve_token")
+
+// Serve implements the platform:serve command
+type Serve struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring     keyring.Keyring
+	Listen      string // Address of the server, default is DefaultListen
+	TokenKey    string // Keyring key of the token, default is DefaultTokenKey
+	AllowDeploy bool   // Enable the endpoint triggering deployments
+}
+
+// Execute runs the platform:serve action until it's interrupted
+func (s *Serve) Execute(ctx context.Context) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	listen := s.Listen
+	if listen == "" {
+		listen = DefaultListen
+	}
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := newServer(ctx, platform.New(platform.Options{Keyring: s.Keyring, Log: s.Log(), Term: s.Term()}), token, s.AllowDeploy, s.Log())
+	httpSrv := &http.Server{Handler: srv.routes(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	s.Term().Success().Printfln("Serving the platform API on http://%s", ln.Addr())
+	if !s.AllowDeploy {
+		s.Term().Info().Println("Deployments are disabled, enable them with --allow-deploy")
+	}
+	err = httpSrv.Serve(ln)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// The deployments stop with the server, let them record their result
+	srv.jobs.wait()
+	s.Term().Info().Println("Server stopped")
+	return nil
+}
+
+// token returns the token of the API from the environment or the keyring, requesting it on the
+// terminal if missing
+func (s *Serve) token() (string, error) {
+	token := EnvVarToken.Get()
+	if token == "" {
+		key := s.TokenKey
+		if key == "" {
+			key = DefaultTokenKey
+		}
+		var err error
+		if token, err = api.Token(s.Keyring, s.Log(), s.Term(), key); err != nil {
+			return "", err
+		}
+	}
+	if token == "" {
+		return "", errors.New("the token of the API must not be empty")
+	}
+	redact.Add(token)
+	return token, nil
+}
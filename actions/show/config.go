@@ -0,0 +1,71 @@
+package show
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// configInfo is the summary of the configuration of a platform
+type configInfo struct {
+	ValueCount int                  `yaml:"value_count"`
+	VaultKeys  int                  `yaml:"vault_keys"`
+	Encrypted  []string             `yaml:"encrypted,omitempty" json:",omitempty"` // Files encrypted as a whole
+	Modified   time.Time            `yaml:"modified"`
+	Values     []schema.ConfigValue `yaml:"values,omitempty" json:",omitempty"` // Non-secret values, with --config
+}
+
+// loadConfig returns the summary of the configuration of the platform, nil if it has none
+func (s *Show) loadConfig() (*configInfo, error) {
+	config, err := schema.LoadConfig(s.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Values) == 0 && len(config.Encrypted) == 0 {
+		return nil, nil
+	}
+	info := &configInfo{
+		ValueCount: len(config.Values),
+		VaultKeys:  config.VaultKeys(),
+		Encrypted:  config.Encrypted,
+		Modified:   config.Modified,
+	}
+	if s.Config {
+		for _, v := range config.Values {
+			if !v.Vault {
+				info.Values = append(info.Values, v)
+			}
+		}
+	}
+	return info, nil
+}
+
+// printConfig writes the configuration section, and the table of the non-secret values if loaded. Files
+// are relative to the configuration directory.
+func printConfig(out io.Writer, info *configInfo, dir string) {
+	fmt.Fprintf(out, "Config:    %d values, %d in vault, modified %s\n", info.ValueCount, info.VaultKeys, info.Modified.Local().Format(time.DateTime))
+	for _, f := range info.Encrypted {
+		fmt.Fprintf(out, "  - %s encrypted\n", relPath(dir, f))
+	}
+	if len(info.Values) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tFILE")
+	for _, v := range info.Values {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, relPath(dir, v.File))
+	}
+	w.Flush()
+}
+
+// relPath returns a path relative to a directory, the path itself if it isn't under it
+func relPath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,66 @@
+package show
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// loadNodes reads the node files of the nodes directory, sorted by file name. Node files which can't be
+// parsed are kept with the hostname of their file name, so they're still listed.
+func (s *Show) loadNodes(dir string) ([]*schema.Node, error) {
+	files, err := schema.NodeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*schema.Node, 0, len(files))
+	for _, f := range files {
+		node, err := schema.LoadNode(f)
+		if err != nil {
+			s.Log.Warn("Failed to load node", "file", f, "error", err)
+			node = &schema.Node{File: f}
+		}
+		if node.Hostname == "" {
+			node.Hostname = strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// printNodes writes the table of the nodes with their addresses, chassis, roles and capabilities, and
+// their offer, location and groups when wide
+func printNodes(out io.Writer, nodes []*schema.Node, wide bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	if wide {
+		fmt.Fprintln(w, "HOSTNAME\tPUBLIC IPS\tPRIVATE IPS\tCHASSIS\tROLES\tCAPABILITIES\tOFFER\tLOCATION\tGROUPS")
+	} else {
+		fmt.Fprintln(w, "HOSTNAME\tPUBLIC IPS\tPRIVATE IPS\tCHASSIS\tROLES\tCAPABILITIES")
+	}
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s", n.Hostname, joinOrDash(n.PublicIPs), joinOrDash(n.PrivateIPs),
+			orDash(n.Chassis), joinOrDash(n.Roles), joinOrDash(n.Capabilities))
+		if wide {
+			fmt.Fprintf(w, "\t%s\t%s\t%s", orDash(n.Offer), orDash(n.Location), joinOrDash(n.Groups))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// joinOrDash joins values with commas, a dash when there are none
+func joinOrDash(values []string) string {
+	return orDash(strings.Join(values, ","))
+}
+
+// orDash returns the value, a dash when empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
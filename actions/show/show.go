@@ -1,15 +1,19 @@
 package show
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/health"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
 	"github.com/plasmash/plasmactl-platform/pkg/schema"
-	"gopkg.in/yaml.v3"
 )
 
 // Show implements the platform:show command
@@ -18,67 +22,92 @@ type Show struct {
 	Term   *launchr.Terminal
 	Name   string
 	Format string
+
+	Health  bool // Probe the reachability of the nodes
+	HTTP    string
+	SSHPort int
+	Timeout string
+
+	NodesOnly bool // Print only the nodes
+	Config    bool // Print the non-secret configuration values
+	History   int  // Number of the last deployments shown
+
+	summary *result.PlatformSummary
 }
 
 func (s *Show) SetLogger(log *launchr.Logger) { s.Log = log }
 func (s *Show) SetTerm(term *launchr.Terminal) { s.Term = term }
 
-func (s *Show) Execute() error {
-	instDir := filepath.Join("inst", s.Name)
-	platformFile := filepath.Join(instDir, "platform.yaml")
-
-	// Check if platform exists
-	if _, err := os.Stat(platformFile); os.IsNotExist(err) {
-		return fmt.Errorf("platform %q not found (no platform.yaml at %s)", s.Name, platformFile)
+func (s *Show) Execute(ctx context.Context) error {
+	if _, err := output.Parse(s.Format); err != nil {
+		return err
 	}
+	instDir := schema.PlatformDir(s.Name)
 
-	// Read platform.yaml
-	data, err := os.ReadFile(platformFile)
+	platform, err := schema.Load(s.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found (no platform.yaml at %s)", s.Name, schema.PlatformFile(s.Name))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read platform.yaml: %w", err)
+		return err
 	}
 
-	var platform schema.Platform
-	if err := yaml.Unmarshal(data, &platform); err != nil {
-		return fmt.Errorf("failed to parse platform.yaml: %w", err)
+	// Load the nodes
+	nodes, err := s.loadNodes(filepath.Join(instDir, schema.NodesDirName))
+	if err != nil {
+		return err
+	}
+	s.summary = &result.PlatformSummary{
+		Name:          platform.Name,
+		Domain:        platform.DNS.Domain,
+		MetalProvider: platform.Infrastructure.MetalProvider,
+		DNSProvider:   platform.DNS.Provider,
+		Nodes:         len(nodes),
 	}
 
-	// Count and list nodes
-	nodesDir := filepath.Join(instDir, "nodes")
-	var nodes []string
-	if nodeEntries, err := os.ReadDir(nodesDir); err == nil {
-		for _, nodeEntry := range nodeEntries {
-			if !nodeEntry.IsDir() && filepath.Ext(nodeEntry.Name()) == ".yaml" && nodeEntry.Name() != ".gitkeep" {
-				nodes = append(nodes, nodeEntry.Name()[:len(nodeEntry.Name())-5]) // Remove .yaml extension
-			}
+	var results []health.Result
+	if s.Health {
+		opts, err := health.ParseOptions(s.HTTP, s.SSHPort, s.Timeout)
+		if err != nil {
+			return err
 		}
+		results = health.Check(ctx, nodes, opts)
 	}
 
-	// Output based on format
-	switch strings.ToLower(s.Format) {
-	case "json":
-		output := map[string]interface{}{
-			"platform": platform,
-			"nodes":    nodes,
+	data := map[string]interface{}{
+		"nodes": nodes,
+	}
+	var config *configInfo
+	var deployments []history.Deployment
+	if !s.NodesOnly {
+		data["platform"] = platform
+		if config, err = s.loadConfig(); err != nil {
+			return err
 		}
-		jsonData, err := json.MarshalIndent(output, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+		if config != nil {
+			data["config"] = config
 		}
-		fmt.Println(string(jsonData))
-
-	case "yaml":
-		output := map[string]interface{}{
-			"platform": platform,
-			"nodes":    nodes,
+		if s.History > 0 {
+			if deployments, err = history.Journal("", s.Name, s.History); err != nil {
+				return err
+			}
+			if len(deployments) > 0 {
+				data["history"] = deployments
+			}
 		}
-		yamlData, err := yaml.Marshal(output)
-		if err != nil {
-			return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if s.Health {
+		data["health"] = results
+	}
+	return output.Render(os.Stdout, s.Format, data, func(wide bool) error {
+		if s.NodesOnly {
+			printNodes(os.Stdout, nodes, wide)
+			if len(results) > 0 {
+				fmt.Println()
+				health.Print(os.Stdout, results, s.HTTP != "")
+			}
+			return nil
 		}
-		fmt.Println(string(yamlData))
-
-	default: // human-readable sections
 		fmt.Printf("Name:      %s\n", platform.Name)
 		fmt.Printf("Domain:    %s\n", platform.DNS.Domain)
 		fmt.Printf("Provider:  %s\n", platform.Infrastructure.MetalProvider)
@@ -93,9 +122,11 @@ func (s *Show) Execute() error {
 		}
 		fmt.Printf("Nodes:     %d\n", len(nodes))
 		if len(nodes) > 0 {
-			for _, node := range nodes {
-				fmt.Printf("  - %s\n", node)
-			}
+			printNodes(os.Stdout, nodes, wide)
+		}
+		if len(results) > 0 {
+			fmt.Println("Health:")
+			health.Print(os.Stdout, results, s.HTTP != "")
 		}
 		if len(platform.Chassis) > 0 {
 			fmt.Println("Chassis:")
@@ -105,7 +136,18 @@ func (s *Show) Execute() error {
 				}
 			}
 		}
-	}
+		if config != nil {
+			printConfig(os.Stdout, config, schema.ConfigDir(s.Name))
+		}
+		if len(deployments) > 0 {
+			fmt.Println("History:")
+			history.Print(os.Stdout, deployments, wide)
+		}
+		return nil
+	})
+}
 
-	return nil
+// Result returns the summary of the shown platform for the callers of the action, nil if it wasn't found
+func (s *Show) Result() *result.PlatformSummary {
+	return s.summary
 }
@@ -0,0 +1,77 @@
+// Package status implements the platform:status command reporting the reachability of the nodes
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/health"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Status implements the platform:status command
+type Status struct {
+	action.WithLogger
+	action.WithTerm
+
+	Name    string
+	Format  string
+	HTTP    string
+	SSHPort int
+	Timeout string
+}
+
+// Execute runs the platform:status action
+func (s *Status) Execute(ctx context.Context) error {
+	if _, err := output.Parse(s.Format); err != nil {
+		return err
+	}
+	if _, err := schema.Load(s.Name); errors.Is(err, schema.ErrNotFound) {
+		return failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", s.Name)
+	} else if err != nil {
+		return err
+	}
+	opts, err := health.ParseOptions(s.HTTP, s.SSHPort, s.Timeout)
+	if err != nil {
+		return err
+	}
+	nodes, err := schema.LoadNodes(s.Name)
+	if err != nil {
+		return err
+	}
+
+	results := health.Check(ctx, nodes, opts)
+	if results == nil {
+		results = []health.Result{}
+	}
+	down := 0
+	for _, r := range results {
+		if r.Status == health.StatusDown {
+			down++
+		}
+	}
+
+	err = output.Render(os.Stdout, s.Format, results, func(bool) error {
+		if len(results) == 0 {
+			s.Term().Warning().Printfln("Platform %q has no nodes in %s", s.Name, schema.NodesDir(s.Name))
+			return nil
+		}
+		health.Print(os.Stdout, results, opts.HTTP != "")
+		if down == 0 {
+			s.Term().Success().Printfln("%d node(s) reachable", len(results))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if down > 0 {
+		return fmt.Errorf("%d of %d node(s) down", down, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package up
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"gopkg.in/yaml.v3"
+)
+
+// containerizedActions need the ansible/python toolchain and run in the toolchain image with --containerized
+var containerizedActions = []string{"model:compose", "model:prepare", "component:sync", "platform:deploy"}
+
+// toolchain runs actions with the binary of a container image, mounting the working directory
+type toolchain struct {
+	Image string
+	Bin   string
+}
+
+// useContainer reports whether the action must run in the toolchain image
+func (u *Up) useContainer(id string) bool {
+	return u.toolchain != nil && slices.Contains(containerizedActions, id)
+}
+
+// executeInContainer runs the action with the binary of the toolchain image through the docker container runtime,
+// passing the persistent flags, like the log level, to the binary
+func (u *Up) executeInContainer(ctx context.Context, id string, args, opts, persistent action.InputParams, streams launchr.Streams) error {
+	a, ok := u.M.Get(id)
+	if !ok {
+		return fmt.Errorf("action %q was not found", id)
+	}
+	command := append([]string{u.toolchain.Bin, id}, flags(persistent)...)
+	command = append(command, commandLine(a.ActionDef(), args, opts)...)
+	u.Term().Info().Printfln("Running %s in %s", id, u.toolchain.Image)
+
+	def, err := yaml.Marshal(map[string]any{
+		"runtime": map[string]any{
+			"type":    "container",
+			"image":   u.toolchain.Image,
+			"command": command,
+		},
+		"action": map[string]any{
+			"title": id,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The container runtime is set by the manager according to the launchr runtime config
+	ca := action.NewFromYAML(id, def)
+	input := action.NewInput(ca, nil, nil, streams)
+	if err = u.M.ValidateInput(ca, input); err != nil {
+		return fmt.Errorf("failed to validate input for action %q: %w", id, err)
+	}
+	if err = ca.SetInput(input); err != nil {
+		return fmt.Errorf("failed to set input for action %q: %w", id, err)
+	}
+
+	u.M.Decorate(ca)
+	if err = ca.Execute(ctx); err != nil {
+		return fmt.Errorf("error executing action %q in container: %w", id, err)
+	}
+	return nil
+}
+
+// commandLine returns the command line arguments of an action input, positional arguments first
+func commandLine(def *action.DefAction, args, opts action.InputParams) []string {
+	var line []string
+	for _, p := range def.Arguments {
+		if v, ok := args[p.Name]; ok {
+			line = append(line, fmt.Sprint(v))
+		}
+	}
+
+	return append(line, flags(opts)...)
+}
+
+// flags returns the flags of the options sorted by name, a slice option being repeated for each of its values
+func flags(opts action.InputParams) []string {
+	names := make([]string, 0, len(opts))
+	for name := range opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var line []string
+	for _, name := range names {
+		switch v := opts[name].(type) {
+		case nil:
+		case bool:
+			if v {
+				line = append(line, "--"+name)
+			}
+		case string:
+			if v != "" {
+				line = append(line, "--"+name+"="+v)
+			}
+		default:
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+				for i := range rv.Len() {
+					line = append(line, fmt.Sprintf("--%s=%v", name, rv.Index(i).Interface()))
+				}
+				continue
+			}
+			line = append(line, fmt.Sprintf("--%s=%v", name, v))
+		}
+	}
+	return line
+}
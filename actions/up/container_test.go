@@ -0,0 +1,46 @@
+package up
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/launchrctl/launchr/pkg/action"
+)
+
+func TestCommandLine(t *testing.T) {
+	def := &action.DefAction{Arguments: action.ParametersList{{Name: "environment"}, {Name: "tags"}}}
+	tests := []struct {
+		name string
+		args action.InputParams
+		opts action.InputParams
+		want []string
+	}{
+		{"arguments in order", action.InputParams{"tags": "core", "environment": "dev"}, nil, []string{"dev", "core"}},
+		{
+			"options",
+			action.InputParams{"environment": "dev"},
+			action.InputParams{"debug": true, "check": false, "img": "", "retries": 3, "limit": "node1"},
+			[]string{"dev", "--debug", "--limit=node1", "--retries=3"},
+		},
+		{
+			"slice options",
+			nil,
+			action.InputParams{"var": []any{"a=1", "b=2"}, "skip": []string{"x"}, "none": []any{}},
+			[]string{"--skip=x", "--var=a=1", "--var=b=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandLine(def, tt.args, tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("commandLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersistentFlags(t *testing.T) {
+	persistent := action.InputParams{"log-level": "INFO", "log-format": "plain", "quiet": false}
+	if got, want := flags(persistent), []string{"--log-format=plain", "--log-level=INFO"}; !slices.Equal(got, want) {
+		t.Errorf("flags() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package up
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+)
+
+// runStateFile records the progress of the last platform:up run, to resume it after a failure
+const runStateFile = ".plasmactl/state/up.json"
+
+// Steps of the platform:up workflow recorded in the run state
+const (
+	stepBump    = "bump"
+	stepCompose = "compose"
+	stepPrepare = "prepare"
+	stepSync    = "sync"
+	stepPackage = "package"
+	stepPublish = "publish"
+	stepDeploy  = "deploy"
+	stepCI      = "ci"
+)
+
+// runState is the progress of a platform:up run
+type runState struct {
+	Environment string    `json:"environment"`
+	Tags        string    `json:"tags"`
+	Local       bool      `json:"local"`
+	Started     time.Time `json:"started"`
+	Completed   []string  `json:"completed"`
+	Failed      string    `json:"failed,omitempty"`
+	// Commit is the git HEAD after the last completed step
+	Commit string `json:"commit,omitempty"`
+
+	// selected are the steps to run in this run
+	selected map[string]bool
+}
+
+// loadRunState reads the state of the previous run, nil if there is none
+func loadRunState() (*runState, error) {
+	data, err := os.ReadFile(runStateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+	var s runState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse run state %s: %w", runStateFile, err)
+	}
+	return &s, nil
+}
+
+// save writes the run state
+func (s *runState) save() error {
+	// A resumed run must not skip the steps which were only simulated
+	if simulate.Enabled() {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(runStateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create run state directory: %w", err)
+	}
+	return atomicfile.WriteFile(runStateFile, data, 0644)
+}
+
+// done reports whether the step was completed
+func (s *runState) done(step string) bool {
+	return slices.Contains(s.Completed, step)
+}
+
+// clearRunState removes the run state once a run succeeded
+func clearRunState() error {
+	// A failed real run stays resumable after a simulation
+	if simulate.Enabled() {
+		return nil
+	}
+	if err := os.Remove(runStateFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// headCommit returns the current git HEAD, empty if unknown
+func headCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// startRun returns the state of a new run, or of the previous failed run to resume
+func (u *Up) startRun(environment, tags string, options UpOptions) (*runState, error) {
+	selected, err := selectSteps(options)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := &runState{
+		Environment: environment,
+		Tags:        tags,
+		Local:       options.Local,
+		Started:     time.Now().UTC(),
+		Completed:   []string{},
+		selected:    selected,
+	}
+	if !options.Resume {
+		return fresh, nil
+	}
+
+	prev, err := loadRunState()
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil || prev.Failed == "" {
+		u.Term().Info().Println("No failed run to resume, running all steps")
+		return fresh, nil
+	}
+	if prev.Environment != environment || prev.Tags != tags || prev.Local != options.Local {
+		return nil, fmt.Errorf("cannot resume: the failed run was for environment %q and tags %q (local: %t), run without --resume",
+			prev.Environment, prev.Tags, prev.Local)
+	}
+
+	u.Term().Info().Printfln("Resuming run of %s from the failed %s step", prev.Started.Local().Format(time.DateTime), prev.Failed)
+	if head := headCommit(); prev.Commit != "" && head != prev.Commit {
+		u.Term().Warning().Println("HEAD moved since the failed run, skipped steps keep the outputs of the previous run")
+	}
+	prev.Failed = ""
+	prev.selected = selected
+	return prev, nil
+}
+
+// runStep runs a selected step of the workflow unless the resumed run completed it, recording its outcome
+func (u *Up) runStep(state *runState, step string, fn func() error) error {
+	if !state.selected[step] {
+		if !slices.Contains(optionalSteps, step) {
+			u.Term().Info().Printfln("Skipping %s: not selected", step)
+		}
+		return nil
+	}
+	if state.done(step) {
+		u.Term().Info().Printfln("Skipping %s: completed by the previous run", step)
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		state.Failed = step
+		if saveErr := state.save(); saveErr != nil {
+			u.Log().Warn("failed to save run state", "error", saveErr)
+		}
+		return err
+	}
+
+	state.Completed = append(state.Completed, step)
+	state.Commit = headCommit()
+	if err := state.save(); err != nil {
+		u.Log().Warn("failed to save run state", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package up
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// localSteps are the steps of a local run in execution order
+var localSteps = []string{stepBump, stepCompose, stepPrepare, stepSync, stepPackage, stepPublish, stepDeploy}
+
+// ciSteps are the steps of a CI run in execution order
+var ciSteps = []string{stepBump, stepCI}
+
+// optionalSteps only run when selected with --only, or --publish for the publish step
+var optionalSteps = []string{stepPublish}
+
+// selectSteps returns the steps to run according to --only, --skip, --publish, --skip-bump and
+// --skip-prepare
+func selectSteps(options UpOptions) (map[string]bool, error) {
+	if len(options.Only) > 0 && len(options.Skip) > 0 {
+		return nil, fmt.Errorf("--only and --skip can't be used together")
+	}
+
+	steps := ciSteps
+	if options.Local {
+		steps = localSteps
+	}
+	for _, step := range append(slices.Clone(options.Only), options.Skip...) {
+		if slices.Contains(steps, step) {
+			continue
+		}
+		if slices.Contains(localSteps, step) {
+			return nil, fmt.Errorf("step %q only runs with --local", step)
+		}
+		return nil, fmt.Errorf("unknown step %q, expected one of: %s", step, strings.Join(steps, ", "))
+	}
+
+	selected := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		if len(options.Only) > 0 {
+			selected[step] = slices.Contains(options.Only, step)
+		} else {
+			selected[step] = !slices.Contains(options.Skip, step) && !slices.Contains(optionalSteps, step)
+		}
+	}
+	if options.Publish && len(options.Only) == 0 && !slices.Contains(options.Skip, stepPublish) && slices.Contains(steps, stepPublish) {
+		selected[stepPublish] = true
+	}
+	if options.SkipBump {
+		selected[stepBump] = false
+	}
+	if options.SkipPrepare {
+		selected[stepPrepare] = false
+	}
+	return selected, nil
+}
@@ -1,15 +1,31 @@
 package up
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
 	"github.com/launchrctl/launchr/pkg/action"
 	"github.com/plasmash/plasmactl-platform/internal/ci"
 	"github.com/plasmash/plasmactl-platform/internal/git"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/metrics"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+	"golang.org/x/term"
 )
 
 // UpOptions holds options for the platform:up command
@@ -25,7 +41,35 @@ type UpOptions struct {
 	CleanPrepare       bool
 	Debug              bool
 	ConflictsVerbosity bool
+	CommitMessage      string
+	SignCommit         bool
+	Dirty              string
+	NoAutoCommit       bool // NoAutoCommit is the former alias of Dirty set to git.DirtyFail
+	Containerized      bool
+	ContainerImage     string
 	GitlabDomain       string
+	GitlabAuth         string
+	APIRetries         int
+	APITimeout         string
+	CIProvider         string
+	GiteaDomain        string
+	Follow             bool
+	Wait               bool
+	Timeout            string
+	CancelOnInterrupt  bool
+	Queue              bool
+	FetchImage         bool
+	CreateMR           bool
+	MRTarget           string
+	PipelineVars       []string
+	RunnerTags         []string
+	Schedule           string
+	CreateSchedule     bool
+	Resume             bool
+	Only               []string
+	Skip               []string
+	Publish            bool
+	SkipUnchanged      bool
 	Streams            launchr.Streams
 	Persistent         action.InputParams
 }
@@ -35,10 +79,13 @@ type Up struct {
 	action.WithLogger
 	action.WithTerm
 
-	K  keyring.Keyring
-	M  action.Manager
-	G  *git.GitUp
-	CI *ci.ContinuousIntegration
+	K      keyring.Keyring
+	M      action.Manager
+	Config launchr.Config
+	G      *git.GitUp
+	CI     *ci.ContinuousIntegration
+
+	toolchain *toolchain
 }
 
 // NewUp creates a new Up instance
@@ -58,7 +105,7 @@ func NewUp(a *action.Action, k keyring.Keyring, m action.Manager) *Up {
 	u.SetTerm(term)
 
 	u.G = &git.GitUp{WithLogger: u.WithLogger, WithTerm: u.WithTerm}
-	u.CI = &ci.ContinuousIntegration{WithLogger: u.WithLogger, WithTerm: u.WithTerm}
+	u.CI = &ci.ContinuousIntegration{WithLogger: u.WithLogger, WithTerm: u.WithTerm, Keyring: k}
 	return u
 }
 
@@ -68,17 +115,24 @@ func (u *Up) Run(ctx context.Context, environment, tags string, options UpOption
 		u.Term().Info().Println("--ci option is deprecated: builds are now done by default in CI")
 	}
 
+	if options.Containerized {
+		if !options.Local && options.Img == "" {
+			return fmt.Errorf("--containerized only applies to --local and --img runs")
+		}
+		if options.ContainerImage == "" {
+			return failure.Newf(failure.ErrConfigMissing, "container-image is empty: pass it as option or local config")
+		}
+		u.toolchain = &toolchain{Image: options.ContainerImage, Bin: options.Bin}
+	}
+
 	// Deploy from Platform Image - skip compose/sync/bump/prepare
 	if options.Img != "" {
 		u.Term().Info().Printfln("Deploying from Platform Image: %s", options.Img)
 
-		err := u.executeAction(ctx, "platform:deploy", action.InputParams{
-			"environment": environment,
-			"tags":        tags,
-		}, action.InputParams{
+		err := u.deploy(ctx, environment, tags, action.InputParams{
 			"img":   options.Img,
 			"debug": options.Debug,
-		}, options.Persistent, options.Streams)
+		}, options)
 		if err != nil {
 			return fmt.Errorf("deploy error: %w", err)
 		}
@@ -92,164 +146,250 @@ func (u *Up) Run(ctx context.Context, environment, tags string, options UpOption
 		u.Term().Info().Printfln("Ansible debug mode: %t", ansibleDebug)
 	}
 
-	var username, password string
+	state, err := u.startRun(environment, tags, options)
+	if err != nil {
+		return err
+	}
 
-	// Commit unversioned changes if any
-	err := u.G.CommitChangesIfAny()
+	// Deal with unversioned changes if any
+	restore, err := u.handleChanges(ctx, environment, tags, options)
 	if err != nil {
-		return fmt.Errorf("commit error: %w", err)
+		return err
 	}
+	defer restore()
 
 	// Execute bump
-	if !options.SkipBump {
-		err = u.executeAction(ctx, "component:bump", nil, action.InputParams{
+	err = u.runStep(state, stepBump, func() error {
+		return u.executeAction(ctx, "component:bump", nil, action.InputParams{
 			"last": options.Last,
 		},
 			options.Persistent, options.Streams)
-		if err != nil {
-			return fmt.Errorf("bump error: %w", err)
-		}
-	} else {
-		u.Term().Info().Println("--skip-bump option detected: Skipping bump execution")
+	})
+	if err != nil {
+		return fmt.Errorf("bump error: %w", err)
 	}
 	u.Term().Printf("\n")
 
 	if options.Local {
 		u.Term().Info().Println("Starting local build")
 
-		// Commands executed sequentially: compose → prepare → sync → deploy
-		err = u.executeAction(ctx, "model:compose", nil, action.InputParams{
-			"skip-not-versioned":  true,
-			"conflicts-verbosity": options.ConflictsVerbosity,
-			"clean":               options.Clean,
-		}, options.Persistent, options.Streams)
+		// Commands executed sequentially: compose → prepare → sync → package → (publish) → deploy
+		err = u.runStep(state, stepCompose, func() error {
+			return u.executeAction(ctx, "model:compose", nil, action.InputParams{
+				"skip-not-versioned":  true,
+				"conflicts-verbosity": options.ConflictsVerbosity,
+				"clean":               options.Clean,
+			}, options.Persistent, options.Streams)
+		})
 		if err != nil {
 			return fmt.Errorf("compose error: %w", err)
 		}
 
 		u.Term().Println()
-		if !options.SkipPrepare {
-			err = u.executeAction(ctx, "model:prepare", nil, action.InputParams{
+		err = u.runStep(state, stepPrepare, func() error {
+			return u.executeAction(ctx, "model:prepare", nil, action.InputParams{
 				"clean": options.CleanPrepare,
 			}, options.Persistent, options.Streams)
-			if err != nil {
-				return fmt.Errorf("prepare error: %w", err)
-			}
-			u.Term().Println()
-		} else {
-			u.Term().Info().Println("--skip-prepare option detected: Skipping prepare execution")
+		})
+		if err != nil {
+			return fmt.Errorf("prepare error: %w", err)
 		}
+		u.Term().Println()
 
-		err = u.executeAction(ctx, "component:sync", nil, nil, options.Persistent, options.Streams)
+		err = u.runStep(state, stepSync, func() error {
+			return u.executeAction(ctx, "component:sync", nil, nil, options.Persistent, options.Streams)
+		})
 		if err != nil {
 			return fmt.Errorf("sync error: %w", err)
 		}
 
-		err = u.executeAction(ctx, "platform:deploy", action.InputParams{
-			"environment": environment,
-			"tags":        tags,
-		}, action.InputParams{
-			"debug": options.Debug,
-		}, options.Persistent, options.Streams)
+		// Always produce the Platform Image artifact of what is being deployed
+		u.Term().Println()
+		err = u.runStep(state, stepPackage, func() error {
+			return u.executeAction(ctx, "platform:package", nil, action.InputParams{
+				"environment": environment,
+			}, options.Persistent, options.Streams)
+		})
 		if err != nil {
-			return fmt.Errorf("deploy error: %w", err)
+			return fmt.Errorf("package error: %w", err)
 		}
+		u.Term().Println()
 
-	} else {
-		u.Term().Info().Println("Starting CI build (now default behavior)")
-
-		// Push branch if it does not exist on remote
-		if err := u.G.PushBranchIfNotRemote(); err != nil {
-			return err
+		err = u.runStep(state, stepPublish, func() error {
+			return u.executeAction(ctx, "platform:publish", nil, action.InputParams{
+				"platform": environment,
+			}, options.Persistent, options.Streams)
+		})
+		if err != nil {
+			return fmt.Errorf("publish error: %w", err)
 		}
 
-		// Push any un-pushed commits
-		if err := u.G.PushCommitsIfAny(); err != nil {
-			return err
+		err = u.runStep(state, stepDeploy, func() error {
+			return u.deploy(ctx, environment, tags, action.InputParams{
+				"debug": options.Debug,
+			}, options)
+		})
+		if err != nil {
+			return fmt.Errorf("deploy error: %w", err)
 		}
 
-		gitlabDomain := options.GitlabDomain
-		if gitlabDomain == "" {
-			return fmt.Errorf("gitlab-domain is empty: pass it as option or local config")
-		}
-		u.Term().Info().Printfln("Getting user credentials for %s from keyring", gitlabDomain)
-		c, save, err := u.getCredentials(gitlabDomain, username, password)
+	} else {
+		err = u.runStep(state, stepCI, func() error {
+			return u.runCI(ctx, environment, tags, ansibleDebug, options)
+		})
 		if err != nil {
 			return err
 		}
-		u.Term().Printfln("URL: %s", c.URL)
-		u.Term().Printfln("Username: %s", c.Username)
+	}
 
-		username = c.Username
-		password = c.Password
+	if err := clearRunState(); err != nil {
+		u.Log().Warn("failed to clear run state", "error", err)
+	}
+	return nil
+}
 
-		// Get Gitlab OAuth token
-		gitlabAccessToken, err := u.CI.GetOAuthTokens(gitlabDomain, username, password)
-		if err != nil {
-			return fmt.Errorf("failed to get OAuth token: %w", err)
-		}
+// runCI pushes the branch and builds it in CI
+func (u *Up) runCI(ctx context.Context, environment, tags string, ansibleDebug bool, options UpOptions) error {
+	u.Term().Info().Println("Starting CI build (now default behavior)")
+	u.CI.Platform = environment
 
-		// Save gitlab credentials to keyring once API requests are successful
-		if save {
-			err = u.K.Save()
-			u.Log().Debug("saving user credentials to keyring", "url", gitlabDomain)
-			if err != nil {
-				u.Log().Error("error during saving keyring file", "error", err)
-			}
+	options.CIProvider = strings.ToLower(options.CIProvider)
+	if options.CIProvider == "" {
+		options.CIProvider = ci.ProviderGitLab
+	}
+	if options.CreateMR && options.CIProvider != ci.ProviderGitLab {
+		return fmt.Errorf("--create-mr is only supported by the gitlab CI provider")
+	}
+	if len(options.RunnerTags) > 0 && options.CIProvider != ci.ProviderGitLab {
+		return fmt.Errorf("--runner-tag is only supported by the gitlab CI provider")
+	}
+	if options.Schedule != "" && options.CIProvider != ci.ProviderGitLab {
+		return fmt.Errorf("--schedule is only supported by the gitlab CI provider")
+	}
+	if options.CreateSchedule && options.Schedule == "" {
+		return fmt.Errorf("--create-schedule requires --schedule")
+	}
+	if simulate.Enabled() {
+		if _, err := pipelineVariables(environment, options.PipelineVars); err != nil {
+			return err
 		}
+		simulate.Record(simulate.Command{Name: "git", Args: []string{"push"}})
+		simulate.Record(simulate.Command{Name: options.CIProvider, Args: []string{"build", environment, tags}})
+		u.Term().Info().Printfln("Simulated the %s pipeline deploying %s to %s", options.CIProvider, tags, environment)
+		return nil
+	}
 
-		// Get branch name
-		branchName, err := u.CI.GetBranchName()
-		if err != nil {
-			return fmt.Errorf("failed to get branch name: %w", err)
-		}
+	// Push branch if it does not exist on remote
+	if err := u.G.PushBranchIfNotRemote(ctx); err != nil {
+		return err
+	}
 
-		// Get repo name
-		repoName, err := u.CI.GetRepoName()
-		if err != nil {
-			return fmt.Errorf("failed to get repo name: %w", err)
-		}
+	// Push any un-pushed commits
+	if err := u.G.PushCommitsIfAny(ctx); err != nil {
+		return err
+	}
 
-		// Get project ID
-		projectID, err := u.CI.GetProjectID(gitlabDomain, gitlabAccessToken, repoName)
+	var err error
+	var timeout time.Duration
+	if options.Timeout != "" {
+		timeout, err = time.ParseDuration(options.Timeout)
 		if err != nil {
-			return fmt.Errorf("failed to get ID of project %q: %w", repoName, err)
+			return fmt.Errorf("invalid timeout %q: %w", options.Timeout, err)
 		}
+	}
 
-		// Trigger pipeline
-		pipelineID, err := u.CI.TriggerPipeline(gitlabDomain, gitlabAccessToken, projectID, branchName, environment, tags, ansibleDebug)
-		if err != nil {
-			return fmt.Errorf("failed to trigger pipeline: %w", err)
-		}
+	variables, err := pipelineVariables(environment, options.PipelineVars)
+	if err != nil {
+		return err
+	}
 
-		// Get all jobs in the pipeline
-		jobs, err := u.CI.GetJobsInPipeline(gitlabDomain, gitlabAccessToken, projectID, pipelineID)
-		if err != nil {
-			return fmt.Errorf("failed to retrieve jobs in pipeline: %w", err)
-		}
+	provider, err := u.ciProvider(ctx, options)
+	if err != nil {
+		return err
+	}
 
-		// Find the target job ID
-		var targetJobID int
-		for _, job := range jobs {
-			if job.Name == ci.TargetJobName {
-				targetJobID = job.ID
-				break
-			}
-		}
-		if targetJobID == 0 {
-			return fmt.Errorf("no %s job found in pipeline", ci.TargetJobName)
-		}
+	// Get branch name
+	branchName, err := u.CI.GetBranchName()
+	if err != nil {
+		return fmt.Errorf("failed to get branch name: %w", err)
+	}
 
-		// Trigger the manual job
-		err = u.CI.TriggerManualJob(gitlabDomain, gitlabAccessToken, projectID, targetJobID, pipelineID)
-		if err != nil {
-			return fmt.Errorf("failed to trigger manual job: %w", err)
+	// Get repo path, CI APIs address repositories by their full namespace path
+	repoPath, err := u.CI.GetRepoPath()
+	if err != nil {
+		return fmt.Errorf("failed to get repo path: %w", err)
+	}
+
+	if options.CreateMR {
+		if err = u.createMergeRequest(ctx, provider.(*ci.GitLab), repoPath, branchName, options.MRTarget); err != nil {
+			return err
 		}
 	}
+
+	var imageDir string
+	if options.FetchImage {
+		imageDir = pi.ImageDir
+	}
+
+	// Catch Ctrl-C while waiting on CI to deal with the triggered pipeline
+	buildCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	start := time.Now()
+	err = provider.Build(buildCtx, ci.BuildRequest{
+		Repo:        repoPath,
+		Branch:      branchName,
+		Environment: environment,
+		Tags:        tags,
+		Debug:       ansibleDebug,
+		Follow:      options.Follow,
+		Wait:        options.Wait,
+		Queue:       options.Queue,
+		Timeout:     timeout,
+		Variables:   variables,
+		ImageDir:    imageDir,
+
+		RunnerTags:     options.RunnerTags,
+		Schedule:       options.Schedule,
+		CreateSchedule: options.CreateSchedule,
+	})
+	if options.Wait || options.Follow {
+		recordCIWait(environment, options.CIProvider, time.Since(start), err)
+	}
+	if buildCtx.Err() != nil && ctx.Err() == nil {
+		// Restore default handling, a second interrupt terminates immediately
+		stop()
+		return u.handleInterrupt(ctx, provider, options.CancelOnInterrupt)
+	}
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
+// recordCIWait records the time waited for the pipeline deploying the environment, by its result
+func recordCIWait(environment, provider string, elapsed time.Duration, err error) {
+	result := history.ResultSuccess
+	if err != nil {
+		result = history.ResultFailed
+	}
+	metrics.Record(environment, metrics.Metric{
+		Name:   metrics.CIWaitDuration,
+		Labels: map[string]string{"provider": provider, "result": result},
+		Value:  elapsed.Seconds(),
+	})
+}
+
 func (u *Up) executeAction(ctx context.Context, id string, args, opts, persistent action.InputParams, streams launchr.Streams) error {
+	// Only the deployment simulates its changes of the infrastructure, the other steps are recorded
+	if simulate.Enabled() && (id != "platform:deploy" || u.useContainer(id)) {
+		simulate.Record(simulate.Command{Name: id, Args: actionArgs(args, opts)})
+		u.Term().Info().Printfln("Simulated %s", id)
+		return nil
+	}
+	if u.useContainer(id) {
+		return u.executeInContainer(ctx, id, args, opts, persistent, streams)
+	}
+
 	a, ok := u.M.Get(id)
 	if !ok {
 		return fmt.Errorf("action %q was not found", id)
@@ -279,37 +419,185 @@ func (u *Up) executeAction(ctx context.Context, id string, args, opts, persisten
 	return nil
 }
 
-func (u *Up) getCredentials(url, username, password string) (keyring.CredentialsItem, bool, error) {
-	ci, err := u.K.GetForURL(url)
-	save := false
-	if err != nil {
-		if errors.Is(err, keyring.ErrEmptyPass) {
-			return ci, false, err
-		} else if !errors.Is(err, keyring.ErrNotFound) {
-			u.Log().Error("error", "error", err)
-			return ci, false, errors.New("the keyring is malformed or wrong passphrase provided")
+// actionArgs returns the arguments and options of an action as command line arguments, sorted by name
+func actionArgs(args, opts action.InputParams) []string {
+	var cmdArgs []string
+	for _, k := range slices.Sorted(maps.Keys(args)) {
+		cmdArgs = append(cmdArgs, fmt.Sprint(args[k]))
+	}
+	for _, k := range slices.Sorted(maps.Keys(opts)) {
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--%s=%v", k, opts[k]))
+	}
+	return cmdArgs
+}
+
+// deploy runs platform:deploy. With SkipUnchanged, a check mode run comes first and the deployment is
+// skipped when no task would change.
+func (u *Up) deploy(ctx context.Context, environment, tags string, opts action.InputParams, options UpOptions) error {
+	args := action.InputParams{"environment": environment, "tags": tags}
+	if options.SkipUnchanged {
+		results := result.NewCollector()
+		check := maps.Clone(opts)
+		check["check"] = true
+		u.Term().Info().Println("Checking the changes of the deployment")
+		if err := u.executeAction(result.WithCollector(ctx, results), "platform:deploy", args, check, options.Persistent, options.Streams); err != nil {
+			return err
 		}
-		ci = keyring.CredentialsItem{}
-		ci.URL = url
-		ci.Username = username
-		ci.Password = password
-		if ci.Username == "" || ci.Password == "" {
-			if ci.URL != "" {
-				u.Term().Info().Printfln("Please add login and password for %s", ci.URL)
-			}
-			err = keyring.RequestCredentialsFromTty(&ci)
-			if err != nil {
-				return ci, false, err
-			}
+		plan, ok := result.Get[result.DeployResult](results)
+		switch {
+		case !ok:
+			// The toolchain container runs the actions in another process
+			u.Log().Warn("no result of the check mode run, deploying")
+		case plan.Changed == 0:
+			u.Term().Success().Println("No task would change, skipping the deployment")
+			return nil
+		default:
+			u.Term().Info().Printfln("%d tasks would change on %d hosts, deploying", plan.Changed, len(plan.Hosts))
 		}
+	}
+	return u.executeAction(ctx, "platform:deploy", args, opts, options.Persistent, options.Streams)
+}
+
+// handleInterrupt cancels the triggered pipeline if requested or confirmed by the user
+func (u *Up) handleInterrupt(ctx context.Context, provider ci.Provider, cancel bool) error {
+	u.Term().Println()
+	u.Term().Warning().Println("Interrupted while waiting on CI")
 
-		err = u.K.AddItem(ci)
+	if !cancel && term.IsTerminal(int(os.Stdin.Fd())) {
+		u.Term().Printf("Cancel the triggered pipeline? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		cancel = answer == "y" || answer == "yes"
+	}
+	if !cancel {
+		u.Term().Info().Println("Pipeline left running")
+		return errors.New("interrupted")
+	}
+
+	if err := provider.Cancel(ctx); err != nil {
+		return err
+	}
+	return errors.New("interrupted, pipeline canceled")
+}
+
+// handleChanges commits, stashes or refuses uncommitted changes according to --dirty.
+// The returned function restores stashed changes once the run is over.
+func (u *Up) handleChanges(ctx context.Context, environment, tags string, options UpOptions) (func(), error) {
+	noop := func() {}
+	mode, err := dirtyMode(options)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case git.DirtyStash:
+		restore, err := u.G.StashChangesIfAny(ctx)
 		if err != nil {
-			return ci, false, err
+			return nil, fmt.Errorf("stash error: %w", err)
+		}
+		return restore, nil
+	case git.DirtyFail:
+		if err := u.G.FailIfDirty(); err != nil {
+			return nil, err
 		}
+		return noop, nil
+	case "", git.DirtyCommit:
+		err = u.G.CommitChangesIfAny(ctx, git.CommitOptions{
+			Message:     options.CommitMessage,
+			Environment: environment,
+			Tags:        tags,
+			Sign:        options.SignCommit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("commit error: %w", err)
+		}
+		return noop, nil
+	}
+	return nil, git.ValidateDirtyMode(mode)
+}
 
-		save = true
+// dirtyMode returns the handling of uncommitted changes, --no-auto-commit being an alias of --dirty=fail
+func dirtyMode(options UpOptions) (string, error) {
+	if !options.NoAutoCommit {
+		return options.Dirty, nil
+	}
+	switch options.Dirty {
+	case "", git.DirtyCommit, git.DirtyFail:
+		return git.DirtyFail, nil
+	}
+	return "", fmt.Errorf("--no-auto-commit conflicts with --dirty=%s", options.Dirty)
+}
+
+// createMergeRequest opens a merge request of the pushed branch unless it already has one, and prints its URL
+func (u *Up) createMergeRequest(ctx context.Context, provider *ci.GitLab, repo, branch, target string) error {
+	mr, created, err := provider.MergeRequest(ctx, repo, branch, target)
+	if err != nil {
+		return err
 	}
+	switch {
+	case mr == nil:
+		u.Term().Info().Printfln("Branch %s is the merge request target, skipping merge request", branch)
+	case created:
+		u.Term().Success().Printfln("Created merge request !%d into %s: %s", mr.IID, mr.TargetBranch, mr.WebURL)
+	default:
+		u.Term().Info().Printfln("Merge request !%d is already open: %s", mr.IID, mr.WebURL)
+	}
+	return nil
+}
 
-	return ci, save, nil
+// pipelineVariables merges --pipeline-var flags over the CI variables of the environment platform.yaml
+func pipelineVariables(environment string, vars []string) (map[string]string, error) {
+	name, err := schema.FindPlatform("", environment)
+	if errors.Is(err, schema.ErrNotFound) {
+		return ci.ParseVariables(nil, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+	platform, err := schema.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return ci.ParseVariables(platform.CI.Variables, vars)
+}
+
+// ciProvider authenticates against the selected CI service and returns its provider
+func (u *Up) ciProvider(ctx context.Context, options UpOptions) (ci.Provider, error) {
+	if err := ci.ValidateProvider(options.CIProvider); err != nil {
+		return nil, err
+	}
+
+	api, err := ci.ParseAPIConfig(options.APIRetries, options.APITimeout)
+	if err != nil {
+		return nil, err
+	}
+	u.CI.API = api
+
+	if options.CIProvider == ci.ProviderGitea {
+		if options.GiteaDomain == "" {
+			return nil, failure.Newf(failure.ErrConfigMissing, "gitea-domain is empty: pass it as option or local config")
+		}
+		u.Term().Info().Printfln("Getting access token for %s from keyring", options.GiteaDomain)
+		c, save, err := u.CI.GetCredentials(u.K, options.GiteaDomain)
+		if err != nil {
+			return nil, err
+		}
+		if save {
+			if err = u.K.Save(); err != nil {
+				u.Log().Error("error during saving keyring file", "error", err)
+			}
+		}
+		return &ci.Gitea{
+			WithLogger: u.WithLogger,
+			WithTerm:   u.WithTerm,
+			Domain:     options.GiteaDomain,
+			// Access token is stored as the password of the Gitea URL
+			Token: c.Password,
+		}, nil
+	}
+
+	gitlabToken, err := u.CI.GitLabToken(ctx, u.K, options.GitlabDomain, options.GitlabAuth)
+	if err != nil {
+		return nil, err
+	}
+	return &ci.GitLab{CI: u.CI, Domain: options.GitlabDomain, Token: gitlabToken}, nil
 }
@@ -0,0 +1,28 @@
+package up
+
+import (
+	"testing"
+
+	"github.com/plasmash/plasmactl-platform/internal/git"
+)
+
+func TestDirtyMode(t *testing.T) {
+	tests := []struct {
+		dirty        string
+		noAutoCommit bool
+		want         string
+		wantErr      bool
+	}{
+		{dirty: git.DirtyStash, want: git.DirtyStash},
+		{dirty: git.DirtyCommit, noAutoCommit: true, want: git.DirtyFail},
+		{dirty: "", noAutoCommit: true, want: git.DirtyFail},
+		{dirty: git.DirtyFail, noAutoCommit: true, want: git.DirtyFail},
+		{dirty: git.DirtyStash, noAutoCommit: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := dirtyMode(UpOptions{Dirty: tt.dirty, NoAutoCommit: tt.noAutoCommit})
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("dirtyMode(%q, %t) = %q, %v, want %q", tt.dirty, tt.noAutoCommit, got, err, tt.want)
+		}
+	}
+}
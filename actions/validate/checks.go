@@ -0,0 +1,135 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default limits of the concurrent checks
+const (
+	DefaultParallel     = 4
+	DefaultCheckTimeout = 30 * time.Second
+	DefaultTimeout      = 2 * time.Minute
+)
+
+// check is an independent check of a validation, run concurrently with the others
+type check struct {
+	name    string // e.g. DNSSEC
+	section string // Section of its results, unless it starts another one
+	run     func(ctx context.Context, v *Validate, hasErrors *bool)
+}
+
+// result is the outcome of a check
+type result struct {
+	report    Report
+	hasErrors bool
+	duration  time.Duration
+	timedOut  bool
+	skipped   bool // The total timeout passed before the check could start
+}
+
+// parseTimeouts returns the timeout of each check and of all of them, empty ones are the default ones
+func parseTimeouts(checkTimeout, timeout string) (time.Duration, time.Duration, error) {
+	check, total := DefaultCheckTimeout, DefaultTimeout
+	var err error
+	if checkTimeout != "" {
+		if check, err = time.ParseDuration(checkTimeout); err != nil {
+			return 0, 0, fmt.Errorf("invalid check timeout %q: %w", checkTimeout, err)
+		}
+	}
+	if timeout != "" {
+		if total, err = time.ParseDuration(timeout); err != nil {
+			return 0, 0, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+		}
+	}
+	return check, total, nil
+}
+
+// runChecks runs the checks with bounded parallelism, each within the check timeout and all of them
+// within the total timeout, then records and prints their results in the order of the checks
+func (v *Validate) runChecks(ctx context.Context, checks []check, checkTimeout, timeout time.Duration, hasErrors *bool) {
+	parallel := v.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]result, len(checks))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].skipped = true
+				return
+			}
+			defer func() { <-sem }()
+			results[i] = v.runCheck(ctx, c, checkTimeout)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		r := results[i]
+		for _, recorded := range r.report.Checks {
+			recorded.check = c.name
+			v.replay(recorded)
+		}
+		switch {
+		case r.skipped:
+			v.replay(Check{Section: c.section, Status: StatusError, Message: fmt.Sprintf("%s not run within the timeout of %s", c.name, timeout), check: c.name})
+			*hasErrors = true
+			continue
+		case r.timedOut:
+			v.replay(Check{Section: c.section, Status: StatusError, Message: fmt.Sprintf("%s timed out after %s", c.name, r.duration.Round(time.Millisecond)), check: c.name})
+			*hasErrors = true
+		case r.hasErrors:
+			*hasErrors = true
+		}
+		v.report.Timings = append(v.report.Timings, Timing{
+			Check:      c.name,
+			Section:    c.section,
+			DurationMs: float64(r.duration.Round(100*time.Microsecond)) / float64(time.Millisecond),
+			TimedOut:   r.timedOut,
+		})
+		v.Log.Debug("check completed", "check", c.name, "duration", r.duration, "timed_out", r.timedOut)
+	}
+}
+
+// runCheck runs a check on a copy of the validation recording its results without printing them. A
+// check still running at its timeout is abandoned, and its results discarded.
+func (v *Validate) runCheck(ctx context.Context, c check, timeout time.Duration) result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	buffered := *v
+	buffered.report, buffered.current, buffered.buffered = Report{}, c.section, true
+	done := make(chan bool, 1)
+	start := time.Now()
+	go func() {
+		hasErrors := false
+		c.run(ctx, &buffered, &hasErrors)
+		done <- hasErrors
+	}()
+	select {
+	case hasErrors := <-done:
+		return result{report: buffered.report, hasErrors: hasErrors, duration: time.Since(start)}
+	case <-ctx.Done():
+		return result{duration: time.Since(start), timedOut: true}
+	}
+}
+
+// replay records and prints a check of a concurrent check, starting its section when it changes
+func (v *Validate) replay(c Check) {
+	if c.Section != v.current {
+		v.section(c.Section)
+	}
+	v.add(c)
+}
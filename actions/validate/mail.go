@@ -0,0 +1,176 @@
+package validate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mtaSTSPolicy is an MTA-STS policy served at https://mta-sts.<domain>/.well-known/mta-sts.txt
+type mtaSTSPolicy struct {
+	Version string
+	Mode    string
+	MaxAge  int
+	MX      []string
+}
+
+// validateMTASTS checks the MTA-STS record, fetches the policy and checks it covers the MX hosts
+func (v *Validate) validateMTASTS(ctx context.Context, domain string, hasErrors *bool) {
+	txts, _ := v.resolver.LookupTXT(ctx, "_mta-sts."+domain)
+	var record string
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, "v=STSv1") {
+			record = txt
+			break
+		}
+	}
+	if record == "" {
+		v.warn("MTA-STS record not found")
+		return
+	}
+	if id := tagValue(record, "id"); id == "" {
+		v.fail("MTA-STS record has no id: %q", record)
+		*hasErrors = true
+		return
+	}
+
+	policy, err := fetchMTASTSPolicy(ctx, v.resolver, domain)
+	if err != nil {
+		v.fail("MTA-STS policy: %v", err)
+		*hasErrors = true
+		return
+	}
+
+	mxs, _ := v.resolver.LookupMX(ctx, domain)
+	var uncovered []string
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(strings.ToLower(mx.Host), ".")
+		if !policy.covers(host) {
+			uncovered = append(uncovered, host)
+		}
+	}
+	if len(uncovered) > 0 {
+		v.fail("MTA-STS policy (mode %s) doesn't cover MX hosts: %s", policy.Mode, strings.Join(uncovered, ", "))
+		*hasErrors = true
+		return
+	}
+	v.ok("MTA-STS policy: mode %s, max_age %d, mx %s", policy.Mode, policy.MaxAge, strings.Join(policy.MX, ", "))
+	if policy.Mode != "enforce" {
+		v.warn("MTA-STS mode is %s, receivers don't enforce TLS yet", policy.Mode)
+	}
+}
+
+// fetchMTASTSPolicy fetches and parses the MTA-STS policy of the domain, resolving its host with the resolver
+func fetchMTASTSPolicy(ctx context.Context, resolver *net.Resolver, domain string) (mtaSTSPolicy, error) {
+	var policy mtaSTSPolicy
+	url := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return policy, err
+	}
+	dialer := &net.Dialer{Resolver: resolver}
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: http.ProxyFromEnvironment},
+		// Policies must not be served through redirects
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return policy, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return policy, fmt.Errorf("%s returned status %s", url, res.Status)
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(res.Body, 64*1024))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			policy.Version = value
+		case "mode":
+			policy.Mode = value
+		case "max_age":
+			policy.MaxAge, _ = strconv.Atoi(value)
+		case "mx":
+			policy.MX = append(policy.MX, strings.ToLower(value))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return policy, err
+	}
+
+	switch {
+	case policy.Version != "STSv1":
+		return policy, fmt.Errorf("unsupported version %q, expected STSv1", policy.Version)
+	case policy.Mode != "enforce" && policy.Mode != "testing" && policy.Mode != "none":
+		return policy, fmt.Errorf("invalid mode %q, expected enforce, testing or none", policy.Mode)
+	case policy.MaxAge <= 0:
+		return policy, fmt.Errorf("invalid max_age")
+	case len(policy.MX) == 0 && policy.Mode != "none":
+		return policy, fmt.Errorf("no mx in policy")
+	}
+	return policy, nil
+}
+
+// covers reports whether an mx pattern of the policy matches the host, "*." matching a single label
+func (p mtaSTSPolicy) covers(host string) bool {
+	for _, pattern := range p.MX {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+		if pattern == host {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTLSRPT checks the TLS-RPT record and its reporting addresses
+func (v *Validate) validateTLSRPT(ctx context.Context, domain string, hasErrors *bool) {
+	txts, _ := v.resolver.LookupTXT(ctx, "_smtp._tls."+domain)
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, "v=TLSRPTv1") {
+			continue
+		}
+		rua := tagValue(txt, "rua")
+		for _, uri := range strings.Split(rua, ",") {
+			uri = strings.TrimSpace(uri)
+			if !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https:") {
+				v.fail("TLS-RPT record has invalid rua %q, expected mailto: or https: URIs", rua)
+				*hasErrors = true
+				return
+			}
+		}
+		v.ok("TLS-RPT record found (rua=%s)", rua)
+		return
+	}
+	v.warn("TLS-RPT record not found")
+}
+
+// tagValue returns the value of a tag of a "tag=value; tag=value" record
+func tagValue(record, tag string) string {
+	for _, part := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(name), tag) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	actionresult "github.com/plasmash/plasmactl-platform/pkg/result"
+)
+
+// Statuses of the checks of a report
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusError   = "error"
+)
+
+// Report is the structured result of a validation
+type Report struct {
+	Platform string   `json:"platform" yaml:"platform"`
+	OK       bool     `json:"ok" yaml:"ok"` // No check failed, warnings don't fail the validation
+	Checks   []Check  `json:"checks" yaml:"checks"`
+	Timings  []Timing `json:"timings,omitempty" yaml:"timings,omitempty"` // Durations of the concurrent checks
+}
+
+// Check is a check of a validation
+type Check struct {
+	Section string   `json:"section" yaml:"section"` // e.g. DNS Records
+	Status  string   `json:"status" yaml:"status"`   // ok, warning or error
+	Message string   `json:"message" yaml:"message"`
+	Details []string `json:"details,omitempty" yaml:"details,omitempty"` // Additional lines, e.g. the expected and actual records
+
+	check string // Name of the concurrent check which recorded it, e.g. DNSSEC
+}
+
+// Timing is the duration of a check run concurrently with the others
+type Timing struct {
+	Check      string  `json:"check" yaml:"check"` // e.g. DNSSEC
+	Section    string  `json:"section" yaml:"section"`
+	DurationMs float64 `json:"duration_ms" yaml:"duration_ms"`
+	TimedOut   bool    `json:"timed_out,omitempty" yaml:"timed_out,omitempty"`
+}
+
+// Result returns the result of the validation for the callers of the action, nil if it didn't run
+func (v *Validate) Result() *actionresult.ValidationReport {
+	if v.report.Platform == "" {
+		return nil
+	}
+	r := &actionresult.ValidationReport{Platform: v.report.Platform, OK: v.report.OK}
+	for _, c := range v.report.Checks {
+		switch c.Status {
+		case StatusError:
+			r.Errors = append(r.Errors, c.Message)
+		case StatusWarning:
+			r.Warnings = append(r.Warnings, c.Message)
+		}
+	}
+	return r
+}
+
+// quiet reports whether the terminal output is replaced by a structured report
+func (v *Validate) quiet() bool {
+	return output.Structured(v.Format)
+}
+
+// silent reports whether the checks are only recorded, for a structured report or to be printed once
+// a concurrent check is done
+func (v *Validate) silent() bool {
+	return v.quiet() || v.buffered
+}
+
+// section starts a section of checks
+func (v *Validate) section(title string) {
+	v.current = title
+	if !v.silent() {
+		v.Term.Info().Println()
+		v.Term.Info().Printfln("%s:", title)
+	}
+}
+
+// ok records a passed check
+func (v *Validate) ok(format string, a ...any) {
+	v.add(Check{Section: v.current, Status: StatusOK, Message: fmt.Sprintf(format, a...)})
+}
+
+// warn records a check with a warning
+func (v *Validate) warn(format string, a ...any) {
+	v.add(Check{Section: v.current, Status: StatusWarning, Message: fmt.Sprintf(format, a...)})
+}
+
+// fail records a failed check
+func (v *Validate) fail(format string, a ...any) {
+	v.add(Check{Section: v.current, Status: StatusError, Message: fmt.Sprintf(format, a...)})
+}
+
+// detail adds a line to the last check
+func (v *Validate) detail(format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	if n := len(v.report.Checks); n > 0 {
+		v.report.Checks[n-1].Details = append(v.report.Checks[n-1].Details, msg)
+	}
+	if !v.silent() {
+		v.Term.Printfln("      %s", msg)
+	}
+}
+
+// add records a check, and prints it with its details
+func (v *Validate) add(c Check) {
+	v.report.Checks = append(v.report.Checks, c)
+	if v.silent() {
+		return
+	}
+	switch c.Status {
+	case StatusOK:
+		v.Term.Success().Printfln("  ✓ %s", c.Message)
+	case StatusWarning:
+		v.Term.Warning().Printfln("  ! %s", c.Message)
+	default:
+		v.Term.Error().Printfln("  ✗ %s", c.Message)
+	}
+	for _, d := range c.Details {
+		v.Term.Printfln("      %s", d)
+	}
+}
+
+// printTimings writes the table of the durations of the concurrent checks
+func (v *Validate) printTimings() {
+	if len(v.report.Timings) == 0 {
+		return
+	}
+	v.Term.Info().Println()
+	v.Term.Info().Println("Timings:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSECTION\tDURATION")
+	for _, t := range v.report.Timings {
+		duration := (time.Duration(t.DurationMs * float64(time.Millisecond))).Round(time.Millisecond).String()
+		if t.TimedOut {
+			duration += " (timed out)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Check, t.Section, duration)
+	}
+	w.Flush()
+}
@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// validateServers lists the servers of the metal provider and reports the drift from platform.yaml:
+// addresses of the platform on none of the servers, and a server count other than the node count
+func (v *Validate) validateServers(ctx context.Context, platform *schema.Platform, nodeCount int, hasErrors *bool) {
+	name := platform.Infrastructure.MetalProvider
+	mp, err := provider.New(platform.Infrastructure, provider.Options{Keyring: v.Keyring, Log: v.Log, Term: v.Term, Platform: v.Name})
+	if errors.Is(err, provider.ErrUnsupported) {
+		v.Log.Debug("servers of the metal provider can't be listed", "provider", name)
+		return
+	}
+	if err != nil {
+		v.fail("Metal provider %s: %v", name, err)
+		*hasErrors = true
+		return
+	}
+	servers, err := mp.ListServers(ctx)
+	if err != nil {
+		v.fail("Servers at %s: %v", name, err)
+		*hasErrors = true
+		return
+	}
+
+	v.ok("Servers at %s: %d", name, len(servers))
+	for _, s := range servers {
+		v.detail("%s (%s, %s): %s", s.Hostname, s.ID, s.Status, strings.Join(s.PublicIPs(), ", "))
+	}
+	if nodeCount != len(servers) {
+		v.warn("%d node(s) in nodes/ but %d server(s) at %s", nodeCount, len(servers), name)
+	}
+	for _, addr := range platformAddresses(platform.DNS) {
+		if _, ok := provider.FindServer(servers, addr); !ok {
+			v.warn("Address %s of platform.yaml is on none of the servers at %s", addr, name)
+		}
+	}
+}
+
+// platformAddresses returns the sorted addresses of dns.addresses and dns.reverse
+func platformAddresses(cfg schema.DNSConfig) []string {
+	addrs := make(map[string]bool)
+	for _, list := range cfg.Addresses {
+		for _, addr := range list {
+			if ip := net.ParseIP(addr); ip != nil {
+				addrs[ip.String()] = true
+			}
+		}
+	}
+	for addr := range cfg.Reverse {
+		if ip := net.ParseIP(addr); ip != nil {
+			addrs[ip.String()] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(addrs))
+}
@@ -0,0 +1,185 @@
+package validate
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/textproto"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// smtpTimeout is the timeout of the SMTP session with each MX host
+const smtpTimeout = 20 * time.Second
+
+// mailRole is the role of the nodes sending mail, their addresses must be allowed by SPF
+const mailRole = "mail"
+
+// validateSMTP connects to port 25 of the MX hosts of the domain, checks their banner, STARTTLS and
+// certificate, then checks that SPF allows the addresses of the mail servers
+func (v *Validate) validateSMTP(ctx context.Context, platform *schema.Platform, hasErrors *bool) {
+	domain := platform.DNS.Domain
+	mxs, err := v.resolver.LookupMX(ctx, domain)
+	if err != nil || len(mxs) == 0 {
+		v.warn("No MX host to connect to")
+	}
+	var resolved []string
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		if !v.checkSMTP(ctx, host) {
+			*hasErrors = true
+		}
+		addrs, _ := v.resolver.LookupIPAddr(ctx, host)
+		for _, a := range addrs {
+			resolved = append(resolved, a.IP.String())
+		}
+	}
+
+	addrs, err := mailAddresses(v.Name, platform)
+	if err != nil {
+		v.fail("Mail server addresses: %v", err)
+		*hasErrors = true
+		return
+	}
+	if len(addrs) == 0 {
+		// Nothing configured, the addresses of the public MX hosts send the mail
+		addrs = resolved
+	}
+	slices.Sort(addrs)
+	for _, addr := range slices.Compact(addrs) {
+		result, err := dns.CheckSPF(ctx, v.resolver, domain, net.ParseIP(addr))
+		switch {
+		case err != nil:
+			v.fail("SPF check of %s: %v", addr, err)
+			*hasErrors = true
+		case result != dns.SPFPass:
+			v.fail("SPF of %s doesn't allow mail server %s (%s)", domain, addr, result)
+			*hasErrors = true
+		default:
+			v.ok("SPF allows mail server %s", addr)
+		}
+	}
+}
+
+// checkSMTP runs an SMTP session with an MX host up to the TLS handshake, and reports whether it
+// passed. A host not reachable is a warning, outbound port 25 is often blocked.
+func (v *Validate) checkSMTP(ctx context.Context, host string) bool {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	dialer := &net.Dialer{Resolver: v.resolver}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "25"))
+	if err != nil {
+		v.warn("%s not reachable on port 25: %v", host, err)
+		return true
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		v.fail("%s: unexpected banner: %v", host, err)
+		return false
+	}
+	if name, _, _ := strings.Cut(banner, " "); !strings.EqualFold(strings.TrimSuffix(name, "."), host) {
+		v.warn("%s: banner announces %s", host, name)
+	} else {
+		v.ok("%s: banner %s", host, banner)
+	}
+
+	helo, _ := os.Hostname()
+	if helo == "" || !strings.Contains(helo, ".") {
+		helo = "localhost.localdomain"
+	}
+	extensions, err := command(text, 250, "EHLO %s", helo)
+	if err != nil {
+		v.fail("%s: EHLO: %v", host, err)
+		return false
+	}
+	if !hasExtension(extensions, "STARTTLS") {
+		v.fail("%s: STARTTLS isn't offered", host)
+		return false
+	}
+	if _, err = command(text, 220, "STARTTLS"); err != nil {
+		v.fail("%s: STARTTLS: %v", host, err)
+		return false
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+	err = tlsConn.HandshakeContext(ctx)
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.As(err, &certErr):
+		v.fail("%s: STARTTLS certificate: %v", host, certErr.Err)
+		return false
+	case err != nil:
+		v.fail("%s: TLS handshake: %v", host, err)
+		return false
+	}
+	defer func() {
+		_ = textproto.NewConn(tlsConn).PrintfLine("QUIT")
+	}()
+
+	threshold := v.TLSExpiryDays
+	if threshold <= 0 {
+		threshold = DefaultTLSExpiryDays
+	}
+	cert := tlsConn.ConnectionState().PeerCertificates[0]
+	days := int(time.Until(cert.NotAfter).Hours() / 24)
+	if days < threshold {
+		v.warn("%s: STARTTLS certificate expires in %d day(s) on %s", host, days, cert.NotAfter.Format(time.DateOnly))
+	} else {
+		v.ok("%s: STARTTLS certificate valid until %s (%d days)", host, cert.NotAfter.Format(time.DateOnly), days)
+	}
+	return true
+}
+
+// command sends an SMTP command and returns the message of its response, which must have the code
+func command(text *textproto.Conn, code int, format string, args ...any) (string, error) {
+	if err := text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	_, msg, err := text.ReadResponse(code)
+	return msg, err
+}
+
+// hasExtension reports whether the EHLO response lists an extension
+func hasExtension(response, name string) bool {
+	for _, line := range strings.Split(response, "\n") {
+		if ext, _, _ := strings.Cut(line, " "); strings.EqualFold(ext, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mailAddresses returns the addresses of the MX hosts configured in platform.yaml and of the nodes
+// with the mail role
+func mailAddresses(name string, platform *schema.Platform) ([]string, error) {
+	ptrs, err := dns.PTRRecords(platform.DNS)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, r := range ptrs {
+		addrs = append(addrs, r.Name)
+	}
+	nodes, err := schema.LoadNodes(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if slices.Contains(n.Roles, mailRole) {
+			addrs = append(addrs, n.PublicIPs...)
+		}
+	}
+	return addrs, nil
+}
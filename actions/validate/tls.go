@@ -0,0 +1,85 @@
+package validate
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// DefaultTLSExpiryDays is the default number of days before the expiry of a certificate from which
+// it is reported
+const DefaultTLSExpiryDays = 21
+
+// tlsTimeout is the timeout of the TLS connection to each host
+const tlsTimeout = 10 * time.Second
+
+// validateTLS connects to the HTTPS port of the domain and of its configured names, verifies their
+// certificate chain and reports the certificates expiring soon
+func (v *Validate) validateTLS(ctx context.Context, platform *schema.Platform, hasErrors *bool) {
+	threshold := v.TLSExpiryDays
+	if threshold <= 0 {
+		threshold = DefaultTLSExpiryDays
+	}
+	for _, host := range tlsHosts(platform.DNS, platform.Cert) {
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: tlsTimeout, Resolver: v.resolver},
+			Config:    &tls.Config{ServerName: host},
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "443"))
+		var certErr *tls.CertificateVerificationError
+		switch {
+		case errors.As(err, &certErr):
+			v.fail("%s: %v", host, certErr.Err)
+			*hasErrors = true
+			continue
+		case err != nil:
+			v.warn("%s not reachable on port 443: %v", host, err)
+			continue
+		}
+
+		state := conn.(*tls.Conn).ConnectionState()
+		conn.Close()
+		cert := state.PeerCertificates[0]
+		days := int(time.Until(cert.NotAfter).Hours() / 24)
+		issuer := cert.Issuer.CommonName
+		if issuer == "" && len(cert.Issuer.Organization) > 0 {
+			issuer = cert.Issuer.Organization[0]
+		}
+		if days < threshold {
+			v.warn("%s: certificate expires in %d day(s) on %s, issued by %s", host, days, cert.NotAfter.Format(time.DateOnly), issuer)
+			continue
+		}
+		v.ok("%s: certificate valid until %s (%d days), issued by %s", host, cert.NotAfter.Format(time.DateOnly), days, issuer)
+	}
+}
+
+// tlsHosts returns the domain and its names with addresses, then the other names of the certificate.
+// Wildcard names can't be connected to and are left out.
+func tlsHosts(cfg schema.DNSConfig, cert schema.CertConfig) []string {
+	hosts := []string{cfg.Domain}
+	var names []string
+	for name := range cfg.Addresses {
+		if name == "@" || strings.Contains(name, "*") {
+			continue
+		}
+		names = append(names, name+"."+cfg.Domain)
+	}
+	for _, name := range cert.Names {
+		if !strings.Contains(name, "*") {
+			names = append(names, strings.TrimSuffix(name, "."))
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !slices.Contains(hosts, name) {
+			hosts = append(hosts, name)
+		}
+	}
+	return hosts
+}
@@ -1,24 +1,59 @@
 package validate
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/metrics"
 	"github.com/plasmash/plasmactl-platform/pkg/schema"
-	"gopkg.in/yaml.v3"
 )
 
 // Validate implements the platform:validate command
 type Validate struct {
-	Log      *launchr.Logger
-	Term     *launchr.Terminal
-	Name     string
-	SkipDNS  bool
-	SkipMail bool
+	Log       *launchr.Logger
+	Term      *launchr.Terminal
+	Keyring   keyring.Keyring
+	Name      string
+	SkipDNS   bool
+	SkipMail  bool
+	SkipInfra bool
+
+	SkipMTASTS bool
+	SkipTLSRPT bool
+	SkipDNSSEC bool
+	SkipTLS    bool
+	DeepMail   bool // Connect to the MX hosts on port 25 and check SPF allows the mail servers
+
+	TLSExpiryDays int // Certificates expiring within this number of days are reported, default is DefaultTLSExpiryDays
+
+	Resolver string // DNS server of the checks as ip:port, the system resolver by default
+	DoH      string // DNS-over-HTTPS endpoint of the checks, URL or provider name
+	Format   string // Output format, json prints the report instead of the checks
+
+	Parallel     int    // Checks run at once, default is DefaultParallel
+	CheckTimeout string // Timeout of each check, default is DefaultCheckTimeout
+	Timeout      string // Timeout of all the checks, default is DefaultTimeout
+
+	Watch    bool   // Validate periodically and print the changes of the checks
+	Interval string // Interval of --watch, default is DefaultWatchInterval
+	Notify   string // Webhook URL the changes of --watch are posted to
+
+	server   dns.Server
+	resolver *net.Resolver
+	report   Report
+	current  string // Section of the checks being run
+	buffered bool   // Checks are recorded without printing, for a concurrent check
 }
 
 // SetLogger sets the logger for the action
@@ -32,159 +67,479 @@ func (v *Validate) SetTerm(term *launchr.Terminal) {
 }
 
 // Execute runs the platform:validate action
-func (v *Validate) Execute() error {
-	instDir := filepath.Join("inst", v.Name)
-	platformFile := filepath.Join(instDir, "platform.yaml")
+func (v *Validate) Execute(ctx context.Context) error {
+	if _, err := output.Parse(v.Format); err != nil {
+		return err
+	}
+	if v.Watch {
+		return v.watch(ctx)
+	}
+	hasErrors, err := v.run(ctx)
+	if err != nil {
+		return err
+	}
 
-	// Check if platform exists
-	if _, err := os.Stat(platformFile); os.IsNotExist(err) {
-		return fmt.Errorf("platform %q not found", v.Name)
+	err = output.Render(os.Stdout, v.Format, v.report, func(wide bool) error {
+		if wide {
+			v.printTimings()
+		}
+		v.Term.Info().Println()
+		if hasErrors {
+			v.Term.Error().Println("Validation failed with errors")
+		} else {
+			v.Term.Success().Println("Validation passed")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	if hasErrors {
+		return fmt.Errorf("validation failed")
+	}
+	return nil
+}
+
+// Run validates the platform and returns the report of the checks, which are only printed with a
+// Format printing them. The validation failed if the report isn't OK.
+func (v *Validate) Run(ctx context.Context) (Report, error) {
+	if _, err := v.run(ctx); err != nil {
+		return Report{}, err
+	}
+	return v.report, nil
+}
 
-	// Read platform.yaml
-	data, err := os.ReadFile(platformFile)
+// run validates the platform into the report and reports whether a check failed
+func (v *Validate) run(ctx context.Context) (bool, error) {
+	server, err := dns.NewServer(v.Resolver, v.DoH)
 	if err != nil {
-		return fmt.Errorf("failed to read platform.yaml: %w", err)
+		return false, err
+	}
+	v.server, v.resolver = server, server.Resolver()
+	checkTimeout, timeout, err := parseTimeouts(v.CheckTimeout, v.Timeout)
+	if err != nil {
+		return false, err
 	}
 
-	var platform schema.Platform
-	if err := yaml.Unmarshal(data, &platform); err != nil {
-		return fmt.Errorf("failed to parse platform.yaml: %w", err)
+	platform, err := schema.Load(v.Name)
+	if errors.Is(err, schema.ErrNotFound) {
+		return false, failure.Newf(failure.ErrPlatformNotFound, "platform %q not found", v.Name)
+	}
+	if err != nil {
+		return false, err
 	}
 
-	v.Term.Info().Printfln("Validating platform %q...", v.Name)
-	v.Term.Info().Println()
+	v.report = Report{Platform: v.Name, Checks: []Check{}}
+	if !v.silent() {
+		v.Term.Info().Printfln("Validating platform %q...", v.Name)
+		if !server.IsSystem() {
+			v.Term.Info().Printfln("Resolving with %s", server)
+		}
+	}
 
 	hasErrors := false
 
 	// Validate basic configuration
-	v.Term.Info().Println("Basic Configuration:")
-	if platform.Name == "" {
-		v.Term.Error().Println("  ✗ Name is missing")
+	v.section("Basic Configuration")
+	var invalid *schema.ValidationError
+	if errors.As(platform.Validate(), &invalid) {
+		for _, f := range invalid.Fields {
+			v.fail("%s", f)
+		}
 		hasErrors = true
-	} else {
-		v.Term.Success().Printfln("  ✓ Name: %s", platform.Name)
 	}
-
-	if platform.Infrastructure.MetalProvider == "" {
-		v.Term.Error().Println("  ✗ Metal provider is missing")
-		hasErrors = true
-	} else {
-		v.Term.Success().Printfln("  ✓ Metal provider: %s", platform.Infrastructure.MetalProvider)
+	if platform.Name != "" {
+		v.ok("Name: %s", platform.Name)
+	}
+	if platform.Infrastructure.MetalProvider != "" {
+		v.ok("Metal provider: %s", platform.Infrastructure.MetalProvider)
 	}
 
 	if platform.DNS.Domain == "" {
-		v.Term.Warning().Println("  ! Domain is not configured")
+		v.warn("Domain is not configured")
 	} else {
-		v.Term.Success().Printfln("  ✓ Domain: %s", platform.DNS.Domain)
+		v.ok("Domain: %s", platform.DNS.Domain)
 	}
 
-	// Validate DNS if not skipped
-	if !v.SkipDNS && platform.DNS.Domain != "" {
-		v.Term.Info().Println()
-		v.Term.Info().Println("DNS Records:")
-		v.validateDNS(platform.DNS.Domain, &hasErrors)
+	// The keyring is read before the concurrent checks, it may request its passphrase
+	if err = v.validateRefs(&hasErrors); err != nil {
+		return false, err
 	}
 
-	// Validate mail authentication if not skipped
-	if !v.SkipMail && platform.DNS.Domain != "" {
-		v.Term.Info().Println()
-		v.Term.Info().Println("Mail Authentication:")
-		v.validateMailAuth(platform.DNS.Domain, &hasErrors)
+	// Records expected from platform.yaml, their values are compared with the public DNS
+	var expected []dns.Record
+	if platform.DNS.Domain != "" && (!v.SkipDNS || !v.SkipMail) {
+		expected, err = dns.Records(platform.DNS)
+		if err != nil {
+			v.fail("DNS configuration: %v", err)
+			hasErrors = true
+		}
 	}
 
-	// Check nodes directory
-	nodesDir := filepath.Join(instDir, "nodes")
-	nodeCount := 0
-	if nodeEntries, err := os.ReadDir(nodesDir); err == nil {
-		for _, nodeEntry := range nodeEntries {
-			if !nodeEntry.IsDir() && filepath.Ext(nodeEntry.Name()) == ".yaml" && nodeEntry.Name() != ".gitkeep" {
-				nodeCount++
-			}
+	// The checks of the network and of the nodes are independent, they run concurrently
+	domain := platform.DNS.Domain
+	var checks []check
+	if !v.SkipDNS && domain != "" {
+		checks = append(checks, check{"DNS records", "DNS Records", func(ctx context.Context, v *Validate, hasErrors *bool) {
+			v.validateDNS(ctx, domain, filterRecords(expected, "A", "AAAA", "MX"), hasErrors)
+		}})
+		if !v.SkipDNSSEC {
+			checks = append(checks, check{"DNSSEC", "DNS Records", func(ctx context.Context, v *Validate, hasErrors *bool) {
+				v.validateDNSSEC(ctx, domain, hasErrors)
+			}})
 		}
 	}
 
-	v.Term.Info().Println()
-	v.Term.Info().Println("Infrastructure:")
-	if nodeCount == 0 {
-		v.Term.Warning().Println("  ! No nodes provisioned")
-	} else {
-		v.Term.Success().Printfln("  ✓ Nodes: %d", nodeCount)
+	if !v.SkipMail && domain != "" {
+		checks = append(checks, check{"Mail authentication", "Mail Authentication", func(ctx context.Context, v *Validate, hasErrors *bool) {
+			v.validateMailAuth(ctx, platform.DNS, filterRecords(expected, "TXT"), hasErrors)
+		}})
+		if !v.SkipMTASTS {
+			checks = append(checks, check{"MTA-STS", "Mail Authentication", func(ctx context.Context, v *Validate, hasErrors *bool) {
+				v.validateMTASTS(ctx, domain, hasErrors)
+			}})
+		}
+		if !v.SkipTLSRPT {
+			checks = append(checks, check{"TLS-RPT", "Mail Authentication", func(ctx context.Context, v *Validate, hasErrors *bool) {
+				v.validateTLSRPT(ctx, domain, hasErrors)
+			}})
+		}
+		checks = append(checks, check{"Reverse DNS", "Reverse DNS", func(ctx context.Context, v *Validate, hasErrors *bool) {
+			v.validateReverseDNS(ctx, platform.DNS, hasErrors)
+		}})
+		if v.DeepMail {
+			checks = append(checks, check{"Mail servers", "Mail Servers", func(ctx context.Context, v *Validate, hasErrors *bool) {
+				v.validateSMTP(ctx, platform, hasErrors)
+			}})
+		}
 	}
 
-	v.Term.Info().Println()
-	if hasErrors {
-		v.Term.Error().Println("Validation failed with errors")
-		return fmt.Errorf("validation failed")
+	// Check the certificates served on HTTPS
+	if !v.SkipTLS && domain != "" {
+		checks = append(checks, check{"TLS certificates", "TLS Certificates", func(ctx context.Context, v *Validate, hasErrors *bool) {
+			v.validateTLS(ctx, platform, hasErrors)
+		}})
 	}
 
-	v.Term.Success().Println("Validation passed")
+	checks = append(checks, check{"Infrastructure", "Infrastructure", func(ctx context.Context, v *Validate, hasErrors *bool) {
+		v.validateInfrastructure(ctx, platform, hasErrors)
+	}})
+	v.runChecks(ctx, checks, checkTimeout, timeout, &hasErrors)
+
+	v.report.OK = !hasErrors
+	failures := 0
+	for _, c := range v.report.Checks {
+		if c.Status == StatusError {
+			failures++
+		}
+	}
+	metrics.Record(v.Name, metrics.Metric{Name: metrics.ValidationFailures, Value: float64(failures)})
+	return hasErrors, nil
+}
+
+// validateRefs checks the references of platform.yaml resolve, the keyring items must exist and not
+// be empty
+func (v *Validate) validateRefs(hasErrors *bool) error {
+	refs, err := schema.Refs(v.Name)
+	if err != nil || len(refs) == 0 {
+		return err
+	}
+	v.section("Credentials")
+	for _, r := range refs {
+		if err := api.CheckRef(v.Keyring, r); err != nil {
+			v.fail("%v", err)
+			*hasErrors = true
+			continue
+		}
+		v.ok("%s: %s", r.Path, r)
+	}
 	return nil
 }
 
-// validateDNS checks DNS records for the domain
-func (v *Validate) validateDNS(domain string, hasErrors *bool) {
+// validateInfrastructure checks the node files, then the servers of the metal provider
+func (v *Validate) validateInfrastructure(ctx context.Context, platform *schema.Platform, hasErrors *bool) {
+	nodes, nodeErrs := schema.ValidateNodes(schema.NodesDir(v.Name), platform)
+	nodeErrs = append(nodeErrs, schema.ValidateGroups(platform, nodes)...)
+	for _, err := range nodeErrs {
+		v.fail("%v", err)
+		*hasErrors = true
+	}
+	switch {
+	case len(nodes) == 0 && len(nodeErrs) == 0:
+		v.warn("No nodes provisioned")
+	case len(nodeErrs) == 0:
+		v.ok("Nodes: %d", len(nodes))
+	}
+	if !v.SkipInfra {
+		v.validateServers(ctx, platform, len(nodes), hasErrors)
+	}
+}
+
+// validateDNS compares the A, AAAA and MX records with the expected ones, or checks their presence
+// when none are configured
+func (v *Validate) validateDNS(ctx context.Context, domain string, expected []dns.Record, hasErrors *bool) {
+	if len(expected) > 0 {
+		v.compareRecords(ctx, expected, hasErrors)
+		return
+	}
+
 	// Check MX records
-	mxRecords, err := net.LookupMX(domain)
+	mxRecords, err := v.resolver.LookupMX(ctx, domain)
 	if err != nil || len(mxRecords) == 0 {
-		v.Term.Warning().Println("  ! MX records not found")
+		v.warn("MX records not found")
 	} else {
-		v.Term.Success().Printfln("  ✓ MX records: %d found", len(mxRecords))
+		v.ok("MX records: %d found", len(mxRecords))
 		for _, mx := range mxRecords {
-			v.Term.Info().Printfln("      %s (priority %d)", mx.Host, mx.Pref)
+			v.detail("%s (priority %d)", mx.Host, mx.Pref)
 		}
 	}
 
 	// Check A/AAAA records
-	ips, err := net.LookupIP(domain)
+	ips, err := v.resolver.LookupIPAddr(ctx, domain)
 	if err != nil || len(ips) == 0 {
-		v.Term.Warning().Println("  ! A/AAAA records not found")
+		v.warn("A/AAAA records not found")
 	} else {
-		v.Term.Success().Printfln("  ✓ A/AAAA records: %d found", len(ips))
+		v.ok("A/AAAA records: %d found", len(ips))
+	}
+}
+
+// validateDNSSEC checks the zone of the domain is signed and its records are validated
+func (v *Validate) validateDNSSEC(ctx context.Context, domain string, hasErrors *bool) {
+	status, err := dns.CheckDNSSEC(ctx, v.server, domain)
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr):
+		v.warn("DNSSEC not checked: %v", err)
+	case err != nil:
+		v.fail("DNSSEC: %v", err)
+		*hasErrors = true
+	case !status.DS && !status.DNSKEY:
+		v.warn("DNSSEC is not enabled for zone %s", status.Zone)
+	case !status.DS:
+		v.warn("DNSSEC keys of zone %s are published but no DS record in its parent zone", status.Zone)
+	case !status.DNSKEY:
+		v.fail("DNSSEC DS record of zone %s is published but the zone has no DNSKEY", status.Zone)
+		*hasErrors = true
+	case !status.Validated:
+		v.fail("DNSSEC records of %s aren't validated by %s", domain, v.server.Validator())
+		*hasErrors = true
+	default:
+		v.ok("DNSSEC signed and validated (zone %s)", status.Zone)
 	}
 }
 
-// validateMailAuth checks DKIM, DMARC, and SPF records
-func (v *Validate) validateMailAuth(domain string, hasErrors *bool) {
+// validateMailAuth compares the SPF, DKIM and DMARC records with the expected ones, or checks their
+// presence when none are configured
+func (v *Validate) validateMailAuth(ctx context.Context, cfg schema.DNSConfig, expected []dns.Record, hasErrors *bool) {
+	if len(expected) > 0 {
+		v.compareRecords(ctx, expected, hasErrors)
+	} else {
+		v.checkMailAuth(ctx, cfg.Domain)
+	}
+	v.validateDKIM(ctx, cfg.Domain, cfg.Mail.DKIM, expected)
+}
+
+// checkMailAuth checks the presence of the SPF and DMARC records
+func (v *Validate) checkMailAuth(ctx context.Context, domain string) {
 	// Check SPF record
-	txtRecords, _ := net.LookupTXT(domain)
+	txtRecords, _ := v.resolver.LookupTXT(ctx, domain)
 	hasSPF := false
 	for _, txt := range txtRecords {
 		if strings.HasPrefix(txt, "v=spf1") {
 			hasSPF = true
-			v.Term.Success().Println("  ✓ SPF record found")
+			v.ok("SPF record found")
 			break
 		}
 	}
 	if !hasSPF {
-		v.Term.Warning().Println("  ! SPF record not found")
+		v.warn("SPF record not found")
 	}
 
 	// Check DMARC record
-	dmarcRecords, _ := net.LookupTXT("_dmarc." + domain)
+	dmarcRecords, _ := v.resolver.LookupTXT(ctx, "_dmarc."+domain)
 	hasDMARC := false
 	for _, txt := range dmarcRecords {
 		if strings.HasPrefix(txt, "v=DMARC1") {
 			hasDMARC = true
-			v.Term.Success().Println("  ✓ DMARC record found")
+			v.ok("DMARC record found")
 			break
 		}
 	}
 	if !hasDMARC {
-		v.Term.Warning().Println("  ! DMARC record not found")
+		v.warn("DMARC record not found")
 	}
+}
 
-	// Check DKIM record (common selector: default)
-	dkimRecords, _ := net.LookupTXT("default._domainkey." + domain)
-	hasDKIM := false
-	for _, txt := range dkimRecords {
-		if strings.Contains(txt, "v=DKIM1") {
-			hasDKIM = true
-			v.Term.Success().Println("  ✓ DKIM record found (selector: default)")
-			break
+// validateDKIM checks the presence of the DKIM records of the selectors without expected record,
+// discovering the common selectors when none is declared, and the removal of the retired ones
+func (v *Validate) validateDKIM(ctx context.Context, domain string, cfg schema.DKIMConfig, expected []dns.Record) {
+	compared := make(map[string]bool, len(expected))
+	for _, r := range expected {
+		compared[r.Name] = true
+	}
+	selectors := dns.DKIMSelectors(cfg)
+	discover := len(selectors) == 0
+	if discover {
+		selectors = dns.CommonDKIMSelectors
+	}
+
+	var found []string
+	for _, selector := range selectors {
+		name := selector + "._domainkey." + domain
+		if compared[name] {
+			continue
+		}
+		switch {
+		case v.hasDKIM(ctx, name):
+			found = append(found, selector)
+			if !discover {
+				v.ok("DKIM record found (selector: %s)", selector)
+			}
+		case !discover:
+			v.warn("DKIM record not found (selector: %s)", selector)
+		}
+	}
+	if discover {
+		if len(found) == 0 {
+			v.warn("DKIM record not found with common selectors, declare yours in dns.mail.dkim")
+		} else {
+			v.ok("DKIM records found (selectors: %s)", strings.Join(found, ", "))
+		}
+	}
+
+	for _, selector := range cfg.Retired {
+		if v.hasDKIM(ctx, selector+"._domainkey."+domain) {
+			v.warn("Retired DKIM selector %s is still published, run platform:dns:apply", selector)
+		}
+	}
+}
+
+// hasDKIM reports whether the name has a DKIM record
+func (v *Validate) hasDKIM(ctx context.Context, name string) bool {
+	records, _ := v.resolver.LookupTXT(ctx, name)
+	return slices.ContainsFunc(records, func(txt string) bool {
+		return strings.Contains(txt, "v=DKIM1")
+	})
+}
+
+// compareRecords compares the records of the public DNS with the expected ones, printing the
+// mismatching ones side by side
+func (v *Validate) compareRecords(ctx context.Context, expected []dns.Record, hasErrors *bool) {
+	for _, c := range dns.CompareWith(ctx, v.resolver, expected) {
+		switch {
+		case c.Match():
+			values := make([]string, 0, len(c.Expected))
+			for _, r := range c.Expected {
+				values = append(values, recordValue(r))
+			}
+			v.ok("%s %s: %s", c.Type, c.Name, strings.Join(values, ", "))
+		case c.Err != nil:
+			v.fail("%s %s: %v", c.Type, c.Name, c.Err)
+			*hasErrors = true
+		default:
+			v.fail("%s %s doesn't match platform.yaml", c.Type, c.Name)
+			v.printDiff(c)
+			*hasErrors = true
 		}
 	}
-	if !hasDKIM {
-		v.Term.Warning().Println("  ! DKIM record not found (selector: default)")
+}
+
+// printDiff prints the expected and actual records side by side, matching records on the same row
+func (v *Validate) printDiff(c dns.Comparison) {
+	rows := make([][2]string, len(c.Expected))
+	used := make([]bool, len(c.Actual))
+	width := len("expected")
+	for i, e := range c.Expected {
+		rows[i][0] = recordValue(e)
+		width = max(width, len(rows[i][0]))
+		for j, a := range c.Actual {
+			if !used[j] && e.SameContent(a) {
+				rows[i][1], used[j] = recordValue(a), true
+				break
+			}
+		}
+	}
+	next := 0
+	for j, a := range c.Actual {
+		if used[j] {
+			continue
+		}
+		for next < len(rows) && rows[next][1] != "" {
+			next++
+		}
+		if next == len(rows) {
+			rows = append(rows, [2]string{})
+		}
+		rows[next][1] = recordValue(a)
+	}
+
+	v.detail("%-*s  %s", width, "expected", "actual")
+	for _, row := range rows {
+		if row[1] == "" {
+			row[1] = "-"
+		}
+		if row[0] == "" {
+			row[0] = "-"
+		}
+		v.detail("%-*s  %s", width, row[0], row[1])
+	}
+}
+
+// recordValue returns the data of a record, long TXT content is shortened in the middle
+func recordValue(r dns.Record) string {
+	const maxLen = 60
+	switch r.Type {
+	case "MX":
+		return fmt.Sprintf("%d %s", r.Priority, r.Content)
+	case "TXT":
+		if len(r.Content) > maxLen {
+			return fmt.Sprintf("%q", r.Content[:maxLen/2-2]+"..."+r.Content[len(r.Content)-maxLen/2+1:])
+		}
+		return fmt.Sprintf("%q", r.Content)
+	default:
+		return r.Content
+	}
+}
+
+// filterRecords returns the records of the types
+func filterRecords(records []dns.Record, types ...string) []dns.Record {
+	var filtered []dns.Record
+	for _, r := range records {
+		if slices.Contains(types, r.Type) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// validateReverseDNS checks the PTR records of the mail servers and of dns.reverse in the public DNS
+func (v *Validate) validateReverseDNS(ctx context.Context, cfg schema.DNSConfig, hasErrors *bool) {
+	records, err := dns.PTRRecords(cfg)
+	if err != nil {
+		v.section("Reverse DNS")
+		v.fail("%v", err)
+		*hasErrors = true
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	v.section("Reverse DNS")
+	wrong := make(map[string]dns.Change)
+	for _, c := range dns.CheckReverseWith(ctx, v.resolver, records) {
+		wrong[c.Record.Name] = c
+	}
+	for _, r := range records {
+		c, ok := wrong[r.Name]
+		switch {
+		case !ok:
+			v.ok("%s -> %s", r.Name, r.Content)
+		case c.Action == dns.ChangeCreate:
+			v.warn("%s has no PTR record, expected %s", r.Name, r.Content)
+		default:
+			v.fail("%s -> %s, expected %s", r.Name, c.Old.Content, r.Content)
+			*hasErrors = true
+		}
 	}
 }
@@ -0,0 +1,174 @@
+package validate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+)
+
+// DefaultWatchInterval is the default interval between the validations of --watch
+const DefaultWatchInterval = 5 * time.Minute
+
+// Transition is a change of the status of a check between two validations of --watch
+type Transition struct {
+	Time     time.Time `json:"time" yaml:"time"`
+	Platform string    `json:"platform" yaml:"platform"`
+	Check    string    `json:"check" yaml:"check"`                   // e.g. DNSSEC, or the section of the basic checks
+	From     string    `json:"from,omitempty" yaml:"from,omitempty"` // Empty on the first validation
+	To       string    `json:"to" yaml:"to"`
+	Messages []string  `json:"messages,omitempty" yaml:"messages,omitempty"` // Warnings and errors of the check
+}
+
+// checkStatus is the status of a check, the worst of its results
+type checkStatus struct {
+	name     string
+	status   string
+	messages []string
+}
+
+// watch validates the platform at each interval until interrupted, printing the status of the checks
+// first, then their transitions only. Transitions are posted to the notification webhook if set.
+func (v *Validate) watch(ctx context.Context) error {
+	interval := DefaultWatchInterval
+	if v.Interval != "" {
+		d, err := time.ParseDuration(v.Interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid interval %q", v.Interval)
+		}
+		interval = d
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	v.buffered = true
+	if !v.quiet() {
+		v.Term.Info().Printfln("Watching platform %q every %s, press Ctrl-C to stop", v.Name, interval)
+	}
+	var previous map[string]string
+	for {
+		if _, err := v.run(ctx); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			// Checks interrupted by Ctrl-C failed, they aren't transitions
+			return nil
+		}
+
+		current := make(map[string]string)
+		now := time.Now()
+		for _, s := range checkStatuses(v.report.Checks) {
+			current[s.name] = s.status
+			from, seen := previous[s.name]
+			if previous != nil && seen && from == s.status {
+				continue
+			}
+			t := Transition{Time: now, Platform: v.Name, Check: s.name, From: from, To: s.status, Messages: s.messages}
+			if err := v.printTransition(t); err != nil {
+				return err
+			}
+			if previous != nil && v.Notify != "" {
+				v.notify(ctx, t)
+			}
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkStatuses returns the status of each check of the results, in their order. The basic checks
+// are grouped by section.
+func checkStatuses(checks []Check) []checkStatus {
+	var statuses []checkStatus
+	index := make(map[string]int)
+	for _, c := range checks {
+		name := cmp.Or(c.check, c.Section)
+		i, ok := index[name]
+		if !ok {
+			i = len(statuses)
+			index[name] = i
+			statuses = append(statuses, checkStatus{name: name, status: StatusOK})
+		}
+		s := &statuses[i]
+		if severity(c.Status) > severity(s.status) {
+			s.status = c.Status
+		}
+		if c.Status != StatusOK {
+			s.messages = append(s.messages, c.Message)
+		}
+	}
+	return statuses
+}
+
+// severity orders the statuses of the checks
+func severity(status string) int {
+	switch status {
+	case StatusError:
+		return 2
+	case StatusWarning:
+		return 1
+	}
+	return 0
+}
+
+// printTransition prints a transition, as a JSON line with -o json and a YAML document with -o yaml
+func (v *Validate) printTransition(t Transition) error {
+	switch format, _ := output.Parse(v.Format); {
+	case format == output.JSON:
+		line, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(line))
+		return nil
+	case format == output.YAML:
+		fmt.Println("---")
+		return output.Render(os.Stdout, v.Format, t, nil)
+	case v.quiet():
+		return output.Render(os.Stdout, v.Format, t, nil)
+	}
+
+	change := t.To
+	if t.From != "" {
+		change = t.From + " → " + t.To
+	}
+	stamp := t.Time.Format(time.TimeOnly)
+	switch t.To {
+	case StatusOK:
+		v.Term.Success().Printfln("[%s] ✓ %s: %s", stamp, t.Check, change)
+	case StatusWarning:
+		v.Term.Warning().Printfln("[%s] ! %s: %s", stamp, t.Check, change)
+	default:
+		v.Term.Error().Printfln("[%s] ✗ %s: %s", stamp, t.Check, change)
+	}
+	for _, msg := range t.Messages {
+		v.Term.Printfln("      %s", msg)
+	}
+	return nil
+}
+
+// notify posts a transition to the notification webhook as JSON
+func (v *Validate) notify(ctx context.Context, t Transition) {
+	body, err := json.Marshal(t)
+	if err == nil {
+		header := http.Header{"Content-Type": {"application/json"}}
+		err = api.Request(ctx, http.MethodPost, v.Notify, header, body, nil)
+	}
+	if err != nil {
+		v.Log.Warn("failed to send the notification", "url", v.Notify, "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package platform
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/launchrctl/launchr/pkg/jsonschema"
+	"github.com/plasmash/plasmactl-platform/internal/userconfig"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+// optionSource is a config file providing defaults of action arguments and options
+type optionSource interface {
+	lookup(key string) (any, bool, error)
+}
+
+// repoConfig is the per-repository .plasmactl/config.yaml
+type repoConfig struct {
+	cfg launchr.Config
+}
+
+func (c repoConfig) lookup(key string) (any, bool, error) {
+	if c.cfg == nil || !c.cfg.Exists(key) {
+		return nil, false, nil
+	}
+	var v any
+	if err := c.cfg.Get(key, &v); err != nil {
+		return nil, false, err
+	}
+	return v, v != nil, nil
+}
+
+// globalConfig is the user-wide config, e.g. ~/.config/plasmactl/config.yaml
+type globalConfig struct {
+	userconfig.Config
+}
+
+func (c globalConfig) lookup(key string) (any, bool, error) {
+	v, ok := c.Lookup(key)
+	return v, ok, nil
+}
+
+// applyConfigDefaults sets arguments and options which weren't passed on the command line
+// from the config sections, e.g. platform.up.gitlab_domain for --gitlab-domain.
+// The per-repository config takes precedence over the global one, and later sections over earlier ones.
+func applyConfigDefaults(a *action.Action, cfg launchr.Config, sections ...string) error {
+	global, err := userconfig.Load()
+	if err != nil {
+		return err
+	}
+	sources := []optionSource{repoConfig{cfg}, globalConfig{global}}
+
+	input := a.Input()
+	def := a.ActionDef()
+	for _, p := range def.Arguments {
+		if input.IsArgChanged(p.Name) {
+			continue
+		}
+		if v, ok, err := configDefault(sources, sections, p); err != nil {
+			return err
+		} else if ok {
+			input.SetArg(p.Name, v)
+		}
+	}
+	for _, p := range def.Options {
+		if input.IsOptChanged(p.Name) {
+			continue
+		}
+		if v, ok, err := configDefault(sources, sections, p); err != nil {
+			return err
+		} else if ok {
+			input.SetOpt(p.Name, v)
+		}
+	}
+	return nil
+}
+
+// configDefault returns the config value of a parameter, if any
+func configDefault(sources []optionSource, sections []string, p *action.DefParameter) (any, bool, error) {
+	name := strings.ReplaceAll(p.Name, "-", "_")
+	for _, src := range sources {
+		for _, section := range slices.Backward(sections) {
+			key := section + "." + name
+			v, ok, err := src.lookup(key)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to read config %s: %w", key, err)
+			}
+			if !ok {
+				continue
+			}
+			v, err = jsonschema.EnsureType(p.Type, v)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid config %s: %w", key, err)
+			}
+			return v, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// requiredArg returns a string argument, which must be passed on the command line or set in the config section
+func requiredArg(input *action.Input, section, name string) (string, error) {
+	v, _ := input.Arg(name).(string)
+	if v == "" {
+		return "", failure.Newf(failure.ErrConfigMissing, "%s is required: pass it as argument or set %s.%s in config", name, section, name)
+	}
+	return v, nil
+}
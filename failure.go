@@ -0,0 +1,69 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/metrics"
+)
+
+// newRuntime returns the runtime of an action running fn. Its classified failures are reported with
+// their code and hint, and exit with the exit code of their class. With JSON logs, the failures are
+// logged with their code too. The metrics recorded by the action are sent once it ends, successfully
+// or not.
+func newRuntime(fn action.FnRuntimeCallback) action.Runtime {
+	return action.NewFnRuntime(func(ctx context.Context, a *action.Action) error {
+		err := fn(ctx, a)
+		log, _ := getLoggerTerm(a)
+		// Metrics must not fail the operation they measure
+		if merr := metrics.Flush(context.WithoutCancel(ctx)); merr != nil {
+			log.Warn("failed to send the metrics", "error", merr)
+		}
+		if err != nil && output.JSONLogs() {
+			log.Error("action failed", "error", err, "code", failure.CodeOf(err))
+		}
+		return surface(err)
+	})
+}
+
+// surface returns the error of an action, reporting the class of a classified failure
+func surface(err error) error {
+	class := failure.Classify(err)
+	var reported *classifiedError
+	if class == nil || errors.As(err, &reported) {
+		// Failures of the actions executed by the action are already reported
+		return err
+	}
+	return &classifiedError{err: err, class: class}
+}
+
+// classifiedError is a classified failure of an action, as reported to the user
+type classifiedError struct {
+	err   error
+	class *failure.Class
+}
+
+// Error implements error interface
+func (e *classifiedError) Error() string {
+	return fmt.Sprintf("%s [%s]\nHint: %s", e.err, e.class.Code, failure.HintOf(e.err))
+}
+
+// Unwrap returns the failure
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// As sets the target [launchr.ExitError] to the exit code of the class, the one of the application
+func (e *classifiedError) As(target any) bool {
+	t, ok := target.(*launchr.ExitError)
+	if ok {
+		*t = launchr.NewExitError(e.class.ExitCode, e.Error()).(launchr.ExitError)
+	}
+	return ok
+}
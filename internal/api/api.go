@@ -0,0 +1,67 @@
+// Package api holds the helpers shared by the clients of the provider APIs: JSON requests and the
+// API tokens of the keyring
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+// Error is the error status of an API response
+type Error struct {
+	Status int
+	Body   string
+}
+
+// Error implements error interface
+func (e *Error) Error() string {
+	// Some APIs echo the credentials of rejected requests
+	return fmt.Sprintf("API returned status %d: %s", e.Status, redact.String(e.Body))
+}
+
+// Is reports whether the target is failure.ErrProviderAuth for the responses rejecting the credentials
+func (e *Error) Is(target error) bool {
+	return target == failure.ErrProviderAuth && (e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden)
+}
+
+// Request sends a request to an API and decodes its JSON response into v, if not nil
+func Request(ctx context.Context, method, url string, header http.Header, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return &Error{Status: res.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	if v == nil || len(data) == 0 {
+		return nil
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unexpected response with status %s: %s", res.Status, string(data))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+)
+
+// Purposes of the credentials of the keyring, namespacing them with the platform
+const (
+	PurposeCI           = "ci"            // CI pipelines, e.g. the GitLab access token
+	PurposeProvider     = "provider"      // Metal provider API
+	PurposeDNS          = "dns"           // DNS provider API
+	PurposeArtifactRepo = "artifact-repo" // Artifact repository of the Platform Images
+)
+
+// Purposes returns the purposes of the credentials
+func Purposes() []string {
+	return []string{PurposeCI, PurposeProvider, PurposeDNS, PurposeArtifactRepo}
+}
+
+// scopeSeparator separates the platform, purpose and key of the keys and URLs of a scope
+const scopeSeparator = ":"
+
+// Scope namespaces the keyring credentials of a platform for a purpose, so platforms sharing a
+// provider or GitLab domain can use different accounts. Items are looked up in the scope first, then
+// shared by all the platforms as before namespacing. The zero Scope only reads shared items.
+type Scope struct {
+	Platform string
+	Purpose  string
+}
+
+// NewScope returns the scope of the credentials of a platform for a purpose
+func NewScope(platform, purpose string) Scope {
+	return Scope{Platform: platform, Purpose: purpose}
+}
+
+// IsShared reports whether the scope has no platform, its items are shared by all the platforms
+func (s Scope) IsShared() bool {
+	return s.Platform == ""
+}
+
+// Key returns the keyring key of a value of the scope, e.g. ski-dev:provider:scaleway_api_token
+func (s Scope) Key(key string) string {
+	if s.IsShared() {
+		return key
+	}
+	return strings.Join([]string{s.Platform, s.Purpose, key}, scopeSeparator)
+}
+
+// URL returns the keyring URL of the credentials of a URL of the scope, e.g. ski-dev:ci:gitlab.example.com
+func (s Scope) URL(url string) string {
+	return s.Key(url)
+}
+
+// Token returns an API token of the scope, requesting it on the terminal and adding it to the scope if
+// missing in the scope and shared items
+func (s Scope) Token(k keyring.Keyring, log *launchr.Logger, term *launchr.Terminal, key string) (string, error) {
+	if k == nil {
+		return "", fmt.Errorf("keyring is not available to read %q", key)
+	}
+	item, err := s.item(k, log, key)
+	save := false
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return "", err
+		}
+		term.Info().Printfln("Please add the API token %q to the keyring", s.Key(key))
+		item = keyring.KeyValueItem{Key: s.Key(key), Value: ""}
+		if err = keyring.RequestKeyValueFromTty(&item); err != nil {
+			return "", err
+		}
+		if err = k.AddItem(item); err != nil {
+			return "", err
+		}
+		save = true
+	}
+
+	value, ok := item.Value.(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("keyring value %q must be a non-empty string", item.Key)
+	}
+	if save {
+		if err = k.Save(); err != nil {
+			log.Error("error during saving keyring file", "error", err)
+		}
+	}
+	return value, nil
+}
+
+// Lookup returns a value of the scope without requesting it on the terminal if missing
+func (s Scope) Lookup(k keyring.Keyring, log *launchr.Logger, key string) (string, error) {
+	if k == nil {
+		return "", fmt.Errorf("keyring is not available to read %q", key)
+	}
+	item, err := s.item(k, log, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrMissingItem, s.Key(key))
+	}
+	if err != nil {
+		return "", err
+	}
+	if value, ok := item.Value.(string); ok && value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("keyring value %q must be a non-empty string", item.Key)
+}
+
+// Credentials returns the credentials of a URL of the scope, the error is [keyring.ErrNotFound] if
+// they are missing in the scope and shared items
+func (s Scope) Credentials(k keyring.Keyring, log *launchr.Logger, url string) (keyring.CredentialsItem, error) {
+	if !s.IsShared() {
+		creds, err := k.GetForURL(s.URL(url))
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return creds, err
+		}
+	}
+	creds, err := k.GetForURL(url)
+	if err == nil && !s.IsShared() {
+		s.logShared(log, url)
+	}
+	return creds, err
+}
+
+// item returns the keyring item of a key of the scope, or the shared one
+func (s Scope) item(k keyring.Keyring, log *launchr.Logger, key string) (keyring.KeyValueItem, error) {
+	if !s.IsShared() {
+		item, err := k.GetForKey(s.Key(key))
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return item, err
+		}
+	}
+	item, err := k.GetForKey(key)
+	if err == nil && !s.IsShared() {
+		s.logShared(log, key)
+	}
+	return item, err
+}
+
+// logShared hints at the migration of the shared items read for a platform
+func (s Scope) logShared(log *launchr.Logger, key string) {
+	if log != nil {
+		log.Debug("using the shared keyring item, copy it to the platform with platform:credentials:migrate", "key", key, "platform", s.Platform, "purpose", s.Purpose)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ErrMissingItem is returned for the keyring items referenced by platform.yaml which don't exist, it's
+// a missing configuration
+var ErrMissingItem = failure.Wrap(failure.ErrConfigMissing, errors.New("keyring item is missing"),
+	"add the item with plasmactl keyring:set")
+
+// keyringRef matches the keyring references of platform.yaml values, e.g. {{ .keyring.scaleway_api_token }}
+var keyringRef = regexp.MustCompile(`^\{\{\s*\.keyring\.([A-Za-z0-9_.-]+)\s*\}\}$`)
+
+// KeyringKey returns the keyring key referenced by a platform.yaml value like {{ .keyring.<key> }}
+func KeyringKey(value string) (string, bool) {
+	m := keyringRef.FindStringSubmatch(value)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// Token returns an API token shared by all the platforms, requesting it on the terminal if missing
+func Token(k keyring.Keyring, log *launchr.Logger, term *launchr.Terminal, key string) (string, error) {
+	return Scope{}.Token(k, log, term, key)
+}
+
+// Lookup returns a value shared by all the platforms without requesting it on the terminal if missing
+func Lookup(k keyring.Keyring, key string) (string, error) {
+	return Scope{}.Lookup(k, nil, key)
+}
+
+// CheckRef checks a reference of platform.yaml resolves: keyring items must exist and not be empty,
+// and other sources aren't supported
+func CheckRef(k keyring.Keyring, r schema.Ref) error {
+	switch r.Source {
+	case schema.KeyringSource:
+		if _, err := Lookup(k, r.Key); err != nil {
+			return fmt.Errorf("%s: %w", r.Path, err)
+		}
+		return nil
+	case "":
+		return fmt.Errorf("%s: malformed reference %q, expected {{ .keyring.<key> }}", r.Path, r.Value)
+	}
+	return fmt.Errorf("%s: unsupported reference %s, only keyring items are resolved", r.Path, r)
+}
@@ -0,0 +1,60 @@
+// Package atomicfile writes the files persisted by the plugin atomically: the data is written to a
+// temporary file of the same directory, synced and renamed over the file, so a crash or an interrupted
+// command leaves either the previous file or the new one, never a truncated one.
+package atomicfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to the named file atomically. The permissions of an existing file are kept,
+// a new file is created with perm.
+func WriteFile(name string, data []byte, perm fs.FileMode) (err error) {
+	if info, serr := os.Stat(name); serr == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(serr, fs.ErrNotExist) {
+		return serr
+	}
+
+	dir := filepath.Dir(name)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir persists the rename in the directory, where the platform supports syncing directories
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
@@ -0,0 +1,236 @@
+package cert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/dns"
+	dnsprovider "github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"golang.org/x/crypto/acme"
+)
+
+// Manager issues the certificates of platforms, completing the DNS-01 challenges with their DNS provider
+type Manager struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring  keyring.Keyring
+	Password string // Vault password encrypting the private keys
+
+	Directory          string        // ACME directory URL, overrides cert.directory of platform.yaml
+	PropagationTimeout time.Duration // Maximum time to wait for the challenge records
+}
+
+// challenge is a pending DNS-01 challenge of an authorization
+type challenge struct {
+	authzURL string
+	chal     *acme.Challenge
+	record   dnsprovider.Record
+}
+
+// Issue obtains a certificate of the platform names and stores it in its certificate directory,
+// with the private key encrypted with the vault password
+func (m *Manager) Issue(ctx context.Context, name string, platform *schema.Platform) (Status, error) {
+	if m.Password == "" {
+		return Status{}, failure.Newf(failure.ErrConfigMissing, "vault password is required to store the private keys, add vaultpass to the keyring")
+	}
+	if platform.DNS.Provider == "" || platform.DNS.Provider == dnsprovider.ProviderManual {
+		return Status{}, errors.New("DNS-01 challenges need a DNS provider, dnsprovider.provider is manual")
+	}
+	names, err := Names(platform)
+	if err != nil {
+		return Status{}, err
+	}
+
+	client, err := m.client(ctx, name, platform.Cert)
+	if err != nil {
+		return Status{}, err
+	}
+	m.Term().Info().Printfln("Ordering certificate of %v from %s...", names, client.DirectoryURL)
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(names...))
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to order certificate: %w", err)
+	}
+
+	challenges, err := m.challenges(ctx, client, order)
+	if err != nil {
+		return Status{}, err
+	}
+	if len(challenges) > 0 {
+		if err = m.solve(ctx, client, name, platform.DNS, challenges); err != nil {
+			return Status{}, err
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return Status{}, fmt.Errorf("order failed: %w", err)
+	}
+	key, err := newKey()
+	if err != nil {
+		return Status{}, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: names}, key)
+	if err != nil {
+		return Status{}, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to finalize order: %w", err)
+	}
+
+	certFile, keyFile := files(name, names)
+	if err = saveKey(keyFile, key, m.Password); err != nil {
+		return Status{}, err
+	}
+	if err = saveCert(certFile, chain); err != nil {
+		return Status{}, err
+	}
+	return LoadStatus(name, platform)
+}
+
+// Renew issues a new certificate when the current one expires within the given duration, doesn't
+// cover the names of the platform or doesn't exist. It reports whether the certificate was renewed.
+func (m *Manager) Renew(ctx context.Context, name string, platform *schema.Platform, before time.Duration, force bool) (Status, bool, error) {
+	names, err := Names(platform)
+	if err != nil {
+		return Status{}, false, err
+	}
+	status, err := LoadStatus(name, platform)
+	switch {
+	case errors.Is(err, ErrNotIssued):
+		m.Term().Info().Println("No certificate issued yet")
+	case err != nil:
+		return Status{}, false, err
+	case force:
+	case !status.Covers(names):
+		m.Term().Info().Printfln("Certificate doesn't cover %v", names)
+	case status.Remaining(time.Now()) > before:
+		return status, false, nil
+	}
+
+	status, err = m.Issue(ctx, name, platform)
+	return status, err == nil, err
+}
+
+// client returns an ACME client with the account key of the platform, registering the account on first use
+func (m *Manager) client(ctx context.Context, name string, cfg schema.CertConfig) (*acme.Client, error) {
+	directory := m.Directory
+	if directory == "" {
+		directory = cfg.Directory
+	}
+	if directory == "" {
+		directory = LetsEncryptURL
+	}
+
+	path := filepath.Join(Dir(name), accountKeyFile)
+	key, err := loadKey(path, m.Password)
+	created := errors.Is(err, fs.ErrNotExist)
+	if created {
+		m.Log().Debug("creating ACME account key", "path", path)
+		key, err = newKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client := &acme.Client{Key: key, DirectoryURL: directory, UserAgent: "plasmactl-platform"}
+	account := &acme.Account{}
+	if cfg.Email != "" {
+		account.Contact = []string{"mailto:" + cfg.Email}
+	}
+	if _, err = client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("failed to register ACME account: %w", err)
+	}
+	if created {
+		if err = saveKey(path, key, m.Password); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// challenges returns the DNS-01 challenges of the pending authorizations of the order
+func (m *Manager) challenges(ctx context.Context, client *acme.Client, order *acme.Order) ([]challenge, error) {
+	var challenges []challenge
+	for _, url := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get authorization: %w", err)
+		}
+		if authz.Status == acme.StatusValid {
+			m.Log().Debug("authorization already valid", "name", authz.Identifier.Value)
+			continue
+		}
+
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == "dns-01" {
+				chal = c
+				break
+			}
+		}
+		if chal == nil {
+			return nil, fmt.Errorf("no DNS-01 challenge offered for %s", authz.Identifier.Value)
+		}
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, challenge{
+			authzURL: url,
+			chal:     chal,
+			record:   dnsprovider.ACMEChallengeRecord(authz.Identifier.Value, value),
+		})
+	}
+	return challenges, nil
+}
+
+// solve publishes the challenge records with the DNS provider, waits for them on the authoritative
+// servers, and has the CA validate them. The records are deleted once done.
+func (m *Manager) solve(ctx context.Context, client *acme.Client, name string, cfg schema.DNSConfig, challenges []challenge) error {
+	dm := &dns.Manager{WithLogger: m.WithLogger, WithTerm: m.WithTerm, Keyring: m.Keyring, Platform: name}
+	provider, err := dm.NewProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	records := make([]dnsprovider.Record, 0, len(challenges))
+	for _, c := range challenges {
+		records = append(records, c.record)
+	}
+	m.Term().Info().Printfln("Publishing %d DNS-01 challenge record(s)...", len(records))
+	if _, err = provider.EnsureRecords(ctx, records); err != nil {
+		return fmt.Errorf("failed to publish challenge records: %w", err)
+	}
+	defer func() {
+		// Challenge records are removed even if the order was interrupted
+		if _, err := provider.DeleteRecords(context.WithoutCancel(ctx), records); err != nil {
+			m.Term().Warning().Printfln("Failed to delete the challenge records: %v", err)
+		}
+	}()
+
+	if err = dm.WaitAuthoritative(ctx, cfg, records, m.PropagationTimeout); err != nil {
+		return err
+	}
+	for _, c := range challenges {
+		if _, err = client.Accept(ctx, c.chal); err != nil {
+			return fmt.Errorf("failed to accept challenge of %s: %w", c.record.Name, err)
+		}
+	}
+	for _, c := range challenges {
+		if _, err = client.WaitAuthorization(ctx, c.authzURL); err != nil {
+			return fmt.Errorf("validation of %s failed: %w", c.record.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package cert issues and renews the TLS certificate of platform domains with ACME DNS-01 challenges,
+// completed with the DNS provider of the platform. The private keys are stored encrypted with the
+// ansible vault password, so the deployment can install them.
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ACME directories of Let's Encrypt
+const (
+	LetsEncryptURL        = "https://acme-v02.api.letsencrypt.org/directory"
+	LetsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+// DefaultRenewBefore is the remaining validity under which certificates are renewed
+const DefaultRenewBefore = 30 * 24 * time.Hour
+
+// accountKeyFile is the file of the ACME account key in the certificate directory
+const accountKeyFile = "acme_account.key"
+
+// ErrNotIssued is returned when the platform has no certificate yet
+var ErrNotIssued = errors.New("certificate not issued")
+
+// Status describes the certificate of a platform
+type Status struct {
+	Names     []string  `json:"names"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	CertFile  string    `json:"cert_file"`
+	KeyFile   string    `json:"key_file"`
+	KeyStored bool      `json:"key_stored"` // The encrypted private key is stored next to the certificate
+}
+
+// Remaining returns the validity left at the given time, negative once expired
+func (s Status) Remaining(now time.Time) time.Duration {
+	return s.NotAfter.Sub(now)
+}
+
+// Dir returns the certificate directory of the named platform, relative to the repository root
+func Dir(name string) string {
+	return filepath.Join(schema.PlatformDir(name), "certs")
+}
+
+// Names returns the names of the certificate of the platform, the domain and its wildcard by default
+func Names(platform *schema.Platform) ([]string, error) {
+	if len(platform.Cert.Names) > 0 {
+		names := make([]string, 0, len(platform.Cert.Names))
+		for _, n := range platform.Cert.Names {
+			names = append(names, strings.TrimSuffix(strings.ToLower(n), "."))
+		}
+		return names, nil
+	}
+	domain := strings.TrimSuffix(strings.ToLower(platform.DNS.Domain), ".")
+	if domain == "" {
+		return nil, errors.New("dns.domain is not configured")
+	}
+	return []string{domain, "*." + domain}, nil
+}
+
+// files returns the certificate and private key files of the platform, named after its first name
+func files(name string, names []string) (certFile, keyFile string) {
+	base := strings.ReplaceAll(names[0], "*", "_")
+	return filepath.Join(Dir(name), base+".crt"), filepath.Join(Dir(name), base+".key")
+}
+
+// LoadStatus reads the certificate of the platform, the error wraps [ErrNotIssued] if there is none
+func LoadStatus(name string, platform *schema.Platform) (Status, error) {
+	names, err := Names(platform)
+	if err != nil {
+		return Status{}, err
+	}
+	certFile, keyFile := files(name, names)
+	data, err := os.ReadFile(certFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Status{}, fmt.Errorf("%w: no certificate at %s", ErrNotIssued, certFile)
+	}
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return Status{}, fmt.Errorf("no PEM certificate in %s", certFile)
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to parse certificate %s: %w", certFile, err)
+	}
+
+	s := Status{
+		Names:     leaf.DNSNames,
+		Issuer:    leaf.Issuer.String(),
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		CertFile:  certFile,
+		KeyFile:   keyFile,
+	}
+	_, err = os.Stat(keyFile)
+	s.KeyStored = err == nil
+	return s, nil
+}
+
+// Covers reports whether the certificate holds all the names
+func (s Status) Covers(names []string) bool {
+	for _, n := range names {
+		if !slices.Contains(s.Names, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// newKey generates an ECDSA P-256 private key
+func newKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// saveKey writes the private key as PKCS#8 PEM encrypted with the vault password
+func saveKey(path string, key crypto.Signer, password string) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := encryptVault(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), password)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	if err = atomicfile.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadKey reads a private key encrypted with the vault password, the error wraps fs.ErrNotExist if there is none
+func loadKey(path, password string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = decryptVault(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM key in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T in %s", key, path)
+	}
+	return signer, nil
+}
+
+// saveCert writes the certificate chain as PEM
+func saveCert(path string, chain [][]byte) error {
+	var data []byte
+	for _, der := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	if err := atomicfile.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
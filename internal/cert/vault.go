@@ -0,0 +1,116 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// vaultHeader is the header of files encrypted with ansible-vault, format 1.1 with AES256
+const vaultHeader = "$ANSIBLE_VAULT;1.1;AES256"
+
+// vaultIterations is the PBKDF2 iteration count of ansible-vault
+const vaultIterations = 10000
+
+// errVaultPassword is returned when a vault file can't be decrypted with the password
+var errVaultPassword = errors.New("wrong vault password or corrupted vault file")
+
+// encryptVault encrypts data as ansible-vault does, so the deployment decrypts it with the vault password
+func encryptVault(data []byte, password string) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cipherKey, hmacKey, iv, err := vaultKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding to the AES block size, although CTR mode doesn't need it
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	plain := append(bytes.Clone(data), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(plain))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plain)
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+
+	body := hex.EncodeToString([]byte(hex.EncodeToString(salt) + "\n" + hex.EncodeToString(mac.Sum(nil)) + "\n" + hex.EncodeToString(ciphertext)))
+	var out strings.Builder
+	out.WriteString(vaultHeader + "\n")
+	for len(body) > 80 {
+		out.WriteString(body[:80] + "\n")
+		body = body[80:]
+	}
+	out.WriteString(body + "\n")
+	return []byte(out.String()), nil
+}
+
+// decryptVault decrypts data encrypted with ansible-vault format 1.1
+func decryptVault(data []byte, password string) ([]byte, error) {
+	header, body, _ := strings.Cut(string(data), "\n")
+	if strings.TrimSpace(header) != vaultHeader {
+		return nil, errors.New("not an ansible-vault AES256 file")
+	}
+	decoded, err := hex.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault file: %w", err)
+	}
+	parts := strings.Split(string(decoded), "\n")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid vault file")
+	}
+	var salt, sum, ciphertext []byte
+	for i, dst := range []*[]byte{&salt, &sum, &ciphertext} {
+		if *dst, err = hex.DecodeString(parts[i]); err != nil {
+			return nil, fmt.Errorf("invalid vault file: %w", err)
+		}
+	}
+
+	cipherKey, hmacKey, iv, err := vaultKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, errVaultPassword
+	}
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plain, ciphertext)
+	if n := len(plain); n > 0 {
+		pad := int(plain[n-1])
+		if pad == 0 || pad > aes.BlockSize || pad > n {
+			return nil, errVaultPassword
+		}
+		plain = plain[:n-pad]
+	}
+	return plain, nil
+}
+
+// vaultKeys derives the AES key, HMAC key and counter of a vault file from the password and salt
+func vaultKeys(password string, salt []byte) (cipherKey, hmacKey, iv []byte, err error) {
+	if password == "" {
+		return nil, nil, nil, errors.New("vault password is empty")
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, vaultIterations, 80)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return key[:32], key[32:64], key[64:], nil
+}
@@ -0,0 +1,182 @@
+package ci
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of artifact files fetched as Platform Images, with their signature bundles
+const (
+	imageArtifactExt  = ".pi"
+	bundleArtifactExt = ".pi.bundle"
+)
+
+// ArtifactsFile is the artifacts archive of a GitLab CI job
+type ArtifactsFile struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// GetLatestPipeline calls GitLab API "/projects/<projectID>/pipelines?ref=<branch>",
+// authenticated with <gitlabAccessToken> and returns the newest pipeline ID of the branch
+func (c *ContinuousIntegration) GetLatestPipeline(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, branchName string) (int, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines?ref=%s&per_page=1", gitlabDomain, projectID, url.QueryEscape(branchName))
+	c.Log().Debug("GitLab API URL for latest pipeline", "url", apiURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, newAPIError("getLatestPipeline", resp, body)
+	}
+
+	var pipelines []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &pipelines); err != nil {
+		return 0, err
+	}
+	if len(pipelines) == 0 {
+		return 0, fmt.Errorf("no pipeline found for branch %s", branchName)
+	}
+	return pipelines[0].ID, nil
+}
+
+// DownloadImages downloads the Platform Images built by successful jobs of the pipeline into destDir.
+// Only jobs named jobName are considered if it's not empty. It returns paths of the downloaded images.
+func (c *ContinuousIntegration) DownloadImages(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, pipelineID int, jobName, destDir string) ([]string, error) {
+	jobs, err := c.GetJobsInPipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, pipelineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve jobs in pipeline: %w", err)
+	}
+
+	var images []string
+	found := false
+	for _, job := range jobs {
+		if job.Status != "success" || job.ArtifactsFile == nil || (jobName != "" && job.Name != jobName) {
+			continue
+		}
+		found = true
+		downloaded, err := c.downloadJobImages(ctx, gitlabDomain, gitlabAccessToken, projectID, job, destDir)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, downloaded...)
+	}
+
+	switch {
+	case jobName != "" && !found:
+		return nil, fmt.Errorf("no successful %s job with artifacts in pipeline %d", jobName, pipelineID)
+	case len(images) == 0:
+		return nil, fmt.Errorf("no Platform Image artifact found in pipeline %d", pipelineID)
+	}
+	return images, nil
+}
+
+// downloadJobImages fetches the artifacts archive of the job and extracts its Platform Images
+func (c *ContinuousIntegration) downloadJobImages(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, job Job, destDir string) ([]string, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/jobs/%d/artifacts", gitlabDomain, projectID, job.ID)
+	c.Log().Debug("GitLab API URL for downloading artifacts", "url", apiURL)
+	c.Term().Info().Printfln("Downloading artifacts of %s job (%d bytes)...", job.Name, job.ArtifactsFile.Size)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitLab API downloadArtifacts returned status %s: %s", resp.Status, string(body))
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", destDir, err)
+	}
+
+	// Zip archives need random access, keep the download next to its destination
+	archive, err := os.CreateTemp(destDir, ".artifacts-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifacts file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	size, err := io.Copy(archive, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifacts of %s job: %w", job.Name, err)
+	}
+
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts of %s job: %w", job.Name, err)
+	}
+
+	var images []string
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, ".") ||
+			!(strings.HasSuffix(name, imageArtifactExt) || strings.HasSuffix(name, bundleArtifactExt)) {
+			continue
+		}
+		dest := filepath.Join(destDir, name)
+		if err := extractArtifact(f, dest); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", f.Name, err)
+		}
+		if strings.HasSuffix(name, imageArtifactExt) {
+			c.Term().Printfln("Downloaded %s", dest)
+			images = append(images, dest)
+		}
+	}
+	return images, nil
+}
+
+// extractArtifact writes a file of the artifacts archive, replacing dest atomically
+func extractArtifact(f *zip.File, dest string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".artifact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
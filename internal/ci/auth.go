@@ -0,0 +1,143 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/launchrctl/keyring"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+// GetCredentials returns the credentials of url from the keyring, requesting them on the terminal if missing.
+// New credentials are added to the keyring but not saved, save reports they must be saved once proven to work.
+func (c *ContinuousIntegration) GetCredentials(k keyring.Keyring, url string) (creds keyring.CredentialsItem, save bool, err error) {
+	scope := c.credentials()
+	creds, err = scope.Credentials(k, c.Log(), url)
+	if err == nil {
+		return creds, false, nil
+	}
+	if errors.Is(err, keyring.ErrEmptyPass) {
+		return creds, false, err
+	} else if !errors.Is(err, keyring.ErrNotFound) {
+		c.Log().Error("error", "error", err)
+		return creds, false, errors.New("the keyring is malformed or wrong passphrase provided")
+	}
+
+	creds = keyring.CredentialsItem{URL: scope.URL(url)}
+	c.Term().Info().Printfln("Please add login and password for %s", creds.URL)
+	if err = keyring.RequestCredentialsFromTty(&creds); err != nil {
+		return creds, false, err
+	}
+	if err = k.AddItem(creds); err != nil {
+		return creds, false, err
+	}
+	return creds, true, nil
+}
+
+// credentials returns the scope of the CI credentials of the keyring
+func (c *ContinuousIntegration) credentials() api.Scope {
+	return api.NewScope(c.Platform, api.PurposeCI)
+}
+
+// GitLab authentication methods
+const (
+	GitLabAuthAuto     = "auto"      // GitLabAuthAuto uses the job token inside CI, a stored access token, or OAuth
+	GitLabAuthOAuth    = "oauth"     // GitLabAuthOAuth exchanges the keyring username and password for an OAuth token
+	GitLabAuthToken    = "token"     // GitLabAuthToken uses a personal, group or project access token of the keyring
+	GitLabAuthJobToken = "job-token" // GitLabAuthJobToken uses CI_JOB_TOKEN of the running GitLab CI job
+)
+
+// GitLabTokenKey is the keyring key of the GitLab personal, group or project access token
+const GitLabTokenKey = "gitlab_token"
+
+// GitLabToken authenticates against GitLab with the given method and returns an API access token.
+// Username and password requested on the terminal are saved to the keyring once OAuth succeeds.
+func (c *ContinuousIntegration) GitLabToken(ctx context.Context, k keyring.Keyring, gitlabDomain, method string) (string, error) {
+	if gitlabDomain == "" {
+		return "", failure.Newf(failure.ErrConfigMissing, "gitlab-domain is empty: pass it as option or local config")
+	}
+
+	if method == "" || method == GitLabAuthAuto {
+		method = c.detectGitLabAuth(k)
+	}
+	c.jobToken = false
+
+	switch method {
+	case GitLabAuthJobToken:
+		token := os.Getenv("CI_JOB_TOKEN")
+		if token == "" {
+			return "", errors.New("CI_JOB_TOKEN is not set: job token authentication only works inside GitLab CI jobs")
+		}
+		redact.Add(token)
+		c.Term().Info().Println("Authenticating to GitLab with CI_JOB_TOKEN")
+		c.jobToken = true
+		return token, nil
+
+	case GitLabAuthToken:
+		return c.gitlabAccessToken(k)
+
+	case GitLabAuthOAuth:
+		return c.gitlabOAuthToken(ctx, k, gitlabDomain)
+
+	default:
+		return "", fmt.Errorf("unsupported GitLab authentication method %q, expected %s, %s, %s or %s",
+			method, GitLabAuthAuto, GitLabAuthOAuth, GitLabAuthToken, GitLabAuthJobToken)
+	}
+}
+
+// detectGitLabAuth picks the job token inside CI, then a stored access token, then OAuth
+func (c *ContinuousIntegration) detectGitLabAuth(k keyring.Keyring) string {
+	if os.Getenv("GITLAB_CI") == "true" && os.Getenv("CI_JOB_TOKEN") != "" {
+		return GitLabAuthJobToken
+	}
+	if _, err := c.credentials().Lookup(k, nil, GitLabTokenKey); err == nil {
+		return GitLabAuthToken
+	}
+	return GitLabAuthOAuth
+}
+
+// gitlabAccessToken returns the access token stored in the keyring, requesting it on the terminal if missing
+func (c *ContinuousIntegration) gitlabAccessToken(k keyring.Keyring) (string, error) {
+	c.Term().Info().Printfln("Getting GitLab access token %q from keyring", GitLabTokenKey)
+	return c.credentials().Token(k, c.Log(), c.Term(), GitLabTokenKey)
+}
+
+// gitlabOAuthToken exchanges the username and password of the keyring for an OAuth token
+func (c *ContinuousIntegration) gitlabOAuthToken(ctx context.Context, k keyring.Keyring, gitlabDomain string) (string, error) {
+	c.Term().Info().Printfln("Getting user credentials for %s from keyring", gitlabDomain)
+	creds, save, err := c.GetCredentials(k, gitlabDomain)
+	if err != nil {
+		return "", err
+	}
+	c.Term().Printfln("URL: %s", creds.URL)
+	c.Term().Printfln("Username: %s", creds.Username)
+
+	// Reuse the cached token to avoid repeated password grants
+	if !save {
+		if token, ok := c.cachedOAuthToken(ctx, gitlabDomain, creds.Username); ok {
+			c.startOAuthSession(gitlabDomain, creds, token)
+			return token.AccessToken, nil
+		}
+	}
+
+	// Get Gitlab OAuth token
+	token, err := c.GetOAuthTokens(ctx, gitlabDomain, creds.Username, creds.Password)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth token: %w", err)
+	}
+
+	// Save gitlab credentials to keyring once API requests are successful
+	if save {
+		c.Log().Debug("saving user credentials to keyring", "url", gitlabDomain)
+		if err = k.Save(); err != nil {
+			c.Log().Error("error during saving keyring file", "error", err)
+		}
+	}
+	c.cacheOAuthToken(gitlabDomain, creds.Username, token)
+	c.startOAuthSession(gitlabDomain, creds, token)
+	return token.AccessToken, nil
+}
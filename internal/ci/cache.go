@@ -0,0 +1,170 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+)
+
+// Keyring key prefixes of cached GitLab data, followed by the GitLab host
+const (
+	oauthCacheKey   = "gitlab_oauth"
+	projectCacheKey = "gitlab_project"
+)
+
+// oauthExpiryMargin renews OAuth tokens a bit before they expire, so they don't expire mid-run
+const oauthExpiryMargin = 5 * time.Minute
+
+// OAuthToken is a GitLab OAuth token
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	CreatedAt    int64  `json:"created_at,omitempty"`
+
+	// SessionToken is the Ory session which requested the token, the Bearer of its refresh
+	SessionToken string `json:"session_token,omitempty"`
+}
+
+// expiring reports whether the token expires within the expiry margin, tokens without expiry never do
+func (t OAuthToken) expiring() bool {
+	return t.ExpiresIn != 0 && t.CreatedAt != 0 && !t.valid()
+}
+
+// valid reports whether the token can still be used, tokens without expiry are renewed every run
+func (t OAuthToken) valid() bool {
+	if t.AccessToken == "" || t.ExpiresIn == 0 || t.CreatedAt == 0 {
+		return false
+	}
+	expiry := time.Unix(t.CreatedAt+t.ExpiresIn, 0)
+	return time.Now().Add(oauthExpiryMargin).Before(expiry)
+}
+
+// parseOAuthToken parses the response of the GitLab OAuth token endpoint
+func parseOAuthToken(body []byte) (OAuthToken, error) {
+	var t OAuthToken
+	if err := json.Unmarshal(body, &t); err != nil {
+		return t, err
+	}
+	if t.AccessToken == "" {
+		return t, errors.New("GitLab OAuth response has no access token")
+	}
+	if t.CreatedAt == 0 {
+		t.CreatedAt = time.Now().Unix()
+	}
+	redact.Add(t.AccessToken, t.RefreshToken)
+	return t, nil
+}
+
+// RefreshOAuthToken calls GitLab "/oauth/token" to exchange a refresh token for a new OAuth token,
+// authorized with the Ory session which requested the token like [ContinuousIntegration.GetOAuthTokens]
+func (c *ContinuousIntegration) RefreshOAuthToken(ctx context.Context, gitlabDomain string, t OAuthToken) (OAuthToken, error) {
+	if t.RefreshToken == "" || t.SessionToken == "" {
+		return OAuthToken{}, errors.New("the OAuth token has no refresh token or Ory session")
+	}
+	return c.requestOAuthToken(ctx, gitlabDomain, t.SessionToken, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": t.RefreshToken,
+	})
+}
+
+// cacheKey returns the keyring key of cached data of a GitLab instance
+func cacheKey(prefix, gitlabDomain string, parts ...string) string {
+	key := prefix + ":" + gitlabDomain
+	if u, err := url.Parse(gitlabDomain); err == nil && u.Host != "" {
+		key = prefix + ":" + u.Host
+	}
+	for _, p := range parts {
+		key += ":" + p
+	}
+	return key
+}
+
+// cacheGet returns a value cached in the keyring
+func (c *ContinuousIntegration) cacheGet(key string) (string, bool) {
+	if c.Keyring == nil {
+		return "", false
+	}
+	item, err := c.Keyring.GetForKey(key)
+	if err != nil {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			c.Log().Debug("failed to read keyring cache", "key", key, "error", err)
+		}
+		return "", false
+	}
+	v, ok := item.Value.(string)
+	return v, ok && v != ""
+}
+
+// cachePut stores a value in the keyring, failures only disable caching
+func (c *ContinuousIntegration) cachePut(key, value string) {
+	if c.Keyring == nil {
+		return
+	}
+	if err := c.Keyring.AddItem(keyring.KeyValueItem{Key: key, Value: value}); err != nil {
+		c.Log().Debug("failed to cache value in keyring", "key", key, "error", err)
+		return
+	}
+	if err := c.Keyring.Save(); err != nil {
+		c.Log().Error("error during saving keyring file", "error", err)
+	}
+}
+
+// cacheDelete removes a value from the keyring
+func (c *ContinuousIntegration) cacheDelete(key string) {
+	if c.Keyring == nil {
+		return
+	}
+	if err := c.Keyring.RemoveByKey(key); err != nil {
+		return
+	}
+	if err := c.Keyring.Save(); err != nil {
+		c.Log().Error("error during saving keyring file", "error", err)
+	}
+}
+
+// cachedOAuthToken returns the OAuth token cached for the GitLab instance, refreshing it if it expired
+func (c *ContinuousIntegration) cachedOAuthToken(ctx context.Context, gitlabDomain, username string) (OAuthToken, bool) {
+	key := cacheKey(oauthCacheKey, gitlabDomain, username)
+	data, ok := c.cacheGet(key)
+	if !ok {
+		return OAuthToken{}, false
+	}
+	var t OAuthToken
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		c.cacheDelete(key)
+		return OAuthToken{}, false
+	}
+	redact.Add(t.AccessToken, t.RefreshToken, t.SessionToken)
+	if t.valid() {
+		c.Log().Debug("using cached GitLab OAuth token", "key", key)
+		return t, true
+	}
+	if t.RefreshToken == "" || t.SessionToken == "" {
+		return OAuthToken{}, false
+	}
+
+	t, err := c.RefreshOAuthToken(ctx, gitlabDomain, t)
+	if err != nil {
+		c.Log().Debug("failed to refresh GitLab OAuth token", "error", err)
+		c.cacheDelete(key)
+		return OAuthToken{}, false
+	}
+	c.Log().Debug("refreshed GitLab OAuth token", "key", key)
+	c.cacheOAuthToken(gitlabDomain, username, t)
+	return t, true
+}
+
+// cacheOAuthToken stores the OAuth token of the GitLab instance in the keyring
+func (c *ContinuousIntegration) cacheOAuthToken(gitlabDomain, username string, t OAuthToken) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return
+	}
+	c.cachePut(cacheKey(oauthCacheKey, gitlabDomain, username), string(data))
+}
@@ -0,0 +1,103 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+// newCI returns the CI client of the tests
+func newCI() *ContinuousIntegration {
+	c := &ContinuousIntegration{API: APIConfig{Retries: -1}}
+	c.SetLogger(launchr.Log())
+	c.SetTerm(launchr.Term())
+	return c
+}
+
+func TestRefreshProjectID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": 7})
+	}))
+	defer srv.Close()
+
+	notFound := &apiError{name: "getPipelines", status: "404 Not Found", code: http.StatusNotFound}
+	tests := []struct {
+		name   string
+		cached bool
+		err    error
+		want   string
+	}{
+		{"cached ID not found", true, notFound, "7"},
+		{"cached ID forbidden", true, &apiError{name: "getPipelines", status: "403 Forbidden", code: http.StatusForbidden}, "7"},
+		{"looked up ID not found", false, notFound, ""},
+		{"server error", true, &apiError{name: "getPipelines", status: "500 Internal Server Error", code: http.StatusInternalServerError}, ""},
+		{"no error", true, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCI()
+			if tt.cached {
+				c.cachedProjects = map[string]bool{cacheKey(projectCacheKey, srv.URL, "group/project"): true}
+			}
+			id, ok := c.RefreshProjectID(context.Background(), srv.URL, "token", "group/project", tt.err)
+			if id != tt.want || ok != (tt.want != "") {
+				t.Fatalf("RefreshProjectID() = %q, %t, want %q", id, ok, tt.want)
+			}
+			// The lookup is retried once
+			if _, ok = c.RefreshProjectID(context.Background(), srv.URL, "token", "group/project", tt.err); ok {
+				t.Error("RefreshProjectID() looked the project up twice")
+			}
+		})
+	}
+}
+
+func TestRefreshOAuthToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path != "/oauth/token" || r.Header.Get("Authorization") != "Bearer session" ||
+			payload["grant_type"] != "refresh_token" || payload["refresh_token"] != "refresh" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(OAuthToken{AccessToken: "access2", RefreshToken: "refresh2", ExpiresIn: 7200})
+	}))
+	defer srv.Close()
+
+	// The CI client logs with the default logger
+	var logs bytes.Buffer
+	log := launchr.NewTextHandlerLogger(&logs)
+	log.SetLevel(launchr.LogLevelDebug)
+	defaultLog := launchr.Log()
+	launchr.SetLogger(log)
+	defer launchr.SetLogger(defaultLog)
+
+	c := newCI()
+	token, err := c.RefreshOAuthToken(context.Background(), srv.URL, OAuthToken{AccessToken: "access", RefreshToken: "refresh", SessionToken: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access2" || token.SessionToken != "session" {
+		t.Errorf("RefreshOAuthToken() = %+v, want the new access token and the session", token)
+	}
+	if strings.Contains(logs.String(), "access2") || strings.Contains(logs.String(), "refresh2") {
+		t.Errorf("the OAuth tokens are in the debug logs:\n%s", logs.String())
+	}
+
+	if _, err = c.RefreshOAuthToken(context.Background(), srv.URL, OAuthToken{AccessToken: "access", RefreshToken: "refresh"}); err == nil {
+		t.Error("RefreshOAuthToken() without Ory session returned no error")
+	}
+}
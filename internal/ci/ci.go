@@ -2,19 +2,25 @@ package ci
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
 	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
 )
 
 // TargetJobName is the name of the job to trigger in CI pipelines
@@ -24,6 +30,20 @@ const TargetJobName = "platform:deploy"
 type ContinuousIntegration struct {
 	action.WithLogger
 	action.WithTerm
+
+	// API configures retries and timeouts of API calls
+	API APIConfig
+	// Keyring caches project IDs and OAuth tokens between runs, nil disables caching
+	Keyring keyring.Keyring
+	// Platform namespaces the credentials of the keyring, only shared ones are read if empty
+	Platform string
+
+	// jobToken is set when authenticated with the CI_JOB_TOKEN of the running job
+	jobToken bool
+	// oauth is set when authenticated with an OAuth token, renewed when it expires
+	oauth *oauthSession
+	// cachedProjects are the cache keys of the project IDs read from the cache in the run
+	cachedProjects map[string]bool
 }
 
 // Job represents a GitLab CI job
@@ -33,31 +53,40 @@ type Job struct {
 	Status       string `json:"status"`
 	Stage        string `json:"stage"`
 	AllowFailure bool   `json:"allow_failure"`
+	WebURL       string `json:"web_url"`
+
+	ArtifactsFile *ArtifactsFile `json:"artifacts_file,omitempty"`
 }
 
 // GetOAuthTokens gets OAuth tokens from Ory and GitLab
-// 1. orySessionToken is used only to request GitLab OAuth token.
+// 1. orySessionToken is used only to request GitLab OAuth tokens, it is kept to refresh them.
 // 2. gitlabAccessToken is used in Authorization headers for all subsequent GitLab API calls.
-func (c *ContinuousIntegration) GetOAuthTokens(gitlabDomain, username, password string) (string, error) {
+func (c *ContinuousIntegration) GetOAuthTokens(ctx context.Context, gitlabDomain, username, password string) (OAuthToken, error) {
+	orySessionToken, err := c.orySession(ctx, username, password)
+	if err != nil {
+		return OAuthToken{}, err
+	}
+	return c.requestOAuthToken(ctx, gitlabDomain, orySessionToken, map[string]string{
+		"grant_type": "password",
+		"username":   username,
+		"password":   password,
+	})
+}
+
+// orySession logs in to Ory with the credentials and returns the session token
+func (c *ContinuousIntegration) orySession(ctx context.Context, username, password string) (string, error) {
 	// Get ui.action URL from Ory self‐service login flow JSON
 	oryDomain := "https://auth.skilld.cloud"
 	oryLoginApiPath := "/self-service/login/api"
 	oryLoginApiURL := oryDomain + oryLoginApiPath
 	c.Log().Debug("oryLoginApiURL", "url", oryLoginApiURL)
 
-	req, err := http.NewRequest("GET", oryLoginApiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", oryLoginApiURL, nil)
 	if err != nil {
 		return "", err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	_, body, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
@@ -91,21 +120,14 @@ func (c *ContinuousIntegration) GetOAuthTokens(gitlabDomain, username, password
 		return "", fmt.Errorf("failed to marshal Ory login payload: %w", err)
 	}
 
-	req, err = http.NewRequest("POST", apiLoginFlowURL, bytes.NewBuffer(jsonBytes))
+	req, err = http.NewRequestWithContext(ctx, "POST", apiLoginFlowURL, bytes.NewBuffer(jsonBytes))
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	client = &http.Client{}
-	resp, err = client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err = io.ReadAll(resp.Body)
+	_, body, err = c.do(req)
 	if err != nil {
 		return "", err
 	}
@@ -122,43 +144,36 @@ func (c *ContinuousIntegration) GetOAuthTokens(gitlabDomain, username, password
 	if orySessionToken == "" {
 		return "", fmt.Errorf("received empty session_token from Ory; response body: %s", string(body))
 	}
+	redact.Add(orySessionToken)
 	c.Log().Debug("orySessionToken", "value", orySessionToken)
+	return orySessionToken, nil
+}
 
-	// Use orySessionToken as Bearer to get a GitLab OAuth token
+// requestOAuthToken calls GitLab "/oauth/token" with the Ory session token as Bearer, for the grant of
+// the payload. The session token is kept in the returned token.
+func (c *ContinuousIntegration) requestOAuthToken(ctx context.Context, gitlabDomain, orySessionToken string, payload map[string]string) (OAuthToken, error) {
 	oauthURL := fmt.Sprintf("%s/oauth/token", gitlabDomain)
 	c.Log().Debug("OAuth token request URL", "url", oauthURL)
 
-	// Send JSON payload
-	gitlabPayload := map[string]string{
-		"grant_type": "password",
-		"username":   username,
-		"password":   password,
-	}
-	gitlabJSON, err := json.Marshal(gitlabPayload)
+	gitlabJSON, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal GitLab OAuth payload: %w", err)
+		return OAuthToken{}, fmt.Errorf("failed to marshal GitLab OAuth payload: %w", err)
 	}
 
-	req, err = http.NewRequest("POST", oauthURL, bytes.NewBuffer(gitlabJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", oauthURL, bytes.NewBuffer(gitlabJSON))
 	if err != nil {
-		return "", err
+		return OAuthToken{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+orySessionToken)
 
-	client = &http.Client{}
-	resp, err = client.Do(req)
+	resp, body, err := c.do(req)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+		return OAuthToken{}, err
 	}
 
-	c.Log().Debug("OAuth token response", "body", string(body))
+	// The body holds the tokens, they're registered with redact once parsed
+	c.Log().Debug("OAuth token response", "status", resp.Status)
 
 	if resp.StatusCode != http.StatusOK {
 		// If not 200, check if the response appears to be HTML.
@@ -169,23 +184,18 @@ func (c *ContinuousIntegration) GetOAuthTokens(gitlabDomain, username, password
 			matches := re.FindSubmatch(body)
 			if len(matches) > 1 {
 				title := string(matches[1])
-				return "", fmt.Errorf("unexpected HTTP status: %s, HTML title: %s", resp.Status, title)
+				return OAuthToken{}, fmt.Errorf("unexpected HTTP status: %s, HTML title: %s", resp.Status, title)
 			}
 		}
-		return "", fmt.Errorf("unexpected HTTP status: %s, body: %s", resp.Status, body)
+		return OAuthToken{}, fmt.Errorf("unexpected HTTP status: %s, body: %s", resp.Status, body)
 	}
 
-	// OAuthResponse is a struct to parse GitLab OAuth response.
-	type OAuthResponse struct {
-		AccessToken string `json:"access_token"`
-	}
-	// Parse JSON response to extract access token.
-	var oauthResp OAuthResponse
-	if err := json.Unmarshal(body, &oauthResp); err != nil {
-		return "", err
+	token, err := parseOAuthToken(body)
+	if err != nil {
+		return OAuthToken{}, err
 	}
-
-	return oauthResp.AccessToken, nil
+	token.SessionToken = orySessionToken
+	return token, nil
 }
 
 // GetBranchName returns the current git branch name
@@ -198,97 +208,124 @@ func (c *ContinuousIntegration) GetBranchName() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// GetRepoName returns the repository name from remote origin URL
-func (c *ContinuousIntegration) GetRepoName() (string, error) {
+// GetRepoPath returns the namespace path of the repository from remote origin URL,
+// e.g. group/subgroup/name for GitLab subgroups or owner/name for Gitea
+func (c *ContinuousIntegration) GetRepoPath() (string, error) {
 	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
 	}
-	repoURL := strings.TrimSpace(string(output))
-	repoParts := strings.Split(repoURL, "/")
-	return strings.TrimSuffix(repoParts[len(repoParts)-1], ".git"), nil
+	return RemotePath(strings.TrimSpace(string(output)))
 }
 
-// GetProjectID calls GitLab API "/projects?search=<repoName>",
-// sets Header "Authorization: Bearer <gitlabAccessToken>", and checks HTTP status first.
-func (c *ContinuousIntegration) GetProjectID(gitlabDomain, gitlabAccessToken, repoName string) (string, error) {
-	apiURL := fmt.Sprintf("%s/api/v4/projects?search=%s", gitlabDomain, url.QueryEscape(repoName))
-	c.Log().Debug("GitLab API URL to get project ID", "url", apiURL)
+// RemotePath returns the repository path of a git remote URL, without host and .git suffix.
+// Both URL (https://, ssh://) and scp-like (git@host:group/name.git) forms are supported.
+func RemotePath(remote string) (string, error) {
+	var repoPath string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		repoPath = u.Path
+		// Don't leak credentials of https remotes in errors
+		remote = u.Redacted()
+	} else if at := strings.Index(remote, ":"); at > 0 && !strings.Contains(remote[:at], "/") {
+		// scp-like syntax [user@]host:path
+		repoPath = remote[at+1:]
+	} else {
+		repoPath = remote
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if !strings.Contains(repoPath, "/") {
+		return "", fmt.Errorf("cannot determine repository path from remote %q", remote)
+	}
+	return repoPath, nil
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return "", err
+// GetProjectID returns the ID of the GitLab project at the namespace path <repoPath>,
+// authenticated with <gitlabAccessToken>, and checks HTTP status first.
+func (c *ContinuousIntegration) GetProjectID(ctx context.Context, gitlabDomain, gitlabAccessToken, repoPath string) (string, error) {
+	// Job tokens can't read arbitrary projects, but the running job knows its own project
+	if c.jobToken && repoPath == os.Getenv("CI_PROJECT_PATH") && os.Getenv("CI_PROJECT_ID") != "" {
+		return os.Getenv("CI_PROJECT_ID"), nil
+	}
+
+	key := cacheKey(projectCacheKey, gitlabDomain, repoPath)
+	if id, ok := c.cacheGet(key); ok {
+		c.Log().Debug("using cached GitLab project ID", "key", key, "id", id)
+		if c.cachedProjects == nil {
+			c.cachedProjects = make(map[string]bool)
+		}
+		c.cachedProjects[key] = true
+		return id, nil
 	}
-	req.Header.Set("Authorization", "Bearer "+gitlabAccessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s", gitlabDomain, url.PathEscape(repoPath))
+	c.Log().Debug("GitLab API URL to get project ID", "url", apiURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	c.authorize(req, gitlabAccessToken)
 
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
 
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("project %s not found on %s or not accessible", repoPath, gitlabDomain)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("GitLab API %s returned status %s: %s",
 			"getProjectID", resp.Status, string(body))
 	}
 
-	// Unmarshal into an array of project maps
-	var projects []map[string]interface{}
-	if err := json.Unmarshal(body, &projects); err != nil {
-		return "", fmt.Errorf("cannot parse projects list: %w; raw response: %s", err, string(body))
+	var project struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &project); err != nil {
+		return "", fmt.Errorf("cannot parse project: %w; raw response: %s", err, string(body))
+	}
+	id := strconv.Itoa(project.ID)
+	c.cachePut(key, id)
+	return id, nil
+}
+
+// RefreshProjectID looks the ID of a project up again when err is a 404 or 403 response to the first
+// request with its cached ID, e.g. once the project was moved or recreated. The cached ID is dropped,
+// ok reports a new ID to retry the request with. The lookup is only retried once per run.
+func (c *ContinuousIntegration) RefreshProjectID(ctx context.Context, gitlabDomain, gitlabAccessToken, repoPath string, err error) (projectID string, ok bool) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || (apiErr.code != http.StatusNotFound && apiErr.code != http.StatusForbidden) {
+		return "", false
+	}
+	key := cacheKey(projectCacheKey, gitlabDomain, repoPath)
+	if !c.cachedProjects[key] {
+		return "", false
 	}
-	if len(projects) == 0 {
-		return "", fmt.Errorf("project not found (empty list returned)")
+	delete(c.cachedProjects, key)
+	c.cacheDelete(key)
+
+	c.Log().Debug("cached GitLab project ID was refused, looking it up again", "key", key, "error", err)
+	projectID, lookupErr := c.GetProjectID(ctx, gitlabDomain, gitlabAccessToken, repoPath)
+	if lookupErr != nil {
+		c.Log().Debug("failed to look up GitLab project ID", "error", lookupErr)
+		return "", false
 	}
-	return fmt.Sprintf("%.0f", projects[0]["id"].(float64)), nil
+	return projectID, true
 }
 
 // TriggerPipeline calls GitLab API "/projects/<projectID>/pipeline",
-// sets Header "Authorization: Bearer <gitlabAccessToken>"
-func (c *ContinuousIntegration) TriggerPipeline(gitlabDomain, gitlabAccessToken, projectID, branchName, buildEnv, buildResources string, ansibleDebug bool) (int, error) {
+// authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) TriggerPipeline(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, branchName, buildEnv, buildResources string, ansibleDebug bool, extraVars map[string]string) (int, error) {
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipeline", gitlabDomain, projectID)
 	c.Log().Debug("GitLab API URL for triggering pipeline", "url", apiURL)
 
 	// Prepare the variables to pass during pipeline creation
 	data := map[string]interface{}{
-		"ref": branchName,
-		"variables": []map[string]string{
-			{
-				"key":   "PLASMA_BUILD_ENV",
-				"value": buildEnv,
-			},
-			{
-				"key":   "PLASMA_BUILD_RESOURCES",
-				"value": buildResources,
-			},
-		},
-	}
-
-	// Only add BUILD_DEBUG_MODE if provided
-	if ansibleDebug {
-		c.Log().Info("Appending BUILD_DEBUG_MODE to pipelines variables")
-		data["variables"] = append(data["variables"].([]map[string]string), map[string]string{
-			"key":   "BUILD_DEBUG_MODE",
-			"value": strconv.FormatBool(ansibleDebug),
-		})
-	}
-	// Only add VERBOSITY if provided
-	logLvl := c.Log().Level()
-	if logLvl != launchr.LogLevelDisabled {
-		logLvl := int(logLvl) - 1
-		verbosity := "-" + strings.Repeat("v", int(launchr.LogLevelError)-logLvl)
-		c.Log().Info("Appending VERBOSITY to pipelines variables")
-		data["variables"] = append(data["variables"].([]map[string]string), map[string]string{
-			"key":   "VERBOSITY",
-			"value": verbosity,
-		})
+		"ref":       branchName,
+		"variables": c.pipelineVariables(buildEnv, buildResources, ansibleDebug, extraVars),
 	}
 
 	jsonData, err := json.Marshal(data)
@@ -297,24 +334,34 @@ func (c *ContinuousIntegration) TriggerPipeline(gitlabDomain, gitlabAccessToken,
 	}
 	c.Log().Debug("JSON data for triggering pipeline", "json", string(jsonData))
 
+	// Job tokens can't create pipelines, they use the pipeline trigger API instead
+	if c.jobToken {
+		apiURL = fmt.Sprintf("%s/api/v4/projects/%s/trigger/pipeline", gitlabDomain, projectID)
+		vars := make(map[string]string)
+		for _, v := range data["variables"].([]map[string]string) {
+			vars[v["key"]] = v["value"]
+		}
+		jsonData, err = json.Marshal(map[string]interface{}{
+			"ref":       branchName,
+			"token":     gitlabAccessToken,
+			"variables": vars,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	c.Term().Info().Printfln("Creating CI pipeline...")
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return 0, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+gitlabAccessToken)
+	c.authorize(req, gitlabAccessToken)
 
 	c.Log().Debug("Request for triggering pipeline", "request", req)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -347,33 +394,69 @@ func (c *ContinuousIntegration) TriggerPipeline(gitlabDomain, gitlabAccessToken,
 	return pipelineResp.ID, nil
 }
 
+// pipelineVariables returns the variables of a platform build pipeline
+func (c *ContinuousIntegration) pipelineVariables(buildEnv, buildResources string, ansibleDebug bool, extraVars map[string]string) []map[string]string {
+	variables := []map[string]string{
+		{
+			"key":   "PLASMA_BUILD_ENV",
+			"value": buildEnv,
+		},
+		{
+			"key":   "PLASMA_BUILD_RESOURCES",
+			"value": buildResources,
+		},
+	}
+
+	// Only add BUILD_DEBUG_MODE if provided
+	if ansibleDebug {
+		c.Log().Info("Appending BUILD_DEBUG_MODE to pipelines variables")
+		variables = append(variables, map[string]string{
+			"key":   "BUILD_DEBUG_MODE",
+			"value": strconv.FormatBool(ansibleDebug),
+		})
+	}
+	// Only add VERBOSITY if provided
+	logLvl := c.Log().Level()
+	if logLvl != launchr.LogLevelDisabled {
+		logLvl := int(logLvl) - 1
+		verbosity := "-" + strings.Repeat("v", int(launchr.LogLevelError)-logLvl)
+		c.Log().Info("Appending VERBOSITY to pipelines variables")
+		variables = append(variables, map[string]string{
+			"key":   "VERBOSITY",
+			"value": verbosity,
+		})
+	}
+	// Custom variables toggling optional stages, in a stable order
+	for _, key := range sortedKeys(extraVars) {
+		c.Log().Info("Appending custom variable to pipelines variables", "key", key)
+		variables = append(variables, map[string]string{
+			"key":   key,
+			"value": extraVars[key],
+		})
+	}
+	return variables
+}
+
 // GetJobsInPipeline calls GitLab API "/projects/<projectID>/pipelines/<pipelineID>/jobs",
-// sets Header "Authorization: Bearer <gitlabAccessToken>"
-func (c *ContinuousIntegration) GetJobsInPipeline(gitlabDomain, gitlabAccessToken, projectID string, pipelineID int) ([]Job, error) {
+// authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) GetJobsInPipeline(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, pipelineID int) ([]Job, error) {
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines/%d/jobs", gitlabDomain, projectID, pipelineID)
 	c.Log().Debug("GitLab API URL for retrieving jobs", "url", apiURL)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+gitlabAccessToken)
+	c.authorize(req, gitlabAccessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	resp, body, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	c.Log().Debug("GitLab API response for job", "body", string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitLab API getJobsInPipeline returned status %s: %s", resp.Status, string(body))
+		return nil, newAPIError("getJobsInPipeline", resp, body)
 	}
 
 	var jobs []Job
@@ -384,119 +467,223 @@ func (c *ContinuousIntegration) GetJobsInPipeline(gitlabDomain, gitlabAccessToke
 	return jobs, nil
 }
 
-// GetJobTrace calls GitLab API "/projects/<projectID>/jobs/<jobID>/trace",
-// sets Header "Authorization: Bearer <gitlabAccessToken>" and tails the log until completion
-func (c *ContinuousIntegration) GetJobTrace(gitlabDomain, gitlabAccessToken, projectID string, jobID int) error {
-	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/jobs/%d/trace", gitlabDomain, projectID, jobID)
-	c.Log().Debug("GitLab API URL for retrieving job trace", "url", apiURL)
+// Job statuses in which a GitLab job won't change anymore
+var jobFinalStatuses = map[string]bool{
+	"success":  true,
+	"failed":   true,
+	"canceled": true,
+	"skipped":  true,
+}
 
-	const maxRetries = 20               // Retry up to 20 times to ensure job has started
-	const retryDelay = 10 * time.Second // Wait 10 seconds between retries
+// Polling intervals when following or waiting for a job
+const (
+	followInterval = 2 * time.Second
+	waitInterval   = 10 * time.Second
+)
 
-	// Retry until job has started and trace is available
-	for i := 0; i < maxRetries; i++ {
-		traceContent, err := c.fetchTrace(apiURL, gitlabAccessToken)
-		if err != nil {
-			return err
-		}
+// GetJob calls GitLab API "/projects/<projectID>/jobs/<jobID>",
+// authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) GetJob(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, jobID int) (Job, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/jobs/%d", gitlabDomain, projectID, jobID)
 
-		if len(traceContent) > 0 {
-			c.Term().Println("Job has started!")
-			break
-		}
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return Job{}, err
+	}
+	c.authorize(req, gitlabAccessToken)
 
-		c.Term().Println("Waiting for job to start...")
-		time.Sleep(retryDelay)
+	resp, body, err := c.do(req)
+	if err != nil {
+		return Job{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Job{}, fmt.Errorf("GitLab API getJob returned status %s: %s", resp.Status, string(body))
 	}
 
-	// Start tailing the job trace
-	c.Term().Println("Now tailing job's trace:")
-	var lastLength int // Keeps track of how much trace has already been printed
+	var job Job
+	if err := json.Unmarshal(body, &job); err != nil {
+		return Job{}, err
+	}
+	return job, nil
+}
+
+// ErrTimeout is returned when a job doesn't reach a final status before the deadline
+var ErrTimeout = errors.New("timed out waiting for CI job")
 
+// WaitJob polls the pipeline until the job reaches a final status, printing per-stage progress.
+// It returns an error if the job didn't succeed. A zero deadline waits indefinitely.
+func (c *ContinuousIntegration) WaitJob(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, pipelineID, jobID int, deadline time.Time) error {
+	lastSummary := ""
 	for {
-		traceContent, err := c.fetchTrace(apiURL, gitlabAccessToken)
+		jobs, err := c.GetJobsInPipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, pipelineID)
 		if err != nil {
 			return err
 		}
+		if summary := stageSummary(jobs); summary != lastSummary {
+			c.Term().Printfln("Pipeline %d: %s", pipelineID, summary)
+			lastSummary = summary
+		}
 
-		if len(traceContent) > lastLength {
-			// Print only new trace content
-			newContent := traceContent[lastLength:]
-			c.Term().Print(newContent)
-			lastLength = len(traceContent)
+		for _, job := range jobs {
+			if job.ID != jobID || !jobFinalStatuses[job.Status] {
+				continue
+			}
+			if job.Status != "success" {
+				return &failure.PipelineError{Job: job.Name + " job", Status: job.Status, URL: job.WebURL}
+			}
+			c.Term().Success().Printfln("%s job succeeded", job.Name)
+			return nil
 		}
 
-		// If the trace has not changed for a while, assume it's still running
-		if len(traceContent) == lastLength {
-			c.Term().Print(".") // Indicate waiting
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		if err := sleep(ctx, waitInterval); err != nil {
+			return err
 		}
+	}
+}
 
-		// Determine job completion status
-		statusCode, completed := c.jobCompleted(traceContent)
-		if completed {
-			if statusCode == 0 {
-				c.Term().Println("\nEnd of trace.")
-				return nil // Exit with code 0
-			}
-			// If the job failed, return an error with the corresponding exit code
-			return fmt.Errorf("job failed with exit code %d", statusCode)
+// CancelPipeline calls GitLab API "/projects/<projectID>/pipelines/<pipelineID>/cancel",
+// authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) CancelPipeline(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, pipelineID int) error {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines/%d/cancel", gitlabDomain, projectID, pipelineID)
+	c.Log().Debug("GitLab API URL for canceling pipeline", "url", apiURL)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitLab API cancelPipeline returned status %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+// authorize sets the authentication header of a GitLab API request
+func (c *ContinuousIntegration) authorize(req *http.Request, gitlabAccessToken string) {
+	if c.jobToken {
+		req.Header.Set("JOB-TOKEN", gitlabAccessToken)
+		return
+	}
+	// OAuth, personal, group and project access tokens are all accepted as Bearer tokens
+	req.Header.Set("Authorization", "Bearer "+c.oauthToken(req.Context(), gitlabAccessToken))
+}
+
+// sleep waits for the duration unless the context is canceled, e.g. on interrupt
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// stageSummary counts job statuses per stage, in pipeline order
+func stageSummary(jobs []Job) string {
+	var stages []string
+	counts := make(map[string]map[string]int)
+	// GitLab lists jobs newest first, reverse to get stages in pipeline order
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		if _, ok := counts[job.Stage]; !ok {
+			stages = append(stages, job.Stage)
+			counts[job.Stage] = make(map[string]int)
 		}
+		counts[job.Stage][job.Status]++
+	}
 
-		// Sleep briefly before polling the trace again
-		time.Sleep(5 * time.Second)
+	parts := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		statuses := make([]string, 0, len(counts[stage]))
+		for status, n := range counts[stage] {
+			statuses = append(statuses, fmt.Sprintf("%d %s", n, status))
+		}
+		sort.Strings(statuses)
+		parts = append(parts, fmt.Sprintf("%s (%s)", stage, strings.Join(statuses, ", ")))
 	}
+	return strings.Join(parts, " → ")
 }
 
-// jobCompleted determines if a GitLab job trace indicates completion.
-// Returns (exitCode, true) if completed; otherwise (1, false).
-func (c *ContinuousIntegration) jobCompleted(traceContent string) (int, bool) {
-	if strings.Contains(traceContent, "Job succeeded") {
-		return 0, true
-	}
-
-	if strings.Contains(traceContent, "Job failed") {
-		// Extract the exit code from the trace content
-		lines := strings.Split(traceContent, "\n")
-		for _, line := range lines {
-			if strings.Contains(line, "Job failed") {
-				parts := strings.Fields(line)
-				if len(parts) > 3 {
-					if code, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
-						return code, true
-					}
-				}
+// FollowJob streams the trace of a job to the terminal until the job reaches a final status.
+// It returns an error if the job didn't succeed. A zero deadline waits indefinitely.
+func (c *ContinuousIntegration) FollowJob(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, jobID int, deadline time.Time) error {
+	traceURL := fmt.Sprintf("%s/api/v4/projects/%s/jobs/%d/trace", gitlabDomain, projectID, jobID)
+	c.Log().Debug("GitLab API URL for retrieving job trace", "url", traceURL)
+
+	var printed int // Keeps track of how much trace has already been printed
+	lastStatus := ""
+	for {
+		job, err := c.GetJob(ctx, gitlabDomain, gitlabAccessToken, projectID, jobID)
+		if err != nil {
+			return err
+		}
+		if job.Status != lastStatus && (job.Status == "pending" || job.Status == "created") {
+			c.Term().Printfln("Waiting for %s job to start (%s)...", job.Name, job.Status)
+		}
+		lastStatus = job.Status
+
+		// Fetch trace after the status, so the final trace is complete once the job is finished
+		trace, err := c.fetchTrace(ctx, traceURL, gitlabAccessToken)
+		if err != nil {
+			return err
+		}
+		if len(trace) > printed {
+			c.Term().Print(trace[printed:])
+			printed = len(trace)
+		}
+
+		if jobFinalStatuses[job.Status] {
+			c.Term().Println()
+			if job.Status != "success" {
+				return &failure.PipelineError{Job: job.Name + " job", Status: job.Status, URL: job.WebURL}
 			}
+			c.Term().Success().Printfln("%s job succeeded", job.Name)
+			return nil
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			c.Term().Println()
+			return ErrTimeout
+		}
+		if err := sleep(ctx, followInterval); err != nil {
+			c.Term().Println()
+			return err
 		}
 	}
-	return 1, false // Default: treat as failed with code=1 if no explicit indicator
 }
 
-// fetchTrace performs the GET request to the given trace URL, passing Header "Authorization: Bearer <gitlabAccessToken>"
-func (c *ContinuousIntegration) fetchTrace(apiURL, gitlabAccessToken string) (string, error) {
-	req, err := http.NewRequest("GET", apiURL, nil)
+// fetchTrace performs the GET request to the given trace URL, authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) fetchTrace(ctx context.Context, apiURL, gitlabAccessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+gitlabAccessToken)
+	c.authorize(req, gitlabAccessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, body, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitLab API getJobTrace returned status %s: %s", resp.Status, string(body))
 	}
 
 	return string(body), nil
 }
 
 // TriggerManualJob calls GitLab API "/projects/<projectID>/jobs/<jobID>/play",
-// sets Header "Authorization: Bearer <gitlabAccessToken>"
-func (c *ContinuousIntegration) TriggerManualJob(gitlabDomain, gitlabAccessToken, projectID string, jobID int, pipelineID int) error {
+// authenticated with <gitlabAccessToken>
+func (c *ContinuousIntegration) TriggerManualJob(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, jobID int, pipelineID int) error {
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/jobs/%d/play", gitlabDomain, projectID, jobID)
 	c.Log().Debug("GitLab API URL for triggering manual job", "url", apiURL)
 
@@ -513,7 +700,7 @@ func (c *ContinuousIntegration) TriggerManualJob(gitlabDomain, gitlabAccessToken
 	}()
 
 	// Retrieve all jobs to determine the stage of the target job
-	allJobs, err := c.GetJobsInPipeline(gitlabDomain, gitlabAccessToken, projectID, pipelineID)
+	allJobs, err := c.GetJobsInPipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, pipelineID)
 	if err != nil {
 		return err
 	}
@@ -532,7 +719,7 @@ func (c *ContinuousIntegration) TriggerManualJob(gitlabDomain, gitlabAccessToken
 
 	for i := 0; i < maxRetries; i++ {
 		// Check the status of jobs in previous stages
-		jobs, err := c.GetJobsInPipeline(gitlabDomain, gitlabAccessToken, projectID, pipelineID)
+		jobs, err := c.GetJobsInPipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, pipelineID)
 		if err != nil {
 			return err
 		}
@@ -554,33 +741,28 @@ func (c *ContinuousIntegration) TriggerManualJob(gitlabDomain, gitlabAccessToken
 
 		// If there are failed jobs, no need to retry further
 		if len(failed) > 0 {
-			return fmt.Errorf("cannot trigger %s job due to failed jobs: %v", TargetJobName, failed)
+			return failure.Newf(failure.ErrPipelineFailed, "cannot trigger %s job due to failed jobs: %v", TargetJobName, failed)
 		}
 
 		// If there are still jobs in progress, list them and wait before retrying
 		if len(inProgress) > 0 {
 			c.Term().Printfln("Waiting for previous jobs to finish: %v...", inProgress)
-			time.Sleep(retryDelay)
+			if err := sleep(ctx, retryDelay); err != nil {
+				return err
+			}
 			continue
 		}
 
 		// No failed jobs, no jobs in progress, proceed with triggering the manual job
 		// Create the request to trigger the job
-		req, err := http.NewRequest("POST", apiURL, nil)
+		req, err := http.NewRequestWithContext(ctx, "POST", apiURL, nil)
 		if err != nil {
 			return err
 		}
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+gitlabAccessToken)
+		c.authorize(req, gitlabAccessToken)
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
+		resp, body, err := c.do(req)
 		if err != nil {
 			return err
 		}
@@ -598,20 +780,15 @@ func (c *ContinuousIntegration) TriggerManualJob(gitlabDomain, gitlabAccessToken
 
 			jobURL = jsonResponse.WebURL // Save the Job URL for later printing
 
-			// Print the Job URL
-			c.Term().Printfln("Job URL: %s", jobURL)
-
-			// Retrieve and print the job trace
-			if err := c.GetJobTrace(gitlabDomain, gitlabAccessToken, projectID, jobID); err != nil {
-				return fmt.Errorf("failed to retrieve job trace: %v", err)
-			}
-			return nil // End the program after successfully retrieving job trace
+			return nil
 		}
 
 		// Handle unplayable job response
 		if strings.Contains(string(body), "Unplayable Job") {
 			c.Term().Printfln("%s job cannot be played yet. Retrying in %v...", TargetJobName, retryDelay)
-			time.Sleep(retryDelay)
+			if err := sleep(ctx, retryDelay); err != nil {
+				return err
+			}
 		} else {
 			return fmt.Errorf("failed to trigger job: %s", resp.Status)
 		}
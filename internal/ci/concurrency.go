@@ -0,0 +1,134 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Pipeline is a GitLab CI pipeline
+type Pipeline struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	WebURL string `json:"web_url"`
+	Source string `json:"source"` // Event which created the pipeline, e.g. api, schedule or push
+}
+
+// activePipelineStatuses are the statuses of pipelines which may deploy, a manual deploy job
+// waiting to be played included
+var activePipelineStatuses = []string{"created", "waiting_for_resource", "preparing", "pending", "running", "manual"}
+
+// deploySources are the sources of pipelines which may have the PLASMA_BUILD_ENV variable: the
+// pipelines created with the API or a trigger by platform:up, by a schedule, or from the web UI
+var deploySources = []string{"api", "trigger", "schedule", "web"}
+
+// queueInterval is the delay between checks of pipelines deploying the same environment
+const queueInterval = 30 * time.Second
+
+// GetActivePipelines calls GitLab API "/projects/<projectID>/pipelines" and returns the
+// unfinished pipelines deploying to <buildEnv>. Only the variables of the pipelines of
+// a deploy source are fetched.
+func (c *ContinuousIntegration) GetActivePipelines(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, buildEnv string) ([]Pipeline, error) {
+	var active []Pipeline
+	for _, status := range activePipelineStatuses {
+		apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines?status=%s&per_page=100", gitlabDomain, projectID, status)
+		var pipelines []Pipeline
+		if err := c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelines", &pipelines); err != nil {
+			return nil, err
+		}
+		for _, p := range pipelines {
+			if !slices.Contains(deploySources, p.Source) {
+				continue
+			}
+			env, err := c.getPipelineVariable(ctx, gitlabDomain, gitlabAccessToken, projectID, p.ID, "PLASMA_BUILD_ENV")
+			if err != nil {
+				return nil, err
+			}
+			if env == buildEnv {
+				active = append(active, p)
+			}
+		}
+	}
+	return active, nil
+}
+
+// getPipelineVariable calls GitLab API "/projects/<projectID>/pipelines/<pipelineID>/variables"
+// and returns the value of a variable, empty if the pipeline doesn't have it
+func (c *ContinuousIntegration) getPipelineVariable(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, pipelineID int, key string) (string, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines/%d/variables", gitlabDomain, projectID, pipelineID)
+	var variables []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	if err := c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelineVariables", &variables); err != nil {
+		return "", err
+	}
+	for _, v := range variables {
+		if v.Key == key {
+			return v.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// getJSON sends a GET request to the GitLab API and decodes its JSON response into v
+func (c *ContinuousIntegration) getJSON(ctx context.Context, apiURL, gitlabAccessToken, name string, v any) error {
+	c.Log().Debug("GitLab API URL", "api", name, "url", apiURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(name, resp, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// WaitEnvironment checks for pipelines deploying to <buildEnv>. Without queue it refuses to proceed
+// if any is found, otherwise it waits until they finish.
+func (c *ContinuousIntegration) WaitEnvironment(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, buildEnv string, queue bool, deadline time.Time) error {
+	queued := false
+	for {
+		active, err := c.GetActivePipelines(ctx, gitlabDomain, gitlabAccessToken, projectID, buildEnv)
+		if err != nil {
+			return fmt.Errorf("failed to check pipelines deploying %s: %w", buildEnv, err)
+		}
+		if len(active) == 0 {
+			if queued {
+				c.Term().Info().Printfln("Environment %s is free", buildEnv)
+			}
+			return nil
+		}
+
+		urls := make([]string, len(active))
+		for i, p := range active {
+			urls[i] = fmt.Sprintf("%s (%s, %s)", p.WebURL, p.Ref, p.Status)
+		}
+		if !queue {
+			return fmt.Errorf("environment %s is being deployed by another pipeline, wait for it or run with --queue:\n  %s",
+				buildEnv, strings.Join(urls, "\n  "))
+		}
+		if !queued {
+			c.Term().Info().Printfln("Environment %s is being deployed, queuing behind:\n  %s", buildEnv, strings.Join(urls, "\n  "))
+			queued = true
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		if err := sleep(ctx, queueInterval); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestGetActivePipelines(t *testing.T) {
+	// Pipelines by status, and their PLASMA_BUILD_ENV by ID
+	pipelines := map[string][]Pipeline{
+		"running": {
+			{ID: 1, Status: "running", Source: "api"},
+			{ID: 2, Status: "running", Source: "push"},
+		},
+		"manual":               {{ID: 3, Status: "manual", Source: "trigger"}},
+		"waiting_for_resource": {{ID: 4, Status: "waiting_for_resource", Source: "schedule"}},
+		"preparing":            {{ID: 5, Status: "preparing", Source: "merge_request_event"}},
+		"pending":              {{ID: 6, Status: "pending", Source: "web"}},
+	}
+	envs := map[int]string{1: "dev", 2: "dev", 3: "dev", 4: "prod", 5: "dev", 6: "dev"}
+
+	var variableRequests []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v4/projects/42/pipelines" {
+			_ = json.NewEncoder(w).Encode(pipelines[r.URL.Query().Get("status")])
+			return
+		}
+		var id int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/api/v4/projects/42/pipelines/"), "%d/variables", &id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		variableRequests = append(variableRequests, id)
+		_ = json.NewEncoder(w).Encode([]map[string]string{{"key": "PLASMA_BUILD_ENV", "value": envs[id]}})
+	}))
+	defer srv.Close()
+
+	c := &ContinuousIntegration{}
+	c.SetLogger(launchr.Log())
+	c.SetTerm(launchr.Term())
+	active, err := c.GetActivePipelines(context.Background(), srv.URL, "token", "42", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	for _, p := range active {
+		ids = append(ids, p.ID)
+	}
+	slices.Sort(ids)
+	if want := []int{1, 3, 6}; !slices.Equal(ids, want) {
+		t.Errorf("active pipelines = %v, want %v", ids, want)
+	}
+	slices.Sort(variableRequests)
+	if want := []int{1, 3, 4, 6}; !slices.Equal(variableRequests, want) {
+		t.Errorf("variables of pipelines %v were fetched, want %v", variableRequests, want)
+	}
+}
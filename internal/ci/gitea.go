@@ -0,0 +1,297 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+)
+
+// Default Gitea Actions workflows, mirroring the GitLab build stages and the manual deploy job.
+const (
+	DefaultGiteaBuildWorkflow  = "platform-build.yaml"
+	DefaultGiteaDeployWorkflow = "platform-deploy.yaml"
+)
+
+// giteaPollInterval is the delay between workflow run status checks
+const giteaPollInterval = 10 * time.Second
+
+// giteaStartTimeout bounds the wait for a dispatched workflow run to appear
+const giteaStartTimeout = 5 * time.Minute
+
+// Gitea is the Gitea/Forgejo Actions [Provider]
+// Gitea has no manual jobs, the deploy stage is a separate workflow dispatched once the build succeeds.
+type Gitea struct {
+	action.WithLogger
+	action.WithTerm
+
+	Domain         string
+	Token          string
+	BuildWorkflow  string
+	DeployWorkflow string
+
+	// Last dispatched run, used to cancel it
+	repo string
+	run  giteaRun
+}
+
+// giteaRun is a workflow run returned by the Gitea actions runs API
+type giteaRun struct {
+	ID           int    `json:"id"`
+	DisplayTitle string `json:"display_title"`
+	Path         string `json:"path"` // Workflow file and ref, e.g. platform-build.yaml@refs/heads/main
+	HeadBranch   string `json:"head_branch"`
+	RunNumber    int    `json:"run_number"`
+	Status       string `json:"status"`     // queued, waiting, in_progress or completed
+	Conclusion   string `json:"conclusion"` // Result of a completed run, e.g. success or failure
+	URL          string `json:"html_url"`
+}
+
+// workflow returns the workflow file of the run
+func (r giteaRun) workflow() string {
+	workflow, _, _ := strings.Cut(r.Path, "@")
+	return workflow
+}
+
+// result returns the conclusion of a completed run, its status otherwise
+func (r giteaRun) result() string {
+	if r.Status == "completed" {
+		return r.Conclusion
+	}
+	return r.Status
+}
+
+// Build implements [Provider] interface
+func (g *Gitea) Build(ctx context.Context, req BuildRequest) error {
+	if req.ImageDir != "" {
+		return errors.New("fetching Platform Images is not supported by the gitea CI provider")
+	}
+
+	// Gitea has no subgroups, a longer path comes from an instance served under a sub-path
+	if parts := strings.Split(req.Repo, "/"); len(parts) > 2 {
+		req.Repo = strings.Join(parts[len(parts)-2:], "/")
+	}
+
+	buildWorkflow := g.BuildWorkflow
+	if buildWorkflow == "" {
+		buildWorkflow = DefaultGiteaBuildWorkflow
+	}
+	deployWorkflow := g.DeployWorkflow
+	if deployWorkflow == "" {
+		deployWorkflow = DefaultGiteaDeployWorkflow
+	}
+
+	inputs := map[string]string{
+		"environment": req.Environment,
+		"tags":        req.Tags,
+	}
+	if req.Debug {
+		inputs["debug"] = strconv.FormatBool(req.Debug)
+	}
+	// Custom variables must be declared as inputs of both workflows
+	for k, v := range req.Variables {
+		inputs[k] = v
+	}
+
+	g.Term().Info().Printfln("Dispatching %s workflow...", buildWorkflow)
+	run, err := g.dispatchAndFind(ctx, req.Repo, buildWorkflow, req.Branch, inputs)
+	if err != nil {
+		return err
+	}
+	g.Term().Printfln("Build run URL: %s", run.URL)
+
+	run, err = g.waitRun(ctx, req.Repo, run.ID, time.Time{})
+	if err != nil {
+		return err
+	}
+	if run.result() != "success" {
+		return fmt.Errorf("cannot trigger %s workflow: %w", deployWorkflow, &failure.PipelineError{Job: buildWorkflow + " run", Status: run.result(), URL: run.URL})
+	}
+
+	g.Term().Info().Printfln("Build succeeded, dispatching %s workflow...", deployWorkflow)
+	run, err = g.dispatchAndFind(ctx, req.Repo, deployWorkflow, req.Branch, inputs)
+	if err != nil {
+		return err
+	}
+	g.Term().Printfln("Deploy run URL: %s", run.URL)
+
+	// Gitea API doesn't expose run logs, following reports status changes only
+	if req.Follow || req.Wait {
+		run, err = g.waitRun(ctx, req.Repo, run.ID, req.deadline())
+		if errors.Is(err, ErrTimeout) {
+			return fmt.Errorf("%s run didn't finish within %s", deployWorkflow, req.Timeout)
+		}
+		if err != nil {
+			return err
+		}
+		if run.result() != "success" {
+			return &failure.PipelineError{Job: deployWorkflow + " run", Status: run.result(), URL: run.URL}
+		}
+		g.Term().Success().Printfln("%s run succeeded", deployWorkflow)
+	}
+	return nil
+}
+
+// Cancel implements [Provider] interface
+func (g *Gitea) Cancel(ctx context.Context) error {
+	if g.run.ID == 0 {
+		return nil
+	}
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/actions/runs/%d/cancel", g.Domain, g.repo, g.run.ID)
+	if _, err := g.request(ctx, http.MethodPost, apiURL, nil, http.StatusOK, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to cancel run %s, cancel it manually: %w", g.run.URL, err)
+	}
+	g.Term().Info().Printfln("Canceled run %s", g.run.URL)
+	return nil
+}
+
+// dispatchAndFind dispatches a workflow and returns the run it created, the first run of the workflow
+// and branch newer than the runs listed before the dispatch
+func (g *Gitea) dispatchAndFind(ctx context.Context, repo, workflow, branch string, inputs map[string]string) (giteaRun, error) {
+	runs, err := g.listRuns(ctx, repo, branch)
+	if err != nil {
+		return giteaRun{}, err
+	}
+	lastID := 0
+	for _, run := range runs {
+		lastID = max(lastID, run.ID)
+	}
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/actions/workflows/%s/dispatches", g.Domain, repo, url.PathEscape(workflow))
+	payload := map[string]any{"ref": branch, "inputs": inputs}
+	if _, err := g.request(ctx, http.MethodPost, apiURL, payload, http.StatusNoContent, http.StatusOK, http.StatusCreated); err != nil {
+		return giteaRun{}, fmt.Errorf("failed to dispatch %s workflow: %w", workflow, err)
+	}
+	g.repo = repo
+	g.run = giteaRun{}
+
+	deadline := time.Now().Add(giteaStartTimeout)
+	for time.Now().Before(deadline) {
+		runs, err := g.listRuns(ctx, repo, branch)
+		if err != nil {
+			return giteaRun{}, err
+		}
+		for _, run := range runs {
+			if run.ID > lastID && run.workflow() == workflow && run.HeadBranch == branch {
+				g.run = run
+				return run, nil
+			}
+		}
+		g.Term().Printfln("Waiting for %s run to start...", workflow)
+		if err := sleep(ctx, giteaPollInterval); err != nil {
+			return giteaRun{}, err
+		}
+	}
+	return giteaRun{}, fmt.Errorf("%s run did not start within %s", workflow, giteaStartTimeout)
+}
+
+// waitRun polls a workflow run until it completes or the deadline, if not zero
+func (g *Gitea) waitRun(ctx context.Context, repo string, runID int, deadline time.Time) (giteaRun, error) {
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	lastStatus := ""
+	for {
+		run, err := g.getRun(ctx, repo, runID)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return giteaRun{}, ErrTimeout
+		}
+		if err != nil {
+			return giteaRun{}, err
+		}
+		if run.result() != lastStatus {
+			g.Term().Printfln("Run #%d %s: %s", run.RunNumber, run.DisplayTitle, run.result())
+			lastStatus = run.result()
+		}
+		if run.Status == "completed" {
+			return run, nil
+		}
+		if err := sleep(ctx, giteaPollInterval); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return giteaRun{}, ErrTimeout
+			}
+			return giteaRun{}, err
+		}
+	}
+}
+
+// getRun returns a workflow run by ID
+func (g *Gitea) getRun(ctx context.Context, repo string, runID int) (giteaRun, error) {
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/actions/runs/%d", g.Domain, repo, runID)
+	body, err := g.request(ctx, http.MethodGet, apiURL, nil, http.StatusOK)
+	if err != nil {
+		return giteaRun{}, fmt.Errorf("failed to get workflow run %d: %w", runID, err)
+	}
+	var run giteaRun
+	if err := json.Unmarshal(body, &run); err != nil {
+		return giteaRun{}, fmt.Errorf("cannot parse workflow run: %w; raw response: %s", err, string(body))
+	}
+	return run, nil
+}
+
+// listRuns returns the latest workflow runs of a branch, newest first
+func (g *Gitea) listRuns(ctx context.Context, repo, branch string) ([]giteaRun, error) {
+	q := url.Values{"branch": {branch}, "event": {"workflow_dispatch"}, "limit": {"50"}}
+	apiURL := fmt.Sprintf("%s/api/v1/repos/%s/actions/runs?%s", g.Domain, repo, q.Encode())
+	body, err := g.request(ctx, http.MethodGet, apiURL, nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+	var resp struct {
+		WorkflowRuns []giteaRun `json:"workflow_runs"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("cannot parse workflow runs: %w; raw response: %s", err, string(body))
+	}
+	return resp.WorkflowRuns, nil
+}
+
+// request calls the Gitea API with token authentication
+func (g *Gitea) request(ctx context.Context, method, apiURL string, payload any, expected ...int) ([]byte, error) {
+	g.Log().Debug("Gitea API request", "method", method, "url", apiURL)
+
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+g.Token)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return body, nil
+		}
+	}
+	return nil, fmt.Errorf("Gitea API returned status %s: %s", resp.Status, string(body))
+}
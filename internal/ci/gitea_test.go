@@ -0,0 +1,124 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/launchrctl/launchr"
+)
+
+// giteaAPI is the path of the actions API of the repository of the tests
+const giteaAPI = "/api/v1/repos/group/project/actions"
+
+// fakeGitea is a Gitea actions API whose dispatched workflows run with the given status
+type fakeGitea struct {
+	mu         sync.Mutex
+	runs       []giteaRun // Newest first
+	status     string
+	conclusion string
+	requests   []string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	path := strings.TrimPrefix(r.URL.Path, giteaAPI)
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/workflows/"):
+		workflow := strings.TrimSuffix(strings.TrimPrefix(path, "/workflows/"), "/dispatches")
+		id := 100
+		if len(f.runs) > 0 {
+			id = f.runs[0].ID + 1
+		}
+		run := giteaRun{ID: id, RunNumber: id - 99, Path: workflow + "@refs/heads/main", HeadBranch: "main", Status: f.status, Conclusion: f.conclusion}
+		f.runs = append([]giteaRun{run}, f.runs...)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && path == "/runs":
+		_ = json.NewEncoder(w).Encode(map[string]any{"workflow_runs": f.runs})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/runs/"):
+		for _, run := range f.runs {
+			if path == "/runs/"+strconv.Itoa(run.ID) {
+				_ = json.NewEncoder(w).Encode(run)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/cancel"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newGitea returns the provider of a fake Gitea, and the fake
+func newGitea(t *testing.T, status, conclusion string) (*Gitea, *fakeGitea) {
+	t.Helper()
+	f := &fakeGitea{status: status, conclusion: conclusion}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	g := &Gitea{Domain: srv.URL, Token: "token"}
+	g.SetLogger(launchr.Log())
+	g.SetTerm(launchr.Term())
+	return g, f
+}
+
+func TestGiteaBuild(t *testing.T) {
+	tests := []struct {
+		name       string
+		conclusion string
+		wantErr    bool
+	}{
+		{"success", "success", false},
+		{"failure", "failure", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, f := newGitea(t, "completed", tt.conclusion)
+			err := g.Build(context.Background(), BuildRequest{Repo: "group/project", Branch: "main", Environment: "dev", Tags: "core", Follow: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Contains(f.requests, "GET "+giteaAPI+"/runs/101") {
+				t.Errorf("deploy run not looked up by its ID, requests: %v", f.requests)
+			}
+
+			if err := g.Cancel(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			want := "POST " + giteaAPI + "/runs/" + strconv.Itoa(g.run.ID) + "/cancel"
+			if !slices.Contains(f.requests, want) {
+				t.Errorf("cancel request %q not sent, requests: %v", want, f.requests)
+			}
+		})
+	}
+}
+
+func TestGiteaWaitRun(t *testing.T) {
+	g, f := newGitea(t, "in_progress", "")
+	run, err := g.dispatchAndFind(context.Background(), "group/project", DefaultGiteaBuildWorkflow, "main", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = g.waitRun(context.Background(), "group/project", run.ID, time.Now()); !errors.Is(err, ErrTimeout) {
+		t.Errorf("waitRun() past its deadline error = %v, want %v", err, ErrTimeout)
+	}
+
+	// A run which can't be found anymore ends the wait
+	f.mu.Lock()
+	f.runs = nil
+	f.mu.Unlock()
+	if _, err = g.waitRun(context.Background(), "group/project", run.ID, time.Time{}); err == nil {
+		t.Error("waitRun() of a missing run returned no error")
+	}
+}
@@ -0,0 +1,161 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults of GitLab API calls
+const (
+	DefaultAPIRetries = 3
+	DefaultAPITimeout = 30 * time.Second
+)
+
+// Backoff between retries of a GitLab API call, doubled after each attempt
+const (
+	retryBackoff    = time.Second
+	retryMaxBackoff = 30 * time.Second
+)
+
+// APIConfig configures the HTTP client of GitLab API calls
+type APIConfig struct {
+	// Retries of a failed request, negative disables retrying, zero uses [DefaultAPIRetries]
+	Retries int
+	// Timeout of each request, zero uses [DefaultAPITimeout]
+	Timeout time.Duration
+}
+
+// ParseAPIConfig returns the API configuration of command options, where zero retries disable retrying
+func ParseAPIConfig(retries int, timeout string) (APIConfig, error) {
+	cfg := APIConfig{Retries: retries}
+	if retries <= 0 {
+		cfg.Retries = -1
+	}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid API timeout %q, expected a positive duration like 30s", timeout)
+		}
+		cfg.Timeout = d
+	}
+	return cfg, nil
+}
+
+// do sends a GitLab API request and reads its response body.
+// Network errors, rate limiting and server errors are retried with exponential backoff.
+// Requests which aren't idempotent are only retried when the server didn't process them.
+func (c *ContinuousIntegration) do(req *http.Request) (*http.Response, []byte, error) {
+	retries := c.API.Retries
+	if retries == 0 {
+		retries = DefaultAPIRetries
+	}
+	timeout := c.API.Timeout
+	if timeout == 0 {
+		timeout = DefaultAPITimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	backoff := retryBackoff
+	reauthorized := false
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, nil, err
+			}
+			req.Body = body
+		}
+
+		resp, body, err := send(client, req)
+		// An expired OAuth token is renewed once, GitLab didn't process the request
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !reauthorized && c.reauthorize(req) {
+			reauthorized = true
+			continue
+		}
+		retry, wait := retryable(resp, err, idempotent)
+		if !retry || attempt >= retries {
+			return resp, body, err
+		}
+
+		if wait == 0 {
+			wait = backoff
+			backoff = min(backoff*2, retryMaxBackoff)
+		}
+		if err != nil {
+			c.Log().Warn("GitLab API request failed, retrying", "url", req.URL.String(), "error", err, "retry_in", wait)
+		} else {
+			c.Log().Warn("GitLab API request failed, retrying", "url", req.URL.String(), "status", resp.Status, "retry_in", wait)
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// apiError is a GitLab API response with an unexpected status
+type apiError struct {
+	name   string // Name of the API call, e.g. getJobsInPipeline
+	status string
+	code   int
+	body   string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("GitLab API %s returned status %s: %s", e.name, e.status, e.body)
+}
+
+// newAPIError returns the error of an unexpected response to a GitLab API call
+func newAPIError(name string, resp *http.Response, body []byte) error {
+	return &apiError{name: name, status: resp.Status, code: resp.StatusCode, body: string(body)}
+}
+
+// send performs a single request and reads the whole response body
+func send(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response of %s: %w", req.URL.Redacted(), err)
+	}
+	return resp, body, nil
+}
+
+// retryable reports whether a request must be retried, and after which delay if the server asked for one
+func retryable(resp *http.Response, err error, idempotent bool) (bool, time.Duration) {
+	if err != nil {
+		// The server may have processed a request which failed midway
+		return idempotent && !errors.Is(err, context.Canceled), 0
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true, retryAfter(resp)
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent, 0
+	}
+	return false, 0
+}
+
+// retryAfter parses the Retry-After header of a response, in seconds or as a date
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, retryMaxBackoff)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(t), 0), retryMaxBackoff)
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// MergeRequest is a GitLab merge request
+type MergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	WebURL       string `json:"web_url"`
+}
+
+// GetLastCommitTitle returns the subject line of the last commit
+func (c *ContinuousIntegration) GetLastCommitTitle() (string, error) {
+	output, err := exec.Command("git", "log", "-1", "--format=%s").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetDefaultBranch calls GitLab API "/projects/<projectID>" and returns the project default branch
+func (c *ContinuousIntegration) GetDefaultBranch(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string) (string, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s", gitlabDomain, projectID)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitLab API getProject returned status %s: %s", resp.Status, string(body))
+	}
+
+	var project struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.Unmarshal(body, &project); err != nil {
+		return "", err
+	}
+	if project.DefaultBranch == "" {
+		return "", fmt.Errorf("project %s has no default branch", projectID)
+	}
+	return project.DefaultBranch, nil
+}
+
+// FindMergeRequest calls GitLab API "/projects/<projectID>/merge_requests" and returns
+// the open merge request of the source branch, nil if there is none
+func (c *ContinuousIntegration) FindMergeRequest(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, sourceBranch string) (*MergeRequest, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=opened&source_branch=%s",
+		gitlabDomain, projectID, url.QueryEscape(sourceBranch))
+	c.Log().Debug("GitLab API URL to find merge request", "url", apiURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("findMergeRequest", resp, body)
+	}
+
+	var mrs []MergeRequest
+	if err := json.Unmarshal(body, &mrs); err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+	return &mrs[0], nil
+}
+
+// CreateMergeRequest calls GitLab API "/projects/<projectID>/merge_requests" to open a merge request
+func (c *ContinuousIntegration) CreateMergeRequest(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, sourceBranch, targetBranch, title string) (MergeRequest, error) {
+	var mr MergeRequest
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", gitlabDomain, projectID)
+	payload, err := json.Marshal(map[string]any{
+		"source_branch": sourceBranch,
+		"target_branch": targetBranch,
+		"title":         title,
+	})
+	if err != nil {
+		return mr, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return mr, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return mr, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return mr, fmt.Errorf("GitLab API createMergeRequest returned status %s: %s", resp.Status, string(body))
+	}
+	if err := json.Unmarshal(body, &mr); err != nil {
+		return mr, err
+	}
+	return mr, nil
+}
+
+// MergeRequest opens a merge request of the branch unless one is already open.
+// An empty target branch targets the project default branch. The merge request is nil when
+// the branch is the target itself, created reports whether a merge request was opened.
+func (g *GitLab) MergeRequest(ctx context.Context, repo, branch, target string) (mr *MergeRequest, created bool, err error) {
+	projectID, err := g.CI.GetProjectID(ctx, g.Domain, g.Token, repo)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get ID of project %q: %w", repo, err)
+	}
+
+	mr, err = g.CI.FindMergeRequest(ctx, g.Domain, g.Token, projectID, branch)
+	if id, ok := g.CI.RefreshProjectID(ctx, g.Domain, g.Token, repo, err); ok {
+		projectID = id
+		mr, err = g.CI.FindMergeRequest(ctx, g.Domain, g.Token, projectID, branch)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up merge request: %w", err)
+	}
+	if mr != nil {
+		return mr, false, nil
+	}
+
+	if target == "" {
+		target, err = g.CI.GetDefaultBranch(ctx, g.Domain, g.Token, projectID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get default branch: %w", err)
+		}
+	}
+	if target == branch {
+		return nil, false, nil
+	}
+
+	title, err := g.CI.GetLastCommitTitle()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get last commit title: %w", err)
+	}
+	opened, err := g.CI.CreateMergeRequest(ctx, g.Domain, g.Token, projectID, branch, target, title)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return &opened, true, nil
+}
@@ -0,0 +1,96 @@
+package ci
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/launchrctl/keyring"
+)
+
+// oauthSession is the GitLab OAuth token of the run. It is refreshed when it expires, so long runs
+// like following a deploy job outlive the access token, and the credentials are exchanged again when
+// the refresh fails.
+type oauthSession struct {
+	mu       sync.Mutex
+	domain   string
+	creds    keyring.CredentialsItem
+	token    OAuthToken
+	accesses map[string]bool // Access tokens issued in the session, replaced with the current one
+}
+
+// startOAuthSession makes the GitLab API calls authorized with the OAuth token use its refreshed
+// successors, callers keep passing the token they got
+func (c *ContinuousIntegration) startOAuthSession(gitlabDomain string, creds keyring.CredentialsItem, token OAuthToken) {
+	c.oauth = &oauthSession{
+		domain:   gitlabDomain,
+		creds:    creds,
+		token:    token,
+		accesses: map[string]bool{token.AccessToken: true},
+	}
+}
+
+// oauthToken returns the current access token of the session for an access token issued in it,
+// refreshed if it is about to expire, or the access token itself outside the session
+func (c *ContinuousIntegration) oauthToken(ctx context.Context, accessToken string) string {
+	s := c.oauth
+	if s == nil {
+		return accessToken
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.accesses[accessToken] {
+		return accessToken
+	}
+	if s.token.expiring() {
+		if err := c.renewOAuth(ctx, s); err != nil {
+			c.Log().Warn("failed to renew the GitLab OAuth token", "error", err)
+		}
+	}
+	return s.token.AccessToken
+}
+
+// reauthorize renews the OAuth token of the session after GitLab rejected a request authorized with
+// it, and authorizes the request again. It reports whether the request can be retried.
+func (c *ContinuousIntegration) reauthorize(req *http.Request) bool {
+	s := c.oauth
+	if s == nil {
+		return false
+	}
+	accessToken, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.accesses[accessToken] {
+		return false
+	}
+	// Renew unless another request did since this one was authorized
+	if accessToken == s.token.AccessToken {
+		if err := c.renewOAuth(req.Context(), s); err != nil {
+			c.Log().Warn("failed to renew the GitLab OAuth token", "error", err)
+			return false
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token.AccessToken)
+	return true
+}
+
+// renewOAuth refreshes the OAuth token of the session, or exchanges the credentials for a new one
+// if the refresh fails. The caller holds the lock of the session.
+func (c *ContinuousIntegration) renewOAuth(ctx context.Context, s *oauthSession) error {
+	token, err := c.RefreshOAuthToken(ctx, s.domain, s.token)
+	if err != nil {
+		c.Log().Debug("failed to refresh GitLab OAuth token, authenticating again", "error", err)
+		if token, err = c.GetOAuthTokens(ctx, s.domain, s.creds.Username, s.creds.Password); err != nil {
+			return err
+		}
+	}
+	c.Log().Debug("renewed GitLab OAuth token", "domain", s.domain)
+	s.token = token
+	s.accesses[token.AccessToken] = true
+	c.cacheOAuthToken(s.domain, s.creds.Username, token)
+	return nil
+}
@@ -0,0 +1,240 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported CI providers.
+const (
+	ProviderGitLab = "gitlab" // ProviderGitLab runs GitLab CI pipelines and plays the manual deploy job
+	ProviderGitea  = "gitea"  // ProviderGitea dispatches Gitea/Forgejo Actions build and deploy workflows
+)
+
+// BuildRequest describes a platform build to run in CI
+type BuildRequest struct {
+	Repo        string // Repository path, e.g. group/project
+	Branch      string // Git ref to build
+	Environment string // Target environment, PLASMA_BUILD_ENV
+	Tags        string // Resources to deploy, PLASMA_BUILD_RESOURCES
+	Debug       bool   // Run Ansible in debug mode
+	Follow      bool   // Stream the deploy job log and wait for its final status
+	Wait        bool   // Wait for the final status of the deploy job, reporting stage progress
+	Queue       bool   // Wait for other pipelines deploying the environment instead of refusing to build
+
+	// Timeout bounds Follow, Wait and Queue, zero waits indefinitely
+	Timeout time.Duration
+	// Variables are passed to the pipeline in addition to the build variables
+	Variables map[string]string
+	// ImageDir receives the Platform Images built by the pipeline, empty skips downloading them
+	ImageDir string
+	// RunnerTags select the runners of the jobs, passed as PLASMA_RUNNER_TAG_<n> variables
+	RunnerTags []string
+	// Schedule is the ID or description of the pipeline schedule running the build, empty creates a pipeline
+	Schedule string
+	// CreateSchedule creates the schedule if it doesn't exist
+	CreateSchedule bool
+}
+
+// deadline returns the time after which waiting for CI is aborted, zero if unbounded
+func (r BuildRequest) deadline() time.Time {
+	if r.Timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(r.Timeout)
+}
+
+// variables returns the custom variables of the pipeline, including the runner tags
+func (r BuildRequest) variables() map[string]string {
+	if len(r.RunnerTags) == 0 {
+		return r.Variables
+	}
+	vars := make(map[string]string, len(r.Variables)+len(r.RunnerTags))
+	for k, v := range r.Variables {
+		vars[k] = v
+	}
+	for i, tag := range r.RunnerTags {
+		vars[fmt.Sprintf("%s%d", runnerTagVariable, i+1)] = tag
+	}
+	return vars
+}
+
+// Provider triggers a platform build in CI and then its manual deploy stage
+type Provider interface {
+	// Build triggers the pipeline for the request and then its deploy stage
+	// Waiting for CI stops when ctx is canceled, leaving the pipeline running.
+	Build(ctx context.Context, req BuildRequest) error
+	// Cancel cancels the pipeline triggered by the last Build, if any
+	Cancel(ctx context.Context) error
+}
+
+// ValidateProvider checks that the CI provider is supported
+func ValidateProvider(name string) error {
+	switch name {
+	case ProviderGitLab, ProviderGitea:
+		return nil
+	default:
+		return fmt.Errorf("unsupported CI provider %q, expected %s or %s", name, ProviderGitLab, ProviderGitea)
+	}
+}
+
+// reservedVariables are set by the build request and can't be overridden by custom variables
+var reservedVariables = map[string]bool{
+	"PLASMA_BUILD_ENV":       true,
+	"PLASMA_BUILD_RESOURCES": true,
+	"BUILD_DEBUG_MODE":       true,
+	"VERBOSITY":              true,
+}
+
+// runnerTagVariable prefixes the variables of runner tags, numbered from 1, they are reserved too
+const runnerTagVariable = "PLASMA_RUNNER_TAG_"
+
+// reservedVariable reports whether a variable is set by the build request
+func reservedVariable(key string) bool {
+	return reservedVariables[key] || strings.HasPrefix(key, runnerTagVariable)
+}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseVariables merges KEY=VALUE pipeline variables over the defaults
+func ParseVariables(defaults map[string]string, vars []string) (map[string]string, error) {
+	result := make(map[string]string, len(defaults)+len(vars))
+	for k, v := range defaults {
+		result[k] = v
+	}
+	for _, kv := range vars {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid pipeline variable %q, expected KEY=VALUE", kv)
+		}
+		result[k] = v
+	}
+	for k := range result {
+		if !variableName.MatchString(k) {
+			return nil, fmt.Errorf("invalid pipeline variable name %q", k)
+		}
+		if reservedVariable(k) {
+			return nil, fmt.Errorf("pipeline variable %s is set by platform:up and can't be overridden", k)
+		}
+	}
+	return result, nil
+}
+
+// sortedKeys returns the keys of variables in a stable order
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GitLab is the GitLab CI [Provider]
+type GitLab struct {
+	CI     *ContinuousIntegration
+	Domain string
+	Token  string
+
+	// Last triggered pipeline, used to cancel it
+	projectID  string
+	pipelineID int
+}
+
+// Build implements [Provider] interface
+func (g *GitLab) Build(ctx context.Context, req BuildRequest) error {
+	// Get project ID
+	projectID, err := g.CI.GetProjectID(ctx, g.Domain, g.Token, req.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to get ID of project %q: %w", req.Repo, err)
+	}
+
+	// Don't deploy the environment concurrently with another pipeline
+	err = g.CI.WaitEnvironment(ctx, g.Domain, g.Token, projectID, req.Environment, req.Queue, req.deadline())
+	if id, ok := g.CI.RefreshProjectID(ctx, g.Domain, g.Token, req.Repo, err); ok {
+		projectID = id
+		err = g.CI.WaitEnvironment(ctx, g.Domain, g.Token, projectID, req.Environment, req.Queue, req.deadline())
+	}
+	if errors.Is(err, ErrTimeout) {
+		return fmt.Errorf("environment %s is still being deployed after %s", req.Environment, req.Timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Trigger pipeline, directly or through a schedule running it as the schedule owner
+	var pipelineID int
+	if req.Schedule != "" {
+		pipelineID, err = g.runSchedule(ctx, projectID, req)
+		if err != nil {
+			return err
+		}
+	} else {
+		pipelineID, err = g.CI.TriggerPipeline(ctx, g.Domain, g.Token, projectID, req.Branch, req.Environment, req.Tags, req.Debug, req.variables())
+		if err != nil {
+			return fmt.Errorf("failed to trigger pipeline: %w", err)
+		}
+	}
+	g.projectID, g.pipelineID = projectID, pipelineID
+
+	// Get all jobs in the pipeline
+	jobs, err := g.CI.GetJobsInPipeline(ctx, g.Domain, g.Token, projectID, pipelineID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve jobs in pipeline: %w", err)
+	}
+
+	// Find the target job ID
+	var targetJobID int
+	for _, job := range jobs {
+		if job.Name == TargetJobName {
+			targetJobID = job.ID
+			break
+		}
+	}
+	if targetJobID == 0 {
+		return fmt.Errorf("no %s job found in pipeline", TargetJobName)
+	}
+
+	// Trigger the manual job
+	err = g.CI.TriggerManualJob(ctx, g.Domain, g.Token, projectID, targetJobID, pipelineID)
+	if err != nil {
+		return fmt.Errorf("failed to trigger manual job: %w", err)
+	}
+
+	// Build stages are done once the deploy job is played, their artifacts are available
+	if req.ImageDir != "" {
+		_, err = g.CI.DownloadImages(ctx, g.Domain, g.Token, projectID, pipelineID, "", req.ImageDir)
+		if err != nil {
+			return fmt.Errorf("failed to fetch Platform Image: %w", err)
+		}
+	}
+
+	// Follow is the default, the stage progress of Wait replaces the log when asked for
+	switch {
+	case req.Wait:
+		err = g.CI.WaitJob(ctx, g.Domain, g.Token, projectID, pipelineID, targetJobID, req.deadline())
+	case req.Follow:
+		err = g.CI.FollowJob(ctx, g.Domain, g.Token, projectID, targetJobID, req.deadline())
+	}
+	if errors.Is(err, ErrTimeout) {
+		return fmt.Errorf("%s job didn't finish within %s", TargetJobName, req.Timeout)
+	}
+	return err
+}
+
+// Cancel implements [Provider] interface
+func (g *GitLab) Cancel(ctx context.Context) error {
+	if g.pipelineID == 0 {
+		return nil
+	}
+	if err := g.CI.CancelPipeline(ctx, g.Domain, g.Token, g.projectID, g.pipelineID); err != nil {
+		return fmt.Errorf("failed to cancel pipeline %d: %w", g.pipelineID, err)
+	}
+	g.CI.Term().Info().Printfln("Canceled pipeline %d", g.pipelineID)
+	return nil
+}
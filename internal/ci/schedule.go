@@ -0,0 +1,277 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PipelineSchedule is a GitLab pipeline schedule
+type PipelineSchedule struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Ref         string `json:"ref"`
+	Active      bool   `json:"active"`
+	Owner       struct {
+		Username string `json:"username"`
+	} `json:"owner"`
+	Variables []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"variables"`
+}
+
+// scheduleCron is the cron of created schedules, which are inactive and only played on demand
+const scheduleCron = "0 0 1 1 *"
+
+// scheduleMarker ends the description of the schedules created by platform:up, which it may update
+const scheduleMarker = " [plasmactl]"
+
+// managed reports whether the schedule was created by platform:up
+func (s *PipelineSchedule) managed() bool {
+	return strings.HasSuffix(s.Description, scheduleMarker)
+}
+
+// Polling of the pipeline created by playing a schedule, GitLab doesn't return it
+const (
+	scheduleInterval = 2 * time.Second
+	scheduleAttempts = 30
+)
+
+// FindPipelineSchedule calls GitLab API "/projects/<projectID>/pipeline_schedules" and returns
+// the schedule with the given ID or description, without the marker of the created schedules, nil
+// if there is none
+func (c *ContinuousIntegration) FindPipelineSchedule(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, name string) (*PipelineSchedule, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules/%d", gitlabDomain, projectID, id)
+		var schedule PipelineSchedule
+		if err = c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelineSchedule", &schedule); err != nil {
+			return nil, err
+		}
+		return &schedule, nil
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules?per_page=100", gitlabDomain, projectID)
+	var schedules []PipelineSchedule
+	if err := c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelineSchedules", &schedules); err != nil {
+		return nil, err
+	}
+	for _, s := range schedules {
+		if strings.TrimSuffix(s.Description, scheduleMarker) == name {
+			// The list doesn't include the schedule variables
+			apiURL = fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules/%d", gitlabDomain, projectID, s.ID)
+			var schedule PipelineSchedule
+			if err := c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelineSchedule", &schedule); err != nil {
+				return nil, err
+			}
+			return &schedule, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreatePipelineSchedule calls GitLab API "/projects/<projectID>/pipeline_schedules" to create
+// an inactive schedule of the branch, which is only played on demand. The marker of the created
+// schedules is appended to the description.
+func (c *ContinuousIntegration) CreatePipelineSchedule(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, description, branchName string) (PipelineSchedule, error) {
+	var schedule PipelineSchedule
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules", gitlabDomain, projectID)
+	err := c.sendJSON(ctx, "POST", apiURL, gitlabAccessToken, "createPipelineSchedule", map[string]any{
+		"description": description + scheduleMarker,
+		"ref":         branchName,
+		"cron":        scheduleCron,
+		"active":      false,
+	}, &schedule)
+	return schedule, err
+}
+
+// UpdatePipelineSchedule points the schedule to the branch and sets its variables. Build and runner
+// tag variables left from previous runs are removed, other variables of the schedule are kept.
+// Only the schedules created by platform:up or owned by the user are updated.
+func (c *ContinuousIntegration) UpdatePipelineSchedule(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, schedule *PipelineSchedule, branchName string, variables []map[string]string) error {
+	if !schedule.managed() {
+		username, err := c.currentUser(ctx, gitlabDomain, gitlabAccessToken)
+		if err != nil {
+			return err
+		}
+		if username != schedule.Owner.Username {
+			return fmt.Errorf("it wasn't created by platform:up and isn't owned by %s", username)
+		}
+	}
+
+	base := fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules/%d", gitlabDomain, projectID, schedule.ID)
+	if strings.TrimPrefix(schedule.Ref, "refs/heads/") != branchName {
+		err := c.sendJSON(ctx, "PUT", base, gitlabAccessToken, "updatePipelineSchedule", map[string]any{"ref": branchName}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing := make(map[string]string, len(schedule.Variables))
+	for _, v := range schedule.Variables {
+		existing[v.Key] = v.Value
+	}
+	wanted := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		key, value := v["key"], v["value"]
+		wanted[key] = true
+		current, ok := existing[key]
+		switch {
+		case ok && current == value:
+			continue
+		case ok:
+			err := c.sendJSON(ctx, "PUT", base+"/variables/"+url.PathEscape(key), gitlabAccessToken,
+				"updatePipelineScheduleVariable", map[string]any{"value": value}, nil)
+			if err != nil {
+				return err
+			}
+		default:
+			err := c.sendJSON(ctx, "POST", base+"/variables", gitlabAccessToken,
+				"createPipelineScheduleVariable", map[string]any{"key": key, "value": value}, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for key := range existing {
+		if wanted[key] || !reservedVariable(key) {
+			continue
+		}
+		err := c.sendJSON(ctx, "DELETE", base+"/variables/"+url.PathEscape(key), gitlabAccessToken,
+			"deletePipelineScheduleVariable", nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentUser calls GitLab API "/user" and returns the username of the token
+func (c *ContinuousIntegration) currentUser(ctx context.Context, gitlabDomain, gitlabAccessToken string) (string, error) {
+	var user struct {
+		Username string `json:"username"`
+	}
+	if err := c.getJSON(ctx, gitlabDomain+"/api/v4/user", gitlabAccessToken, "getCurrentUser", &user); err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+// PlayPipelineSchedule calls GitLab API "/projects/<projectID>/pipeline_schedules/<scheduleID>/play"
+// and returns the ID of the created pipeline
+func (c *ContinuousIntegration) PlayPipelineSchedule(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID string, scheduleID int, branchName string) (int, error) {
+	last, err := c.lastSchedulePipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, branchName)
+	if err != nil {
+		return 0, err
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipeline_schedules/%d/play", gitlabDomain, projectID, scheduleID)
+	if err = c.sendJSON(ctx, "POST", apiURL, gitlabAccessToken, "playPipelineSchedule", nil, nil); err != nil {
+		return 0, err
+	}
+
+	// Playing a schedule only enqueues it, wait for its pipeline to show up
+	for range scheduleAttempts {
+		if err = sleep(ctx, scheduleInterval); err != nil {
+			return 0, err
+		}
+		p, err := c.lastSchedulePipeline(ctx, gitlabDomain, gitlabAccessToken, projectID, branchName)
+		if err != nil {
+			return 0, err
+		}
+		if p.ID > last.ID {
+			c.Term().Printfln("Pipeline URL: %s", p.WebURL)
+			return p.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no pipeline was created by schedule %d", scheduleID)
+}
+
+// lastSchedulePipeline returns the last pipeline of the branch created by a schedule, empty if there is none
+func (c *ContinuousIntegration) lastSchedulePipeline(ctx context.Context, gitlabDomain, gitlabAccessToken, projectID, branchName string) (Pipeline, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/pipelines?source=schedule&ref=%s&order_by=id&sort=desc&per_page=1",
+		gitlabDomain, projectID, url.QueryEscape(branchName))
+	var pipelines []Pipeline
+	if err := c.getJSON(ctx, apiURL, gitlabAccessToken, "getPipelines", &pipelines); err != nil {
+		return Pipeline{}, err
+	}
+	if len(pipelines) == 0 {
+		return Pipeline{}, nil
+	}
+	return pipelines[0], nil
+}
+
+// sendJSON sends a request with a JSON payload to the GitLab API and decodes its JSON response into v, if not nil
+func (c *ContinuousIntegration) sendJSON(ctx context.Context, method, apiURL, gitlabAccessToken, name string, payload any, v any) error {
+	c.Log().Debug("GitLab API URL", "api", name, "url", apiURL)
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req, gitlabAccessToken)
+
+	resp, body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("GitLab API %s returned status %s: %s", name, resp.Status, string(body))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+// runSchedule runs the build through the pipeline schedule of the request, creating it if allowed
+func (g *GitLab) runSchedule(ctx context.Context, projectID string, req BuildRequest) (int, error) {
+	if g.CI.jobToken {
+		return 0, errors.New("pipeline schedules can't be run with a job token")
+	}
+
+	schedule, err := g.CI.FindPipelineSchedule(ctx, g.Domain, g.Token, projectID, req.Schedule)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up pipeline schedule %q: %w", req.Schedule, err)
+	}
+	if schedule == nil {
+		if !req.CreateSchedule {
+			return 0, fmt.Errorf("pipeline schedule %q not found, pass --create-schedule to create it", req.Schedule)
+		}
+		created, err := g.CI.CreatePipelineSchedule(ctx, g.Domain, g.Token, projectID, req.Schedule, req.Branch)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create pipeline schedule %q: %w", req.Schedule, err)
+		}
+		g.CI.Term().Info().Printfln("Created inactive pipeline schedule %q (%d)", created.Description, created.ID)
+		schedule = &created
+	}
+
+	vars := g.CI.pipelineVariables(req.Environment, req.Tags, req.Debug, req.variables())
+	err = g.CI.UpdatePipelineSchedule(ctx, g.Domain, g.Token, projectID, schedule, req.Branch, vars)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update pipeline schedule %q owned by %s: %w", schedule.Description, schedule.Owner.Username, err)
+	}
+
+	g.CI.Term().Info().Printfln("Running pipeline schedule %q (%d)...", schedule.Description, schedule.ID)
+	pipelineID, err := g.CI.PlayPipelineSchedule(ctx, g.Domain, g.Token, projectID, schedule.ID, req.Branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run pipeline schedule %q: %w", schedule.Description, err)
+	}
+	return pipelineID, nil
+}
@@ -0,0 +1,73 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUpdatePipelineSchedule(t *testing.T) {
+	var changes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v4/user" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"username": "alice"})
+			return
+		}
+		changes = append(changes, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/api/v4/projects/42/pipeline_schedules/1"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		description string
+		owner       string
+		wantErr     bool
+	}{
+		{"created by platform:up", "deploy" + scheduleMarker, "bob", false},
+		{"owned by the user", "deploy", "alice", false},
+		{"owned by another user", "deploy", "bob", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes = nil
+			var schedule PipelineSchedule
+			data := `{"id": 1, "ref": "main", "variables": [{"key": "PLASMA_RUNNER_TAG_2", "value": "dc2"}, {"key": "OTHER", "value": "kept"}]}`
+			if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+				t.Fatal(err)
+			}
+			schedule.Description, schedule.Owner.Username = tt.description, tt.owner
+			vars := []map[string]string{{"key": "PLASMA_BUILD_ENV", "value": "dev"}}
+
+			err := newCI().UpdatePipelineSchedule(context.Background(), srv.URL, "token", "42", &schedule, "feature", vars)
+			if tt.wantErr {
+				if err == nil || len(changes) != 0 {
+					t.Fatalf("UpdatePipelineSchedule() error = %v, changes %v, want a refusal without change", err, changes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"PUT ", "POST /variables", "DELETE /variables/PLASMA_RUNNER_TAG_2"}
+			if !slices.Equal(changes, want) {
+				t.Errorf("changes = %v, want %v", changes, want)
+			}
+		})
+	}
+}
+
+func TestParseVariablesReserved(t *testing.T) {
+	for _, kv := range []string{"PLASMA_BUILD_ENV=prod", "PLASMA_RUNNER_TAG_1=dc1"} {
+		if _, err := ParseVariables(nil, []string{kv}); err == nil {
+			t.Errorf("ParseVariables(%q) returned no error", kv)
+		}
+	}
+	if _, err := ParseVariables(nil, []string{"PLASMA_RUNNER=dc1"}); err != nil {
+		t.Errorf("ParseVariables() error = %v", err)
+	}
+}
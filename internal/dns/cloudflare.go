@@ -0,0 +1,314 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// CloudflareTokenKey is the keyring key of the Cloudflare API token, which needs the Zone:Read and DNS:Edit permissions
+const CloudflareTokenKey = "cloudflare_api_token"
+
+// cloudflareAPI is the base URL of the Cloudflare API
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflare manages records with the Cloudflare API
+type cloudflare struct {
+	cfg   schema.DNSConfig
+	token string
+	log   *launchr.Logger
+
+	zoneID string
+}
+
+// cloudflareResponse is the envelope of Cloudflare API responses
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo struct {
+		Page       int `json:"page"`
+		TotalPages int `json:"total_pages"`
+	} `json:"result_info"`
+}
+
+// cloudflareRecord is a DNS record of the Cloudflare API
+type cloudflareRecord struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority,omitempty"`
+}
+
+// newCloudflare creates the Cloudflare provider with the API token of the keyring
+func newCloudflare(cfg schema.DNSConfig, opts dns.Options) (dns.DNSProvider, error) {
+	t, err := token(opts, CloudflareTokenKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudflare{cfg: cfg, token: t, log: opts.Log}, nil
+}
+
+// PlanChanges implements dns.DNSProvider interface
+func (c *cloudflare) PlanChanges(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dns.Plan(existing, records), nil
+}
+
+// EnsureRecords implements dns.DNSProvider interface
+func (c *cloudflare) EnsureRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	changes, err := c.PlanChanges(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return c.apply(ctx, changes)
+}
+
+// DeleteRecords implements dns.DNSProvider interface
+func (c *cloudflare) DeleteRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.apply(ctx, dns.PlanDeletion(existing, records))
+}
+
+// apply makes the changes and returns the ones done
+func (c *cloudflare) apply(ctx context.Context, changes []dns.Change) ([]dns.Change, error) {
+	for i, ch := range changes {
+		var err error
+		path := fmt.Sprintf("/zones/%s/dns_records", c.zoneID)
+		switch ch.Action {
+		case dns.ChangeCreate:
+			err = c.call(ctx, http.MethodPost, path, toCloudflare(ch.Record), nil)
+		case dns.ChangeUpdate:
+			err = c.call(ctx, http.MethodPut, path+"/"+ch.Record.ID, toCloudflare(ch.Record), nil)
+		case dns.ChangeDelete:
+			err = c.call(ctx, http.MethodDelete, path+"/"+ch.Record.ID, nil, nil)
+		}
+		if err != nil {
+			return changes[:i], fmt.Errorf("failed to %s %s record %s: %w", ch.Action, ch.Record.Type, ch.Record.Name, err)
+		}
+	}
+	return changes, nil
+}
+
+// list returns the managed records of the domain
+func (c *cloudflare) list(ctx context.Context) ([]dns.Record, error) {
+	if err := c.lookupZone(ctx); err != nil {
+		return nil, err
+	}
+
+	domain := strings.TrimSuffix(c.cfg.Domain, ".")
+	var records []dns.Record
+	for page := 1; ; page++ {
+		var result []cloudflareRecord
+		path := fmt.Sprintf("/zones/%s/dns_records?per_page=100&page=%d", c.zoneID, page)
+		resp, err := c.get(ctx, path, &result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list records: %w", err)
+		}
+		for _, cr := range result {
+			r := fromCloudflare(cr)
+			if dns.InDomain(r.Name, domain) && r.Managed() {
+				records = append(records, r)
+			}
+		}
+		if page >= resp.ResultInfo.TotalPages {
+			return records, nil
+		}
+	}
+}
+
+// Check implements provider.Checker interface. The DNS:Edit permission can't be verified without
+// changing a record, so only reading the zone and its records is.
+func (c *cloudflare) Check(ctx context.Context) (provider.Check, error) {
+	var token struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if _, err := c.get(ctx, "/user/tokens/verify", &token); err != nil {
+		return provider.Check{}, fmt.Errorf("API token refused: %w", err)
+	}
+	if token.Status != "active" {
+		return provider.Check{}, fmt.Errorf("API token is %s", token.Status)
+	}
+
+	check := provider.Check{Account: "token " + token.ID}
+	if err := c.lookupZone(ctx); err != nil {
+		return check, err
+	}
+	check.Scopes = append(check.Scopes, "Zone:Read")
+	var records []cloudflareRecord
+	if _, err := c.get(ctx, "/zones/"+c.zoneID+"/dns_records?per_page=1", &records); err != nil {
+		c.log.Debug("failed to list records", "error", err)
+		check.Missing = append(check.Missing, "DNS:Edit")
+	} else {
+		check.Scopes = append(check.Scopes, "DNS:Read")
+	}
+	return check, nil
+}
+
+// lookupZone finds the zone of the configured one or holding the domain
+func (c *cloudflare) lookupZone(ctx context.Context) error {
+	if c.zoneID != "" {
+		return nil
+	}
+
+	candidates := []string{c.cfg.Zone}
+	if c.cfg.Zone == "" {
+		candidates = parentDomains(c.cfg.Domain)
+	}
+	for _, name := range candidates {
+		var zones []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if _, err := c.get(ctx, "/zones?name="+url.QueryEscape(name), &zones); err != nil {
+			return fmt.Errorf("failed to look up zone %s: %w", name, err)
+		}
+		if len(zones) > 0 {
+			c.log.Debug("found Cloudflare zone", "zone", zones[0].Name, "id", zones[0].ID)
+			c.zoneID = zones[0].ID
+			return nil
+		}
+	}
+	return fmt.Errorf("no Cloudflare zone found for %s, check dns.zone and the permissions of the API token", c.cfg.Domain)
+}
+
+// get calls the Cloudflare API and decodes the result into v
+func (c *cloudflare) get(ctx context.Context, path string, v any) (cloudflareResponse, error) {
+	var resp cloudflareResponse
+	err := c.do(ctx, http.MethodGet, path, nil, &resp)
+	if err == nil {
+		err = json.Unmarshal(resp.Result, v)
+	}
+	return resp, err
+}
+
+// call sends a request to the Cloudflare API and decodes the result into v, if not nil
+func (c *cloudflare) call(ctx context.Context, method, path string, payload any, v any) error {
+	var resp cloudflareResponse
+	if err := c.do(ctx, method, path, payload, &resp); err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, v)
+}
+
+// do sends a request to the Cloudflare API and checks its response envelope
+func (c *cloudflare) do(ctx context.Context, method, path string, payload any, resp *cloudflareResponse) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.log.Debug("Cloudflare API request", "method", method, "path", path)
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, resp); err != nil {
+		return fmt.Errorf("unexpected response with status %s: %s", res.Status, string(data))
+	}
+	if !resp.Success {
+		msgs := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			msgs = append(msgs, fmt.Sprintf("%s (%d)", e.Message, e.Code))
+		}
+		return fmt.Errorf("cloudflare API returned status %s: %s", res.Status, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// toCloudflare converts a record to the Cloudflare API
+func toCloudflare(r dns.Record) cloudflareRecord {
+	cr := cloudflareRecord{Type: r.Type, Name: r.Name, Content: r.Content, TTL: r.TTL}
+	if r.Type == "MX" {
+		priority := r.Priority
+		cr.Priority = &priority
+	}
+	return cr
+}
+
+// fromCloudflare converts a record of the Cloudflare API, which may return TXT content quoted
+func fromCloudflare(cr cloudflareRecord) dns.Record {
+	r := dns.Record{ID: cr.ID, Type: cr.Type, Name: strings.TrimSuffix(cr.Name, "."), Content: cr.Content, TTL: cr.TTL}
+	switch r.Type {
+	case "TXT":
+		r.Content = unquoteTXT(r.Content)
+	case "MX":
+		r.Content = strings.TrimSuffix(r.Content, ".")
+		if cr.Priority != nil {
+			r.Priority = *cr.Priority
+		}
+	}
+	return r
+}
+
+// unquoteTXT joins the quoted character strings of TXT content, unquoted content is returned as is
+func unquoteTXT(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, `"`) {
+		return content
+	}
+	var b strings.Builder
+	for _, part := range strings.SplitAfter(content, `" `) {
+		part = strings.TrimSpace(part)
+		part = strings.TrimSuffix(strings.TrimPrefix(part, `"`), `"`)
+		b.WriteString(strings.ReplaceAll(part, `\"`, `"`))
+	}
+	return b.String()
+}
+
+// parentDomains returns the domain and its parents holding at least two labels, e.g. a.b.example.com,
+// b.example.com, example.com
+func parentDomains(domain string) []string {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	var names []string
+	for strings.Count(domain, ".") >= 1 {
+		names = append(names, domain)
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return names
+}
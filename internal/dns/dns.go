@@ -0,0 +1,233 @@
+// Package dns provides the built-in DNS providers and applies the DNS records of platforms
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/metal"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Built-in DNS providers.
+const (
+	ProviderCloudflare = "cloudflare" // ProviderCloudflare manages records with the Cloudflare API
+	ProviderRoute53    = "route53"    // ProviderRoute53 manages records of AWS Route53 hosted zones with the aws CLI
+	ProviderTerraform  = "terraform"  // ProviderTerraform manages records with the Terraform workspace of the platform
+)
+
+// Built-in reverse DNS providers, named after their metal provider. The ones of the metal providers of
+// internal/metal, e.g. scaleway and hetzner, set the reverse DNS of their servers.
+const (
+	ProviderOVH = "ovh" // ProviderOVH sets the reverse DNS of OVH IP blocks
+)
+
+func init() {
+	dns.Register(ProviderCloudflare, newCloudflare)
+	dns.Register(ProviderRoute53, newRoute53)
+	dns.Register(ProviderTerraform, newTerraform)
+	dns.RegisterReverse(metal.ProviderScaleway, newMetalReverse)
+	dns.RegisterReverse(metal.ProviderHetzner, newMetalReverse)
+	dns.RegisterReverse(metal.ProviderHetznerRobot, newMetalReverse)
+	dns.RegisterReverse(ProviderOVH, newOVH)
+}
+
+// Manager applies the DNS records of platforms with their DNS provider
+type Manager struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring  keyring.Keyring
+	Resolver dns.Server // DNS server of the checks, the system resolver by default
+	Platform string     // Platform namespacing the credentials of the keyring
+}
+
+// NewProvider creates the DNS provider selected in the configuration
+func (m *Manager) NewProvider(cfg schema.DNSConfig) (dns.DNSProvider, error) {
+	return dns.New(cfg, m.options())
+}
+
+// options returns the services available to providers
+func (m *Manager) options() dns.Options {
+	return dns.Options{Keyring: m.Keyring, Log: m.Log(), Term: m.Term(), Platform: m.Platform}
+}
+
+// Apply creates and updates the records of the platform, then the reverse DNS of its addresses with
+// the metal provider, only printing the changes with dryRun
+func (m *Manager) Apply(ctx context.Context, platform *schema.Platform, dryRun bool) ([]dns.Change, error) {
+	records, err := dns.Records(platform.DNS)
+	if err != nil {
+		return nil, err
+	}
+	retired := dns.RetiredRecords(platform.DNS)
+	if len(records) == 0 && len(retired) == 0 && len(platform.DNS.Reverse) == 0 {
+		m.Term().Warning().Println("No DNS records configured in platform.yaml, see dns.addresses and dns.mail")
+		return nil, nil
+	}
+
+	var changes []dns.Change
+	switch {
+	case len(records) == 0 && len(retired) == 0:
+	case platform.DNS.Provider == "" || platform.DNS.Provider == dns.ProviderManual:
+		m.Term().Info().Println("DNS records are managed by hand, only setting reverse DNS")
+	default:
+		provider, err := m.NewProvider(platform.DNS)
+		if err != nil {
+			return nil, err
+		}
+		if dryRun {
+			changes, err = provider.PlanChanges(ctx, records)
+		} else {
+			changes, err = provider.EnsureRecords(ctx, records)
+		}
+		m.printChanges(changes)
+		if err != nil {
+			return changes, fmt.Errorf("failed to apply DNS records of %s: %w", platform.DNS.Domain, err)
+		}
+		deleted, err := m.deleteRetired(ctx, provider, retired, dryRun)
+		changes = append(changes, deleted...)
+		if err != nil {
+			return changes, fmt.Errorf("failed to delete retired DKIM records of %s: %w", platform.DNS.Domain, err)
+		}
+	}
+
+	ptrChanges, err := m.applyReverse(ctx, platform, dryRun)
+	changes = append(changes, ptrChanges...)
+	if err != nil {
+		return changes, fmt.Errorf("failed to apply reverse DNS of %s: %w", platform.DNS.Domain, err)
+	}
+	return changes, nil
+}
+
+// deleteRetired deletes the DKIM records of the retired selectors once the records of the current
+// ones are applied, only printing the selectors with dryRun since providers can't plan deletions
+func (m *Manager) deleteRetired(ctx context.Context, provider dns.DNSProvider, retired []dns.Record, dryRun bool) ([]dns.Change, error) {
+	if len(retired) == 0 {
+		return nil, nil
+	}
+	if dryRun {
+		for _, r := range retired {
+			m.Term().Printfln("  - %s TXT (retired DKIM selector, if published)", r.Name)
+		}
+		return nil, nil
+	}
+	changes, err := provider.DeleteRecords(ctx, retired)
+	m.printChanges(changes)
+	return changes, err
+}
+
+// applyReverse sets the PTR records of the platform addresses with the metal provider. Metal
+// providers without reverse DNS support get the missing records printed to set them by hand.
+func (m *Manager) applyReverse(ctx context.Context, platform *schema.Platform, dryRun bool) ([]dns.Change, error) {
+	records, err := dns.PTRRecords(platform.DNS)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	provider, err := dns.NewReverse(platform.Infrastructure, m.options())
+	if errors.Is(err, dns.ErrReverseUnsupported) {
+		m.printReverseDNS(ctx, platform, records)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []dns.Change
+	if dryRun {
+		changes, err = provider.PlanReverse(ctx, records)
+	} else {
+		changes, err = provider.EnsureReverse(ctx, records)
+	}
+	m.printChanges(changes)
+	return changes, err
+}
+
+// Delete deletes the records of the platform, including the ones of retired DKIM selectors
+func (m *Manager) Delete(ctx context.Context, platform *schema.Platform) ([]dns.Change, error) {
+	records, err := dns.Records(platform.DNS)
+	if err != nil {
+		return nil, err
+	}
+	records = append(records, dns.RetiredRecords(platform.DNS)...)
+	if len(records) == 0 {
+		return nil, nil
+	}
+	provider, err := m.NewProvider(platform.DNS)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := provider.DeleteRecords(ctx, records)
+	m.printChanges(changes)
+	if err != nil {
+		return changes, fmt.Errorf("failed to delete DNS records of %s: %w", platform.DNS.Domain, err)
+	}
+	return changes, nil
+}
+
+// printChanges prints the changes of records, one per line
+func (m *Manager) printChanges(changes []dns.Change) {
+	for _, c := range changes {
+		m.Term().Printfln("  %s", c)
+	}
+}
+
+// printReverseDNS prints the PTR records missing in the public DNS, to be set by the owner of the addresses
+func (m *Manager) printReverseDNS(ctx context.Context, platform *schema.Platform, records []dns.Record) {
+	missing := dns.CheckReverseWith(ctx, m.Resolver.Resolver(), records)
+	if len(missing) == 0 {
+		return
+	}
+
+	m.Term().Warning().Printfln("Reverse DNS can't be set with metal provider %q, set it where the addresses are hosted:", platform.Infrastructure.MetalProvider)
+	for _, c := range missing {
+		m.Term().Printfln("  %s -> %s", c.Record.Name, c.Record.Content)
+	}
+	if platform.Infrastructure.MetalProvider == "aws" || platform.DNS.Provider == ProviderRoute53 {
+		m.Term().Info().Println("For AWS Elastic IPs, run: aws ec2 modify-address-attribute --allocation-id <id> --domain-name <name>")
+	}
+}
+
+// awsEnv returns the environment passing the AWS access key of the keyring to the aws CLI,
+// empty to use the standard AWS credential chain when the keyring has none
+func awsEnv(opts dns.Options) ([]string, error) {
+	if opts.Keyring == nil {
+		return nil, nil
+	}
+	var env []string
+	for key, name := range map[string]string{
+		AWSAccessKeyIDKey:     "AWS_ACCESS_KEY_ID",
+		AWSSecretAccessKeyKey: "AWS_SECRET_ACCESS_KEY",
+	} {
+		value, err := credentials(opts).Lookup(opts.Keyring, opts.Log, key)
+		if errors.Is(err, api.ErrMissingItem) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, name+"="+value)
+	}
+	switch len(env) {
+	case 0:
+		opts.Log.Debug("no AWS access key in keyring, using the AWS credential chain")
+	case 1:
+		return nil, fmt.Errorf("keyring must hold both %q and %q", AWSAccessKeyIDKey, AWSSecretAccessKeyKey)
+	}
+	return env, nil
+}
+
+// token returns an API token of the keyring, requesting it on the terminal if missing
+func token(opts dns.Options, key string) (string, error) {
+	return credentials(opts).Token(opts.Keyring, opts.Log, opts.Term, key)
+}
+
+// credentials returns the scope of the DNS credentials of the keyring
+func credentials(opts dns.Options) api.Scope {
+	return api.NewScope(opts.Platform, api.PurposeDNS)
+}
@@ -0,0 +1,60 @@
+package dns
+
+import (
+	"context"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// metalReverse sets the reverse DNS of the public addresses of the servers of a metal provider
+type metalReverse struct {
+	name  string
+	metal provider.MetalProvider
+	log   *launchr.Logger
+}
+
+// newMetalReverse creates the reverse DNS provider of a registered metal provider
+func newMetalReverse(infra schema.Infrastructure, opts dns.Options) (dns.ReverseDNSProvider, error) {
+	mp, err := provider.New(infra, provider.Options{Keyring: opts.Keyring, Log: opts.Log, Term: opts.Term, Platform: opts.Platform})
+	if err != nil {
+		return nil, err
+	}
+	return &metalReverse{name: infra.MetalProvider, metal: mp, log: opts.Log}, nil
+}
+
+// PlanReverse implements dns.ReverseDNSProvider interface
+func (r *metalReverse) PlanReverse(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	servers, err := r.metal.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var existing []dns.Record
+	for _, s := range servers {
+		for _, a := range s.Addresses {
+			if !a.Private {
+				existing = append(existing, dns.Record{Type: "PTR", Name: a.IP, Content: a.Reverse})
+			}
+		}
+		for _, n := range s.Networks {
+			existing = append(existing, dns.Record{Type: "PTR", Name: n})
+		}
+	}
+	return planReverse(r.log, r.name, existing, records), nil
+}
+
+// EnsureReverse implements dns.ReverseDNSProvider interface
+func (r *metalReverse) EnsureReverse(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	changes, err := r.PlanReverse(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	for i, ch := range changes {
+		if err = r.metal.SetPTR(ctx, ch.Record.Name, ch.Record.Content); err != nil {
+			return changes[:i], err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,218 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Keyring keys of the OVH API credentials, created at https://eu.api.ovh.com/createToken/ with the
+// GET /ip, GET /ip/*, POST /ip/*/reverse and DELETE /ip/*/reverse/* rights
+const (
+	OVHApplicationKeyKey    = "ovh_application_key"
+	OVHApplicationSecretKey = "ovh_application_secret"
+	OVHConsumerKeyKey       = "ovh_consumer_key"
+)
+
+// ovhRules are the API rules needed to set the reverse DNS
+var ovhRules = []string{"GET /ip", "GET /ip/*", "POST /ip/*/reverse", "DELETE /ip/*/reverse/*"}
+
+// ovhAPI is the base URL of the OVH Europe API
+const ovhAPI = "https://eu.api.ovh.com/1.0"
+
+// ovh sets the reverse DNS of the IP blocks of an OVH account
+type ovh struct {
+	api       string
+	appKey    string
+	appSecret string
+	consumer  string
+	log       *launchr.Logger
+
+	timeDelta *int64
+}
+
+// newOVH creates the OVH reverse DNS provider with the API credentials of the keyring
+func newOVH(infra schema.Infrastructure, opts dns.Options) (dns.ReverseDNSProvider, error) {
+	uri := infra.API.URI
+	if uri == "" {
+		uri = ovhAPI
+	}
+	o := &ovh{api: strings.TrimSuffix(uri, "/"), log: opts.Log}
+	for key, value := range map[string]*string{
+		OVHApplicationKeyKey:    &o.appKey,
+		OVHApplicationSecretKey: &o.appSecret,
+		OVHConsumerKeyKey:       &o.consumer,
+	} {
+		t, err := token(opts, key)
+		if err != nil {
+			return nil, err
+		}
+		*value = t
+	}
+	return o, nil
+}
+
+// PlanReverse implements dns.ReverseDNSProvider interface
+func (o *ovh) PlanReverse(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := o.list(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	return planReverse(o.log, ProviderOVH, existing, records), nil
+}
+
+// EnsureReverse implements dns.ReverseDNSProvider interface
+func (o *ovh) EnsureReverse(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	changes, err := o.PlanReverse(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+	for i, ch := range changes {
+		block := "/ip/" + url.PathEscape(ch.Record.ID) + "/reverse"
+		if ch.Action == dns.ChangeUpdate {
+			err = o.call(ctx, http.MethodDelete, block+"/"+url.PathEscape(ch.Record.Name), nil, nil)
+		}
+		if err == nil {
+			payload := map[string]string{"ipReverse": ch.Record.Name, "reverse": ch.Record.Content + "."}
+			err = o.call(ctx, http.MethodPost, block, payload, nil)
+		}
+		if err != nil {
+			return changes[:i], fmt.Errorf("failed to set reverse DNS of %s: %w", ch.Record.Name, err)
+		}
+	}
+	return changes, nil
+}
+
+// Check implements provider.Checker interface, with the rules granted to the consumer key
+func (o *ovh) Check(ctx context.Context) (provider.Check, error) {
+	var credential struct {
+		CredentialID int64  `json:"credentialId"`
+		Status       string `json:"status"`
+		Rules        []struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+		} `json:"rules"`
+	}
+	if err := o.call(ctx, http.MethodGet, "/auth/currentCredential", nil, &credential); err != nil {
+		return provider.Check{}, fmt.Errorf("API credentials refused: %w", err)
+	}
+	if credential.Status != "validated" {
+		return provider.Check{}, fmt.Errorf("consumer key is %s", credential.Status)
+	}
+
+	check := provider.Check{Account: fmt.Sprintf("credential %d", credential.CredentialID)}
+	for _, rule := range credential.Rules {
+		check.Scopes = append(check.Scopes, rule.Method+" "+rule.Path)
+	}
+	for _, needed := range ovhRules {
+		if !slices.ContainsFunc(check.Scopes, func(granted string) bool { return ovhRuleMatch(granted, needed) }) {
+			check.Missing = append(check.Missing, needed)
+		}
+	}
+	return check, nil
+}
+
+// ovhRuleMatch reports whether a granted rule like "GET /ip/*" covers the needed one, its * matching
+// any part of the path
+func ovhRuleMatch(granted, needed string) bool {
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(granted), `\*`, ".*") + "$"
+	ok, err := regexp.MatchString(pattern, needed)
+	return err == nil && ok
+}
+
+// list returns the desired addresses held by the IP blocks of the account with their reverse,
+// identified by their block. The account may hold many blocks, so only the desired ones are read.
+func (o *ovh) list(ctx context.Context, desired []dns.Record) ([]dns.Record, error) {
+	var blocks []string
+	if err := o.call(ctx, http.MethodGet, "/ip", nil, &blocks); err != nil {
+		return nil, fmt.Errorf("failed to list IP blocks: %w", err)
+	}
+
+	var records []dns.Record
+	for _, r := range desired {
+		block := ovhBlock(blocks, r.Name)
+		if block == "" {
+			continue
+		}
+		var reverse struct {
+			Reverse string `json:"reverse"`
+		}
+		path := "/ip/" + url.PathEscape(block) + "/reverse/" + url.PathEscape(r.Name)
+		err := o.call(ctx, http.MethodGet, path, nil, &reverse)
+		var httpErr *api.Error
+		if err != nil && (!errors.As(err, &httpErr) || httpErr.Status != http.StatusNotFound) {
+			return nil, fmt.Errorf("failed to get reverse DNS of %s: %w", r.Name, err)
+		}
+		records = append(records, dns.Record{ID: block, Type: "PTR", Name: r.Name, Content: strings.TrimSuffix(reverse.Reverse, ".")})
+	}
+	return records, nil
+}
+
+// ovhBlock returns the IP block holding the address, empty if none
+func ovhBlock(blocks []string, addr string) string {
+	ip := net.ParseIP(addr)
+	for _, block := range blocks {
+		if _, ipNet, err := net.ParseCIDR(block); err == nil && ipNet.Contains(ip) {
+			return block
+		}
+	}
+	return ""
+}
+
+// call sends a signed request to the OVH API and decodes its response into v, if not nil
+func (o *ovh) call(ctx context.Context, method, path string, payload any, v any) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	ts, err := o.timestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The signature algorithm of the OVH API is SHA1
+	u := o.api + path
+	sum := sha1.Sum([]byte(strings.Join([]string{o.appSecret, o.consumer, method, u, string(body), ts}, "+")))
+	header := http.Header{
+		"X-Ovh-Application": {o.appKey},
+		"X-Ovh-Consumer":    {o.consumer},
+		"X-Ovh-Timestamp":   {ts},
+		"X-Ovh-Signature":   {"$1$" + hex.EncodeToString(sum[:])},
+		"Content-Type":      {"application/json"},
+	}
+	o.log.Debug("OVH API request", "method", method, "path", path)
+	return api.Request(ctx, method, u, header, body, v)
+}
+
+// timestamp returns the current time of the OVH API, signatures are refused when the local clock drifts
+func (o *ovh) timestamp(ctx context.Context) (string, error) {
+	if o.timeDelta == nil {
+		var serverTime int64
+		if err := api.Request(ctx, http.MethodGet, o.api+"/auth/time", nil, nil, &serverTime); err != nil {
+			return "", fmt.Errorf("failed to get OVH API time: %w", err)
+		}
+		delta := serverTime - time.Now().Unix()
+		o.timeDelta = &delta
+	}
+	return strconv.FormatInt(time.Now().Unix()+*o.timeDelta, 10), nil
+}
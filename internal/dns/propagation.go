@@ -0,0 +1,167 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// propagationInterval is the delay between two rounds of lookups while waiting for propagation
+const propagationInterval = 10 * time.Second
+
+// nameServer is a DNS server queried while waiting for propagation
+type nameServer struct {
+	name   string
+	server dns.Server
+}
+
+// publicResolvers are the public resolvers queried, with the authoritative servers of the zone
+var publicResolvers = []nameServer{
+	{name: "Google", server: dns.Server{Addr: "8.8.8.8:53"}},
+	{name: "Cloudflare", server: dns.Server{Addr: "1.1.1.1:53"}},
+	{name: "Quad9", server: dns.Server{Addr: "9.9.9.9:53"}},
+	{name: "OpenDNS", server: dns.Server{Addr: "208.67.222.222:53"}},
+}
+
+// String returns the name and address of the server
+func (s nameServer) String() string {
+	return fmt.Sprintf("%s (%s)", s.name, s.server)
+}
+
+// pending returns the records of the server not matching the expected ones, as TYPE name
+func (s nameServer) pending(ctx context.Context, records []dns.Record) []string {
+	var pending []string
+	for _, c := range dns.CompareWith(ctx, s.server.Resolver(), records) {
+		if !c.Match() {
+			pending = append(pending, c.Type+" "+c.Name)
+		}
+	}
+	return pending
+}
+
+// WaitPropagation waits until the records of the platform are served by the authoritative servers of
+// its zone and by public resolvers, and by the resolver of the manager if set, printing each server once
+// it converged and the pending records of the others on timeout
+func (m *Manager) WaitPropagation(ctx context.Context, platform *schema.Platform, timeout time.Duration) error {
+	records, err := dns.Records(platform.DNS)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	servers, err := authoritativeServers(ctx, m.Resolver.Resolver(), platform.DNS)
+	if err != nil {
+		m.Term().Warning().Printfln("Only checking public resolvers: %v", err)
+	}
+	servers = append(servers, publicResolvers...)
+	if !m.Resolver.IsSystem() {
+		servers = append(servers, nameServer{name: "Resolver", server: m.Resolver})
+	}
+	return m.wait(ctx, servers, records, timeout)
+}
+
+// WaitAuthoritative waits until the records are served by the authoritative servers of the zone, e.g.
+// ACME challenge records queried there by the CA. Public resolvers are waited for when the servers
+// can't be found.
+func (m *Manager) WaitAuthoritative(ctx context.Context, cfg schema.DNSConfig, records []dns.Record, timeout time.Duration) error {
+	servers, err := authoritativeServers(ctx, m.Resolver.Resolver(), cfg)
+	if err != nil {
+		m.Term().Warning().Printfln("Checking public resolvers: %v", err)
+		servers = publicResolvers
+	}
+	return m.wait(ctx, servers, records, timeout)
+}
+
+// wait queries the servers in rounds until they all serve the records, printing each server once it
+// converged and the pending records of the others on timeout
+func (m *Manager) wait(ctx context.Context, servers []nameServer, records []dns.Record, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	m.Term().Info().Printfln("Waiting for DNS propagation of %d record(s) to %d name servers (timeout %s)...", len(records), len(servers), timeout)
+
+	// Pending records by server, lookups interrupted by the timeout keep the ones of the previous round
+	pending := make(map[nameServer][]string, len(servers))
+	for _, s := range servers {
+		pending[s] = []string{"not queried yet"}
+	}
+	for {
+		var queried []nameServer
+		for _, s := range servers {
+			if _, ok := pending[s]; ok {
+				queried = append(queried, s)
+			}
+		}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, s := range queried {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := s.pending(ctx, records)
+				if ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if len(p) == 0 {
+					delete(pending, s)
+					m.Term().Success().Printfln("  ✓ %s", s)
+					return
+				}
+				pending[s] = p
+			}()
+		}
+		wg.Wait()
+		if len(pending) == 0 {
+			m.Term().Success().Println("DNS records propagated")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, s := range servers {
+				if p, ok := pending[s]; ok {
+					m.Term().Error().Printfln("  ✗ %s: %s", s, strings.Join(p, ", "))
+				}
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("DNS records didn't propagate to %d name server(s) within %s", len(pending), timeout)
+			}
+			return ctx.Err()
+		case <-time.After(propagationInterval):
+		}
+	}
+}
+
+// authoritativeServers returns the name servers of the zone holding the domain, looked up with the resolver
+func authoritativeServers(ctx context.Context, resolver *net.Resolver, cfg schema.DNSConfig) ([]nameServer, error) {
+	zones := parentDomains(cfg.Domain)
+	if cfg.Zone != "" {
+		zones = []string{strings.TrimSuffix(strings.ToLower(cfg.Zone), ".")}
+	}
+	for _, zone := range zones {
+		nss, err := resolver.LookupNS(ctx, zone)
+		if err != nil || len(nss) == 0 {
+			continue
+		}
+		var servers []nameServer
+		for _, ns := range nss {
+			host := strings.TrimSuffix(ns.Host, ".")
+			addrs, err := resolver.LookupHost(ctx, host)
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+			servers = append(servers, nameServer{name: host, server: dns.Server{Addr: net.JoinHostPort(addrs[0], "53")}})
+		}
+		if len(servers) > 0 {
+			return servers, nil
+		}
+	}
+	return nil, fmt.Errorf("no authoritative name servers found for %s", cfg.Domain)
+}
@@ -0,0 +1,64 @@
+package dns
+
+import (
+	"net"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+)
+
+// planReverse returns the changes of the PTR records of the addresses owned by a metal provider.
+// Existing records are the owned addresses with their current PTR, possibly empty, and the owned
+// networks named by CIDR, e.g. the IPv6 /64 of a server. Addresses owned elsewhere are skipped.
+func planReverse(log *launchr.Logger, provider string, existing, desired []dns.Record) []dns.Change {
+	owned := make(map[string]dns.Record, len(existing))
+	var networks []dns.Record
+	for _, r := range existing {
+		if strings.Contains(r.Name, "/") {
+			networks = append(networks, r)
+			continue
+		}
+		owned[r.Name] = r
+	}
+
+	var changes []dns.Change
+	for _, r := range desired {
+		old, ok := owned[r.Name]
+		if !ok {
+			old, ok = inNetworks(networks, r.Name)
+		}
+		if !ok {
+			log.Warn("address is not owned by the metal provider, set its reverse DNS where it's hosted", "provider", provider, "address", r.Name)
+			continue
+		}
+		r.ID = old.ID
+		switch {
+		case old.Content == "":
+			changes = append(changes, dns.Change{Action: dns.ChangeCreate, Record: r})
+		case !old.SameContent(r):
+			changes = append(changes, dns.Change{Action: dns.ChangeUpdate, Record: r, Old: old})
+		}
+	}
+	return changes
+}
+
+// inNetworks returns an empty record with the ID of the network holding the address
+func inNetworks(networks []dns.Record, addr string) (dns.Record, bool) {
+	ip := net.ParseIP(addr)
+	for _, n := range networks {
+		if _, ipNet, err := net.ParseCIDR(n.Name); err == nil && ipNet.Contains(ip) {
+			return dns.Record{ID: n.ID, Type: "PTR", Name: addr}, true
+		}
+	}
+	return dns.Record{}, false
+}
+
+// normalizeIP returns the canonical notation of an address, empty if invalid
+func normalizeIP(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,341 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Keyring keys of the optional AWS access key used by the Route53 provider, the standard AWS
+// credential chain of the aws CLI is used otherwise
+const (
+	AWSAccessKeyIDKey     = "aws_access_key_id"
+	AWSSecretAccessKeyKey = "aws_secret_access_key"
+)
+
+// route53 manages records with the aws CLI, which works on record sets of all values of a name and type
+type route53 struct {
+	cfg schema.DNSConfig
+	env []string
+	log *launchr.Logger
+
+	zoneID string
+}
+
+// route53RecordSet is a resource record set of the aws CLI
+type route53RecordSet struct {
+	Name            string `json:"Name"`
+	Type            string `json:"Type"`
+	TTL             int    `json:"TTL,omitempty"`
+	ResourceRecords []struct {
+		Value string `json:"Value"`
+	} `json:"ResourceRecords,omitempty"`
+}
+
+// newRoute53 creates the Route53 provider with the AWS access key of the keyring, if any
+func newRoute53(cfg schema.DNSConfig, opts dns.Options) (dns.DNSProvider, error) {
+	env, err := awsEnv(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &route53{cfg: cfg, env: env, log: opts.Log}, nil
+}
+
+// PlanChanges implements dns.DNSProvider interface
+func (r *route53) PlanChanges(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, _, err := r.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dns.Plan(existing, records), nil
+}
+
+// EnsureRecords implements dns.DNSProvider interface
+func (r *route53) EnsureRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, sets, err := r.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := dns.Plan(existing, records)
+	return changes, r.apply(ctx, sets, changes)
+}
+
+// DeleteRecords implements dns.DNSProvider interface
+func (r *route53) DeleteRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, sets, err := r.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := dns.PlanDeletion(existing, records)
+	return changes, r.apply(ctx, sets, changes)
+}
+
+// apply upserts or deletes the record sets touched by the changes in a single atomic batch.
+// Existing holds all records of the domain, the values of record sets not managed by the plugin are kept.
+func (r *route53) apply(ctx context.Context, existing []dns.Record, changes []dns.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Record sets after the changes, by name and type
+	type setKey struct{ name, typ string }
+	var keys []setKey
+	sets := make(map[setKey][]dns.Record)
+	touched := make(map[setKey]bool)
+	for _, rec := range existing {
+		k := setKey{rec.Name, rec.Type}
+		sets[k] = append(sets[k], rec)
+	}
+	for _, ch := range changes {
+		k := setKey{ch.Record.Name, ch.Record.Type}
+		if !touched[k] {
+			keys = append(keys, k)
+			touched[k] = true
+		}
+		old := ch.Record
+		if ch.Action == dns.ChangeUpdate {
+			old = ch.Old
+		}
+		if ch.Action != dns.ChangeCreate {
+			sets[k] = removeRecord(sets[k], old)
+		}
+		if ch.Action != dns.ChangeDelete {
+			sets[k] = append(sets[k], ch.Record)
+		}
+	}
+
+	var batch []map[string]any
+	for _, k := range keys {
+		set := sets[k]
+		if len(set) == 0 {
+			// Deleting a record set requires its current values and TTL
+			var current []dns.Record
+			for _, rec := range existing {
+				if rec.Name == k.name && rec.Type == k.typ {
+					current = append(current, rec)
+				}
+			}
+			batch = append(batch, map[string]any{"Action": "DELETE", "ResourceRecordSet": toRoute53(current)})
+			continue
+		}
+		// A record set has a single TTL, the one of the last change wins
+		for i := range set {
+			set[i].TTL = set[len(set)-1].TTL
+		}
+		batch = append(batch, map[string]any{"Action": "UPSERT", "ResourceRecordSet": toRoute53(set)})
+	}
+
+	data, err := json.Marshal(map[string]any{"Comment": "plasmactl platform DNS records", "Changes": batch})
+	if err != nil {
+		return err
+	}
+	_, err = r.aws(ctx, "route53", "change-resource-record-sets", "--hosted-zone-id", r.zoneID, "--change-batch", string(data))
+	return err
+}
+
+// list returns the managed records of the domain and all its records, one per value of the record sets
+func (r *route53) list(ctx context.Context) (managed, all []dns.Record, err error) {
+	if err = r.lookupZone(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	out, err := r.aws(ctx, "route53", "list-resource-record-sets", "--hosted-zone-id", r.zoneID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	var result struct {
+		ResourceRecordSets []route53RecordSet `json:"ResourceRecordSets"`
+	}
+	if err = json.Unmarshal(out, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse record sets: %w", err)
+	}
+
+	domain := strings.TrimSuffix(r.cfg.Domain, ".")
+	for _, set := range result.ResourceRecordSets {
+		for _, rec := range fromRoute53(set) {
+			if !dns.InDomain(rec.Name, domain) {
+				continue
+			}
+			all = append(all, rec)
+			if rec.Managed() {
+				managed = append(managed, rec)
+			}
+		}
+	}
+	return managed, all, nil
+}
+
+// Check implements provider.Checker interface, with the identity of the AWS credentials and the record
+// set limit of the hosted zone. The change permission can't be verified without changing a record.
+func (r *route53) Check(ctx context.Context) (provider.Check, error) {
+	out, err := r.aws(ctx, "sts", "get-caller-identity")
+	if err != nil {
+		return provider.Check{}, fmt.Errorf("AWS credentials refused: %w", err)
+	}
+	var identity struct {
+		Arn string `json:"Arn"`
+	}
+	if err = json.Unmarshal(out, &identity); err != nil {
+		return provider.Check{}, fmt.Errorf("failed to parse caller identity: %w", err)
+	}
+
+	check := provider.Check{Account: identity.Arn}
+	if err = r.lookupZone(ctx); err != nil {
+		return check, err
+	}
+	check.Scopes = append(check.Scopes, "route53:ListHostedZonesByName")
+	if _, err = r.aws(ctx, "route53", "list-resource-record-sets", "--hosted-zone-id", r.zoneID, "--max-items", "1"); err != nil {
+		r.log.Debug("failed to list records", "error", err)
+		check.Missing = append(check.Missing, "route53:ListResourceRecordSets")
+	} else {
+		check.Scopes = append(check.Scopes, "route53:ListResourceRecordSets")
+	}
+
+	// The limit is informative, the permission isn't needed to manage records
+	out, err = r.aws(ctx, "route53", "get-hosted-zone-limit", "--type", "MAX_RRSETS_BY_ZONE", "--hosted-zone-id", r.zoneID)
+	if err != nil {
+		r.log.Debug("failed to get hosted zone limit", "error", err)
+		return check, nil
+	}
+	var limit struct {
+		Limit struct {
+			Value int `json:"Value"`
+		} `json:"Limit"`
+		Count int `json:"Count"`
+	}
+	if err = json.Unmarshal(out, &limit); err != nil {
+		return check, fmt.Errorf("failed to parse hosted zone limit: %w", err)
+	}
+	check.Quotas = append(check.Quotas, provider.Quota{Resource: "record sets", Used: limit.Count, Limit: limit.Limit.Value})
+	return check, nil
+}
+
+// lookupZone finds the public hosted zone of the configured one or holding the domain
+func (r *route53) lookupZone(ctx context.Context) error {
+	if r.zoneID != "" {
+		return nil
+	}
+
+	candidates := []string{strings.TrimSuffix(strings.ToLower(r.cfg.Zone), ".")}
+	if r.cfg.Zone == "" {
+		candidates = parentDomains(r.cfg.Domain)
+	}
+	for _, name := range candidates {
+		// Zones are listed in name order starting at the given one
+		out, err := r.aws(ctx, "route53", "list-hosted-zones-by-name", "--dns-name", name, "--max-items", "10")
+		if err != nil {
+			return fmt.Errorf("failed to look up hosted zone %s: %w", name, err)
+		}
+		var result struct {
+			HostedZones []struct {
+				ID     string `json:"Id"`
+				Name   string `json:"Name"`
+				Config struct {
+					PrivateZone bool `json:"PrivateZone"`
+				} `json:"Config"`
+			} `json:"HostedZones"`
+		}
+		if err = json.Unmarshal(out, &result); err != nil {
+			return fmt.Errorf("failed to parse hosted zones: %w", err)
+		}
+		for _, z := range result.HostedZones {
+			if strings.TrimSuffix(z.Name, ".") == name && !z.Config.PrivateZone {
+				r.zoneID = strings.TrimPrefix(z.ID, "/hostedzone/")
+				r.log.Debug("found Route53 hosted zone", "zone", z.Name, "id", r.zoneID)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no public Route53 hosted zone found for %s, check dns.zone and the AWS credentials", r.cfg.Domain)
+}
+
+// aws runs the aws CLI with JSON output and returns its output
+func (r *route53) aws(ctx context.Context, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return nil, fmt.Errorf("aws CLI is not installed: %w", err)
+	}
+
+	args = append(args, "--output", "json")
+	r.log.Debug("running aws CLI", "args", args[:2])
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	cmd.Env = append(os.Environ(), r.env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("aws failed: %w: %s", err, redact.String(strings.TrimSpace(stderr.String())))
+	}
+	return stdout.Bytes(), nil
+}
+
+// removeRecord removes the first record with the same content from the set
+func removeRecord(set []dns.Record, rec dns.Record) []dns.Record {
+	for i, r := range set {
+		if r.SameContent(rec) {
+			return append(set[:i:i], set[i+1:]...)
+		}
+	}
+	return set
+}
+
+// toRoute53 converts the records of a name and type to a record set
+func toRoute53(set []dns.Record) route53RecordSet {
+	rs := route53RecordSet{Name: set[0].Name + ".", Type: set[0].Type, TTL: set[0].TTL}
+	for _, rec := range set {
+		value := rec.Content
+		switch rec.Type {
+		case "MX":
+			value = fmt.Sprintf("%d %s.", rec.Priority, rec.Content)
+		case "TXT":
+			value = quoteTXT(rec.Content)
+		}
+		rs.ResourceRecords = append(rs.ResourceRecords, struct {
+			Value string `json:"Value"`
+		}{value})
+	}
+	return rs
+}
+
+// fromRoute53 converts a record set to its records
+func fromRoute53(rs route53RecordSet) []dns.Record {
+	var records []dns.Record
+	for _, rr := range rs.ResourceRecords {
+		rec := dns.Record{Type: rs.Type, Name: strings.TrimSuffix(rs.Name, "."), Content: rr.Value, TTL: rs.TTL}
+		switch rs.Type {
+		case "TXT":
+			rec.Content = unquoteTXT(rr.Value)
+		case "MX":
+			priority, host, _ := strings.Cut(rr.Value, " ")
+			rec.Priority, _ = strconv.Atoi(priority)
+			rec.Content = strings.TrimSuffix(host, ".")
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// quoteTXT quotes TXT content, split in character strings of at most 255 bytes, e.g. long DKIM keys
+func quoteTXT(content string) string {
+	const maxLen = 255
+	var parts []string
+	for len(content) > maxLen {
+		parts = append(parts, content[:maxLen])
+		content = content[maxLen:]
+	}
+	parts = append(parts, content)
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+	}
+	return strings.Join(parts, " ")
+}
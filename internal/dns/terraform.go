@@ -0,0 +1,116 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"github.com/plasmash/plasmactl-platform/internal/terraform"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// TerraformRecordsFile is the variables file of the records managed by the terraform provider, in the
+// Terraform workspace of the platform. Its configuration creates the records of the dns_records variable.
+const TerraformRecordsFile = "dns_records.auto.tfvars.json"
+
+// terraformRecords is the content of the variables file of the records
+type terraformRecords struct {
+	Records []dns.Record `json:"dns_records"`
+}
+
+// tfDNS manages records with the Terraform workspace of the platform. The records last applied are
+// the ones of the variables file, Terraform makes the DNS service match them.
+type tfDNS struct {
+	ws *terraform.Workspace
+}
+
+// newTerraform creates the terraform provider with the workspace of the platform
+func newTerraform(_ schema.DNSConfig, opts dns.Options) (dns.DNSProvider, error) {
+	if opts.Platform == "" {
+		return nil, errors.New("the terraform DNS provider needs the platform of the records")
+	}
+	ws := terraform.Open(opts.Platform, opts.Log)
+	if !ws.Exists() {
+		return nil, fmt.Errorf("no Terraform configuration in %s, add the configuration creating the records of the dns_records variable", ws.Dir)
+	}
+	return &tfDNS{ws: ws}, nil
+}
+
+// PlanChanges implements dns.DNSProvider interface
+func (t *tfDNS) PlanChanges(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := t.list()
+	if err != nil {
+		return nil, err
+	}
+	return dns.Plan(existing, records), nil
+}
+
+// EnsureRecords implements dns.DNSProvider interface
+func (t *tfDNS) EnsureRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := t.list()
+	if err != nil {
+		return nil, err
+	}
+	changes := dns.Plan(existing, records)
+	return changes, t.apply(ctx, existing, changes)
+}
+
+// DeleteRecords implements dns.DNSProvider interface
+func (t *tfDNS) DeleteRecords(ctx context.Context, records []dns.Record) ([]dns.Change, error) {
+	existing, err := t.list()
+	if err != nil {
+		return nil, err
+	}
+	changes := dns.PlanDeletion(existing, records)
+	return changes, t.apply(ctx, existing, changes)
+}
+
+// list returns the records of the variables file, none before the first apply
+func (t *tfDNS) list() ([]dns.Record, error) {
+	data, err := t.ws.ReadFile(TerraformRecordsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var vars terraformRecords
+	if err = json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s: %w", TerraformRecordsFile, t.ws.Platform, err)
+	}
+	return vars.Records, nil
+}
+
+// apply writes the records changed from the existing ones to the variables file and applies the
+// workspace
+func (t *tfDNS) apply(ctx context.Context, existing []dns.Record, changes []dns.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	records := slices.Clone(existing)
+	for _, c := range changes {
+		switch c.Action {
+		case dns.ChangeCreate:
+			records = append(records, c.Record)
+		case dns.ChangeUpdate:
+			records = removeRecord(records, c.Old)
+			records = append(records, c.Record)
+		case dns.ChangeDelete:
+			records = removeRecord(records, c.Record)
+		}
+	}
+	if records == nil {
+		records = []dns.Record{}
+	}
+
+	data, err := json.MarshalIndent(terraformRecords{Records: records}, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{TerraformRecordsFile: append(data, '\n')}
+	return t.ws.ApplyFiles(ctx, files, terraform.Options{})
+}
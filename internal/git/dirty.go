@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+)
+
+// Handling of uncommitted changes by platform:up
+const (
+	DirtyCommit = "commit" // DirtyCommit commits uncommitted changes before the run
+	DirtyStash  = "stash"  // DirtyStash stashes uncommitted changes during the run and restores them afterwards
+	DirtyFail   = "fail"   // DirtyFail refuses to run with uncommitted changes
+)
+
+// stashMessage identifies stashes created by platform:up
+const stashMessage = "plasmactl platform:up"
+
+// openRepo opens the repository of the current directory
+func openRepo() (*git.Repository, error) {
+	repoPath, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	return repo, nil
+}
+
+// dirtyFiles returns the sorted paths of uncommitted changes, including untracked files
+func dirtyFiles() ([]string, error) {
+	repo, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the working tree
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	// Check for uncommitted changes
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	files := make([]string, 0, len(status))
+	for file, s := range status {
+		// The lock files of the platforms belong to the running commands
+		if path.Base(file) == lock.FileName {
+			continue
+		}
+		if s.Staging != git.Unmodified || s.Worktree != git.Unmodified {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ValidateDirtyMode checks the handling of uncommitted changes
+func ValidateDirtyMode(mode string) error {
+	switch mode {
+	case DirtyCommit, DirtyStash, DirtyFail:
+		return nil
+	}
+	return fmt.Errorf("unsupported --dirty mode %q, expected %s, %s or %s", mode, DirtyCommit, DirtyStash, DirtyFail)
+}
+
+// FailIfDirty returns an error listing uncommitted changes if any are found
+func (g *GitUp) FailIfDirty() error {
+	files, err := dirtyFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return fmt.Errorf("uncommitted changes, commit or stash them, or run with --dirty=%s or --dirty=%s:\n  %s",
+		DirtyCommit, DirtyStash, strings.Join(files, "\n  "))
+}
+
+// StashChangesIfAny stashes uncommitted changes if any are found.
+// The returned function restores them, it's a no-op if nothing was stashed.
+func (g *GitUp) StashChangesIfAny(ctx context.Context) (restore func(), err error) {
+	files, err := dirtyFiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		g.Log().Debug("No changes to stash.")
+		return func() {}, nil
+	}
+
+	g.Term().Info().Printfln("Stashing %d uncommitted change(s) during the run...", len(files))
+	cmdStash := exec.CommandContext(ctx, "git", "stash", "push", "--include-untracked", "--message", stashMessage)
+	if out, err := cmdStash.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to stash changes: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	// The changes are restored even if the run was interrupted
+	return func() {
+		cmdPop := exec.Command("git", "stash", "pop", "--index")
+		if out, err := cmdPop.CombinedOutput(); err != nil {
+			g.Term().Warning().Printfln("Failed to restore stashed changes, restore them with `git stash pop`: %s", strings.TrimSpace(string(out)))
+			return
+		}
+		g.Term().Info().Println("Restored stashed changes.")
+	}, nil
+}
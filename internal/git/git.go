@@ -2,15 +2,14 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
+	"regexp"
 	"strings"
-	"time"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
 )
 
 // GitUp provides git operations for the platform:up workflow
@@ -19,32 +18,62 @@ type GitUp struct {
 	action.WithTerm
 }
 
-// CommitChangesIfAny checks for uncommitted changes and creates a commit if any are found
-func (g *GitUp) CommitChangesIfAny() error {
-	// Open the existing repository
-	repoPath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
+// DefaultCommitMessage is the message template of commits of uncommitted changes
+const DefaultCommitMessage = "Work in progress"
 
-	repo, err := git.PlainOpen(repoPath)
-	if err != nil {
-		return fmt.Errorf("failed to open repository: %w", err)
+// commitAuthor is the author of commits of uncommitted changes, the committer is the local git user
+const commitAuthor = "Plasmactl <noreply@plasma.sh>"
+
+// CommitOptions configures the commit of uncommitted changes
+type CommitOptions struct {
+	Message     string // Message template, defaults to DefaultCommitMessage
+	Environment string // Environment replacing {environment} in the message
+	Tags        string // Tags replacing {tags} in the message
+	Sign        bool   // Sign the commit with the GPG or SSH key of the local git config
+}
+
+var commitPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// commitMessage renders the message template
+func (o CommitOptions) commitMessage() (string, error) {
+	tmpl := o.Message
+	if tmpl == "" {
+		tmpl = DefaultCommitMessage
+	}
+	fields := map[string]string{
+		"environment": o.Environment,
+		"tags":        o.Tags,
+	}
+	var unknown string
+	msg := commitPlaceholder.ReplaceAllStringFunc(tmpl, func(p string) string {
+		value, ok := fields[p[1:len(p)-1]]
+		if !ok && unknown == "" {
+			unknown = p
+		}
+		return value
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown field %s in commit message %q, supported: {environment}, {tags}", unknown, tmpl)
+	}
+	if strings.TrimSpace(msg) == "" {
+		return "", fmt.Errorf("commit message %q renders to an empty message", tmpl)
 	}
+	return msg, nil
+}
 
-	// Get the working tree
-	worktree, err := repo.Worktree()
+// CommitChangesIfAny checks for uncommitted changes and creates a commit if any are found.
+// The commit is created with the git CLI, so signing settings of the local git config apply.
+func (g *GitUp) CommitChangesIfAny(ctx context.Context, opts CommitOptions) error {
+	msg, err := opts.commitMessage()
 	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+		return err
 	}
 
-	// Check for uncommitted changes
-	status, err := worktree.Status()
+	files, err := dirtyFiles()
 	if err != nil {
-		return fmt.Errorf("failed to get worktree status: %w", err)
+		return err
 	}
-
-	if status.IsClean() {
+	if len(files) == 0 {
 		g.Log().Debug("No changes to commit.")
 		return nil
 	}
@@ -52,26 +81,31 @@ func (g *GitUp) CommitChangesIfAny() error {
 	g.Term().Info().Println("Unversioned changes detected. Creating commit...")
 
 	// Add all changes to the index
-	err = worktree.AddGlob(".")
-	if err != nil {
-		return fmt.Errorf("failed to stage changes: %w", err)
+	cmdAdd := exec.CommandContext(ctx, "git", "add", "--all", "--", ":/", lock.ExcludePathspec)
+	if out, err := cmdAdd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage changes: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 
 	// Create a commit with the staged changes
-	commitMessage := "Work in progress"
-	commit, err := worktree.Commit(commitMessage, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "Plasmactl",
-			Email: "noreply@plasma.sh",
-			When:  time.Now(),
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to commit changes: %w", err)
+	args := []string{"commit", "--quiet", "--author", commitAuthor, "--message", msg}
+	if opts.Sign {
+		args = append(args, "--gpg-sign")
+	}
+	cmdCommit := exec.CommandContext(ctx, "git", args...)
+	if out, err := cmdCommit.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to commit changes: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 
 	// Print commit details
-	obj, err := repo.CommitObject(commit)
+	repo, err := openRepo()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	obj, err := repo.CommitObject(head.Hash())
 	if err != nil {
 		return fmt.Errorf("failed to retrieve commit object: %w", err)
 	}
@@ -80,19 +114,22 @@ func (g *GitUp) CommitChangesIfAny() error {
 	g.Term().Printf("Author: %s <%s>\n", obj.Author.Name, obj.Author.Email)
 	g.Term().Printf("Date:   %s\n", obj.Author.When.Format("Mon Jan 2 15:04:05 2006 -0700"))
 	g.Term().Printf("Message: %s\n", obj.Message)
+	if obj.PGPSignature != "" {
+		g.Term().Printf("Signed: yes\n")
+	}
 	g.Term().Printf("\n")
 	return nil
 }
 
 // PushCommitsIfAny checks for unpushed commits and pushes them if any are found
-func (g *GitUp) PushCommitsIfAny() error {
+func (g *GitUp) PushCommitsIfAny(ctx context.Context) error {
 
 	// Check for un-pushed commits
-	cmdFetch := exec.Command("git", "fetch", "--quiet")
+	cmdFetch := exec.CommandContext(ctx, "git", "fetch", "--quiet")
 	if err := cmdFetch.Run(); err != nil {
 		return fmt.Errorf("failed to fetch updates: %w", err)
 	}
-	cmdStatus := exec.Command("git", "status", "-sb")
+	cmdStatus := exec.CommandContext(ctx, "git", "status", "-sb")
 	var statusOut bytes.Buffer
 	cmdStatus.Stdout = &statusOut
 	if err := cmdStatus.Run(); err != nil {
@@ -105,7 +142,7 @@ func (g *GitUp) PushCommitsIfAny() error {
 		g.Term().Info().Println("There are un-pushed commits: Pushing...")
 
 		// Push the commits
-		cmdPush := exec.Command("git", "push")
+		cmdPush := exec.CommandContext(ctx, "git", "push")
 		cmdPush.Stdout = &statusOut
 		cmdPush.Stderr = &statusOut
 		if err := cmdPush.Run(); err != nil {
@@ -121,9 +158,9 @@ func (g *GitUp) PushCommitsIfAny() error {
 }
 
 // PushBranchIfNotRemote pushes the branch to remote if it doesn't exist there
-func (g *GitUp) PushBranchIfNotRemote() error {
+func (g *GitUp) PushBranchIfNotRemote(ctx context.Context) error {
 	// Verify the remote name
-	cmdRemote := exec.Command("git", "remote")
+	cmdRemote := exec.CommandContext(ctx, "git", "remote")
 	var remoteOut bytes.Buffer
 	cmdRemote.Stdout = &remoteOut
 	if err := cmdRemote.Run(); err != nil {
@@ -144,13 +181,13 @@ func (g *GitUp) PushBranchIfNotRemote() error {
 	}
 
 	// Fetch updates to ensure we have the latest remote information
-	cmdFetch := exec.Command("git", "fetch", "--quiet", "origin")
+	cmdFetch := exec.CommandContext(ctx, "git", "fetch", "--quiet", "origin")
 	if err := cmdFetch.Run(); err != nil {
 		return fmt.Errorf("failed to fetch updates: %w", err)
 	}
 
 	// Get the current git status with tracking information
-	cmdStatus := exec.Command("git", "status", "-sb")
+	cmdStatus := exec.CommandContext(ctx, "git", "status", "-sb")
 	var statusOut bytes.Buffer
 	cmdStatus.Stdout = &statusOut
 	if err := cmdStatus.Run(); err != nil {
@@ -173,7 +210,7 @@ func (g *GitUp) PushBranchIfNotRemote() error {
 		g.Term().Info().Printf("Branch '%s' exists locally but not remotely: Pushing...\n", branchName)
 
 		// Push the branch to the remote
-		cmdPush := exec.Command("git", "push", "--set-upstream", "origin", branchName)
+		cmdPush := exec.CommandContext(ctx, "git", "push", "--set-upstream", "origin", branchName)
 		cmdPush.Stdout = &statusOut
 		cmdPush.Stderr = &statusOut
 		if err := cmdPush.Run(); err != nil {
@@ -0,0 +1,298 @@
+// Package health probes the reachability of the nodes of platforms: their SSH port, ICMP echo latency
+// and an optional HTTP health endpoint
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Statuses of a node
+const (
+	StatusUp       = "up"       // SSH and the health endpoint answer
+	StatusDegraded = "degraded" // The node answers, but not its SSH port or health endpoint
+	StatusDown     = "down"     // Nothing answers
+)
+
+// Default probe options
+const (
+	DefaultSSHPort     = 22
+	DefaultTimeout     = 3 * time.Second
+	DefaultConcurrency = 16
+)
+
+// ErrPingUnavailable is returned when ICMP sockets can't be opened, unprivileged users need
+// net.ipv4.ping_group_range on Linux
+var ErrPingUnavailable = errors.New("ICMP not permitted")
+
+// Options are the options of the probes
+type Options struct {
+	SSHPort     int           // Default is DefaultSSHPort
+	HTTP        string        // URL of the health endpoint, {host} is replaced with the node address
+	Timeout     time.Duration // Timeout of each probe, default is DefaultTimeout
+	Concurrency int           // Nodes probed at once, default is DefaultConcurrency
+}
+
+// Result is the reachability of a node
+type Result struct {
+	Node    string `json:"node" yaml:"node"`
+	Address string `json:"address" yaml:"address"`
+	Status  string `json:"status" yaml:"status"`
+
+	SSH      bool    `json:"ssh" yaml:"ssh"`
+	SSHMs    float64 `json:"ssh_ms,omitempty" yaml:"ssh_ms,omitempty"` // Connection time of the SSH port
+	SSHError string  `json:"ssh_error,omitempty" yaml:"ssh_error,omitempty"`
+
+	Ping      bool    `json:"ping" yaml:"ping"`
+	PingMs    float64 `json:"ping_ms,omitempty" yaml:"ping_ms,omitempty"`
+	PingError string  `json:"ping_error,omitempty" yaml:"ping_error,omitempty"`
+
+	HTTPStatus int    `json:"http_status,omitempty" yaml:"http_status,omitempty"` // Status of the health endpoint, when probed
+	HTTPError  string `json:"http_error,omitempty" yaml:"http_error,omitempty"`
+}
+
+// Healthy reports whether the health endpoint answered with a success status, or wasn't probed
+func (r Result) Healthy() bool {
+	return r.HTTPError == "" && (r.HTTPStatus == 0 || r.HTTPStatus < http.StatusBadRequest)
+}
+
+// ParseOptions returns the probe options of command options, an empty timeout is the default one
+func ParseOptions(httpURL string, sshPort int, timeout string) (Options, error) {
+	opts := Options{HTTP: httpURL, SSHPort: sshPort}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+		}
+		opts.Timeout = d
+	}
+	return opts, nil
+}
+
+// Check probes the nodes concurrently and returns their results in the order of the nodes
+func Check(ctx context.Context, nodes []*schema.Node, opts Options) []Result {
+	if opts.SSHPort == 0 {
+		opts.SSHPort = DefaultSSHPort
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+
+	results := make([]Result, len(nodes))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = probe(ctx, n, opts)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Address returns the address probed for a node: its first public address, or private one, or its
+// hostname
+func Address(n *schema.Node) string {
+	if addrs := slices.Concat(n.PublicIPs, n.PrivateIPs); len(addrs) > 0 {
+		return addrs[0]
+	}
+	return n.Hostname
+}
+
+// Print writes the reachability table of the nodes, with the status of the health endpoint if probed
+func Print(out io.Writer, results []Result, withHTTP bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	if withHTTP {
+		fmt.Fprintln(w, "NODE\tADDRESS\tSSH\tPING\tHTTP\tSTATUS")
+	} else {
+		fmt.Fprintln(w, "NODE\tADDRESS\tSSH\tPING\tSTATUS")
+	}
+	for _, r := range results {
+		ssh, ping := "-", "-"
+		if r.SSH {
+			ssh = fmt.Sprintf("%.1fms", r.SSHMs)
+		}
+		switch {
+		case r.Ping:
+			ping = fmt.Sprintf("%.1fms", r.PingMs)
+		case r.PingError == ErrPingUnavailable.Error():
+			ping = "n/a"
+		}
+		if !withHTTP {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Node, r.Address, ssh, ping, r.Status)
+			continue
+		}
+		status := "-"
+		if r.HTTPStatus != 0 {
+			status = strconv.Itoa(r.HTTPStatus)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Node, r.Address, ssh, ping, status, r.Status)
+	}
+	w.Flush()
+}
+
+// probe runs the probes of a node
+func probe(ctx context.Context, n *schema.Node, opts Options) Result {
+	r := Result{Node: n.Hostname, Address: Address(n)}
+
+	start := time.Now()
+	dialer := net.Dialer{Timeout: opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(r.Address, strconv.Itoa(opts.SSHPort)))
+	if err != nil {
+		r.SSHError = err.Error()
+	} else {
+		r.SSH, r.SSHMs = true, ms(time.Since(start))
+		conn.Close()
+	}
+
+	rtt, err := ping(ctx, r.Address, opts.Timeout)
+	if err != nil {
+		r.PingError = err.Error()
+	} else {
+		r.Ping, r.PingMs = true, ms(rtt)
+	}
+
+	if opts.HTTP != "" {
+		r.HTTPStatus, err = httpStatus(ctx, strings.ReplaceAll(opts.HTTP, "{host}", hostForURL(r.Address)), opts.Timeout)
+		if err != nil {
+			r.HTTPError = err.Error()
+		}
+	}
+
+	switch {
+	case r.SSH && r.Healthy():
+		r.Status = StatusUp
+	case r.SSH || r.Ping || r.HTTPStatus != 0:
+		r.Status = StatusDegraded
+	default:
+		r.Status = StatusDown
+	}
+	return r
+}
+
+// httpStatus returns the status of a GET request of the URL
+func httpStatus(ctx context.Context, url string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return res.StatusCode, fmt.Errorf("status %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// ping sends an ICMP echo request to the address and returns the round-trip time. Unprivileged
+// datagram sockets are tried first, then raw sockets.
+func ping(ctx context.Context, addr string, timeout time.Duration) (time.Duration, error) {
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", addr)
+	if err != nil {
+		return 0, err
+	}
+	ip := ips[0]
+
+	network, rawNetwork, listen, proto := "udp4", "ip4:icmp", "0.0.0.0", 1
+	var typ, reply icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, rawNetwork, listen, proto = "udp6", "ip6:ipv6-icmp", "::", 58
+		typ, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	conn, err := icmp.ListenPacket(network, listen)
+	if err != nil {
+		if conn, err = icmp.ListenPacket(rawNetwork, listen); err != nil {
+			return 0, ErrPingUnavailable
+		}
+		dst = &net.IPAddr{IP: ip}
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("plasmactl")}}
+	data, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	if _, err = conn.WriteTo(data, dst); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return 0, errors.New("no echo reply")
+			}
+			return 0, err
+		}
+		rm, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || rm.Type != reply || !sameIP(peer, ip) {
+			continue
+		}
+		// Datagram sockets rewrite the echo ID, replies are matched by peer and sequence
+		if echo, ok := rm.Body.(*icmp.Echo); ok && echo.Seq == 1 {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// sameIP reports whether the address of a peer is the IP
+func sameIP(peer net.Addr, ip net.IP) bool {
+	switch a := peer.(type) {
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
+
+// hostForURL returns the address in URL host notation, IPv6 addresses in brackets
+func hostForURL(addr string) string {
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+// ms returns the duration in milliseconds, rounded to a tenth
+func ms(d time.Duration) float64 {
+	return float64(d.Round(100*time.Microsecond)) / float64(time.Millisecond)
+}
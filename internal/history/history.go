@@ -0,0 +1,177 @@
+// Package history records the deployments of platforms, to tell when each was last deployed and
+// keep a journal of their runs
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+)
+
+// Dir is the directory of the deployment records relative to the repository root, one file per platform
+const Dir = ".plasmactl/state/deploys"
+
+// Results of the deployments
+const (
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+)
+
+// Deployment is a completed deployment of a platform
+type Deployment struct {
+	Environment string    `json:"environment" yaml:"environment"`
+	Tags        string    `json:"tags" yaml:"tags"`
+	Image       string    `json:"image,omitempty" yaml:"image,omitempty"`     // Platform Image deployed, if any
+	Version     string    `json:"version,omitempty" yaml:"version,omitempty"` // Version of the Platform Image
+	Time        time.Time `json:"time" yaml:"time"`                           // End of the deployment
+	Result      string    `json:"result,omitempty" yaml:"result,omitempty"`
+	DurationMs  int64     `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+}
+
+// File returns the record of the last deployment of a platform, relative to the repository root
+func File(environment string) string {
+	return filepath.Join(Dir, environment+".json")
+}
+
+// JournalFile returns the journal of the deployments of a platform, relative to the repository root,
+// one JSON deployment per line
+func JournalFile(environment string) string {
+	return filepath.Join(Dir, environment+".jsonl")
+}
+
+// Record appends a deployment to the journal of its platform under the repository root, and writes it as
+// its last deployment if it succeeded
+func Record(root string, d Deployment) error {
+	path := filepath.Join(root, File(d.Environment))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create deployment history directory: %w", err)
+	}
+
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(root, JournalFile(d.Environment)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open deployment journal: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record deployment: %w", err)
+	}
+
+	if d.Result != ResultSuccess {
+		return nil
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = atomicfile.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to record deployment: %w", err)
+	}
+	return nil
+}
+
+// Journal returns the last deployments of a platform from the repository root, the most recent first,
+// all of them if limit isn't positive
+func Journal(root, environment string, limit int) ([]Deployment, error) {
+	f, err := os.Open(filepath.Join(root, JournalFile(environment)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment journal: %w", err)
+	}
+	defer f.Close()
+
+	var deployments []Deployment
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var d Deployment
+		if err = json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", JournalFile(environment), n, err)
+		}
+		deployments = append(deployments, d)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deployment journal: %w", err)
+	}
+	slices.Reverse(deployments)
+	if limit > 0 && len(deployments) > limit {
+		deployments = deployments[:limit]
+	}
+	return deployments, nil
+}
+
+// Last returns the last deployment of a platform from the repository root, nil if it was never deployed
+// from it
+func Last(root, environment string) (*Deployment, error) {
+	data, err := os.ReadFile(filepath.Join(root, File(environment)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment history: %w", err)
+	}
+	var d Deployment
+	if err = json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", File(environment), err)
+	}
+	return &d, nil
+}
+
+// Print writes the table of deployments, with the version and file of their Platform Image in separate
+// columns when wide
+func Print(out io.Writer, deployments []Deployment, wide bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	if wide {
+		fmt.Fprintln(w, "TIME\tENVIRONMENT\tTAGS\tVERSION\tIMAGE\tRESULT\tDURATION")
+	} else {
+		fmt.Fprintln(w, "TIME\tENVIRONMENT\tTAGS\tIMAGE\tRESULT\tDURATION")
+	}
+	for _, d := range deployments {
+		image := d.Version
+		if image == "" && d.Image != "" {
+			image = filepath.Base(d.Image)
+		}
+		if wide {
+			image = dash(d.Version) + "\t" + dash(d.Image)
+		} else {
+			image = dash(image)
+		}
+		result, duration := d.Result, "-"
+		if result == "" {
+			result = ResultSuccess // Recorded before the journal, only successful deployments were
+		}
+		if d.DurationMs > 0 {
+			duration = (time.Duration(d.DurationMs) * time.Millisecond).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Time.Local().Format(time.DateTime), d.Environment, d.Tags, image, result, duration)
+	}
+	w.Flush()
+}
+
+// dash returns the value, a dash when empty
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
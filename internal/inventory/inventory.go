@@ -0,0 +1,167 @@
+// Package inventory generates static Ansible inventories from the node files of platforms, so
+// deployments don't depend on the dynamic inventory cache of the metal provider
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Group name prefixes of the capabilities and chassis, roles and groups of the nodes are groups of
+// their own name
+const (
+	CapabilityPrefix = "capability_"
+	ChassisPrefix    = "chassis_"
+)
+
+// ErrNoNodes is returned when the nodes directory has no node file
+var ErrNoNodes = errors.New("no node files")
+
+// unsafeGroupChars are replaced in group names, Ansible only accepts letters, digits and underscores
+var unsafeGroupChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// host is a host of the inventory with its variables
+type host struct {
+	name string
+	vars map[string]any
+}
+
+// Generate returns the YAML inventory of the node files of a nodes directory and its host count.
+// Host variables are the keys of the node files, with ansible_host set to the first public address,
+// or private one, unless the file sets it.
+func Generate(nodesDir string) ([]byte, int, error) {
+	files, err := schema.NodeFiles(nodesDir)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(files) == 0 {
+		return nil, 0, fmt.Errorf("%w in %s", ErrNoNodes, nodesDir)
+	}
+
+	hosts := make([]host, 0, len(files))
+	groups := make(map[string][]string)
+	for _, f := range files {
+		node, err := schema.LoadNode(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		vars, err := readVars(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		delete(vars, "hostname")
+		if _, ok := vars["ansible_host"]; !ok {
+			if addrs := slices.Concat(node.PublicIPs, node.PrivateIPs); len(addrs) > 0 {
+				vars["ansible_host"] = addrs[0]
+			}
+		}
+		hosts = append(hosts, host{name: node.Hostname, vars: vars})
+
+		for _, name := range slices.Concat(node.Roles, node.Groups) {
+			addToGroup(groups, groupName("", name), node.Hostname)
+		}
+		for _, c := range node.Capabilities {
+			addToGroup(groups, groupName(CapabilityPrefix, c), node.Hostname)
+		}
+		if node.Chassis != "" {
+			addToGroup(groups, groupName(ChassisPrefix, node.Chassis), node.Hostname)
+		}
+	}
+
+	data, err := yaml.Marshal(inventory(hosts, groups))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	return data, len(hosts), nil
+}
+
+// Write generates the inventory of a nodes directory into a file and returns its host count
+func Write(nodesDir, path string) (int, error) {
+	data, count, err := Generate(nodesDir)
+	if err != nil {
+		return 0, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create inventory directory: %w", err)
+	}
+	if err = atomicfile.WriteFile(path, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write inventory: %w", err)
+	}
+	return count, nil
+}
+
+// readVars returns the keys of a node file
+func readVars(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node: %w", err)
+	}
+	vars := make(map[string]any)
+	if err = yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// groupName returns the Ansible group name of a role, capability or chassis
+func groupName(prefix, name string) string {
+	return prefix + unsafeGroupChars.ReplaceAllString(name, "_")
+}
+
+// addToGroup adds a host to a group once
+func addToGroup(groups map[string][]string, group, hostname string) {
+	if !slices.Contains(groups[group], hostname) {
+		groups[group] = append(groups[group], hostname)
+	}
+}
+
+// inventory returns the YAML document of the inventory, hosts and groups sorted by name
+func inventory(hosts []host, groups map[string][]string) *yaml.Node {
+	hostsNode := mapping()
+	slices.SortFunc(hosts, func(a, b host) int { return strings.Compare(a.name, b.name) })
+	for _, h := range hosts {
+		var vars yaml.Node
+		_ = vars.Encode(h.vars)
+		hostsNode.Content = append(hostsNode.Content, scalar(h.name), &vars)
+	}
+
+	children := mapping()
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		members := mapping()
+		for _, h := range slices.Sorted(slices.Values(groups[name])) {
+			members.Content = append(members.Content, scalar(h), mapping())
+		}
+		group := mapping()
+		group.Content = append(group.Content, scalar("hosts"), members)
+		children.Content = append(children.Content, scalar(name), group)
+	}
+
+	all := mapping()
+	all.Content = append(all.Content, scalar("hosts"), hostsNode)
+	if len(children.Content) > 0 {
+		all.Content = append(all.Content, scalar("children"), children)
+	}
+	root := mapping()
+	root.Content = append(root.Content, scalar("all"), all)
+	return root
+}
+
+// mapping returns an empty YAML mapping
+func mapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// scalar returns a YAML string
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,258 @@
+package metal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// HetznerTokenKey is the keyring key of the Hetzner Cloud API token, which needs the read and write permissions
+const HetznerTokenKey = "hetzner_api_token"
+
+// hetznerAPI is the base URL of the Hetzner Cloud API
+const hetznerAPI = "https://api.hetzner.cloud/v1"
+
+// hetznerIPKinds are the resources of the Hetzner Cloud API holding public addresses
+var hetznerIPKinds = []string{"primary_ips", "floating_ips"}
+
+// hetzner manages the servers of a Hetzner Cloud project
+type hetzner struct {
+	api   string
+	token string
+	log   *launchr.Logger
+}
+
+// hetznerPTR is the reverse DNS of an address of the Hetzner Cloud API
+type hetznerPTR struct {
+	IP     string `json:"ip"`
+	DNSPtr string `json:"dns_ptr"`
+}
+
+// hetznerServer is a server of the Hetzner Cloud API
+type hetznerServer struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	ServerType struct {
+		Name string `json:"name"`
+	} `json:"server_type"`
+	Datacenter struct {
+		Name string `json:"name"`
+	} `json:"datacenter"`
+	PublicNet struct {
+		IPv4 *struct {
+			IP     string `json:"ip"`
+			DNSPtr string `json:"dns_ptr"`
+		} `json:"ipv4"`
+		IPv6 *struct {
+			IP     string       `json:"ip"`
+			DNSPtr []hetznerPTR `json:"dns_ptr"`
+		} `json:"ipv6"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		IP string `json:"ip"`
+	} `json:"private_net"`
+}
+
+// hetznerIP is a primary or floating IP of the Hetzner Cloud API, IPv6 ones are a /64 network
+type hetznerIP struct {
+	ID     int64        `json:"id"`
+	IP     string       `json:"ip"`
+	Type   string       `json:"type"`
+	Server *int64       `json:"server"`
+	DNSPtr []hetznerPTR `json:"dns_ptr"`
+}
+
+// newHetzner creates the Hetzner Cloud provider with the API token of platform.yaml or the keyring
+func newHetzner(infra schema.Infrastructure, opts provider.Options) (provider.MetalProvider, error) {
+	uri := infra.API.URI
+	if uri == "" {
+		uri = hetznerAPI
+	}
+	t, err := token(infra, opts, HetznerTokenKey)
+	if err != nil {
+		return nil, err
+	}
+	return &hetzner{api: strings.TrimSuffix(uri, "/"), token: t, log: opts.Log}, nil
+}
+
+// ListServers implements provider.MetalProvider interface
+func (h *hetzner) ListServers(ctx context.Context) ([]provider.Server, error) {
+	servers, err := hetznerList[hetznerServer](ctx, h, "servers")
+	if err != nil {
+		return nil, err
+	}
+	floating, err := hetznerList[hetznerIP](ctx, h, "floating_ips")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]provider.Server, 0, len(servers))
+	for _, s := range servers {
+		result = append(result, h.server(s, floating))
+	}
+	return result, nil
+}
+
+// GetServer implements provider.MetalProvider interface
+func (h *hetzner) GetServer(ctx context.Context, id string) (provider.Server, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return provider.Server{}, fmt.Errorf("%w: invalid Hetzner Cloud server ID %q", provider.ErrServerNotFound, id)
+	}
+	var result struct {
+		Server hetznerServer `json:"server"`
+	}
+	err := h.call(ctx, http.MethodGet, "/servers/"+id, nil, &result)
+	if isNotFound(err) {
+		return provider.Server{}, fmt.Errorf("%w: %s", provider.ErrServerNotFound, id)
+	}
+	if err != nil {
+		return provider.Server{}, fmt.Errorf("failed to get server %s: %w", id, err)
+	}
+	floating, err := hetznerList[hetznerIP](ctx, h, "floating_ips")
+	if err != nil {
+		return provider.Server{}, err
+	}
+	return h.server(result.Server, floating), nil
+}
+
+// Reboot implements provider.MetalProvider interface
+func (h *hetzner) Reboot(ctx context.Context, id string) error {
+	if err := h.call(ctx, http.MethodPost, "/servers/"+id+"/actions/reboot", nil, nil); err != nil {
+		return fmt.Errorf("failed to reboot server %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetPTR implements provider.MetalProvider interface. The address is looked up in the primary and
+// floating IPs of the project, IPv6 ones in their /64 network.
+func (h *hetzner) SetPTR(ctx context.Context, addr, name string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	for _, kind := range hetznerIPKinds {
+		ips, err := hetznerList[hetznerIP](ctx, h, kind)
+		if err != nil {
+			return err
+		}
+		for _, hip := range ips {
+			if !hetznerContains(hip, ip) {
+				continue
+			}
+			// A null dns_ptr restores the default reverse DNS
+			payload := map[string]any{"ip": ip.String(), "dns_ptr": nil}
+			if name != "" {
+				payload["dns_ptr"] = name
+			}
+			path := fmt.Sprintf("/%s/%d/actions/change_dns_ptr", kind, hip.ID)
+			if err = h.call(ctx, http.MethodPost, path, payload, nil); err != nil {
+				return fmt.Errorf("failed to set reverse DNS of %s: %w", addr, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("address %s is not a primary or floating IP of the project", addr)
+}
+
+// server converts a server of the API, with the floating IPs assigned to it
+func (h *hetzner) server(s hetznerServer, floating []hetznerIP) provider.Server {
+	result := provider.Server{
+		ID:       strconv.FormatInt(s.ID, 10),
+		Hostname: s.Name,
+		Status:   s.Status,
+		Offer:    s.ServerType.Name,
+		Location: s.Datacenter.Name,
+	}
+	if v4 := s.PublicNet.IPv4; v4 != nil && v4.IP != "" {
+		result.Addresses = append(result.Addresses, provider.Address{IP: v4.IP, Reverse: strings.TrimSuffix(v4.DNSPtr, ".")})
+	}
+	if v6 := s.PublicNet.IPv6; v6 != nil && v6.IP != "" {
+		result.Networks = append(result.Networks, v6.IP)
+		result.Addresses = append(result.Addresses, hetznerAddresses(v6.DNSPtr)...)
+	}
+	for _, ip := range floating {
+		if ip.Server == nil || *ip.Server != s.ID {
+			continue
+		}
+		if ip.Type == "ipv6" {
+			result.Networks = append(result.Networks, ip.IP)
+		} else if len(ip.DNSPtr) == 0 {
+			result.Addresses = append(result.Addresses, provider.Address{IP: ip.IP})
+		}
+		result.Addresses = append(result.Addresses, hetznerAddresses(ip.DNSPtr)...)
+	}
+	for _, p := range s.PrivateNet {
+		result.Addresses = append(result.Addresses, provider.Address{IP: p.IP, Private: true})
+	}
+	return result
+}
+
+// hetznerAddresses returns the public addresses with a reverse DNS
+func hetznerAddresses(ptrs []hetznerPTR) []provider.Address {
+	var addrs []provider.Address
+	for _, p := range ptrs {
+		if ip := net.ParseIP(p.IP); ip != nil {
+			addrs = append(addrs, provider.Address{IP: ip.String(), Reverse: strings.TrimSuffix(p.DNSPtr, ".")})
+		}
+	}
+	return addrs
+}
+
+// hetznerContains reports whether the address is the IPv4 or in the IPv6 network of a primary or floating IP
+func hetznerContains(hip hetznerIP, ip net.IP) bool {
+	if _, ipNet, err := net.ParseCIDR(hip.IP); err == nil {
+		return ipNet.Contains(ip)
+	}
+	return ip.Equal(net.ParseIP(hip.IP))
+}
+
+// hetznerList returns all the pages of a resource list of the API
+func hetznerList[T any](ctx context.Context, h *hetzner, kind string) ([]T, error) {
+	var items []T
+	for page := 1; page > 0; {
+		var result map[string]json.RawMessage
+		if err := h.call(ctx, http.MethodGet, fmt.Sprintf("/%s?per_page=50&page=%d", kind, page), nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+		}
+		var pageItems []T
+		var meta struct {
+			Pagination struct {
+				NextPage int `json:"next_page"`
+			} `json:"pagination"`
+		}
+		if err := json.Unmarshal(result[kind], &pageItems); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", kind, err)
+		}
+		if err := json.Unmarshal(result["meta"], &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", kind, err)
+		}
+		items = append(items, pageItems...)
+		page = meta.Pagination.NextPage
+	}
+	return items, nil
+}
+
+// call sends a request to the Hetzner Cloud API and decodes its response into v, if not nil
+func (h *hetzner) call(ctx context.Context, method, path string, payload any, v any) error {
+	header := http.Header{"Authorization": {"Bearer " + h.token}}
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	h.log.Debug("Hetzner API request", "method", method, "path", path)
+	return api.Request(ctx, method, h.api+path, header, body, v)
+}
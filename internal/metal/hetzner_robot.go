@@ -0,0 +1,207 @@
+package metal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Keyring keys of the Hetzner Robot webservice user, created in Robot under Settings > Webservice and app settings
+const (
+	HetznerRobotUserKey     = "hetzner_robot_user"
+	HetznerRobotPasswordKey = "hetzner_robot_password"
+)
+
+// hetznerRobotAPI is the base URL of the Hetzner Robot webservice
+const hetznerRobotAPI = "https://robot-ws.hetzner.com"
+
+// hetznerRobot manages the dedicated servers of a Hetzner Robot account
+type hetznerRobot struct {
+	api  string
+	auth string
+	log  *launchr.Logger
+}
+
+// hetznerRobotServer is a dedicated server of the Robot webservice
+type hetznerRobotServer struct {
+	ServerNumber int      `json:"server_number"`
+	ServerName   string   `json:"server_name"`
+	Product      string   `json:"product"`
+	DC           string   `json:"dc"`
+	Status       string   `json:"status"`
+	IP           []string `json:"ip"`
+	Subnet       []struct {
+		IP   string `json:"ip"`
+		Mask string `json:"mask"`
+	} `json:"subnet"`
+}
+
+// hetznerRobotRDNS is a reverse DNS entry of the Robot webservice
+type hetznerRobotRDNS struct {
+	RDNS struct {
+		IP  string `json:"ip"`
+		PTR string `json:"ptr"`
+	} `json:"rdns"`
+}
+
+// newHetznerRobot creates the Hetzner Robot provider with the webservice user of the keyring
+func newHetznerRobot(infra schema.Infrastructure, opts provider.Options) (provider.MetalProvider, error) {
+	uri := infra.API.URI
+	if uri == "" {
+		uri = hetznerRobotAPI
+	}
+	user, err := credentials(opts).Token(opts.Keyring, opts.Log, opts.Term, HetznerRobotUserKey)
+	if err != nil {
+		return nil, err
+	}
+	password, err := credentials(opts).Token(opts.Keyring, opts.Log, opts.Term, HetznerRobotPasswordKey)
+	if err != nil {
+		return nil, err
+	}
+	return &hetznerRobot{
+		api:  strings.TrimSuffix(uri, "/"),
+		auth: base64.StdEncoding.EncodeToString([]byte(user + ":" + password)),
+		log:  opts.Log,
+	}, nil
+}
+
+// ListServers implements provider.MetalProvider interface
+func (h *hetznerRobot) ListServers(ctx context.Context) ([]provider.Server, error) {
+	var servers []struct {
+		Server hetznerRobotServer `json:"server"`
+	}
+	if err := h.call(ctx, http.MethodGet, "/server", nil, &servers); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	reverse, err := h.reverse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]provider.Server, 0, len(servers))
+	for _, s := range servers {
+		result = append(result, s.Server.server(reverse))
+	}
+	return result, nil
+}
+
+// GetServer implements provider.MetalProvider interface
+func (h *hetznerRobot) GetServer(ctx context.Context, id string) (provider.Server, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return provider.Server{}, fmt.Errorf("%w: invalid Hetzner Robot server number %q", provider.ErrServerNotFound, id)
+	}
+	var result struct {
+		Server hetznerRobotServer `json:"server"`
+	}
+	err := h.call(ctx, http.MethodGet, "/server/"+id, nil, &result)
+	if isNotFound(err) {
+		return provider.Server{}, fmt.Errorf("%w: %s", provider.ErrServerNotFound, id)
+	}
+	if err != nil {
+		return provider.Server{}, fmt.Errorf("failed to get server %s: %w", id, err)
+	}
+	reverse, err := h.reverse(ctx)
+	if err != nil {
+		return provider.Server{}, err
+	}
+	return result.Server.server(reverse), nil
+}
+
+// Reboot implements provider.MetalProvider interface, with a software reset (CTRL+ALT+DEL)
+func (h *hetznerRobot) Reboot(ctx context.Context, id string) error {
+	if err := h.call(ctx, http.MethodPost, "/reset/"+url.PathEscape(id), url.Values{"type": {"sw"}}, nil); err != nil {
+		return fmt.Errorf("failed to reboot server %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetPTR implements provider.MetalProvider interface
+func (h *hetznerRobot) SetPTR(ctx context.Context, addr, name string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	path := "/rdns/" + url.PathEscape(ip.String())
+	var err error
+	if name == "" {
+		// Robot has no default reverse DNS, removing the entry restores the one of Hetzner
+		if err = h.call(ctx, http.MethodDelete, path, nil, nil); isNotFound(err) {
+			err = nil
+		}
+	} else {
+		err = h.call(ctx, http.MethodPost, path, url.Values{"ptr": {name}}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set reverse DNS of %s: %w", addr, err)
+	}
+	return nil
+}
+
+// reverse returns the reverse DNS entries of the account by address
+func (h *hetznerRobot) reverse(ctx context.Context) (map[string]string, error) {
+	var entries []hetznerRobotRDNS
+	// The webservice answers 404 when the account has no entry
+	if err := h.call(ctx, http.MethodGet, "/rdns", nil, &entries); err != nil && !isNotFound(err) {
+		return nil, fmt.Errorf("failed to list reverse DNS: %w", err)
+	}
+	reverse := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if ip := net.ParseIP(e.RDNS.IP); ip != nil {
+			reverse[ip.String()] = strings.TrimSuffix(e.RDNS.PTR, ".")
+		}
+	}
+	return reverse, nil
+}
+
+// server converts a server of the webservice, with the reverse DNS of its addresses and subnets
+func (s hetznerRobotServer) server(reverse map[string]string) provider.Server {
+	result := provider.Server{
+		ID:       strconv.Itoa(s.ServerNumber),
+		Hostname: s.ServerName,
+		Status:   s.Status,
+		Offer:    s.Product,
+		Location: s.DC,
+	}
+	for _, addr := range s.IP {
+		if ip := net.ParseIP(addr); ip != nil {
+			result.Addresses = append(result.Addresses, provider.Address{IP: ip.String(), Reverse: reverse[ip.String()]})
+		}
+	}
+	for _, sub := range s.Subnet {
+		_, ipNet, err := net.ParseCIDR(sub.IP + "/" + sub.Mask)
+		if err != nil {
+			continue
+		}
+		result.Networks = append(result.Networks, ipNet.String())
+		for _, addr := range slices.Sorted(maps.Keys(reverse)) {
+			if ipNet.Contains(net.ParseIP(addr)) {
+				result.Addresses = append(result.Addresses, provider.Address{IP: addr, Reverse: reverse[addr]})
+			}
+		}
+	}
+	return result
+}
+
+// call sends a request to the Robot webservice and decodes its response into v, if not nil
+func (h *hetznerRobot) call(ctx context.Context, method, path string, form url.Values, v any) error {
+	header := http.Header{"Authorization": {"Basic " + h.auth}}
+	var body []byte
+	if form != nil {
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+		body = []byte(form.Encode())
+	}
+	h.log.Debug("Hetzner Robot API request", "method", method, "path", path)
+	return api.Request(ctx, method, h.api+path, header, body, v)
+}
@@ -0,0 +1,48 @@
+// Package metal implements the built-in metal providers, registered in the provider registry
+package metal
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Built-in metal providers.
+const (
+	ProviderScaleway     = "scaleway"      // ProviderScaleway manages Scaleway Dedibox (online.net) servers
+	ProviderHetzner      = "hetzner"       // ProviderHetzner manages Hetzner Cloud servers
+	ProviderHetznerRobot = "hetzner-robot" // ProviderHetznerRobot manages Hetzner Robot dedicated servers
+)
+
+func init() {
+	provider.Register(ProviderScaleway, newScaleway)
+	provider.Register(ProviderHetzner, newHetzner)
+	provider.Register(ProviderHetznerRobot, newHetznerRobot)
+}
+
+// token returns the API token of infrastructure.api.token of platform.yaml, read from the keyring when
+// it references a keyring key like {{ .keyring.<key> }} or is empty, then under the default key
+func token(infra schema.Infrastructure, opts provider.Options, defaultKey string) (string, error) {
+	value := infra.API.Token
+	if key, ok := api.KeyringKey(value); ok {
+		return api.Token(opts.Keyring, opts.Log, opts.Term, key)
+	}
+	if value != "" {
+		return value, nil
+	}
+	return credentials(opts).Token(opts.Keyring, opts.Log, opts.Term, defaultKey)
+}
+
+// credentials returns the scope of the provider credentials of the keyring
+func credentials(opts provider.Options) api.Scope {
+	return api.NewScope(opts.Platform, api.PurposeProvider)
+}
+
+// isNotFound reports whether the provider API answered 404
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
@@ -0,0 +1,168 @@
+package metal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ScalewayTokenKey is the keyring key of the Scaleway Dedibox API private token
+const ScalewayTokenKey = "scaleway_api_token"
+
+// scalewayAPI is the base URL of the Scaleway Dedibox (Online) API
+const scalewayAPI = "https://api.online.net/api/v1/"
+
+// scaleway manages Dedibox servers with the Online API
+type scaleway struct {
+	api   *url.URL
+	token string
+	log   *launchr.Logger
+}
+
+// scalewayServer is a server of the Online API
+type scalewayServer struct {
+	ID       int    `json:"id"`
+	Hostname string `json:"hostname"`
+	Power    string `json:"power"`
+	Offer    string `json:"offer"`
+	Location struct {
+		Datacenter string `json:"datacenter"`
+	} `json:"location"`
+	IP []struct {
+		Address string `json:"address"`
+		Reverse string `json:"reverse"`
+		Type    string `json:"type"`
+	} `json:"ip"`
+}
+
+// newScaleway creates the Scaleway provider with the API token of platform.yaml or the keyring
+func newScaleway(infra schema.Infrastructure, opts provider.Options) (provider.MetalProvider, error) {
+	uri := infra.API.URI
+	if uri == "" {
+		uri = scalewayAPI
+	}
+	u, err := url.Parse(strings.TrimSuffix(uri, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid infrastructure.api.uri %q: %w", uri, err)
+	}
+	t, err := token(infra, opts, ScalewayTokenKey)
+	if err != nil {
+		return nil, err
+	}
+	return &scaleway{api: u, token: t, log: opts.Log}, nil
+}
+
+// ListServers implements provider.MetalProvider interface
+func (s *scaleway) ListServers(ctx context.Context) ([]provider.Server, error) {
+	var paths []string
+	if err := s.call(ctx, http.MethodGet, "server", nil, &paths); err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+
+	servers := make([]provider.Server, 0, len(paths))
+	for _, path := range paths {
+		server, err := s.get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// GetServer implements provider.MetalProvider interface
+func (s *scaleway) GetServer(ctx context.Context, id string) (provider.Server, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return provider.Server{}, fmt.Errorf("%w: invalid Dedibox server ID %q", provider.ErrServerNotFound, id)
+	}
+	return s.get(ctx, "server/"+id)
+}
+
+// Reboot implements provider.MetalProvider interface
+func (s *scaleway) Reboot(ctx context.Context, id string) error {
+	if err := s.call(ctx, http.MethodPost, "server/reboot/"+url.PathEscape(id), url.Values{}, nil); err != nil {
+		return fmt.Errorf("failed to reboot server %s: %w", id, err)
+	}
+	return nil
+}
+
+// SetPTR implements provider.MetalProvider interface
+func (s *scaleway) SetPTR(ctx context.Context, addr, name string) error {
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	form := url.Values{"address": {addr}, "reverse": {name}}
+	if err := s.call(ctx, http.MethodPost, "server/ip/edit", form, nil); err != nil {
+		return fmt.Errorf("failed to set reverse DNS of %s: %w", addr, err)
+	}
+	return nil
+}
+
+// Check implements provider.Checker interface, Dedibox tokens have no scopes
+func (s *scaleway) Check(ctx context.Context) (provider.Check, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := s.call(ctx, http.MethodGet, "user", nil, &user); err != nil {
+		return provider.Check{}, fmt.Errorf("API token refused: %w", err)
+	}
+	return provider.Check{Account: user.Login}, nil
+}
+
+// get returns the server of an API path, relative or absolute like the ones of the server list
+func (s *scaleway) get(ctx context.Context, path string) (provider.Server, error) {
+	var server scalewayServer
+	err := s.call(ctx, http.MethodGet, path, nil, &server)
+	if isNotFound(err) {
+		return provider.Server{}, fmt.Errorf("%w: %s", provider.ErrServerNotFound, path)
+	}
+	if err != nil {
+		return provider.Server{}, fmt.Errorf("failed to get server %s: %w", path, err)
+	}
+
+	result := provider.Server{
+		ID:       strconv.Itoa(server.ID),
+		Hostname: server.Hostname,
+		Status:   server.Power,
+		Offer:    server.Offer,
+		Location: server.Location.Datacenter,
+	}
+	for _, ip := range server.IP {
+		parsed := net.ParseIP(ip.Address)
+		if parsed == nil {
+			continue
+		}
+		result.Addresses = append(result.Addresses, provider.Address{
+			IP:      parsed.String(),
+			Private: ip.Type != "public",
+			Reverse: strings.TrimSuffix(ip.Reverse, "."),
+		})
+	}
+	return result, nil
+}
+
+// call sends a request to the Dedibox API, the path is relative to the API or absolute like the
+// server paths it returns
+func (s *scaleway) call(ctx context.Context, method, path string, form url.Values, v any) error {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Authorization": {"Bearer " + s.token}}
+	var body []byte
+	if form != nil {
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+		body = []byte(form.Encode())
+	}
+	s.log.Debug("Scaleway API request", "method", method, "path", path)
+	return api.Request(ctx, method, s.api.ResolveReference(ref).String(), header, body, v)
+}
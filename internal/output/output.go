@@ -0,0 +1,80 @@
+// Package output renders the output of the actions in the formats of their --output option: tables for
+// humans, JSON, YAML and Go templates for scripts
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the --output option of the actions, besides the Go templates
+const (
+	Table = "table" // Human-readable output, the default
+	Wide  = "wide"  // Human-readable output with additional columns
+	JSON  = "json"
+	YAML  = "yaml"
+)
+
+// Parse returns the format of an --output value, lowercased and the table when empty. Templates are kept
+// as they are.
+func Parse(value string) (string, error) {
+	if IsTemplate(value) {
+		return value, nil
+	}
+	format := strings.ToLower(strings.TrimSpace(value))
+	switch format {
+	case "":
+		return Table, nil
+	case Table, Wide, JSON, YAML:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (use %s, %s, %s, %s or %s'...')", value, Table, Wide, JSON, YAML, TemplatePrefix)
+}
+
+// Structured reports whether an --output value prints data for scripts instead of the human-readable output
+func Structured(value string) bool {
+	format, _ := Parse(value)
+	return format == JSON || format == YAML || IsTemplate(format)
+}
+
+// JSONLogs reports whether the logs are written as JSON records for the log pipelines, with --log-format
+// json. The human-readable output then goes to stderr, so stdout holds only the records.
+func JSONLogs() bool {
+	return launchr.EnvVarLogFormat.Get() == JSON
+}
+
+// Render writes data in the format of an --output value, or calls table for the human-readable output,
+// telling whether the wide one was asked
+func Render(out io.Writer, value string, data any, table func(wide bool) error) error {
+	format, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	switch {
+	case format == JSON:
+		output, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(output))
+		return err
+	case format == YAML:
+		output, err := yaml.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		_, err = out.Write(output)
+		return err
+	case IsTemplate(format):
+		return Template(out, format, data)
+	}
+	if table == nil {
+		return nil
+	}
+	return table(format == Wide)
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefixes of the output formats rendering a Go template, inline or from a file, as kubectl does
+const (
+	TemplatePrefix     = "go-template="
+	TemplateFilePrefix = "go-template-file="
+)
+
+// IsTemplate reports whether an output format renders a Go template
+func IsTemplate(format string) bool {
+	return strings.HasPrefix(format, TemplatePrefix) || strings.HasPrefix(format, TemplateFilePrefix)
+}
+
+// Template renders data with the Go template of an output format, e.g. go-template='{{.name}}'. Data
+// goes through YAML first, so the template uses the keys of the YAML output, e.g. {{.platform.dns.domain}}.
+func Template(out io.Writer, format string, data any) error {
+	var text string
+	switch {
+	case strings.HasPrefix(format, TemplatePrefix):
+		text = strings.TrimPrefix(format, TemplatePrefix)
+	case strings.HasPrefix(format, TemplateFilePrefix):
+		path := strings.TrimPrefix(format, TemplateFilePrefix)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		text = string(content)
+	default:
+		return fmt.Errorf("output %q is not a template, use %s or %s", format, TemplatePrefix, TemplateFilePrefix)
+	}
+	if text == "" {
+		return fmt.Errorf("output %s needs a template, e.g. %s'{{.name}}'", format, TemplatePrefix)
+	}
+
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	raw, err := yaml.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	var values any
+	if err = yaml.Unmarshal(raw, &values); err != nil {
+		return fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if err = tmpl.Execute(out, values); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
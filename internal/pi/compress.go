@@ -0,0 +1,172 @@
+package pi
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// Compression is the compression algorithm of a Platform Image
+type Compression string
+
+// Supported compression algorithms
+const (
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+	CompressionNone Compression = "none"
+)
+
+// DefaultCompression is used when no compression is specified
+const DefaultCompression = CompressionGzip
+
+// I/O tuning for large platform trees
+const (
+	// bufferSize is used for file copies and buffered archive writes
+	bufferSize = 1 << 20
+	// gzipBlockSize is the size of blocks compressed in parallel by gzip
+	gzipBlockSize = 1 << 20
+)
+
+// Magic numbers used to detect compression on extraction
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseCompression validates a compression name, empty string selects the default
+func ParseCompression(s string) (Compression, error) {
+	switch c := Compression(strings.ToLower(s)); c {
+	case "":
+		return DefaultCompression, nil
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q, expected gzip, zstd or none", s)
+	}
+}
+
+// validateLevel checks the compression level, 0 selects the algorithm default
+func validateLevel(c Compression, level int) error {
+	if level == 0 {
+		return nil
+	}
+	switch c {
+	case CompressionGzip:
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return fmt.Errorf("gzip compression level must be between %d and %d", gzip.BestSpeed, gzip.BestCompression)
+		}
+	case CompressionZstd:
+		if level < 1 || level > 22 {
+			return fmt.Errorf("zstd compression level must be between 1 and 22")
+		}
+	case CompressionNone:
+		return fmt.Errorf("compression level can't be used without compression")
+	}
+	return nil
+}
+
+// nopWriteCloser is used for uncompressed archives
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressWriter wraps w with the requested compression
+// Compression runs in parallel on all available CPUs, the output stays a standard gzip or zstd stream.
+func newCompressWriter(w io.Writer, c Compression, level int) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gzw, err := pgzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, err
+		}
+		if err := gzw.SetConcurrency(gzipBlockSize, runtime.GOMAXPROCS(0)); err != nil {
+			return nil, err
+		}
+		return gzw, nil
+	case CompressionZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0))}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", c)
+	}
+}
+
+// newDecompressReader detects the compression of r by its magic number
+func newDecompressReader(r io.Reader) (io.ReadCloser, Compression, error) {
+	br := bufio.NewReaderSize(r, bufferSize)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("failed to read platform image header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		// Decompression runs ahead of the tar reader in a separate goroutine
+		gzr, err := pgzip.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return gzr, CompressionGzip, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		return zr.IOReadCloser(), CompressionZstd, nil
+	default:
+		return io.NopCloser(br), CompressionNone, nil
+	}
+}
+
+// archiveReader is an opened Platform Image
+type archiveReader struct {
+	*tar.Reader
+	Compression Compression
+
+	file *os.File
+	dec  io.ReadCloser
+}
+
+// openArchive opens a Platform Image for reading whatever its compression is
+func openArchive(imgPath string) (*archiveReader, error) {
+	return openArchiveProgress(imgPath, nil)
+}
+
+// openArchiveProgress opens a Platform Image and reports compressed bytes read to progress
+func openArchiveProgress(imgPath string, progress *Progress) (*archiveReader, error) {
+	file, err := os.Open(imgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open platform image: %w", err)
+	}
+	dec, c, err := newDecompressReader(progress.Reader(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &archiveReader{Reader: tar.NewReader(dec), Compression: c, file: file, dec: dec}, nil
+}
+
+// Close releases the decompressor and the underlying file
+func (a *archiveReader) Close() error {
+	a.dec.Close()
+	return a.file.Close()
+}
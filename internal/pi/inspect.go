@@ -0,0 +1,117 @@
+package pi
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Info summarizes the content of a Platform Image
+type Info struct {
+	Path           string        `yaml:"path" json:"path"`
+	Manifest       *Manifest     `yaml:"manifest,omitempty" json:"manifest,omitempty"`
+	Compression    Compression   `yaml:"compression" json:"compression"`
+	CompressedSize int64         `yaml:"compressed_size" json:"compressed_size"`
+	TotalSize      int64         `yaml:"total_size" json:"total_size"`
+	FileCount      int           `yaml:"file_count" json:"file_count"`
+	Ratio          float64       `yaml:"compression_ratio" json:"compression_ratio"`
+	Layout         []LayoutEntry `yaml:"layout" json:"layout"`
+}
+
+// LayoutEntry is a top-level entry of a Platform Image
+type LayoutEntry struct {
+	Name  string `yaml:"name" json:"name"`
+	Dir   bool   `yaml:"dir" json:"dir"`
+	Files int    `yaml:"files" json:"files"`
+	Size  int64  `yaml:"size" json:"size"`
+}
+
+// Inspect reads Platform Image headers without extracting its content
+func Inspect(ctx context.Context, imgPath string) (*Info, error) {
+	stat, err := os.Stat(imgPath)
+	if err != nil {
+		return nil, fmt.Errorf("platform image not found: %s", imgPath)
+	}
+
+	tr, err := openArchive(imgPath)
+	if err != nil {
+		return nil, err
+	}
+	defer tr.Close()
+
+	info := &Info{Path: imgPath, Compression: tr.Compression, CompressedSize: stat.Size()}
+	layout := make(map[string]*LayoutEntry)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if name == ManifestName {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			var m Manifest
+			if err := yaml.Unmarshal(data, &m); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest: %w", err)
+			}
+			info.Manifest = &m
+			continue
+		}
+
+		top, _, nested := strings.Cut(name, "/")
+		e, ok := layout[top]
+		if !ok {
+			e = &LayoutEntry{Name: top}
+			layout[top] = e
+		}
+		if nested || header.Typeflag == tar.TypeDir {
+			e.Dir = true
+		}
+		if header.Typeflag == tar.TypeReg {
+			info.FileCount++
+			info.TotalSize += header.Size
+			e.Files++
+			e.Size += header.Size
+		}
+	}
+
+	for _, e := range layout {
+		info.Layout = append(info.Layout, *e)
+	}
+	sort.Slice(info.Layout, func(i, j int) bool { return info.Layout[i].Name < info.Layout[j].Name })
+	if info.CompressedSize > 0 {
+		info.Ratio = float64(info.TotalSize) / float64(info.CompressedSize)
+	}
+	return info, nil
+}
+
+// FormatSize renders a byte size in human-readable units
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
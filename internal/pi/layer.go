@@ -0,0 +1,158 @@
+package pi
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// BaseRef identifies the base image a delta layer applies to
+type BaseRef struct {
+	Version string `yaml:"version" json:"version"`
+	Commit  string `yaml:"commit,omitempty" json:"commit,omitempty"`
+	Digest  string `yaml:"digest" json:"digest"`
+}
+
+// match ensures the image at path is the referenced base
+func (b BaseRef) match(path string) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return fmt.Errorf("failed to read base image %s: %w", path, err)
+	}
+	if m.Digest != b.Digest {
+		return fmt.Errorf("base image %s has digest %s, expected %s", path, m.Digest, b.Digest)
+	}
+	return nil
+}
+
+// FindBase looks up the base image of a delta layer in a directory
+func FindBase(dir string, b BaseRef) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+	if err != nil {
+		return "", err
+	}
+	for _, path := range matches {
+		m, err := ReadManifest(path)
+		if err != nil {
+			continue
+		}
+		if m.Digest == b.Digest && (b.Commit == "" || m.Commit == b.Commit) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("base image %s (commit %s) not found in %s", b.Version, b.Commit, dir)
+}
+
+// Layers returns the images applied to extract a Platform Image, the image itself first and then its
+// bases down to the full image. baseImg is the base of a delta image, looked up next to it if empty,
+// the bases of the bases are always looked up.
+func Layers(imgPath, baseImg string) ([]string, error) {
+	layers := []string{imgPath}
+	for {
+		m, err := ReadManifest(imgPath)
+		if err != nil && !errors.Is(err, ErrNoManifest) {
+			return nil, err
+		}
+		if m == nil || m.Base == nil {
+			return layers, nil
+		}
+		if baseImg == "" {
+			if baseImg, err = FindBase(filepath.Dir(imgPath), *m.Base); err != nil {
+				return nil, err
+			}
+		} else if err = m.Base.match(baseImg); err != nil {
+			return nil, err
+		}
+		if slices.Contains(layers, baseImg) {
+			return nil, fmt.Errorf("base image %s of %s is one of its own layers", baseImg, imgPath)
+		}
+		layers = append(layers, baseImg)
+		imgPath, baseImg = baseImg, ""
+	}
+}
+
+// delta keeps only entries changed since the base image and records deleted files
+func (m *Manifest) delta(baseImg string, entries []entry) ([]entry, error) {
+	base, baseIndex, err := indexImage(baseImg)
+	if err != nil {
+		return nil, err
+	}
+	m.Base = &BaseRef{Version: base.Version, Commit: base.Commit, Digest: base.Digest}
+
+	present := make(map[string]struct{}, len(entries))
+	var changed []entry
+	for _, e := range entries {
+		present[e.name] = struct{}{}
+		// Directories are cheap and keep permissions of changed files' parents
+		if e.info.IsDir() || baseIndex[e.name] != e.hash {
+			changed = append(changed, e)
+			if e.hash != "" {
+				m.LayerFiles++
+			}
+		}
+	}
+	for name := range baseIndex {
+		if _, ok := present[name]; !ok {
+			m.Deleted = append(m.Deleted, name)
+		}
+	}
+	sort.Strings(m.Deleted)
+	return changed, nil
+}
+
+// indexImage returns the manifest and content hashes of a full image, reassembling delta bases if needed
+func indexImage(imgPath string) (*Manifest, map[string]string, error) {
+	m, err := ReadManifest(imgPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read base image %s: %w", imgPath, err)
+	}
+
+	index := make(map[string]string)
+	if m.Base != nil {
+		baseImg, err := FindBase(filepath.Dir(imgPath), *m.Base)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, index, err = indexImage(baseImg); err != nil {
+			return nil, nil, err
+		}
+		for _, name := range m.Deleted {
+			delete(index, name)
+		}
+	}
+
+	tr, err := openArchive(imgPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tr.Close()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		switch {
+		case name == ManifestName:
+		case header.Typeflag == tar.TypeReg:
+			h := sha256.New()
+			if _, err := io.Copy(h, tr); err != nil {
+				return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			index[name] = hex.EncodeToString(h.Sum(nil))
+		case header.Typeflag == tar.TypeSymlink:
+			index[name] = "->" + header.Linkname
+		}
+	}
+	return m, index, nil
+}
@@ -0,0 +1,123 @@
+package pi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestName is the manifest file embedded at the root of every Platform Image
+const ManifestName = ".plasma-image.yaml"
+
+// ErrNoManifest is returned when a Platform Image doesn't embed a manifest
+var ErrNoManifest = errors.New("platform image has no manifest")
+
+// Manifest describes how and from what a Platform Image was built
+type Manifest struct {
+	Name      string    `yaml:"name" json:"name"`
+	Version   string    `yaml:"version" json:"version"`
+	Commit    string    `yaml:"commit,omitempty" json:"commit,omitempty"`
+	Tag       string    `yaml:"tag,omitempty" json:"tag,omitempty"`
+	Branch    string    `yaml:"branch,omitempty" json:"branch,omitempty"`
+	BuildTime time.Time `yaml:"build_time" json:"build_time"`
+	Builder   string    `yaml:"builder" json:"builder"`
+	SourceDir string    `yaml:"source_dir" json:"source_dir"`
+	FileCount int       `yaml:"file_count" json:"file_count"`
+	Digest    string    `yaml:"digest" json:"digest"`
+
+	Compression  Compression `yaml:"compression,omitempty" json:"compression,omitempty"`
+	Environment  string      `yaml:"environment,omitempty" json:"environment,omitempty"`
+	NameTemplate string      `yaml:"name_template,omitempty" json:"name_template,omitempty"`
+
+	// Delta layer information, empty for full images
+	Base       *BaseRef `yaml:"base,omitempty" json:"base,omitempty"`
+	LayerFiles int      `yaml:"layer_files,omitempty" json:"layer_files,omitempty"`
+	Deleted    []string `yaml:"deleted,omitempty" json:"deleted,omitempty"`
+}
+
+// ReadManifest reads the manifest of a Platform Image without extracting it
+func ReadManifest(imgPath string) (*Manifest, error) {
+	tr, err := openArchive(imgPath)
+	if err != nil {
+		return nil, err
+	}
+	defer tr.Close()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrNoManifest
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		if filepath.Clean(header.Name) != ManifestName {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		var m Manifest
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		return &m, nil
+	}
+}
+
+// gitInfo fills repository name, commit, tag and branch from the current git repository
+func (m *Manifest) gitInfo() {
+	if out, err := gitOutput("config", "--get", "remote.origin.url"); err == nil && out != "" {
+		parts := strings.Split(out, "/")
+		m.Name = strings.TrimSuffix(parts[len(parts)-1], ".git")
+	} else if wd, err := os.Getwd(); err == nil {
+		m.Name = filepath.Base(wd)
+	}
+	if out, err := gitOutput("rev-parse", "HEAD"); err == nil {
+		m.Commit = out
+	}
+	if out, err := gitOutput("describe", "--tags", "--exact-match", "HEAD"); err == nil {
+		m.Tag = out
+	}
+	// Detached HEAD, e.g. in CI, has no branch
+	if out, err := gitOutput("rev-parse", "--abbrev-ref", "HEAD"); err == nil && out != "HEAD" {
+		m.Branch = out
+	}
+	if out, err := gitOutput("describe", "--tags", "--always"); err == nil {
+		m.Version = out
+	}
+	if m.Version == "" {
+		m.Version = "dev"
+	}
+}
+
+// builder returns user@host of the current build environment
+func builder() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + host
+}
+
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
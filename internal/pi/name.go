@@ -0,0 +1,86 @@
+package pi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultNameTemplate is the file name of an image, without extension
+const DefaultNameTemplate = "{name}-{version}"
+
+// NameTemplateConfigKey is the launchr config key of the repository image name template
+const NameTemplateConfigKey = "platform.image.name_template"
+
+// timestampLayout is used for the {timestamp} field, sortable and safe in file names
+const timestampLayout = "20060102T150405Z"
+
+// nameFields lists placeholders supported in image name templates
+var nameFields = map[string]func(m *Manifest) string{
+	"name":        func(m *Manifest) string { return m.Name },
+	"version":     func(m *Manifest) string { return m.Version },
+	"tag":         func(m *Manifest) string { return m.Tag },
+	"branch":      func(m *Manifest) string { return m.Branch },
+	"environment": func(m *Manifest) string { return m.Environment },
+	"commit":      func(m *Manifest) string { return m.Commit },
+	"short_sha": func(m *Manifest) string {
+		if len(m.Commit) > 8 {
+			return m.Commit[:8]
+		}
+		return m.Commit
+	},
+	"timestamp": func(m *Manifest) string { return m.BuildTime.UTC().Format(timestampLayout) },
+}
+
+var (
+	namePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+	// unsafeNameChars are replaced in rendered field values, e.g. slashes of feature branches
+	unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._+-]`)
+)
+
+// NameFields returns the placeholders supported in image name templates
+func NameFields() []string {
+	fields := make([]string, 0, len(nameFields))
+	for f := range nameFields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ValidateNameTemplate checks that an image name template only uses known placeholders
+// and can't render to a path outside of the output directory.
+func ValidateNameTemplate(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+	if strings.ContainsAny(tmpl, `/\`) {
+		return fmt.Errorf("image name template %q must not contain path separators", tmpl)
+	}
+	for _, match := range namePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := nameFields[match[1]]; !ok {
+			return fmt.Errorf("unknown field {%s} in image name template %q, supported: {%s}",
+				match[1], tmpl, strings.Join(NameFields(), "}, {"))
+		}
+	}
+	if rest := namePlaceholder.ReplaceAllString(tmpl, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unbalanced braces in image name template %q", tmpl)
+	}
+	return nil
+}
+
+// fileName renders the image name template, tmpl must be validated
+func (m *Manifest) fileName(tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultNameTemplate
+	}
+	name := namePlaceholder.ReplaceAllStringFunc(tmpl, func(p string) string {
+		value := nameFields[p[1:len(p)-1]](m)
+		return unsafeNameChars.ReplaceAllString(value, "-")
+	})
+	if strings.Trim(name, ".-") == "" {
+		return "", fmt.Errorf("image name template %q renders to an empty name", tmpl)
+	}
+	return name, nil
+}
@@ -0,0 +1,470 @@
+// Package pi builds, reads and extracts Platform Images (.pi files)
+package pi
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ext is the Platform Image file extension
+const Ext = ".pi"
+
+// CreateOptions holds options for building a Platform Image
+type CreateOptions struct {
+	SourceDir string // Directory to package, e.g. the prepare directory
+	OutputDir string // Directory where the image is written
+	Name      string // Image name, defaults to the repository name
+	Version   string // Image version, defaults to git describe
+	Base      string // Base image path, when set only the delta since the base is packaged
+
+	NameTemplate string // Image file name template, defaults to DefaultNameTemplate
+	Environment  string // Target environment, available as {environment} in the name template
+
+	Compression Compression // Archive compression, defaults to gzip
+	Level       int         // Compression level, 0 selects the algorithm default
+	Progress    *Progress   // Progress reporter, nil disables reporting
+}
+
+// entry is a file collected from the source directory
+type entry struct {
+	path string // Absolute path on disk
+	name string // Slash separated path inside the archive
+	info os.FileInfo
+	link string
+	hash string // Content hash of regular files and link target of symlinks
+}
+
+// Create builds a Platform Image from the source directory and returns its path. Canceling the
+// context stops the build between two files, without leaving a partial image.
+func Create(ctx context.Context, opts CreateOptions) (string, *Manifest, error) {
+	if _, err := os.Stat(opts.SourceDir); err != nil {
+		return "", nil, fmt.Errorf("source directory %s is not accessible: %w", opts.SourceDir, err)
+	}
+	if opts.Compression == "" {
+		opts.Compression = DefaultCompression
+	}
+	if err := ValidateNameTemplate(opts.NameTemplate); err != nil {
+		return "", nil, err
+	}
+	if err := validateLevel(opts.Compression, opts.Level); err != nil {
+		return "", nil, err
+	}
+
+	m := &Manifest{}
+	m.gitInfo()
+	if opts.Name != "" {
+		m.Name = opts.Name
+	}
+	if opts.Version != "" {
+		m.Version = opts.Version
+	}
+	m.BuildTime = time.Now().UTC()
+	m.Builder = builder()
+	m.SourceDir = opts.SourceDir
+	m.Compression = opts.Compression
+	m.Environment = opts.Environment
+	m.NameTemplate = opts.NameTemplate
+	if m.NameTemplate == "" {
+		m.NameTemplate = DefaultNameTemplate
+	}
+
+	entries, err := collect(opts.SourceDir)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := m.digest(ctx, entries, opts.Progress); err != nil {
+		return "", nil, err
+	}
+	if opts.Base != "" {
+		if entries, err = m.delta(opts.Base, entries); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	name, err := m.fileName(m.NameTemplate)
+	if err != nil {
+		return "", nil, err
+	}
+	if m.Base != nil {
+		name += "-delta"
+	}
+	imgPath := filepath.Join(opts.OutputDir, name+Ext)
+	if err := createArchive(ctx, imgPath, m, entries, opts.Level, opts.Progress); err != nil {
+		return "", nil, err
+	}
+
+	return imgPath, m, nil
+}
+
+// collect walks the source directory in lexical order
+func collect(srcDir string) ([]entry, error) {
+	var entries []entry
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || rel == ManifestName {
+			return nil
+		}
+		e := entry{path: path, name: filepath.ToSlash(rel), info: info}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if e.link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk source directory: %w", err)
+	}
+	return entries, nil
+}
+
+// totals returns the size and count of regular files among entries
+func totals(entries []entry) (int64, int) {
+	var size int64
+	var files int
+	for _, e := range entries {
+		if e.info.Mode().IsRegular() {
+			size += e.info.Size()
+			files++
+		}
+	}
+	return size, files
+}
+
+// digest computes the content digest and file count of the collected entries
+func (m *Manifest) digest(ctx context.Context, entries []entry, progress *Progress) error {
+	size, files := totals(entries)
+	progress.start("Hashing", size, files)
+	defer progress.Finish()
+
+	h := sha256.New()
+	for i := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e := &entries[i]
+		switch {
+		case e.info.Mode().IsRegular():
+			fh := sha256.New()
+			f, err := os.Open(e.path)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", e.path, err)
+			}
+			_, err = io.Copy(fh, progress.Reader(f))
+			f.Close()
+			progress.file()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", e.path, err)
+			}
+			e.hash = hex.EncodeToString(fh.Sum(nil))
+			fmt.Fprintf(h, "%s\x00%s\n", e.name, e.hash)
+			m.FileCount++
+		case e.link != "":
+			e.hash = "->" + e.link
+			fmt.Fprintf(h, "%s\x00%s\n", e.name, e.hash)
+		}
+	}
+	m.Digest = "sha256:" + hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// createArchive writes the manifest and entries to a tar archive compressed as recorded in the manifest
+// The archive is built next to its destination and atomically renamed once complete,
+// so a partially written image is never left at imgPath.
+func createArchive(ctx context.Context, imgPath string, m *Manifest, entries []entry, level int, progress *Progress) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(imgPath), ".platform-image-*"+Ext)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary archive: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+		}
+	}()
+
+	bw := bufio.NewWriterSize(tmpFile, bufferSize)
+	cw, err := newCompressWriter(bw, m.Compression, level)
+	if err != nil {
+		return fmt.Errorf("failed to create %s writer: %w", m.Compression, err)
+	}
+	tw := tar.NewWriter(cw)
+
+	// Manifest goes first so it can be read without scanning the whole archive
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: m.BuildTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write manifest header: %w", err)
+	}
+	if _, err = tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	size, files := totals(entries)
+	progress.start("Packaging", size, files)
+	buf := make([]byte, bufferSize)
+	for _, e := range entries {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = writeEntry(tw, e, buf, progress); err != nil {
+			return err
+		}
+	}
+	progress.Finish()
+
+	if err = tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize tar: %w", err)
+	}
+	if err = cw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize %s: %w", m.Compression, err)
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write platform image: %w", err)
+	}
+	if err = tmpFile.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to set platform image permissions: %w", err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write platform image: %w", err)
+	}
+	if err = os.Rename(tmpFile.Name(), imgPath); err != nil {
+		return fmt.Errorf("failed to move platform image into place: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, e entry, buf []byte, progress *Progress) error {
+	header, err := tar.FileInfoHeader(e.info, e.link)
+	if err != nil {
+		return fmt.Errorf("failed to create header for %s: %w", e.name, err)
+	}
+	header.Name = e.name
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", e.name, err)
+	}
+	if !e.info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", e.path, err)
+	}
+	defer f.Close()
+	if _, err := io.CopyBuffer(tw, progress.Reader(f), buf); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", e.path, err)
+	}
+	progress.file()
+	return nil
+}
+
+// ExtractLayers unpacks a Platform Image into the destination directory
+// Delta layers are reassembled on top of their base image, baseImg may be empty
+// to look the base up next to the image by its git SHA and digest.
+func ExtractLayers(ctx context.Context, imgPath, destDir, baseImg string, progress *Progress) error {
+	layers, err := Layers(imgPath, baseImg)
+	if err != nil {
+		return err
+	}
+	return ApplyLayers(ctx, layers, destDir, progress)
+}
+
+// ApplyLayers unpacks the layers of a Platform Image returned by [Layers] into the destination directory
+func ApplyLayers(ctx context.Context, layers []string, destDir string, progress *Progress) error {
+	// The full image first, then each delta over its base
+	for i := len(layers) - 1; i >= 0; i-- {
+		if i < len(layers)-1 {
+			m, err := ReadManifest(layers[i])
+			if err != nil {
+				return err
+			}
+			for _, name := range m.Deleted {
+				target, err := destPath(destDir, filepath.FromSlash(name))
+				if err != nil {
+					return err
+				}
+				if err := os.RemoveAll(target); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", name, err)
+				}
+			}
+		}
+		if err := Extract(ctx, layers[i], destDir, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract unpacks a Platform Image into the destination directory
+// Progress is measured on compressed bytes read, which total is known upfront. Canceling the context
+// stops the extraction between two files.
+func Extract(ctx context.Context, imgPath, destDir string, progress *Progress) error {
+	if progress != nil {
+		var files int
+		if m, err := ReadManifest(imgPath); err == nil {
+			files = m.FileCount
+			if m.Base != nil {
+				files = m.LayerFiles
+			}
+		}
+		stat, err := os.Stat(imgPath)
+		if err != nil {
+			return fmt.Errorf("platform image not found: %s", imgPath)
+		}
+		progress.start("Extracting "+filepath.Base(imgPath), stat.Size(), files)
+		defer progress.Finish()
+	}
+
+	tr, err := openArchiveProgress(imgPath, progress)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	buf := make([]byte, bufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		target, err := destPath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := notSymlink(target, header.Name); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to create parent directory: %w", err)
+			}
+			// A file replacing a link of a base layer is written in place of the link, not through it
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(target); err != nil {
+					return fmt.Errorf("failed to replace symlink: %w", err)
+				}
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return fmt.Errorf("failed to create file: %w", err)
+			}
+			if _, err := io.CopyBuffer(f, tr, buf); err != nil {
+				f.Close()
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			if header.Name != ManifestName {
+				progress.file()
+			}
+		case tar.TypeSymlink:
+			if !linkInside(destDir, target, header.Linkname) {
+				return fmt.Errorf("illegal symlink in platform image: %s -> %s", header.Name, header.Linkname)
+			}
+			// Replace links coming from a base layer
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to replace symlink: %w", err)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("failed to create symlink: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// destPath returns the path of an archive entry in the destination directory. The entry must stay
+// inside the directory and its parent directories must not be symlinks, which could point outside it.
+func destPath(destDir, name string) (string, error) {
+	destDir = filepath.Clean(destDir)
+	target := filepath.Join(destDir, name)
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil || rel == "." || outside(rel) {
+		return "", fmt.Errorf("illegal path in platform image: %s", name)
+	}
+
+	dir := destDir
+	parts := strings.Split(rel, string(os.PathSeparator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check %s: %w", dir, err)
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("illegal path in platform image: %s is written through the symlink %s", name, dir)
+		}
+	}
+	return target, nil
+}
+
+// notSymlink fails if the path of an archive entry is an existing symlink
+func notSymlink(target, name string) error {
+	if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("illegal path in platform image: %s is a symlink", name)
+	}
+	return nil
+}
+
+// linkInside reports whether a symlink created at target resolves inside the destination directory,
+// absolute link targets are refused
+func linkInside(destDir, target, linkname string) bool {
+	if linkname == "" || filepath.IsAbs(linkname) {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(destDir), filepath.Join(filepath.Dir(target), linkname))
+	return err == nil && !outside(rel)
+}
+
+// outside reports whether a path relative to a directory leaves it
+func outside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
@@ -0,0 +1,98 @@
+package pi
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTar writes an uncompressed Platform Image of the given entries
+func writeTar(t *testing.T, headers []*tar.Header) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "img"+Ext)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, h := range headers {
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(h.Name))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(h.Name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractRefusesEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+		wantErr string
+	}{
+		{
+			name:    "parent path",
+			headers: []*tar.Header{{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644}},
+			wantErr: "illegal path",
+		},
+		{
+			name:    "absolute symlink",
+			headers: []*tar.Header{{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}},
+			wantErr: "illegal symlink",
+		},
+		{
+			name:    "parent symlink",
+			headers: []*tar.Header{{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../../outside"}},
+			wantErr: "illegal symlink",
+		},
+		{
+			name: "file through symlink",
+			headers: []*tar.Header{
+				{Name: "data", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "data"},
+				{Name: "link/file", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+			wantErr: "through the symlink",
+		},
+		{
+			name: "inner symlink",
+			headers: []*tar.Header{
+				{Name: "data/file", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "dir", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../data/file"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			if err := os.Mkdir(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			err := Extract(context.Background(), writeTar(t, tt.headers), dest, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Extract() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Extract() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
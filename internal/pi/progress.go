@@ -0,0 +1,180 @@
+package pi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Progress refresh intervals, plain output is used when stdout is not a terminal, e.g. in CI logs
+const (
+	progressInterval      = 200 * time.Millisecond
+	progressPlainInterval = 10 * time.Second
+	progressBarWidth      = 30
+)
+
+// Progress reports byte and file progress of image creation and extraction, and byte progress of
+// uploads. A nil Progress is valid and reports nothing.
+type Progress struct {
+	mx  sync.Mutex
+	w   io.Writer
+	tty bool
+
+	label      string
+	total      int64
+	done       int64
+	files      int
+	totalFiles int
+	started    time.Time
+	rendered   time.Time
+}
+
+// NewProgress creates a progress reporter writing to w
+func NewProgress(w io.Writer) *Progress {
+	return &Progress{w: w, tty: term.IsTerminal(int(os.Stdout.Fd()))}
+}
+
+// StartBytes begins a new phase reporting bytes only, e.g. the upload of an artifact
+func (p *Progress) StartBytes(label string, total int64) {
+	p.start(label, total, -1)
+}
+
+// Rewind moves the progress of the current phase back to done bytes, e.g. to retry a failed upload
+func (p *Progress) Rewind(done int64) {
+	if p == nil {
+		return
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.done = done
+}
+
+// start begins a new phase with precomputed totals, totalFiles may be 0 when unknown or negative to
+// report bytes only
+func (p *Progress) start(label string, total int64, totalFiles int) {
+	if p == nil {
+		return
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.label = label
+	p.total = total
+	p.totalFiles = totalFiles
+	p.done = 0
+	p.files = 0
+	p.started = time.Now()
+	p.rendered = time.Time{}
+}
+
+// add records processed bytes
+func (p *Progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.done += n
+	p.render(false)
+}
+
+// file records a processed file
+func (p *Progress) file() {
+	if p == nil {
+		return
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.files++
+	p.render(false)
+}
+
+// Finish renders the final state of the current phase
+func (p *Progress) Finish() {
+	if p == nil {
+		return
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	if p.done < p.total {
+		p.done = p.total
+	}
+	p.render(true)
+	if p.tty {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// render prints the progress line, throttled unless forced
+func (p *Progress) render(force bool) {
+	interval := progressInterval
+	if !p.tty {
+		interval = progressPlainInterval
+	}
+	now := time.Now()
+	if !force && now.Sub(p.rendered) < interval {
+		return
+	}
+	p.rendered = now
+
+	ratio := 0.0
+	if p.total > 0 {
+		ratio = float64(p.done) / float64(p.total)
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	line := fmt.Sprintf("%s %3.0f%% %s/%s", p.label, ratio*100, FormatSize(p.done), FormatSize(p.total))
+	switch {
+	case p.totalFiles > 0:
+		line += fmt.Sprintf(", %d/%d files", p.files, p.totalFiles)
+	case p.totalFiles == 0:
+		line += fmt.Sprintf(", %d files", p.files)
+	}
+	if eta := p.eta(now, ratio); eta != "" && !force {
+		line += ", ETA " + eta
+	}
+
+	if !p.tty {
+		fmt.Fprintln(p.w, line)
+		return
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(p.w, "\r\033[K[%s] %s", bar, line)
+}
+
+// eta estimates the remaining time from the average throughput so far
+func (p *Progress) eta(now time.Time, ratio float64) string {
+	elapsed := now.Sub(p.started)
+	if ratio <= 0 || ratio >= 1 || elapsed < time.Second {
+		return ""
+	}
+	remaining := time.Duration(float64(elapsed) * (1 - ratio) / ratio)
+	return remaining.Round(time.Second).String()
+}
+
+// Reader counts bytes read from r in the current phase
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+// Read implements io.Reader interface
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(int64(n))
+	return n, err
+}
@@ -0,0 +1,148 @@
+package pi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Local image locations
+const (
+	// ImageDir is where Platform Images are built
+	ImageDir = "img"
+	// CacheDir is where pulled Platform Images are cached
+	CacheDir = ".plasmactl/cache/img"
+)
+
+// Image sources
+const (
+	SourceLocal = "local"
+	SourceCache = "cache"
+)
+
+// LocalImage is a Platform Image found on disk
+type LocalImage struct {
+	Path     string    `yaml:"path" json:"path"`
+	Source   string    `yaml:"source" json:"source"`
+	Name     string    `yaml:"name" json:"name"`
+	Version  string    `yaml:"version" json:"version"`
+	Commit   string    `yaml:"commit,omitempty" json:"commit,omitempty"`
+	Digest   string    `yaml:"digest,omitempty" json:"digest,omitempty"`
+	Size     int64     `yaml:"size" json:"size"`
+	Created  time.Time `yaml:"created" json:"created"`
+	Current  bool      `yaml:"current" json:"current"`
+	Delta    bool      `yaml:"delta,omitempty" json:"delta,omitempty"`
+	Base     *BaseRef  `yaml:"base,omitempty" json:"base,omitempty"`
+	Manifest bool      `yaml:"manifest" json:"manifest"`
+}
+
+// ListImages returns Platform Images of the build and cache directories, newest first
+func ListImages() ([]LocalImage, error) {
+	head, _ := gitOutput("rev-parse", "HEAD")
+
+	var images []LocalImage
+	for _, src := range []struct{ dir, source string }{{ImageDir, SourceLocal}, {CacheDir, SourceCache}} {
+		found, err := scanImages(src.dir, src.source, head)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, found...)
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		return images[i].Created.After(images[j].Created)
+	})
+	return images, nil
+}
+
+// scanImages reads Platform Images of a single directory
+func scanImages(dir, source, head string) ([]LocalImage, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s directory: %w", dir, err)
+	}
+
+	var images []LocalImage
+	for _, e := range entries {
+		// Hidden files are images being written
+		if e.IsDir() || filepath.Ext(e.Name()) != Ext || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		img := LocalImage{
+			Path:    filepath.Join(dir, e.Name()),
+			Source:  source,
+			Name:    strings.TrimSuffix(e.Name(), Ext),
+			Size:    info.Size(),
+			Created: info.ModTime(),
+		}
+		// Images built before manifests were embedded are listed from file info only
+		if m, err := ReadManifest(img.Path); err == nil {
+			img.Manifest = true
+			img.Name = m.Name
+			img.Version = m.Version
+			img.Commit = m.Commit
+			img.Digest = m.Digest
+			img.Created = m.BuildTime
+			img.Base = m.Base
+			img.Delta = m.Base != nil
+			img.Current = head != "" && m.Commit == head
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// PruneCandidates selects images to remove, keeping the newest keep images of each name and source.
+// Bases of kept delta images are always kept so they can still be reassembled.
+func PruneCandidates(images []LocalImage, keep int) []LocalImage {
+	sorted := make([]LocalImage, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Created.After(sorted[j].Created)
+	})
+
+	kept := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, img := range sorted {
+		group := img.Source + "\x00" + img.Name
+		if counts[group] < keep {
+			kept[img.Path] = true
+			counts[group]++
+		}
+	}
+
+	// Walk delta chains of kept images down to their full base
+	for changed := true; changed; {
+		changed = false
+		for _, img := range sorted {
+			if !kept[img.Path] || img.Base == nil {
+				continue
+			}
+			for _, base := range sorted {
+				if !kept[base.Path] && base.Digest == img.Base.Digest && (img.Base.Commit == "" || base.Commit == img.Base.Commit) {
+					kept[base.Path] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	var prune []LocalImage
+	for _, img := range sorted {
+		if !kept[img.Path] {
+			prune = append(prune, img)
+		}
+	}
+	return prune
+}
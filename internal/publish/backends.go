@@ -0,0 +1,435 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+)
+
+// httpBackend uploads artifacts with HTTP PUT using basic or token auth from the keyring, in a single
+// request or in Content-Range chunks
+type httpBackend struct {
+	cfg      Config
+	k        keyring.Keyring
+	log      *launchr.Logger
+	scope    api.Scope // Scope of the credentials of the keyring
+	creds    string    // Source of the credentials
+	progress *pi.Progress
+}
+
+// probeTimeout bounds the reachability probe of each repository URL
+const probeTimeout = 5 * time.Second
+
+// progressMinSize is the size of the smallest uploads showing their progress, not the checksum files
+const progressMinSize = 1 << 20
+
+// Backoff between the retries of a failed upload request, doubled after each attempt
+const (
+	retryBackoff    = 2 * time.Second
+	retryMaxBackoff = time.Minute
+)
+
+// errRangeUnsupported is returned when the repository refuses the first Content-Range chunk
+var errRangeUnsupported = errors.New("repository doesn't support Content-Range uploads")
+
+// upload is an upload of an artifact to the http backend
+type upload struct {
+	f        *os.File
+	target   string
+	meta     Metadata
+	auth     authorization
+	client   *http.Client
+	progress *pi.Progress
+}
+
+// Upload implements Backend interface
+func (b *httpBackend) Upload(ctx context.Context, filePath string, meta Metadata) (string, error) {
+	timeout, err := b.cfg.timeout()
+	if err != nil {
+		return "", err
+	}
+	repoURL, err := b.repository(ctx)
+	if err != nil {
+		return "", err
+	}
+	auth, err := b.authorization(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer f.Close()
+
+	u := &upload{
+		f:      f,
+		target: strings.TrimSuffix(repoURL, "/") + "/" + meta.Path,
+		meta:   meta,
+		auth:   auth,
+		client: &http.Client{Timeout: timeout},
+	}
+	if meta.Size >= progressMinSize {
+		u.progress = b.progress
+	}
+	u.progress.StartBytes("Uploading", meta.Size)
+	defer u.progress.Finish()
+
+	chunk := int64(b.cfg.ChunkSize) << 20
+	if chunk > 0 && meta.Size > chunk {
+		err = b.uploadChunks(ctx, u, chunk)
+		if err == nil {
+			return u.target, nil
+		}
+		if !errors.Is(err, errRangeUnsupported) {
+			return "", err
+		}
+		b.log.Warn("repository refused the chunked upload, uploading the whole artifact", "url", repoURL)
+	}
+	if err = b.put(ctx, u, 0, meta.Size, false); err != nil {
+		return "", err
+	}
+	return u.target, nil
+}
+
+// uploadChunks uploads the artifact in chunks, a failed chunk is retried without sending the previous
+// ones again
+func (b *httpBackend) uploadChunks(ctx context.Context, u *upload, chunk int64) error {
+	for offset := int64(0); offset < u.meta.Size; offset += chunk {
+		if err := b.put(ctx, u, offset, min(chunk, u.meta.Size-offset), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put uploads a part of the artifact, retrying network errors, rate limiting and server errors with
+// exponential backoff. ranged sends the part as a Content-Range chunk.
+func (b *httpBackend) put(ctx context.Context, u *upload, offset, length int64, ranged bool) error {
+	retries := b.cfg.retries()
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		u.progress.Rewind(offset)
+		status, err := b.send(ctx, u, offset, length, ranged)
+		if err == nil {
+			return nil
+		}
+		if ranged && offset == 0 && (status == http.StatusBadRequest || status == http.StatusNotImplemented || status == http.StatusRequestedRangeNotSatisfiable) {
+			return fmt.Errorf("%w: %w", errRangeUnsupported, err)
+		}
+		if (status != 0 && status != http.StatusTooManyRequests && status < 500) || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		b.log.Warn("artifact upload failed, retrying", "url", u.target, "offset", offset, "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retryMaxBackoff)
+	}
+}
+
+// send sends a single PUT of a part of the artifact and returns the response status, zero if the
+// request failed
+func (b *httpBackend) send(ctx context.Context, u *upload, offset, length int64, ranged bool) (int, error) {
+	body := u.progress.Reader(io.NewSectionReader(u.f, offset, length))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.target, body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Type", u.meta.contentType())
+	req.Header.Set("X-Checksum-Sha256", u.meta.SHA256)
+	if ranged {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, u.meta.Size))
+	}
+	u.auth.set(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("repository returned status %s: %s", resp.Status, string(respBody))
+	}
+	return resp.StatusCode, nil
+}
+
+// Download implements Backend interface, from the repository reachable now
+func (b *httpBackend) Download(ctx context.Context, meta Metadata, w io.Writer) error {
+	timeout, err := b.cfg.timeout()
+	if err != nil {
+		return err
+	}
+	repoURL, err := b.repository(ctx)
+	if err != nil {
+		return err
+	}
+	auth, err := b.authorization(repoURL)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(repoURL, "/")+"/"+meta.Path, nil)
+	if err != nil {
+		return err
+	}
+	auth.set(req)
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("repository returned status %s", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// authorization authenticates the requests to a repository, anonymous if header is empty
+type authorization struct {
+	header string // Header carrying the credentials, e.g. Authorization
+	value  string
+}
+
+// set sets the authorization header of a request
+func (a authorization) set(req *http.Request) {
+	if a.header != "" {
+		req.Header.Set(a.header, a.value)
+	}
+}
+
+// authorization returns the authorization of the repository from the auth mode and the source of the
+// credentials
+func (b *httpBackend) authorization(repoURL string) (authorization, error) {
+	if b.creds == CredentialsNone {
+		return authorization{}, nil
+	}
+	if b.cfg.TokenAuth() {
+		token, err := b.token()
+		if err != nil {
+			return authorization{}, err
+		}
+		redact.Add(token)
+		if b.cfg.Auth == AuthHeader {
+			return authorization{header: b.cfg.TokenHeader, value: token}, nil
+		}
+		return authorization{header: "Authorization", value: "Bearer " + token}, nil
+	}
+	creds, err := b.credentials(repoURL)
+	if err != nil || creds == nil {
+		return authorization{}, err
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(creds.Username + ":" + creds.Password))
+	return authorization{header: "Authorization", value: "Basic " + basic}, nil
+}
+
+// token returns the token of the bearer and header auth from the source of the credentials
+func (b *httpBackend) token() (string, error) {
+	if b.creds == CredentialsEnv {
+		token := os.Getenv(TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("%s must be set to publish with the token of the environment", TokenEnv)
+		}
+		return token, nil
+	}
+	token, err := b.scope.Lookup(b.k, b.log, b.cfg.TokenKey)
+	if errors.Is(err, api.ErrMissingItem) {
+		return "", fmt.Errorf("%w, add the token of the artifact repository with plasmactl keyring:set %s <token>", err, b.scope.Key(b.cfg.TokenKey))
+	}
+	return token, err
+}
+
+// credentials returns the basic auth of the repository from the source of the credentials, nil to
+// upload anonymously
+func (b *httpBackend) credentials(repoURL string) (*keyring.CredentialsItem, error) {
+	switch b.creds {
+	case CredentialsNone:
+		return nil, nil
+	case CredentialsEnv:
+		username, password := os.Getenv(UsernameEnv), os.Getenv(PasswordEnv)
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("%s and %s must be set to publish with the credentials of the environment", UsernameEnv, PasswordEnv)
+		}
+		redact.Add(password)
+		return &keyring.CredentialsItem{URL: repoURL, Username: username, Password: password}, nil
+	}
+	if b.k == nil {
+		return nil, nil
+	}
+	creds, err := b.scope.Credentials(b.k, b.log, repoURL)
+	switch {
+	case err == nil:
+		return &creds, nil
+	case errors.Is(err, keyring.ErrNotFound):
+		b.log.Debug("no credentials in keyring, uploading anonymously", "url", repoURL)
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// repository returns the repository URL, or the first reachable fallback if it is unreachable. Any
+// HTTP response makes a repository reachable, the upload reports the refused ones.
+func (b *httpBackend) repository(ctx context.Context) (string, error) {
+	urls := b.cfg.URLs()
+	if len(urls) == 1 {
+		return urls[0], nil
+	}
+	client := &http.Client{Timeout: probeTimeout}
+	var errs []error
+	for _, u := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if u != b.cfg.URL {
+				b.log.Warn("artifact repository unreachable, publishing to a fallback", "url", b.cfg.URL, "fallback", u)
+			}
+			return u, nil
+		}
+		b.log.Debug("artifact repository unreachable", "url", u, "error", err)
+		errs = append(errs, err)
+	}
+	return "", fmt.Errorf("no artifact repository reachable: %w", errors.Join(errs...))
+}
+
+// s3Backend uploads artifacts with the aws CLI, which switches to multipart upload for large files
+type s3Backend struct {
+	cfg Config
+}
+
+// Upload implements Backend interface
+func (b *s3Backend) Upload(ctx context.Context, filePath string, meta Metadata) (string, error) {
+	if b.cfg.Bucket == "" {
+		return "", fmt.Errorf("bucket is not configured for %s backend", BackendS3)
+	}
+	target := b.target(meta)
+
+	args := []string{"s3", "cp", filePath, target,
+		"--metadata", "sha256=" + meta.SHA256,
+		"--content-type", meta.contentType(),
+		"--checksum-algorithm", "SHA256",
+		"--only-show-errors",
+	}
+	if b.cfg.Region != "" {
+		args = append(args, "--region", b.cfg.Region)
+	}
+	if b.cfg.Encryption != "" {
+		args = append(args, "--sse", b.cfg.Encryption)
+		if b.cfg.KMSKey != "" {
+			args = append(args, "--sse-kms-key-id", b.cfg.KMSKey)
+		}
+	}
+
+	// The aws CLI retries the failed parts of multipart uploads
+	if err := runCLI(ctx, b.env(), nil, "aws", args...); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// Download implements Backend interface
+func (b *s3Backend) Download(ctx context.Context, meta Metadata, w io.Writer) error {
+	args := []string{"s3", "cp", b.target(meta), "-", "--only-show-errors"}
+	if b.cfg.Region != "" {
+		args = append(args, "--region", b.cfg.Region)
+	}
+	return runCLI(ctx, b.env(), w, "aws", args...)
+}
+
+// target returns the S3 URI of an artifact
+func (b *s3Backend) target(meta Metadata) string {
+	return fmt.Sprintf("s3://%s/%s", b.cfg.Bucket, path.Join(b.cfg.Prefix, meta.Path))
+}
+
+// env returns the retry policy of the aws CLI
+func (b *s3Backend) env() []string {
+	return []string{"AWS_RETRY_MODE=standard", fmt.Sprintf("AWS_MAX_ATTEMPTS=%d", b.cfg.retries()+1)}
+}
+
+// gcsBackend uploads artifacts with the gcloud CLI, which uses parallel composite uploads for large files
+type gcsBackend struct {
+	cfg Config
+}
+
+// Upload implements Backend interface
+func (b *gcsBackend) Upload(ctx context.Context, filePath string, meta Metadata) (string, error) {
+	if b.cfg.Bucket == "" {
+		return "", fmt.Errorf("bucket is not configured for %s backend", BackendGCS)
+	}
+	target := b.target(meta)
+
+	args := []string{"storage", "cp", filePath, target,
+		"--custom-metadata", "sha256=" + meta.SHA256,
+		"--content-type", meta.contentType(),
+	}
+	if b.cfg.KMSKey != "" {
+		args = append(args, "--encryption-key", b.cfg.KMSKey)
+	}
+
+	if err := runCLI(ctx, b.env(), nil, "gcloud", args...); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// Download implements Backend interface
+func (b *gcsBackend) Download(ctx context.Context, meta Metadata, w io.Writer) error {
+	return runCLI(ctx, b.env(), w, "gcloud", "storage", "cat", b.target(meta))
+}
+
+// target returns the GCS URI of an artifact
+func (b *gcsBackend) target(meta Metadata) string {
+	return fmt.Sprintf("gs://%s/%s", b.cfg.Bucket, path.Join(b.cfg.Prefix, meta.Path))
+}
+
+// env returns the retry policy of the gcloud CLI
+func (b *gcsBackend) env() []string {
+	return []string{fmt.Sprintf("CLOUDSDK_STORAGE_MAX_RETRIES=%d", b.cfg.retries())}
+}
+
+// runCLI runs a storage CLI with additional environment variables and returns its output on failure.
+// The standard output is written to stdout if not nil, e.g. a downloaded artifact.
+func runCLI(ctx context.Context, env []string, stdout io.Writer, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s CLI is not installed: %w", name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
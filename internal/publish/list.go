@@ -0,0 +1,203 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/api"
+)
+
+// nexusRepoURL matches the URL of a Nexus repository, e.g. https://nexus.example.com/repository/platform-images,
+// capturing the base URL of the Nexus API, the repository and the directory in the repository
+var nexusRepoURL = regexp.MustCompile(`^(https?://.+?)/repository/([^/]+)/?(.*)$`)
+
+// nexusAssets is a page of the assets search of the Nexus API
+type nexusAssets struct {
+	Items []struct {
+		ID           string    `json:"id"`
+		Path         string    `json:"path"`
+		LastModified time.Time `json:"lastModified"`
+		FileSize     int64     `json:"fileSize"`
+	} `json:"items"`
+	ContinuationToken string `json:"continuationToken"`
+}
+
+// nexus returns the base URL of the Nexus API, the repository and its directory of the repository
+// URL. Listing and deleting artifacts is only supported by Nexus repositories, plain HTTP has no
+// listing.
+func (b *httpBackend) nexus() (string, string, string, error) {
+	m := nexusRepoURL.FindStringSubmatch(b.cfg.URL)
+	if m == nil {
+		return "", "", "", fmt.Errorf("listing artifacts of the %s backend needs a Nexus repository URL like https://<host>/repository/<name>, got %s", BackendHTTP, b.cfg.URL)
+	}
+	dir := strings.Trim(m[3], "/")
+	if dir != "" {
+		dir += "/"
+	}
+	return m[1], m[2], dir, nil
+}
+
+// header returns the authorization header of the Nexus API
+func (b *httpBackend) header() (http.Header, error) {
+	auth, err := b.authorization(b.cfg.URL)
+	if err != nil || auth.header == "" {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set(auth.header, auth.value)
+	return header, nil
+}
+
+// List implements Backend interface with the assets search of the Nexus API
+func (b *httpBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	base, repo, dir, err := b.nexus()
+	if err != nil {
+		return nil, err
+	}
+	header, err := b.header()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []Object
+	token := ""
+	for {
+		q := url.Values{"repository": {repo}}
+		if token != "" {
+			q.Set("continuationToken", token)
+		}
+		var page nexusAssets
+		if err = api.Request(ctx, http.MethodGet, base+"/service/rest/v1/search/assets?"+q.Encode(), header, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, it := range page.Items {
+			rel, ok := strings.CutPrefix(strings.TrimPrefix(it.Path, "/"), dir)
+			if ok && strings.HasPrefix(rel, prefix) {
+				objects = append(objects, Object{Path: rel, Size: it.FileSize, Modified: it.LastModified, id: it.ID})
+			}
+		}
+		if token = page.ContinuationToken; token == "" {
+			return objects, nil
+		}
+	}
+}
+
+// Delete implements Backend interface with the Nexus API
+func (b *httpBackend) Delete(ctx context.Context, o Object) error {
+	base, _, _, err := b.nexus()
+	if err != nil {
+		return err
+	}
+	header, err := b.header()
+	if err != nil {
+		return err
+	}
+	return api.Request(ctx, http.MethodDelete, base+"/service/rest/v1/assets/"+url.PathEscape(o.id), header, nil, nil)
+}
+
+// s3Objects is the output of aws s3api list-objects-v2
+type s3Objects struct {
+	Contents []struct {
+		Key          string    `json:"Key"`
+		LastModified time.Time `json:"LastModified"`
+		Size         int64     `json:"Size"`
+	} `json:"Contents"`
+}
+
+// List implements Backend interface
+func (b *s3Backend) List(ctx context.Context, prefix string) ([]Object, error) {
+	if b.cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is not configured for %s backend", BackendS3)
+	}
+	args := []string{"s3api", "list-objects-v2", "--bucket", b.cfg.Bucket, "--output", "json"}
+	if p := objectPrefix(b.cfg.Prefix, prefix); p != "" {
+		args = append(args, "--prefix", p)
+	}
+	if b.cfg.Region != "" {
+		args = append(args, "--region", b.cfg.Region)
+	}
+	var out bytes.Buffer
+	if err := runCLI(ctx, b.env(), &out, "aws", args...); err != nil {
+		return nil, err
+	}
+	var res s3Objects
+	if data := bytes.TrimSpace(out.Bytes()); len(data) > 0 {
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("unexpected output of aws s3api list-objects-v2: %w", err)
+		}
+	}
+	base := objectPrefix(b.cfg.Prefix, "")
+	objects := make([]Object, 0, len(res.Contents))
+	for _, c := range res.Contents {
+		objects = append(objects, Object{Path: strings.TrimPrefix(c.Key, base), Size: c.Size, Modified: c.LastModified})
+	}
+	return objects, nil
+}
+
+// Delete implements Backend interface
+func (b *s3Backend) Delete(ctx context.Context, o Object) error {
+	args := []string{"s3", "rm", b.target(Metadata{Path: o.Path}), "--only-show-errors"}
+	if b.cfg.Region != "" {
+		args = append(args, "--region", b.cfg.Region)
+	}
+	return runCLI(ctx, b.env(), nil, "aws", args...)
+}
+
+// List implements Backend interface with the long listing of gcloud, a line per object with its
+// size, creation time and URL
+func (b *gcsBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	if b.cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is not configured for %s backend", BackendGCS)
+	}
+	var out bytes.Buffer
+	uri := fmt.Sprintf("gs://%s/%s**", b.cfg.Bucket, objectPrefix(b.cfg.Prefix, prefix))
+	if err := runCLI(ctx, b.env(), &out, "gcloud", "storage", "ls", "--long", uri); err != nil {
+		// No object matches the wildcard
+		if strings.Contains(err.Error(), "One or more URLs matched no objects") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	base := fmt.Sprintf("gs://%s/%s", b.cfg.Bucket, objectPrefix(b.cfg.Prefix, ""))
+	var objects []Object
+	for line := range strings.Lines(out.String()) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], base) {
+			continue // Total line
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Path: strings.TrimPrefix(fields[2], base), Size: size, Modified: modified})
+	}
+	return objects, nil
+}
+
+// Delete implements Backend interface
+func (b *gcsBackend) Delete(ctx context.Context, o Object) error {
+	return runCLI(ctx, b.env(), nil, "gcloud", "storage", "rm", b.target(Metadata{Path: o.Path}))
+}
+
+// objectPrefix returns the prefix of the object names of a directory in the bucket prefix, with a
+// trailing slash unless empty
+func objectPrefix(bucketPrefix, dir string) string {
+	p := path.Join(bucketPrefix, dir)
+	if p == "." || p == "" {
+		return ""
+	}
+	return strings.TrimPrefix(p, "/") + "/"
+}
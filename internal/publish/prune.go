@@ -0,0 +1,141 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/internal/sigstore"
+)
+
+// Object is a file stored by a backend
+type Object struct {
+	Path     string // Path under the repository URL or bucket prefix
+	Size     int64
+	Modified time.Time
+	id       string // Identifier of the object in the backend API, if any
+}
+
+// Retention is the retention policy of the published artifacts
+type Retention struct {
+	Keep   int    `yaml:"keep"`    // Newest artifacts kept in each directory, zero disables pruning
+	MaxAge string `yaml:"max_age"` // Artifacts younger than this are kept too, e.g. 720h
+}
+
+// maxAge returns the age of the artifacts kept regardless of their number, zero for none
+func (r Retention) maxAge() (time.Duration, error) {
+	if r.MaxAge == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.MaxAge)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention max age %q, expected a positive duration like 720h", r.MaxAge)
+	}
+	return d, nil
+}
+
+// Validate checks the retention policy
+func (r Retention) Validate() error {
+	if r.Keep < 0 {
+		return fmt.Errorf("retention must not keep a negative number of artifacts, got %d", r.Keep)
+	}
+	_, err := r.maxAge()
+	return err
+}
+
+// isSidecar reports whether an object is published next to an artifact, like its checksum file
+func isSidecar(p string) bool {
+	return strings.HasSuffix(p, ChecksumExt) || strings.HasSuffix(p, sigstore.BundleExt)
+}
+
+// PruneCandidates returns the artifacts beyond the newest ones of their directory kept by the
+// retention policy, followed by their checksum files and signature bundles. The objects the
+// checksum files and bundles belong to are matched by path.
+func PruneCandidates(objects []Object, r Retention, now time.Time) ([]Object, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	if r.Keep == 0 {
+		return nil, nil
+	}
+	maxAge, _ := r.maxAge()
+
+	dirs := make(map[string][]Object)
+	sidecars := make(map[string][]Object)
+	for _, o := range objects {
+		if isSidecar(o.Path) {
+			owner := strings.TrimSuffix(strings.TrimSuffix(o.Path, ChecksumExt), sigstore.BundleExt)
+			sidecars[owner] = append(sidecars[owner], o)
+			continue
+		}
+		dirs[path.Dir(o.Path)] = append(dirs[path.Dir(o.Path)], o)
+	}
+
+	var prune []Object
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		artifacts := dirs[dir]
+		slices.SortStableFunc(artifacts, func(a, b Object) int {
+			return b.Modified.Compare(a.Modified)
+		})
+		for i, o := range artifacts {
+			if i < r.Keep || (maxAge > 0 && now.Sub(o.Modified) < maxAge) {
+				continue
+			}
+			prune = append(prune, o)
+			prune = append(prune, sidecars[o.Path]...)
+		}
+	}
+	return prune, nil
+}
+
+// listPrefix returns the directory of the artifacts of the platform, the static part of the path
+// template with the platform set
+func listPrefix(tmpl, platform string) string {
+	if tmpl == "" {
+		tmpl = DefaultPath
+	}
+	if platform != "" {
+		tmpl = strings.ReplaceAll(tmpl, "{platform}", platform)
+	}
+	if i := strings.Index(tmpl, "{"); i >= 0 {
+		tmpl = tmpl[:i]
+	}
+	if i := strings.LastIndex(tmpl, "/"); i >= 0 {
+		return tmpl[:i+1]
+	}
+	return ""
+}
+
+// Prune deletes the artifacts of the platform, or of the whole repository without platform, beyond
+// the retention policy and returns them. Nothing is deleted with dryRun.
+func (p *Publisher) Prune(ctx context.Context, r Retention, dryRun bool) ([]Object, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	backend, err := p.NewBackend()
+	if err != nil {
+		return nil, err
+	}
+	prefix := listPrefix(p.Config.Path, p.Platform)
+	objects, err := backend.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the published artifacts: %w", err)
+	}
+	p.Log().Debug("listed published artifacts", "prefix", prefix, "count", len(objects))
+
+	prune, err := PruneCandidates(objects, r, time.Now())
+	if err != nil || dryRun {
+		return prune, err
+	}
+	for i, o := range prune {
+		if err = backend.Delete(ctx, o); err != nil {
+			return prune[:i], fmt.Errorf("failed to delete %s: %w", o.Path, err)
+		}
+		p.Log().Debug("deleted published artifact", "path", o.Path)
+	}
+	return prune, nil
+}
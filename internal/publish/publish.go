@@ -0,0 +1,474 @@
+// Package publish uploads Platform Images and other artifacts to remote storage
+package publish
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/internal/pi"
+	"github.com/plasmash/plasmactl-platform/internal/sigstore"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ConfigKey is the launchr config key holding publish settings
+const ConfigKey = "platform.publish"
+
+// Storage backends.
+const (
+	BackendHTTP = "http" // BackendHTTP uploads with a plain HTTP PUT, e.g. to Nexus
+	BackendS3   = "s3"   // BackendS3 uploads to an S3 bucket with the aws CLI
+	BackendGCS  = "gcs"  // BackendGCS uploads to a GCS bucket with the gcloud CLI
+)
+
+// Sources of the credentials of the http backend
+const (
+	CredentialsKeyring = "keyring" // CredentialsKeyring reads the credentials of the repository URL from the keyring
+	CredentialsEnv     = "env"     // CredentialsEnv reads the credentials from UsernameEnv and PasswordEnv, or TokenEnv, e.g. in CI jobs
+	CredentialsNone    = "none"    // CredentialsNone uploads anonymously
+)
+
+// Environment variables of the credentials of the http backend with [CredentialsEnv]
+const (
+	UsernameEnv = "PLASMA_PUBLISH_USERNAME"
+	PasswordEnv = "PLASMA_PUBLISH_PASSWORD"
+	TokenEnv    = "PLASMA_PUBLISH_TOKEN" // Token of the bearer and header auth
+)
+
+// Authentication modes of the http backend
+const (
+	AuthBasic  = "basic"  // AuthBasic sends the username and password of the repository URL
+	AuthBearer = "bearer" // AuthBearer sends a token in the Authorization header, e.g. GitLab package registries
+	AuthHeader = "header" // AuthHeader sends a token in the token_header header, e.g. X-JFrog-Art-Api of Artifactory
+)
+
+// DefaultTokenKey is the keyring key of the token of the bearer and header auth, in the artifact-repo
+// scope of the platform
+const DefaultTokenKey = "artifact_repo_token"
+
+// DefaultURL is the artifact repository used by the HTTP backend when none is configured
+const DefaultURL = "https://repositories.skilld.cloud/repository/platform-images"
+
+// DefaultRetries is the number of retries of a failed upload
+const DefaultRetries = 3
+
+// DefaultPath is the path template of the artifacts, the file name under the repository URL or prefix
+const DefaultPath = "{name}"
+
+// Config defines where and how artifacts are published
+type Config struct {
+	Backend      string    `yaml:"backend"`       // http, s3, gcs
+	URL          string    `yaml:"url"`           // Repository URL for the http backend
+	FallbackURLs []string  `yaml:"fallback_urls"` // Repositories tried in order when URL is unreachable, http backend
+	Path         string    `yaml:"path"`          // Artifact path template, default is DefaultPath
+	Timeout      string    `yaml:"timeout"`       // Timeout of each upload request, e.g. 30m, http backend. Default is none
+	Retries      int       `yaml:"retries"`       // Retries of a failed upload, default is DefaultRetries, negative disables
+	ChunkSize    int       `yaml:"chunk_size"`    // Size in MiB of the Content-Range chunks, http backend. Default is a single PUT
+	Auth         string    `yaml:"auth"`          // Authentication of the http backend: basic (default), bearer or header
+	TokenKey     string    `yaml:"token_key"`     // Keyring key of the token of the bearer and header auth, default is DefaultTokenKey
+	TokenHeader  string    `yaml:"token_header"`  // Header carrying the token of the header auth, e.g. PRIVATE-TOKEN
+	Retention    Retention `yaml:"retention"`     // Retention of the published artifacts, see platform:artifact:prune
+	Bucket       string    `yaml:"bucket"`        // Bucket name for s3 and gcs backends
+	Prefix       string    `yaml:"prefix"`        // Object name prefix inside the bucket
+	Region       string    `yaml:"region"`        // Bucket region for the s3 backend
+	Encryption   string    `yaml:"encryption"`    // Server-side encryption: AES256 or aws:kms for s3
+	KMSKey       string    `yaml:"kms_key"`       // KMS key used for server-side encryption
+}
+
+// LoadConfig reads publish settings from the launchr config
+func LoadConfig(cfg launchr.Config) (Config, error) {
+	return loadConfig(cfg, schema.PublishConfig{})
+}
+
+// LoadPlatformConfig reads publish settings from the launchr config, overridden by the publish
+// section of the platform.yaml of the named platform, if any
+func LoadPlatformConfig(cfg launchr.Config, name string) (Config, error) {
+	if name == "" {
+		return LoadConfig(cfg)
+	}
+	platform, err := schema.LoadOptional(name)
+	if err != nil {
+		return Config{}, err
+	}
+	return loadConfig(cfg, platform.Publish)
+}
+
+// loadConfig reads publish settings from the launchr config, then applies the platform overrides
+// and the defaults
+func loadConfig(cfg launchr.Config, override schema.PublishConfig) (Config, error) {
+	var c Config
+	if cfg != nil {
+		if err := cfg.Get(ConfigKey, &c); err != nil {
+			return c, fmt.Errorf("failed to read %s config: %w", ConfigKey, err)
+		}
+	}
+	if override.URL != "" {
+		c.URL = override.URL
+		c.FallbackURLs = nil
+	}
+	if len(override.FallbackURLs) > 0 {
+		c.FallbackURLs = override.FallbackURLs
+	}
+	if override.Path != "" {
+		c.Path = override.Path
+	}
+	if override.Auth != "" {
+		c.Auth = override.Auth
+	}
+	if override.TokenKey != "" {
+		c.TokenKey = override.TokenKey
+	}
+	if override.TokenHeader != "" {
+		c.TokenHeader = override.TokenHeader
+	}
+
+	if c.Backend == "" {
+		c.Backend = BackendHTTP
+	}
+	if c.Backend == BackendHTTP && c.URL == "" {
+		c.URL = DefaultURL
+	}
+	if c.Path == "" {
+		c.Path = DefaultPath
+	}
+	if err := ValidatePath(c.Path); err != nil {
+		return c, err
+	}
+	if _, err := c.timeout(); err != nil {
+		return c, err
+	}
+	if c.ChunkSize < 0 {
+		return c, fmt.Errorf("invalid chunk_size %d in %s config, expected a size in MiB", c.ChunkSize, ConfigKey)
+	}
+	if err := c.Retention.Validate(); err != nil {
+		return c, fmt.Errorf("invalid %s.retention config: %w", ConfigKey, err)
+	}
+	if err := c.validateAuth(); err != nil {
+		return c, err
+	}
+	if c.TokenAuth() && c.TokenKey == "" {
+		c.TokenKey = DefaultTokenKey
+	}
+	return c, nil
+}
+
+// validateAuth checks the authentication mode of the http backend
+func (c Config) validateAuth() error {
+	switch c.Auth {
+	case "", AuthBasic, AuthBearer:
+	case AuthHeader:
+		if c.TokenHeader == "" {
+			return fmt.Errorf("token_header must be set with the %s auth in %s config, e.g. PRIVATE-TOKEN or X-JFrog-Art-Api", AuthHeader, ConfigKey)
+		}
+	default:
+		return fmt.Errorf("unknown auth %q in %s config (use %s, %s or %s)", c.Auth, ConfigKey, AuthBasic, AuthBearer, AuthHeader)
+	}
+	if c.Auth != AuthHeader && c.TokenHeader != "" {
+		return fmt.Errorf("token_header only applies to the %s auth in %s config", AuthHeader, ConfigKey)
+	}
+	return nil
+}
+
+// TokenAuth reports whether the http backend authenticates with a token instead of the username and
+// password of the repository URL
+func (c Config) TokenAuth() bool {
+	return c.Backend == BackendHTTP && (c.Auth == AuthBearer || c.Auth == AuthHeader)
+}
+
+// timeout returns the timeout of each upload request, zero for none
+func (c Config) timeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q in %s config, expected a positive duration like 30m", c.Timeout, ConfigKey)
+	}
+	return d, nil
+}
+
+// retries returns the retries of a failed upload
+func (c Config) retries() int {
+	switch {
+	case c.Retries == 0:
+		return DefaultRetries
+	case c.Retries < 0:
+		return 0
+	}
+	return c.Retries
+}
+
+// URLs returns the repository URL of the http backend followed by its fallbacks, none for the other
+// backends
+func (c Config) URLs() []string {
+	if c.Backend != BackendHTTP {
+		return nil
+	}
+	urls := []string{c.URL}
+	for _, u := range c.FallbackURLs {
+		if u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// pathPlaceholder matches the placeholders of the path templates, e.g. {name}
+var pathPlaceholder = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+// pathFields are the placeholders of the path templates
+var pathFields = map[string]func(Metadata) string{
+	"platform": func(m Metadata) string { return m.Platform },
+	"name":     func(m Metadata) string { return m.Name },
+	"sha256":   func(m Metadata) string { return m.SHA256 },
+}
+
+// ValidatePath checks that an artifact path template only uses known placeholders and is relative
+func ValidatePath(tmpl string) error {
+	for _, match := range pathPlaceholder.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := pathFields[match[1]]; !ok {
+			return fmt.Errorf("unknown field {%s} in artifact path template %q, supported: {platform}, {name}, {sha256}", match[1], tmpl)
+		}
+	}
+	if rest := pathPlaceholder.ReplaceAllString(tmpl, ""); strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("unbalanced braces in artifact path template %q", tmpl)
+	}
+	if strings.HasPrefix(tmpl, "/") || slices.Contains(strings.Split(tmpl, "/"), "..") {
+		return fmt.Errorf("artifact path template %q must be relative to the repository", tmpl)
+	}
+	return nil
+}
+
+// renderPath renders the artifact path template, tmpl must be validated
+func renderPath(tmpl string, meta Metadata) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultPath
+	}
+	var missing []string
+	p := pathPlaceholder.ReplaceAllStringFunc(tmpl, func(ph string) string {
+		field := ph[1 : len(ph)-1]
+		value := pathFields[field](meta)
+		if value == "" {
+			missing = append(missing, field)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("artifact path template %q needs {%s}", tmpl, strings.Join(missing, "}, {"))
+	}
+	return path.Clean(p), nil
+}
+
+// ChecksumExt is the extension of the SHA-256 checksum file published next to an artifact, in the
+// format of sha256sum
+const ChecksumExt = ".sha256"
+
+// Content types of the published files
+const (
+	contentTypeArtifact = "application/octet-stream"
+	contentTypeChecksum = "text/plain"
+	contentTypeBundle   = "application/json"
+)
+
+// Metadata describes an uploaded artifact
+type Metadata struct {
+	Name        string
+	Size        int64
+	SHA256      string
+	Platform    string // Platform the artifact is published for, empty if none
+	Path        string // Path of the artifact under the repository URL or bucket prefix
+	ContentType string // Default is application/octet-stream
+}
+
+// contentType returns the content type of the artifact
+func (m Metadata) contentType() string {
+	if m.ContentType == "" {
+		return contentTypeArtifact
+	}
+	return m.ContentType
+}
+
+// Backend uploads artifacts to a remote storage
+type Backend interface {
+	// Upload stores the local file under the metadata path and returns its remote location
+	Upload(ctx context.Context, path string, meta Metadata) (string, error)
+	// Download writes the artifact stored under the metadata path to w
+	Download(ctx context.Context, meta Metadata, w io.Writer) error
+	// List returns the objects stored under the path prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete deletes a listed object
+	Delete(ctx context.Context, o Object) error
+}
+
+// Publisher publishes artifacts with the configured backend
+type Publisher struct {
+	action.WithLogger
+	action.WithTerm
+
+	Keyring     keyring.Keyring
+	Config      Config
+	Platform    string // Platform namespacing the credentials of the keyring, only shared ones are read if empty
+	Credentials string // Source of the credentials of the http backend, default is CredentialsKeyring
+	Verify      bool   // Download the published files back and check their checksum
+}
+
+// NewBackend creates the storage backend selected in the configuration
+func (p *Publisher) NewBackend() (Backend, error) {
+	switch p.Config.Backend {
+	case BackendHTTP:
+		switch p.Credentials {
+		case "", CredentialsKeyring, CredentialsEnv, CredentialsNone:
+		default:
+			return nil, fmt.Errorf("unknown credentials source %q (use %s, %s or %s)", p.Credentials, CredentialsKeyring, CredentialsEnv, CredentialsNone)
+		}
+		return &httpBackend{
+			cfg:      p.Config,
+			k:        p.Keyring,
+			log:      p.Log(),
+			scope:    api.NewScope(p.Platform, api.PurposeArtifactRepo),
+			creds:    p.Credentials,
+			progress: pi.NewProgress(p.Term()),
+		}, nil
+	case BackendS3:
+		return &s3Backend{cfg: p.Config}, nil
+	case BackendGCS:
+		return &gcsBackend{cfg: p.Config}, nil
+	default:
+		return nil, fmt.Errorf("unknown publish backend %q (supported: %s, %s, %s)", p.Config.Backend, BackendHTTP, BackendS3, BackendGCS)
+	}
+}
+
+// Publish uploads the artifact, its SHA-256 checksum file and its signature bundle if it is signed,
+// and returns the remote location of the artifact
+func (p *Publisher) Publish(ctx context.Context, path string) (string, error) {
+	meta, err := artifactMetadata(path)
+	if err != nil {
+		return "", err
+	}
+	meta.Platform = p.Platform
+	if meta.Path, err = renderPath(p.Config.Path, meta); err != nil {
+		return "", err
+	}
+
+	backend, err := p.NewBackend()
+	if err != nil {
+		return "", err
+	}
+
+	p.Term().Info().Printfln("Publishing %s (%d bytes) with %s backend", meta.Name, meta.Size, p.Config.Backend)
+	p.Log().Debug("publishing artifact", "path", path, "sha256", meta.SHA256, "backend", p.Config.Backend)
+
+	location, err := backend.Upload(ctx, path, meta)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish %s: %w", meta.Name, err)
+	}
+	p.Term().Success().Printfln("Published %s", location)
+
+	sidecars, err := p.publishSidecars(ctx, backend, path, meta)
+	if err != nil {
+		return "", err
+	}
+	if p.Verify {
+		for _, m := range append([]Metadata{meta}, sidecars...) {
+			if err = verify(ctx, backend, m); err != nil {
+				return "", err
+			}
+		}
+		p.Term().Success().Printfln("Verified the checksum of %d published file(s)", len(sidecars)+1)
+	}
+	return location, nil
+}
+
+// sidecar is a file published next to an artifact, under the path of the artifact with its extension
+type sidecar struct {
+	path        string
+	ext         string
+	contentType string
+}
+
+// publishSidecars uploads the checksum file of the artifact and its signature bundle, if any
+func (p *Publisher) publishSidecars(ctx context.Context, backend Backend, path string, meta Metadata) ([]Metadata, error) {
+	dir, err := os.MkdirTemp("", "publish-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	checksum := filepath.Join(dir, meta.Name+ChecksumExt)
+	if err = atomicfile.WriteFile(checksum, fmt.Appendf(nil, "%s  %s\n", meta.SHA256, meta.Name), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write checksum file: %w", err)
+	}
+
+	files := []sidecar{{checksum, ChecksumExt, contentTypeChecksum}}
+	bundle := sigstore.BundlePath(path)
+	if _, err = os.Stat(bundle); err == nil {
+		files = append(files, sidecar{bundle, sigstore.BundleExt, contentTypeBundle})
+	} else {
+		p.Log().Debug("no signature bundle to publish", "path", bundle)
+	}
+
+	var sidecars []Metadata
+	for _, file := range files {
+		m, err := artifactMetadata(file.path)
+		if err != nil {
+			return nil, err
+		}
+		m.Name = meta.Name + file.ext
+		m.Platform = meta.Platform
+		m.Path = meta.Path + file.ext
+		m.ContentType = file.contentType
+		location, err := backend.Upload(ctx, file.path, m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish %s: %w", m.Name, err)
+		}
+		p.Term().Success().Printfln("Published %s", location)
+		sidecars = append(sidecars, m)
+	}
+	return sidecars, nil
+}
+
+// verify downloads a published file back and compares its checksum with the local one
+func verify(ctx context.Context, backend Backend, meta Metadata) error {
+	h := sha256.New()
+	if err := backend.Download(ctx, meta, h); err != nil {
+		return fmt.Errorf("failed to download %s back: %w", meta.Path, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != meta.SHA256 {
+		return fmt.Errorf("published %s is corrupted: SHA-256 %s, expected %s", meta.Path, sum, meta.SHA256)
+	}
+	return nil
+}
+
+// artifactMetadata computes name, size and checksum of the artifact
+func artifactMetadata(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	return Metadata{
+		Name:   filepath.Base(path),
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
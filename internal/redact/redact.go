@@ -0,0 +1,59 @@
+// Package redact masks the secrets of a run, like the vault password and the API and OAuth tokens,
+// in the logs, the terminal output, the log files and the error messages. The secrets are registered
+// with the sensitive mask of the application, which masks its output streams.
+package redact
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/launchrctl/launchr"
+)
+
+var mask atomic.Pointer[launchr.SensitiveMask]
+
+func init() {
+	// The default mask of launchr, until the one of the application is set
+	mask.Store((&launchr.SensitiveMask{}).ServiceCreate(nil).(*launchr.SensitiveMask))
+}
+
+// SetMask makes the secrets registered from now on masked by the sensitive mask of the application,
+// along with the values of the keyring
+func SetMask(m *launchr.SensitiveMask) {
+	if m != nil {
+		mask.Store(m)
+	}
+}
+
+// Add registers secret values to mask, empty values are ignored
+func Add(values ...string) {
+	m := mask.Load()
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			m.AddString(v)
+		}
+	}
+}
+
+// String returns s with the registered secrets masked
+func String(s string) string {
+	masked, _, _ := mask.Load().ReplaceAll([]byte(s))
+	return string(masked)
+}
+
+// Writer wraps a writer not backed by the streams of the application, like a log file or the output of
+// a subprocess, to mask the registered secrets. Close flushes the buffered output, it doesn't close w.
+func Writer(w io.Writer) io.WriteCloser {
+	return launchr.NewMaskingWriter(nopCloser{w}, mask.Load())
+}
+
+// nopCloser keeps the wrapped writer open when the masking writer is closed
+type nopCloser struct {
+	io.Writer
+}
+
+// Close implements [io.Closer]
+func (nopCloser) Close() error {
+	return nil
+}
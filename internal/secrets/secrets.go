@@ -0,0 +1,127 @@
+// Package secrets stores the secrets of the platforms in their backend: the keyring by default, or the
+// KV engine of a HashiCorp Vault configured in platform.yaml
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Backends of the secrets
+const (
+	BackendKeyring = "keyring"
+	BackendVault   = "vault"
+)
+
+// VaultPasswordKey is the key of the Ansible vault password decrypting the secrets of the deployments
+const VaultPasswordKey = "vaultpass"
+
+// VaultIDKey returns the key of the password of an Ansible vault identity
+func VaultIDKey(id schema.VaultID) string {
+	if id.Key != "" {
+		return id.Key
+	}
+	return VaultPasswordKey + "_" + id.ID
+}
+
+// ErrNotFound is returned for the secrets missing in their backend
+var ErrNotFound = errors.New("secret not found")
+
+// Backend stores the secrets of a platform
+type Backend interface {
+	// Name describes the backend, e.g. keyring or vault secret/plasma/dev
+	Name() string
+	// Get returns a secret, the error wraps [ErrNotFound] if it is missing
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores a secret
+	Set(ctx context.Context, key, value string) error
+	// List returns the sorted keys of the secrets
+	List(ctx context.Context) ([]string, error)
+}
+
+// New returns the secrets backend of a platform. The keyring stores the Vault token referenced by
+// platform.yaml, and the secrets of the keyring backend.
+func New(platform *schema.Platform, k keyring.Keyring, log *launchr.Logger, term *launchr.Terminal) (Backend, error) {
+	switch platform.Secrets.Backend {
+	case "", BackendKeyring:
+		if k == nil {
+			return nil, fmt.Errorf("keyring is not available to store the secrets of %s", platform.Name)
+		}
+		return &keyringBackend{k: k}, nil
+	case BackendVault:
+		return newVault(platform, k, log, term)
+	}
+	return nil, fmt.Errorf("unknown secrets backend %q of %s (use %s or %s)", platform.Secrets.Backend, platform.Name, BackendKeyring, BackendVault)
+}
+
+// Request requests a missing secret on the terminal and stores it in the backend
+func Request(ctx context.Context, b Backend, term *launchr.Terminal, key string) (string, error) {
+	term.Info().Printfln("Please add the secret %q to the %s", key, b.Name())
+	item := keyring.KeyValueItem{Key: key, Value: ""}
+	if err := keyring.RequestKeyValueFromTty(&item); err != nil {
+		return "", err
+	}
+	value, ok := item.Value.(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("secret %q must be a non-empty string", key)
+	}
+	redact.Add(value)
+	if err := b.Set(ctx, key, value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// envOr returns a value, or the environment variable if the value is empty
+func envOr(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+// keyringBackend stores the secrets in the keyring, shared by all the platforms
+type keyringBackend struct {
+	k keyring.Keyring
+}
+
+// Name implements [Backend]
+func (b *keyringBackend) Name() string {
+	return BackendKeyring
+}
+
+// Get implements [Backend]
+func (b *keyringBackend) Get(_ context.Context, key string) (string, error) {
+	value, err := api.Lookup(b.k, key)
+	if errors.Is(err, api.ErrMissingItem) {
+		return "", fmt.Errorf("%w: %s in the keyring", ErrNotFound, key)
+	}
+	return value, err
+}
+
+// Set implements [Backend]
+func (b *keyringBackend) Set(_ context.Context, key, value string) error {
+	if err := b.k.AddItem(keyring.KeyValueItem{Key: key, Value: value}); err != nil {
+		return err
+	}
+	return b.k.Save()
+}
+
+// List implements [Backend]
+func (b *keyringBackend) List(_ context.Context) ([]string, error) {
+	keys, err := b.k.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,170 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Defaults of the Vault configuration of platform.yaml
+const (
+	DefaultVaultMount = "secret"
+	vaultPathPrefix   = "plasma/"
+)
+
+// vault stores the secrets of a platform as the fields of a secret of a Vault KV v2 engine
+type vault struct {
+	addr      string
+	namespace string
+	mount     string
+	path      string
+	token     string
+}
+
+// kvSecret is the response of the read of a KV v2 secret
+type kvSecret struct {
+	Data struct {
+		Data     map[string]any `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// newVault returns the Vault backend of a platform, the token is resolved from the keyring
+// reference of platform.yaml or $VAULT_TOKEN
+func newVault(platform *schema.Platform, k keyring.Keyring, log *launchr.Logger, term *launchr.Terminal) (*vault, error) {
+	cfg := platform.Secrets.Vault
+	v := &vault{
+		addr:      strings.TrimRight(envOr(cfg.Address, "VAULT_ADDR"), "/"),
+		namespace: envOr(cfg.Namespace, "VAULT_NAMESPACE"),
+		mount:     strings.Trim(cfg.Mount, "/"),
+		path:      strings.Trim(cfg.Path, "/"),
+	}
+	if v.addr == "" {
+		return nil, fmt.Errorf("no Vault address for the secrets of %s, set secrets.vault.address or VAULT_ADDR", platform.Name)
+	}
+	if v.mount == "" {
+		v.mount = DefaultVaultMount
+	}
+	if v.path == "" {
+		v.path = vaultPathPrefix + platform.Name
+	}
+
+	if key, ok := api.KeyringKey(cfg.Token); ok {
+		token, err := api.Token(k, log, term, key)
+		if err != nil {
+			return nil, err
+		}
+		v.token = token
+	} else {
+		v.token = envOr(cfg.Token, "VAULT_TOKEN")
+	}
+	if v.token == "" {
+		return nil, fmt.Errorf("no Vault token for the secrets of %s, set secrets.vault.token or VAULT_TOKEN", platform.Name)
+	}
+	redact.Add(v.token)
+	return v, nil
+}
+
+// Name implements [Backend]
+func (v *vault) Name() string {
+	return fmt.Sprintf("%s %s/%s", BackendVault, v.mount, v.path)
+}
+
+// Get implements [Backend]
+func (v *vault) Get(ctx context.Context, key string) (string, error) {
+	data, _, err := v.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s in %s", ErrNotFound, key, v.Name())
+	}
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("secret %q of %s must be a non-empty string", key, v.Name())
+	}
+	redact.Add(s)
+	return s, nil
+}
+
+// Set implements [Backend], the other fields of the secret are kept. The write is checked against
+// the version read, so concurrent writes fail instead of dropping fields.
+func (v *vault) Set(ctx context.Context, key, value string) error {
+	data, version, err := v.read(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+	data[key] = value
+
+	body, err := json.Marshal(map[string]any{
+		"data":    data,
+		"options": map[string]int{"cas": version},
+	})
+	if err != nil {
+		return err
+	}
+	if err = api.Request(ctx, http.MethodPost, v.url(), v.header(), body, nil); err != nil {
+		return fmt.Errorf("failed to write %s: %w", v.Name(), err)
+	}
+	return nil
+}
+
+// List implements [Backend]
+func (v *vault) List(ctx context.Context) ([]string, error) {
+	data, _, err := v.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// read returns the fields of the secret and its version, none if the secret doesn't exist
+func (v *vault) read(ctx context.Context) (map[string]any, int, error) {
+	var res kvSecret
+	err := api.Request(ctx, http.MethodGet, v.url(), v.header(), nil, &res)
+	var apiErr *api.Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", v.Name(), err)
+	}
+	return res.Data.Data, res.Data.Metadata.Version, nil
+}
+
+// url returns the URL of the data of the secret
+func (v *vault) url() string {
+	return fmt.Sprintf("%s/v1/%s/data/%s", v.addr, v.mount, v.path)
+}
+
+// header returns the authentication headers of the requests
+func (v *vault) header() http.Header {
+	h := http.Header{}
+	h.Set("X-Vault-Token", v.token)
+	h.Set("Content-Type", "application/json")
+	if v.namespace != "" {
+		h.Set("X-Vault-Namespace", v.namespace)
+	}
+	return h
+}
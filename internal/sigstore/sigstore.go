@@ -0,0 +1,90 @@
+// Package sigstore signs and verifies Platform Images with cosign
+package sigstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// BundleExt is the extension of the Sigstore bundle stored next to a Platform Image
+const BundleExt = ".bundle"
+
+// BundlePath returns the default bundle location for a Platform Image
+func BundlePath(img string) string {
+	return img + BundleExt
+}
+
+// SignOptions holds options for signing a Platform Image
+type SignOptions struct {
+	Key    string // Private key path or KMS URI, keyless signing is used when empty
+	Bundle string // Output bundle path, defaults to BundlePath
+}
+
+// Sign signs a Platform Image and writes the Sigstore bundle next to it
+func Sign(ctx context.Context, img string, opts SignOptions, out, errOut io.Writer) (string, error) {
+	bundle := opts.Bundle
+	if bundle == "" {
+		bundle = BundlePath(img)
+	}
+
+	args := []string{"sign-blob", "--yes", "--bundle", bundle}
+	if opts.Key != "" {
+		args = append(args, "--key", opts.Key)
+	}
+	args = append(args, img)
+
+	if err := runCosign(ctx, args, out, errOut); err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", img, err)
+	}
+	return bundle, nil
+}
+
+// Verify checks the Platform Image signature against the policy
+func Verify(ctx context.Context, img, bundle string, policy schema.SignaturePolicy, out, errOut io.Writer) error {
+	if bundle == "" {
+		bundle = BundlePath(img)
+	}
+	if _, err := os.Stat(bundle); os.IsNotExist(err) {
+		return fmt.Errorf("signature bundle not found: %s", bundle)
+	}
+
+	args := []string{"verify-blob", "--bundle", bundle}
+	switch {
+	case policy.Key != "":
+		args = append(args, "--key", policy.Key)
+	case policy.Identity != "" && policy.Issuer != "":
+		args = append(args, "--certificate-identity", policy.Identity, "--certificate-oidc-issuer", policy.Issuer)
+	default:
+		return fmt.Errorf("signature policy requires either a key or a certificate identity and issuer")
+	}
+	args = append(args, img)
+
+	if err := runCosign(ctx, args, out, errOut); err != nil {
+		return fmt.Errorf("signature verification failed for %s: %w", img, err)
+	}
+	return nil
+}
+
+func runCosign(ctx context.Context, args []string, out, errOut io.Writer) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return fmt.Errorf("cosign is not installed: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("cosign exited with code %d", exitErr.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
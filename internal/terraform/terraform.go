@@ -0,0 +1,284 @@
+// Package terraform runs Terraform in the workspaces of the platforms, the directories holding the
+// Terraform configuration of their infrastructure. It's shared by the features provisioning resources
+// with Terraform, like the terraform DNS provider and the destruction of the platforms.
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/internal/tfstate"
+	"github.com/plasmash/plasmactl-platform/pkg/lock"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+)
+
+// WorkspaceDir is the directory of the Terraform workspace in a platform directory
+const WorkspaceDir = ".terraform"
+
+// Defaults of the workspaces
+const (
+	DefaultLockTimeout = time.Minute      // Time Terraform waits for the lock of the state
+	stopDelay          = 30 * time.Second // Time Terraform has to exit after a run is cancelled
+)
+
+// Workspace is the Terraform workspace of a platform
+type Workspace struct {
+	Dir      string
+	Platform string
+
+	Log    *launchr.Logger
+	Stdout io.Writer // Output of the runs, default is os.Stdout. The secrets are masked.
+	Stderr io.Writer // Default is os.Stderr
+	Env    []string  // Environment added to the one of the process, e.g. TF_VAR_ variables
+
+	LockTimeout time.Duration // Time Terraform waits for the lock of the state, default is DefaultLockTimeout
+}
+
+// Open returns the workspace of a platform, which may not exist yet
+func Open(platform string, log *launchr.Logger) *Workspace {
+	if log == nil {
+		log = launchr.Log()
+	}
+	return &Workspace{Dir: Dir(platform), Platform: platform, Log: log}
+}
+
+// Dir returns the directory of the workspace of the named platform, relative to the repository root
+func Dir(platform string) string {
+	return filepath.Join(schema.PlatformDir(platform), WorkspaceDir)
+}
+
+// Exists reports whether the workspace has Terraform configuration files
+func (w *Workspace) Exists() bool {
+	for _, pattern := range []string{"*.tf", "*.tf.json"} {
+		if matches, _ := filepath.Glob(filepath.Join(w.Dir, pattern)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteFile writes a file of the workspace, e.g. generated variables, creating the workspace if needed
+func (w *Workspace) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(w.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create the Terraform workspace of %s: %w", w.Platform, err)
+	}
+	return atomicfile.WriteFile(filepath.Join(w.Dir, name), data, 0o644)
+}
+
+// ReadFile reads a file of the workspace, the error wraps fs.ErrNotExist if it doesn't exist
+func (w *Workspace) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(w.Dir, name))
+}
+
+// Options are the options of the runs changing the infrastructure
+type Options struct {
+	Targets []string          // Addresses of the resources the run is limited to, all if empty
+	Vars    map[string]string // Values of the input variables
+}
+
+// args returns the arguments of the options
+func (o Options) args() []string {
+	var args []string
+	for _, t := range o.Targets {
+		args = append(args, "-target="+t)
+	}
+	for _, k := range slices.Sorted(maps.Keys(o.Vars)) {
+		args = append(args, "-var", k+"="+o.Vars[k])
+	}
+	return args
+}
+
+// Init initializes the workspace: the backend of the state, the providers and the modules
+func (w *Workspace) Init(ctx context.Context) error {
+	return w.locked(ctx, func() error {
+		_, err := w.run(ctx, "init", "-input=false")
+		return err
+	})
+}
+
+// Plan prints the changes of the infrastructure and reports whether there are any
+func (w *Workspace) Plan(ctx context.Context, opts Options) (bool, error) {
+	var changed bool
+	err := w.locked(ctx, func() error {
+		if err := w.ensureInit(ctx); err != nil {
+			return err
+		}
+		args := append([]string{"plan", "-input=false", "-detailed-exitcode", w.lockTimeout()}, opts.args()...)
+		code, err := w.run(ctx, args...)
+		if code == 2 {
+			changed = true
+			return nil
+		}
+		return err
+	})
+	return changed, err
+}
+
+// Apply applies the changes of the infrastructure without asking for confirmation
+func (w *Workspace) Apply(ctx context.Context, opts Options) error {
+	return w.locked(ctx, func() error {
+		if err := w.ensureInit(ctx); err != nil {
+			return err
+		}
+		args := append([]string{"apply", "-input=false", "-auto-approve", w.lockTimeout()}, opts.args()...)
+		_, err := w.run(ctx, args...)
+		return err
+	})
+}
+
+// ApplyFiles writes generated files of the workspace, e.g. variables, and applies the changes of the
+// infrastructure. The previous files are restored if Terraform fails, so they keep matching the state.
+func (w *Workspace) ApplyFiles(ctx context.Context, files map[string][]byte, opts Options) error {
+	return w.locked(ctx, func() error {
+		previous := make(map[string][]byte, len(files))
+		for name := range files {
+			data, err := w.ReadFile(name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			previous[name] = data
+		}
+		for name, data := range files {
+			if err := w.WriteFile(name, data); err != nil {
+				return err
+			}
+		}
+
+		err := w.ensureInit(ctx)
+		if err == nil {
+			args := append([]string{"apply", "-input=false", "-auto-approve", w.lockTimeout()}, opts.args()...)
+			_, err = w.run(ctx, args...)
+		}
+		if err == nil {
+			return nil
+		}
+		for name, data := range previous {
+			var rerr error
+			if data == nil {
+				rerr = os.Remove(filepath.Join(w.Dir, name))
+			} else {
+				rerr = w.WriteFile(name, data)
+			}
+			if rerr != nil {
+				return fmt.Errorf("%w, and failed to restore %s: %w", err, name, rerr)
+			}
+		}
+		return err
+	})
+}
+
+// Destroy destroys the resources of the workspace without asking for confirmation
+func (w *Workspace) Destroy(ctx context.Context, opts Options) error {
+	return w.locked(ctx, func() error {
+		if err := w.ensureInit(ctx); err != nil {
+			return err
+		}
+		args := append([]string{"destroy", "-input=false", "-auto-approve", w.lockTimeout()}, opts.args()...)
+		_, err := w.run(ctx, args...)
+		return err
+	})
+}
+
+// State returns the state of the workspace, pulled from its backend
+func (w *Workspace) State(ctx context.Context) (*tfstate.State, error) {
+	var state *tfstate.State
+	err := w.locked(ctx, func() error {
+		if err := w.ensureInit(ctx); err != nil {
+			return err
+		}
+		var err error
+		state, err = tfstate.Read(ctx, w.Dir)
+		return err
+	})
+	return state, err
+}
+
+// ensureInit initializes the workspace unless Terraform already did, the workspace must be locked
+func (w *Workspace) ensureInit(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(w.Dir, ".terraform")); err == nil {
+		return nil
+	}
+	_, err := w.run(ctx, "init", "-input=false")
+	return err
+}
+
+// lockTimeout returns the argument of the time Terraform waits for the lock of the state
+func (w *Workspace) lockTimeout() string {
+	timeout := w.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return "-lock-timeout=" + timeout.String()
+}
+
+// run runs a Terraform command in the workspace with its output streamed, and returns its exit code
+func (w *Workspace) run(ctx context.Context, args ...string) (int, error) {
+	if !w.Exists() {
+		return 0, fmt.Errorf("no Terraform configuration in %s", w.Dir)
+	}
+	stdout, stderr := w.Stdout, w.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	maskedOut, maskedErr := redact.Writer(stdout), redact.Writer(stderr)
+	defer maskedOut.Close()
+	defer maskedErr.Close()
+
+	w.Log.Debug("running terraform", "dir", w.Dir, "args", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "terraform", append([]string{"-chdir=" + w.Dir}, args...)...)
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+	cmd.Env = append(cmd.Env, w.Env...)
+	cmd.Stdout = maskedOut
+	cmd.Stderr = maskedErr
+	// Let Terraform release the lock of the state and save it when the run is cancelled, it's killed if
+	// it doesn't exit in time
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = stopDelay
+
+	err := simulate.Run(cmd)
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("terraform %s was interrupted: %w", args[0], ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), fmt.Errorf("terraform %s failed with exit code %d", args[0], exitErr.ExitCode())
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run terraform %s: %w", args[0], err)
+	}
+	return 0, nil
+}
+
+// locked runs fn holding the lock of the workspace, so concurrent runs of the plugin don't interleave
+// their changes of the generated files and their Terraform runs. The state itself is locked by
+// Terraform when its backend supports it.
+func (w *Workspace) locked(ctx context.Context, fn func() error) error {
+	if !w.Exists() {
+		return fmt.Errorf("no Terraform configuration in %s", w.Dir)
+	}
+	l, err := lock.Acquire(ctx, filepath.Join(w.Dir, lock.FileName), lock.Timeout())
+	if err != nil {
+		return fmt.Errorf("failed to lock the Terraform workspace of %s: %w", w.Platform, err)
+	}
+	defer l.Release()
+	return fn()
+}
@@ -0,0 +1,250 @@
+// Package tfstate reads the servers of Terraform state files, to import infrastructure already managed
+// by Terraform as node files
+package tfstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+)
+
+// State is a Terraform state, format version 4
+type State struct {
+	Version   int        `json:"version"`
+	Resources []Resource `json:"resources"`
+}
+
+// Resource is a resource of a Terraform state, with its instances when created with count or for_each
+type Resource struct {
+	Module    string     `json:"module,omitempty"`
+	Mode      string     `json:"mode"` // managed or data
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Instances []Instance `json:"instances"`
+}
+
+// Instance is an instance of a resource
+type Instance struct {
+	IndexKey   any            `json:"index_key,omitempty"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// mapping holds the attribute paths of the server values of a resource type. Paths are dotted,
+// lists along a path are flattened, and the first path with a value wins.
+type mapping struct {
+	hostname []string
+	offer    []string
+	location []string
+	public   []string
+	private  []string
+}
+
+// mappings are the server resources of the Terraform providers of the supported clouds
+var mappings = map[string]mapping{
+	"hcloud_server": {
+		hostname: []string{"name"},
+		offer:    []string{"server_type"},
+		location: []string{"location", "datacenter"},
+		public:   []string{"ipv4_address", "ipv6_address"},
+		private:  []string{"network.ip"},
+	},
+	"scaleway_instance_server": {
+		hostname: []string{"name"},
+		offer:    []string{"type"},
+		location: []string{"zone"},
+		public:   []string{"public_ips.address", "public_ip", "ipv6_address"},
+		private:  []string{"private_ips.address", "private_ip"},
+	},
+	"scaleway_baremetal_server": {
+		hostname: []string{"hostname", "name"},
+		offer:    []string{"offer_name", "offer"},
+		location: []string{"zone"},
+		public:   []string{"ips.address"},
+		private:  []string{"private_ips.address"},
+	},
+	"aws_instance": {
+		hostname: []string{"tags.Name", "private_dns"},
+		offer:    []string{"instance_type"},
+		location: []string{"availability_zone"},
+		public:   []string{"public_ip", "ipv6_addresses"},
+		private:  []string{"private_ip"},
+	},
+	"digitalocean_droplet": {
+		hostname: []string{"name"},
+		offer:    []string{"size"},
+		location: []string{"region"},
+		public:   []string{"ipv4_address", "ipv6_address"},
+		private:  []string{"ipv4_address_private"},
+	},
+	"google_compute_instance": {
+		hostname: []string{"hostname", "name"},
+		offer:    []string{"machine_type"},
+		location: []string{"zone"},
+		public:   []string{"network_interface.access_config.nat_ip"},
+		private:  []string{"network_interface.network_ip"},
+	},
+}
+
+// Types returns the supported server resource types, sorted
+func Types() []string {
+	return slices.Sorted(maps.Keys(mappings))
+}
+
+// Read reads a Terraform state from a local file, an HTTP backend URL, or a Terraform working
+// directory whose configured backend is pulled with terraform state pull
+func Read(ctx context.Context, source string) (*State, error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		var raw json.RawMessage
+		if err := api.Request(ctx, "GET", source, nil, nil, &raw); err != nil {
+			return nil, fmt.Errorf("failed to download Terraform state: %w", err)
+		}
+		data = raw
+	default:
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Terraform state: %w", err)
+		}
+		if info.IsDir() {
+			data, err = pull(ctx, source)
+		} else {
+			data, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Parse(data)
+}
+
+// Parse decodes a Terraform state
+func Parse(data []byte) (*State, error) {
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform state: %w", err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("unsupported Terraform state version %d, only version 4 is supported", state.Version)
+	}
+	return &state, nil
+}
+
+// pull returns the state of the backend of a Terraform working directory
+func pull(ctx context.Context, dir string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "terraform", "-chdir="+dir, "state", "pull")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run terraform state pull in %s: %w: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Servers returns the servers of the managed resources of supported types, and the types of the
+// managed resources it skipped
+func (s *State) Servers() ([]provider.Server, []string) {
+	var servers []provider.Server
+	var skipped []string
+	for _, r := range s.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		m, ok := mappings[r.Type]
+		if !ok {
+			if !slices.Contains(skipped, r.Type) {
+				skipped = append(skipped, r.Type)
+			}
+			continue
+		}
+		for _, inst := range r.Instances {
+			servers = append(servers, m.server(inst.Attributes))
+		}
+	}
+	slices.Sort(skipped)
+	return servers, skipped
+}
+
+// server returns the server of the attributes of a resource instance
+func (m mapping) server(attrs map[string]any) provider.Server {
+	s := provider.Server{
+		ID:       first(attrs, "id"),
+		Hostname: first(attrs, m.hostname...),
+		Offer:    first(attrs, m.offer...),
+		Location: first(attrs, m.location...),
+	}
+	// Machine types of Google Cloud are URLs
+	if i := strings.LastIndex(s.Offer, "/"); i >= 0 {
+		s.Offer = s.Offer[i+1:]
+	}
+	if s.Hostname == "" {
+		s.Hostname = s.ID
+	}
+	seen := make(map[string]bool)
+	add := func(paths []string, private bool) {
+		for _, p := range paths {
+			for _, v := range values(attrs, p) {
+				ip := net.ParseIP(v)
+				if ip == nil || seen[ip.String()] {
+					continue
+				}
+				seen[ip.String()] = true
+				s.Addresses = append(s.Addresses, provider.Address{IP: v, Private: private})
+			}
+		}
+	}
+	add(m.public, false)
+	add(m.private, true)
+	return s
+}
+
+// first returns the first non-empty value of the attribute paths
+func first(attrs map[string]any, paths ...string) string {
+	for _, p := range paths {
+		for _, v := range values(attrs, p) {
+			if v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// values returns the string values at a dotted attribute path, flattening the lists along it
+func values(v any, path string) []string {
+	key, rest, nested := strings.Cut(path, ".")
+	switch val := v.(type) {
+	case []any:
+		var out []string
+		for _, item := range val {
+			out = append(out, values(item, path)...)
+		}
+		return out
+	case map[string]any:
+		if !nested {
+			return values(val[key], "")
+		}
+		return values(val[key], rest)
+	case string:
+		if path == "" {
+			return []string{val}
+		}
+	case float64:
+		if path == "" {
+			return []string{strconv.FormatFloat(val, 'f', -1, 64)}
+		}
+	}
+	return nil
+}
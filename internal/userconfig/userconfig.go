@@ -0,0 +1,55 @@
+// Package userconfig reads the user-wide config, e.g. ~/.config/plasmactl/config.yaml. Its values are
+// defaults like the ones of the repository config, which takes precedence.
+package userconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the user-wide config file, relative to the config directory of the application
+const FileName = "config.yaml"
+
+// Config is the user-wide config
+type Config map[string]any
+
+// Load reads the user-wide config file, empty if it doesn't exist
+func Load() (Config, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return Config{}, nil
+	}
+	path := filepath.Join(dir, launchr.Version().Name, FileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var c map[string]any
+	if err = yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Lookup returns the value of a dotted key, e.g. platform.up.gitlab_domain
+func (c Config) Lookup(key string) (any, bool) {
+	var v any = map[string]any(c)
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Comparison is the expected and actual records of a name, type and policy in the public DNS
+type Comparison struct {
+	Type     string
+	Name     string
+	Expected []Record
+	Actual   []Record
+	Err      error // Lookup error other than a missing name
+}
+
+// Match reports whether the actual records are the expected ones
+func (c Comparison) Match() bool {
+	if c.Err != nil || len(c.Actual) != len(c.Expected) {
+		return false
+	}
+	for _, e := range c.Expected {
+		found := false
+		for _, a := range c.Actual {
+			if e.SameContent(a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare looks up the records sharing the name, type and policy of the expected ones in the public DNS,
+// and returns them side by side in the order of the expected records
+func Compare(ctx context.Context, expected []Record) []Comparison {
+	return CompareWith(ctx, net.DefaultResolver, expected)
+}
+
+// CompareWith is [Compare] querying the given resolver, e.g. a specific name server
+func CompareWith(ctx context.Context, resolver *net.Resolver, expected []Record) []Comparison {
+	var comparisons []Comparison
+	index := make(map[string]int)
+	for _, r := range expected {
+		s := r.slot()
+		if i, ok := index[s]; ok {
+			comparisons[i].Expected = append(comparisons[i].Expected, r)
+			continue
+		}
+		index[s] = len(comparisons)
+		c := Comparison{Type: r.Type, Name: r.Name, Expected: []Record{r}}
+		c.Actual, c.Err = lookup(ctx, resolver, r)
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// lookup returns the records of the public DNS sharing the name, type and policy of the record
+func lookup(ctx context.Context, resolver *net.Resolver, r Record) ([]Record, error) {
+	var records []Record
+	var err error
+	switch r.Type {
+	case "A", "AAAA":
+		var addrs []net.IPAddr
+		addrs, err = resolver.LookupIPAddr(ctx, r.Name)
+		for _, addr := range addrs {
+			if (addr.IP.To4() != nil) == (r.Type == "A") {
+				records = append(records, Record{Type: r.Type, Name: r.Name, Content: addr.IP.String()})
+			}
+		}
+	case "MX":
+		var mxs []*net.MX
+		mxs, err = resolver.LookupMX(ctx, r.Name)
+		for _, mx := range mxs {
+			records = append(records, Record{Type: "MX", Name: r.Name, Content: strings.TrimSuffix(mx.Host, "."), Priority: int(mx.Pref)})
+		}
+	case "TXT":
+		var txts []string
+		txts, err = resolver.LookupTXT(ctx, r.Name)
+		for _, txt := range txts {
+			rec := Record{Type: "TXT", Name: r.Name, Content: txt}
+			if rec.slot() == r.slot() {
+				records = append(records, rec)
+			}
+		}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		err = nil
+	}
+	return records, err
+}
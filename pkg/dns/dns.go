@@ -0,0 +1,407 @@
+// Package dns defines the DNS records of platforms and the interface of the DNS providers managing them.
+// Providers are registered by name and selected with dns.provider of platform.yaml, so other plugins
+// can add their own with [Register]. Reverse DNS is set by the metal provider owning the addresses,
+// registered with [RegisterReverse].
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ProviderManual is the DNS provider of records managed by hand, it can't be registered
+const ProviderManual = schema.ProviderManual
+
+// Defaults of the records of platform.yaml
+const (
+	DefaultTTL          = 300
+	DefaultMXPriority   = 10
+	DefaultDKIMSelector = "default"
+	DefaultSPF          = "v=spf1 mx -all"
+	DefaultDMARC        = "v=DMARC1; p=quarantine"
+)
+
+// Record is a DNS record
+type Record struct {
+	ID       string `json:"-"`    // Provider ID of an existing record
+	Type     string `json:"type"` // A, AAAA, MX, TXT or PTR
+	Name     string `json:"name"` // Fully qualified name, without trailing dot, or the address of PTR records
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl,omitempty"`
+	Priority int    `json:"priority,omitempty"` // Priority of MX records
+}
+
+// String returns the record in zone file notation
+func (r Record) String() string {
+	if r.Type == "MX" {
+		return fmt.Sprintf("%s %d MX %d %s", r.Name, r.TTL, r.Priority, r.Content)
+	}
+	if r.Type == "TXT" {
+		return fmt.Sprintf("%s %d TXT %q", r.Name, r.TTL, r.Content)
+	}
+	if r.Type == "PTR" {
+		return fmt.Sprintf("%s PTR %s", r.Name, r.Content)
+	}
+	return fmt.Sprintf("%s %d %s %s", r.Name, r.TTL, r.Type, r.Content)
+}
+
+// slot identifies records replacing each other: the same name and type, and for TXT the same policy,
+// so SPF, DKIM and DMARC records don't touch other TXT records of the name, e.g. site verifications
+func (r Record) slot() string {
+	key := r.Type + " " + strings.ToLower(r.Name)
+	if r.Type == "TXT" {
+		key += " " + txtPolicy(r.Content)
+	}
+	return key
+}
+
+// txtPolicy returns the version tag of SPF, DKIM and DMARC records, empty for other TXT records
+func txtPolicy(content string) string {
+	tag, _, _ := strings.Cut(content, ";")
+	tag, _, _ = strings.Cut(strings.TrimSpace(tag), " ")
+	tag = strings.ToLower(tag)
+	switch tag {
+	case "v=spf1", "v=dkim1", "v=dmarc1":
+		return tag
+	default:
+		return ""
+	}
+}
+
+// Managed reports whether the record is of a type and policy managed by platforms, or an ACME
+// challenge record. Providers only list and change managed records.
+func (r Record) Managed() bool {
+	switch r.Type {
+	case "A", "AAAA", "MX":
+		return true
+	case "TXT":
+		return txtPolicy(r.Content) != "" || strings.HasPrefix(strings.ToLower(r.Name), ACMEChallengeLabel+".")
+	default:
+		return false
+	}
+}
+
+// ACMEChallengeLabel is the label of the TXT records of ACME DNS-01 challenges, prepended to the validated name
+const ACMEChallengeLabel = "_acme-challenge"
+
+// ACMEChallengeRecord returns the TXT record of an ACME DNS-01 challenge of the name, wildcard names
+// being validated by the record of their base name
+func ACMEChallengeRecord(name, value string) Record {
+	name = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(name), "."), "*.")
+	return Record{Type: "TXT", Name: ACMEChallengeLabel + "." + name, Content: value, TTL: 60}
+}
+
+// SameContent reports whether the records have the same data, ignoring their notation
+func (r Record) SameContent(o Record) bool {
+	switch r.Type {
+	case "A", "AAAA":
+		return net.ParseIP(r.Content).Equal(net.ParseIP(o.Content))
+	case "MX":
+		return r.Priority == o.Priority && strings.EqualFold(r.Content, o.Content)
+	case "PTR":
+		return strings.EqualFold(strings.TrimSuffix(r.Content, "."), strings.TrimSuffix(o.Content, "."))
+	case "TXT":
+		return normalizeTXT(r.Content) == normalizeTXT(o.Content)
+	default:
+		return r.Content == o.Content
+	}
+}
+
+// normalizeTXT returns the policy of SPF, DKIM and DMARC records without the optional whitespace
+// between their terms and tags, other TXT content is returned as is
+func normalizeTXT(content string) string {
+	switch txtPolicy(content) {
+	case "v=spf1":
+		return strings.Join(strings.Fields(content), " ")
+	case "v=dkim1", "v=dmarc1":
+		var tags []string
+		for _, tag := range strings.Split(content, ";") {
+			name, value, _ := strings.Cut(tag, "=")
+			name, value = strings.TrimSpace(name), strings.Join(strings.Fields(value), "")
+			if name != "" {
+				tags = append(tags, name+"="+value)
+			}
+		}
+		return strings.Join(tags, "; ")
+	default:
+		return content
+	}
+}
+
+// ChangeAction is the kind of change of a record
+type ChangeAction string
+
+// Changes of records.
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// Change is a change of a record made by a provider. Old is the replaced record of an update.
+type Change struct {
+	Action ChangeAction
+	Record Record
+	Old    Record
+}
+
+// String returns a human-readable change
+func (c Change) String() string {
+	switch c.Action {
+	case ChangeCreate:
+		return "+ " + c.Record.String()
+	case ChangeDelete:
+		return "- " + c.Record.String()
+	default:
+		return fmt.Sprintf("~ %s (was %s)", c.Record, c.Old)
+	}
+}
+
+// DNSProvider manages the records of a domain with a DNS service
+type DNSProvider interface {
+	// PlanChanges returns the changes making the records of the domain match the desired ones
+	PlanChanges(ctx context.Context, records []Record) ([]Change, error)
+	// EnsureRecords creates and updates records to match the desired ones, and returns the changes
+	EnsureRecords(ctx context.Context, records []Record) ([]Change, error)
+	// DeleteRecords deletes the existing records matching the given ones, see [PlanDeletion], and
+	// returns the changes
+	DeleteRecords(ctx context.Context, records []Record) ([]Change, error)
+}
+
+// Plan returns the changes turning the existing records into the desired ones. Only the existing
+// records sharing the name, type and policy of a desired record are updated or deleted.
+func Plan(existing, desired []Record) []Change {
+	var slots []string
+	want := make(map[string][]Record)
+	for _, r := range desired {
+		s := r.slot()
+		if _, ok := want[s]; !ok {
+			slots = append(slots, s)
+		}
+		want[s] = append(want[s], r)
+	}
+	have := make(map[string][]Record)
+	for _, r := range existing {
+		have[r.slot()] = append(have[r.slot()], r)
+	}
+
+	var changes []Change
+	for _, s := range slots {
+		var missing, stale []Record
+		olds := have[s]
+		for _, r := range want[s] {
+			found := -1
+			for i, old := range olds {
+				if old.SameContent(r) {
+					found = i
+					break
+				}
+			}
+			if found < 0 {
+				missing = append(missing, r)
+				continue
+			}
+			if olds[found].TTL != r.TTL {
+				r.ID = olds[found].ID
+				changes = append(changes, Change{Action: ChangeUpdate, Record: r, Old: olds[found]})
+			}
+			olds = append(olds[:found:found], olds[found+1:]...)
+		}
+		stale = olds
+
+		for i, r := range missing {
+			if i < len(stale) {
+				r.ID = stale[i].ID
+				changes = append(changes, Change{Action: ChangeUpdate, Record: r, Old: stale[i]})
+				continue
+			}
+			changes = append(changes, Change{Action: ChangeCreate, Record: r})
+		}
+		for i := len(missing); i < len(stale); i++ {
+			changes = append(changes, Change{Action: ChangeDelete, Record: stale[i]})
+		}
+	}
+	return changes
+}
+
+// PlanDeletion returns the deletion of the existing records with the name, type and content of a given
+// record, the records of the name added by hand, e.g. the apex A and MX records of a shared domain, are
+// kept. A given record of a policy without content, e.g. the TXT v=DKIM1 of a retired DKIM selector,
+// matches all the records of its name and policy.
+func PlanDeletion(existing, records []Record) []Change {
+	want := make(map[string][]Record, len(records))
+	for _, r := range records {
+		want[r.slot()] = append(want[r.slot()], r)
+	}
+	var changes []Change
+	for _, r := range existing {
+		for _, w := range want[r.slot()] {
+			if w.policyOnly() || w.SameContent(r) {
+				changes = append(changes, Change{Action: ChangeDelete, Record: r})
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// policyOnly reports whether the record is a TXT record of a policy without any tag but its version
+func (r Record) policyOnly() bool {
+	return r.Type == "TXT" && txtPolicy(r.Content) != "" && txtPolicy(r.Content) == strings.ToLower(strings.TrimSpace(r.Content))
+}
+
+// Records returns the records of the platform domain configured in platform.yaml
+func Records(cfg schema.DNSConfig) ([]Record, error) {
+	domain := strings.TrimSuffix(strings.ToLower(cfg.Domain), ".")
+	if domain == "" {
+		return nil, errors.New("dns.domain is not configured")
+	}
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	var records []Record
+	for _, name := range sortedNames(cfg.Addresses) {
+		fqdn := domain
+		if name != "@" && name != "" {
+			fqdn = strings.ToLower(name) + "." + domain
+		}
+		for _, addr := range cfg.Addresses[name] {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q of dns.addresses.%s", addr, name)
+			}
+			typ := "AAAA"
+			if ip.To4() != nil {
+				typ = "A"
+			}
+			records = append(records, Record{Type: typ, Name: fqdn, Content: ip.String(), TTL: ttl})
+		}
+	}
+
+	mail := cfg.Mail
+	for _, mx := range mail.MX {
+		host := strings.TrimSuffix(strings.ToLower(mx.Host), ".")
+		if host == "" {
+			return nil, errors.New("dns.mail.mx host is empty")
+		}
+		if !strings.Contains(host, ".") {
+			host += "." + domain
+		}
+		priority := mx.Priority
+		if priority == 0 {
+			priority = DefaultMXPriority
+		}
+		records = append(records, Record{Type: "MX", Name: domain, Content: host, TTL: ttl, Priority: priority})
+	}
+
+	spf, dmarc := mail.SPF, mail.DMARC
+	if len(mail.MX) > 0 {
+		if spf == "" {
+			spf = DefaultSPF
+		}
+		if dmarc == "" {
+			dmarc = DefaultDMARC
+		}
+	}
+	if spf != "" {
+		if txtPolicy(spf) != "v=spf1" {
+			return nil, fmt.Errorf("invalid dns.mail.spf %q, expected v=spf1 policy", spf)
+		}
+		records = append(records, Record{Type: "TXT", Name: domain, Content: spf, TTL: ttl})
+	}
+	keys, err := dkimKeys(mail.DKIM)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.PublicKey == "" {
+			continue
+		}
+		records = append(records, Record{
+			Type:    "TXT",
+			Name:    key.Selector + "._domainkey." + domain,
+			Content: "v=DKIM1; k=rsa; p=" + strings.Join(strings.Fields(key.PublicKey), ""),
+			TTL:     ttl,
+		})
+	}
+	if dmarc != "" {
+		if txtPolicy(dmarc) != "v=dmarc1" {
+			return nil, fmt.Errorf("invalid dns.mail.dmarc %q, expected v=DMARC1 policy", dmarc)
+		}
+		records = append(records, Record{Type: "TXT", Name: "_dmarc." + domain, Content: dmarc, TTL: ttl})
+	}
+	return records, nil
+}
+
+// CommonDKIMSelectors are the selectors looked up when platform.yaml doesn't declare any
+var CommonDKIMSelectors = []string{"default", "dkim", "mail", "selector1", "selector2", "google", "k1", "s1", "s2", "smtp"}
+
+// DKIMSelectors returns the selectors declared in the DKIM config, with or without public key
+func DKIMSelectors(cfg schema.DKIMConfig) []string {
+	keys, _ := dkimKeys(cfg)
+	selectors := make([]string, 0, len(keys))
+	for _, key := range keys {
+		selectors = append(selectors, key.Selector)
+	}
+	return selectors
+}
+
+// RetiredRecords returns the DKIM records of the retired selectors, deleted by providers
+func RetiredRecords(cfg schema.DNSConfig) []Record {
+	domain := strings.TrimSuffix(strings.ToLower(cfg.Domain), ".")
+	records := make([]Record, 0, len(cfg.Mail.DKIM.Retired))
+	for _, selector := range cfg.Mail.DKIM.Retired {
+		records = append(records, Record{Type: "TXT", Name: selector + "._domainkey." + domain, Content: "v=DKIM1"})
+	}
+	return records
+}
+
+// dkimKeys returns the keys of the DKIM config, starting with the one of selector and public_key when set
+func dkimKeys(cfg schema.DKIMConfig) ([]schema.DKIMKey, error) {
+	var keys []schema.DKIMKey
+	if cfg.Selector != "" || cfg.PublicKey != "" {
+		selector := cfg.Selector
+		if selector == "" {
+			selector = DefaultDKIMSelector
+		}
+		keys = append(keys, schema.DKIMKey{Selector: selector, PublicKey: cfg.PublicKey})
+	}
+	keys = append(keys, cfg.Keys...)
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.Selector == "" {
+			return nil, errors.New("dns.mail.dkim.keys selector is empty")
+		}
+		if seen[key.Selector] {
+			return nil, fmt.Errorf("DKIM selector %q is declared twice", key.Selector)
+		}
+		seen[key.Selector] = true
+	}
+	for _, selector := range cfg.Retired {
+		if seen[selector] {
+			return nil, fmt.Errorf("DKIM selector %q is both declared and retired", selector)
+		}
+	}
+	return keys, nil
+}
+
+// sortedNames returns the names of the addresses in a stable order
+func sortedNames(addresses map[string][]string) []string {
+	return slices.Sorted(maps.Keys(addresses))
+}
+
+// InDomain reports whether the record name is the domain or one of its subdomains
+func InDomain(name, domain string) bool {
+	name, domain = strings.ToLower(name), strings.ToLower(domain)
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
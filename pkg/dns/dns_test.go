@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanDeletion(t *testing.T) {
+	existing := []Record{
+		{ID: "1", Type: "A", Name: "dev.example.com", Content: "192.0.2.1"},
+		{ID: "2", Type: "A", Name: "dev.example.com", Content: "192.0.2.99"},
+		{ID: "3", Type: "MX", Name: "dev.example.com", Content: "mail.dev.example.com", Priority: 10},
+		{ID: "4", Type: "MX", Name: "dev.example.com", Content: "mx.other.com", Priority: 20},
+		{ID: "5", Type: "TXT", Name: "dev.example.com", Content: "v=spf1  mx -all"},
+		{ID: "6", Type: "TXT", Name: "dev.example.com", Content: "google-site-verification=abc"},
+		{ID: "7", Type: "TXT", Name: "old._domainkey.dev.example.com", Content: "v=DKIM1; k=rsa; p=MIIB"},
+	}
+	tests := []struct {
+		name    string
+		records []Record
+		want    []string
+	}{
+		{
+			name:    "same content only",
+			records: []Record{{Type: "A", Name: "dev.example.com", Content: "192.0.2.1"}},
+			want:    []string{"1"},
+		},
+		{
+			name:    "mx of another host kept",
+			records: []Record{{Type: "MX", Name: "dev.example.com", Content: "mail.dev.example.com", Priority: 10}},
+			want:    []string{"3"},
+		},
+		{
+			name:    "txt notation and other txt kept",
+			records: []Record{{Type: "TXT", Name: "dev.example.com", Content: "v=spf1 mx -all"}},
+			want:    []string{"5"},
+		},
+		{
+			name:    "retired dkim selector",
+			records: []Record{{Type: "TXT", Name: "old._domainkey.dev.example.com", Content: "v=DKIM1"}},
+			want:    []string{"7"},
+		},
+		{
+			name:    "unknown content",
+			records: []Record{{Type: "A", Name: "dev.example.com", Content: "192.0.2.50"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range PlanDeletion(existing, tt.records) {
+				if c.Action != ChangeDelete {
+					t.Fatalf("PlanDeletion() action = %s, want %s", c.Action, ChangeDelete)
+				}
+				got = append(got, c.Record.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PlanDeletion() deleted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ValidatingResolver is the public resolver checking DNSSEC signatures
+const ValidatingResolver = "1.1.1.1"
+
+// DNSSEC record types, not defined by dnsmessage
+const (
+	typeDS     dnsmessage.Type = 43
+	typeDNSKEY dnsmessage.Type = 48
+)
+
+// DNSSECStatus is the DNSSEC state of a domain
+type DNSSECStatus struct {
+	Zone      string // Zone holding the domain
+	DS        bool   // Delegation signer records of the zone are published in its parent
+	DNSKEY    bool   // The zone publishes its keys
+	Validated bool   // The validating resolver authenticated the records of the domain
+}
+
+// CheckDNSSEC returns the DNSSEC state of the domain with the server, which must validate signatures.
+// The system resolver is replaced by [ValidatingResolver]. Broken signatures are returned as error,
+// since validating resolvers refuse to resolve the domain.
+func CheckDNSSEC(ctx context.Context, server Server, domain string) (DNSSECStatus, error) {
+	var status DNSSECStatus
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	h, _, authorities, err := query(ctx, server, domain, dnsmessage.TypeSOA)
+	if err != nil {
+		return status, err
+	}
+	if h.RCode == dnsmessage.RCodeServerFailure {
+		return status, fmt.Errorf("%s fails to resolve %s, its DNSSEC signatures may be invalid", server.Validator(), domain)
+	}
+	status.Validated = h.AuthenticData
+	status.Zone = domain
+	for _, r := range authorities {
+		if r.Header.Type == dnsmessage.TypeSOA {
+			status.Zone = strings.TrimSuffix(r.Header.Name.String(), ".")
+		}
+	}
+
+	if status.DS, err = hasRecord(ctx, server, status.Zone, typeDS); err != nil {
+		return status, err
+	}
+	if status.DNSKEY, err = hasRecord(ctx, server, status.Zone, typeDNSKEY); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// Validator returns the server validating DNSSEC signatures in [CheckDNSSEC]
+func (s Server) Validator() string {
+	if s.IsSystem() {
+		return ValidatingResolver
+	}
+	return s.String()
+}
+
+// hasRecord reports whether the name has records of the type
+func hasRecord(ctx context.Context, server Server, name string, typ dnsmessage.Type) (bool, error) {
+	_, answers, _, err := query(ctx, server, name, typ)
+	for _, r := range answers {
+		if r.Header.Type == typ {
+			return true, err
+		}
+	}
+	return false, err
+}
+
+// query sends a query with the DNSSEC OK bit to the server, and returns the header, answers and
+// authorities of the response
+func query(ctx context.Context, server Server, name string, typ dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource, []dnsmessage.Resource, error) {
+	var h dnsmessage.Header
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return h, nil, nil, err
+	}
+	id := uint16(rand.Uint32())
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{ID: id, RecursionDesired: true, AuthenticData: true})
+	b.EnableCompression()
+	var opt dnsmessage.ResourceHeader
+	err = errors.Join(
+		b.StartQuestions(),
+		b.Question(dnsmessage.Question{Name: qname, Type: typ, Class: dnsmessage.ClassINET}),
+		b.StartAdditionals(),
+		opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, true),
+		b.OPTResource(opt, dnsmessage.OPTResource{}),
+	)
+	if err != nil {
+		return h, nil, nil, err
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return h, nil, nil, err
+	}
+	resp, err := server.exchange(ctx, msg)
+	if err != nil {
+		return h, nil, nil, err
+	}
+
+	var p dnsmessage.Parser
+	if h, err = p.Start(resp); err != nil {
+		return h, nil, nil, err
+	}
+	if h.ID != id {
+		return h, nil, nil, fmt.Errorf("unexpected DNS response ID %d", h.ID)
+	}
+	if err = p.SkipAllQuestions(); err != nil {
+		return h, nil, nil, err
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return h, nil, nil, err
+	}
+	authorities, err := p.AllAuthorities()
+	return h, answers, authorities, err
+}
@@ -0,0 +1,112 @@
+package dns
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Export holds the records of a platform as handed over to the team managing its DNS, e.g. a NOC
+type Export struct {
+	Domain  string   `json:"domain"`
+	Records []Record `json:"records"`
+	Reverse []Record `json:"reverse,omitempty"` // PTR records, set by the owner of the addresses
+	Retired []string `json:"retired,omitempty"` // Names of the TXT records to delete
+}
+
+// NewExport returns the records, reverse DNS and retired DKIM records of the platform
+func NewExport(cfg schema.DNSConfig) (Export, error) {
+	records, err := Records(cfg)
+	if err != nil {
+		return Export{}, err
+	}
+	reverse, err := PTRRecords(cfg)
+	if err != nil {
+		return Export{}, err
+	}
+	e := Export{
+		Domain:  strings.TrimSuffix(strings.ToLower(cfg.Domain), "."),
+		Records: records,
+		Reverse: reverse,
+	}
+	for _, r := range RetiredRecords(cfg) {
+		e.Retired = append(e.Retired, r.Name)
+	}
+	return e, nil
+}
+
+// WriteZone writes the records as a BIND zone file snippet with fully qualified names, so it can be
+// pasted in the zone of the domain or of a parent domain. Reverse DNS and retired records follow in
+// commented sections, the former being served by the reverse zones of the addresses.
+func (e Export) WriteZone(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "; DNS records of %s\n", e.Domain)
+	for _, r := range e.Records {
+		fmt.Fprintf(tw, "%s.\t%d\tIN\t%s\t%s\n", r.Name, r.TTL, r.Type, zoneContent(r))
+	}
+
+	if len(e.Reverse) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "; Reverse DNS, set by the owner of the addresses")
+		for _, r := range e.Reverse {
+			fmt.Fprintf(tw, "%s\t\tIN\tPTR\t%s.\n", reverseName(r.Name), r.Content)
+		}
+	}
+
+	if len(e.Retired) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "; Retired DKIM selectors, to delete")
+		for _, name := range e.Retired {
+			fmt.Fprintf(tw, "; %s.\tIN\tTXT\n", name)
+		}
+	}
+	return tw.Flush()
+}
+
+// zoneContent returns the data of the record in zone file notation
+func zoneContent(r Record) string {
+	switch r.Type {
+	case "MX":
+		return fmt.Sprintf("%d %s.", r.Priority, r.Content)
+	case "TXT":
+		// Character strings are limited to 255 bytes, e.g. DKIM keys are split in several
+		const maxLen = 255
+		var parts []string
+		content := r.Content
+		for len(content) > maxLen {
+			parts = append(parts, content[:maxLen])
+			content = content[maxLen:]
+		}
+		parts = append(parts, content)
+		for i, p := range parts {
+			p = strings.ReplaceAll(p, `\`, `\\`)
+			parts[i] = `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+		}
+		return strings.Join(parts, " ")
+	default:
+		return r.Content
+	}
+}
+
+// reverseName returns the fully qualified name of the PTR record of an address, with trailing dot
+func reverseName(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	var labels []string
+	if ip4 := ip.To4(); ip4 != nil {
+		for i := len(ip4) - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprint(ip4[i]))
+		}
+		return strings.Join(labels, ".") + ".in-addr.arpa."
+	}
+	for i := len(ip) - 1; i >= 0; i-- {
+		labels = append(labels, fmt.Sprintf("%x", ip[i]&0xf), fmt.Sprintf("%x", ip[i]>>4))
+	}
+	return strings.Join(labels, ".") + ".ip6.arpa."
+}
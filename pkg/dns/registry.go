@@ -0,0 +1,176 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Options are the services available to providers when they are created
+type Options struct {
+	Keyring keyring.Keyring   // Keyring holding the credentials of the DNS service, may be nil
+	Log     *launchr.Logger   // Logger of the running action
+	Term    *launchr.Terminal // Terminal of the running action, e.g. to request missing credentials
+
+	Platform string // Platform namespacing the credentials of the keyring, only shared ones are read if empty
+}
+
+// Factory creates a provider managing the records of the DNS config of a platform
+type Factory func(cfg schema.DNSConfig, opts Options) (DNSProvider, error)
+
+// ReverseFactory creates a provider managing the PTR records of the addresses of a metal provider
+type ReverseFactory func(infra schema.Infrastructure, opts Options) (ReverseDNSProvider, error)
+
+// ErrReverseUnsupported is returned by [NewReverse] for metal providers without reverse DNS provider
+var ErrReverseUnsupported = errors.New("metal provider can't set reverse DNS")
+
+var (
+	registryMu      sync.RWMutex
+	registry        = make(map[string]Factory)
+	reverseRegistry = make(map[string]ReverseFactory)
+
+	// Factories replacing the registered ones in simulation, see [Simulate]
+	simulated        Factory
+	simulatedReverse ReverseFactory
+)
+
+// Register makes a provider available under the name used in dns.provider of platform.yaml.
+// It's meant to be called from init functions and panics if the name is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if err := checkRegister(registry, "DNS", name, factory); err != nil {
+		panic("dns: Register: " + err.Error())
+	}
+	registry[name] = factory
+}
+
+// checkRegister checks a provider can be registered under the name, the registry must be locked
+func checkRegister[F any](reg map[string]F, kind, name string, factory F) error {
+	if reflect.ValueOf(factory).IsNil() {
+		return fmt.Errorf("factory of %s provider %q is nil", kind, name)
+	}
+	if name == "" || name == ProviderManual {
+		return fmt.Errorf("%s provider name %q is reserved", kind, name)
+	}
+	if _, dup := reg[name]; dup {
+		return fmt.Errorf("%s provider %q is already registered", kind, name)
+	}
+	return nil
+}
+
+// Providers returns the sorted names of the registered providers
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// New creates the provider selected in the DNS config
+func New(cfg schema.DNSConfig, opts Options) (DNSProvider, error) {
+	if cfg.Provider == "" || cfg.Provider == ProviderManual {
+		return nil, fmt.Errorf("dns.provider %q doesn't manage records", cfg.Provider)
+	}
+	registryMu.RLock()
+	factory, ok := registry[cfg.Provider]
+	if simulated != nil {
+		factory, ok = simulated, true
+	}
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported DNS provider %q (supported: %s)", cfg.Provider, strings.Join(Providers(), ", "))
+	}
+	return factory(cfg, opts)
+}
+
+// RegisterReverse makes a reverse DNS provider available for the metal provider of the name used in
+// infrastructure.metal_provider of platform.yaml. It panics if the name is registered twice.
+func RegisterReverse(name string, factory ReverseFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if err := checkRegister(reverseRegistry, "reverse DNS", name, factory); err != nil {
+		panic("dns: RegisterReverse: " + err.Error())
+	}
+	reverseRegistry[name] = factory
+}
+
+// NewReverse creates the reverse DNS provider of the metal provider, see [ErrReverseUnsupported]
+func NewReverse(infra schema.Infrastructure, opts Options) (ReverseDNSProvider, error) {
+	registryMu.RLock()
+	factory, ok := reverseRegistry[infra.MetalProvider]
+	if simulatedReverse != nil && infra.MetalProvider != "" && infra.MetalProvider != ProviderManual {
+		factory, ok = simulatedReverse, true
+	}
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrReverseUnsupported, infra.MetalProvider)
+	}
+	return factory(infra, opts)
+}
+
+// Simulate makes [New] and [NewReverse] create the providers of the factories instead of the registered
+// ones, so the workflows run without real DNS services. The manual providers stay unsupported. Nil
+// factories restore the registered providers.
+func Simulate(factory Factory, reverse ReverseFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	simulated, simulatedReverse = factory, reverse
+}
+
+// Plugin is implemented by the launchr plugins contributing DNS providers. Their providers are
+// registered when the application is initialized, so other DNS services are supported out of tree.
+type Plugin interface {
+	launchr.Plugin
+	// DNSProviders returns the factories of the contributed providers by name
+	DNSProviders() map[string]Factory
+}
+
+// ReversePlugin is implemented by the launchr plugins contributing the reverse DNS of metal providers,
+// usually the plugins of the metal providers
+type ReversePlugin interface {
+	launchr.Plugin
+	// ReverseDNSProviders returns the factories of the contributed providers by metal provider name
+	ReverseDNSProviders() map[string]ReverseFactory
+}
+
+// RegisterPlugin registers the DNS and reverse DNS providers of a plugin implementing [Plugin],
+// [ReversePlugin] or both. Unlike [Register] it returns an error if a name is reserved or already
+// registered, and registers none of the providers of the plugin then.
+func RegisterPlugin(p launchr.Plugin) error {
+	var providers map[string]Factory
+	var reverse map[string]ReverseFactory
+	if dp, ok := p.(Plugin); ok {
+		providers = dp.DNSProviders()
+	}
+	if rp, ok := p.(ReversePlugin); ok {
+		reverse = rp.ReverseDNSProviders()
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for name, factory := range providers {
+		if err := checkRegister(registry, "DNS", name, factory); err != nil {
+			return err
+		}
+	}
+	for name, factory := range reverse {
+		if err := checkRegister(reverseRegistry, "reverse DNS", name, factory); err != nil {
+			return err
+		}
+	}
+	maps.Copy(registry, providers)
+	maps.Copy(reverseRegistry, reverse)
+	return nil
+}
@@ -0,0 +1,202 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DoHProviders are the DNS-over-HTTPS endpoints usable by name
+var DoHProviders = map[string]string{
+	"cloudflare": "https://cloudflare-dns.com/dns-query",
+	"google":     "https://dns.google/dns-query",
+	"quad9":      "https://dns.quad9.net/dns-query",
+}
+
+// Server is the DNS server answering lookups, the system resolver when empty. Split-DNS setups may
+// answer differently than the public DNS, so checks can run against a chosen public resolver.
+type Server struct {
+	Addr string // Address of a DNS server as ip:port
+	DoH  string // URL of a DNS-over-HTTPS endpoint
+}
+
+// NewServer returns the server of a resolver address (ip or ip:port) or of a DNS-over-HTTPS endpoint
+// (URL or name of [DoHProviders]), the system resolver when both are empty
+func NewServer(addr, doh string) (Server, error) {
+	if addr != "" && doh != "" {
+		return Server{}, errors.New("a resolver and a DNS-over-HTTPS endpoint can't be used together")
+	}
+	if addr != "" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, "53"
+		}
+		if net.ParseIP(host) == nil {
+			return Server{}, fmt.Errorf("invalid resolver %q, expected ip or ip:port", addr)
+		}
+		addr = net.JoinHostPort(host, port)
+	}
+	if doh != "" {
+		if endpoint, ok := DoHProviders[doh]; ok {
+			doh = endpoint
+		}
+		u, err := url.Parse(doh)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return Server{}, fmt.Errorf("invalid DNS-over-HTTPS endpoint %q, expected an https URL", doh)
+		}
+	}
+	return Server{Addr: addr, DoH: doh}, nil
+}
+
+// IsSystem reports whether lookups use the system resolver
+func (s Server) IsSystem() bool {
+	return s.Addr == "" && s.DoH == ""
+}
+
+// String returns the server for messages
+func (s Server) String() string {
+	switch {
+	case s.DoH != "":
+		return s.DoH
+	case s.Addr != "":
+		return s.Addr
+	default:
+		return "system resolver"
+	}
+}
+
+// Resolver returns a resolver sending lookups to the server
+func (s Server) Resolver() *net.Resolver {
+	switch {
+	case s.DoH != "":
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return &dohConn{ctx: ctx, url: s.DoH}, nil
+			},
+		}
+	case s.Addr != "":
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, s.Addr)
+			},
+		}
+	default:
+		return net.DefaultResolver
+	}
+}
+
+// exchange sends a DNS message and returns the response, over HTTPS or over TCP, which doesn't truncate
+// large answers. The system resolver can't be queried directly, the validating resolver is used instead.
+func (s Server) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if s.DoH != "" {
+		return dohExchange(ctx, s.DoH, msg)
+	}
+
+	addr := s.Addr
+	if addr == "" {
+		addr = net.JoinHostPort(ValidatingResolver, "53")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+	if _, err = conn.Write(append(framed, msg...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err = io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	_, err = io.ReadFull(conn, resp)
+	return resp, err
+}
+
+// dohExchange posts a DNS message to a DNS-over-HTTPS endpoint and returns the response
+func dohExchange(ctx context.Context, endpoint string, msg []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS endpoint %s returned status %s", endpoint, res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 64*1024))
+}
+
+// dohConn is a connection of the Go resolver to a DNS-over-HTTPS endpoint. Not being a packet
+// connection, the resolver writes a length-prefixed message as over TCP, and reads the response
+// framed the same way once it's posted.
+type dohConn struct {
+	ctx  context.Context
+	url  string
+	req  bytes.Buffer
+	resp *bytes.Reader
+}
+
+// Read implements net.Conn interface
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.resp == nil {
+		data := c.req.Bytes()
+		if len(data) < 2 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		msg, err := dohExchange(c.ctx, c.url, data[2:])
+		if err != nil {
+			return 0, err
+		}
+		c.resp = bytes.NewReader(append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...))
+	}
+	return c.resp.Read(b)
+}
+
+// Write implements net.Conn interface
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.req.Write(b)
+}
+
+// Close implements net.Conn interface
+func (c *dohConn) Close() error { return nil }
+
+// LocalAddr implements net.Conn interface
+func (c *dohConn) LocalAddr() net.Addr { return &net.TCPAddr{} }
+
+// RemoteAddr implements net.Conn interface
+func (c *dohConn) RemoteAddr() net.Addr { return &net.TCPAddr{} }
+
+// SetDeadline implements net.Conn interface, the context of the lookup bounds the request
+func (c *dohConn) SetDeadline(time.Time) error { return nil }
+
+// SetReadDeadline implements net.Conn interface
+func (c *dohConn) SetReadDeadline(time.Time) error { return nil }
+
+// SetWriteDeadline implements net.Conn interface
+func (c *dohConn) SetWriteDeadline(time.Time) error { return nil }
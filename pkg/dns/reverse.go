@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ReverseDNSProvider manages the PTR records of the addresses owned by a metal provider.
+// Records are of type PTR, named after their address and holding the host name as content.
+type ReverseDNSProvider interface {
+	// PlanReverse returns the changes making the PTR records of the addresses match the desired ones
+	PlanReverse(ctx context.Context, records []Record) ([]Change, error)
+	// EnsureReverse sets the PTR records of the addresses to match the desired ones, and returns the changes
+	EnsureReverse(ctx context.Context, records []Record) ([]Change, error)
+}
+
+// PTRRecords returns the reverse DNS records of the platform addresses: the ones of dns.reverse,
+// and the addresses of the mail servers named after their MX host
+func PTRRecords(cfg schema.DNSConfig) ([]Record, error) {
+	records, err := Records(cfg)
+	if err != nil {
+		return nil, err
+	}
+	domain := strings.TrimSuffix(strings.ToLower(cfg.Domain), ".")
+
+	hosts := make(map[string]bool)
+	for _, r := range records {
+		if r.Type == "MX" {
+			hosts[r.Content] = true
+		}
+	}
+	names := make(map[string]string)
+	for _, r := range records {
+		if (r.Type == "A" || r.Type == "AAAA") && hosts[r.Name] {
+			if _, ok := names[r.Content]; !ok {
+				names[r.Content] = r.Name
+			}
+		}
+	}
+	for addr, name := range cfg.Reverse {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q of dns.reverse", addr)
+		}
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if name == "" {
+			return nil, fmt.Errorf("dns.reverse name of %s is empty", addr)
+		}
+		if !strings.Contains(name, ".") {
+			name += "." + domain
+		}
+		names[ip.String()] = name
+	}
+
+	ptrs := make([]Record, 0, len(names))
+	for _, addr := range slices.Sorted(maps.Keys(names)) {
+		ptrs = append(ptrs, Record{Type: "PTR", Name: addr, Content: names[addr]})
+	}
+	return ptrs, nil
+}
+
+// CheckReverse looks up the PTR records of the addresses in the public DNS and returns the changes
+// they need to match the desired ones
+func CheckReverse(ctx context.Context, records []Record) []Change {
+	return CheckReverseWith(ctx, net.DefaultResolver, records)
+}
+
+// CheckReverseWith is [CheckReverse] querying the given resolver
+func CheckReverseWith(ctx context.Context, resolver *net.Resolver, records []Record) []Change {
+	var changes []Change
+	for _, r := range records {
+		names, _ := resolver.LookupAddr(ctx, r.Name)
+		found := slices.ContainsFunc(names, func(n string) bool {
+			return r.SameContent(Record{Type: "PTR", Content: n})
+		})
+		switch {
+		case found:
+		case len(names) == 0:
+			changes = append(changes, Change{Action: ChangeCreate, Record: r})
+		default:
+			old := Record{Type: "PTR", Name: r.Name, Content: strings.TrimSuffix(names[0], ".")}
+			changes = append(changes, Change{Action: ChangeUpdate, Record: r, Old: old})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,214 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Results of SPF checks, RFC 7208
+const (
+	SPFPass     = "pass"
+	SPFFail     = "fail"
+	SPFSoftFail = "softfail"
+	SPFNeutral  = "neutral"
+	SPFNone     = "none" // The domain has no SPF record
+)
+
+// spfMaxLookups is the limit of mechanisms and modifiers doing DNS lookups in an SPF evaluation
+const spfMaxLookups = 10
+
+// ErrSPFLookups is returned when an SPF evaluation exceeds the limit of DNS lookups
+var ErrSPFLookups = errors.New("SPF record needs more than 10 DNS lookups")
+
+// CheckSPF evaluates the SPF record of the domain for a sender address, following include and
+// redirect. Macros and the deprecated ptr mechanism aren't supported: macros fail the check, ptr never
+// matches.
+func CheckSPF(ctx context.Context, resolver *net.Resolver, domain string, ip net.IP) (string, error) {
+	e := &spfEval{resolver: resolver, ip: ip}
+	return e.check(ctx, strings.TrimSuffix(domain, "."))
+}
+
+// spfEval holds the state of an SPF evaluation
+type spfEval struct {
+	resolver *net.Resolver
+	ip       net.IP
+	lookups  int
+}
+
+// check returns the result of the SPF record of a domain
+func (e *spfEval) check(ctx context.Context, domain string) (string, error) {
+	record, err := e.record(ctx, domain)
+	if err != nil || record == "" {
+		return SPFNone, err
+	}
+
+	redirect := ""
+	for _, term := range strings.Fields(record)[1:] {
+		lower := strings.ToLower(term)
+		if target, ok := strings.CutPrefix(lower, "redirect="); ok {
+			redirect = target
+			continue
+		}
+		if strings.Contains(lower, "=") {
+			continue // Other modifiers, e.g. exp=
+		}
+
+		qualifier := SPFPass
+		switch lower[0] {
+		case '+':
+			lower = lower[1:]
+		case '-':
+			qualifier, lower = SPFFail, lower[1:]
+		case '~':
+			qualifier, lower = SPFSoftFail, lower[1:]
+		case '?':
+			qualifier, lower = SPFNeutral, lower[1:]
+		}
+		match, err := e.match(ctx, domain, lower)
+		if err != nil {
+			return "", err
+		}
+		if match {
+			return qualifier, nil
+		}
+	}
+	if redirect != "" {
+		if err = e.count(); err != nil {
+			return "", err
+		}
+		return e.check(ctx, redirect)
+	}
+	return SPFNeutral, nil
+}
+
+// record returns the SPF record of a domain, empty when it has none
+func (e *spfEval) record(ctx context.Context, domain string) (string, error) {
+	txts, err := e.resolver.LookupTXT(ctx, domain)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up SPF record of %s: %w", domain, err)
+	}
+	for _, txt := range txts {
+		if lower := strings.ToLower(txt); lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			return txt, nil
+		}
+	}
+	return "", nil
+}
+
+// match reports whether a mechanism matches the sender address
+func (e *spfEval) match(ctx context.Context, domain, mechanism string) (bool, error) {
+	if strings.Contains(mechanism, "%{") {
+		return false, fmt.Errorf("SPF macros aren't supported: %s", mechanism)
+	}
+	name, arg, _ := strings.Cut(mechanism, ":")
+	// a and mx take the prefix lengths of their addresses after a slash, a/24 or a:host/24//64
+	prefix4, prefix6 := 32, 128
+	if name == "a" || name == "mx" || strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "mx/") {
+		var cidr string
+		if arg != "" {
+			arg, cidr, _ = strings.Cut(arg, "/")
+		} else {
+			name, cidr, _ = strings.Cut(name, "/")
+		}
+		if cidr != "" {
+			v4, v6, _ := strings.Cut(cidr, "//")
+			if v4 == "" && strings.HasPrefix(cidr, "/") {
+				v6 = strings.TrimPrefix(cidr, "/")
+			}
+			if n, err := strconv.Atoi(v4); err == nil {
+				prefix4 = n
+			}
+			if n, err := strconv.Atoi(v6); err == nil {
+				prefix6 = n
+			}
+		}
+	}
+	target := domain
+	if arg != "" {
+		target = arg
+	}
+
+	switch name {
+	case "all":
+		return true, nil
+	case "ip4", "ip6":
+		if !strings.Contains(arg, "/") {
+			return e.ip.Equal(net.ParseIP(arg)), nil
+		}
+		_, network, err := net.ParseCIDR(arg)
+		if err != nil {
+			return false, fmt.Errorf("invalid SPF mechanism %s: %w", mechanism, err)
+		}
+		return network.Contains(e.ip), nil
+	case "a":
+		if err := e.count(); err != nil {
+			return false, err
+		}
+		return e.matchHost(ctx, target, prefix4, prefix6)
+	case "mx":
+		if err := e.count(); err != nil {
+			return false, err
+		}
+		mxs, err := e.resolver.LookupMX(ctx, target)
+		if err != nil {
+			return false, nil
+		}
+		for _, mx := range mxs {
+			if ok, err := e.matchHost(ctx, mx.Host, prefix4, prefix6); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	case "include":
+		if err := e.count(); err != nil {
+			return false, err
+		}
+		result, err := e.check(ctx, target)
+		return result == SPFPass, err
+	case "exists":
+		if err := e.count(); err != nil {
+			return false, err
+		}
+		addrs, err := e.resolver.LookupIPAddr(ctx, target)
+		return err == nil && len(addrs) > 0, nil
+	case "ptr":
+		return false, e.count()
+	}
+	return false, fmt.Errorf("unknown SPF mechanism %s", mechanism)
+}
+
+// matchHost reports whether an address of the host, with the prefix lengths, holds the sender address
+func (e *spfEval) matchHost(ctx context.Context, host string, prefix4, prefix6 int) (bool, error) {
+	addrs, err := e.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false, nil
+	}
+	for _, a := range addrs {
+		bits, prefix := 128, prefix6
+		if a.IP.To4() != nil {
+			bits, prefix = 32, prefix4
+		}
+		network := &net.IPNet{IP: a.IP.Mask(net.CIDRMask(prefix, bits)), Mask: net.CIDRMask(prefix, bits)}
+		if network.Contains(e.ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// count counts a DNS lookup of the evaluation
+func (e *spfEval) count() error {
+	e.lookups++
+	if e.lookups > spfMaxLookups {
+		return ErrSPFLookups
+	}
+	return nil
+}
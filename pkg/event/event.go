@@ -0,0 +1,107 @@
+// Package event is the bus of the platform lifecycle events, e.g. a deployment starting or finishing.
+// The actions publish their events on the [Default] bus, other plugins and the tools embedding the
+// platform operations [Subscribe] to them for audit or automation, and the sinks of the config forward
+// them to webhooks and files.
+package event
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Type is the type of an event
+type Type string
+
+// Types of the events published by the actions
+const (
+	PlatformCreated  Type = "platform.created"  // platform:create wrote platform.yaml
+	DeployStarted    Type = "deploy.started"    // platform:deploy starts the playbook, check mode runs aren't deployments
+	DeployFinished   Type = "deploy.finished"   // The playbook of a deployment ended, successfully or not
+	ConfigChanged    Type = "config.changed"    // Configuration files of a platform were changed, e.g. by platform:nodes:sync
+	DestroyCompleted Type = "destroy.completed" // platform:destroy removed the platform
+)
+
+// Types returns the types of the events published by the actions
+func Types() []Type {
+	return []Type{PlatformCreated, DeployStarted, DeployFinished, ConfigChanged, DestroyCompleted}
+}
+
+// Event is a lifecycle event of a platform
+type Event struct {
+	Type     Type           `json:"type"`
+	Platform string         `json:"platform"`
+	Time     time.Time      `json:"time"`
+	Data     map[string]any `json:"data,omitempty"` // Details of the event type, e.g. the result of a deployment
+}
+
+// Handler handles the events of a subscription. Handlers run synchronously in the action publishing
+// the event, the slow ones should hand the event over to a goroutine.
+type Handler func(Event)
+
+// subscription is a handler of the bus and the types of events it receives, all if empty
+type subscription struct {
+	id      int
+	handler Handler
+	types   []Type
+}
+
+// Bus dispatches the published events to the handlers subscribed to their type
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID int
+}
+
+// NewBus creates a bus without subscriptions
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe calls the handler with the published events of the types, or of any type without types.
+// The returned function cancels the subscription.
+func (b *Bus) Subscribe(h Handler, types ...Type) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, handler: h, types: types})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subs = slices.DeleteFunc(b.subs, func(s subscription) bool { return s.id == id })
+	}
+}
+
+// Publish calls the handlers subscribed to the type of the event in the order of their subscription.
+// The time of the event is set to now if it's zero.
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b.mu.RLock()
+	subs := slices.Clone(b.subs)
+	b.mu.RUnlock()
+	for _, s := range subs {
+		if len(s.types) == 0 || slices.Contains(s.types, e.Type) {
+			s.handler(e)
+		}
+	}
+}
+
+var defaultBus = NewBus()
+
+// Default returns the bus the actions publish their events on
+func Default() *Bus {
+	return defaultBus
+}
+
+// Subscribe subscribes the handler to the events of the [Default] bus, see [Bus.Subscribe]
+func Subscribe(h Handler, types ...Type) (unsubscribe func()) {
+	return defaultBus.Subscribe(h, types...)
+}
+
+// Publish publishes an event on the [Default] bus
+func Publish(e Event) {
+	defaultBus.Publish(e)
+}
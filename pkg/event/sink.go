@@ -0,0 +1,158 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+)
+
+// ConfigKey is the key of the events section in the launchr config
+const ConfigKey = "events"
+
+// Types of the sinks
+const (
+	SinkWebhook = "webhook" // Events are posted as JSON to a URL
+	SinkFile    = "file"    // Events are appended to a file as JSON lines
+)
+
+// Config is the events section of the launchr config
+type Config struct {
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig configures a sink the events are forwarded to
+type SinkConfig struct {
+	Type    string            `yaml:"type"`    // webhook or file
+	URL     string            `yaml:"url"`     // URL of a webhook
+	Headers map[string]string `yaml:"headers"` // Headers of the webhook requests, ${VAR} are expanded from the environment
+	Path    string            `yaml:"path"`    // File of a file sink, relative to the repository root
+	Events  []Type            `yaml:"events"`  // Types of the events forwarded, all if empty
+}
+
+// Sink forwards the events outside of the process
+type Sink interface {
+	// Send forwards an event
+	Send(e Event) error
+}
+
+// LoadConfig reads the events section of the launchr config and checks its sinks
+func LoadConfig(cfg launchr.Config) (Config, error) {
+	var c Config
+	if cfg != nil {
+		if err := cfg.Get(ConfigKey, &c); err != nil {
+			return c, fmt.Errorf("failed to read %s config: %w", ConfigKey, err)
+		}
+	}
+	for i, s := range c.Sinks {
+		if err := s.Validate(); err != nil {
+			return c, fmt.Errorf("%s.sinks[%d]: %w", ConfigKey, i, err)
+		}
+	}
+	return c, nil
+}
+
+// Validate checks the config of the sink
+func (c SinkConfig) Validate() error {
+	switch c.Type {
+	case SinkWebhook:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook sink needs an http or https url, got %q", c.URL)
+		}
+	case SinkFile:
+		if c.Path == "" {
+			return errors.New("file sink needs a path")
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (use %s or %s)", c.Type, SinkWebhook, SinkFile)
+	}
+	for _, t := range c.Events {
+		if !slices.Contains(Types(), t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+// NewSink creates the sink of the config
+func NewSink(c SinkConfig) (Sink, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Type == SinkFile {
+		return &fileSink{path: c.Path}, nil
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	for k, v := range c.Headers {
+		header.Set(k, os.ExpandEnv(v))
+	}
+	return &webhookSink{url: c.URL, header: header}, nil
+}
+
+// AddSinks subscribes the sinks of the config to the events of the bus. The events a sink fails to
+// forward are logged, the actions publishing them keep running.
+func (b *Bus) AddSinks(cfg Config, log *launchr.Logger) error {
+	for _, c := range cfg.Sinks {
+		sink, err := NewSink(c)
+		if err != nil {
+			return err
+		}
+		b.Subscribe(func(e Event) {
+			if err := sink.Send(e); err != nil {
+				log.Warn("failed to forward the event", "sink", c.Type, "type", e.Type, "platform", e.Platform, "error", err)
+			}
+		}, c.Events...)
+	}
+	return nil
+}
+
+// webhookSink posts the events as JSON to a URL
+type webhookSink struct {
+	url    string
+	header http.Header
+}
+
+func (s *webhookSink) Send(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return api.Request(context.Background(), http.MethodPost, s.url, s.header, body, nil)
+}
+
+// fileSink appends the events to a file as JSON lines
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSink) Send(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory of %s: %w", s.path, err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
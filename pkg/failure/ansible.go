@@ -0,0 +1,61 @@
+package failure
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostRecap is the line of a host in the PLAY RECAP of ansible-playbook
+type HostRecap struct {
+	Host        string `json:"host"`
+	OK          int    `json:"ok"`
+	Changed     int    `json:"changed"`
+	Unreachable int    `json:"unreachable"`
+	Failed      int    `json:"failed"`
+	Skipped     int    `json:"skipped"`
+	Rescued     int    `json:"rescued"`
+	Ignored     int    `json:"ignored"`
+}
+
+// AnsibleError is a failed run of ansible-playbook, it matches [ErrAnsibleFailed]
+type AnsibleError struct {
+	ExitCode int
+	Recap    []HostRecap // Hosts of the recap, empty if the playbook failed before running its plays
+}
+
+// Error implements error interface
+func (e *AnsibleError) Error() string {
+	msg := fmt.Sprintf("ansible-playbook failed with exit code %d", e.ExitCode)
+	var failed, unreachable []string
+	for _, h := range e.Recap {
+		if h.Failed > 0 {
+			failed = append(failed, h.Host)
+		}
+		if h.Unreachable > 0 {
+			unreachable = append(unreachable, h.Host)
+		}
+	}
+	if len(failed) > 0 {
+		msg += ", failed on " + strings.Join(failed, ", ")
+	}
+	if len(unreachable) > 0 {
+		msg += ", unreachable " + strings.Join(unreachable, ", ")
+	}
+	return msg
+}
+
+// Is reports whether the target is [ErrAnsibleFailed]
+func (e *AnsibleError) Is(target error) bool {
+	return target == ErrAnsibleFailed
+}
+
+// FailedHosts returns the hosts of the recap with failed or unreachable tasks
+func (e *AnsibleError) FailedHosts() []string {
+	var hosts []string
+	for _, h := range e.Recap {
+		if h.Failed > 0 || h.Unreachable > 0 {
+			hosts = append(hosts, h.Host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,131 @@
+// Package failure classifies the failures of the platform operations, so wrappers and scripts can
+// branch on their class instead of parsing messages. Every class has a stable code, the exit code of
+// the actions failing with it and a hint to fix the failure. The errors of a class match it with
+// errors.Is, and carry their details as typed errors like [AnsibleError] and [PipelineError].
+package failure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code identifies a class of failures, it's stable across versions
+type Code string
+
+// Codes of the classes of failures
+const (
+	CodePlatformNotFound Code = "platform_not_found"
+	CodeConfigMissing    Code = "config_missing"
+	CodeProviderAuth     Code = "provider_auth"
+	CodeAnsibleFailed    Code = "ansible_failed"
+	CodePipelineFailed   Code = "pipeline_failed"
+)
+
+// Class is a class of failures, matched with errors.Is
+type Class struct {
+	Code     Code
+	ExitCode int    // Exit code of the actions failing with the class
+	Hint     string // Default hint to fix the failures of the class
+
+	msg string
+}
+
+// Error implements error interface
+func (c *Class) Error() string {
+	return c.msg
+}
+
+// Classes of failures
+var (
+	ErrPlatformNotFound = &Class{
+		Code: CodePlatformNotFound, ExitCode: 3, msg: "platform not found",
+		Hint: "check the name with platform:list, or create the platform with platform:create",
+	}
+	ErrConfigMissing = &Class{
+		Code: CodeConfigMissing, ExitCode: 4, msg: "configuration is missing",
+		Hint: "pass the value as option, or set it in the config or the keyring",
+	}
+	ErrProviderAuth = &Class{
+		Code: CodeProviderAuth, ExitCode: 5, msg: "provider rejected the credentials",
+		Hint: "check the credentials with platform:provider:check and replace them with keyring:set",
+	}
+	ErrAnsibleFailed = &Class{
+		Code: CodeAnsibleFailed, ExitCode: 6, msg: "ansible-playbook failed",
+		Hint: "check the failed tasks of the hosts in the recap, run again with --debug for their details",
+	}
+	ErrPipelineFailed = &Class{
+		Code: CodePipelineFailed, ExitCode: 7, msg: "CI pipeline failed",
+		Hint: "open the URL of the pipeline for the logs of its jobs",
+	}
+)
+
+// classes are the classes looked up by [Classify], in their order of precedence
+var classes = []*Class{ErrPlatformNotFound, ErrConfigMissing, ErrProviderAuth, ErrAnsibleFailed, ErrPipelineFailed}
+
+// Error is a failure of a class, with a hint specific to it
+type Error struct {
+	Class *Class
+	Err   error  // Cause of the failure, its message is the one of the error
+	Hint  string // Hint to fix the failure, the one of the class if empty
+}
+
+// Wrap returns the error as a failure of the class, nil if err is nil. The hint replaces the one of
+// the class if it isn't empty.
+func Wrap(class *Class, err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, Err: err, Hint: hint}
+}
+
+// Newf returns a failure of the class with the formatted message, which wraps the errors of %w verbs
+func Newf(class *Class, format string, args ...any) error {
+	return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// Error implements error interface
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause and the class of the failure
+func (e *Error) Unwrap() []error {
+	return []error{e.Err, e.Class}
+}
+
+// Classify returns the class of the failure, nil for unclassified errors
+func Classify(err error) *Class {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	for _, c := range classes {
+		if errors.Is(err, c) {
+			return c
+		}
+	}
+	return nil
+}
+
+// CodeOf returns the code of the class of the failure, empty for unclassified errors
+func CodeOf(err error) Code {
+	if c := Classify(err); c != nil {
+		return c.Code
+	}
+	return ""
+}
+
+// HintOf returns the hint to fix the failure, empty for unclassified errors
+func HintOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Hint != "" {
+		return e.Hint
+	}
+	if c := Classify(err); c != nil {
+		return c.Hint
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package failure
+
+import "fmt"
+
+// PipelineError is a CI job or workflow run which didn't succeed, it matches [ErrPipelineFailed]
+type PipelineError struct {
+	Job    string // Job or workflow run which failed, e.g. platform:deploy job
+	Status string // Final status reported by the CI service, e.g. failed or canceled
+	URL    string // Web page of the job or run, empty if unknown
+}
+
+// Error implements error interface
+func (e *PipelineError) Error() string {
+	msg := fmt.Sprintf("%s finished with status %s", e.Job, e.Status)
+	if e.URL != "" {
+		msg += ": " + e.URL
+	}
+	return msg
+}
+
+// Is reports whether the target is [ErrPipelineFailed]
+func (e *PipelineError) Is(target error) bool {
+	return target == ErrPipelineFailed
+}
@@ -0,0 +1,235 @@
+// Package lock provides the advisory locks of the platform directories, so concurrent runs of the
+// plugin don't interleave their changes of platform.yaml, the node files and the configuration. A lock
+// is a file created exclusively, holding the process which owns it.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// FileName is the name of the lock files, in the platform directories and their Terraform workspace
+const FileName = ".lock"
+
+// ExcludePathspec excludes the lock files of the platforms from git commands, e.g. from the commits
+const ExcludePathspec = ":(top,exclude,glob)" + schema.InstDir + "/**/" + FileName
+
+// DefaultTimeout is the time a lock held by another process is waited for
+const DefaultTimeout = 30 * time.Second
+
+// EnvVarTimeout overrides the timeout of the locks, e.g. PLASMACTL_LOCK_TIMEOUT=5m
+const EnvVarTimeout = launchr.EnvVar("lock_timeout")
+
+// retryInterval is the interval of the attempts to acquire a lock held by another process
+const retryInterval = 200 * time.Millisecond
+
+// ErrLocked is wrapped by the errors of locks held by another process
+var ErrLocked = errors.New("locked")
+
+// Owner is the process holding a lock
+type Owner struct {
+	PID     int       `json:"pid"`
+	User    string    `json:"user,omitempty"`
+	Host    string    `json:"host,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// LockedError is returned when another process holds a lock after the timeout
+type LockedError struct {
+	Path  string
+	Owner *Owner // Nil if the lock file can't be read
+}
+
+// Error implements error interface
+func (e *LockedError) Error() string {
+	if e.Owner == nil {
+		return fmt.Sprintf("%s is locked by another process, remove it if no plasmactl command is running", e.Path)
+	}
+	o := e.Owner
+	return fmt.Sprintf("%s is locked by PID %d of %s@%s since %s (%s), remove it if the process is gone",
+		e.Path, o.PID, o.User, o.Host, o.Time.Local().Format(time.DateTime), o.Command)
+}
+
+// Is reports whether the error matches ErrLocked
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Lock is a lock held by this process
+type Lock struct {
+	path string // Absolute path of the lock file, empty for a lock of nothing
+}
+
+// held counts the acquisitions of the locks held by this process by path, so the actions executed by
+// other actions, e.g. by platform:up, acquire the locks of their caller again
+var (
+	heldMu sync.Mutex
+	held   = make(map[string]int)
+)
+
+// Platform acquires the lock of the directory of the named platform. A platform without directory
+// has nothing to lock, its lock is released without effect.
+func Platform(ctx context.Context, name string) (*Lock, error) {
+	dir := schema.PlatformDir(name)
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return &Lock{}, nil
+	}
+	l, err := Acquire(ctx, filepath.Join(dir, FileName), Timeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock platform %q: %w", name, err)
+	}
+	return l, nil
+}
+
+// Timeout returns the timeout of the locks, overridden with PLASMACTL_LOCK_TIMEOUT
+func Timeout() time.Duration {
+	if d, err := time.ParseDuration(EnvVarTimeout.Get()); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultTimeout
+}
+
+// Acquire creates the lock file at path, waiting up to the timeout while another process holds it.
+// The locks of processes which exited without releasing them on this host are taken over.
+func Acquire(ctx context.Context, path string, timeout time.Duration) (*Lock, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if reacquire(abs) {
+		return &Lock{path: abs}, nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = create(abs)
+		if err == nil {
+			heldMu.Lock()
+			held[abs]++
+			heldMu.Unlock()
+			return &Lock{path: abs}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		owner := readOwner(abs)
+		if owner != nil && owner.stale() {
+			launchr.Log().Warn("taking over lock of exited process", "path", abs, "pid", owner.PID)
+			if err = os.Remove(abs); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, &LockedError{Path: path, Owner: owner}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// reacquire acquires a lock already held by this process again
+func reacquire(path string) bool {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if held[path] == 0 {
+		return false
+	}
+	held[path]++
+	return true
+}
+
+// Release releases the lock, the lock file is removed once all the acquisitions of this process are
+// released. It's safe to call on a nil lock.
+func (l *Lock) Release() error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if held[l.path] == 0 {
+		return nil
+	}
+	held[l.path]--
+	if held[l.path] > 0 {
+		return nil
+	}
+	delete(held, l.path)
+	// The directory of the lock may be gone, e.g. after platform:destroy
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to release lock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// create creates the lock file exclusively with the owner of this process
+func create(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(currentOwner())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+	return nil
+}
+
+// currentOwner returns the owner of the locks of this process
+func currentOwner() Owner {
+	o := Owner{PID: os.Getpid(), Time: time.Now().UTC()}
+	if u, err := user.Current(); err == nil {
+		o.User = u.Username
+	}
+	o.Host, _ = os.Hostname()
+	// The options aren't recorded, they may hold secrets like --password
+	if len(os.Args) > 0 {
+		o.Command = filepath.Base(os.Args[0])
+	}
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		o.Command += " " + os.Args[1]
+	}
+	return o
+}
+
+// readOwner returns the owner of a lock file, nil if it can't be read, e.g. while it's written
+func readOwner(path string) *Owner {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var o Owner
+	if err = json.Unmarshal(data, &o); err != nil || o.PID == 0 {
+		return nil
+	}
+	return &o
+}
+
+// stale reports whether the owner of a lock is a process of this host which exited
+func (o *Owner) stale() bool {
+	host, err := os.Hostname()
+	if err != nil || o.Host != host || o.PID == os.Getpid() {
+		return false
+	}
+	return !processAlive(o.PID)
+}
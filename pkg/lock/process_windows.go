@@ -0,0 +1,15 @@
+//go:build windows
+
+package lock
+
+import "os"
+
+// processAlive reports whether a process with the PID exists, finding a process opens it on Windows
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,119 @@
+// Package metrics collects the metrics of the platform operations, e.g. the duration of a deployment or
+// the size of a Platform Image, and sends them to Prometheus after each run. The operations [Record]
+// their metrics, [Flush] pushes them to the Pushgateway and writes them to the textfile collector file
+// of the metrics section of platform.yaml.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+)
+
+// Names of the metrics of the operations, they are gauges of the last run
+const (
+	DeployDuration     = "plasmactl_platform_deploy_duration_seconds"
+	ImageBuildDuration = "plasmactl_platform_image_build_duration_seconds"
+	ImageSize          = "plasmactl_platform_image_size_bytes"
+	ValidationFailures = "plasmactl_platform_validation_failures"
+	CIWaitDuration     = "plasmactl_platform_ci_wait_duration_seconds"
+)
+
+// help is the description of the metrics by name
+var help = map[string]string{
+	DeployDuration:     "Duration of the last deployment of the platform by result.",
+	ImageBuildDuration: "Duration of the last build of a Platform Image of the platform.",
+	ImageSize:          "Size of the last Platform Image built for the platform.",
+	ValidationFailures: "Failed checks of the last validation of the platform.",
+	CIWaitDuration:     "Time waited for the last CI pipeline deploying the platform by result.",
+}
+
+// Metric is a sample of a metric of a platform
+type Metric struct {
+	Name   string
+	Labels map[string]string // Labels besides the platform, e.g. the result of a deployment
+	Value  float64
+}
+
+// collector holds the metrics recorded since the last flush by platform
+var collector = struct {
+	mu      sync.Mutex
+	metrics map[string][]Metric
+}{metrics: map[string][]Metric{}}
+
+// Record records a metric of a platform, replacing the sample of the same name and labels. Metrics
+// without platform, e.g. of an image built without environment, aren't recorded.
+func Record(platform string, m Metric) {
+	if platform == "" {
+		return
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	recorded := slices.DeleteFunc(collector.metrics[platform], func(r Metric) bool {
+		return r.Name == m.Name && maps.Equal(r.Labels, m.Labels)
+	})
+	collector.metrics[platform] = append(recorded, m)
+}
+
+// Pending returns the metrics recorded since the last flush by platform
+func Pending() map[string][]Metric {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	pending := make(map[string][]Metric, len(collector.metrics))
+	for platform, metrics := range collector.metrics {
+		pending[platform] = slices.Clone(metrics)
+	}
+	return pending
+}
+
+// Flush sends the recorded metrics of each platform to the destinations of its metrics config, and
+// forgets them. The metrics of simulated runs are dropped, they must not reach the dashboards.
+func Flush(ctx context.Context) error {
+	collector.mu.Lock()
+	pending := collector.metrics
+	collector.metrics = map[string][]Metric{}
+	collector.mu.Unlock()
+	if simulate.Enabled() {
+		return nil
+	}
+
+	var errs []error
+	for _, platform := range slices.Sorted(maps.Keys(pending)) {
+		p, err := schema.LoadOptional(platform)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err = Send(ctx, platform, p.Metrics, pending[platform]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send the metrics of %s: %w", platform, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send sends the metrics of a platform to the Pushgateway and the textfile of the config, if set
+func Send(ctx context.Context, platform string, cfg schema.MetricsConfig, metrics []Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	var errs []error
+	if cfg.Pushgateway != "" {
+		errs = append(errs, push(ctx, cfg, platform, metrics))
+	}
+	if cfg.Textfile != "" {
+		errs = append(errs, writeTextfile(cfg.Textfile, platform, metrics))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	launchr.Log().Debug("sent the metrics", "platform", platform, "metrics", len(metrics))
+	return nil
+}
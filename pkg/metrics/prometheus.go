@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// DefaultJob is the job of the metrics pushed to the Pushgateway
+const DefaultJob = "plasmactl"
+
+// contentType is the content type of the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Encode writes the metrics of a platform in the Prometheus text exposition format, grouped in families
+// sorted by name
+func Encode(platform string, metrics []Metric) []byte {
+	families := map[string][]Metric{}
+	for _, m := range metrics {
+		families[m.Name] = append(families[m.Name], m)
+	}
+	var b bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(families)) {
+		if h := help[name]; h != "" {
+			fmt.Fprintf(&b, "# HELP %s %s\n", name, h)
+		}
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		for _, m := range families[name] {
+			fmt.Fprintf(&b, "%s{%s} %s\n", name, labels(platform, m.Labels), strconv.FormatFloat(m.Value, 'g', -1, 64))
+		}
+	}
+	return b.Bytes()
+}
+
+// labels returns the label pairs of a sample, the platform followed by the others sorted by name
+func labels(platform string, extra map[string]string) string {
+	pairs := []string{`platform="` + escape(platform) + `"`}
+	for _, k := range slices.Sorted(maps.Keys(extra)) {
+		pairs = append(pairs, k+`="`+escape(extra[k])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// escape escapes a label value of the text exposition format
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// push posts the metrics to the Pushgateway, grouped by job and platform. POST replaces the metrics of
+// the same names only, the ones of the other operations of the platform are kept.
+func push(ctx context.Context, cfg schema.MetricsConfig, platform string, metrics []Metric) error {
+	job := cfg.Job
+	if job == "" {
+		job = DefaultJob
+	}
+	u, err := url.Parse(cfg.Pushgateway)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("metrics.pushgateway needs an http or https url, got %q", cfg.Pushgateway)
+	}
+	u = u.JoinPath("metrics", "job", job, "platform", platform)
+	header := http.Header{"Content-Type": {contentType}}
+	return api.Request(ctx, http.MethodPost, u.String(), header, Encode(platform, metrics), nil)
+}
+
+// writeTextfile writes the metrics to the file read by the textfile collector of the node exporter. The
+// families of the other operations already in the file are kept, like the Pushgateway does.
+func writeTextfile(path, platform string, metrics []Metric) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var names []string
+	for _, m := range metrics {
+		names = append(names, m.Name)
+	}
+	data := append(keepFamilies(existing, names), Encode(platform, metrics)...)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the directory of %s: %w", path, err)
+	}
+	// The collector must never read a partially written file
+	return atomicfile.WriteFile(path, data, 0o644)
+}
+
+// keepFamilies returns the families of a text exposition without the ones of the names
+func keepFamilies(data []byte, names []string) []byte {
+	var b bytes.Buffer
+	keep := true
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if name, ok := familyName(line); ok {
+			keep = !slices.Contains(names, name)
+		}
+		if keep {
+			b.WriteString(line)
+		}
+	}
+	return b.Bytes()
+}
+
+// familyName returns the name of the family a HELP or TYPE line starts
+func familyName(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "#" || (fields[1] != "HELP" && fields[1] != "TYPE") {
+		return "", false
+	}
+	return fields[2], true
+}
@@ -0,0 +1,102 @@
+package platform
+
+import (
+	"context"
+	"errors"
+
+	"github.com/plasmash/plasmactl-platform/actions/deploy"
+	"github.com/plasmash/plasmactl-platform/actions/image"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
+)
+
+// Defaults of the build and deployment directories, relative to the repository root
+const (
+	DefaultPrepareDir = ".plasma/prepare" // Prepared platform, the source of the images
+	DefaultImageDir   = "img"             // Output directory of the images
+)
+
+// DeployOptions are the options of [Client.Deploy]
+type DeployOptions struct {
+	Name       string // Platform deployed
+	Tags       string // Ansible tags of the playbook run, e.g. core
+	Img        string // Platform Image deployed, the prepared directory if empty
+	BaseImg    string // Base of a delta Platform Image, looked up next to it if empty
+	PrepareDir string // Prepared platform deployed without Img, default is DefaultPrepareDir
+	Password   string // Vault password, read from the secrets backend if empty
+	Limit      string // Nodes or groups the deployment is limited to
+
+	Check         bool   // Dry run showing the planned changes, not recorded
+	PlanOutput    string // Format of the plan of a Check run printed on stdout, text (default) or json
+	SkipPreflight bool
+	Debug         bool
+	Logs          bool // Write the output of ansible-playbook to a log file
+
+	InventorySource string // cache (default) or nodes
+	PasswordMode    string // How the vault password is handed to ansible: auto (default), socket or script
+}
+
+// Deploy runs the playbook of a platform and returns the deployment, nil for a Check run. A failed
+// playbook returns its error and is recorded in the history of the platform.
+func (c *Client) Deploy(ctx context.Context, opts DeployOptions) (*Deployment, error) {
+	if err := requireName("deploy", opts.Name); err != nil {
+		return nil, err
+	}
+	if opts.Tags == "" {
+		return nil, errors.New("deploy needs the tags of the playbook run")
+	}
+	d := &deploy.Deploy{
+		Keyring:     c.keyring,
+		Environment: opts.Name,
+		Tags:        opts.Tags,
+		Img:         opts.Img,
+		BaseImg:     opts.BaseImg,
+		Debug:       opts.Debug,
+		Check:       opts.Check,
+		Password:    opts.Password,
+		Logs:        opts.Logs,
+		PrepareDir:  or(opts.PrepareDir, DefaultPrepareDir),
+		PlanOutput:  opts.PlanOutput,
+
+		InventorySource: opts.InventorySource,
+		Limit:           opts.Limit,
+		SkipPreflight:   opts.SkipPreflight,
+		PasswordMode:    opts.PasswordMode,
+	}
+	d.SetLogger(c.log)
+	d.SetTerm(c.term)
+	if err := d.Execute(ctx); err != nil {
+		return nil, err
+	}
+	return d.Deployment(), nil
+}
+
+// BuildImageOptions are the options of [Client.BuildImage]
+type BuildImageOptions struct {
+	Environment  string // Platform whose name template applies, none if empty
+	SourceDir    string // Default is DefaultPrepareDir
+	OutputDir    string // Default is DefaultImageDir
+	NameTemplate string // Default is the one of the platform, then of the repository config
+	Compression  string // gzip (default), zstd or none
+	Level        int    // Compression level, the algorithm default if zero
+	Base         string // Base image of a delta image, a full image if empty
+}
+
+// Image is a Platform Image built by [Client.BuildImage]
+type Image = result.ImageInfo
+
+// BuildImage packages the prepared platform as a Platform Image
+func (c *Client) BuildImage(ctx context.Context, opts BuildImageOptions) (*Image, error) {
+	p := &image.Package{
+		Config:       c.config,
+		Environment:  opts.Environment,
+		SourceDir:    or(opts.SourceDir, DefaultPrepareDir),
+		OutputDir:    or(opts.OutputDir, DefaultImageDir),
+		NameTemplate: opts.NameTemplate,
+		Compression:  opts.Compression,
+		Level:        opts.Level,
+		Base:         opts.Base,
+	}
+	p.SetLogger(c.log)
+	p.SetTerm(c.term)
+	return p.Build(ctx)
+}
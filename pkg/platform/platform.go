@@ -0,0 +1,244 @@
+// Package platform is the Go API of the platform management, for the tools and tests embedding it
+// without going through the launchr actions. The operations work on the platforms of the repository in
+// the current directory like the actions do, they return typed results and only print their progress
+// to the terminal of the [Client].
+package platform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/actions/create"
+	"github.com/plasmash/plasmactl-platform/actions/destroy"
+	"github.com/plasmash/plasmactl-platform/actions/list"
+	"github.com/plasmash/plasmactl-platform/actions/validate"
+	"github.com/plasmash/plasmactl-platform/internal/history"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ErrNotFound is returned for a platform without platform.yaml
+var ErrNotFound = schema.ErrNotFound
+
+// Report is the result of [Client.Validate], the validation failed if it isn't OK
+type Report = validate.Report
+
+// Check is a check of a [Report]
+type Check = validate.Check
+
+// Deployment is a deployment of a platform, as recorded in its history
+type Deployment = history.Deployment
+
+// Options configures a [Client]
+type Options struct {
+	Keyring keyring.Keyring   // Credentials of the providers, nil for the operations which don't need any
+	Config  launchr.Config    // Repository config, e.g. the name template of the images. Optional
+	Log     *launchr.Logger   // Default is launchr.Log()
+	Term    *launchr.Terminal // Progress of the operations, default is launchr.Term()
+}
+
+// Client runs the platform operations
+type Client struct {
+	keyring keyring.Keyring
+	config  launchr.Config
+	log     *launchr.Logger
+	term    *launchr.Terminal
+}
+
+// New creates a client of the platform operations
+func New(opts Options) *Client {
+	c := &Client{keyring: opts.Keyring, config: opts.Config, log: opts.Log, term: opts.Term}
+	if c.log == nil {
+		c.log = launchr.Log()
+	}
+	if c.term == nil {
+		c.term = launchr.Term()
+	}
+	return c
+}
+
+// CreateOptions are the options of [Client.Create]
+type CreateOptions struct {
+	Name          string
+	MetalProvider string // Default is manual
+	DNSProvider   string // Default is manual
+	Domain        string
+	SkipDNS       bool // Don't configure the DNS records of the provider
+
+	WaitPropagation    bool
+	PropagationTimeout time.Duration // Default is 10 minutes
+}
+
+// Create scaffolds a platform and configures its DNS records, and returns its configuration. A failed
+// DNS configuration is only reported on the terminal, like platform:create does.
+func (c *Client) Create(ctx context.Context, opts CreateOptions) (*schema.Platform, error) {
+	if err := requireName("create", opts.Name); err != nil {
+		return nil, err
+	}
+	timeout := 10 * time.Minute
+	if opts.PropagationTimeout > 0 {
+		timeout = opts.PropagationTimeout
+	}
+	a := &create.Create{
+		Keyring:            c.keyring,
+		Name:               opts.Name,
+		MetalProvider:      opts.MetalProvider,
+		DNSProvider:        opts.DNSProvider,
+		Domain:             opts.Domain,
+		SkipDNS:            opts.SkipDNS,
+		WaitPropagation:    opts.WaitPropagation,
+		PropagationTimeout: timeout.String(),
+	}
+	a.SetLogger(c.log)
+	a.SetTerm(c.term)
+	if err := a.Execute(ctx); err != nil {
+		return nil, err
+	}
+	return schema.Load(opts.Name)
+}
+
+// ListOptions are the options of [Client.List]
+type ListOptions struct {
+	Filter string   // Conditions the listed platforms match, e.g. provider=hetzner,nodes>3
+	Sort   string   // Key of the order of the platforms: name (default), nodes, domain or last-deploy
+	Roots  []string // Directories searched for repositories of platforms, the current repository by default
+}
+
+// List returns the platforms matching the filter, none if the repository has no inst/ directory
+func (c *Client) List(opts ListOptions) ([]schema.PlatformInfo, error) {
+	l := &list.List{Filter: opts.Filter, Sort: opts.Sort, Roots: opts.Roots}
+	l.SetLogger(c.log)
+	l.SetTerm(c.term)
+	return l.Platforms()
+}
+
+// Details are the configuration, the nodes and the last deployment of a platform
+type Details struct {
+	Platform   *schema.Platform
+	Nodes      []*schema.Node
+	LastDeploy *Deployment // Last deployment from this repository, nil if none
+}
+
+// Show returns the details of a platform, the error is [ErrNotFound] if it doesn't exist
+func (c *Client) Show(name string) (*Details, error) {
+	if err := requireName("show", name); err != nil {
+		return nil, err
+	}
+	platform, err := schema.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := schema.LoadNodes(name)
+	if err != nil {
+		return nil, err
+	}
+	last, err := history.Last("", name)
+	if err != nil {
+		return nil, err
+	}
+	return &Details{Platform: platform, Nodes: nodes, LastDeploy: last}, nil
+}
+
+// ValidateOptions are the options of [Client.Validate]
+type ValidateOptions struct {
+	Name      string
+	SkipDNS   bool
+	SkipMail  bool
+	SkipInfra bool // Don't compare the nodes with the servers of the metal provider
+
+	SkipMTASTS bool
+	SkipTLSRPT bool
+	SkipDNSSEC bool
+	SkipTLS    bool
+	DeepMail   bool // Connect to the MX hosts on port 25 and check SPF allows the mail servers
+
+	TLSExpiryDays int    // Certificates expiring within this number of days are reported, default is 21
+	Resolver      string // DNS server of the checks as ip:port, the system resolver by default
+	DoH           string // DNS-over-HTTPS endpoint of the checks, URL or provider name
+
+	Parallel     int           // Checks run at once, default is 4
+	CheckTimeout time.Duration // Timeout of each check, default is 30 seconds
+	Timeout      time.Duration // Timeout of all the checks, default is 2 minutes
+}
+
+// Validate checks the configuration, the DNS records, the mail authentication and the infrastructure
+// of a platform without printing the checks. The error only reports a validation which couldn't run,
+// the failed checks are in the report.
+func (c *Client) Validate(ctx context.Context, opts ValidateOptions) (Report, error) {
+	if err := requireName("validate", opts.Name); err != nil {
+		return Report{}, err
+	}
+	v := &validate.Validate{
+		Keyring:   c.keyring,
+		Name:      opts.Name,
+		SkipDNS:   opts.SkipDNS,
+		SkipMail:  opts.SkipMail,
+		SkipInfra: opts.SkipInfra,
+
+		SkipMTASTS: opts.SkipMTASTS,
+		SkipTLSRPT: opts.SkipTLSRPT,
+		SkipDNSSEC: opts.SkipDNSSEC,
+		SkipTLS:    opts.SkipTLS,
+		DeepMail:   opts.DeepMail,
+
+		TLSExpiryDays: opts.TLSExpiryDays,
+		Resolver:      opts.Resolver,
+		DoH:           opts.DoH,
+		Format:        output.JSON, // The structured output doesn't print the checks
+
+		Parallel:     opts.Parallel,
+		CheckTimeout: duration(opts.CheckTimeout),
+		Timeout:      duration(opts.Timeout),
+	}
+	v.SetLogger(c.log)
+	v.SetTerm(c.term)
+	return v.Run(ctx)
+}
+
+// DestroyOptions are the options of [Client.Destroy]
+type DestroyOptions struct {
+	Name    string
+	KeepDNS bool // Keep the DNS records and the reverse DNS of the addresses
+}
+
+// Destroy removes the DNS records and the directory of a platform without asking for confirmation, the
+// error is [ErrNotFound] if it doesn't exist
+func (c *Client) Destroy(ctx context.Context, opts DestroyOptions) error {
+	if err := requireName("destroy", opts.Name); err != nil {
+		return err
+	}
+	if _, err := schema.Load(opts.Name); err != nil {
+		return err
+	}
+	d := &destroy.Destroy{Keyring: c.keyring, Name: opts.Name, YesIAmSure: true, KeepDNS: opts.KeepDNS}
+	d.SetLogger(c.log)
+	d.SetTerm(c.term)
+	return d.Execute(ctx)
+}
+
+// duration formats an optional duration for the options of the actions, empty for their default
+func duration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// or returns v, or def if v is empty
+func or(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+// requireName checks the name of the platform of an operation is set
+func requireName(op, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s needs the name of a platform", op)
+	}
+	return nil
+}
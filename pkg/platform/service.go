@@ -0,0 +1,178 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/internal/api"
+	"github.com/plasmash/plasmactl-platform/internal/secrets"
+	"github.com/plasmash/plasmactl-platform/internal/userconfig"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// EnvVarPlatform selects the current platform, e.g. PLASMACTL_PLATFORM=ski-dev
+var EnvVarPlatform = launchr.EnvVar("platform")
+
+// currentKeys are the config keys of the platform the actions deploy to by default, in their order of
+// precedence
+var currentKeys = []string{"platform.up.environment", "platform.deploy.environment"}
+
+// PlatformConfigService is a [launchr.Service] resolving the platforms of the repository for the other
+// plugins, e.g. plasmactl-node and plasmactl-model, the way the platform actions do:
+//
+//	var platforms platform.PlatformConfigService
+//	app.GetService(&platforms)
+//	name, err := platforms.Current()
+type PlatformConfigService = *configService
+
+type configService struct {
+	keyring keyring.Keyring
+	config  launchr.Config
+}
+
+// NewPlatformConfigService creates the service resolving the platforms with the keyring and the
+// repository config
+func NewPlatformConfigService(k keyring.Keyring, cfg launchr.Config) PlatformConfigService {
+	return &configService{keyring: k, config: cfg}
+}
+
+// ServiceInfo implements [launchr.Service] interface
+func (s *configService) ServiceInfo() launchr.ServiceInfo {
+	return launchr.ServiceInfo{}
+}
+
+// ServiceCreate implements [launchr.ServiceCreate] interface, the service is created by the first
+// plugin getting it
+func (s *configService) ServiceCreate(svc *launchr.ServiceManager) launchr.Service {
+	var k keyring.Keyring
+	var cfg launchr.Config
+	svc.Get(&k)
+	svc.Get(&cfg)
+	return NewPlatformConfigService(k, cfg)
+}
+
+// Current returns the name of the current platform: $PLASMACTL_PLATFORM, the default environment of
+// platform:up and platform:deploy in the repository config or the user-wide config, or the only
+// platform of the repository
+func (s *configService) Current() (string, error) {
+	if name := EnvVarPlatform.Get(); name != "" {
+		return name, nil
+	}
+	if name, err := s.configured(); name != "" || err != nil {
+		return name, err
+	}
+	names, err := s.Platforms()
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 1 {
+		return names[0], nil
+	}
+	return "", failure.Newf(failure.ErrConfigMissing, "no current platform among %d platforms: set $%s or %s in config",
+		len(names), EnvVarPlatform, currentKeys[len(currentKeys)-1])
+}
+
+// configured returns the default environment of the actions in the config, empty if none
+func (s *configService) configured() (string, error) {
+	global, err := userconfig.Load()
+	if err != nil {
+		return "", err
+	}
+	for _, key := range currentKeys {
+		if s.config != nil && s.config.Exists(key) {
+			var name string
+			if err = s.config.Get(key, &name); err != nil {
+				return "", fmt.Errorf("failed to read config %s: %w", key, err)
+			}
+			if name != "" {
+				return name, nil
+			}
+		}
+	}
+	for _, key := range currentKeys {
+		v, _ := global.Lookup(key)
+		if name, _ := v.(string); name != "" {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// Platforms returns the sorted names of the platforms of the repository, none without inst/ directory
+func (s *configService) Platforms() ([]string, error) {
+	entries, err := os.ReadDir(schema.InstDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inst directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if _, err := os.Stat(schema.PlatformFile(e.Name())); e.IsDir() && err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Platform returns the platform.yaml of the named platform, the current one if the name is empty. The
+// error is [ErrNotFound] if it doesn't exist.
+func (s *configService) Platform(name string) (*schema.Platform, error) {
+	name, err := s.name(name)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Load(name)
+}
+
+// Config returns the configuration values of the named platform, the current one if the name is empty
+func (s *configService) Config(name string) (*schema.Config, error) {
+	name, err := s.name(name)
+	if err != nil {
+		return nil, err
+	}
+	return schema.LoadConfig(name)
+}
+
+// Secret returns a secret of the named platform from its backend, the keyring or a HashiCorp Vault,
+// the current platform if the name is empty
+func (s *configService) Secret(ctx context.Context, name, key string) (string, error) {
+	platform, err := s.Platform(name)
+	if err != nil {
+		return "", err
+	}
+	backend, err := secrets.New(platform, s.keyring, launchr.Log(), launchr.Term())
+	if err != nil {
+		return "", err
+	}
+	return backend.Get(ctx, key)
+}
+
+// Resolve returns a value of platform.yaml with its keyring reference resolved, e.g. the token of
+// {{ .keyring.scaleway_api_token }}. Values without reference are returned as they are.
+func (s *configService) Resolve(value string) (string, error) {
+	key, ok := api.KeyringKey(value)
+	if !ok {
+		return value, nil
+	}
+	if s.keyring == nil {
+		return "", fmt.Errorf("keyring is not available to resolve %s", value)
+	}
+	return api.Lookup(s.keyring, key)
+}
+
+// name returns the name of a platform, the current one if empty
+func (s *configService) name(name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	return s.Current()
+}
@@ -0,0 +1,34 @@
+package provider
+
+import "context"
+
+// Check is the result of the credential check of a metal or DNS provider
+type Check struct {
+	Account string   `json:"account,omitempty"` // Account, user or token the credentials belong to
+	Scopes  []string `json:"scopes,omitempty"`  // Permissions verified, as named by the provider
+	Missing []string `json:"missing,omitempty"` // Permissions needed by the platform actions but not granted
+	Quotas  []Quota  `json:"quotas,omitempty"`  // Limits of the account
+}
+
+// Quota is a limit of a provider account
+type Quota struct {
+	Resource string `json:"resource"` // Limited resource, e.g. servers or the offer of a chassis profile
+	Used     int    `json:"used"`
+	Limit    int    `json:"limit"` // Negative when unlimited or not reported
+}
+
+// Remaining returns the resources left, negative when unlimited
+func (q Quota) Remaining() int {
+	if q.Limit < 0 {
+		return -1
+	}
+	return max(q.Limit-q.Used, 0)
+}
+
+// Checker is implemented by providers able to verify their credentials and report the limits of their
+// account. Checks must not change anything at the provider. Providers without it are checked by
+// listing their servers or records.
+type Checker interface {
+	// Check verifies the credentials, the error is set when they are refused
+	Check(ctx context.Context) (Check, error)
+}
@@ -0,0 +1,112 @@
+// Package provider defines the interface of the metal providers hosting the servers of platforms.
+// Providers are registered by name and selected with infrastructure.metal_provider of platform.yaml,
+// so other plugins can add their own with [Register].
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// ProviderManual is the metal provider of servers managed by hand, it can't be registered
+const ProviderManual = schema.ProviderManual
+
+// ErrServerNotFound is returned when a server doesn't exist at the provider
+var ErrServerNotFound = errors.New("server not found")
+
+// Server is a server of a metal provider
+type Server struct {
+	ID        string // Provider ID of the server
+	Hostname  string // Host name set at the provider
+	Status    string // Power or provisioning state as reported by the provider, e.g. ON
+	Offer     string // Commercial offer, e.g. Start-2-S-SATA
+	Location  string // Datacenter of the server
+	Addresses []Address
+	Networks  []string // Routed networks in CIDR notation whose addresses can get a PTR, e.g. the IPv6 /64
+}
+
+// Address is an IP address of a server
+type Address struct {
+	IP      string
+	Private bool
+	Reverse string // PTR name of public addresses, without trailing dot
+}
+
+// PublicIPs returns the public addresses of the server
+func (s Server) PublicIPs() []string {
+	var ips []string
+	for _, a := range s.Addresses {
+		if !a.Private {
+			ips = append(ips, a.IP)
+		}
+	}
+	return ips
+}
+
+// HasIP reports whether the address belongs to the server or its networks, in any notation
+func (s Server) HasIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, a := range s.Addresses {
+		if ip.Equal(net.ParseIP(a.IP)) {
+			return true
+		}
+	}
+	for _, n := range s.Networks {
+		if _, ipNet, err := net.ParseCIDR(n); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// MetalProvider manages the servers of a metal provider account
+type MetalProvider interface {
+	// ListServers returns the servers of the account
+	ListServers(ctx context.Context) ([]Server, error)
+	// GetServer returns a server by ID, the error wraps [ErrServerNotFound] if it doesn't exist
+	GetServer(ctx context.Context, id string) (Server, error)
+	// Reboot restarts a server
+	Reboot(ctx context.Context, id string) error
+	// SetPTR sets the reverse DNS of a public address of the account, an empty name restores the
+	// default of the provider
+	SetPTR(ctx context.Context, addr, name string) error
+}
+
+// FindServer returns the server holding the address
+func FindServer(servers []Server, addr string) (Server, bool) {
+	for _, s := range servers {
+		if s.HasIP(addr) {
+			return s, true
+		}
+	}
+	return Server{}, false
+}
+
+// FindNode returns the node file of the server, matched by provider ID, then by hostname or public
+// address for nodes without provider ID
+func FindNode(nodes []*schema.Node, server Server) *schema.Node {
+	for _, n := range nodes {
+		if n.ProviderID != "" && n.ProviderID == server.ID {
+			return n
+		}
+	}
+	for _, n := range nodes {
+		if n.ProviderID == "" && n.Hostname != "" && strings.EqualFold(n.Hostname, server.Hostname) {
+			return n
+		}
+	}
+	for _, n := range nodes {
+		if n.ProviderID == "" && slices.ContainsFunc(n.PublicIPs, server.HasIP) {
+			return n
+		}
+	}
+	return nil
+}
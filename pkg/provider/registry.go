@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// Options are the services available to providers when they are created
+type Options struct {
+	Keyring keyring.Keyring   // Keyring holding the credentials of the provider API, may be nil
+	Log     *launchr.Logger   // Logger of the running action
+	Term    *launchr.Terminal // Terminal of the running action, e.g. to request missing credentials
+
+	Platform string // Platform namespacing the credentials of the keyring, only shared ones are read if empty
+}
+
+// Factory creates a provider of the infrastructure config of a platform
+type Factory func(infra schema.Infrastructure, opts Options) (MetalProvider, error)
+
+// ErrUnsupported is returned by [New] for metal providers without registered implementation, e.g. manual
+var ErrUnsupported = errors.New("metal provider is not supported")
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+	simulated  Factory // Factory replacing the registered ones in simulation, see [Simulate]
+)
+
+// Register makes a provider available under the name used in infrastructure.metal_provider of
+// platform.yaml. It's meant to be called from init functions and panics if the name is registered twice.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if err := checkRegister(name, factory); err != nil {
+		panic("provider: Register: " + err.Error())
+	}
+	registry[name] = factory
+}
+
+// checkRegister checks a provider can be registered under the name, the registry must be locked
+func checkRegister(name string, factory Factory) error {
+	if factory == nil {
+		return fmt.Errorf("factory of metal provider %q is nil", name)
+	}
+	if name == "" || name == ProviderManual {
+		return fmt.Errorf("metal provider name %q is reserved", name)
+	}
+	if _, dup := registry[name]; dup {
+		return fmt.Errorf("metal provider %q is already registered", name)
+	}
+	return nil
+}
+
+// Plugin is implemented by the launchr plugins contributing metal providers. Their providers are
+// registered when the application is initialized, so other clouds are supported out of tree.
+type Plugin interface {
+	launchr.Plugin
+	// MetalProviders returns the factories of the contributed providers by name
+	MetalProviders() map[string]Factory
+}
+
+// RegisterPlugin registers the providers of a plugin. Unlike [Register] it returns an error if a name
+// is reserved or already registered, and registers none of the providers of the plugin then.
+func RegisterPlugin(p Plugin) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	providers := p.MetalProviders()
+	for name, factory := range providers {
+		if err := checkRegister(name, factory); err != nil {
+			return err
+		}
+	}
+	maps.Copy(registry, providers)
+	return nil
+}
+
+// Providers returns the sorted names of the registered providers
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// New creates the provider selected in the infrastructure config, see [ErrUnsupported]
+func New(infra schema.Infrastructure, opts Options) (MetalProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[infra.MetalProvider]
+	if simulated != nil && infra.MetalProvider != "" && infra.MetalProvider != ProviderManual {
+		factory, ok = simulated, true
+	}
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, infra.MetalProvider)
+	}
+	return factory(infra, opts)
+}
+
+// Simulate makes [New] create the providers of the factory instead of the registered ones, so the
+// workflows run without real metal providers. The manual provider stays unsupported. A nil factory
+// restores the registered providers.
+func Simulate(factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	simulated = factory
+}
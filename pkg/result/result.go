@@ -0,0 +1,91 @@
+// Package result defines the typed results of the platform actions, so the callers composing them like
+// platform:up act on what an action did instead of parsing its output. A caller passes a [Collector]
+// in the context of the actions it executes, each action records its result in it.
+package result
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ValidationReport is the result of platform:validate
+type ValidationReport struct {
+	Platform string
+	OK       bool     // No check failed, warnings don't fail the validation
+	Errors   []string // Messages of the failed checks
+	Warnings []string
+}
+
+// DeployResult is the result of platform:deploy
+type DeployResult struct {
+	Platform string
+	Tags     string
+	Image    string // Platform Image deployed, empty for the prepared directory
+	Version  string // Version of the Platform Image
+	Check    bool   // Check mode run, nothing was changed
+	Changed  int    // Tasks which would change, in check mode
+	Hosts    []string
+	Duration time.Duration
+}
+
+// ImageInfo is the result of platform:package
+type ImageInfo struct {
+	Path        string
+	Version     string
+	Digest      string
+	Compression string
+	Files       int
+	Base        string // Version of the base of a delta image, empty for a full image
+	LayerFiles  int    // Files changed over the base of a delta image
+	Deleted     int    // Files of the base deleted by a delta image
+}
+
+// PlatformSummary is the result of platform:create and platform:show
+type PlatformSummary struct {
+	Name          string
+	Domain        string
+	MetalProvider string
+	DNSProvider   string
+	Nodes         int
+}
+
+// Collector collects the results of the actions executed with its context, the last one of each type
+type Collector struct {
+	mu      sync.Mutex
+	results map[reflect.Type]any
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	return &Collector{results: make(map[reflect.Type]any)}
+}
+
+type collectorKey struct{}
+
+// WithCollector returns a context whose actions record their results in the collector
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, c)
+}
+
+// Record records the result of an action in the collector of the context, if any. Nil results aren't
+// recorded.
+func Record[T any](ctx context.Context, r *T) {
+	c, ok := ctx.Value(collectorKey{}).(*Collector)
+	if !ok || r == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[reflect.TypeFor[T]()] = r
+}
+
+// Get returns the last result of type T recorded in the collector, the action may not have recorded
+// any, e.g. when it ran in a container
+func Get[T any](c *Collector) (*T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.results[reflect.TypeFor[T]()].(*T)
+	return r, ok
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDirName is the directory of the configuration values of a platform, YAML files passed to the
+// deployment in addition to platform.yaml
+const ConfigDirName = "config"
+
+// vaultTag is the YAML tag of the values encrypted with ansible-vault
+const vaultTag = "!vault"
+
+// vaultHeader starts the files encrypted as a whole with ansible-vault
+var vaultHeader = []byte("$ANSIBLE_VAULT;")
+
+// ConfigValue is a value of the configuration of a platform
+type ConfigValue struct {
+	Key   string `yaml:"key"`             // Path of the value, e.g. monitoring.retention
+	Value string `yaml:"value,omitempty"` // Empty for vault values
+	File  string `yaml:"file"`
+	Vault bool   `yaml:"vault,omitempty"` // Encrypted with ansible-vault
+}
+
+// Config is the configuration of a platform
+type Config struct {
+	Values    []ConfigValue // Values of the files in the order of the files, sorted by path
+	Encrypted []string      // Files encrypted as a whole with ansible-vault, their values are unknown
+	Modified  time.Time     // Last modification of the files
+}
+
+// ConfigDir returns the configuration directory of the named platform, relative to the repository root
+func ConfigDir(name string) string {
+	return filepath.Join(PlatformDir(name), ConfigDirName)
+}
+
+// LoadConfig reads the YAML files of the configuration directory of the named platform and its
+// subdirectories. A missing directory has no configuration.
+func LoadConfig(name string) (*Config, error) {
+	config := &Config{}
+	err := filepath.WalkDir(ConfigDir(name), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(config.Modified) {
+			config.Modified = info.ModTime()
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if bytes.HasPrefix(data, vaultHeader) {
+			config.Encrypted = append(config.Encrypted, path)
+			return nil
+		}
+		var doc yaml.Node
+		if err = yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		collectValues(&doc, "", path, &config.Values)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return config, nil
+}
+
+// VaultKeys returns the number of values encrypted with ansible-vault
+func (c *Config) VaultKeys() int {
+	n := 0
+	for _, v := range c.Values {
+		if v.Vault {
+			n++
+		}
+	}
+	return n
+}
+
+// collectValues appends the scalars of a YAML node, without the content of the vault values
+func collectValues(n *yaml.Node, path, file string, values *[]ConfigValue) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			collectValues(c, path, file, values)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			collectValues(n.Content[i+1], key, file, values)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			collectValues(c, path+"["+strconv.Itoa(i)+"]", file, values)
+		}
+	case yaml.ScalarNode:
+		if n.Tag == vaultTag {
+			*values = append(*values, ConfigValue{Key: path, File: file, Vault: true})
+			return
+		}
+		*values = append(*values, ConfigValue{Key: path, Value: n.Value, File: file})
+	case yaml.AliasNode:
+		collectValues(n.Alias, path, file, values)
+	}
+}
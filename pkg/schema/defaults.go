@@ -0,0 +1,44 @@
+package schema
+
+// ProviderManual is the metal and DNS provider of the servers and records managed by hand
+const ProviderManual = "manual"
+
+// DefaultPrivateNetwork is the private network of the nodes of the platforms
+const DefaultPrivateNetwork = "192.168.0.0/16"
+
+// providerAPIs are the API settings of the metal providers by name. The keyring holds their tokens,
+// the cloud providers use their environment variables or SDK defaults.
+var providerAPIs = map[string]APIConfig{
+	"scaleway": {URI: "https://api.online.net/api/v1/", Token: "{{ .keyring.scaleway_api_token }}"},
+	"hetzner":  {Token: "{{ .keyring.hetzner_api_token }}"},
+	// The webservice user is read from the keyring keys hetzner_robot_user and hetzner_robot_password
+	"hetzner-robot": {URI: "https://robot-ws.hetzner.com"},
+	"ovh":           {Token: "{{ .keyring.ovh_api_token }}"},
+}
+
+// ApplyDefaults sets the values of the platform which aren't set to their defaults: the metal provider
+// to the given one, the providers to manual without one, the API settings of the metal provider and
+// the private network. The values already set are kept.
+func (p *Platform) ApplyDefaults(provider string) {
+	if p.Infrastructure.MetalProvider == "" {
+		p.Infrastructure.MetalProvider = provider
+	}
+	if p.Infrastructure.MetalProvider == "" {
+		p.Infrastructure.MetalProvider = ProviderManual
+	}
+	if p.DNS.Provider == "" {
+		p.DNS.Provider = ProviderManual
+	}
+
+	api := providerAPIs[p.Infrastructure.MetalProvider]
+	if p.Infrastructure.API.URI == "" {
+		p.Infrastructure.API.URI = api.URI
+	}
+	if p.Infrastructure.API.Token == "" {
+		p.Infrastructure.API.Token = api.Token
+	}
+
+	if p.Networking.PrivateNetwork == "" {
+		p.Networking.PrivateNetwork = DefaultPrivateNetwork
+	}
+}
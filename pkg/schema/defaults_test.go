@@ -0,0 +1,71 @@
+package schema
+
+import "testing"
+
+func TestApplyDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform Platform
+		provider string
+		want     Platform
+	}{
+		{
+			name: "manual without provider",
+			want: Platform{
+				Infrastructure: Infrastructure{MetalProvider: ProviderManual},
+				DNS:            DNSConfig{Provider: ProviderManual},
+				Networking:     Networking{PrivateNetwork: DefaultPrivateNetwork},
+			},
+		},
+		{
+			name:     "provider api",
+			provider: "scaleway",
+			want: Platform{
+				Infrastructure: Infrastructure{MetalProvider: "scaleway", API: APIConfig{
+					URI:   "https://api.online.net/api/v1/",
+					Token: "{{ .keyring.scaleway_api_token }}",
+				}},
+				DNS:        DNSConfig{Provider: ProviderManual},
+				Networking: Networking{PrivateNetwork: DefaultPrivateNetwork},
+			},
+		},
+		{
+			name:     "provider without api",
+			provider: "aws",
+			want: Platform{
+				Infrastructure: Infrastructure{MetalProvider: "aws"},
+				DNS:            DNSConfig{Provider: ProviderManual},
+				Networking:     Networking{PrivateNetwork: DefaultPrivateNetwork},
+			},
+		},
+		{
+			name: "set values kept",
+			platform: Platform{
+				Infrastructure: Infrastructure{MetalProvider: "hetzner", API: APIConfig{Token: "{{ .keyring.hz }}"}},
+				DNS:            DNSConfig{Provider: "cloudflare"},
+				Networking:     Networking{PrivateNetwork: "10.0.0.0/8"},
+			},
+			provider: "ovh",
+			want: Platform{
+				Infrastructure: Infrastructure{MetalProvider: "hetzner", API: APIConfig{Token: "{{ .keyring.hz }}"}},
+				DNS:            DNSConfig{Provider: "cloudflare"},
+				Networking:     Networking{PrivateNetwork: "10.0.0.0/8"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.platform
+			p.ApplyDefaults(tt.provider)
+			if p.Infrastructure != tt.want.Infrastructure {
+				t.Errorf("Infrastructure = %+v, want %+v", p.Infrastructure, tt.want.Infrastructure)
+			}
+			if p.DNS.Provider != tt.want.DNS.Provider {
+				t.Errorf("DNS.Provider = %q, want %q", p.DNS.Provider, tt.want.DNS.Provider)
+			}
+			if p.Networking.PrivateNetwork != tt.want.Networking.PrivateNetwork {
+				t.Errorf("Networking.PrivateNetwork = %q, want %q", p.Networking.PrivateNetwork, tt.want.Networking.PrivateNetwork)
+			}
+		})
+	}
+}
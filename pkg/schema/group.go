@@ -0,0 +1,153 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// NodeGroup is a group of nodes of platform.yaml, selecting the nodes listed by hostname and the
+// nodes matching all its other criteria, each matched by any of its values
+type NodeGroup struct {
+	Nodes        []string `yaml:"nodes,omitempty"`        // Hostnames of the nodes of the group
+	Roles        []string `yaml:"roles,omitempty"`        // Roles of the nodes, e.g. worker
+	Capabilities []string `yaml:"capabilities,omitempty"` // Capabilities of the nodes, e.g. gpu
+	Chassis      []string `yaml:"chassis,omitempty"`      // Chassis of the nodes, or their parents, e.g. foundation.cluster
+}
+
+// IsEmpty reports whether the group has no criteria
+func (g NodeGroup) IsEmpty() bool {
+	return len(g.Nodes) == 0 && len(g.Roles) == 0 && len(g.Capabilities) == 0 && len(g.Chassis) == 0
+}
+
+// Matches reports whether the node belongs to the group
+func (g NodeGroup) Matches(n *Node) bool {
+	if slices.ContainsFunc(g.Nodes, func(h string) bool { return strings.EqualFold(h, n.Hostname) }) {
+		return true
+	}
+	if len(g.Roles) == 0 && len(g.Capabilities) == 0 && len(g.Chassis) == 0 {
+		return false
+	}
+	if len(g.Roles) > 0 && !slices.ContainsFunc(g.Roles, func(r string) bool { return slices.Contains(n.Roles, r) }) {
+		return false
+	}
+	if len(g.Capabilities) > 0 && !slices.ContainsFunc(g.Capabilities, func(c string) bool { return slices.Contains(n.Capabilities, c) }) {
+		return false
+	}
+	if len(g.Chassis) > 0 && !slices.ContainsFunc(g.Chassis, func(c string) bool { return n.Chassis == c || strings.HasPrefix(n.Chassis, c+".") }) {
+		return false
+	}
+	return true
+}
+
+// GroupNodes returns the nodes of a group: the nodes of the group of platform.yaml with this name, and
+// the nodes having it in their groups or roles. The boolean is false when no group nor node has the name.
+func GroupNodes(platform *Platform, nodes []*Node, name string) ([]*Node, bool) {
+	group, known := NodeGroup{}, false
+	if platform != nil {
+		group, known = platform.Groups[name]
+	}
+	var members []*Node
+	for _, n := range nodes {
+		inNode := slices.Contains(n.Groups, name) || slices.Contains(n.Roles, name)
+		known = known || inNode
+		if inNode || group.Matches(n) {
+			members = append(members, n)
+		}
+	}
+	return members, known
+}
+
+// GroupNames returns the names of the groups of platform.yaml and of the groups and roles of the
+// nodes, sorted
+func GroupNames(platform *Platform, nodes []*Node) []string {
+	names := make(map[string]bool)
+	if platform != nil {
+		for name := range platform.Groups {
+			names[name] = true
+		}
+	}
+	for _, n := range nodes {
+		for _, name := range slices.Concat(n.Groups, n.Roles) {
+			names[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// ValidateGroups checks the groups of platform.yaml have criteria, and their nodes and chassis exist
+func ValidateGroups(platform *Platform, nodes []*Node) []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(platform.Groups)) {
+		g := platform.Groups[name]
+		if g.IsEmpty() {
+			errs = append(errs, fmt.Errorf("group %s: no nodes, roles, capabilities nor chassis", name))
+			continue
+		}
+		for _, h := range g.Nodes {
+			if !slices.ContainsFunc(nodes, func(n *Node) bool { return strings.EqualFold(n.Hostname, h) }) {
+				errs = append(errs, fmt.Errorf("group %s: node %s has no node file", name, h))
+			}
+		}
+		for _, c := range g.Chassis {
+			if len(platform.Chassis) > 0 && !hasChassis(platform, c) {
+				errs = append(errs, fmt.Errorf("group %s: chassis %s has no profile in platform.yaml", name, c))
+			}
+		}
+	}
+	return errs
+}
+
+// hasChassis reports whether the chassis, or one of its children, has a profile
+func hasChassis(platform *Platform, chassis string) bool {
+	for name := range platform.Chassis {
+		if name == chassis || strings.HasPrefix(name, chassis+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownGroup is returned when a group of an Ansible limit is neither a group of platform.yaml nor
+// a group or role of a node
+var ErrUnknownGroup = errors.New("unknown group")
+
+// ResolveLimit resolves the @group entries of a comma-separated Ansible limit pattern into the
+// hostnames of their nodes, see GroupNodes. Other entries are kept, as are @file entries when no
+// group has their name, since Ansible reads them as files of hosts.
+func ResolveLimit(platform *Platform, nodes []*Node, limit string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(limit, ",") {
+		part = strings.TrimSpace(part)
+		name, isGroup := strings.CutPrefix(part, "@")
+		if !isGroup {
+			if part != "" {
+				parts = append(parts, part)
+			}
+			continue
+		}
+		members, known := GroupNodes(platform, nodes, name)
+		if !known {
+			if strings.ContainsAny(name, "/.") {
+				parts = append(parts, part)
+				continue
+			}
+			names := GroupNames(platform, nodes)
+			if len(names) == 0 {
+				return "", fmt.Errorf("%w %q, the platform has no group", ErrUnknownGroup, name)
+			}
+			return "", fmt.Errorf("%w %q, groups are %s", ErrUnknownGroup, name, strings.Join(names, ", "))
+		}
+		if len(members) == 0 {
+			return "", fmt.Errorf("group %q has no node", name)
+		}
+		for _, n := range members {
+			if !slices.Contains(parts, n.Hostname) {
+				parts = append(parts, n.Hostname)
+			}
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"github.com/plasmash/plasmactl-platform/pkg/failure"
+	"gopkg.in/yaml.v3"
+)
+
+// InstDir is the directory of platform instances, one subdirectory per platform
+const InstDir = "inst"
+
+// PlatformFileName is the name of the platform configuration file in a platform directory
+const PlatformFileName = "platform.yaml"
+
+// ErrNotFound is returned when a platform has no platform.yaml, it's the class of the failures of the
+// missing platforms
+var ErrNotFound error = failure.ErrPlatformNotFound
+
+// PlatformDir returns the directory of the named platform, relative to the repository root
+func PlatformDir(name string) string {
+	return filepath.Join(InstDir, name)
+}
+
+// PlatformFile returns the path of platform.yaml of the named platform, relative to the repository root
+func PlatformFile(name string) string {
+	return filepath.Join(PlatformDir(name), PlatformFileName)
+}
+
+// LoadFile reads a platform.yaml file, the error wraps [ErrNotFound] if it doesn't exist
+func LoadFile(path string) (*Platform, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no platform.yaml at %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read platform.yaml: %w", err)
+	}
+
+	var platform Platform
+	if err := yaml.Unmarshal(data, &platform); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &platform, nil
+}
+
+// Load reads platform.yaml of the named platform
+func Load(name string) (*Platform, error) {
+	return LoadFile(PlatformFile(name))
+}
+
+// FindPlatform returns the platform an environment deploys to, in the repository at root, the current
+// one if empty: the platform of inst/ named after the environment, otherwise the one whose platform.yaml
+// has the environment as name, e.g. inst/ski-dev of name dev. The error wraps [ErrNotFound] if none.
+func FindPlatform(root, environment string) (string, error) {
+	if _, err := os.Stat(filepath.Join(root, PlatformFile(environment))); err == nil {
+		return environment, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(root, InstDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read %s: %w", InstDir, err)
+	}
+	var found []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		platform, err := LoadFile(filepath.Join(root, PlatformFile(e.Name())))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if platform.Name == environment {
+			found = append(found, e.Name())
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%w: no platform of environment %s in %s", ErrNotFound, environment, InstDir)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("platforms %s are all named %s, deploy one of them by its directory name", strings.Join(found, ", "), environment)
+	}
+}
+
+// LoadOptional reads platform.yaml of the named platform, returning an empty Platform if it doesn't exist.
+// It's used to read optional settings of the environment a command targets.
+func LoadOptional(name string) (*Platform, error) {
+	platform, err := Load(name)
+	if errors.Is(err, ErrNotFound) {
+		return &Platform{}, nil
+	}
+	return platform, err
+}
+
+// Save writes the platform to a platform.yaml file
+func (p *Platform) Save(path string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal platform.yaml: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write platform.yaml: %w", err)
+	}
+	return nil
+}
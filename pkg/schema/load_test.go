@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindPlatform(t *testing.T) {
+	root := t.TempDir()
+	for dir, name := range map[string]string{"ski-dev": "dev", "ski-prod": "prod", "a-qa": "qa", "b-qa": "qa"} {
+		if err := os.MkdirAll(filepath.Join(root, PlatformDir(dir)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, PlatformFile(dir)), []byte("name: "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		environment string
+		want        string
+		wantErr     bool
+		notFound    bool
+	}{
+		{environment: "ski-dev", want: "ski-dev"},
+		{environment: "dev", want: "ski-dev"},
+		{environment: "prod", want: "ski-prod"},
+		{environment: "qa", wantErr: true},
+		{environment: "staging", wantErr: true, notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			got, err := FindPlatform(root, tt.environment)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindPlatform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrNotFound) != tt.notFound {
+				t.Errorf("FindPlatform() error = %v, not found %v", err, tt.notFound)
+			}
+			if got != tt.want {
+				t.Errorf("FindPlatform() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
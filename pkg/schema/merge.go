@@ -0,0 +1,62 @@
+package schema
+
+import "reflect"
+
+// Merge returns the platform of the values of override merged over the ones of base, e.g. the settings
+// of an environment over the shared ones. Values set in override replace the ones of base, maps are
+// merged by key, their values recursively, and lists are replaced as a whole. Zero values of override,
+// e.g. false or an empty string, don't replace the values of base. Neither platform is changed.
+func Merge(base, override *Platform) *Platform {
+	if base == nil {
+		base = &Platform{}
+	}
+	if override == nil {
+		override = &Platform{}
+	}
+	merged := merge(reflect.ValueOf(*base), reflect.ValueOf(*override)).Interface().(Platform)
+	return &merged
+}
+
+// merge returns the value of override merged over the value of base, of the same type
+func merge(base, override reflect.Value) reflect.Value {
+	switch base.Kind() {
+	case reflect.Struct:
+		merged := reflect.New(base.Type()).Elem()
+		for i := range base.NumField() {
+			if merged.Field(i).CanSet() {
+				merged.Field(i).Set(merge(base.Field(i), override.Field(i)))
+			}
+		}
+		return merged
+	case reflect.Map:
+		if base.IsNil() && override.IsNil() {
+			return base
+		}
+		merged := reflect.MakeMapWithSize(base.Type(), base.Len()+override.Len())
+		zero := reflect.Zero(base.Type().Elem())
+		for it := base.MapRange(); it.Next(); {
+			merged.SetMapIndex(it.Key(), merge(it.Value(), zero))
+		}
+		for it := override.MapRange(); it.Next(); {
+			prev := base.MapIndex(it.Key())
+			if !prev.IsValid() {
+				prev = zero
+			}
+			merged.SetMapIndex(it.Key(), merge(prev, it.Value()))
+		}
+		return merged
+	case reflect.Slice:
+		if override.Len() > 0 {
+			base = override
+		}
+		if base.IsNil() {
+			return base
+		}
+		return reflect.AppendSlice(reflect.MakeSlice(base.Type(), 0, base.Len()), base)
+	default:
+		if override.IsZero() {
+			return base
+		}
+		return override
+	}
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     *Platform
+		override *Platform
+		want     *Platform
+	}{
+		{
+			name:     "nil platforms",
+			want:     &Platform{},
+			base:     nil,
+			override: nil,
+		},
+		{
+			name:     "values replaced",
+			base:     &Platform{Name: "ski", DNS: DNSConfig{Domain: "skilld.cloud", TTL: 300}},
+			override: &Platform{DNS: DNSConfig{Domain: "dev.skilld.cloud"}},
+			want:     &Platform{Name: "ski", DNS: DNSConfig{Domain: "dev.skilld.cloud", TTL: 300}},
+		},
+		{
+			name:     "zero values ignored",
+			base:     &Platform{Environment: EnvironmentConfig{Type: "production", AutoDeploy: true}, DNS: DNSConfig{TTL: 300}},
+			override: &Platform{Environment: EnvironmentConfig{AutoDeploy: false}, DNS: DNSConfig{TTL: 0}},
+			want:     &Platform{Environment: EnvironmentConfig{Type: "production", AutoDeploy: true}, DNS: DNSConfig{TTL: 300}},
+		},
+		{
+			name:     "maps merged by key",
+			base:     &Platform{Labels: map[string]string{"team": "infra", "tier": "gold"}},
+			override: &Platform{Labels: map[string]string{"tier": "silver", "region": "eu"}},
+			want:     &Platform{Labels: map[string]string{"team": "infra", "tier": "silver", "region": "eu"}},
+		},
+		{
+			name:     "map values merged",
+			base:     &Platform{Groups: map[string]NodeGroup{"web": {Roles: []string{"worker"}, Chassis: []string{"platform.interaction"}}}},
+			override: &Platform{Groups: map[string]NodeGroup{"web": {Roles: []string{"front"}}}},
+			want:     &Platform{Groups: map[string]NodeGroup{"web": {Roles: []string{"front"}, Chassis: []string{"platform.interaction"}}}},
+		},
+		{
+			name:     "slices replaced",
+			base:     &Platform{DNS: DNSConfig{Mail: MailDNSConfig{MX: []MXRecord{{Host: "mx1"}, {Host: "mx2"}}}}},
+			override: &Platform{DNS: DNSConfig{Mail: MailDNSConfig{MX: []MXRecord{{Host: "mx3"}}}}},
+			want:     &Platform{DNS: DNSConfig{Mail: MailDNSConfig{MX: []MXRecord{{Host: "mx3"}}}}},
+		},
+		{
+			name:     "empty slices ignored",
+			base:     &Platform{Cert: CertConfig{Names: []string{"dev.skilld.cloud"}}},
+			override: &Platform{Cert: CertConfig{Names: []string{}}},
+			want:     &Platform{Cert: CertConfig{Names: []string{"dev.skilld.cloud"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Merge(tt.base, tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotChangeInputs(t *testing.T) {
+	base := &Platform{Labels: map[string]string{"team": "infra"}, Cert: CertConfig{Names: []string{"a"}}}
+	override := &Platform{Labels: map[string]string{"tier": "gold"}}
+	merged := Merge(base, override)
+	merged.Labels["team"] = "changed"
+	merged.Cert.Names[0] = "changed"
+	if base.Labels["team"] != "infra" || len(base.Labels) != 1 || base.Cert.Names[0] != "a" {
+		t.Errorf("Merge() changed base: %+v", base)
+	}
+	if len(override.Labels) != 1 {
+		t.Errorf("Merge() changed override: %+v", override)
+	}
+}
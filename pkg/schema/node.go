@@ -0,0 +1,284 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/internal/atomicfile"
+	"gopkg.in/yaml.v3"
+)
+
+// NodesDirName is the directory of the node files in a platform directory
+const NodesDirName = "nodes"
+
+// Node is a node file of a platform, named after the hostname of the node. Node files are shared
+// with other plugins, so saving one keeps the keys it doesn't know.
+type Node struct {
+	Hostname   string   `yaml:"hostname"`
+	ProviderID string   `yaml:"provider_id,omitempty"` // ID of the server at the metal provider
+	PublicIPs  []string `yaml:"public_ips,omitempty"`
+	PrivateIPs []string `yaml:"private_ips,omitempty"`
+	Chassis    string   `yaml:"chassis,omitempty"` // Chassis the node is attached to, e.g. foundation.cluster.control
+	Offer      string   `yaml:"offer,omitempty"`   // Offer of the server, e.g. GP1-L
+	Location   string   `yaml:"location,omitempty"`
+
+	Capabilities []string `yaml:"capabilities,omitempty"` // Capabilities of the node, e.g. gpu
+	Roles        []string `yaml:"roles,omitempty"`        // Roles of the node in the platform, e.g. control
+	Groups       []string `yaml:"groups,omitempty"`       // Groups of the node, in addition to the groups of platform.yaml
+
+	File string `yaml:"-"` // File of the node, set by LoadNodes
+}
+
+// NodesDir returns the node directory of the named platform, relative to the repository root
+func NodesDir(name string) string {
+	return filepath.Join(PlatformDir(name), NodesDirName)
+}
+
+// NodeFile returns the path of the file of a node of the named platform
+func NodeFile(name, hostname string) string {
+	return filepath.Join(NodesDir(name), hostname+".yaml")
+}
+
+// NodeFiles returns the node files of a nodes directory, sorted by name. A missing directory has none.
+func NodeFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodes directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// LoadNode reads a node file
+func LoadNode(path string) (*Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node: %w", err)
+	}
+	node := &Node{File: path}
+	if err = yaml.Unmarshal(data, node); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return node, nil
+}
+
+// LoadNodesDir reads the node files of a nodes directory, sorted by file name
+func LoadNodesDir(dir string) ([]*Node, error) {
+	files, err := NodeFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*Node, 0, len(files))
+	for _, f := range files {
+		node, err := LoadNode(f)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// LoadNodes reads the node files of the named platform, sorted by file name
+func LoadNodes(name string) ([]*Node, error) {
+	return LoadNodesDir(NodesDir(name))
+}
+
+// Save writes the node to its file, keeping the keys of an existing file that Node doesn't hold
+func (n *Node) Save() error {
+	path := n.File
+	if path == "" {
+		return fmt.Errorf("node %q has no file", n.Hostname)
+	}
+	var updated yaml.Node
+	if err := updated.Encode(n); err != nil {
+		return fmt.Errorf("failed to marshal node: %w", err)
+	}
+
+	doc := &updated
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read node: %w", err)
+	}
+	var existing yaml.Node
+	if len(data) > 0 {
+		if err = yaml.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if len(existing.Content) == 1 && existing.Content[0].Kind == yaml.MappingNode {
+			doc = existing.Content[0]
+			mergeMapping(doc, &updated, nodeKeys())
+		}
+	}
+
+	if data, err = yaml.Marshal(doc); err != nil {
+		return fmt.Errorf("failed to marshal node: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create nodes directory: %w", err)
+	}
+	if err = atomicfile.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write node: %w", err)
+	}
+	return nil
+}
+
+// nodeKeys returns the YAML keys held by Node
+func nodeKeys() []string {
+	return []string{"hostname", "provider_id", "public_ips", "private_ips", "chassis", "offer", "location", "capabilities", "roles", "groups"}
+}
+
+// mergeMapping sets the keys of the updated mapping in dst, and removes the given keys it doesn't hold
+func mergeMapping(dst, updated *yaml.Node, keys []string) {
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(updated.Content); i += 2 {
+		values[updated.Content[i].Value] = updated.Content[i+1]
+	}
+
+	content := dst.Content[:0:0]
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		if v, ok := values[key]; ok {
+			content = append(content, dst.Content[i], v)
+			delete(values, key)
+			continue
+		}
+		if !slices.Contains(keys, key) {
+			content = append(content, dst.Content[i], dst.Content[i+1])
+		}
+	}
+	// New keys are appended in the order of the updated mapping
+	for i := 0; i+1 < len(updated.Content); i += 2 {
+		if _, ok := values[updated.Content[i].Value]; ok {
+			content = append(content, updated.Content[i], updated.Content[i+1])
+		}
+	}
+	dst.Content = content
+}
+
+// sharedNetwork is the shared address space of carrier-grade NAT and overlay networks, RFC 6598
+var sharedNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// hostnameRe matches RFC 1123 host names, labels of letters, digits and inner hyphens
+var hostnameRe = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// Validate checks the values of the node, and its chassis against the chassis profiles of the platform
+// when it has some. The error joins every problem found.
+func (n *Node) Validate(platform *Platform) error {
+	var errs []error
+	switch {
+	case n.Hostname == "":
+		errs = append(errs, errors.New("hostname is missing"))
+	case len(n.Hostname) > 253 || !hostnameRe.MatchString(n.Hostname):
+		errs = append(errs, fmt.Errorf("hostname %q is not a valid host name", n.Hostname))
+	}
+
+	for _, addr := range n.PublicIPs {
+		if net.ParseIP(addr) == nil {
+			errs = append(errs, fmt.Errorf("public_ips: %q is not an IP address", addr))
+		}
+	}
+	for _, addr := range n.PrivateIPs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			errs = append(errs, fmt.Errorf("private_ips: %q is not an IP address", addr))
+		case !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !sharedNetwork.Contains(ip):
+			errs = append(errs, fmt.Errorf("private_ips: %s is not a private address", addr))
+		}
+	}
+	if len(n.PublicIPs) == 0 && len(n.PrivateIPs) == 0 {
+		errs = append(errs, errors.New("node has no public_ips nor private_ips"))
+	}
+
+	if n.Chassis != "" && platform != nil && len(platform.Chassis) > 0 {
+		if _, ok := platform.Chassis[n.Chassis]; !ok {
+			errs = append(errs, fmt.Errorf("chassis %q has no profile in platform.yaml", n.Chassis))
+		}
+	}
+	errs = append(errs, validateList("capabilities", n.Capabilities)...)
+	errs = append(errs, validateList("roles", n.Roles)...)
+	errs = append(errs, validateList("groups", n.Groups)...)
+	return errors.Join(errs...)
+}
+
+// validateList checks the values of a list are set and unique
+func validateList(key string, values []string) []error {
+	var errs []error
+	for i, v := range values {
+		switch {
+		case strings.TrimSpace(v) == "":
+			errs = append(errs, fmt.Errorf("%s: empty value", key))
+		case slices.Contains(values[:i], v):
+			errs = append(errs, fmt.Errorf("%s: duplicate value %q", key, v))
+		}
+	}
+	return errs
+}
+
+// ValidateNodes reads and validates the node files of a nodes directory, and checks no host name or
+// address is used by several nodes. It returns the nodes read and the problems found, prefixed with
+// their file.
+func ValidateNodes(dir string, platform *Platform) ([]*Node, []error) {
+	files, err := NodeFiles(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var nodes []*Node
+	var errs []error
+	hostnames := make(map[string]string)
+	addrs := make(map[string]string)
+	for _, f := range files {
+		node, err := LoadNode(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		nodes = append(nodes, node)
+		if err = node.Validate(platform); err != nil {
+			for _, e := range unjoin(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", f, e))
+			}
+		}
+
+		if other, ok := hostnames[strings.ToLower(node.Hostname)]; ok && node.Hostname != "" {
+			errs = append(errs, fmt.Errorf("%s: hostname %s is also used by %s", f, node.Hostname, other))
+		}
+		hostnames[strings.ToLower(node.Hostname)] = f
+		for _, addr := range slices.Concat(node.PublicIPs, node.PrivateIPs) {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				continue
+			}
+			if other, ok := addrs[ip.String()]; ok && other != f {
+				errs = append(errs, fmt.Errorf("%s: address %s is also used by %s", f, addr, other))
+			}
+			addrs[ip.String()] = f
+		}
+	}
+	return nodes, errs
+}
+
+// unjoin returns the errors joined by errors.Join, or the error itself
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
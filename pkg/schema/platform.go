@@ -2,16 +2,33 @@
 // This is the public API consumed by other plasmactl plugins (e.g., plasmactl-node).
 package schema
 
+import (
+	"path"
+	"slices"
+	"time"
+
+	"github.com/plasmash/plasmactl-platform/pkg/secretgen"
+)
+
 // Platform represents the platform.yaml configuration
 type Platform struct {
 	Name        string `yaml:"name"`
 	Cluster     string `yaml:"cluster,omitempty"`
 	Description string `yaml:"description,omitempty"`
 
-	Infrastructure Infrastructure            `yaml:"infrastructure"`
-	DNS            DNSConfig                  `yaml:"dns,omitempty"`
-	Networking     Networking                 `yaml:"networking,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"` // Labels selecting the platform, e.g. team: infra
+
+	Infrastructure Infrastructure              `yaml:"infrastructure"`
+	DNS            DNSConfig                   `yaml:"dns,omitempty"`
+	Networking     Networking                  `yaml:"networking,omitempty"`
 	Chassis        map[string][]ChassisProfile `yaml:"chassis,omitempty"`
+	Groups         map[string]NodeGroup        `yaml:"groups,omitempty"`
+	Image          ImageConfig                 `yaml:"image,omitempty"`
+	Publish        PublishConfig               `yaml:"publish,omitempty"`
+	CI             CIConfig                    `yaml:"ci,omitempty"`
+	Cert           CertConfig                  `yaml:"cert,omitempty"`
+	Secrets        SecretsConfig               `yaml:"secrets,omitempty"`
+	Metrics        MetricsConfig               `yaml:"metrics,omitempty"`
 
 	Defaults    PlatformDefaults  `yaml:"defaults,omitempty"`
 	Features    PlatformFeatures  `yaml:"features,omitempty"`
@@ -20,15 +37,53 @@ type Platform struct {
 
 // Infrastructure defines the infrastructure provider configuration
 type Infrastructure struct {
-	MetalProvider string    `yaml:"metal_provider"` // scaleway, hetzner, aws, ovh, gcp, azure, manual
+	MetalProvider string    `yaml:"metal_provider"` // scaleway, hetzner, hetzner-robot, aws, ovh, gcp, azure, manual
 	API           APIConfig `yaml:"api,omitempty"`
 }
 
 // DNSConfig defines DNS provider configuration
 type DNSConfig struct {
-	Provider string `yaml:"provider"`          // ovh, cloudflare, route53, gcp, manual
-	Domain   string `yaml:"domain"`            // e.g., dev.skilld.cloud
-	// Records are auto-configured: MX, DKIM, DMARC, SPF, rDNS
+	Provider string `yaml:"provider"`       // ovh, cloudflare, route53, terraform, gcp, manual
+	Domain   string `yaml:"domain"`         // e.g., dev.skilld.cloud
+	Zone     string `yaml:"zone,omitempty"` // Zone holding the domain, e.g. skilld.cloud. Default is looked up.
+	TTL      int    `yaml:"ttl,omitempty"`  // TTL of the records in seconds, default is 300
+
+	// Addresses are the A/AAAA records by name relative to the domain, "@" for the domain itself
+	Addresses map[string][]string `yaml:"addresses,omitempty"`
+	Mail      MailDNSConfig       `yaml:"mail,omitempty"`
+
+	// Reverse are the PTR names by address, set with the metal provider. The addresses of the mail
+	// servers get the name of their MX host by default.
+	Reverse map[string]string `yaml:"reverse,omitempty"`
+}
+
+// MailDNSConfig defines the mail records of the domain: MX, SPF, DKIM and DMARC
+type MailDNSConfig struct {
+	MX    []MXRecord `yaml:"mx,omitempty"`
+	SPF   string     `yaml:"spf,omitempty"`   // SPF policy, default is "v=spf1 mx -all" with MX records
+	DMARC string     `yaml:"dmarc,omitempty"` // DMARC policy, default is "v=DMARC1; p=quarantine" with MX records
+	DKIM  DKIMConfig `yaml:"dkim,omitempty"`
+}
+
+// MXRecord defines a mail server of the domain
+type MXRecord struct {
+	Host     string `yaml:"host"`               // Mail server, names without dots are relative to the domain
+	Priority int    `yaml:"priority,omitempty"` // Default is 10
+}
+
+// DKIMConfig defines the DKIM public key records. Keys are rotated by adding the next one to keys,
+// switching the mail servers to it, then moving the previous selector to retired.
+type DKIMConfig struct {
+	Selector  string    `yaml:"selector,omitempty"`   // Default is "default"
+	PublicKey string    `yaml:"public_key,omitempty"` // Base64 public key, the p= tag of the record
+	Keys      []DKIMKey `yaml:"keys,omitempty"`       // Keys of additional selectors
+	Retired   []string  `yaml:"retired,omitempty"`    // Selectors of rotated keys, their records are deleted
+}
+
+// DKIMKey defines the public key of a DKIM selector
+type DKIMKey struct {
+	Selector  string `yaml:"selector"`
+	PublicKey string `yaml:"public_key,omitempty"`
 }
 
 // APIConfig defines API connection settings
@@ -37,6 +92,97 @@ type APIConfig struct {
 	Token string `yaml:"token,omitempty"`
 }
 
+// ImageConfig defines Platform Image settings
+type ImageConfig struct {
+	NameTemplate string          `yaml:"name_template,omitempty"` // Image file name template, e.g. {name}-{environment}-{short_sha}
+	Signature    SignaturePolicy `yaml:"signature,omitempty"`
+}
+
+// PublishConfig overrides the artifact repository of the platform.publish config for the platform
+type PublishConfig struct {
+	URL          string   `yaml:"url,omitempty"`           // Repository URL of the http backend
+	FallbackURLs []string `yaml:"fallback_urls,omitempty"` // Repositories tried in order when the URL is unreachable
+	Path         string   `yaml:"path,omitempty"`          // Artifact path template, e.g. {platform}/{name}
+	Auth         string   `yaml:"auth,omitempty"`          // Authentication of the repository: basic, bearer or header
+	TokenKey     string   `yaml:"token_key,omitempty"`     // Keyring key of the token of the bearer and header auth
+	TokenHeader  string   `yaml:"token_header,omitempty"`  // Header carrying the token of the header auth
+}
+
+// CIConfig defines CI pipeline settings
+type CIConfig struct {
+	Variables map[string]string `yaml:"variables,omitempty"` // Default pipeline variables, e.g. RUN_E2E_TESTS: "true"
+}
+
+// CertConfig defines the TLS certificate of the platform domain, issued by an ACME CA with DNS-01 challenges
+type CertConfig struct {
+	Email     string   `yaml:"email,omitempty"`     // Contact of the ACME account, e.g. for expiry notices
+	Directory string   `yaml:"directory,omitempty"` // ACME directory URL, default is Let's Encrypt
+	Names     []string `yaml:"names,omitempty"`     // Names of the certificate, default is the domain and its wildcard
+}
+
+// MetricsConfig defines where the metrics of the operations of the platform are sent after each run
+type MetricsConfig struct {
+	Pushgateway string `yaml:"pushgateway,omitempty"` // URL of a Prometheus Pushgateway
+	Job         string `yaml:"job,omitempty"`         // Job of the pushed metrics, default is plasmactl
+	Textfile    string `yaml:"textfile,omitempty"`    // .prom file read by the textfile collector of the node exporter
+}
+
+// SecretsConfig defines the backend storing the secrets of the platform, e.g. the Ansible vault password
+type SecretsConfig struct {
+	Backend  string      `yaml:"backend,omitempty"` // keyring (default) or vault
+	Vault    VaultConfig `yaml:"vault,omitempty"`
+	VaultIDs []VaultID   `yaml:"vault_ids,omitempty"` // Ansible vault identities, a single password by default
+	// Policies of the generated secrets by key or glob pattern of keys, e.g. db_*
+	Policies map[string]secretgen.Policy `yaml:"policies,omitempty"`
+}
+
+// Policy returns the generation policy of a secret, the one of its key or of the first matching
+// pattern in lexical order
+func (c SecretsConfig) Policy(key string) (secretgen.Policy, bool) {
+	if p, ok := c.Policies[key]; ok {
+		return p, true
+	}
+	patterns := make([]string, 0, len(c.Policies))
+	for pattern := range c.Policies {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return c.Policies[pattern], true
+		}
+	}
+	return secretgen.Policy{}, false
+}
+
+// VaultID defines an Ansible vault identity of the platform, its password is a secret of the backend
+type VaultID struct {
+	ID  string `yaml:"id"`
+	Key string `yaml:"key,omitempty"` // Key of the password in the secrets backend, default is vaultpass_<id>
+}
+
+// VaultConfig defines the HashiCorp Vault KV v2 secret holding the secrets of the platform
+type VaultConfig struct {
+	Address   string `yaml:"address,omitempty"`   // Default is $VAULT_ADDR
+	Namespace string `yaml:"namespace,omitempty"` // Vault Enterprise namespace, default is $VAULT_NAMESPACE
+	Mount     string `yaml:"mount,omitempty"`     // Mount of the KV v2 engine, default is secret
+	Path      string `yaml:"path,omitempty"`      // Path of the secret, default is plasma/<platform>
+	Token     string `yaml:"token,omitempty"`     // e.g. {{ .keyring.vault_token }}, default is $VAULT_TOKEN
+}
+
+// SignaturePolicy defines Platform Image signature requirements
+type SignaturePolicy struct {
+	Required bool   `yaml:"required,omitempty"` // Refuse to deploy images without a valid signature
+	Key      string `yaml:"key,omitempty"`      // Public key path or KMS URI for key-based verification
+	Identity string `yaml:"identity,omitempty"` // Certificate identity for keyless verification
+	Issuer   string `yaml:"issuer,omitempty"`   // Certificate OIDC issuer for keyless verification
+}
+
+// Enabled reports whether signatures are verified under this policy
+func (p SignaturePolicy) Enabled() bool {
+	return p.Required || p.Key != "" || p.Identity != ""
+}
+
 // Networking defines network configuration
 type Networking struct {
 	PrivateNetwork    string    `yaml:"private_network,omitempty"`
@@ -111,7 +257,7 @@ func NewPlatform(name, metalProvider, dnsProvider, domain string) *Platform {
 			Domain:   domain,
 		},
 		Networking: Networking{
-			PrivateNetwork: "192.168.0.0/16",
+			PrivateNetwork: DefaultPrivateNetwork,
 		},
 		Chassis: make(map[string][]ChassisProfile),
 	}
@@ -124,4 +270,8 @@ type PlatformInfo struct {
 	MetalProvider string `yaml:"metal_provider"`
 	DNSProvider   string `yaml:"dns_provider"`
 	NodeCount     int    `yaml:"node_count"`
+
+	Labels     map[string]string `yaml:"labels,omitempty" json:",omitempty"`
+	LastDeploy *time.Time        `yaml:"last_deploy,omitempty" json:",omitempty"` // Last deployment from this repository
+	Repository string            `yaml:"repository,omitempty" json:",omitempty"`  // Repository of the platform, when listed across repositories
 }
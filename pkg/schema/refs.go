@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyringSource is the source of the references to keyring items, e.g. {{ .keyring.scaleway_api_token }}
+const KeyringSource = "keyring"
+
+// templateRef matches the template references of platform.yaml values, e.g. {{ .keyring.scaleway_api_token }}
+var templateRef = regexp.MustCompile(`\{\{\s*\.([A-Za-z0-9_]+)\.([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// Ref is a template reference of a value of platform.yaml
+type Ref struct {
+	Path   string // Path of the value, e.g. infrastructure.api.token
+	Value  string // The value holding the reference
+	Source string // Source of the reference, e.g. keyring, empty when the template is malformed
+	Key    string // Key of the item of the source, e.g. scaleway_api_token
+}
+
+// String returns the template of the reference
+func (r Ref) String() string {
+	if r.Source == "" {
+		return r.Value
+	}
+	return fmt.Sprintf("{{ .%s.%s }}", r.Source, r.Key)
+}
+
+// Refs returns the template references of the values of the platform.yaml of the named platform, in
+// the order of the file. Values with template braces but no reference are returned without source. The
+// error wraps [ErrNotFound] if the platform has no platform.yaml.
+func Refs(name string) ([]Ref, error) {
+	data, err := os.ReadFile(PlatformFile(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no platform.yaml at %s", ErrNotFound, PlatformFile(name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read platform.yaml: %w", err)
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PlatformFile(name), err)
+	}
+	var refs []Ref
+	collectRefs(&doc, "", &refs)
+	return refs, nil
+}
+
+// collectRefs appends the references of the scalars of a YAML node
+func collectRefs(n *yaml.Node, path string, refs *[]Ref) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			collectRefs(c, path, refs)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			collectRefs(n.Content[i+1], key, refs)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			collectRefs(c, path+"["+strconv.Itoa(i)+"]", refs)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "{{") {
+			return
+		}
+		matches := templateRef.FindAllStringSubmatch(n.Value, -1)
+		if len(matches) == 0 {
+			*refs = append(*refs, Ref{Path: path, Value: n.Value})
+		}
+		for _, m := range matches {
+			*refs = append(*refs, Ref{Path: path, Value: n.Value, Source: m[1], Key: m[2]})
+		}
+	}
+}
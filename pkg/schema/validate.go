@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// platformName matches the names of the platforms, the directories of inst/
+var platformName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// domainName matches the domain names of the DNS config, e.g. dev.skilld.cloud
+var domainName = regexp.MustCompile(`(?i)^([a-z0-9_]([a-z0-9-]{0,61}[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.?$`)
+
+// Allowed values of platform.yaml, empty for the default
+var (
+	secretsBackends  = []string{"", "keyring", "vault"}
+	environmentTypes = []string{"", "development", "staging", "production"}
+)
+
+// FieldError is an invalid value of platform.yaml
+type FieldError struct {
+	Field   string // Path of the value, e.g. dns.domain
+	Message string
+}
+
+// Error implements error interface
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists the invalid values of platform.yaml
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error implements error interface
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return "invalid platform.yaml: " + strings.Join(msgs, "; ")
+}
+
+// ValidateName checks a platform name read from outside the repository, e.g. the path of an API
+// request: it's a single directory of inst/, lowercase letters, digits, dots, dashes and underscores
+func ValidateName(name string) error {
+	if !platformName.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid platform name %q, use lowercase letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
+
+// Validate checks the values of the platform, the error is a [ValidationError] listing the invalid
+// ones. The checks don't reach the providers or the DNS, platform:validate does.
+func (p *Platform) Validate() error {
+	var errs []*FieldError
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if p.Name == "" {
+		fail("name", "is required")
+	}
+	if p.Infrastructure.MetalProvider == "" {
+		fail("infrastructure.metal_provider", "is required, use %s for servers managed by hand", ProviderManual)
+	}
+	if uri := p.Infrastructure.API.URI; uri != "" && !isHTTPURL(uri) {
+		fail("infrastructure.api.uri", "%q isn't an http or https URL", uri)
+	}
+
+	if d := p.DNS.Domain; d != "" && (len(d) > 253 || !domainName.MatchString(d)) {
+		fail("dns.domain", "%q isn't a domain name", d)
+	}
+	if p.DNS.TTL < 0 {
+		fail("dns.ttl", "must not be negative, got %d", p.DNS.TTL)
+	}
+	for i, mx := range p.DNS.Mail.MX {
+		if mx.Host == "" {
+			fail(fmt.Sprintf("dns.mail.mx[%d].host", i), "is required")
+		}
+		if mx.Priority < 0 {
+			fail(fmt.Sprintf("dns.mail.mx[%d].priority", i), "must not be negative, got %d", mx.Priority)
+		}
+	}
+
+	for _, n := range []struct{ field, cidr string }{
+		{"networking.private_network", p.Networking.PrivateNetwork},
+		{"networking.private_vip_network", p.Networking.PrivateVIPNetwork},
+	} {
+		if _, _, err := net.ParseCIDR(n.cidr); n.cidr != "" && err != nil {
+			fail(n.field, "%q isn't a CIDR network", n.cidr)
+		}
+	}
+
+	for _, chassis := range slices.Sorted(maps.Keys(p.Chassis)) {
+		for i, profile := range p.Chassis[chassis] {
+			field := fmt.Sprintf("chassis.%s[%d]", chassis, i)
+			if profile.Type == "" {
+				fail(field+".type", "is required")
+			}
+			if profile.Count < 0 {
+				fail(field+".count", "must not be negative, got %d", profile.Count)
+			}
+		}
+	}
+
+	if !slices.Contains(secretsBackends, p.Secrets.Backend) {
+		fail("secrets.backend", "unknown backend %q (use %s)", p.Secrets.Backend, strings.Join(secretsBackends[1:], " or "))
+	}
+	if !slices.Contains(environmentTypes, p.Environment.Type) {
+		fail("environment.type", "unknown type %q (use %s)", p.Environment.Type, strings.Join(environmentTypes[1:], ", "))
+	}
+	if u := p.Metrics.Pushgateway; u != "" && !isHTTPURL(u) {
+		fail("metrics.pushgateway", "%q isn't an http or https URL", u)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: errs}
+}
+
+// isHTTPURL reports whether the value is an absolute http or https URL
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
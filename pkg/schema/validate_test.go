@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// validPlatform returns a platform passing Validate
+func validPlatform() *Platform {
+	p := NewPlatform("ski-dev", "hetzner", "cloudflare", "dev.skilld.cloud")
+	p.ApplyDefaults("")
+	return p
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Platform)
+		fields []string
+	}{
+		{name: "valid", modify: func(*Platform) {}},
+		{
+			name: "required values",
+			modify: func(p *Platform) {
+				p.Name = ""
+				p.Infrastructure.MetalProvider = ""
+			},
+			fields: []string{"name", "infrastructure.metal_provider"},
+		},
+		{
+			name:   "api uri",
+			modify: func(p *Platform) { p.Infrastructure.API.URI = "ftp://api.example.com" },
+			fields: []string{"infrastructure.api.uri"},
+		},
+		{
+			name: "dns",
+			modify: func(p *Platform) {
+				p.DNS.Domain = "dev..skilld.cloud"
+				p.DNS.TTL = -1
+				p.DNS.Mail.MX = []MXRecord{{Host: "mail"}, {Priority: -5}}
+			},
+			fields: []string{"dns.domain", "dns.ttl", "dns.mail.mx[1].host", "dns.mail.mx[1].priority"},
+		},
+		{
+			name: "networks",
+			modify: func(p *Platform) {
+				p.Networking.PrivateNetwork = "192.168.0.0"
+				p.Networking.PrivateVIPNetwork = "10.0.0.0/33"
+			},
+			fields: []string{"networking.private_network", "networking.private_vip_network"},
+		},
+		{
+			name: "chassis",
+			modify: func(p *Platform) {
+				p.Chassis = map[string][]ChassisProfile{
+					"platform.interaction": {{Type: "GP1-L", Count: 2}, {Count: -1}},
+				}
+			},
+			fields: []string{"chassis.platform.interaction[1].type", "chassis.platform.interaction[1].count"},
+		},
+		{
+			name: "allowed values",
+			modify: func(p *Platform) {
+				p.Secrets.Backend = "file"
+				p.Environment.Type = "qa"
+				p.Metrics.Pushgateway = "pushgateway:9091"
+			},
+			fields: []string{"secrets.backend", "environment.type", "metrics.pushgateway"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validPlatform()
+			tt.modify(p)
+			err := p.Validate()
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Validate() error = %v, want a ValidationError", err)
+			}
+			var fields []string
+			for _, f := range invalid.Fields {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("Validate() fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"ski-dev":     true,
+		"ski.dev_2":   true,
+		"0ski":        true,
+		"":            false,
+		"Ski-Dev":     false,
+		"-ski":        false,
+		".ski":        false,
+		"ski..dev":    false,
+		"../etc":      false,
+		"ski/dev":     false,
+		`ski\dev`:     false,
+		"ski dev":     false,
+		"ski-dev\n":   false,
+		"..":          false,
+		"ski-dev/../": false,
+	} {
+		if err := ValidateName(name); (err == nil) != valid {
+			t.Errorf("ValidateName(%q) error = %v, want valid %v", name, err, valid)
+		}
+	}
+}
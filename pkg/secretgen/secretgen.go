@@ -0,0 +1,173 @@
+// Package secretgen generates secrets compliant with a generation policy, e.g. a 32 characters
+// alphanumeric password or an ed25519 SSH key, so operators don't invent them. The policies of the
+// secrets of a platform are declared in secrets.policies of platform.yaml, other plugins generating
+// secrets of their configuration can apply the same policies with [Generate].
+package secretgen
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Formats of the generated secrets
+const (
+	FormatPassword   = "password"    // FormatPassword is a random string of the characters of the charset
+	FormatHex        = "hex"         // FormatHex is random bytes in hexadecimal
+	FormatUUID       = "uuid"        // FormatUUID is a random UUID (version 4)
+	FormatRSA        = "rsa"         // FormatRSA is a PEM encoded PKCS #8 RSA private key
+	FormatSSHEd25519 = "ssh-ed25519" // FormatSSHEd25519 is an OpenSSH ed25519 private key
+)
+
+// Defaults of the policies
+const (
+	DefaultPasswordLength = 32   // Characters
+	DefaultHexLength      = 32   // Bytes
+	DefaultRSABits        = 4096 // Bits
+	minRSABits            = 2048
+)
+
+// Named charsets of the passwords
+var charsets = map[string]string{
+	"alnum":   "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":   "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"lower":   "abcdefghijklmnopqrstuvwxyz",
+	"upper":   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digits":  "0123456789",
+	"symbols": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%+-.:=@^_~",
+}
+
+// Policy defines how a secret is generated
+type Policy struct {
+	Format string `yaml:"format,omitempty"` // password (default), hex, uuid, rsa or ssh-ed25519
+	// Length is the number of characters of passwords, of bytes of hex secrets and of bits of RSA keys
+	Length int `yaml:"length,omitempty"`
+	// Charset of passwords: alnum (default), alpha, lower, upper, digits, symbols, or the characters
+	// themselves
+	Charset string `yaml:"charset,omitempty"`
+}
+
+// Validate checks the policy can generate secrets
+func (p Policy) Validate() error {
+	if p.Length < 0 {
+		return fmt.Errorf("negative length %d", p.Length)
+	}
+	switch p.Format {
+	case "", FormatPassword:
+		if len([]rune(p.charset())) < 2 {
+			return fmt.Errorf("charset %q must have at least 2 characters", p.Charset)
+		}
+	case FormatHex:
+	case FormatUUID, FormatSSHEd25519:
+		if p.Length != 0 {
+			return fmt.Errorf("length can't be set for the %s format", p.Format)
+		}
+	case FormatRSA:
+		if p.Length != 0 && p.Length < minRSABits {
+			return fmt.Errorf("RSA keys must have at least %d bits", minRSABits)
+		}
+	default:
+		return fmt.Errorf("unknown format %q (use %s)", p.Format, strings.Join(Formats(), ", "))
+	}
+	if p.Charset != "" && p.Format != "" && p.Format != FormatPassword {
+		return fmt.Errorf("charset can't be set for the %s format", p.Format)
+	}
+	return nil
+}
+
+// Formats returns the formats of the secrets
+func Formats() []string {
+	return []string{FormatPassword, FormatHex, FormatUUID, FormatRSA, FormatSSHEd25519}
+}
+
+// Generate generates a secret compliant with the policy
+func Generate(p Policy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	switch p.Format {
+	case FormatHex:
+		b := make([]byte, or(p.Length, DefaultHexLength))
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b), nil
+	case FormatUUID:
+		return newUUID()
+	case FormatRSA:
+		key, err := rsa.GenerateKey(rand.Reader, or(p.Length, DefaultRSABits))
+		if err != nil {
+			return "", err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+	case FormatSSHEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "")
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(block)), nil
+	default:
+		return password(p.charset(), or(p.Length, DefaultPasswordLength))
+	}
+}
+
+// charset returns the characters of the passwords
+func (p Policy) charset() string {
+	if p.Charset == "" {
+		return charsets["alnum"]
+	}
+	if cs, ok := charsets[p.Charset]; ok {
+		return cs
+	}
+	return p.Charset
+}
+
+// password returns n characters of the charset picked uniformly
+func password(charset string, n int) (string, error) {
+	chars := []rune(charset)
+	size := big.NewInt(int64(len(chars)))
+	var sb strings.Builder
+	for range n {
+		i, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteRune(chars[i.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// newUUID returns a random UUID, RFC 9562 version 4
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// or returns v, or def if v is zero
+func or(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
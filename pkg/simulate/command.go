@@ -0,0 +1,46 @@
+package simulate
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/launchrctl/launchr"
+)
+
+// Command is a command recorded instead of being run in simulation: an executable, an action of
+// another plugin, or a call changing a remote service like a git push
+type Command struct {
+	Name string   // Executable, action ID or service, e.g. ansible-playbook, model:compose or git
+	Args []string // Arguments of the command, without the name
+	Dir  string   // Working directory of executables, the current one if empty
+}
+
+// String returns the command line
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Record records a command run in simulation
+func Record(c Command) {
+	launchr.Log().Debug("simulated command", "command", c.String(), "dir", c.Dir)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.commands = append(state.commands, c)
+}
+
+// Run runs the command, or records it without running it in simulation
+func Run(cmd *exec.Cmd) error {
+	if !Enabled() {
+		return cmd.Run()
+	}
+	Record(Command{Name: cmd.Args[0], Args: slices.Clone(cmd.Args[1:]), Dir: cmd.Dir})
+	return nil
+}
+
+// Commands returns the commands recorded since the simulation was enabled or reset, in their order
+func Commands() []Command {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return slices.Clone(state.commands)
+}
@@ -0,0 +1,138 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// fakeDNS manages the records of a domain in the memory of the process
+type fakeDNS struct {
+	domain string
+}
+
+// newDNS creates the fake of the DNS providers
+func newDNS(cfg schema.DNSConfig, _ dns.Options) (dns.DNSProvider, error) {
+	domain := normalize(cfg.Domain)
+	if domain == "" {
+		return nil, errors.New("dns.domain is required")
+	}
+	return &fakeDNS{domain: domain}, nil
+}
+
+// Records returns the records of a domain set by the fake DNS providers
+func Records(domain string) []dns.Record {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return slices.Clone(state.zones[normalize(domain)])
+}
+
+// SetRecords replaces the records of a domain, e.g. the existing records of a test
+func SetRecords(domain string, records []dns.Record) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.zones[normalize(domain)] = slices.Clone(records)
+}
+
+// PlanChanges implements dns.DNSProvider interface
+func (f *fakeDNS) PlanChanges(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	return dns.Plan(Records(f.domain), records), nil
+}
+
+// EnsureRecords implements dns.DNSProvider interface
+func (f *fakeDNS) EnsureRecords(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	changes := dns.Plan(state.zones[f.domain], records)
+	state.zones[f.domain] = applyChanges(state.zones[f.domain], changes)
+	return changes, nil
+}
+
+// DeleteRecords implements dns.DNSProvider interface
+func (f *fakeDNS) DeleteRecords(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	changes := dns.PlanDeletion(state.zones[f.domain], records)
+	state.zones[f.domain] = applyChanges(state.zones[f.domain], changes)
+	return changes, nil
+}
+
+// applyChanges returns the records with the changes applied
+func applyChanges(records []dns.Record, changes []dns.Change) []dns.Record {
+	records = slices.Clone(records)
+	for _, c := range changes {
+		switch c.Action {
+		case dns.ChangeCreate:
+			records = append(records, c.Record)
+		case dns.ChangeUpdate:
+			records = slices.DeleteFunc(records, func(r dns.Record) bool { return sameRecord(r, c.Old) })
+			records = append(records, c.Record)
+		case dns.ChangeDelete:
+			records = slices.DeleteFunc(records, func(r dns.Record) bool { return sameRecord(r, c.Record) })
+		}
+	}
+	return records
+}
+
+// sameRecord reports whether two records have the same name, type and content
+func sameRecord(a, b dns.Record) bool {
+	return a.Type == b.Type && strings.EqualFold(a.Name, b.Name) && a.Content == b.Content
+}
+
+// fakeReverse manages the PTR records of the addresses in the memory of the process, shared with the
+// fake metal providers
+type fakeReverse struct{}
+
+// newReverse creates the fake of the reverse DNS providers
+func newReverse(_ schema.Infrastructure, _ dns.Options) (dns.ReverseDNSProvider, error) {
+	return fakeReverse{}, nil
+}
+
+// PTR returns the PTR name of an address set by the fake providers, empty if none
+func PTR(addr string) string {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.ptr[addr]
+}
+
+// PlanReverse implements dns.ReverseDNSProvider interface
+func (fakeReverse) PlanReverse(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return dns.Plan(ptrRecords(records), records), nil
+}
+
+// EnsureReverse implements dns.ReverseDNSProvider interface
+func (fakeReverse) EnsureReverse(_ context.Context, records []dns.Record) ([]dns.Change, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	changes := dns.Plan(ptrRecords(records), records)
+	for _, c := range changes {
+		if c.Action == dns.ChangeDelete {
+			delete(state.ptr, c.Record.Name)
+		} else {
+			state.ptr[c.Record.Name] = c.Record.Content
+		}
+	}
+	return changes, nil
+}
+
+// ptrRecords returns the existing PTR records of the addresses of the records, the state must be locked
+func ptrRecords(records []dns.Record) []dns.Record {
+	var existing []dns.Record
+	for _, r := range records {
+		if name, ok := state.ptr[r.Name]; ok {
+			existing = append(existing, dns.Record{Type: "PTR", Name: r.Name, Content: name, TTL: r.TTL})
+		}
+	}
+	return existing
+}
+
+// normalize returns the domain in lower case without trailing dot
+func normalize(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
@@ -0,0 +1,129 @@
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+// fakeMetal is the account of a platform at a metal provider, its servers are the ones set with
+// SetServers or the node files of the platform
+type fakeMetal struct {
+	name     string
+	platform string
+}
+
+// newMetal creates the fake of the metal providers
+func newMetal(infra schema.Infrastructure, opts provider.Options) (provider.MetalProvider, error) {
+	return &fakeMetal{name: infra.MetalProvider, platform: opts.Platform}, nil
+}
+
+// SetServers sets the servers of the account of a platform, replacing the ones of its node files
+func SetServers(platform string, servers []provider.Server) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.servers[platform] = slices.Clone(servers)
+}
+
+// ListServers implements provider.MetalProvider interface
+func (m *fakeMetal) ListServers(_ context.Context) ([]provider.Server, error) {
+	state.mu.Lock()
+	servers, ok := state.servers[m.platform]
+	state.mu.Unlock()
+	if !ok {
+		var err error
+		if servers, err = nodeServers(m.platform); err != nil {
+			return nil, err
+		}
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	servers = slices.Clone(servers)
+	for i, s := range servers {
+		s.Addresses = slices.Clone(s.Addresses)
+		for j, a := range s.Addresses {
+			if name, ok := state.ptr[a.IP]; ok && !a.Private {
+				s.Addresses[j].Reverse = name
+			}
+		}
+		servers[i] = s
+	}
+	return servers, nil
+}
+
+// GetServer implements provider.MetalProvider interface
+func (m *fakeMetal) GetServer(ctx context.Context, id string) (provider.Server, error) {
+	servers, err := m.ListServers(ctx)
+	if err != nil {
+		return provider.Server{}, err
+	}
+	for _, s := range servers {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return provider.Server{}, fmt.Errorf("%w: %s", provider.ErrServerNotFound, id)
+}
+
+// Reboot implements provider.MetalProvider interface, the reboot is recorded as a command
+func (m *fakeMetal) Reboot(ctx context.Context, id string) error {
+	if _, err := m.GetServer(ctx, id); err != nil {
+		return err
+	}
+	Record(Command{Name: m.name, Args: []string{"reboot", id}})
+	return nil
+}
+
+// SetPTR implements provider.MetalProvider interface
+func (m *fakeMetal) SetPTR(_ context.Context, addr, name string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if name == "" {
+		delete(state.ptr, ip.String())
+	} else {
+		state.ptr[ip.String()] = name
+	}
+	return nil
+}
+
+// nodeServers returns the servers of the node files of a platform, identified by their provider ID or
+// hostname
+func nodeServers(platform string) ([]provider.Server, error) {
+	if platform == "" {
+		return nil, nil
+	}
+	nodes, err := schema.LoadNodes(platform)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]provider.Server, 0, len(nodes))
+	for _, n := range nodes {
+		s := provider.Server{
+			ID:       n.ProviderID,
+			Hostname: n.Hostname,
+			Status:   "running",
+			Offer:    n.Offer,
+			Location: n.Location,
+		}
+		if s.ID == "" {
+			s.ID = n.Hostname
+		}
+		for _, ip := range n.PublicIPs {
+			s.Addresses = append(s.Addresses, provider.Address{IP: ip})
+		}
+		for _, ip := range n.PrivateIPs {
+			s.Addresses = append(s.Addresses, provider.Address{IP: ip, Private: true})
+		}
+		servers = append(servers, s)
+	}
+	return servers, nil
+}
@@ -0,0 +1,207 @@
+// Package simtest provides a harness running the platform operations in simulation from Go tests, so
+// workflows like create, deploy and destroy are exercised end-to-end without real infrastructure:
+//
+//	func TestLifecycle(t *testing.T) {
+//		h := simtest.New(t)
+//		ctx := t.Context()
+//		h.Create(ctx, platform.CreateOptions{Name: "dev", DNSProvider: "ovh", Domain: "dev.example.com"})
+//		h.Prepare()
+//		h.Deploy(ctx, platform.DeployOptions{Name: "dev", Tags: "core", InventorySource: "nodes"})
+//		// h.Commands() holds the composed ansible-playbook command
+//	}
+//
+// [Harness.Up] runs the platform:up workflow in a git repository of the directory, its steps but the
+// deployment are recorded.
+//
+// The harness changes the current directory and the global state of the simulation, the tests using it
+// can't run in parallel.
+package simtest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/actions/deploy"
+	"github.com/plasmash/plasmactl-platform/actions/up"
+	"github.com/plasmash/plasmactl-platform/internal/ci"
+	"github.com/plasmash/plasmactl-platform/internal/git"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+)
+
+// deployYaml is the platform:deploy action run by [Harness.Up], with the options passed by platform:up
+const deployYaml = `runtime: plugin
+action:
+  title: Deploy Platform
+  arguments:
+    - name: environment
+    - name: tags
+  options:
+    - name: img
+      type: string
+      default: ""
+    - name: debug
+      type: boolean
+      default: false
+    - name: check
+      type: boolean
+      default: false
+`
+
+// playbook is the platform playbook written by [Harness.Prepare]
+const playbook = `- hosts: all
+  gather_facts: false
+  tasks: []
+`
+
+// Harness is a repository of platforms in a temporary directory, whose operations run in simulation
+type Harness struct {
+	*platform.Client
+
+	Dir string // Repository root, the current directory while the test runs
+	tb  testing.TB
+}
+
+// New creates a harness in a temporary directory, which is the current one until the test ends. The
+// simulation is enabled until then too.
+func New(tb testing.TB) *Harness {
+	tb.Helper()
+	dir := tb.TempDir()
+	tb.Chdir(dir)
+	simulate.Enable()
+	tb.Cleanup(simulate.Disable)
+	return &Harness{Client: platform.New(platform.Options{}), Dir: dir, tb: tb}
+}
+
+// WriteFile writes a file of the repository, creating its directory, and fails the test if it can't
+func (h *Harness) WriteFile(name string, data []byte) {
+	h.tb.Helper()
+	path := filepath.Join(h.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		h.tb.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		h.tb.Fatal(err)
+	}
+}
+
+// Prepare writes a prepared platform with an empty playbook, the working directory of the deployments
+// without image
+func (h *Harness) Prepare() {
+	h.tb.Helper()
+	h.WriteFile(filepath.Join(platform.DefaultPrepareDir, "platform", "platform.yaml"), []byte(playbook))
+}
+
+// AddNode writes the node file of a platform, and fails the test if it can't
+func (h *Harness) AddNode(name string, node schema.Node) {
+	h.tb.Helper()
+	if err := os.MkdirAll(schema.NodesDir(name), 0o755); err != nil {
+		h.tb.Fatal(err)
+	}
+	node.File = schema.NodeFile(name, node.Hostname)
+	if err := node.Save(); err != nil {
+		h.tb.Fatal(err)
+	}
+}
+
+// Create creates a platform and fails the test if it can't
+func (h *Harness) Create(ctx context.Context, opts platform.CreateOptions) *schema.Platform {
+	h.tb.Helper()
+	p, err := h.Client.Create(ctx, opts)
+	if err != nil {
+		h.tb.Fatalf("create %s: %v", opts.Name, err)
+	}
+	return p
+}
+
+// Deploy deploys a platform and fails the test if it can't
+func (h *Harness) Deploy(ctx context.Context, opts platform.DeployOptions) *platform.Deployment {
+	h.tb.Helper()
+	d, err := h.Client.Deploy(ctx, opts)
+	if err != nil {
+		h.tb.Fatalf("deploy %s: %v", opts.Name, err)
+	}
+	return d
+}
+
+// Destroy destroys a platform and fails the test if it can't
+func (h *Harness) Destroy(ctx context.Context, opts platform.DestroyOptions) {
+	h.tb.Helper()
+	if err := h.Client.Destroy(ctx, opts); err != nil {
+		h.tb.Fatalf("destroy %s: %v", opts.Name, err)
+	}
+}
+
+// Up runs the platform:up workflow of a platform and fails the test if it can't. The directory becomes a
+// git repository whose changes are committed by the run, unless opts.Dirty says otherwise. The
+// deployment uses the static inventory of the node files.
+func (h *Harness) Up(ctx context.Context, environment, tags string, opts up.UpOptions) {
+	h.tb.Helper()
+	h.initRepo()
+	log, term := launchr.Log(), launchr.Term()
+	u := &up.Up{M: h.manager()}
+	u.SetLogger(log)
+	u.SetTerm(term)
+	u.G = &git.GitUp{WithLogger: u.WithLogger, WithTerm: u.WithTerm}
+	u.CI = &ci.ContinuousIntegration{WithLogger: u.WithLogger, WithTerm: u.WithTerm}
+	if err := u.Run(ctx, environment, tags, opts); err != nil {
+		h.tb.Fatalf("up %s: %v", environment, err)
+	}
+}
+
+// manager returns the actions run by platform:up without being recorded, only platform:deploy
+func (h *Harness) manager() action.Manager {
+	m := action.NewManager()
+	m.SetTemplateProcessors(action.NewTemplateProcessors())
+	a := action.NewFromYAML("platform:deploy", []byte(deployYaml))
+	a.SetRuntime(action.NewFnRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		_, err := h.Client.Deploy(ctx, platform.DeployOptions{
+			Name:            input.Arg("environment").(string),
+			Tags:            input.Arg("tags").(string),
+			Img:             input.Opt("img").(string),
+			Debug:           input.Opt("debug").(bool),
+			Check:           input.Opt("check").(bool),
+			InventorySource: deploy.InventorySourceNodes,
+		})
+		return err
+	}))
+	if err := m.Add(a); err != nil {
+		h.tb.Fatal(err)
+	}
+	return m
+}
+
+// initRepo makes the directory a git repository with an identity for the commits, once
+func (h *Harness) initRepo() {
+	h.tb.Helper()
+	if _, err := os.Stat(filepath.Join(h.Dir, ".git")); err == nil {
+		return
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		h.tb.Setenv(name, "simtest")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		h.tb.Setenv(name, "simtest@example.com")
+	}
+	if out, err := exec.Command("git", "init", "--quiet", h.Dir).CombinedOutput(); err != nil {
+		h.tb.Fatalf("git init: %v: %s", err, out)
+	}
+}
+
+// Commands returns the commands recorded by the simulation, e.g. the composed ansible-playbook command
+func (h *Harness) Commands() []simulate.Command {
+	return simulate.Commands()
+}
+
+// Records returns the records of a domain in the fake DNS
+func (h *Harness) Records(domain string) []dns.Record {
+	return simulate.Records(domain)
+}
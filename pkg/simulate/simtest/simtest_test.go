@@ -0,0 +1,59 @@
+package simtest_test
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/plasmash/plasmactl-platform/actions/up"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/platform"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate/simtest"
+)
+
+func TestUpAndDestroy(t *testing.T) {
+	h := simtest.New(t)
+	ctx := t.Context()
+
+	p := h.Create(ctx, platform.CreateOptions{Name: "dev", MetalProvider: "ovh", DNSProvider: "ovh", Domain: "dev.example.com"})
+	p.DNS.Addresses = map[string][]string{"@": {"192.0.2.10"}}
+	if err := p.Save(schema.PlatformFile("dev")); err != nil {
+		t.Fatal(err)
+	}
+	// The record of the platform and one of another service sharing its name
+	address := dns.Record{Type: "A", Name: "dev.example.com", Content: "192.0.2.10"}
+	other := dns.Record{Type: "A", Name: "dev.example.com", Content: "198.51.100.1"}
+	simulate.SetRecords("dev.example.com", []dns.Record{address, other})
+	h.AddNode("dev", schema.Node{Hostname: "node1", PublicIPs: []string{"192.0.2.10"}})
+	h.Prepare()
+
+	h.Up(ctx, "dev", "core", up.UpOptions{Local: true})
+	names := commandNames(h.Commands())
+	for _, want := range []string{"component:bump", "model:compose", "model:prepare", "component:sync", "platform:package", "ansible-playbook"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("up did not run %s, commands: %v", want, names)
+		}
+	}
+	if i := slices.Index(names, "ansible-playbook"); i >= 0 && i < slices.Index(names, "platform:package") {
+		t.Errorf("up deployed before packaging, commands: %v", names)
+	}
+
+	h.Destroy(ctx, platform.DestroyOptions{Name: "dev"})
+	if records := h.Records("dev.example.com"); !slices.Equal(records, []dns.Record{other}) {
+		t.Errorf("records after destroy = %v, want %v", records, []dns.Record{other})
+	}
+	if _, err := os.Stat(schema.PlatformDir("dev")); !os.IsNotExist(err) {
+		t.Errorf("destroy kept the platform directory: %v", err)
+	}
+}
+
+// commandNames returns the names of the recorded commands, in their order
+func commandNames(commands []simulate.Command) []string {
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+// Package simulate runs the platform workflows without real infrastructure. In simulation the DNS and
+// metal providers are replaced by in-memory fakes, and the commands changing the infrastructure, like
+// ansible-playbook and terraform, are recorded instead of being run. The files of the repository are
+// changed as in a real run, so the workflows can be exercised end-to-end in CI, see the simtest package.
+package simulate
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/launchrctl/launchr"
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+)
+
+// EnvVar enables the simulation when set to 1, it's set by [Enable] so the child processes simulate too
+var EnvVar = launchr.EnvVar("simulate")
+
+var enabled atomic.Bool
+
+// Enabled reports whether the simulation is enabled in the process
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Enable replaces the DNS and metal providers with the fakes and makes the actions record their
+// commands instead of running them
+func Enable() {
+	enabled.Store(true)
+	_ = EnvVar.Set("1")
+	dns.Simulate(newDNS, newReverse)
+	provider.Simulate(newMetal)
+}
+
+// Disable restores the real providers and commands, and forgets the state of the fakes and the
+// recorded commands
+func Disable() {
+	enabled.Store(false)
+	_ = EnvVar.Unset()
+	dns.Simulate(nil, nil)
+	provider.Simulate(nil)
+	Reset()
+}
+
+// Reset forgets the state of the fakes and the recorded commands, keeping the simulation enabled
+func Reset() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.commands = nil
+	state.zones = make(map[string][]dns.Record)
+	state.ptr = make(map[string]string)
+	state.servers = make(map[string][]provider.Server)
+}
+
+// state is the infrastructure of the simulation, shared by the fakes of the process
+var state = struct {
+	mu       sync.Mutex
+	commands []Command
+	zones    map[string][]dns.Record      // Records by domain
+	ptr      map[string]string            // PTR names by address
+	servers  map[string][]provider.Server // Servers by platform, set with SetServers
+}{
+	zones:   make(map[string][]dns.Record),
+	ptr:     make(map[string]string),
+	servers: make(map[string][]provider.Server),
+}
@@ -4,18 +4,39 @@ package platform
 import (
 	"context"
 	"embed"
+	"os"
 
 	"github.com/launchrctl/keyring"
 	"github.com/launchrctl/launchr"
 	"github.com/launchrctl/launchr/pkg/action"
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
 
+	"github.com/plasmash/plasmactl-platform/actions/cert"
+	"github.com/plasmash/plasmactl-platform/actions/ci"
 	"github.com/plasmash/plasmactl-platform/actions/create"
+	"github.com/plasmash/plasmactl-platform/actions/credentials"
 	"github.com/plasmash/plasmactl-platform/actions/deploy"
 	"github.com/plasmash/plasmactl-platform/actions/destroy"
+	"github.com/plasmash/plasmactl-platform/actions/dns"
+	"github.com/plasmash/plasmactl-platform/actions/history"
+	"github.com/plasmash/plasmactl-platform/actions/image"
 	"github.com/plasmash/plasmactl-platform/actions/list"
+	"github.com/plasmash/plasmactl-platform/actions/nodes"
+	"github.com/plasmash/plasmactl-platform/actions/plan"
+	"github.com/plasmash/plasmactl-platform/actions/provider"
+	"github.com/plasmash/plasmactl-platform/actions/publish"
+	"github.com/plasmash/plasmactl-platform/actions/secret"
+	"github.com/plasmash/plasmactl-platform/actions/serve"
 	"github.com/plasmash/plasmactl-platform/actions/show"
+	"github.com/plasmash/plasmactl-platform/actions/status"
 	"github.com/plasmash/plasmactl-platform/actions/up"
 	"github.com/plasmash/plasmactl-platform/actions/validate"
+	"github.com/plasmash/plasmactl-platform/internal/output"
+	"github.com/plasmash/plasmactl-platform/internal/redact"
+	"github.com/plasmash/plasmactl-platform/pkg/event"
+	platformapi "github.com/plasmash/plasmactl-platform/pkg/platform"
+	"github.com/plasmash/plasmactl-platform/pkg/result"
+	"github.com/plasmash/plasmactl-platform/pkg/simulate"
 )
 
 //go:embed actions/*/*.yaml
@@ -29,7 +50,11 @@ func init() {
 type Plugin struct {
 	k   keyring.Keyring
 	m   action.Manager
+	cfg launchr.Config
 	app launchr.App
+
+	events   event.Config // Sinks of the lifecycle events, added unless the run is simulated
+	simulate bool
 }
 
 // PluginInfo implements [launchr.Plugin] interface.
@@ -43,7 +68,54 @@ func (p *Plugin) PluginInfo() launchr.PluginInfo {
 func (p *Plugin) OnAppInit(app launchr.App) error {
 	app.GetService(&p.k)
 	app.GetService(&p.m)
+	app.GetService(&p.cfg)
 	p.app = app
+	// Other plugins resolve the platforms through the service, it's created with the keyring and the
+	// config of the app unless one of them already got it.
+	var platforms platformapi.PlatformConfigService
+	app.GetService(&platforms)
+	// Mask the secrets read from outside the keyring in the output streams as well.
+	redact.SetMask(app.SensitiveMask())
+	// Lifecycle events are forwarded to the sinks of the config once the command is parsed.
+	events, err := event.LoadConfig(p.cfg)
+	if err != nil {
+		return err
+	}
+	p.events = events
+	// The infrastructure of all the actions is simulated with --simulate.
+	if root, ok := app.(interface{ RootCmd() *launchr.Command }); ok {
+		root.RootCmd().PersistentFlags().BoolVar(&p.simulate, "simulate", false,
+			"run the platform actions with fake providers, recording the commands changing the infrastructure instead of running them")
+	}
+	// Providers of other plugins are available to the actions like the built-in ones.
+	return registerPluginProviders(app)
+}
+
+// PersistentPreRun implements [launchr.PersistentPreRunPlugin] interface.
+func (p *Plugin) PersistentPreRun(_ *launchr.Command, _ []string) error {
+	if p.simulate || simulate.EnvVar.Get() == "1" {
+		simulate.Enable()
+		launchr.Term().Warning().Println("Simulation: the providers are fake, the commands changing the infrastructure are recorded instead of run")
+		// Events of simulated runs must not reach the sinks watching the real platforms
+		return nil
+	}
+	return event.Default().AddSinks(p.events, launchr.Log())
+}
+
+// CobraAddCommands implements [launchr.CobraPlugin] interface.
+func (p *Plugin) CobraAddCommands(root *launchr.Command) error {
+	if !deploy.IsAskpass() {
+		return nil
+	}
+	// Run as askpass program by ansible or ssh, the root command prints the vault password. Its
+	// arguments are the prompt of ssh or the vault ID of ansible, not the ones of plasmactl.
+	root.Args = func(*launchr.Command, []string) error { return nil }
+	root.DisableFlagParsing = true
+	root.PersistentPreRunE = nil
+	root.RunE = func(_ *launchr.Command, args []string) error {
+		// Straight to stdout, the output streams of the app mask the secrets
+		return deploy.RunAskpass(os.Stdout, args)
+	}
 	return nil
 }
 
@@ -54,10 +126,20 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 	// platform:up action (full workflow: compose → prepare → deploy)
 	upYaml, _ := actionYamlFS.ReadFile("actions/up/up.yaml")
 	upAction := action.NewFromYAML("platform:up", upYaml)
-	upAction.SetRuntime(action.NewFnRuntime(func(ctx context.Context, a *action.Action) error {
+	upAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		// Connection settings of platform.deploy are shared with platform:deploy, platform.up takes precedence.
+		if err := applyConfigDefaults(a, p.cfg, "platform.deploy", "platform.up"); err != nil {
+			return err
+		}
 		input := a.Input()
-		env := input.Arg("environment").(string)
-		tags := input.Arg("tags").(string)
+		env, err := requiredArg(input, "platform.up", "environment")
+		if err != nil {
+			return err
+		}
+		tags, err := requiredArg(input, "platform.up", "tags")
+		if err != nil {
+			return err
+		}
 		v := launchr.Version()
 		options := up.UpOptions{
 			Bin:                v.Name,
@@ -71,12 +153,41 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			CleanPrepare:       input.Opt("clean-prepare").(bool),
 			Debug:              input.Opt("debug").(bool),
 			ConflictsVerbosity: input.Opt("conflicts-verbosity").(bool),
+			CommitMessage:      input.Opt("commit-message").(string),
+			SignCommit:         input.Opt("sign-commit").(bool),
+			Dirty:              input.Opt("dirty").(string),
+			NoAutoCommit:       input.Opt("no-auto-commit").(bool),
+			Containerized:      input.Opt("containerized").(bool),
+			ContainerImage:     input.Opt("container-image").(string),
 			GitlabDomain:       input.Opt("gitlab-domain").(string),
+			GitlabAuth:         input.Opt("gitlab-auth").(string),
+			APIRetries:         input.Opt("api-retries").(int),
+			APITimeout:         input.Opt("api-timeout").(string),
+			CIProvider:         input.Opt("ci-provider").(string),
+			GiteaDomain:        input.Opt("gitea-domain").(string),
+			Follow:             input.Opt("follow").(bool),
+			Wait:               input.Opt("wait").(bool),
+			Timeout:            input.Opt("timeout").(string),
+			CancelOnInterrupt:  input.Opt("cancel-on-interrupt").(bool),
+			Queue:              input.Opt("queue").(bool),
+			FetchImage:         input.Opt("fetch-image").(bool),
+			CreateMR:           input.Opt("create-mr").(bool),
+			MRTarget:           input.Opt("mr-target").(string),
+			PipelineVars:       action.InputOptSlice[string](input, "pipeline-var"),
+			RunnerTags:         action.InputOptSlice[string](input, "runner-tag"),
+			Schedule:           input.Opt("schedule").(string),
+			CreateSchedule:     input.Opt("create-schedule").(bool),
+			Resume:             input.Opt("resume").(bool),
+			Only:               action.InputOptSlice[string](input, "only"),
+			Skip:               action.InputOptSlice[string](input, "skip"),
+			Publish:            input.Opt("publish").(bool),
+			SkipUnchanged:      input.Opt("skip-unchanged").(bool),
 			Streams:            a.Input().Streams(),
 			Persistent:         a.Input().GroupFlags(p.m.GetPersistentFlags().GetName()),
 		}
 
 		u := up.NewUp(a, p.k, p.m)
+		u.Config = p.cfg
 		return u.Run(ctx, env, tags, options)
 	}))
 	actions = append(actions, upAction)
@@ -84,7 +195,7 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 	// platform:create action
 	createYaml, _ := actionYamlFS.ReadFile("actions/create/create.yaml")
 	createAction := action.NewFromYAML("platform:create", createYaml)
-	createAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	createAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
 		input := a.Input()
 		log, term := getLoggerTerm(a)
 		c := &create.Create{
@@ -94,65 +205,119 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			DNSProvider:   input.Opt("dns-provider").(string),
 			Domain:        input.Opt("domain").(string),
 			SkipDNS:       input.Opt("skip-dns").(bool),
+
+			WaitPropagation:    input.Opt("wait-propagation").(bool),
+			PropagationTimeout: input.Opt("propagation-timeout").(string),
 		}
 		c.SetLogger(log)
 		c.SetTerm(term)
-		return c.Execute()
+		err := c.Execute(ctx)
+		result.Record(ctx, c.Result())
+		return err
 	}))
 	actions = append(actions, createAction)
 
 	// platform:list action
 	listYaml, _ := actionYamlFS.ReadFile("actions/list/list.yaml")
 	listAction := action.NewFromYAML("platform:list", listYaml)
-	listAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	listAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		if err := applyConfigDefaults(a, p.cfg, "platform.list"); err != nil {
+			return err
+		}
 		input := a.Input()
 		log, term := getLoggerTerm(a)
 		l := &list.List{
 			Format: input.Opt("output").(string),
+			Filter: input.Opt("filter").(string),
+
+			Sort:     input.Opt("sort").(string),
+			Columns:  input.Opt("columns").(string),
+			NoHeader: input.Opt("no-header").(bool),
+
+			Roots: action.InputOptSlice[string](input, "root"),
+
+			Watch:    input.Opt("watch").(bool),
+			Interval: input.Opt("interval").(string),
 		}
 		l.SetLogger(log)
 		l.SetTerm(term)
-		return l.Execute()
+		return l.Execute(ctx)
 	}))
 	actions = append(actions, listAction)
 
 	// platform:show action
 	showYaml, _ := actionYamlFS.ReadFile("actions/show/show.yaml")
 	showAction := action.NewFromYAML("platform:show", showYaml)
-	showAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	showAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
 		input := a.Input()
 		log, term := getLoggerTerm(a)
 		s := &show.Show{
 			Name:   input.Arg("name").(string),
 			Format: input.Opt("output").(string),
+
+			Health:  input.Opt("health").(bool),
+			HTTP:    input.Opt("http").(string),
+			SSHPort: input.Opt("ssh-port").(int),
+			Timeout: input.Opt("timeout").(string),
+
+			NodesOnly: input.Opt("nodes-only").(bool),
+			Config:    input.Opt("config").(bool),
+			History:   input.Opt("history").(int),
 		}
 		s.SetLogger(log)
 		s.SetTerm(term)
-		return s.Execute()
+		err := s.Execute(ctx)
+		result.Record(ctx, s.Result())
+		return err
 	}))
 	actions = append(actions, showAction)
 
 	// platform:validate action
 	validateYaml, _ := actionYamlFS.ReadFile("actions/validate/validate.yaml")
 	validateAction := action.NewFromYAML("platform:validate", validateYaml)
-	validateAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	validateAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
 		input := a.Input()
 		log, term := getLoggerTerm(a)
 		v := &validate.Validate{
-			Name:     input.Arg("name").(string),
-			SkipDNS:  input.Opt("skip-dns").(bool),
-			SkipMail: input.Opt("skip-mail").(bool),
+			Keyring:   p.k,
+			Name:      input.Arg("name").(string),
+			SkipDNS:   input.Opt("skip-dns").(bool),
+			SkipMail:  input.Opt("skip-mail").(bool),
+			SkipInfra: input.Opt("skip-infra").(bool),
+
+			SkipMTASTS: input.Opt("skip-mta-sts").(bool),
+			SkipTLSRPT: input.Opt("skip-tls-rpt").(bool),
+			SkipDNSSEC: input.Opt("skip-dnssec").(bool),
+			SkipTLS:    input.Opt("skip-tls").(bool),
+			DeepMail:   input.Opt("deep-mail").(bool),
+
+			TLSExpiryDays: input.Opt("tls-expiry-days").(int),
+
+			Resolver: input.Opt("resolver").(string),
+			DoH:      input.Opt("doh").(string),
+			Format:   input.Opt("output").(string),
+
+			Parallel:     input.Opt("parallel").(int),
+			CheckTimeout: input.Opt("check-timeout").(string),
+			Timeout:      input.Opt("timeout").(string),
+
+			Watch:    input.Opt("watch").(bool),
+			Interval: input.Opt("interval").(string),
+			Notify:   input.Opt("notify").(string),
 		}
 		v.SetLogger(log)
 		v.SetTerm(term)
-		return v.Execute()
+		// The report of a failed validation is recorded too
+		err := v.Execute(ctx)
+		result.Record(ctx, v.Result())
+		return err
 	}))
 	actions = append(actions, validateAction)
 
 	// platform:destroy action
 	destroyYaml, _ := actionYamlFS.ReadFile("actions/destroy/destroy.yaml")
 	destroyAction := action.NewFromYAML("platform:destroy", destroyYaml)
-	destroyAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	destroyAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
 		input := a.Input()
 		log, term := getLoggerTerm(a)
 		d := &destroy.Destroy{
@@ -163,33 +328,535 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		}
 		d.SetLogger(log)
 		d.SetTerm(term)
-		return d.Execute()
+		return d.Execute(ctx)
 	}))
 	actions = append(actions, destroyAction)
 
 	// platform:deploy action
 	deployYaml, _ := actionYamlFS.ReadFile("actions/deploy/deploy.yaml")
 	deployAction := action.NewFromYAML("platform:deploy", deployYaml)
-	deployAction.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+	deployAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		if err := applyConfigDefaults(a, p.cfg, "platform.deploy"); err != nil {
+			return err
+		}
 		input := a.Input()
+		env, err := requiredArg(input, "platform.deploy", "environment")
+		if err != nil {
+			return err
+		}
+		tags, err := requiredArg(input, "platform.deploy", "tags")
+		if err != nil {
+			return err
+		}
 		log, term := getLoggerTerm(a)
 		d := &deploy.Deploy{
 			Keyring:     p.k,
-			Environment: input.Arg("environment").(string),
-			Tags:        input.Arg("tags").(string),
+			Environment: env,
+			Tags:        tags,
 			Img:         input.Opt("img").(string),
+			BaseImg:     input.Opt("base-img").(string),
 			Debug:       input.Opt("debug").(bool),
 			Check:       input.Opt("check").(bool),
 			Password:    input.Opt("password").(string),
 			Logs:        input.Opt("logs").(bool),
 			PrepareDir:  input.Opt("prepare-dir").(string),
+			SyntaxCheck: input.Opt("syntax-check").(bool),
+			Lint:        input.Opt("lint").(bool),
+			Strict:      input.Opt("strict").(bool),
+			PlanOutput:  input.Opt("plan-output").(string),
+
+			InventorySource: input.Opt("inventory-source").(string),
+			Limit:           input.Opt("limit").(string),
+			SkipPreflight:   input.Opt("skip-preflight").(bool),
+			PasswordMode:    input.Opt("password-mode").(string),
 		}
 		d.SetLogger(log)
 		d.SetTerm(term)
-		return d.Execute()
+		err = d.Execute(ctx)
+		result.Record(ctx, d.Result())
+		return err
 	}))
 	actions = append(actions, deployAction)
 
+	// platform:publish action
+	publishYaml, _ := actionYamlFS.ReadFile("actions/publish/publish.yaml")
+	publishAction := action.NewFromYAML("platform:publish", publishYaml)
+	publishAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pub := &publish.Publish{
+			Keyring:     p.k,
+			Config:      p.cfg,
+			Image:       input.Arg("image").(string),
+			Platform:    input.Opt("platform").(string),
+			URL:         input.Opt("url").(string),
+			Path:        input.Opt("path").(string),
+			Credentials: input.Opt("credentials").(string),
+			Verify:      input.Opt("verify").(bool),
+			PruneKeep:   input.Opt("prune-keep").(int),
+		}
+		pub.SetLogger(log)
+		pub.SetTerm(term)
+		return pub.Execute(ctx)
+	}))
+	actions = append(actions, publishAction)
+
+	// platform:artifact:prune action
+	artifactPruneYaml, _ := actionYamlFS.ReadFile("actions/publish/prune.yaml")
+	artifactPruneAction := action.NewFromYAML("platform:artifact:prune", artifactPruneYaml)
+	artifactPruneAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pr := &publish.Prune{
+			Keyring:     p.k,
+			Config:      p.cfg,
+			Platform:    input.Opt("platform").(string),
+			URL:         input.Opt("url").(string),
+			Credentials: input.Opt("credentials").(string),
+			Keep:        input.Opt("keep").(int),
+			MaxAge:      input.Opt("max-age").(string),
+			DryRun:      input.Opt("dry-run").(bool),
+		}
+		pr.SetLogger(log)
+		pr.SetTerm(term)
+		return pr.Execute(ctx)
+	}))
+	actions = append(actions, artifactPruneAction)
+
+	// platform:image:sign action
+	signYaml, _ := actionYamlFS.ReadFile("actions/image/sign.yaml")
+	signAction := action.NewFromYAML("platform:image:sign", signYaml)
+	signAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		s := &image.Sign{
+			Image:  input.Arg("image").(string),
+			Key:    input.Opt("key").(string),
+			Bundle: input.Opt("bundle").(string),
+		}
+		s.SetLogger(log)
+		s.SetTerm(term)
+		return s.Execute(ctx)
+	}))
+	actions = append(actions, signAction)
+
+	// platform:image:inspect action
+	inspectYaml, _ := actionYamlFS.ReadFile("actions/image/inspect.yaml")
+	inspectAction := action.NewFromYAML("platform:image:inspect", inspectYaml)
+	inspectAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		i := &image.Inspect{
+			Image:  input.Arg("image").(string),
+			Format: input.Opt("output").(string),
+		}
+		i.SetLogger(log)
+		i.SetTerm(term)
+		return i.Execute(ctx)
+	}))
+	actions = append(actions, inspectAction)
+
+	// platform:package action
+	packageYaml, _ := actionYamlFS.ReadFile("actions/image/package.yaml")
+	packageAction := action.NewFromYAML("platform:package", packageYaml)
+	packageAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pkg := &image.Package{
+			Config:       p.cfg,
+			Environment:  input.Opt("environment").(string),
+			SourceDir:    input.Opt("source-dir").(string),
+			OutputDir:    input.Opt("output-dir").(string),
+			NameTemplate: input.Opt("name-template").(string),
+			Compression:  input.Opt("compression").(string),
+			Level:        input.Opt("level").(int),
+			Base:         input.Opt("base").(string),
+		}
+		pkg.SetLogger(log)
+		pkg.SetTerm(term)
+		err := pkg.Execute(ctx)
+		result.Record(ctx, pkg.Result())
+		return err
+	}))
+	actions = append(actions, packageAction)
+
+	// platform:image:list action
+	imageListYaml, _ := actionYamlFS.ReadFile("actions/image/list.yaml")
+	imageListAction := action.NewFromYAML("platform:image:list", imageListYaml)
+	imageListAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		l := &image.List{
+			Format: input.Opt("output").(string),
+		}
+		l.SetLogger(log)
+		l.SetTerm(term)
+		return l.Execute()
+	}))
+	actions = append(actions, imageListAction)
+
+	// platform:image:prune action
+	pruneYaml, _ := actionYamlFS.ReadFile("actions/image/prune.yaml")
+	pruneAction := action.NewFromYAML("platform:image:prune", pruneYaml)
+	pruneAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pr := &image.Prune{
+			Keep:   input.Opt("keep").(int),
+			DryRun: input.Opt("dry-run").(bool),
+		}
+		pr.SetLogger(log)
+		pr.SetTerm(term)
+		return pr.Execute()
+	}))
+	actions = append(actions, pruneAction)
+
+	// platform:ci:artifacts action
+	artifactsYaml, _ := actionYamlFS.ReadFile("actions/ci/artifacts.yaml")
+	artifactsAction := action.NewFromYAML("platform:ci:artifacts", artifactsYaml)
+	artifactsAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		if err := applyConfigDefaults(a, p.cfg, "platform.deploy"); err != nil {
+			return err
+		}
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		art := &ci.Artifacts{
+			Keyring:      p.k,
+			Pipeline:     input.Opt("pipeline").(int),
+			Job:          input.Opt("job").(string),
+			OutputDir:    input.Opt("output-dir").(string),
+			GitlabDomain: input.Opt("gitlab-domain").(string),
+			GitlabAuth:   input.Opt("gitlab-auth").(string),
+			APIRetries:   input.Opt("api-retries").(int),
+			APITimeout:   input.Opt("api-timeout").(string),
+			Platform:     input.Opt("platform").(string),
+		}
+		art.SetLogger(log)
+		art.SetTerm(term)
+		return art.Execute(ctx)
+	}))
+	actions = append(actions, artifactsAction)
+
+	// platform:dns:apply action
+	dnsApplyYaml, _ := actionYamlFS.ReadFile("actions/dns/apply.yaml")
+	dnsApplyAction := action.NewFromYAML("platform:dns:apply", dnsApplyYaml)
+	dnsApplyAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ap := &dns.Apply{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			DryRun:  input.Opt("dry-run").(bool),
+
+			WaitPropagation:    input.Opt("wait-propagation").(bool),
+			PropagationTimeout: input.Opt("propagation-timeout").(string),
+			Resolver:           input.Opt("resolver").(string),
+			DoH:                input.Opt("doh").(string),
+		}
+		ap.SetLogger(log)
+		ap.SetTerm(term)
+		return ap.Execute(ctx)
+	}))
+	actions = append(actions, dnsApplyAction)
+
+	// platform:dns:export action
+	dnsExportYaml, _ := actionYamlFS.ReadFile("actions/dns/export.yaml")
+	dnsExportAction := action.NewFromYAML("platform:dns:export", dnsExportYaml)
+	dnsExportAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ex := &dns.Export{
+			Name:   input.Arg("name").(string),
+			Format: input.Opt("output").(string),
+		}
+		ex.SetLogger(log)
+		ex.SetTerm(term)
+		return ex.Execute()
+	}))
+	actions = append(actions, dnsExportAction)
+
+	// platform:cert:issue action
+	certIssueYaml, _ := actionYamlFS.ReadFile("actions/cert/issue.yaml")
+	certIssueAction := action.NewFromYAML("platform:cert:issue", certIssueYaml)
+	certIssueAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ci := &cert.Issue{
+			Keyring:  p.k,
+			Name:     input.Arg("name").(string),
+			Password: input.Opt("password").(string),
+			Staging:  input.Opt("staging").(bool),
+
+			PropagationTimeout: input.Opt("propagation-timeout").(string),
+		}
+		ci.SetLogger(log)
+		ci.SetTerm(term)
+		return ci.Execute(ctx)
+	}))
+	actions = append(actions, certIssueAction)
+
+	// platform:cert:renew action
+	certRenewYaml, _ := actionYamlFS.ReadFile("actions/cert/renew.yaml")
+	certRenewAction := action.NewFromYAML("platform:cert:renew", certRenewYaml)
+	certRenewAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		cr := &cert.Renew{
+			Keyring:  p.k,
+			Name:     input.Arg("name").(string),
+			Password: input.Opt("password").(string),
+			Staging:  input.Opt("staging").(bool),
+			Days:     input.Opt("days").(int),
+			Force:    input.Opt("force").(bool),
+
+			PropagationTimeout: input.Opt("propagation-timeout").(string),
+		}
+		cr.SetLogger(log)
+		cr.SetTerm(term)
+		return cr.Execute(ctx)
+	}))
+	actions = append(actions, certRenewAction)
+
+	// platform:cert:status action
+	certStatusYaml, _ := actionYamlFS.ReadFile("actions/cert/status.yaml")
+	certStatusAction := action.NewFromYAML("platform:cert:status", certStatusYaml)
+	certStatusAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		cs := &cert.Status{
+			Name:   input.Arg("name").(string),
+			Format: input.Opt("output").(string),
+		}
+		cs.SetLogger(log)
+		cs.SetTerm(term)
+		return cs.Execute()
+	}))
+	actions = append(actions, certStatusAction)
+
+	// platform:provider:check action
+	providerCheckYaml, _ := actionYamlFS.ReadFile("actions/provider/check.yaml")
+	providerCheckAction := action.NewFromYAML("platform:provider:check", providerCheckYaml)
+	providerCheckAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pc := &provider.Check{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			Format:  input.Opt("output").(string),
+		}
+		pc.SetLogger(log)
+		pc.SetTerm(term)
+		return pc.Execute(ctx)
+	}))
+	actions = append(actions, providerCheckAction)
+
+	// platform:nodes:sync action
+	nodesSyncYaml, _ := actionYamlFS.ReadFile("actions/nodes/sync.yaml")
+	nodesSyncAction := action.NewFromYAML("platform:nodes:sync", nodesSyncYaml)
+	nodesSyncAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ns := &nodes.Sync{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			DryRun:  input.Opt("dry-run").(bool),
+		}
+		ns.SetLogger(log)
+		ns.SetTerm(term)
+		return ns.Execute(ctx)
+	}))
+	actions = append(actions, nodesSyncAction)
+
+	// platform:nodes:import action
+	nodesImportYaml, _ := actionYamlFS.ReadFile("actions/nodes/import.yaml")
+	nodesImportAction := action.NewFromYAML("platform:nodes:import", nodesImportYaml)
+	nodesImportAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ni := &nodes.Import{
+			Name:    input.Arg("name").(string),
+			TFState: input.Opt("tfstate").(string),
+			DryRun:  input.Opt("dry-run").(bool),
+		}
+		ni.SetLogger(log)
+		ni.SetTerm(term)
+		return ni.Execute(ctx)
+	}))
+	actions = append(actions, nodesImportAction)
+
+	// platform:plan action
+	planYaml, _ := actionYamlFS.ReadFile("actions/plan/plan.yaml")
+	planAction := action.NewFromYAML("platform:plan", planYaml)
+	planAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		pl := &plan.Plan{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			Format:  input.Opt("output").(string),
+			Offline: input.Opt("offline").(bool),
+		}
+		pl.SetLogger(log)
+		pl.SetTerm(term)
+		return pl.Execute(ctx)
+	}))
+	actions = append(actions, planAction)
+
+	// platform:status action
+	statusYaml, _ := actionYamlFS.ReadFile("actions/status/status.yaml")
+	statusAction := action.NewFromYAML("platform:status", statusYaml)
+	statusAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		st := &status.Status{
+			Name:    input.Arg("name").(string),
+			Format:  input.Opt("output").(string),
+			HTTP:    input.Opt("http").(string),
+			SSHPort: input.Opt("ssh-port").(int),
+			Timeout: input.Opt("timeout").(string),
+		}
+		st.SetLogger(log)
+		st.SetTerm(term)
+		return st.Execute(ctx)
+	}))
+	actions = append(actions, statusAction)
+
+	// platform:history action
+	historyYaml, _ := actionYamlFS.ReadFile("actions/history/history.yaml")
+	historyAction := action.NewFromYAML("platform:history", historyYaml)
+	historyAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		h := &history.History{
+			Name:   input.Arg("name").(string),
+			Format: input.Opt("output").(string),
+			Limit:  input.Opt("limit").(int),
+		}
+		h.SetLogger(log)
+		h.SetTerm(term)
+		return h.Execute()
+	}))
+	actions = append(actions, historyAction)
+
+	// platform:serve action
+	serveYaml, _ := actionYamlFS.ReadFile("actions/serve/serve.yaml")
+	serveAction := action.NewFromYAML("platform:serve", serveYaml)
+	serveAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		sv := &serve.Serve{
+			Keyring:     p.k,
+			Listen:      input.Opt("listen").(string),
+			TokenKey:    input.Opt("token-key").(string),
+			AllowDeploy: input.Opt("allow-deploy").(bool),
+		}
+		sv.SetLogger(log)
+		sv.SetTerm(term)
+		return sv.Execute(ctx)
+	}))
+	actions = append(actions, serveAction)
+
+	// platform:secret:get action
+	secretGetYaml, _ := actionYamlFS.ReadFile("actions/secret/get.yaml")
+	secretGetAction := action.NewFromYAML("platform:secret:get", secretGetYaml)
+	secretGetAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		sg := &secret.Get{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			Key:     input.Arg("key").(string),
+		}
+		sg.SetLogger(log)
+		sg.SetTerm(term)
+		return sg.Execute(ctx)
+	}))
+	actions = append(actions, secretGetAction)
+
+	// platform:secret:set action
+	secretSetYaml, _ := actionYamlFS.ReadFile("actions/secret/set.yaml")
+	secretSetAction := action.NewFromYAML("platform:secret:set", secretSetYaml)
+	secretSetAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		ss := &secret.Set{
+			Keyring:  p.k,
+			Name:     input.Arg("name").(string),
+			Key:      input.Arg("key").(string),
+			Value:    input.Arg("value").(string),
+			Generate: input.Opt("generate").(bool),
+			Force:    input.Opt("force").(bool),
+		}
+		ss.SetLogger(log)
+		ss.SetTerm(term)
+		return ss.Execute(ctx)
+	}))
+	actions = append(actions, secretSetAction)
+
+	// platform:secret:list action
+	secretListYaml, _ := actionYamlFS.ReadFile("actions/secret/list.yaml")
+	secretListAction := action.NewFromYAML("platform:secret:list", secretListYaml)
+	secretListAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		sl := &secret.List{
+			Keyring: p.k,
+			Name:    input.Arg("name").(string),
+			Format:  input.Opt("output").(string),
+		}
+		sl.SetLogger(log)
+		sl.SetTerm(term)
+		return sl.Execute(ctx)
+	}))
+	actions = append(actions, secretListAction)
+
+	// platform:credentials action
+	credentialsYaml, _ := actionYamlFS.ReadFile("actions/credentials/bootstrap.yaml")
+	credentialsAction := action.NewFromYAML("platform:credentials", credentialsYaml)
+	credentialsAction.SetRuntime(newRuntime(func(ctx context.Context, a *action.Action) error {
+		if err := applyConfigDefaults(a, p.cfg, "platform.up"); err != nil {
+			return err
+		}
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		b := &credentials.Bootstrap{
+			Keyring:      p.k,
+			Config:       p.cfg,
+			Name:         input.Arg("name").(string),
+			Check:        input.Opt("check").(bool),
+			GitlabDomain: input.Opt("gitlab-domain").(string),
+			GiteaDomain:  input.Opt("gitea-domain").(string),
+		}
+		b.SetLogger(log)
+		b.SetTerm(term)
+		return b.Execute(ctx)
+	}))
+	actions = append(actions, credentialsAction)
+
+	// platform:credentials:migrate action
+	migrateYaml, _ := actionYamlFS.ReadFile("actions/credentials/migrate.yaml")
+	migrateAction := action.NewFromYAML("platform:credentials:migrate", migrateYaml)
+	migrateAction.SetRuntime(newRuntime(func(_ context.Context, a *action.Action) error {
+		if err := applyConfigDefaults(a, p.cfg, "platform.up"); err != nil {
+			return err
+		}
+		input := a.Input()
+		log, term := getLoggerTerm(a)
+		m := &credentials.Migrate{
+			Keyring:      p.k,
+			Config:       p.cfg,
+			Name:         input.Arg("name").(string),
+			Purposes:     action.InputOptSlice[string](input, "purpose"),
+			DryRun:       input.Opt("dry-run").(bool),
+			GitlabDomain: input.Opt("gitlab-domain").(string),
+			GiteaDomain:  input.Opt("gitea-domain").(string),
+		}
+		m.SetLogger(log)
+		m.SetTerm(term)
+		return m.Execute()
+	}))
+	actions = append(actions, migrateAction)
+
 	// Note: platform:prepare is NOT embedded here.
 	// It must be provided by plasmactl-model plugin.
 	// platform:up validates its existence at runtime.
@@ -197,17 +864,53 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 	return actions, nil
 }
 
-// getLoggerTerm extracts logger and terminal from action runtime
+// getLoggerTerm extracts logger and terminal from action runtime. The records of the logger have the
+// action and the platform it targets as fields, so the JSON logs are filtered without parsing messages.
 func getLoggerTerm(a *action.Action) (*launchr.Logger, *launchr.Terminal) {
 	log := launchr.Log()
 	if rt, ok := a.Runtime().(action.RuntimeLoggerAware); ok {
 		log = rt.LogWith()
 	}
+	log = &launchr.Logger{Slog: log.With(logFields(a)...), LogOptions: log.LogOptions}
 
 	term := launchr.Term()
 	if rt, ok := a.Runtime().(action.RuntimeTermAware); ok {
 		term = rt.Term()
 	}
+	if output.JSONLogs() {
+		// Keep stdout for the log records
+		term.SetOutput(a.Input().Streams().Err())
+	}
 
 	return log, term
 }
+
+// logFields returns the fields of the log records of an action: its ID, the platform given by its name
+// or platform argument, otherwise the one found for the environment deployed to, and the environment
+func logFields(a *action.Action) []any {
+	fields := []any{"action", a.ID}
+	input := a.Input()
+	environment, ok := input.Arg("environment").(string)
+	if !ok || environment == "" {
+		environment, _ = input.Opt("environment").(string)
+	}
+
+	platform := ""
+	for _, name := range []string{"name", "platform"} {
+		if v, ok := input.Arg(name).(string); ok && v != "" {
+			platform = v
+			break
+		}
+	}
+	if platform == "" && environment != "" {
+		// The platform of an environment may be named differently, e.g. inst/ski-dev of dev
+		platform, _ = schema.FindPlatform("", environment)
+	}
+	if platform != "" {
+		fields = append(fields, "platform", platform)
+	}
+	if environment != "" {
+		fields = append(fields, "environment", environment)
+	}
+	return fields
+}
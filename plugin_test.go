@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/launchrctl/launchr/pkg/action"
+
+	"github.com/plasmash/plasmactl-platform/pkg/schema"
+)
+
+func TestLogFields(t *testing.T) {
+	t.Chdir(t.TempDir())
+	path := schema.PlatformFile("ski-dev")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("name: dev\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		file string
+		args action.InputParams
+		want []any
+	}{
+		{"platform argument", "platform:validate", "actions/validate/validate.yaml", action.InputParams{"name": "ski-dev"}, []any{"action", "platform:validate", "platform", "ski-dev"}},
+		{"platform of the environment", "platform:deploy", "actions/deploy/deploy.yaml", action.InputParams{"environment": "dev", "tags": "core"}, []any{"action", "platform:deploy", "platform", "ski-dev", "environment", "dev"}},
+		{"environment without platform", "platform:deploy", "actions/deploy/deploy.yaml", action.InputParams{"environment": "prod", "tags": "core"}, []any{"action", "platform:deploy", "environment", "prod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := actionYamlFS.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := action.NewFromYAML(tt.id, def)
+			input := action.NewInput(a, tt.args, nil, nil)
+			input.SetValidated(true)
+			if err = a.SetInput(input); err != nil {
+				t.Fatal(err)
+			}
+			if got := logFields(a); !slices.Equal(got, tt.want) {
+				t.Errorf("logFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/launchrctl/launchr"
+
+	"github.com/plasmash/plasmactl-platform/pkg/dns"
+	"github.com/plasmash/plasmactl-platform/pkg/provider"
+)
+
+// registerPluginProviders registers the metal and DNS providers contributed by the other plugins of
+// the application, in the order of their weight
+func registerPluginProviders(app launchr.App) error {
+	var pm launchr.PluginManager
+	app.GetService(&pm)
+	plugins := pm.All()
+	infos := slices.SortedFunc(maps.Keys(plugins), func(a, b launchr.PluginInfo) int {
+		return cmp.Or(cmp.Compare(a.Weight, b.Weight), cmp.Compare(a.String(), b.String()))
+	})
+	for _, info := range infos {
+		p := plugins[info]
+		if mp, ok := p.(provider.Plugin); ok {
+			if err := provider.RegisterPlugin(mp); err != nil {
+				return fmt.Errorf("plugin %s: %w", info, err)
+			}
+			launchr.Log().Debug("registered metal providers of plugin", "plugin", info)
+		}
+		_, isDNS := p.(dns.Plugin)
+		_, isReverse := p.(dns.ReversePlugin)
+		if isDNS || isReverse {
+			if err := dns.RegisterPlugin(p); err != nil {
+				return fmt.Errorf("plugin %s: %w", info, err)
+			}
+			launchr.Log().Debug("registered DNS providers of plugin", "plugin", info)
+		}
+	}
+	return nil
+}